@@ -29,6 +29,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/pkg/monitor"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/pkg/updater"
 )
 
 type Agent struct {
@@ -52,6 +53,9 @@ func (a *Agent) start(stop chan struct{}) {
 	// Initialize the agent
 	InitAgent()
 
+	// Finish or roll back the upgrade that restarted the agent
+	updater.CheckUpgrade()
+
 	// Start the agent core service
 	agentCtl := agent.NewAgentController()
 	go agentCtl.Start(a.Ctx)
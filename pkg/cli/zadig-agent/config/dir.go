@@ -111,6 +111,17 @@ func GetStopFilePath() (string, error) {
 	return path, nil
 }
 
+// GetUpgradeRecordFilePath returns the file recording the last self-upgrade of the agent
+func GetUpgradeRecordFilePath() (string, error) {
+	homeDir, err := osutil.GetUserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(homeDir, ".zadig-agent/upgrade.json")
+	return path, nil
+}
+
 func GetAgentWorkDir(dir string) (string, error) {
 	if dir == "" {
 		home, err := osutil.GetUserHomeDir()
@@ -19,6 +19,8 @@ package agent
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
@@ -37,9 +39,15 @@ func NewAgentController() *AgentController {
 		StopRunJobChan:       make(chan struct{}, 1),
 		ConcurrencyBlockTime: common.DefaultAgentConcurrencyBlockTime,
 		CurrentJobNum:        0,
+		DispatchedJobChan:    make(chan *types.ZadigJobTask, common.DefaultAgentConcurrency),
 	}
 }
 
+type runningJob struct {
+	executor *jobexecutor.JobExecutor
+	cancel   context.CancelFunc
+}
+
 type AgentController struct {
 	Client               *network.ZadigClient
 	JobChan              chan *types.ZadigJobTask
@@ -47,8 +55,18 @@ type AgentController struct {
 	StopRunJobChan       chan struct{}
 	Concurrency          int
 	ConcurrencyBlockTime int
-	CurrentJobNum        int
-	WorkingDirectory     string
+	// CurrentJobNum is the number of accepted jobs that are not finished, it is only accessed atomically
+	CurrentJobNum    int32
+	WorkingDirectory string
+
+	// DispatchedJobChan receives the jobs dispatched by zadig server over the agent channel
+	DispatchedJobChan chan *types.ZadigJobTask
+	// runningJobs is the running jobs keyed by job id, for cancellation pushed by zadig server
+	runningJobs sync.Map
+
+	// acceptMu serializes accepting jobs with draining them, draining is set while the jobs are drained for an upgrade
+	acceptMu sync.Mutex
+	draining int32
 }
 
 func (c *AgentController) Start(ctx context.Context) {
@@ -76,6 +94,19 @@ func (c *AgentController) PollingJob(ctx context.Context) {
 		close(c.JobChan)
 	}()
 
+	// jobs are dispatched over the agent channel while it is connected, polling only happens when it is not
+	channelCtx, channelCancel := context.WithCancel(ctx)
+	defer channelCancel()
+	c.Client.Channel.Free = c.freeJobSlots
+	c.Client.Channel.OnDispatch = func(job *types.ZadigJobTask) {
+		select {
+		case c.DispatchedJobChan <- job:
+		case <-channelCtx.Done():
+		}
+	}
+	c.Client.Channel.OnCancel = c.CancelJob
+	go c.Client.Channel.Run(channelCtx)
+
 	log.Infof("start polling job.")
 	for {
 		select {
@@ -85,29 +116,23 @@ func (c *AgentController) PollingJob(ctx context.Context) {
 		case <-c.StopPollingJobChan:
 			log.Infof("stop polling job, received stop signal.")
 			return
+		case job := <-c.DispatchedJobChan:
+			c.acceptJob(job)
 		default:
-			if config.GetAgentStatus() == common.AGENT_STATUS_RUNNING && config.GetScheduleWorkflow() && c.CurrentJobNum < config.GetConcurrency() {
-				job, err := c.Client.RequestJob()
-				if err != nil {
-					log.Errorf("failed to request job from zadig server, error: %s", err)
-					time.Sleep(common.DefaultAgentPollingInterval * time.Second)
-					continue
-				}
+			if c.Client.Channel.Connected() {
+				time.Sleep(common.DefaultJobLogStreamInterval * time.Millisecond)
+				continue
+			}
 
-				if job != nil && job.ID != "" {
-					c.JobChan <- job
-					c.CurrentJobNum++
-					log.Infof("PollingJob: received job workflow name: %v, task id: %v, project name: %v, job name: %v",
-						job.WorkflowName, job.TaskID, job.ProjectName, job.JobName)
-					if config.GetEnableDebug() {
-						log.Debugf("received job detail: %+v", job)
-					}
+			currentJobNum := int(atomic.LoadInt32(&c.CurrentJobNum))
+			if config.GetAgentStatus() == common.AGENT_STATUS_RUNNING && config.GetScheduleWorkflow() && currentJobNum < config.GetConcurrency() {
+				if err := c.pollJob(); err != nil {
+					log.Errorf("failed to request job from zadig server, error: %s", err)
 				}
-
 				time.Sleep(common.DefaultAgentPollingInterval * time.Second)
 			} else {
-				if c.CurrentJobNum >= config.GetConcurrency() {
-					log.Infof("current job num %d is equal to concurrency %d, will block %d seconds to request job again.", c.CurrentJobNum, config.GetConcurrency(), c.ConcurrencyBlockTime)
+				if currentJobNum >= config.GetConcurrency() {
+					log.Infof("current job num %d is equal to concurrency %d, will block %d seconds to request job again.", currentJobNum, config.GetConcurrency(), c.ConcurrencyBlockTime)
 				}
 				time.Sleep(time.Duration(c.ConcurrencyBlockTime) * time.Second)
 			}
@@ -115,6 +140,91 @@ func (c *AgentController) PollingJob(ctx context.Context) {
 	}
 }
 
+// pollJob requests a job from zadig server, no job is requested while the jobs are drained.
+func (c *AgentController) pollJob() error {
+	c.acceptMu.Lock()
+	defer c.acceptMu.Unlock()
+	if atomic.LoadInt32(&c.draining) == 1 {
+		return nil
+	}
+
+	job, err := c.Client.RequestJob()
+	if err != nil {
+		return err
+	}
+	if job != nil && job.ID != "" {
+		c.acceptJobLocked(job)
+	}
+	return nil
+}
+
+// acceptJob runs a job dispatched by zadig server. The jobs dispatched before the server learned that the agent is
+// draining are still run, the drain waits for them.
+func (c *AgentController) acceptJob(job *types.ZadigJobTask) {
+	c.acceptMu.Lock()
+	defer c.acceptMu.Unlock()
+	c.acceptJobLocked(job)
+}
+
+func (c *AgentController) acceptJobLocked(job *types.ZadigJobTask) {
+	c.JobChan <- job
+	atomic.AddInt32(&c.CurrentJobNum, 1)
+	log.Infof("PollingJob: received job workflow name: %v, task id: %v, project name: %v, job name: %v",
+		job.WorkflowName, job.TaskID, job.ProjectName, job.JobName)
+	if config.GetEnableDebug() {
+		log.Debugf("received job detail: %+v", job)
+	}
+	c.Client.Channel.NotifyReady()
+}
+
+func (c *AgentController) freeJobSlots() int {
+	if config.GetAgentStatus() != common.AGENT_STATUS_RUNNING || !config.GetScheduleWorkflow() || atomic.LoadInt32(&c.draining) == 1 {
+		return 0
+	}
+	free := config.GetConcurrency() - int(atomic.LoadInt32(&c.CurrentJobNum)) - len(c.DispatchedJobChan)
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// DrainJobs stops polling jobs and tells zadig server to stop dispatching them, then waits until the accepted jobs are
+// finished. It returns with the jobs locked so that a job dispatched late is not started before the agent is replaced,
+// the returned function resumes accepting jobs if the agent is not replaced after all.
+func (c *AgentController) DrainJobs(interval time.Duration) func() {
+	atomic.StoreInt32(&c.draining, 1)
+	c.Client.Channel.NotifyReady()
+
+	for {
+		c.acceptMu.Lock()
+		if atomic.LoadInt32(&c.CurrentJobNum) == 0 && len(c.DispatchedJobChan) == 0 {
+			break
+		}
+		c.acceptMu.Unlock()
+		time.Sleep(interval)
+	}
+
+	return func() {
+		atomic.StoreInt32(&c.draining, 0)
+		c.acceptMu.Unlock()
+		c.Client.Channel.NotifyReady()
+	}
+}
+
+// CancelJob stops a running job right away when zadig server pushes its cancellation, the processes started by
+// its steps are killed.
+func (c *AgentController) CancelJob(jobID string) {
+	value, ok := c.runningJobs.Load(jobID)
+	if !ok {
+		return
+	}
+	job := value.(*runningJob)
+
+	log.Infof("job %s is cancelled by zadig server.", jobID)
+	*job.executor.Cancel = true
+	job.cancel()
+}
+
 func (c *AgentController) RunJob(ctx context.Context) {
 	log.Infof("start running job.")
 	for {
@@ -133,7 +243,8 @@ func (c *AgentController) RunJob(ctx context.Context) {
 
 			go func() {
 				defer func() {
-					c.CurrentJobNum--
+					atomic.AddInt32(&c.CurrentJobNum, -1)
+					c.Client.Channel.NotifyReady()
 				}()
 				if err := c.RunSingleJob(ctx, job); err != nil {
 					log.Errorf("failed to run job, error: %s", err)
@@ -146,7 +257,12 @@ func (c *AgentController) RunJob(ctx context.Context) {
 func (c *AgentController) RunSingleJob(ctx context.Context, job *types.ZadigJobTask) error {
 	var err error
 	jobCtx, cancel := context.WithCancel(ctx)
-	executor := jobexecutor.NewJobExecutor(ctx, job, c.Client, cancel)
+	stepCtx, stepCancel := context.WithCancel(ctx)
+	defer stepCancel()
+	executor := jobexecutor.NewJobExecutor(stepCtx, job, c.Client, cancel)
+
+	c.runningJobs.Store(job.ID, &runningJob{executor: executor, cancel: stepCancel})
+	defer c.runningJobs.Delete(job.ID)
 
 	// execute some init job before execute zadig job
 	err = executor.BeforeExecute()
@@ -0,0 +1,307 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	jobctl "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+// containerCLIs are the clients of the local container runtime, nerdctl is used on the hosts with only containerd
+var containerCLIs = []string{"docker", "nerdctl"}
+
+// Container is the container a vm job runs in when the job is isolated from the agent host, every command of the job,
+// the scripts, git, tar and the copies of the archives and caches, runs in it.
+type Container struct {
+	Name string
+	// Home is the home directory of the job in the container, the ssh keys of the git step are written to it
+	Home string
+	// Envs are the job variables passed into the container, the container does not inherit the environment of the agent
+	Envs []string
+
+	cli        string
+	removed    chan struct{}
+	removeOnce sync.Once
+}
+
+// Start runs a container of the job image that lives until it is removed, the dirs are bind mounted at the same paths
+// so that the scripts, outputs and workspace of the job are shared with the agent. The container runs as the user of
+// the agent so the files it leaves in the dirs can be removed by the agent, home must be one of the dirs or be in one.
+// Everything running in the container is killed when ctx is done.
+func Start(ctx context.Context, name string, config *jobctl.JobContainerConfig, home string, dirs []string) (*Container, error) {
+	if config.Image == "" {
+		return nil, fmt.Errorf("no image is set for job container %s", name)
+	}
+	cli, err := lookupCLI()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pullImage(ctx, cli, config); err != nil {
+		return nil, err
+	}
+	user := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	out, err := exec.CommandContext(ctx, cli, runArgs(name, config, user, home, dirs)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start container %s of image %s, error: %v, output: %s", name, config.Image, err, strings.TrimSpace(string(out)))
+	}
+
+	c := &Container{Name: name, Home: home, cli: cli, removed: make(chan struct{})}
+	go c.killOnCancel(ctx)
+	return c, nil
+}
+
+func runArgs(name string, config *jobctl.JobContainerConfig, user, home string, dirs []string) []string {
+	args := []string{"run", "-d", "--name", name, "--user", user, "--entrypoint", "sleep"}
+	if home != "" {
+		args = append(args, "-e", "HOME="+home)
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		args = append(args, "-v", fmt.Sprintf("%s:%s", dir, dir))
+	}
+	if config.CpuLimit > 0 {
+		// cpu limit is in millicores
+		args = append(args, "--cpus", strconv.FormatFloat(float64(config.CpuLimit)/1000, 'f', 3, 64))
+	}
+	if config.MemoryLimit > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", config.MemoryLimit))
+	}
+	return append(args, config.Image, "infinity")
+}
+
+// pullImage pulls the job image with the credentials of its registries, the credentials are only kept in a temporary
+// docker config during the pull so they neither end up in the config of the host nor in the container. Images of
+// public registries are pulled by run.
+func pullImage(ctx context.Context, cli string, config *jobctl.JobContainerConfig) error {
+	registries := make([]*step.DockerRegistry, 0)
+	for _, registry := range config.Registries {
+		if registry != nil && registry.UserName != "" {
+			registries = append(registries, registry)
+		}
+	}
+	if len(registries) == 0 {
+		return nil
+	}
+
+	dockerConfig, err := os.MkdirTemp("", "zadig-job-docker-config-")
+	if err != nil {
+		return fmt.Errorf("failed to create docker config dir, error: %v", err)
+	}
+	defer os.RemoveAll(dockerConfig)
+	envs := append(os.Environ(), "DOCKER_CONFIG="+dockerConfig)
+
+	for _, registry := range registries {
+		cmd := exec.CommandContext(ctx, cli, loginArgs(registry)...)
+		cmd.Env = envs
+		cmd.Stdin = strings.NewReader(registry.Password)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to login registry %s, error: %v, output: %s", registry.Host, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, cli, "pull", config.Image)
+	cmd.Env = envs
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to pull image %s, error: %v, output: %s", config.Image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func loginArgs(registry *step.DockerRegistry) []string {
+	host := strings.TrimPrefix(strings.TrimPrefix(registry.Host, "http://"), "https://")
+	return []string{"login", "-u", registry.UserName, "--password-stdin", host}
+}
+
+// Command returns the command running in the container, the values of Envs are passed by key so that they do not
+// show up in the process list of the host.
+func (c *Container) Command(dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(c.cli, c.execArgs(dir, name, args)...)
+	cmd.Env = append(os.Environ(), c.Envs...)
+	return cmd
+}
+
+func (c *Container) execArgs(dir, name string, args []string) []string {
+	execArgs := []string{"exec", "-i"}
+	if dir != "" {
+		execArgs = append(execArgs, "-w", dir)
+	}
+	for _, env := range c.Envs {
+		key := strings.SplitN(env, "=", 2)[0]
+		if key != "" {
+			execArgs = append(execArgs, "-e", key)
+		}
+	}
+	execArgs = append(execArgs, c.Name, name)
+	return append(execArgs, args...)
+}
+
+// Wrap returns a command running the program of cmd in the container, in the working directory and with the stdin of
+// cmd. The environment of cmd is replaced by Envs.
+func (c *Container) Wrap(cmd *exec.Cmd) *exec.Cmd {
+	wrapped := c.Command(cmd.Dir, cmd.Args[0], cmd.Args[1:]...)
+	wrapped.Stdin = cmd.Stdin
+	return wrapped
+}
+
+// CopyOut copies path in the container to dest on the agent host. The links in path are resolved in the container and
+// only directories and regular files are written to dest, so the copy can't reach files of the host outside of dest.
+func (c *Container) CopyOut(path, dest string) error {
+	cmd := c.Command("", "tar", "-chf", "-", "-C", filepath.Dir(path), filepath.Base(path))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	extractErr := extract(stdout, dest)
+	// drain the rest of the archive so that tar is not blocked on a full pipe
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to copy %s out of container %s, error: %v, output: %s", path, c.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return extractErr
+}
+
+func extract(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := extractPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// tar stores the files hard linked to a file it already archived as links, they are copied from that file
+			linked, err := extractPath(dest, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := copyFile(linked, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractPath returns the path of an archive entry in dest, the entries outside of dest are rejected
+func extractPath(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %s in archive", name)
+	}
+	return target, nil
+}
+
+func copyFile(src, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeFile(dest, file, info.Mode().Perm())
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Remove kills the container with everything still running in it.
+func (c *Container) Remove() error {
+	c.removeOnce.Do(func() {
+		close(c.removed)
+	})
+	out, err := exec.Command(c.cli, "rm", "-f", c.Name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove container %s, error: %v, output: %s", c.Name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// killOnCancel kills the container when the job is cancelled. Killing the local exec client of a command does not stop
+// the processes it started in the container, killing the container stops them all.
+func (c *Container) killOnCancel(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		if out, err := exec.Command(c.cli, "kill", c.Name).CombinedOutput(); err != nil {
+			log.Errorf("failed to kill container %s, error: %v, output: %s", c.Name, err, strings.TrimSpace(string(out)))
+		}
+	case <-c.removed:
+	}
+}
+
+func lookupCLI() (string, error) {
+	for _, cli := range containerCLIs {
+		if path, err := exec.LookPath(cli); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no container runtime client found on the host, one of %s is required", strings.Join(containerCLIs, ", "))
+}
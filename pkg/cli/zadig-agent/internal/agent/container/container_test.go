@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jobctl "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+func TestRunArgs(t *testing.T) {
+	config := &jobctl.JobContainerConfig{
+		Image:       "koderover/build-base:focal-amd64",
+		CpuLimit:    1500,
+		MemoryLimit: 2048,
+	}
+
+	args := runArgs("zadig-job-1", config, "1000:1000", "/work/script/home", []string{"/work/ws", "", "/work/script"})
+	assert.Equal(t, []string{
+		"run", "-d", "--name", "zadig-job-1", "--user", "1000:1000", "--entrypoint", "sleep",
+		"-e", "HOME=/work/script/home",
+		"-v", "/work/ws:/work/ws",
+		"-v", "/work/script:/work/script",
+		"--cpus", "1.500",
+		"--memory", "2048m",
+		"koderover/build-base:focal-amd64", "infinity",
+	}, args)
+}
+
+func TestRunArgsWithoutLimits(t *testing.T) {
+	args := runArgs("zadig-job-1", &jobctl.JobContainerConfig{Image: "alpine"}, "0:0", "", nil)
+	assert.Equal(t, []string{"run", "-d", "--name", "zadig-job-1", "--user", "0:0", "--entrypoint", "sleep", "alpine", "infinity"}, args)
+}
+
+func TestStartWithoutImage(t *testing.T) {
+	_, err := Start(context.Background(), "zadig-job-1", &jobctl.JobContainerConfig{}, "", nil)
+	assert.EqualError(t, err, "no image is set for job container zadig-job-1")
+}
+
+func TestLoginArgs(t *testing.T) {
+	registry := &step.DockerRegistry{Host: "https://registry.example.com", UserName: "admin", Password: "secret"}
+
+	args := loginArgs(registry)
+	assert.Equal(t, []string{"login", "-u", "admin", "--password-stdin", "registry.example.com"}, args)
+	// the password is passed through stdin only
+	assert.NotContains(t, args, "secret")
+}
+
+func TestCommand(t *testing.T) {
+	c := &Container{Name: "zadig-job-1", cli: "/usr/bin/docker", Envs: []string{"CI=true", "TOKEN=a=b", "=invalid"}}
+
+	cmd := c.Command("/work/ws", "bash", "/work/script/user_script.sh")
+	assert.Equal(t, "/usr/bin/docker", cmd.Path)
+	assert.Equal(t, []string{
+		"/usr/bin/docker", "exec", "-i", "-w", "/work/ws",
+		"-e", "CI", "-e", "TOKEN",
+		"zadig-job-1", "bash", "/work/script/user_script.sh",
+	}, cmd.Args)
+	// the values are only in the environment of the client
+	assert.Contains(t, cmd.Env, "TOKEN=a=b")
+	assert.NotContains(t, cmd.Args, "TOKEN=a=b")
+}
+
+func TestCommandWithoutDir(t *testing.T) {
+	c := &Container{Name: "zadig-job-1", cli: "docker"}
+
+	cmd := c.Command("", "tar", "-chf", "-")
+	assert.Equal(t, []string{"docker", "exec", "-i", "zadig-job-1", "tar", "-chf", "-"}, cmd.Args)
+}
+
+func TestWrap(t *testing.T) {
+	c := &Container{Name: "zadig-job-1", cli: "docker", Envs: []string{"CI=true"}}
+
+	cmd := exec.Command("git", "fetch", "origin")
+	cmd.Dir = "/work/ws/repo"
+	cmd.Env = []string{"HOST_SECRET=1"}
+	cmd.Stdin = bytes.NewBufferString("input")
+
+	wrapped := c.Wrap(cmd)
+	assert.Equal(t, []string{"docker", "exec", "-i", "-w", "/work/ws/repo", "-e", "CI", "zadig-job-1", "git", "fetch", "origin"}, wrapped.Args)
+	assert.Equal(t, cmd.Stdin, wrapped.Stdin)
+	assert.NotContains(t, wrapped.Env, "HOST_SECRET=1")
+}
+
+type tarEntry struct {
+	header  *tar.Header
+	content string
+}
+
+func buildTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.content))
+		require.NoError(t, tw.WriteHeader(entry.header))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf
+}
+
+func TestExtract(t *testing.T) {
+	dest := t.TempDir()
+	archive := buildTar(t, []tarEntry{
+		{header: &tar.Header{Name: "dist/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: &tar.Header{Name: "dist/app", Typeflag: tar.TypeReg, Mode: 0755}, content: "binary"},
+		{header: &tar.Header{Name: "dist/app-link", Typeflag: tar.TypeLink, Linkname: "dist/app"}},
+		{header: &tar.Header{Name: "dist/passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+	})
+
+	require.NoError(t, extract(archive, dest))
+
+	content, err := os.ReadFile(filepath.Join(dest, "dist", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(content))
+	content, err = os.ReadFile(filepath.Join(dest, "dist", "app-link"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(content))
+	// links are not written to the host
+	_, err = os.Lstat(filepath.Join(dest, "dist", "passwd"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExtractRejectsPathsOutsideDest(t *testing.T) {
+	for _, entry := range []tarEntry{
+		{header: &tar.Header{Name: "../escaped", Typeflag: tar.TypeReg, Mode: 0644}, content: "x"},
+		{header: &tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"}},
+	} {
+		dest := filepath.Join(t.TempDir(), "dest")
+		require.NoError(t, os.Mkdir(dest, 0755))
+
+		assert.Error(t, extract(buildTar(t, []tarEntry{entry}), dest))
+		_, err := os.Stat(filepath.Join(filepath.Dir(dest), "escaped"))
+		assert.True(t, os.IsNotExist(err))
+	}
+}
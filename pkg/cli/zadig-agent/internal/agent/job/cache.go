@@ -27,6 +27,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
 )
@@ -76,14 +77,16 @@ func ReadCache(job types.ZadigJobTask, dest, cacheDir string, logger *zap.Sugare
 		}
 		return
 	} else {
-		err = copyCmd(cachePath, dest, logger)
+		err = copyCmd(cachePath, dest, nil, logger)
 		if err != nil {
 			log.Errorf("failed to copy cache directory %s to %s, error: %v", cachePath, dest, err)
 		}
 	}
 }
 
-func WriteCache(job types.ZadigJobTask, src string, cachePath string, logger *zap.SugaredLogger) {
+// WriteCache saves src to the cache path, the copy runs in the job container when the job runs in one so that the links
+// in src are not followed on the agent host.
+func WriteCache(job types.ZadigJobTask, src string, cachePath string, jobContainer *container.Container, logger *zap.SugaredLogger) {
 	// lock the cache path to avoid other job write or read this path at the same time
 	key := fmt.Sprintf("%s-%s-%s", job.ProjectName, job.WorkflowName, job.JobName)
 	cache.Lock(key)
@@ -96,14 +99,17 @@ func WriteCache(job types.ZadigJobTask, src string, cachePath string, logger *za
 		}
 	}
 
-	err := copyCmd(src, cachePath, logger)
+	err := copyCmd(src, cachePath, jobContainer, logger)
 	if err != nil {
 		log.Errorf("failed to copy cache directory %s to %s, error: %v", src, cachePath, err)
 	}
 }
 
-func copyCmd(src, dest string, logger *zap.SugaredLogger) error {
+func copyCmd(src, dest string, jobContainer *container.Container, logger *zap.SugaredLogger) error {
 	cmd := exec.Command("cp", "-f", "-r", src, dest)
+	if jobContainer != nil {
+		cmd = jobContainer.Wrap(cmd)
+	}
 	var wg sync.WaitGroup
 
 	cmdStdoutReader, err := cmd.StdoutPipe()
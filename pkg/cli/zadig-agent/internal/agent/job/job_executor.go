@@ -23,12 +23,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/reporter"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common"
@@ -72,6 +74,9 @@ type JobExecutor struct {
 	FinishedChan     chan struct{}
 	ReporterCancel   context.CancelFunc
 	Dirs             *types.AgentWorkDirs
+
+	// Container is the container the job runs in, it is nil unless the job is isolated from the agent host
+	Container *container.Container
 }
 
 // BeforeExecute init execute context and command
@@ -253,6 +258,14 @@ func (e *JobExecutor) Execute() {
 		return
 	}
 
+	if e.JobCtx.Container != nil {
+		if err = e.startContainer(); err != nil {
+			e.Logger.Errorf(err.Error())
+			e.JobResult.SetError(err)
+			return
+		}
+	}
+
 	err = e.run()
 	if err != nil {
 		e.Logger.Errorf(fmt.Sprintf("failed to execute job, error: %v", err))
@@ -262,6 +275,9 @@ func (e *JobExecutor) Execute() {
 }
 
 func (e *JobExecutor) run() error {
+	if err := step.ValidateSteps(e.JobCtx.Steps); err != nil {
+		return err
+	}
 	hasFailed := false
 	var respErr error
 
@@ -272,7 +288,10 @@ func (e *JobExecutor) run() error {
 		if hasFailed && !stepInfo.Onfailure {
 			continue
 		}
-		if err := step.RunStep(e.Ctx, e.JobCtx, stepInfo, e.Dirs, e.getUserEnvs(), e.JobCtx.SecretEnvs, e.Logger); err != nil {
+		if e.Container != nil {
+			e.Container.Envs = e.getJobEnvs()
+		}
+		if err := step.RunStep(e.Ctx, e.JobCtx, stepInfo, e.Dirs, e.getUserEnvs(), e.JobCtx.SecretEnvs, e.Container, e.Logger); err != nil {
 			hasFailed = true
 			respErr = err
 		}
@@ -282,16 +301,21 @@ func (e *JobExecutor) run() error {
 
 func (e *JobExecutor) getUserEnvs() []string {
 	envs := os.Environ()
-	envs = append(envs,
-		"CI=true",
-		"ZADIG=true",
-		fmt.Sprintf("HOME=%s", config.Home()),
-		fmt.Sprintf("WORKSPACE=%s", e.Dirs.Workspace),
-	)
+	envs = append(envs, fmt.Sprintf("HOME=%s", config.Home()))
 
 	//e.JobCtx.Paths = strings.Replace(e.JobCtx.Paths, "$HOME", config.Home(), -1)
 	//envs = append(envs, fmt.Sprintf("PATH=%s", e.JobCtx.Paths))
 	envs = append(envs, fmt.Sprintf("DOCKER_HOST=%s", e.DockerHost))
+	return append(envs, e.getJobEnvs()...)
+}
+
+// getJobEnvs returns the variables of the job without the environment of the agent host
+func (e *JobExecutor) getJobEnvs() []string {
+	envs := []string{
+		"CI=true",
+		"ZADIG=true",
+		fmt.Sprintf("WORKSPACE=%s", e.Dirs.Workspace),
+	}
 	envs = append(envs, e.JobCtx.Envs...)
 	envs = append(envs, e.JobCtx.SecretEnvs...)
 	// share output var between steps.
@@ -308,6 +332,8 @@ func (e *JobExecutor) getUserEnvs() []string {
 
 func (e *JobExecutor) AfterExecute() error {
 	log.Infof("start project %s workflow %s job %s AfterExecute stage", e.Job.ProjectName, e.Job.WorkflowName, e.Job.JobName)
+	// the job container is kept until the cache is saved from it
+	defer e.removeContainer()
 
 	// -------------------------------------------------- save job cache ------------------------------------------------
 	if e.JobCtx.Cache != nil && e.JobCtx.Cache.CacheEnable {
@@ -321,19 +347,16 @@ func (e *JobExecutor) AfterExecute() error {
 			return fmt.Errorf("user custom cache path %s does not exist in workspace %s", e.JobCtx.Cache.CacheUserDir, e.Dirs.Workspace)
 		}
 
-		var cachePath string
-		if e.JobCtx.Cache.CacheDirType == common.CacheDirUserDefineType && e.JobCtx.Cache.CacheUserDir != "" {
-			cachePath = filepath.Join(e.Dirs.CacheDir, e.Job.ProjectName, e.Job.WorkflowName, e.Job.JobName)
-		} else {
-			cachePath = filepath.Join(e.Dirs.CacheDir, e.Job.ProjectName, e.Job.WorkflowName)
-		}
-		WriteCache(*e.Job, src, cachePath, log.GetSimpleLogger())
+		WriteCache(*e.Job, src, e.cachePath(), e.Container, log.GetSimpleLogger())
 	}
 
 	// ------------------------------------------------ report all job log ----------------------------------------------
 	for {
 		logStr, EOFErr, err := e.Reporter.GetJobLog()
 		if err == nil {
+			// the log that failed to be streamed goes first
+			logStr = e.JobResult.Log + logStr
+			e.JobResult.Log = ""
 			resp, err := e.Reporter.ReportWithData(
 				&types.JobExecuteResult{
 					JobInfo: e.JobResult.JobInfo,
@@ -374,6 +397,14 @@ func (e *JobExecutor) AfterExecute() error {
 	return nil
 }
 
+// cachePath is the directory the cache of the job is saved to
+func (e *JobExecutor) cachePath() string {
+	if e.JobCtx.Cache.CacheDirType == common.CacheDirUserDefineType && e.JobCtx.Cache.CacheUserDir != "" {
+		return filepath.Join(e.Dirs.CacheDir, e.Job.ProjectName, e.Job.WorkflowName, e.Job.JobName)
+	}
+	return filepath.Join(e.Dirs.CacheDir, e.Job.ProjectName, e.Job.WorkflowName)
+}
+
 func (e *JobExecutor) getJobOutputVars() ([]*job.JobOutput, error) {
 	outputs := []*job.JobOutput{}
 	for _, outputName := range e.JobCtx.Outputs {
@@ -417,6 +448,44 @@ func (e *JobExecutor) deleteTempFileAndDir() error {
 	return nil
 }
 
+func (e *JobExecutor) startContainer() error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("running job in container is not supported on windows")
+	}
+
+	// the home of the job is in the script dir, it is removed with it
+	home := filepath.Join(e.Dirs.JobScriptDir, "home")
+	if err := os.MkdirAll(home, 0700); err != nil {
+		return fmt.Errorf("failed to create job home directory, error: %v", err)
+	}
+	dirs := []string{e.Dirs.Workspace, e.Dirs.JobScriptDir, e.Dirs.JobOutputsDir}
+	// the cache is saved from the container, only the cache of the job is mounted
+	if e.JobCtx.Cache != nil && e.JobCtx.Cache.CacheEnable {
+		cachePath := e.cachePath()
+		if err := os.MkdirAll(cachePath, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create cache directory %s, error: %v", cachePath, err)
+		}
+		dirs = append(dirs, cachePath)
+	}
+
+	e.Logger.Infof(fmt.Sprintf("Starting job container of image %s.", e.JobCtx.Container.Image))
+	c, err := container.Start(e.Ctx, fmt.Sprintf("zadig-job-%s", e.Job.ID), e.JobCtx.Container, home, dirs)
+	if err != nil {
+		return err
+	}
+	e.Container = c
+	return nil
+}
+
+func (e *JobExecutor) removeContainer() {
+	if e.Container == nil {
+		return
+	}
+	if err := e.Container.Remove(); err != nil {
+		log.Errorf("failed to remove job container, error: %s", err)
+	}
+}
+
 func (e *JobExecutor) StopJob() error {
 	return nil
 }
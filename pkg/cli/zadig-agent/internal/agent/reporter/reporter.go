@@ -54,14 +54,21 @@ func (r *JobReporter) Start(ctx context.Context) {
 	log.Infof("start project %s workflow %s job %s reporter.", r.Result.JobInfo.ProjectName, r.Result.JobInfo.WorkflowName, r.Result.JobInfo.JobName)
 	r.Ctx = ctx
 	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	logTicker := time.NewTicker(common.DefaultJobLogStreamInterval * time.Millisecond)
+	defer logTicker.Stop()
 
 	for {
-		r.Seq++
 		select {
 		case <-ticker.C:
+			r.Seq++
 			if err := r.Report(); err != nil {
 				log.Error(err)
 			}
+		case <-logTicker.C:
+			if err := r.StreamLog(); err != nil {
+				log.Error(err)
+			}
 		case <-ctx.Done():
 			log.Infof("stop job reporter, received context cancel signal from job executor.")
 			return
@@ -84,10 +91,12 @@ func (r *JobReporter) Report() error {
 		return fmt.Errorf("reporter result is nil")
 	}
 
-	// get log from job log file
-	err := r.SetLog()
-	if err != nil {
-		log.Errorf("failed to set job log, error: %s", err)
+	// get log from job log file, it is streamed separately while the agent channel is connected
+	if !r.Client.Channel.Connected() {
+		err := r.SetLog()
+		if err != nil {
+			log.Errorf("failed to set job log, error: %s", err)
+		}
 	}
 
 	resp, err := r.Client.ReportJob(&types.ReportJobParameters{
@@ -136,8 +145,34 @@ func (r *JobReporter) SetLog() error {
 	if err != nil {
 		return fmt.Errorf("failed to get job log, error: %s", err)
 	}
-	r.Result.Log = logStr
+	// keep the log that failed to be sent last time
+	r.Result.Log += logStr
+
+	return nil
+}
+
+// StreamLog sends the new lines of the job log over the agent channel as soon as they are written.
+func (r *JobReporter) StreamLog() error {
+	if r.Result == nil {
+		return fmt.Errorf("reporter result is nil")
+	}
 
+	for r.Client.Channel.Connected() {
+		logStr, EOF, err := r.GetJobLog()
+		if err != nil {
+			return fmt.Errorf("failed to get job log, error: %s", err)
+		}
+		if logStr != "" {
+			if err := r.Client.Channel.SendLog(r.Result.JobInfo.JobID, logStr); err != nil {
+				// send it with the next report instead
+				r.Result.Log += logStr
+				return fmt.Errorf("%s-%s failed to stream log, error: %s", r.Result.JobInfo.WorkflowName, r.Result.JobInfo.JobName, err)
+			}
+		}
+		if EOF {
+			break
+		}
+	}
 	return nil
 }
 
@@ -29,6 +29,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
 	"github.com/koderover/zadig/v2/pkg/setting"
@@ -43,10 +44,11 @@ type ArchiveStep struct {
 	workspace  string
 	logger     *log.JobLogger
 	dirs       *types.AgentWorkDirs
+	container  *container.Container
 }
 
-func NewArchiveStep(spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, logger *log.JobLogger) (*ArchiveStep, error) {
-	archiveStep := &ArchiveStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, logger: logger}
+func NewArchiveStep(spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, container *container.Container, logger *log.JobLogger) (*ArchiveStep, error) {
+	archiveStep := &ArchiveStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, container: container, logger: logger}
 	yamlBytes, err := yaml.Marshal(spec)
 	if err != nil {
 		return archiveStep, fmt.Errorf("marshal spec %+v failed", spec)
@@ -91,26 +93,37 @@ func (s *ArchiveStep) Run(ctx context.Context) error {
 		if len(s.spec.S3.Subfolder) > 0 {
 			upload.DestinationPath = strings.TrimLeft(path.Join(s.spec.S3.Subfolder, upload.DestinationPath), "/")
 		}
-		info, err := os.Stat(upload.AbsFilePath)
+		if err := s.upload(client, upload.AbsFilePath, filepath.ToSlash(upload.DestinationPath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ArchiveStep) upload(client *s3.Client, filePath, destinationPath string) error {
+	// the file of a job running in a container is copied out of it first, the links in it must not be followed on the host
+	if s.container != nil {
+		tmpDir, err := os.MkdirTemp("", "zadig-archive-")
 		if err != nil {
-			return fmt.Errorf("failed to upload file path [%s] to destination [%s], the error is: %w", upload.AbsFilePath, upload.DestinationPath, err)
+			return err
 		}
-		upload.DestinationPath = filepath.ToSlash(upload.DestinationPath)
-		// if the given path is a directory
-		if info.IsDir() {
-			err := client.UploadDir(s.spec.S3.Bucket, upload.AbsFilePath, upload.DestinationPath)
-			if err != nil {
-				return err
-			}
-		} else {
-			key := path.Join(upload.DestinationPath, info.Name())
-			err := client.Upload(s.spec.S3.Bucket, upload.AbsFilePath, key)
-			if err != nil {
-				return err
-			}
+		defer os.RemoveAll(tmpDir)
+		if err := s.container.CopyOut(filePath, tmpDir); err != nil {
+			return fmt.Errorf("failed to upload file path [%s] to destination [%s], the error is: %w", filePath, destinationPath, err)
 		}
+		filePath = filepath.Join(tmpDir, filepath.Base(filePath))
 	}
-	return nil
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to upload file path [%s] to destination [%s], the error is: %w", filePath, destinationPath, err)
+	}
+	// if the given path is a directory
+	if info.IsDir() {
+		return client.UploadDir(s.spec.S3.Bucket, filePath, destinationPath)
+	}
+	key := path.Join(destinationPath, info.Name())
+	return client.Upload(s.spec.S3.Bucket, filePath, key)
 }
 
 func replaceEnvWithValue(str string, envs map[string]string) string {
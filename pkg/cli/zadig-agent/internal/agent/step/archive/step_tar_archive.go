@@ -27,6 +27,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
 	"github.com/koderover/zadig/v2/pkg/setting"
@@ -42,10 +43,11 @@ type TarArchiveStep struct {
 	workspace  string
 	logger     *log.JobLogger
 	dirs       *types.AgentWorkDirs
+	container  *container.Container
 }
 
-func NewTararchiveStep(spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, logger *log.JobLogger) (*TarArchiveStep, error) {
-	tarArchiveStep := &TarArchiveStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, logger: logger}
+func NewTararchiveStep(spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, container *container.Container, logger *log.JobLogger) (*TarArchiveStep, error) {
+	tarArchiveStep := &TarArchiveStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, container: container, logger: logger}
 	yamlBytes, err := yaml.Marshal(spec)
 	if err != nil {
 		return tarArchiveStep, fmt.Errorf("marshal spec %+v failed", spec)
@@ -99,13 +101,18 @@ func (s *TarArchiveStep) Run(ctx context.Context) error {
 		s.logger.Errorf("failed to create %s err: %s", tarName, err)
 		return err
 	}
-	err = temp.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close %s err: %s", tarName, err)
-	}
 	cmd := exec.Command("tar", cmdAndArtifactFullPaths...)
+	if s.container != nil {
+		// tar runs in the job container and writes the archive to the agent host through stdout
+		cmd = s.container.Command("", "tar", append([]string{"-czf", "-"}, cmdAndArtifactFullPaths[2:]...)...)
+		cmd.Stdout = temp
+	}
 	cmd.Stderr = os.Stderr
-	if err = cmd.Run(); err != nil {
+	err = cmd.Run()
+	if closeErr := temp.Close(); closeErr != nil {
+		return fmt.Errorf("failed to close %s err: %s", tarName, closeErr)
+	}
+	if err != nil {
 		s.logger.Errorf("failed to compress %s err:%s", tarName, err)
 		return err
 	}
@@ -34,6 +34,7 @@ import (
 
 	gitcmd "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/git"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common"
 	agenttypes "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
@@ -47,11 +48,12 @@ type GitStep struct {
 	envs       []string
 	secretEnvs []string
 	dirs       *agenttypes.AgentWorkDirs
+	container  *container.Container
 	Logger     *log.JobLogger
 }
 
-func NewGitStep(spec interface{}, dirs *agenttypes.AgentWorkDirs, envs, secretEnvs []string, logger *log.JobLogger) (*GitStep, error) {
-	gitStep := &GitStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, Logger: logger}
+func NewGitStep(spec interface{}, dirs *agenttypes.AgentWorkDirs, envs, secretEnvs []string, container *container.Container, logger *log.JobLogger) (*GitStep, error) {
+	gitStep := &GitStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, container: container, Logger: logger}
 	yamlBytes, err := yaml.Marshal(spec)
 	if err != nil {
 		return gitStep, fmt.Errorf("marshal spec %+v failed", spec)
@@ -81,12 +83,22 @@ func (s *GitStep) runGitCmds() error {
 			continue
 		}
 
-		cmds = append(cmds, s.buildGitCommands(repo, hostNames)...)
+		gitCmds, err := s.buildGitCommands(repo, hostNames)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, gitCmds...)
 	}
 
 	// https://stackoverflow.com/questions/24952683/git-push-error-rpc-failed-result-56-http-code-200-fatal-the-remote-end-hun/36843260
 	//cmds = append(cmds, &command.Command{Cmd: gitcmd.SetConfig("http.postBuffer", "524288000"), DisableTrace: true})
 	for _, c := range cmds {
+		// the git commands run in the job container too, only the ssh keys are written by the agent
+		if s.container != nil {
+			c.Cmd = s.container.Wrap(c.Cmd)
+		} else {
+			c.Cmd.Env = s.envs
+		}
 		cmdOutReader, err := c.Cmd.StdoutPipe()
 		if err != nil {
 			return err
@@ -96,7 +108,6 @@ func (s *GitStep) runGitCmds() error {
 			return err
 		}
 
-		c.Cmd.Env = s.envs
 		if !c.DisableTrace {
 			s.Logger.Printf("%s\n", strings.Join(c.Cmd.Args, " "))
 		}
@@ -134,12 +145,12 @@ func (s *GitStep) runGitCmds() error {
 	return nil
 }
 
-func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String) []*common.Command {
+func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String) ([]*common.Command, error) {
 
 	cmds := make([]*common.Command, 0)
 
 	if len(repo.RepoName) == 0 {
-		return cmds
+		return cmds, nil
 	}
 
 	workDir := filepath.Join(s.dirs.Workspace, repo.RepoName)
@@ -205,11 +216,20 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 		})
 	} else if repo.Source == types.ProviderGitee || repo.Source == types.ProviderGiteeEE {
 		cmds = append(cmds, &common.Command{Cmd: gitcmd.RemoteAdd(repo.RemoteName, HTTPSCloneURL(repo.Source, repo.OauthToken, repo.RepoOwner, repo.RepoName, repo.Address)), DisableTrace: true})
+	} else if repo.Source == types.ProviderBitbucket || repo.Source == types.ProviderBitbucketServer || repo.Source == types.ProviderGitea {
+		cloneURL, err := types.BasicAuthCloneURL(repo.Source, repo.Address, repo.Username, repo.OauthToken, owner, repo.RepoName)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, &common.Command{
+			Cmd:          gitcmd.RemoteAdd(repo.RemoteName, cloneURL),
+			DisableTrace: true,
+		})
 	} else if repo.Source == types.ProviderOther {
 		if repo.AuthType == types.SSHAuthType {
 			host := getHost(repo.Address)
 			if !hostNames.Has(host) {
-				if err := writeSSHFile(repo.SSHKey, host); err != nil {
+				if err := writeSSHFile(s.home(), repo.SSHKey, host); err != nil {
 					s.Logger.Errorf("failed to write ssh file %s: %s", repo.SSHKey, err)
 				}
 				hostNames.Insert(host)
@@ -242,7 +262,7 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 
 	ref := repo.Ref()
 	if ref == "" {
-		return cmds
+		return cmds, nil
 	}
 
 	cmds = append(cmds, &common.Command{Cmd: gitcmd.Fetch(repo.RemoteName, ref)}, &common.Command{Cmd: gitcmd.CheckoutHead()})
@@ -273,10 +293,18 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 
 	helper.SetCmdsWorkDir(workDir, cmds)
 
-	return cmds
+	return cmds, nil
+}
+
+// home is the home directory the git commands run with
+func (s *GitStep) home() string {
+	if s.container != nil {
+		return s.container.Home
+	}
+	return config.Home()
 }
 
-func writeSSHFile(sshKey, hostName string) error {
+func writeSSHFile(home, sshKey, hostName string) error {
 	if sshKey == "" {
 		return fmt.Errorf("ssh cannot be empty")
 	}
@@ -287,12 +315,15 @@ func writeSSHFile(sshKey, hostName string) error {
 
 	hostName = strings.Replace(hostName, ".", "", -1)
 	hostName = strings.Replace(hostName, ":", "", -1)
+	if err := os.MkdirAll(path.Join(home, ".ssh"), 0700); err != nil {
+		return err
+	}
 	pathName := fmt.Sprintf("/.ssh/id_rsa.%s", hostName)
-	file := path.Join(config.Home(), pathName)
+	file := path.Join(home, pathName)
 	return ioutil.WriteFile(file, []byte(sshKey), 0400)
 }
 
-func writeSSHConfigFile(hostNames sets.String, proxy *step.Proxy) error {
+func writeSSHConfigFile(home string, hostNames sets.String, proxy *step.Proxy) error {
 	out := "\nHOST *\nStrictHostKeyChecking=no\nUserKnownHostsFile=/dev/null\n"
 	for _, hostName := range hostNames.List() {
 		name := strings.Replace(hostName, ".", "", -1)
@@ -302,7 +333,7 @@ func writeSSHConfigFile(hostNames sets.String, proxy *step.Proxy) error {
 			out = out + fmt.Sprintf("ProxyCommand nc -x %s %%h %%p\n", proxy.GetProxyURL())
 		}
 	}
-	file := path.Join(config.Home(), "/.ssh/config")
+	file := path.Join(home, "/.ssh/config")
 	return ioutil.WriteFile(file, []byte(out), 0600)
 }
 
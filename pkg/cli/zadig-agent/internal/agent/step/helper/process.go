@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helper
+
+import (
+	"context"
+	"os/exec"
+
+	"go.uber.org/zap"
+
+	osutil "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/util/os"
+)
+
+// KillOnCancel kills the started command together with the processes it started when ctx is done, which happens
+// when the job is cancelled. The returned func must be called once the command exits.
+func KillOnCancel(ctx context.Context, cmd *exec.Cmd, logger *zap.SugaredLogger) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := osutil.KillProcessGroup(cmd); err != nil {
+				logger.Errorf("failed to kill process %d, error: %s", cmd.Process.Pid, err)
+			}
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -32,6 +32,7 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
+	osutil "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/util/os"
 )
 
 type BatchFileStep struct {
@@ -75,6 +76,7 @@ func (s *BatchFileStep) Run(ctx context.Context) error {
 		return fmt.Errorf("generate script failed: %v", err)
 	}
 	cmd := exec.Command(userScriptFile)
+	osutil.SetProcessGroup(cmd)
 	cmd.Dir = s.dirs.Workspace
 	cmd.Env = s.envs
 
@@ -112,6 +114,9 @@ func (s *BatchFileStep) Run(ctx context.Context) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	// kill the script and the processes it started when the job is cancelled
+	stop := helper.KillOnCancel(ctx, cmd, log.GetSimpleLogger())
+	defer stop()
 
 	wg.Wait()
 
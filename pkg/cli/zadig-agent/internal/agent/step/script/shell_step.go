@@ -33,7 +33,9 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/helper"
+	osutil "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/util/os"
 )
 
 type ShellStep struct {
@@ -42,6 +44,7 @@ type ShellStep struct {
 	envs       []string
 	secretEnvs []string
 	dirs       *types.AgentWorkDirs
+	container  *container.Container
 	Logger     *log.JobLogger
 }
 
@@ -51,8 +54,8 @@ type StepShellSpec struct {
 	SkipPrepare bool     `json:"skip_prepare"                            yaml:"skip_prepare"`
 }
 
-func NewShellStep(jobOutput []string, spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, logger *log.JobLogger) (*ShellStep, error) {
-	shellStep := &ShellStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, JobOutput: jobOutput}
+func NewShellStep(jobOutput []string, spec interface{}, dirs *types.AgentWorkDirs, envs, secretEnvs []string, container *container.Container, logger *log.JobLogger) (*ShellStep, error) {
+	shellStep := &ShellStep{dirs: dirs, envs: envs, secretEnvs: secretEnvs, JobOutput: jobOutput, container: container}
 	yamlBytes, err := yaml.Marshal(spec)
 	if err != nil {
 		return shellStep, fmt.Errorf("marshal spec %+v failed", spec)
@@ -76,9 +79,15 @@ func (s *ShellStep) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("generate script failed: %v", err)
 	}
-	cmd := exec.Command("bash", userScriptFile)
-	cmd.Dir = s.dirs.Workspace
-	cmd.Env = s.envs
+	var cmd *exec.Cmd
+	if s.container != nil {
+		cmd = s.container.Command(s.dirs.Workspace, "bash", userScriptFile)
+	} else {
+		cmd = exec.Command("bash", userScriptFile)
+		cmd.Dir = s.dirs.Workspace
+		cmd.Env = s.envs
+	}
+	osutil.SetProcessGroup(cmd)
 
 	fileName := s.Logger.GetLogfilePath()
 
@@ -114,6 +123,9 @@ func (s *ShellStep) Run(ctx context.Context) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	// kill the script and the processes it started when the job is cancelled
+	stop := helper.KillOnCancel(ctx, cmd, log.GetSimpleLogger())
+	defer stop()
 
 	wg.Wait()
 
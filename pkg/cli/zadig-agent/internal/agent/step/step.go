@@ -18,8 +18,10 @@ package step
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/container"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/archive"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/docker"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent/step/git"
@@ -27,6 +29,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	jobctl "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	sharedstep "github.com/koderover/zadig/v2/pkg/shared/step"
 )
 
 type StepInfos struct {
@@ -36,56 +39,101 @@ type StepInfos struct {
 	Envs      []string
 }
 
-type Step interface {
-	Run(ctx context.Context) error
+type Step = sharedstep.Step
+
+// Runtime is the agent specific runtime of the steps
+type Runtime struct {
+	Outputs []string
+	Dirs    *types.AgentWorkDirs
+	Logger  *log.JobLogger
+
+	// Container is set when the job runs in a container, the shell, git and archive steps run their commands in it.
+	// The batch file and docker build steps are not isolated, the batch file step only runs on windows where jobs can't
+	// run in containers and the docker build step uses the docker daemon of the host anyway.
+	Container *container.Container
 }
 
-func RunStep(ctx context.Context, jobCtx *jobctl.JobContext, step *commonmodels.StepTask, dirs *types.AgentWorkDirs, envs, secretEnvs []string, logger *log.JobLogger) error {
-	var stepInstance Step
-	var err error
+// registry is the steps zadig-agent can run, the steps shared with the job executor are registered in the shared step package
+var registry = sharedstep.NewRegistry()
 
-	switch step.StepType {
-	case "batch_file":
-		stepInstance, err = script.NewBatchFileStep(jobCtx.Outputs, step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
-		}
-	case "shell":
-		stepInstance, err = script.NewShellStep(jobCtx.Outputs, step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
-		}
-	case "git":
-		stepInstance, err = git.NewGitStep(step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
-		}
-	case "docker_build":
-		stepInstance, err = docker.NewDockerBuildStep(step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
-		}
-	case "archive":
-		stepInstance, err = archive.NewArchiveStep(step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
-		}
-	case "tar_archive":
-		stepInstance, err = archive.NewTararchiveStep(step.Spec, dirs, envs, secretEnvs, logger)
-		if err != nil {
-			return err
+func init() {
+	registerStep("batch_file", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(script.NewBatchFileStep(r.Outputs, spec, r.Dirs, c.Envs, c.SecretEnvs, r.Logger))
+	})
+	registerStep("shell", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(script.NewShellStep(r.Outputs, spec, r.Dirs, c.Envs, c.SecretEnvs, r.Container, r.Logger))
+	})
+	registerStep("git", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(git.NewGitStep(spec, r.Dirs, c.Envs, c.SecretEnvs, r.Container, r.Logger))
+	})
+	registerStep("docker_build", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(docker.NewDockerBuildStep(spec, r.Dirs, c.Envs, c.SecretEnvs, r.Logger))
+	})
+	registerStep("archive", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(archive.NewArchiveStep(spec, r.Dirs, c.Envs, c.SecretEnvs, r.Container, r.Logger))
+	})
+	registerStep("tar_archive", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		r := c.Runtime.(*Runtime)
+		return newStep(archive.NewTararchiveStep(spec, r.Dirs, c.Envs, c.SecretEnvs, r.Container, r.Logger))
+	})
+	// tools, debug and report steps are not supported by zadig-agent, they are skipped
+	for _, stepType := range []string{"tools", "debug_before", "debug_after", "junit_report", "sonar_check"} {
+		registerStep(stepType, func(spec interface{}, c *sharedstep.Context) (Step, error) {
+			return skipStep{}, nil
+		})
+	}
+}
+
+func registerStep(stepType string, factory sharedstep.Factory) {
+	registry.Register(&sharedstep.Definition{
+		Type: stepType,
+		New:  factory,
+	})
+}
+
+func newStep[T Step](step T, err error) (Step, error) {
+	if err != nil {
+		return nil, err
+	}
+	return step, nil
+}
+
+type skipStep struct{}
+
+func (skipStep) Run(ctx context.Context) error {
+	return nil
+}
+
+// ValidateSteps validates the step specs before the job starts, unknown steps are rejected
+func ValidateSteps(steps []*commonmodels.StepTask) error {
+	for _, step := range steps {
+		if err := registry.Validate(string(step.StepType), step.Spec); err != nil {
+			return fmt.Errorf("step %s: %v", step.Name, err)
 		}
-	case "tools":
-		return nil
-	case "debug_before":
-		return nil
-	case "debug_after":
-		return nil
-	default:
-		//err := fmt.Errorf("step type: %s does not match any known type", step.StepType)
-		//log.Error(err)
-		//return err
-		logger.Infof("step type: %s does not match any known type", step.StepType)
+	}
+	return nil
+}
+
+func RunStep(ctx context.Context, jobCtx *jobctl.JobContext, step *commonmodels.StepTask, dirs *types.AgentWorkDirs, envs, secretEnvs []string, container *container.Container, logger *log.JobLogger) error {
+	stepInstance, err := registry.New(string(step.StepType), step.Spec, &sharedstep.Context{
+		Workspace:  dirs.Workspace,
+		Envs:       envs,
+		SecretEnvs: secretEnvs,
+		Logger:     logger,
+		Runtime: &Runtime{
+			Outputs:   jobCtx.Outputs,
+			Dirs:      dirs,
+			Logger:    logger,
+			Container: container,
+		},
+	})
+	if err != nil {
+		return err
 	}
 	if err := stepInstance.Run(ctx); err != nil {
 		return err
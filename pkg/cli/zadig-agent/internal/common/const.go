@@ -76,6 +76,9 @@ const (
 	DefaultAgentPollingInterval      = 3
 	DefaultJobReportInterval         = 1
 	DefaultJobLogReadNum             = 100
+
+	// DefaultJobLogStreamInterval is in milliseconds, logs are streamed at this interval while the agent channel is connected
+	DefaultJobLogStreamInterval = 200
 )
 
 const (
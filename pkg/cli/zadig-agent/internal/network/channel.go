@@ -0,0 +1,290 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
+	"github.com/koderover/zadig/v2/pkg/tool/wsconn"
+)
+
+const (
+	channelMinBackoff = 3 * time.Second
+	channelMaxBackoff = time.Minute
+	reportAckTimeout  = 10 * time.Second
+)
+
+type agentReadyArgs struct {
+	Free int `json:"free"`
+}
+
+type agentLogArgs struct {
+	Log string `json:"log"`
+}
+
+type agentReportAck struct {
+	Resp  *types.ReportAgentJobResp `json:"resp"`
+	Error string                    `json:"error"`
+}
+
+// AgentChannel is the websocket channel between the agent and zadig server. While it is connected, jobs are
+// dispatched and cancelled over it and logs are streamed over it, otherwise the agent falls back to http polling.
+type AgentChannel struct {
+	AgentConfig *AgentConfig
+	// Free returns the number of jobs the agent can accept now
+	Free func() int
+	// OnDispatch and OnCancel are called from the reader goroutine of the channel
+	OnDispatch func(job *types.ZadigJobTask)
+	OnCancel   func(jobID string)
+
+	mu   sync.RWMutex
+	conn *wsconn.AgentConn
+
+	ackMu  sync.Mutex
+	acks   map[string]chan *agentReportAck
+	ackSeq uint64
+}
+
+func NewAgentChannel(config *AgentConfig) *AgentChannel {
+	return &AgentChannel{
+		AgentConfig: config,
+		acks:        make(map[string]chan *agentReportAck),
+	}
+}
+
+// Run keeps the channel connected until ctx is done, reconnecting with backoff when it drops.
+func (c *AgentChannel) Run(ctx context.Context) {
+	backoff := channelMinBackoff
+	for {
+		start := time.Now()
+		err := c.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("agent channel to zadig server is not available, fall back to polling, error: %s", err)
+
+		if time.Since(start) > channelMaxBackoff {
+			backoff = channelMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > channelMaxBackoff {
+			backoff = channelMaxBackoff
+		}
+	}
+}
+
+func (c *AgentChannel) Connected() bool {
+	if c == nil {
+		return false
+	}
+	return c.getConn() != nil
+}
+
+// NotifyReady tells zadig server how many jobs the agent can accept now.
+func (c *AgentChannel) NotifyReady() {
+	conn := c.getConn()
+	if conn == nil || c.Free == nil {
+		return
+	}
+	if err := conn.Send(wsconn.AgentMsgReady, "", "", &agentReadyArgs{Free: c.Free()}); err != nil {
+		log.Warnf("failed to send ready to zadig server, error: %s", err)
+	}
+}
+
+func (c *AgentChannel) SendLog(jobID, logStr string) error {
+	conn := c.getConn()
+	if conn == nil {
+		return fmt.Errorf("agent channel is not connected")
+	}
+	return conn.Send(wsconn.AgentMsgLog, "", jobID, &agentLogArgs{Log: logStr})
+}
+
+// Report sends a job report over the channel and waits for zadig server to ack it.
+func (c *AgentChannel) Report(request *ReportJobRequest) (*types.ReportAgentJobResp, error) {
+	conn := c.getConn()
+	if conn == nil {
+		return nil, fmt.Errorf("agent channel is not connected")
+	}
+
+	ackCh := make(chan *agentReportAck, 1)
+	c.ackMu.Lock()
+	c.ackSeq++
+	id := strconv.FormatUint(c.ackSeq, 10)
+	c.acks[id] = ackCh
+	c.ackMu.Unlock()
+	defer func() {
+		c.ackMu.Lock()
+		delete(c.acks, id)
+		c.ackMu.Unlock()
+	}()
+
+	if err := conn.Send(wsconn.AgentMsgReport, id, request.JobID, request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case ack, ok := <-ackCh:
+		if !ok {
+			return nil, fmt.Errorf("agent channel dropped before report of job %s was acked", request.JobID)
+		}
+		if ack.Error != "" {
+			return nil, fmt.Errorf("%s", ack.Error)
+		}
+		if ack.Resp == nil {
+			return &types.ReportAgentJobResp{}, nil
+		}
+		return ack.Resp, nil
+	case <-time.After(reportAckTimeout):
+		return nil, fmt.Errorf("report of job %s was not acked in %s", request.JobID, reportAckTimeout)
+	}
+}
+
+func (c *AgentChannel) serve(ctx context.Context) error {
+	ws, err := c.dial()
+	if err != nil {
+		return err
+	}
+	conn := wsconn.NewAgentConn(ws)
+
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-serveCtx.Done()
+		conn.Close()
+	}()
+
+	c.setConn(conn)
+	defer c.dropConn()
+
+	log.Infof("agent channel to zadig server connected.")
+	c.NotifyReady()
+	go c.heartbeat(serveCtx, conn)
+
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case wsconn.AgentMsgHeartbeat:
+		case wsconn.AgentMsgDispatch:
+			job := new(types.ZadigJobTask)
+			if err := json.Unmarshal(msg.Data, job); err != nil {
+				log.Errorf("failed to unmarshal job %s dispatched by zadig server, error: %s", msg.JobID, err)
+				continue
+			}
+			if c.OnDispatch != nil {
+				c.OnDispatch(job)
+			}
+		case wsconn.AgentMsgCancel:
+			if c.OnCancel != nil {
+				c.OnCancel(msg.JobID)
+			}
+		case wsconn.AgentMsgReportAck:
+			ack := new(agentReportAck)
+			if err := json.Unmarshal(msg.Data, ack); err != nil {
+				ack.Error = fmt.Sprintf("failed to unmarshal report ack, error: %s", err)
+			}
+			c.ackMu.Lock()
+			if ackCh, ok := c.acks[msg.ID]; ok {
+				ackCh <- ack
+				delete(c.acks, msg.ID)
+			}
+			c.ackMu.Unlock()
+		default:
+			log.Warnf("unknown message type %s from zadig server", msg.Type)
+		}
+	}
+}
+
+// heartbeat keeps the channel alive and refreshes the free job slots, which change with the agent status and config.
+func (c *AgentChannel) heartbeat(ctx context.Context, conn *wsconn.AgentConn) {
+	ticker := time.NewTicker(wsconn.AgentHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.Send(wsconn.AgentMsgHeartbeat, "", "", nil); err != nil {
+				log.Warnf("failed to send heartbeat over agent channel, error: %s", err)
+				conn.Close()
+				return
+			}
+			c.NotifyReady()
+		}
+	}
+}
+
+func (c *AgentChannel) dial() (*websocket.Conn, error) {
+	u, err := url.Parse(GetFullURL(c.AgentConfig.URL, ConnectBaseUrl))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zadig server url %s, error: %s", c.AgentConfig.URL, err)
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	query := u.Query()
+	query.Set("token", c.AgentConfig.Token)
+	u.RawQuery = query.Encode()
+
+	ws, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	return ws, err
+}
+
+func (c *AgentChannel) getConn() *wsconn.AgentConn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn
+}
+
+func (c *AgentChannel) setConn(conn *wsconn.AgentConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn = conn
+}
+
+// dropConn marks the channel disconnected and fails the reports still waiting for an ack.
+func (c *AgentChannel) dropConn() {
+	c.setConn(nil)
+
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+	for id, ackCh := range c.acks {
+		close(ackCh)
+		delete(c.acks, id)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
+	"github.com/koderover/zadig/v2/pkg/tool/wsconn"
+)
+
+const testAgentToken = "test-token"
+
+// fakeServer accepts agent channels like aslan does and hands every accepted connection to the test
+type fakeServer struct {
+	*httptest.Server
+	conns   chan *wsconn.AgentConn
+	dialed  int32
+	refused int32
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{conns: make(chan *wsconn.AgentConn, 10)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ConnectBaseUrl, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.dialed, 1)
+		if r.URL.Query().Get("token") != testAgentToken || atomic.LoadInt32(&s.refused) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade: %s", err)
+			return
+		}
+		s.conns <- wsconn.NewAgentConn(ws)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// accept waits for the next agent connection and its ready message
+func (s *fakeServer) accept(t *testing.T) *wsconn.AgentConn {
+	select {
+	case conn := <-s.conns:
+		t.Cleanup(func() { conn.Close() })
+		msg, err := conn.Receive()
+		require.NoError(t, err)
+		require.Equal(t, wsconn.AgentMsgReady, msg.Type)
+		return conn
+	case <-time.After(channelMinBackoff + 5*time.Second):
+		t.Fatal("agent did not connect")
+		return nil
+	}
+}
+
+func runChannel(t *testing.T, server *fakeServer) *AgentChannel {
+	channel := NewAgentChannel(&AgentConfig{URL: server.URL, Token: testAgentToken})
+	channel.Free = func() int { return 2 }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		channel.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return channel
+}
+
+func waitConnected(t *testing.T, channel *AgentChannel, connected bool) {
+	assert.Eventually(t, func() bool { return channel.Connected() == connected }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAgentChannelDispatchAndCancel(t *testing.T) {
+	server := newFakeServer(t)
+	channel := NewAgentChannel(&AgentConfig{URL: server.URL, Token: testAgentToken})
+	channel.Free = func() int { return 1 }
+	dispatched := make(chan string, 1)
+	cancelled := make(chan string, 1)
+	channel.OnDispatch = func(job *types.ZadigJobTask) { dispatched <- job.ID }
+	channel.OnCancel = func(jobID string) { cancelled <- jobID }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go channel.Run(ctx)
+
+	conn := server.accept(t)
+	waitConnected(t, channel, true)
+
+	require.NoError(t, conn.Send(wsconn.AgentMsgDispatch, "", "job-1", &types.ZadigJobTask{ID: "job-1"}))
+	require.NoError(t, conn.Send(wsconn.AgentMsgCancel, "", "job-1", nil))
+	assert.Equal(t, "job-1", <-dispatched)
+	assert.Equal(t, "job-1", <-cancelled)
+}
+
+func TestAgentChannelReportAck(t *testing.T) {
+	server := newFakeServer(t)
+	channel := runChannel(t, server)
+	conn := server.accept(t)
+	waitConnected(t, channel, true)
+
+	go func() {
+		msg, err := conn.Receive()
+		if err != nil || msg.Type != wsconn.AgentMsgReport {
+			return
+		}
+		conn.Send(wsconn.AgentMsgReportAck, msg.ID, msg.JobID, &agentReportAck{
+			Resp: &types.ReportAgentJobResp{JobID: msg.JobID, JobStatus: "cancelled"},
+		})
+	}()
+
+	resp, err := channel.Report(&ReportJobRequest{JobID: "job-1", JobStatus: "running"})
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", resp.JobID)
+	assert.Equal(t, "cancelled", resp.JobStatus)
+}
+
+func TestAgentChannelReportAckError(t *testing.T) {
+	server := newFakeServer(t)
+	channel := runChannel(t, server)
+	conn := server.accept(t)
+	waitConnected(t, channel, true)
+
+	go func() {
+		msg, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		conn.Send(wsconn.AgentMsgReportAck, msg.ID, msg.JobID, &agentReportAck{Error: "job not found"})
+	}()
+
+	_, err := channel.Report(&ReportJobRequest{JobID: "job-1"})
+	assert.EqualError(t, err, "job not found")
+}
+
+func TestAgentChannelFallbackWhenDropped(t *testing.T) {
+	server := newFakeServer(t)
+	channel := runChannel(t, server)
+	conn := server.accept(t)
+	waitConnected(t, channel, true)
+
+	// the server drops the channel while a report is waiting for its ack
+	reportErr := make(chan error, 1)
+	go func() {
+		_, err := channel.Report(&ReportJobRequest{JobID: "job-1"})
+		reportErr <- err
+	}()
+	msg, err := conn.Receive()
+	require.NoError(t, err)
+	require.Equal(t, wsconn.AgentMsgReport, msg.Type)
+	atomic.StoreInt32(&server.refused, 1)
+	conn.Close()
+
+	select {
+	case err := <-reportErr:
+		assert.Error(t, err)
+	case <-time.After(reportAckTimeout):
+		t.Fatal("pending report was not failed when the channel dropped")
+	}
+	waitConnected(t, channel, false)
+
+	// the caller falls back to http while the channel is down
+	assert.Error(t, channel.SendLog("job-1", "log"))
+	_, err = channel.Report(&ReportJobRequest{JobID: "job-1"})
+	assert.Error(t, err)
+}
+
+func TestAgentChannelReconnect(t *testing.T) {
+	server := newFakeServer(t)
+	channel := runChannel(t, server)
+	conn := server.accept(t)
+	waitConnected(t, channel, true)
+
+	conn.Close()
+	waitConnected(t, channel, false)
+
+	// the channel is dialed again after the backoff
+	server.accept(t)
+	waitConnected(t, channel, true)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.dialed))
+}
+
+func TestAgentChannelNotConnected(t *testing.T) {
+	var channel *AgentChannel
+	assert.False(t, channel.Connected())
+
+	channel = NewAgentChannel(&AgentConfig{URL: "http://127.0.0.1:0", Token: testAgentToken})
+	assert.False(t, channel.Connected())
+	assert.Error(t, channel.SendLog("job-1", "log"))
+	_, err := channel.Report(&ReportJobRequest{JobID: "job-1"})
+	assert.Error(t, err)
+}
+
+func TestAgentReportAckJSON(t *testing.T) {
+	raw, err := json.Marshal(&agentReportAck{Resp: &types.ReportAgentJobResp{JobID: "job-1"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"resp":{"job_id":"job-1","job_status":""},"error":""}`, string(raw))
+}
@@ -29,6 +29,7 @@ const (
 	heartbeatBaseUrl  = "/api/aslan/vm/agents/heartbeat"
 	RequestJobBaseUrl = "/api/aslan/vm/agents/job/request"
 	ReportJobBaseUrl  = "/api/aslan/vm/agents/job/report"
+	ConnectBaseUrl    = "/api/aslan/vm/agents/connect"
 )
 
 type RegisterAgentParameters struct {
@@ -141,6 +142,11 @@ type HeartbeatParameters struct {
 	DiskSpace     uint64 `json:"disk_space"`
 	FreeDiskSpace uint64 `json:"free_disk_space"`
 	Hostname      string `json:"hostname"`
+
+	AgentVersion string `json:"agent_version"`
+	// UpgradeVersion and UpgradeError are set when the last upgrade failed and was rolled back
+	UpgradeVersion string `json:"upgrade_version"`
+	UpgradeError   string `json:"upgrade_error"`
 }
 
 type HeartbeatServerRequest struct {
@@ -160,6 +166,9 @@ type HeartbeatServerResponse struct {
 	VmName                 string `json:"vm_name"`
 	Description            string `json:"description"`
 	ZadigVersion           string `json:"zadig_version"`
+
+	AgentDownloadURL string `json:"agent_download_url"`
+	AgentChecksum    string `json:"agent_checksum"`
 }
 
 func Heartbeat(config *AgentConfig, parameters *HeartbeatParameters) (*HeartbeatServerResponse, error) {
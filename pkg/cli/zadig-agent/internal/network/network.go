@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common/types"
 	httpclient "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/util/client"
 )
@@ -36,14 +37,18 @@ func GetFullURL(host, base string) string {
 type ZadigClient struct {
 	AgentConfig *AgentConfig
 	Config      *config.AgentConfig
+
+	Channel *AgentChannel
 }
 
 func NewZadigClient() *ZadigClient {
+	agentConfig := &AgentConfig{
+		Token: config.GetAgentToken(),
+		URL:   config.GetServerURL(),
+	}
 	return &ZadigClient{
-		AgentConfig: &AgentConfig{
-			Token: config.GetAgentToken(),
-			URL:   config.GetServerURL(),
-		},
+		AgentConfig: agentConfig,
+		Channel:     NewAgentChannel(agentConfig),
 	}
 }
 
@@ -88,8 +93,16 @@ func (c *ZadigClient) ReportJob(parameters *types.ReportJobParameters) (*types.R
 		request.JobOutput = parameters.JobOutput
 		request.Seq = parameters.Seq
 	}
-	resp := new(types.ReportAgentJobResp)
 
+	if c.Channel != nil && c.Channel.Connected() {
+		resp, err := c.Channel.Report(request)
+		if err == nil {
+			return resp, nil
+		}
+		log.Warnf("failed to report job %s over agent channel, fall back to http, error: %s", request.JobID, err)
+	}
+
+	resp := new(types.ReportAgentJobResp)
 	body, err := httpclient.Post(GetFullURL(c.AgentConfig.URL, ReportJobBaseUrl), httpclient.SetBody(request), httpclient.SetResult(resp))
 	if err != nil {
 		return nil, err
@@ -74,7 +74,9 @@ func (h *HeartbeatService) Start(ctx context.Context) {
 				if h.Interval > h.MaxInterval {
 					h.Interval = h.MaxInterval
 				}
-				agentconfig.SetAgentStatus(common.AGENT_STATUS_ABNORMAL)
+				if agentconfig.GetAgentStatus() != common.AGENT_STATUS_UPDATING {
+					agentconfig.SetAgentStatus(common.AGENT_STATUS_ABNORMAL)
+				}
 			}
 			// execute heartbeat detection logic
 			util.Go(func() {
@@ -89,8 +91,8 @@ func (h *HeartbeatService) Start(ctx context.Context) {
 			h.Interval = h.minInterval
 			ticker.Reset(time.Duration(h.Interval) * time.Second)
 
-			// update agent status
-			if agentconfig.GetAgentStatus() != common.AGENT_STATUS_RUNNING {
+			// update agent status, the agent keeps updating until the upgrade is done or rolled back
+			if status := agentconfig.GetAgentStatus(); status != common.AGENT_STATUS_RUNNING && status != common.AGENT_STATUS_UPDATING {
 				agentconfig.SetAgentStatus(common.AGENT_STATUS_RUNNING)
 			}
 		case <-h.StopAgentChan:
@@ -116,6 +118,8 @@ func Heartbeat(agentCtl *agent.AgentController, errChan chan error, successChan
 	if err != nil {
 		panic(fmt.Errorf("failed to convert platform parameters to register agent parameters: %v", err))
 	}
+	params.AgentVersion = agentconfig.BuildAgentVersion
+	params.UpgradeVersion, params.UpgradeError = updater.FailedUpgrade()
 
 	config := &network.AgentConfig{
 		Token: agentconfig.GetAgentToken(),
@@ -127,13 +131,20 @@ func Heartbeat(agentCtl *agent.AgentController, errChan chan error, successChan
 		errChan <- err
 		return
 	}
+	// the agent works with the server, the backup of the last upgrade can be dropped
+	updater.ConfirmUpgrade()
+
 	if resp.NeedUpdateAgentVersion {
-		err = updater.UpdateAgent(agentCtl, resp.AgentVersion)
-		if err != nil {
-			log.Errorf("failed to update agent: %v", err)
-			errChan <- err
-			return
+		upgrade := &updater.Upgrade{
+			Version:  resp.AgentVersion,
+			URL:      resp.AgentDownloadURL,
+			Checksum: resp.AgentChecksum,
 		}
+		util.Go(func() {
+			if err := updater.UpdateAgent(agentCtl, upgrade); err != nil {
+				log.Errorf("failed to update agent: %v", err)
+			}
+		})
 	}
 
 	if resp.NeedOffline {
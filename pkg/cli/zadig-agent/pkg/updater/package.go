@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updater
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const downloadTimeout = 10 * time.Minute
+
+// downloadAgent downloads the agent package of the upgrade, verifies its checksum and extracts the agent binary to path.
+// The checksum must come from zadig server, a checksum downloaded from where the package is would not prove anything.
+func downloadAgent(upgrade *Upgrade, path string) error {
+	if upgrade.URL == "" {
+		return fmt.Errorf("download url of agent version %s is empty", upgrade.Version)
+	}
+	checksum := upgrade.Checksum
+	if checksum == "" {
+		return fmt.Errorf("checksum of agent version %s is not pinned", upgrade.Version)
+	}
+
+	pkg, err := os.CreateTemp("", "zadig-agent-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create agent package file: %v", err)
+	}
+	defer os.Remove(pkg.Name())
+	defer pkg.Close()
+
+	client := &http.Client{Timeout: downloadTimeout}
+	resp, err := client.Get(upgrade.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download agent version %s: %v", upgrade.Version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download agent version %s: unexpected status %s", upgrade.Version, resp.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(pkg, hash), resp.Body); err != nil {
+		return fmt.Errorf("failed to download agent version %s: %v", upgrade.Version, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, checksum) {
+		return fmt.Errorf("checksum mismatch of agent version %s, expected %s, got %s", upgrade.Version, checksum, sum)
+	}
+
+	if _, err := pkg.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read agent package: %v", err)
+	}
+	if err := extractAgent(pkg, path); err != nil {
+		return err
+	}
+	return verifyAgent(path, upgrade.Version)
+}
+
+// extractAgent extracts the agent binary in the package to path.
+func extractAgent(pkg io.Reader, path string) error {
+	gr, err := gzip.NewReader(pkg)
+	if err != nil {
+		return fmt.Errorf("failed to read agent package: %v", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("agent binary not found in agent package")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read agent package: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasPrefix(filepath.Base(header.Name), "zadig-agent") {
+			continue
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create agent binary: %v", err)
+		}
+		_, err = io.Copy(file, tr)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract agent binary: %v", err)
+		}
+		return nil
+	}
+}
+
+// verifyAgent makes sure the binary runs on this host and is of the expected version.
+func verifyAgent(path, version string) error {
+	out, err := exec.Command(path, "version").Output()
+	if err != nil {
+		return fmt.Errorf("failed to run agent binary of version %s: %v", version, err)
+	}
+	if !strings.Contains(string(out), fmt.Sprintf("zadig-agent version %s", version)) {
+		return fmt.Errorf("agent binary is not of version %s: %s", version, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
+)
+
+// UpgradeRecord survives the restart of the agent during an upgrade, it tells the new agent where to roll back to and
+// tells zadig server why the last upgrade failed.
+type UpgradeRecord struct {
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	Backup      string `json:"backup"`
+	// Attempts is the times the new binary has been started without connecting to zadig server
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+func getUpgradeRecord() (*UpgradeRecord, error) {
+	path, err := config.GetUpgradeRecordFilePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upgrade record file path: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upgrade record: %v", err)
+	}
+
+	record := &UpgradeRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal upgrade record: %v", err)
+	}
+	return record, nil
+}
+
+func saveUpgradeRecord(record *UpgradeRecord) error {
+	path, err := config.GetUpgradeRecordFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to get upgrade record file path: %v", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade record: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upgrade record: %v", err)
+	}
+	return nil
+}
+
+func removeUpgradeRecord() error {
+	path, err := config.GetUpgradeRecordFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to get upgrade record file path: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upgrade record: %v", err)
+	}
+	return nil
+}
@@ -18,42 +18,258 @@ package updater
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/helper/log"
 	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/agent"
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/internal/common"
+	osutil "github.com/koderover/zadig/v2/pkg/cli/zadig-agent/util/os"
 )
 
-func UpdateAgent(agentCtl *agent.AgentController, version string) error {
-	// stop agent polling job from zadig
-	agentCtl.StopPollingJob()
+// upgrading makes sure only one upgrade runs at a time, the heartbeats keep asking for it while it runs
+var upgrading int32
 
-	for {
-		// check agent current job number
-		if agentCtl.CurrentJobNum == 0 {
-			break
+// executable and reexec are replaced in tests, which must not replace the test binary
+var (
+	executable = agentExecutable
+	reexec     = osutil.Reexec
+)
+
+const drainInterval = time.Second
+
+// upgradeConfirmTimeout is the time the new binary has to work with zadig server before it is rolled back,
+// it is replaced in tests
+var upgradeConfirmTimeout = 5 * time.Minute
+
+var (
+	// upgradeMu makes sure an upgrade is not confirmed and rolled back at the same time
+	upgradeMu sync.Mutex
+	// confirmTimer rolls back the upgrade if it is not confirmed in time
+	confirmTimer *time.Timer
+)
+
+type Upgrade struct {
+	Version string
+	URL     string
+	// Checksum is the sha256 of the package pinned on zadig server, packages without it are not installed
+	Checksum string
+}
+
+// UpdateAgent upgrades the agent to the version advertised by zadig server. The new binary is downloaded and verified
+// before the running jobs are drained, then it replaces the running binary and is started in place of the current process.
+// Any failure rolls back to the current binary and is reported by the next heartbeats.
+func UpdateAgent(agentCtl *agent.AgentController, upgrade *Upgrade) error {
+	if !atomic.CompareAndSwapInt32(&upgrading, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&upgrading, 0)
+
+	if err := upgradeAgent(agentCtl, upgrade); err != nil {
+		if e := saveUpgradeRecord(&UpgradeRecord{FromVersion: config.BuildAgentVersion, ToVersion: upgrade.Version, Error: err.Error()}); e != nil {
+			log.Errorf("failed to save upgrade record: %v", e)
 		}
-		time.Sleep(1 * time.Second)
+		if config.GetAgentStatus() == common.AGENT_STATUS_UPDATING {
+			config.SetAgentStatus(common.AGENT_STATUS_RUNNING)
+		}
+		return err
 	}
+	return nil
+}
 
-	// stop agent run job
-	agentCtl.StopRunJob()
+func upgradeAgent(agentCtl *agent.AgentController, upgrade *Upgrade) error {
+	log.Infof("start to upgrade agent from version %s to %s", config.BuildAgentVersion, upgrade.Version)
+	// a new attempt clears the failure of the last one
+	if err := removeUpgradeRecord(); err != nil {
+		return err
+	}
 
-	// update agent
-	// 分四个步骤：1. 配置文件更新 2. 二进制文件更新 3. 启动新的agent 4. 删除旧的agent
-	// 1. 配置文件更新
-	err := UpdateAgentConfig(version)
+	exe, err := executable()
 	if err != nil {
-		log.Errorf("failed to update agent config: %v", err)
 		return err
 	}
 
-	// 2. 二进制文件更新
+	newBinary := exe + ".new"
+	defer os.Remove(newBinary)
+	if err := downloadAgent(upgrade, newBinary); err != nil {
+		return err
+	}
+
+	// drain the running jobs, no job is polled or dispatched while the agent is updating
+	config.SetAgentStatus(common.AGENT_STATUS_UPDATING)
+	release := agentCtl.DrainJobs(drainInterval)
+	err = replaceAgent(exe, newBinary, upgrade.Version)
+	// the agent is only still running here if the upgrade failed
+	release()
+	return err
+}
+
+// replaceAgent replaces the agent binary with the new one and starts it in place of the current process, it only
+// returns if the upgrade failed and was rolled back.
+func replaceAgent(exe, newBinary, version string) error {
+	backup := exe + ".bak"
+	if err := swapBinary(exe, newBinary, backup); err != nil {
+		return err
+	}
+
+	oldConfig, err := config.GetAgentConfig()
+	if err != nil {
+		return rollbackBinary(exe, backup, fmt.Errorf("failed to get agent config: %v", err))
+	}
+	if err := UpdateAgentConfig(version); err != nil {
+		return rollbackBinary(exe, backup, err)
+	}
+	if err := saveUpgradeRecord(&UpgradeRecord{FromVersion: config.BuildAgentVersion, ToVersion: version, Backup: backup}); err != nil {
+		UpdateAgentConfig(oldConfig.AgentVersion)
+		return rollbackBinary(exe, backup, err)
+	}
+
+	log.Infof("agent binary is upgraded to version %s, restart agent.", version)
+	err = reexec(exe, os.Args)
 
+	// the new binary failed to start
+	removeUpgradeRecord()
+	UpdateAgentConfig(oldConfig.AgentVersion)
+	return rollbackBinary(exe, backup, fmt.Errorf("failed to start agent of version %s: %v", version, err))
+}
+
+func agentExecutable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get agent binary path: %v", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", fmt.Errorf("failed to get agent binary path: %v", err)
+	}
+	return exe, nil
+}
+
+// swapBinary replaces the binary with the new one, the replaced binary is kept as backup for rollback.
+func swapBinary(exe, newBinary, backup string) error {
+	if err := os.RemoveAll(backup); err != nil {
+		return fmt.Errorf("failed to remove old agent backup: %v", err)
+	}
+	if err := os.Rename(exe, backup); err != nil {
+		return fmt.Errorf("failed to backup agent binary: %v", err)
+	}
+	if err := os.Rename(newBinary, exe); err != nil {
+		return rollbackBinary(exe, backup, fmt.Errorf("failed to replace agent binary: %v", err))
+	}
 	return nil
 }
 
+func rollbackBinary(exe, backup string, cause error) error {
+	log.Errorf("failed to upgrade agent, roll back: %v", cause)
+	if err := os.Rename(backup, exe); err != nil {
+		return fmt.Errorf("%v, and failed to roll back agent binary: %v", cause, err)
+	}
+	return cause
+}
+
+// CheckUpgrade finishes the upgrade recorded by the last agent when the agent starts. If the new binary has been
+// started before without confirming the upgrade, it failed to work, so the backup binary is restored and started.
+// The first start of the new binary is rolled back the same way if it does not confirm the upgrade in time.
+func CheckUpgrade() {
+	record, err := getUpgradeRecord()
+	if err != nil {
+		log.Errorf("failed to get upgrade record: %v", err)
+		return
+	}
+	if record == nil || record.Error != "" || record.ToVersion != config.BuildAgentVersion {
+		return
+	}
+
+	if record.Attempts == 0 {
+		record.Attempts++
+		if err := saveUpgradeRecord(record); err != nil {
+			log.Errorf("failed to save upgrade record: %v", err)
+		}
+		upgradeMu.Lock()
+		confirmTimer = time.AfterFunc(upgradeConfirmTimeout, rollbackUnconfirmedUpgrade)
+		upgradeMu.Unlock()
+		return
+	}
+
+	rollbackUpgrade(record, fmt.Errorf("agent of version %s failed to connect to zadig server after upgrade", record.ToVersion))
+}
+
+// rollbackUnconfirmedUpgrade rolls back the upgrade which was not confirmed before the deadline.
+func rollbackUnconfirmedUpgrade() {
+	upgradeMu.Lock()
+	defer upgradeMu.Unlock()
+
+	record, err := getUpgradeRecord()
+	if err != nil {
+		log.Errorf("failed to get upgrade record: %v", err)
+		return
+	}
+	if record == nil || record.Error != "" || record.ToVersion != config.BuildAgentVersion {
+		return
+	}
+	rollbackUpgrade(record, fmt.Errorf("agent of version %s failed to connect to zadig server in %s after upgrade", record.ToVersion, upgradeConfirmTimeout))
+}
+
+// rollbackUpgrade restores the backup binary of the upgrade and starts it in place of the current process.
+func rollbackUpgrade(record *UpgradeRecord, cause error) {
+	exe, err := executable()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if err := rollbackBinary(exe, record.Backup, cause); err != cause {
+		log.Error(err)
+		return
+	}
+	if err := UpdateAgentConfig(record.FromVersion); err != nil {
+		log.Errorf("failed to roll back agent config: %v", err)
+	}
+	record.Error = cause.Error()
+	if err := saveUpgradeRecord(record); err != nil {
+		log.Errorf("failed to save upgrade record: %v", err)
+	}
+
+	if err := reexec(exe, os.Args); err != nil {
+		log.Errorf("failed to start agent of version %s: %v", record.FromVersion, err)
+	}
+}
+
+// ConfirmUpgrade is called once the agent works with zadig server, the backup of an upgrade is no longer needed then.
+func ConfirmUpgrade() {
+	upgradeMu.Lock()
+	defer upgradeMu.Unlock()
+	if confirmTimer != nil {
+		confirmTimer.Stop()
+		confirmTimer = nil
+	}
+
+	record, err := getUpgradeRecord()
+	if err != nil || record == nil || record.Error != "" || record.ToVersion != config.BuildAgentVersion {
+		return
+	}
+
+	log.Infof("agent is upgraded from version %s to %s.", record.FromVersion, record.ToVersion)
+	if err := os.RemoveAll(record.Backup); err != nil {
+		log.Errorf("failed to remove agent backup: %v", err)
+	}
+	if err := removeUpgradeRecord(); err != nil {
+		log.Errorf("failed to remove upgrade record: %v", err)
+	}
+}
+
+// FailedUpgrade returns the version and error of the last failed upgrade.
+func FailedUpgrade() (string, string) {
+	record, err := getUpgradeRecord()
+	if err != nil || record == nil {
+		return "", ""
+	}
+	return record.ToVersion, record.Error
+}
+
 func UpdateAgentConfig(newVersion string) error {
 	// get old agent config
 	oldConfig, err := config.GetAgentConfig()
@@ -0,0 +1,240 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/v2/pkg/cli/zadig-agent/config"
+)
+
+// setupAgent points the home of the agent to a temp dir with an agent config and fakes the agent binary in it
+func setupAgent(t *testing.T, version string) (string, *[]string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".zadig-agent"), 0755))
+	data, err := yaml.Marshal(&config.AgentConfig{AgentVersion: version})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(home, config.LocalConfig), data, 0644))
+
+	exe := filepath.Join(home, "zadig-agent")
+	require.NoError(t, os.WriteFile(exe, []byte("new"), 0755))
+
+	started := &[]string{}
+	oldExecutable, oldReexec, oldVersion, oldTimeout := executable, reexec, config.BuildAgentVersion, upgradeConfirmTimeout
+	executable = func() (string, error) { return exe, nil }
+	reexec = func(path string, args []string) error {
+		*started = append(*started, path)
+		return nil
+	}
+	config.BuildAgentVersion = version
+	t.Cleanup(func() {
+		upgradeMu.Lock()
+		if confirmTimer != nil {
+			confirmTimer.Stop()
+			confirmTimer = nil
+		}
+		upgradeMu.Unlock()
+		executable, reexec, config.BuildAgentVersion, upgradeConfirmTimeout = oldExecutable, oldReexec, oldVersion, oldTimeout
+	})
+	return exe, started
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func agentVersionInConfig(t *testing.T) string {
+	path, err := config.GetAgentConfigFilePath()
+	require.NoError(t, err)
+	agentConfig := &config.AgentConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(readFile(t, path)), agentConfig))
+	return agentConfig.AgentVersion
+}
+
+func TestSwapBinary(t *testing.T) {
+	dir := t.TempDir()
+	exe, newBinary, backup := filepath.Join(dir, "zadig-agent"), filepath.Join(dir, "zadig-agent.new"), filepath.Join(dir, "zadig-agent.bak")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0755))
+	require.NoError(t, os.WriteFile(newBinary, []byte("new"), 0755))
+	// the backup of an earlier upgrade is replaced
+	require.NoError(t, os.WriteFile(backup, []byte("older"), 0755))
+
+	require.NoError(t, swapBinary(exe, newBinary, backup))
+	assert.Equal(t, "new", readFile(t, exe))
+	assert.Equal(t, "old", readFile(t, backup))
+	assert.NoFileExists(t, newBinary)
+}
+
+func TestSwapBinaryRollsBackWhenNewBinaryIsMissing(t *testing.T) {
+	dir := t.TempDir()
+	exe, backup := filepath.Join(dir, "zadig-agent"), filepath.Join(dir, "zadig-agent.bak")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0755))
+
+	assert.Error(t, swapBinary(exe, filepath.Join(dir, "zadig-agent.new"), backup))
+	assert.Equal(t, "old", readFile(t, exe))
+	assert.NoFileExists(t, backup)
+}
+
+func TestRollbackBinary(t *testing.T) {
+	dir := t.TempDir()
+	exe, backup := filepath.Join(dir, "zadig-agent"), filepath.Join(dir, "zadig-agent.bak")
+	require.NoError(t, os.WriteFile(exe, []byte("new"), 0755))
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+
+	cause := assert.AnError
+	assert.Equal(t, cause, rollbackBinary(exe, backup, cause))
+	assert.Equal(t, "old", readFile(t, exe))
+	assert.NoFileExists(t, backup)
+}
+
+func TestRollbackBinaryWithoutBackup(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "zadig-agent")
+	require.NoError(t, os.WriteFile(exe, []byte("new"), 0755))
+
+	err := rollbackBinary(exe, filepath.Join(dir, "zadig-agent.bak"), assert.AnError)
+	assert.ErrorContains(t, err, assert.AnError.Error())
+	assert.ErrorContains(t, err, "failed to roll back agent binary")
+	assert.Equal(t, "new", readFile(t, exe))
+}
+
+func TestCheckUpgradeWithoutRecord(t *testing.T) {
+	_, started := setupAgent(t, "2.1.0")
+
+	CheckUpgrade()
+	assert.Empty(t, *started)
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestCheckUpgradeFirstStart(t *testing.T) {
+	exe, started := setupAgent(t, "2.1.0")
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: exe + ".bak"}))
+
+	// the first start of the new binary is counted, it has the time until the next start to connect
+	CheckUpgrade()
+	assert.Empty(t, *started)
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.Attempts)
+	assert.Empty(t, record.Error)
+}
+
+func TestCheckUpgradeRollsBack(t *testing.T) {
+	exe, started := setupAgent(t, "2.1.0")
+	backup := exe + ".bak"
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: backup, Attempts: 1}))
+
+	CheckUpgrade()
+	assert.Equal(t, "old", readFile(t, exe))
+	assert.Equal(t, []string{exe}, *started)
+	assert.Equal(t, "2.0.0", agentVersionInConfig(t))
+
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Contains(t, record.Error, "failed to connect to zadig server after upgrade")
+	// the rolled back agent reports the failure
+	version, errMsg := FailedUpgrade()
+	assert.Equal(t, "2.1.0", version)
+	assert.Equal(t, record.Error, errMsg)
+}
+
+func TestCheckUpgradeRollsBackUnconfirmedUpgrade(t *testing.T) {
+	exe, started := setupAgent(t, "2.1.0")
+	upgradeConfirmTimeout = 10 * time.Millisecond
+	backup := exe + ".bak"
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: backup}))
+
+	// the new binary starts but never gets a successful heartbeat
+	CheckUpgrade()
+	assert.Eventually(t, func() bool {
+		upgradeMu.Lock()
+		defer upgradeMu.Unlock()
+		return len(*started) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "old", readFile(t, exe))
+	assert.Equal(t, "2.0.0", agentVersionInConfig(t))
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Contains(t, record.Error, "failed to connect to zadig server in 10ms after upgrade")
+}
+
+func TestConfirmUpgradeStopsRollback(t *testing.T) {
+	exe, started := setupAgent(t, "2.1.0")
+	upgradeConfirmTimeout = 50 * time.Millisecond
+	backup := exe + ".bak"
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: backup}))
+
+	CheckUpgrade()
+	ConfirmUpgrade()
+	time.Sleep(100 * time.Millisecond)
+
+	upgradeMu.Lock()
+	assert.Empty(t, *started)
+	upgradeMu.Unlock()
+	assert.Equal(t, "new", readFile(t, exe))
+	assert.NoFileExists(t, backup)
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestCheckUpgradeIgnoresOtherVersions(t *testing.T) {
+	exe, started := setupAgent(t, "2.0.0")
+	backup := exe + ".bak"
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+	// the record of an upgrade to a version that is not running, the rolled back agent is running
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: backup, Attempts: 1}))
+
+	CheckUpgrade()
+	assert.Empty(t, *started)
+	assert.Equal(t, "new", readFile(t, exe))
+	assert.FileExists(t, backup)
+}
+
+func TestConfirmUpgrade(t *testing.T) {
+	exe, _ := setupAgent(t, "2.1.0")
+	backup := exe + ".bak"
+	require.NoError(t, os.WriteFile(backup, []byte("old"), 0755))
+	require.NoError(t, saveUpgradeRecord(&UpgradeRecord{FromVersion: "2.0.0", ToVersion: "2.1.0", Backup: backup, Attempts: 1}))
+
+	ConfirmUpgrade()
+	assert.NoFileExists(t, backup)
+	record, err := getUpgradeRecord()
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestDownloadAgentRequiresChecksum(t *testing.T) {
+	err := downloadAgent(&Upgrade{Version: "2.1.0", URL: "http://127.0.0.1:0/zadig-agent.tar.gz"}, filepath.Join(t.TempDir(), "zadig-agent"))
+	assert.EqualError(t, err, "checksum of agent version 2.1.0 is not pinned")
+}
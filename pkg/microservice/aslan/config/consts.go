@@ -193,6 +193,8 @@ const (
 	StepDistributeImage   StepType = "distribute_image"
 	StepDebugBefore       StepType = "debug_before"
 	StepDebugAfter        StepType = "debug_after"
+	StepCacheRestore      StepType = "cache_restore"
+	StepCacheSave         StepType = "cache_save"
 )
 
 type JobType string
@@ -242,8 +244,10 @@ const (
 type DBInstanceType string
 
 const (
-	DBInstanceTypeMySQL   DBInstanceType = "mysql"
-	DBInstanceTypeMariaDB DBInstanceType = "mariadb"
+	DBInstanceTypeMySQL      DBInstanceType = "mysql"
+	DBInstanceTypeMariaDB    DBInstanceType = "mariadb"
+	DBInstanceTypePostgreSQL DBInstanceType = "postgresql"
+	DBInstanceTypeSQLServer  DBInstanceType = "sqlserver"
 )
 
 type ObservabilityType string
@@ -470,6 +474,68 @@ const (
 	ForceRun      JobRunPolicy = "force_run"       // force run this job
 )
 
+// JobFailureClass is the class of a job failure used to decide whether the job should be retried
+type JobFailureClass string
+
+const (
+	JobFailureTimeout        JobFailureClass = "timeout"
+	JobFailurePodEvicted     JobFailureClass = "pod_evicted"
+	JobFailureImagePullError JobFailureClass = "image_pull_error"
+	JobFailureOther          JobFailureClass = "other"
+)
+
+type RetryBackoff string
+
+const (
+	RetryBackoffFixed       RetryBackoff = "fixed"
+	RetryBackoffExponential RetryBackoff = "exponential"
+)
+
+// WorkflowCodeAction is what happens to a workflow when the workflow files in the repository are synced
+type WorkflowCodeAction string
+
+const (
+	WorkflowCodeActionCreate    WorkflowCodeAction = "create"
+	WorkflowCodeActionUpdate    WorkflowCodeAction = "update"
+	WorkflowCodeActionUnchanged WorkflowCodeAction = "unchanged"
+	// WorkflowCodeActionAdopt means the workflow created in zadig is taken over by the workflow file with the same name
+	WorkflowCodeActionAdopt WorkflowCodeAction = "adopt"
+	// WorkflowCodeActionRelease means the workflow file is removed, the workflow is kept and becomes editable again
+	WorkflowCodeActionRelease WorkflowCodeAction = "release"
+)
+
+// WorkflowCodeDriftType is how a workflow differs between the repository and zadig
+type WorkflowCodeDriftType string
+
+const (
+	// WorkflowCodeDriftNotSynced means the workflow file is changed in the repository but not synced yet
+	WorkflowCodeDriftNotSynced WorkflowCodeDriftType = "not_synced"
+	// WorkflowCodeDriftModified means the workflow is modified in zadig after the last sync
+	WorkflowCodeDriftModified WorkflowCodeDriftType = "modified"
+	// WorkflowCodeDriftMissingInZadig means the workflow file exists but the workflow is not found in zadig
+	WorkflowCodeDriftMissingInZadig WorkflowCodeDriftType = "missing_in_zadig"
+	// WorkflowCodeDriftMissingInRepo means the workflow is managed by the repository but the file is removed
+	WorkflowCodeDriftMissingInRepo WorkflowCodeDriftType = "missing_in_repo"
+)
+
+// PolicyEnforcement decides what happens when a workflow policy is violated
+type PolicyEnforcement string
+
+const (
+	// PolicyEnforcementBlock fails the task creation or the job
+	PolicyEnforcementBlock PolicyEnforcement = "block"
+	// PolicyEnforcementWarn only records the violation in the task
+	PolicyEnforcementWarn PolicyEnforcement = "warn"
+)
+
+// PolicyCheckpoint is when the workflow policies are evaluated
+type PolicyCheckpoint string
+
+const (
+	PolicyCheckpointTaskCreate PolicyCheckpoint = "task_create"
+	PolicyCheckpointJobRun     PolicyCheckpoint = "job_run"
+)
+
 const DefaultDeleteDeploymentTimeout = 10 * time.Minute
 
 // Service creation source for openAPI
@@ -498,6 +564,8 @@ const (
 	DashboardDataTypeReleaseSuccessRate     = "release_success_rate"
 	DashboardDataTypeReleaseAverageDuration = "release_average_duration"
 	DashboardDataTypeReleaseFrequency       = "release_frequency"
+	DashboardDataTypeChangeFailureRate      = "change_failure_rate"
+	DashboardDataTypeMeanTimeToRestore      = "mean_time_to_restore"
 
 	DashboardDataSourceZadig = "zadig"
 	DashboardDataSourceApi   = "api"
@@ -513,6 +581,23 @@ const (
 	DashboardFunctionTestPassRate         = "(x**2)/80-x/4+1.25"
 	DashboardFunctionTestAverageDuration  = "90000/(x+900)"
 	DashboardFunctionReleaseFrequency     = "100-200/(x+2)"
+	DashboardFunctionChangeFailureRate    = "10000/(5*x+100)"
+	DashboardFunctionMeanTimeToRestore    = "360000/(x+3600)"
+)
+
+// incident source enum, an incident is a failure of a change which is used to calculate
+// the change failure rate and the mean time to restore
+const (
+	IncidentSourceAPI         = "api"
+	IncidentSourceEnvRollback = "env_rollback"
+	IncidentSourceJobRollback = "job_rollback"
+)
+
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen     IncidentStatus = "open"
+	IncidentStatusResolved IncidentStatus = "resolved"
 )
 
 const (
@@ -524,7 +609,9 @@ type ReleasePlanStatus string
 const (
 	StatusPlanning       ReleasePlanStatus = "planning"
 	StatusWaitForApprove ReleasePlanStatus = "waitforapprove"
+	StatusWaitForExecute ReleasePlanStatus = "waitforexecute"
 	StatusExecuting      ReleasePlanStatus = "executing"
+	StatusPaused         ReleasePlanStatus = "paused"
 	StatusSuccess        ReleasePlanStatus = "success"
 	StatusCancel         ReleasePlanStatus = "cancel"
 )
@@ -533,7 +620,9 @@ const (
 var ReleasePlanStatusMap = map[ReleasePlanStatus][]ReleasePlanStatus{
 	StatusPlanning:       {StatusWaitForApprove, StatusExecuting},
 	StatusWaitForApprove: {StatusPlanning, StatusExecuting},
+	StatusWaitForExecute: {StatusPlanning, StatusExecuting, StatusCancel},
 	StatusExecuting:      {StatusPlanning, StatusSuccess, StatusCancel},
+	StatusPaused:         {StatusPlanning, StatusExecuting, StatusCancel},
 }
 
 type ReleasePlanJobType string
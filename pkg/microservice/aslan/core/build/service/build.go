@@ -36,6 +36,7 @@ import (
 	e "github.com/koderover/zadig/v2/pkg/tool/errors"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 	"github.com/koderover/zadig/v2/pkg/types"
+	steptypes "github.com/koderover/zadig/v2/pkg/types/step"
 )
 
 type BuildResp struct {
@@ -588,6 +589,10 @@ func correctFields(build *commonmodels.Build) error {
 	}
 	build.Caches = caches
 
+	if err := lintBuildCacheRules(build.CacheRules); err != nil {
+		return err
+	}
+
 	// trim the docker file and context
 	if build.PostBuild != nil && build.PostBuild.DockerBuild != nil {
 		build.PostBuild.DockerBuild.DockerFile = strings.Trim(build.PostBuild.DockerBuild.DockerFile, " ")
@@ -636,6 +641,38 @@ func correctFields(build *commonmodels.Build) error {
 	return nil
 }
 
+func lintBuildCacheRules(rules []*commonmodels.BuildCacheRule) error {
+	names := sets.NewString()
+	for _, rule := range rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return fmt.Errorf("cache rule name is empty")
+		}
+		if strings.Contains(rule.Name, steptypes.CacheKeySeparator) || strings.Contains(rule.Name, "/") {
+			return fmt.Errorf("cache rule name %s can not contain %s or /", rule.Name, steptypes.CacheKeySeparator)
+		}
+		if names.Has(rule.Name) {
+			return fmt.Errorf("duplicated cache rule name: %s", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		paths := make([]string, 0)
+		for _, p := range rule.Paths {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("paths of cache rule %s is empty", rule.Name)
+		}
+		rule.Paths = paths
+		if rule.MaxSize < 0 || rule.TTL < 0 {
+			return fmt.Errorf("max size and ttl of cache rule %s can not be negative", rule.Name)
+		}
+	}
+	return nil
+}
+
 func modifyAuthType(repo *types.Repository) {
 	repo.RepoOwner = strings.TrimPrefix(repo.RepoOwner, "/")
 	repo.RepoOwner = strings.TrimSuffix(repo.RepoOwner, "/")
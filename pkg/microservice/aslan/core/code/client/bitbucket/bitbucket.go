@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+type Config struct {
+	Username    string `json:"username"`
+	AccessToken string `json:"access_token"`
+	EnableProxy bool   `json:"enable_proxy"`
+}
+
+type Client struct {
+	Client *bitbucket.Client
+}
+
+func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
+	return &Client{
+		Client: bitbucket.NewClient(c.Username, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy),
+	}, nil
+}
+
+func (c *Client) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	branches, err := c.Client.ListBranches(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Branch, 0, len(branches))
+	for _, branch := range branches {
+		res = append(res, &client.Branch{
+			Name: branch.Name,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	tags, err := c.Client.ListTags(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Tag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, &client.Tag{
+			Name:    tag.Name,
+			Message: tag.Message,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	prs, err := c.Client.ListPullRequests(opt.Namespace, opt.ProjectName, opt.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		var author string
+		if pr.Author != nil {
+			author = pr.Author.Nickname
+		}
+		item := &client.PullRequest{
+			ID:             pr.ID,
+			Number:         pr.ID,
+			Title:          pr.Title,
+			State:          pr.State,
+			CreatedAt:      pr.CreatedOn.Unix(),
+			UpdatedAt:      pr.UpdatedOn.Unix(),
+			AuthorUsername: author,
+			User:           author,
+		}
+		if pr.Source != nil {
+			item.SourceBranch = pr.Source.Branch.Name
+		}
+		if pr.Destination != nil {
+			item.TargetBranch = pr.Destination.Branch.Name
+			item.Base = pr.Destination.Branch.Name
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (c *Client) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	workspaces, err := c.Client.ListWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Namespace, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		res = append(res, &client.Namespace{
+			Name: workspace.Slug,
+			Path: workspace.Slug,
+			Kind: client.OrgKind,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	repos, err := c.Client.ListRepositories(opt.Namespace, opt.Key, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Project, 0, len(repos))
+	for i, repo := range repos {
+		project := &client.Project{
+			ID:          i, // fake id, bitbucket cloud identifies the repositories by uuid
+			Name:        repo.Slug,
+			Description: repo.Description,
+			Namespace:   opt.Namespace,
+			RepoUUID:    repo.UUID,
+		}
+		if repo.MainBranch != nil {
+			project.DefaultBranch = repo.MainBranch.Name
+		}
+		res = append(res, project)
+	}
+	return res, nil
+}
+
+func (c *Client) ListCommits(opt client.ListOpt) ([]*client.Commit, error) {
+	commits, err := c.Client.ListCommits(opt.Namespace, opt.ProjectName, opt.TargetBranch, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Commit, 0, len(commits))
+	for _, commit := range commits {
+		item := &client.Commit{
+			ID:        commit.Hash,
+			Message:   commit.Message,
+			CreatedAt: commit.Date.Unix(),
+		}
+		if commit.Author != nil {
+			item.Author = commit.Author.Raw
+			if commit.Author.User != nil {
+				item.Author = commit.Author.User.DisplayName
+			}
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+type ServerConfig struct {
+	Address     string `json:"address"`
+	Username    string `json:"username"`
+	AccessToken string `json:"access_token"`
+	EnableProxy bool   `json:"enable_proxy"`
+}
+
+type ServerClient struct {
+	Client *bitbucket.ServerClient
+}
+
+func (c *ServerConfig) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
+	return &ServerClient{
+		Client: bitbucket.NewServerClient(c.Address, c.Username, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy),
+	}, nil
+}
+
+func (c *ServerClient) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	branches, err := c.Client.ListBranches(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Branch, 0, len(branches))
+	for _, branch := range branches {
+		res = append(res, &client.Branch{
+			Name: branch.DisplayID,
+		})
+	}
+	return res, nil
+}
+
+func (c *ServerClient) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	tags, err := c.Client.ListTags(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Tag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, &client.Tag{
+			Name: tag.DisplayID,
+		})
+	}
+	return res, nil
+}
+
+func (c *ServerClient) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	prs, err := c.Client.ListPullRequests(opt.Namespace, opt.ProjectName, opt.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		item := &client.PullRequest{
+			ID:        pr.ID,
+			Number:    pr.ID,
+			Title:     pr.Title,
+			State:     pr.State,
+			CreatedAt: pr.CreatedDate / 1000,
+			UpdatedAt: pr.UpdatedDate / 1000,
+		}
+		if pr.Author.User != nil {
+			item.AuthorUsername = pr.Author.User.Name
+			item.User = pr.Author.User.Name
+		}
+		if pr.FromRef != nil {
+			item.SourceBranch = pr.FromRef.DisplayID
+		}
+		if pr.ToRef != nil {
+			item.TargetBranch = pr.ToRef.DisplayID
+			item.Base = pr.ToRef.DisplayID
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (c *ServerClient) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	projects, err := c.Client.ListProjects(keyword)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Namespace, 0, len(projects))
+	for _, project := range projects {
+		res = append(res, &client.Namespace{
+			Name: project.Key,
+			Path: project.Key,
+			Kind: client.OrgKind,
+		})
+	}
+	return res, nil
+}
+
+func (c *ServerClient) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	repos, err := c.Client.ListRepositories(opt.Namespace, opt.Key, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Project, 0, len(repos))
+	for _, repo := range repos {
+		res = append(res, &client.Project{
+			ID:          repo.ID,
+			Name:        repo.Slug,
+			Description: repo.Description,
+			Namespace:   opt.Namespace,
+		})
+	}
+	return res, nil
+}
+
+func (c *ServerClient) ListCommits(opt client.ListOpt) ([]*client.Commit, error) {
+	commits, err := c.Client.ListCommits(opt.Namespace, opt.ProjectName, opt.TargetBranch, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Commit, 0, len(commits))
+	for _, commit := range commits {
+		item := &client.Commit{
+			ID:        commit.ID,
+			Message:   commit.Message,
+			CreatedAt: commit.AuthorTimestamp / 1000,
+		}
+		if commit.Author != nil {
+			item.Author = commit.Author.Name
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/v2/pkg/tool/gitea"
+)
+
+type Config struct {
+	Address     string `json:"address"`
+	AccessToken string `json:"access_token"`
+	EnableProxy bool   `json:"enable_proxy"`
+}
+
+type Client struct {
+	Client *gitea.Client
+}
+
+func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
+	return &Client{
+		Client: gitea.NewClient(c.Address, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy),
+	}, nil
+}
+
+func (c *Client) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	branches, err := c.Client.ListBranches(opt.Namespace, opt.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Branch, 0, len(branches))
+	for _, branch := range branches {
+		res = append(res, &client.Branch{
+			Name:      branch.Name,
+			Protected: branch.Protected,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	tags, err := c.Client.ListTags(opt.Namespace, opt.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Tag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, &client.Tag{
+			Name:       tag.Name,
+			Message:    tag.Message,
+			ZipballURL: tag.ZipballURL,
+			TarballURL: tag.TarballURL,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	prs, err := c.Client.ListPullRequests(opt.Namespace, opt.ProjectName, opt.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		item := &client.PullRequest{
+			ID:        pr.Number,
+			Number:    pr.Number,
+			Title:     pr.Title,
+			State:     pr.State,
+			CreatedAt: pr.CreatedAt.Unix(),
+			UpdatedAt: pr.UpdatedAt.Unix(),
+		}
+		if pr.User != nil {
+			item.AuthorUsername = pr.User.Login
+			item.User = pr.User.Login
+		}
+		if pr.Head != nil {
+			item.SourceBranch = pr.Head.Ref
+		}
+		if pr.Base != nil {
+			item.TargetBranch = pr.Base.Ref
+			item.Base = pr.Base.Ref
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (c *Client) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	user, err := c.Client.GetAuthenticatedUser()
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := c.Client.ListOrganizations()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*client.Namespace, 0, len(orgs)+1)
+	res = append(res, &client.Namespace{
+		Name: user.Login,
+		Path: user.Login,
+		Kind: client.UserKind,
+	})
+	for _, org := range orgs {
+		res = append(res, &client.Namespace{
+			Name: org.UserName,
+			Path: org.UserName,
+			Kind: client.OrgKind,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	org := ""
+	if opt.NamespaceType == client.OrgKind {
+		org = opt.Namespace
+	}
+	repos, err := c.Client.ListRepositories(org, opt.Key, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Project, 0, len(repos))
+	for _, repo := range repos {
+		project := &client.Project{
+			ID:            repo.ID,
+			Name:          repo.Name,
+			Description:   repo.Description,
+			DefaultBranch: repo.DefaultBranch,
+		}
+		if repo.Owner != nil {
+			project.Namespace = repo.Owner.Login
+		}
+		res = append(res, project)
+	}
+	return res, nil
+}
+
+func (c *Client) ListCommits(opt client.ListOpt) ([]*client.Commit, error) {
+	commits, err := c.Client.ListCommits(opt.Namespace, opt.ProjectName, opt.TargetBranch, opt.Page, opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*client.Commit, 0, len(commits))
+	for _, commit := range commits {
+		res = append(res, &client.Commit{
+			ID:        commit.Sha,
+			Message:   commit.Commit.Message,
+			Author:    commit.Commit.Author.Name,
+			CreatedAt: commit.Commit.Author.Date.Unix(),
+		})
+	}
+	return res, nil
+}
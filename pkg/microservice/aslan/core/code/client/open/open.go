@@ -23,7 +23,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/bitbucket"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/gerrit"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/gitea"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/gitee"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/github"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/code/client/gitlab"
@@ -41,6 +43,10 @@ var ClientsConfig = map[string]func() ClientConfig{
 	setting.SourceFromGerrit:  func() ClientConfig { return new(gerrit.Config) },
 	setting.SourceFromGitee:   func() ClientConfig { return new(gitee.Config) },
 	setting.SourceFromGiteeEE: func() ClientConfig { return new(gitee.EEConfig) },
+
+	setting.SourceFromBitbucket:       func() ClientConfig { return new(bitbucket.Config) },
+	setting.SourceFromBitbucketServer: func() ClientConfig { return new(bitbucket.ServerConfig) },
+	setting.SourceFromGitea:           func() ClientConfig { return new(gitea.Config) },
 }
 
 func OpenClient(ch *systemconfig.CodeHost, log *zap.SugaredLogger) (client.CodeHostClient, error) {
@@ -55,6 +55,8 @@ type Build struct {
 	CacheEnable  bool               `bson:"cache_enable"   json:"cache_enable"`
 	CacheDirType types.CacheDirType `bson:"cache_dir_type" json:"cache_dir_type"`
 	CacheUserDir string             `bson:"cache_user_dir" json:"cache_user_dir"`
+	// CacheRules are the caches restored before and saved after the build script, keyed by the hash of the key files
+	CacheRules []*BuildCacheRule `bson:"cache_rules"    json:"cache_rules"`
 	// New since V1.10.0. Only to tell the webpage should the advanced settings be displayed
 	AdvancedSettingsModified bool      `bson:"advanced_setting_modified" json:"advanced_setting_modified"`
 	Outputs                  []*Output `bson:"outputs"                   json:"outputs"`
@@ -62,6 +64,19 @@ type Build struct {
 	VMLabels                 []string  `bson:"vm_labels"                 json:"vm_labels"`
 }
 
+// BuildCacheRule is a content-addressed cache of the build, stored in the default object storage
+type BuildCacheRule struct {
+	Name string `bson:"name"      json:"name"`
+	// Paths are the cached files or dirs, relative paths are relative to the workspace
+	Paths []string `bson:"paths"     json:"paths"`
+	// KeyFiles are the lock files such as go.sum, package-lock.json and pom.xml, glob patterns are supported
+	KeyFiles []string `bson:"key_files" json:"key_files"`
+	// MaxSize is the max size of the cache in MB, 0 means no limit
+	MaxSize int64 `bson:"max_size"  json:"max_size"`
+	// TTL is the days a cache is kept, 0 means caches never expire
+	TTL int64 `bson:"ttl"       json:"ttl"`
+}
+
 // PreBuild prepares an environment for a job
 type PreBuild struct {
 	// TODO: Deprecated.
@@ -87,6 +102,8 @@ type PreBuild struct {
 	StrategyID string `bson:"strategy_id"                     json:"strategy_id"`
 	// UseHostDockerDaemon determines is dockerDaemon on host node is used in pod
 	UseHostDockerDaemon bool `bson:"use_host_docker_daemon" json:"use_host_docker_daemon"`
+	// RunInContainer runs the vm job in a container of the build image on the agent host instead of on the host directly
+	RunInContainer bool `bson:"run_in_container,omitempty" json:"run_in_container"`
 
 	// TODO: Deprecated.
 	Namespace string `bson:"namespace"                       json:"namespace"`
@@ -23,17 +23,22 @@ import (
 )
 
 type DBInstance struct {
-	ID        primitive.ObjectID    `bson:"_id,omitempty"         json:"id,omitempty"`
-	Type      config.DBInstanceType `bson:"type"                  json:"type"`
-	Name      string                `bson:"name"                  json:"name"`
-	Projects  []string              `bson:"projects"              json:"projects"`
-	Host      string                `bson:"host"                  json:"host"`
-	Port      string                `bson:"port"                  json:"port"`
-	Username  string                `bson:"username"              json:"username"`
-	Password  string                `bson:"password"              json:"password,omitempty"`
-	UpdateBy  string                `bson:"update_by"             json:"update_by"`
-	CreatedAt int64                 `bson:"created_at"            json:"created_at"`
-	UpdatedAt int64                 `bson:"updated_at"            json:"updated_at"`
+	ID       primitive.ObjectID    `bson:"_id,omitempty"         json:"id,omitempty"`
+	Type     config.DBInstanceType `bson:"type"                  json:"type"`
+	Name     string                `bson:"name"                  json:"name"`
+	Projects []string              `bson:"projects"              json:"projects"`
+	Host     string                `bson:"host"                  json:"host"`
+	Port     string                `bson:"port"                  json:"port"`
+	Username string                `bson:"username"              json:"username"`
+	Password string                `bson:"password"              json:"password,omitempty"`
+	// Database is the database to connect, it is optional for mysql and mariadb
+	Database  string `bson:"database"              json:"database"`
+	UpdateBy  string `bson:"update_by"             json:"update_by"`
+	CreatedAt int64  `bson:"created_at"            json:"created_at"`
+	UpdatedAt int64  `bson:"updated_at"            json:"updated_at"`
+
+	// SSLMode is the ssl mode of the postgresql connection: disable, require, verify-ca or verify-full, require by default
+	SSLMode string `bson:"ssl_mode"              json:"ssl_mode"`
 }
 
 func (h DBInstance) TableName() string {
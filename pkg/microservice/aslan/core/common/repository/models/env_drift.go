@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EnvDrift is a live object in the cluster that differs from the rendered service yaml of the environment
+type EnvDrift struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"          json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"           json:"project_name"`
+	EnvName     string             `bson:"env_name"               json:"env_name"`
+	Production  bool               `bson:"production"             json:"production"`
+	ServiceName string             `bson:"service_name"           json:"service_name"`
+	Namespace   string             `bson:"namespace"              json:"namespace"`
+	Kind        string             `bson:"kind"                   json:"kind"`
+	Name        string             `bson:"name"                   json:"name"`
+	// Missing is true if the object is rendered but not found in the cluster
+	Missing bool          `bson:"missing"                json:"missing"`
+	Fields  []*DriftField `bson:"fields"                 json:"fields"`
+	// Reverted is true if the drift is overwritten by the auto revert policy
+	Reverted   bool  `bson:"reverted"               json:"reverted"`
+	DetectedAt int64 `bson:"detected_at"            json:"detected_at"`
+	UpdateTime int64 `bson:"update_time"            json:"update_time"`
+}
+
+type DriftField struct {
+	Path     string `bson:"path"     json:"path"`
+	Expected string `bson:"expected" json:"expected"`
+	Actual   string `bson:"actual"   json:"actual"`
+}
+
+func (EnvDrift) TableName() string {
+	return "env_drift"
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EnvSnapshot is a point-in-time copy of an environment, it records the services with their revisions and variables,
+// and the configmaps, secrets and pvcs in the namespace of the environment.
+type EnvSnapshot struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"          json:"id,omitempty"`
+	Name        string             `bson:"name"                   json:"name"`
+	Description string             `bson:"description"            json:"description"`
+	ProjectName string             `bson:"project_name"           json:"project_name"`
+	EnvName     string             `bson:"env_name"               json:"env_name"`
+	Production  bool               `bson:"production"             json:"production"`
+	ClusterID   string             `bson:"cluster_id"             json:"cluster_id"`
+	Namespace   string             `bson:"namespace"              json:"namespace"`
+	// Product is the environment at the time the snapshot is taken
+	Product *Product `bson:"product,omitempty"      json:"product,omitempty"`
+	// Resources are stored in the env_snapshot_resource collection, one document per resource,
+	// to keep the snapshot of a large namespace under the document size limit.
+	Resources  []*EnvSnapshotResource `bson:"-"                      json:"resources,omitempty"`
+	CreatedBy  string                 `bson:"created_by"             json:"created_by"`
+	CreateTime int64                  `bson:"create_time"            json:"create_time"`
+}
+
+type EnvSnapshotResource struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	SnapshotID string             `bson:"snapshot_id"   json:"-"`
+	Kind       string             `bson:"kind"          json:"kind"`
+	Name       string             `bson:"name"          json:"name"`
+	// ServiceName is set if the resource is applied by a service of the environment
+	ServiceName string `bson:"service_name"  json:"service_name"`
+	// Managed is true if the resource is created by a service or a helm release, it is restored by deploying the service
+	// instead of applying the yaml.
+	Managed bool   `bson:"managed"       json:"managed"`
+	Yaml    string `bson:"yaml"          json:"yaml"`
+	// Encrypted is true if the yaml is encrypted by the aes key of the system, it is set for secrets
+	Encrypted bool `bson:"encrypted"     json:"-"`
+}
+
+func (EnvSnapshot) TableName() string {
+	return "env_snapshot"
+}
+
+func (EnvSnapshotResource) TableName() string {
+	return "env_snapshot_resource"
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+)
+
+// Incident is a failure caused by a change of a project, it is reported through the API or recorded
+// when a service is rolled back. Incidents are used to calculate the change failure rate and the mean time to restore.
+type Incident struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"          json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"           json:"project_name"`
+	Title       string             `bson:"title"                  json:"title"`
+	Description string             `bson:"description"            json:"description"`
+	Source      string             `bson:"source"                 json:"source"`
+	EnvName     string             `bson:"env_name"               json:"env_name"`
+	ServiceName string             `bson:"service_name"           json:"service_name"`
+	Production  bool               `bson:"production"             json:"production"`
+	// WorkflowName and TaskID are set if the incident is recorded by a rollback job
+	WorkflowName string                `bson:"workflow_name"          json:"workflow_name"`
+	TaskID       int64                 `bson:"task_id"                json:"task_id"`
+	Status       config.IncidentStatus `bson:"status"                 json:"status"`
+	// OccurredAt is the time the failure started, ResolvedAt is the time the service is restored
+	OccurredAt int64  `bson:"occurred_at"            json:"occurred_at"`
+	ResolvedAt int64  `bson:"resolved_at"            json:"resolved_at"`
+	CreatedBy  string `bson:"created_by"             json:"created_by"`
+	CreateTime int64  `bson:"create_time"            json:"create_time"`
+	UpdateTime int64  `bson:"update_time"            json:"update_time"`
+}
+
+func (Incident) TableName() string {
+	return "incident"
+}
@@ -21,13 +21,27 @@ import (
 )
 
 type LLMIntegration struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
-	Name        string             `bson:"name"           json:"name"`
-	Token       string             `bson:"token"          json:"token"`
-	BaseURL     string             `bson:"base_url"       json:"base_url"`
-	EnableProxy bool               `bson:"enable_proxy"   json:"enable_proxy"`
-	UpdatedBy   string             `bson:"updated_by"     json:"updated_by"`
-	UpdateTime  int64              `bson:"update_time"    json:"update_time"`
+	ID   primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
+	Name string             `bson:"name"           json:"name"`
+	// ProviderName is the registered llm provider used by the integration, the name is used as the provider if it is empty
+	ProviderName string `bson:"provider_name"  json:"provider_name"`
+	// Model is the default model of the integration, the default model of the provider is used if it is empty
+	Model       string `bson:"model"          json:"model"`
+	Token       string `bson:"token"          json:"token"`
+	BaseURL     string `bson:"base_url"       json:"base_url"`
+	EnableProxy bool   `bson:"enable_proxy"   json:"enable_proxy"`
+	UpdatedBy   string `bson:"updated_by"     json:"updated_by"`
+	UpdateTime  int64  `bson:"update_time"    json:"update_time"`
+
+	// Usage is the tokens used by all the requests to the integration, it is only updated by the llm clients
+	Usage *LLMUsage `bson:"usage,omitempty"  json:"usage,omitempty"`
+}
+
+type LLMUsage struct {
+	Requests         int64 `bson:"requests"           json:"requests"`
+	PromptTokens     int64 `bson:"prompt_tokens"      json:"prompt_tokens"`
+	CompletionTokens int64 `bson:"completion_tokens"  json:"completion_tokens"`
+	TotalTokens      int64 `bson:"total_tokens"       json:"total_tokens"`
 }
 
 func (llm LLMIntegration) TableName() string {
@@ -20,7 +20,9 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
+	"strings"
 	"text/template"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -40,12 +42,17 @@ type Notification struct {
 	IsTest       bool                `bson:"is_test"                      json:"is_test"`
 	IsScanning   bool                `bson:"is_scanning"                  json:"is_scanning"`
 	IsWorkflowV4 bool                `bson:"is_workflowv4"                json:"is_workflowv4"`
+	IsPreviewEnv bool                `bson:"is_preview_env"               json:"is_preview_env"`
 	ErrInfo      string              `bson:"err_info"                     json:"err_info"`
 	PrTask       *PrTaskInfo         `bson:"pr_task_info,omitempty"       json:"pr_task_info,omitempty"`
 	Label        string              `bson:"label"                        json:"label"  `
 	Revision     string              `bson:"revision"                     json:"revision"`
 	RepoOwner    string              `bson:"repo_owner"                   json:"repo_owner"`
 	RepoName     string              `bson:"repo_name"                    json:"repo_name"`
+
+	// IsWorkflowCode is true for the comment of the workflow changes of the workflow files in the pull request
+	IsWorkflowCode      bool                  `bson:"is_workflow_code"                json:"is_workflow_code"`
+	WorkflowCodeChanges []*WorkflowCodeChange `bson:"workflow_code_changes,omitempty" json:"workflow_code_changes,omitempty"`
 }
 
 type PrTaskInfo struct {
@@ -53,6 +60,7 @@ type PrTaskInfo struct {
 	EnvName          string `bson:"env_name,omitempty"                  json:"env_name,omitempty"`
 	EnvRecyclePolicy string `bson:"env_recycle_policy,omitempty"        json:"env_recycle_policy,omitempty"`
 	ProductName      string `bson:"product_name,omitempty"              json:"product_name,omitempty"`
+	ExpireTime       int64  `bson:"expire_time,omitempty"               json:"expire_time,omitempty"`
 }
 
 type NotificationTask struct {
@@ -110,6 +118,13 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 		}
 	}
 
+	if n.IsPreviewEnv {
+		return n.createPreviewEnvCommentBody(), nil
+	}
+	if n.IsWorkflowCode {
+		return n.createWorkflowCodeCommentBody(), nil
+	}
+
 	tmplSource := ""
 	if n.IsPipeline {
 		if len(n.Tasks) == 0 {
@@ -191,6 +206,50 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 	return buffer.String(), nil
 }
 
+func (n *Notification) createPreviewEnvCommentBody() string {
+	if n.PrTask == nil {
+		return "预览环境：等待创建中"
+	}
+	comment := fmt.Sprintf("预览环境：[%s](%s/v1/projects/detail/%s/envs/detail?envName=%s) 状态：%s \n\n", n.PrTask.EnvName, n.BaseURI, n.PrTask.ProductName, n.PrTask.EnvName, n.PrTask.EnvStatus)
+	if n.PrTask.ExpireTime > 0 {
+		comment += fmt.Sprintf("过期时间：%s \n\n", time.Unix(n.PrTask.ExpireTime, 0).Format("2006-01-02 15:04:05"))
+	}
+	return comment
+}
+
+func (n *Notification) createWorkflowCodeCommentBody() string {
+	if len(n.WorkflowCodeChanges) == 0 {
+		return "工作流变更：无"
+	}
+	comment := "|工作流|文件|变更|新增任务|删除任务|修改任务| \n |---|---|---|---|---|---| \n "
+	for _, change := range n.WorkflowCodeChanges {
+		action := getWorkflowCodeAction(change.Action)
+		if change.Error != "" {
+			// the error is put in a table cell, it can't break the table
+			errInfo := strings.NewReplacer("\n", " ", "|", "\\|").Replace(change.Error)
+			action = fmt.Sprintf("{- 校验失败：%s -}", errInfo)
+		}
+		comment += fmt.Sprintf("|%s|%s|%s|%s|%s|%s| \n ", change.WorkflowName, change.Path, action,
+			strings.Join(change.AddedJobs, "<br>"), strings.Join(change.DeletedJobs, "<br>"), strings.Join(change.ChangedJobs, "<br>"))
+	}
+	return comment
+}
+
+func getWorkflowCodeAction(action config.WorkflowCodeAction) string {
+	switch action {
+	case config.WorkflowCodeActionCreate:
+		return "新建"
+	case config.WorkflowCodeActionUpdate:
+		return "更新"
+	case config.WorkflowCodeActionAdopt:
+		return "接管"
+	case config.WorkflowCodeActionRelease:
+		return "取消代码管理"
+	default:
+		return "无变化"
+	}
+}
+
 func getEnvRecyclePolicy(policy string) string {
 	switch policy {
 	case config.EnvRecyclePolicyAlways:
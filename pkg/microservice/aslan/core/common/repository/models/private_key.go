@@ -76,6 +76,13 @@ type VMAgent struct {
 	AgentVersion      string `bson:"agent_version"        json:"agent_version"`
 	ZadigVersion      string `bson:"zadig_version"        json:"zadig_version"`
 	LastHeartbeatTime int64  `bson:"last_heartbeat_time"  json:"last_heartbeat_time"`
+
+	// DesiredVersion is the version the agent is pinned to, the agent upgrades itself to it
+	DesiredVersion string `bson:"desired_version,omitempty" json:"desired_version,omitempty"`
+	// DesiredChecksums are the sha256 checksums of the agent packages of DesiredVersion, keyed by platform like linux_amd64
+	DesiredChecksums map[string]string `bson:"desired_checksums,omitempty" json:"-"`
+	UpgradeStatus    string            `bson:"upgrade_status,omitempty"    json:"upgrade_status,omitempty"`
+	UpgradeError     string            `bson:"upgrade_error,omitempty"     json:"upgrade_error,omitempty"`
 }
 
 func (PrivateKey) TableName() string {
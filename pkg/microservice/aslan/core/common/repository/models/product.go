@@ -61,6 +61,11 @@ type Product struct {
 	// New Since v1.11.0.
 	ShareEnv ProductShareEnv `bson:"share_env" json:"share_env"`
 
+	// PreviewEnvPolicy is set on the base environment, a sub environment is created for each pull request of the configured repos
+	PreviewEnvPolicy *PreviewEnvPolicy `bson:"preview_env_policy,omitempty" json:"preview_env_policy,omitempty"`
+	// PreviewEnv is set on the sub environments created by pull requests
+	PreviewEnv *PreviewEnv `bson:"preview_env,omitempty" json:"preview_env,omitempty"`
+
 	// New Since v1.13.0.
 	EnvConfigs []*CreateUpdateCommonEnvCfgArgs `bson:"-"   json:"env_configs,omitempty"`
 
@@ -70,6 +75,8 @@ type Product struct {
 	// New Since v.1.18.0, env configs
 	AnalysisConfig      *AnalysisConfig       `bson:"analysis_config"      json:"analysis_config"`
 	NotificationConfigs []*NotificationConfig `bson:"notification_configs" json:"notification_configs"`
+	// DriftPolicy decides how the drift between the rendered services and the live cluster objects is handled
+	DriftPolicy *DriftPolicy `bson:"drift_policy,omitempty" json:"drift_policy,omitempty"`
 
 	// New Since v1.19.0, env sleep configs
 	PreSleepStatus map[string]int `bson:"pre_sleep_status" json:"pre_sleep_status"`
@@ -94,6 +101,7 @@ type NotificationEvent string
 const (
 	NotificationEventAnalyzerNoraml   NotificationEvent = "notification_event_analyzer_normal"
 	NotificationEventAnalyzerAbnormal NotificationEvent = "notification_event_analyzer_abnormal"
+	NotificationEventEnvDrift         NotificationEvent = "notification_event_env_drift"
 )
 
 type WebHookType string
@@ -102,12 +110,17 @@ const (
 	WebHookTypeFeishu   WebHookType = "feishu"
 	WebHookTypeDingding WebHookType = "dingding"
 	WebHookTypeWeChat   WebHookType = "wechat"
+	WebHookTypeSlack    WebHookType = "slack"
+	WebHookTypeMSTeams  WebHookType = "msteams"
+	WebHookTypeWebhook  WebHookType = "webhook"
 )
 
 type NotificationConfig struct {
-	WebHookType WebHookType         `bson:"webhook_type" json:"webhook_type"`
-	WebHookURL  string              `bson:"webhook_url"  json:"webhook_url"`
-	Events      []NotificationEvent `bson:"events"       json:"events"`
+	WebHookType WebHookType `bson:"webhook_type" json:"webhook_type"`
+	WebHookURL  string      `bson:"webhook_url"  json:"webhook_url"`
+	// WebHookSecret is only used by the generic webhook to sign the body
+	WebHookSecret string              `bson:"webhook_secret,omitempty" json:"webhook_secret,omitempty"`
+	Events        []NotificationEvent `bson:"events"       json:"events"`
 }
 
 type ResourceType string
@@ -130,6 +143,16 @@ type AnalysisConfig struct {
 	ResourceTypes []ResourceType `bson:"resource_types" json:"resource_types"`
 }
 
+type DriftPolicy struct {
+	Enabled bool `bson:"enabled"        json:"enabled"`
+	// Alert sends the detected drift through the notification configs which subscribe the drift event
+	Alert bool `bson:"alert"          json:"alert"`
+	// AutoRevert applies the rendered objects again to overwrite the drift
+	AutoRevert bool `bson:"auto_revert"    json:"auto_revert"`
+	// IgnoredFields are the field paths not checked for drift, e.g. spec.replicas or metadata.annotations
+	IgnoredFields []string `bson:"ignored_fields" json:"ignored_fields"`
+}
+
 type CreateUpdateCommonEnvCfgArgs struct {
 	EnvName              string                        `json:"env_name"`
 	ProductName          string                        `json:"product_name"`
@@ -211,6 +234,40 @@ type ProductShareEnv struct {
 	BaseEnv string `bson:"base_env" json:"base_env"`
 }
 
+type PreviewEnvPolicy struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// TTL is the max lifetime of the preview environments in hours, 0 means they are only destroyed when the pull request is closed
+	TTL   int               `bson:"ttl"   json:"ttl"`
+	Repos []*PreviewEnvRepo `bson:"repos" json:"repos"`
+}
+
+type PreviewEnvRepo struct {
+	// MainRepo decides the repo and the target branch of the pull requests
+	MainRepo *MainHookRepo        `bson:"main_repo" json:"main_repo"`
+	Services []*PreviewEnvService `bson:"services"  json:"services"`
+	// Workflow is the custom workflow which builds and deploys the changed services to the preview environment
+	// at the commit of the pull request, it is triggered whenever the pull request is opened or updated.
+	Workflow string `bson:"workflow"  json:"workflow"`
+}
+
+// PreviewEnvService is deployed in the preview environment if any of the changed files of the pull request is in the match folders
+type PreviewEnvService struct {
+	ServiceName  string   `bson:"service_name"  json:"service_name"`
+	MatchFolders []string `bson:"match_folders" json:"match_folders"`
+}
+
+type PreviewEnv struct {
+	CodehostID     int    `bson:"codehost_id"     json:"codehost_id"`
+	RepoOwner      string `bson:"repo_owner"      json:"repo_owner"`
+	RepoNamespace  string `bson:"repo_namespace"  json:"repo_namespace"`
+	RepoName       string `bson:"repo_name"       json:"repo_name"`
+	PrID           int    `bson:"pr_id"           json:"pr_id"`
+	CommitID       string `bson:"commit_id"       json:"commit_id"`
+	NotificationID string `bson:"notification_id" json:"notification_id"`
+	// ExpireTime is 0 if the preview environment never expires
+	ExpireTime int64 `bson:"expire_time" json:"expire_time"`
+}
+
 type IstioGrayscale struct {
 	Enable             bool                     `bson:"enable"   json:"enable"`
 	IsBase             bool                     `bson:"is_base"  json:"is_base"`
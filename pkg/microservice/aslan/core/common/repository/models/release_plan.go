@@ -43,6 +43,14 @@ type ReleasePlan struct {
 
 	Status config.ReleasePlanStatus `bson:"status"       yaml:"status"                   json:"status"`
 
+	// ScheduleExecuteTime is the time when the approved plan starts executing automatically,
+	// 0 means the plan starts executing as soon as it is approved
+	ScheduleExecuteTime int64 `bson:"schedule_execute_time"       yaml:"schedule_execute_time"                   json:"schedule_execute_time"`
+
+	// AutoExecution is set when all the release jobs are executed in the dependency order,
+	// it is cleared when all the jobs are done or any job fails
+	AutoExecution *ReleasePlanAutoExecution `bson:"auto_execution"       yaml:"auto_execution"                   json:"auto_execution,omitempty"`
+
 	PlanningTime  int64 `bson:"planning_time"       yaml:"planning_time"                   json:"planning_time"`
 	ApprovalTime  int64 `bson:"approval_time"       yaml:"approval_time"                   json:"approval_time"`
 	ExecutingTime int64 `bson:"executing_time"       yaml:"executing_time"                   json:"executing_time"`
@@ -53,11 +61,25 @@ func (ReleasePlan) TableName() string {
 	return "release_plan"
 }
 
+// ReleasePlanAutoExecution is the user who executes all the release jobs,
+// workflow release jobs are executed on behalf of the user
+type ReleasePlanAutoExecution struct {
+	UserID   string `bson:"user_id"       yaml:"user_id"                   json:"user_id"`
+	Account  string `bson:"account"       yaml:"account"                   json:"account"`
+	UserName string `bson:"user_name"       yaml:"user_name"                   json:"user_name"`
+	// IdentityType marks the executions started by service accounts, the tasks of the jobs are created as theirs
+	IdentityType string `bson:"identity_type"       yaml:"identity_type"                   json:"identity_type"`
+	StartTime    int64  `bson:"start_time"       yaml:"start_time"                   json:"start_time"`
+}
+
 type ReleaseJob struct {
 	ID   string                    `bson:"id"       yaml:"id"                   json:"id"`
 	Name string                    `bson:"name"       yaml:"name"                   json:"name"`
 	Type config.ReleasePlanJobType `bson:"type"       yaml:"type"                   json:"type"`
 	Spec interface{}               `bson:"spec"       yaml:"spec"                   json:"spec"`
+	// DependsOn is the ids of the release jobs which should be done before this job, the names of the jobs
+	// are accepted on create and update and replaced by their ids
+	DependsOn []string `bson:"depends_on"       yaml:"depends_on"                   json:"depends_on"`
 
 	ReleaseJobRuntime `bson:",inline" yaml:",inline" json:",inline"`
 }
@@ -71,6 +93,9 @@ type ReleaseJobRuntime struct {
 	Updated      bool   `bson:"updated"       yaml:"updated"                   json:"updated"`
 	ExecutedBy   string `bson:"executed_by"       yaml:"executed_by"                   json:"executed_by"`
 	ExecutedTime int64  `bson:"executed_time"       yaml:"executed_time"                   json:"executed_time"`
+	FinishedTime int64  `bson:"finished_time"       yaml:"finished_time"                   json:"finished_time"`
+	// Error is the reason why the release job failed
+	Error string `bson:"error"       yaml:"error"                   json:"error"`
 }
 
 type TextReleaseJobSpec struct {
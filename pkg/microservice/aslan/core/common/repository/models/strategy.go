@@ -25,6 +25,9 @@ const (
 	// 工作流任务的留存
 	WorkflowTaskRetention     CapacityTarget = "WorkflowTaskRetention"
 	DefaultWorkflowRemainDays int            = 365
+	// 环境快照的留存
+	EnvSnapshotRetention         CapacityTarget = "EnvSnapshotRetention"
+	DefaultEnvSnapshotRemainDays int            = 30
 )
 
 var DefaultWorkflowTaskRetention = &CapacityStrategy{
@@ -34,6 +37,13 @@ var DefaultWorkflowTaskRetention = &CapacityStrategy{
 	},
 }
 
+var DefaultEnvSnapshotRetention = &CapacityStrategy{
+	Target: EnvSnapshotRetention,
+	Retention: &RetentionConfig{
+		MaxDays: DefaultEnvSnapshotRemainDays,
+	},
+}
+
 // RetentionConfig 资源留存相关的配置
 type RetentionConfig struct {
 	MaxDays  int `bson:"max_days"      json:"max_days"`  // 最多几天
@@ -54,6 +54,10 @@ type WorkflowTask struct {
 	IsRestart           bool               `bson:"is_restart"                json:"is_restart"`
 	IsDebug             bool               `bson:"is_debug"                  json:"is_debug"`
 	ShareStorages       []*ShareStorage    `bson:"share_storages"            json:"share_storages"`
+	// PolicyViolations records the workflow policies violated when the task is created
+	PolicyViolations []*PolicyViolation `bson:"policy_violations"         json:"policy_violations,omitempty"`
+	// TaskCreatorIsServiceAccount marks the tasks created by service accounts, which are not humans
+	TaskCreatorIsServiceAccount bool `bson:"task_creator_is_service_account" json:"task_creator_is_service_account"`
 }
 
 func (WorkflowTask) TableName() string {
@@ -69,6 +73,9 @@ type StageTask struct {
 	Approval  *Approval     `bson:"approval"      json:"approval,omitempty"`
 	Jobs      []*JobTask    `bson:"jobs"          json:"jobs,omitempty"`
 	Error     string        `bson:"error"         json:"error"`
+	When      string        `bson:"when"          json:"when,omitempty"`
+	// SkipReason records why the stage was skipped by its condition
+	SkipReason string `bson:"skip_reason"   json:"skip_reason,omitempty"`
 }
 
 type JobTask struct {
@@ -94,6 +101,31 @@ type JobTask struct {
 	ServiceModules   []*WorkflowServiceModule `bson:"service_modules"     json:"service_modules"`
 	Infrastructure   string                   `bson:"infrastructure"      json:"infrastructure"`
 	VMLabels         []string                 `bson:"vm_labels"           json:"vm_labels"`
+	When             string                   `bson:"when"                json:"when,omitempty"`
+	// SkipReason records why the job was skipped by its condition
+	SkipReason string `bson:"skip_reason"         json:"skip_reason,omitempty"`
+	// OriginName is the name of the workflow job this job task is generated from
+	OriginName  string       `bson:"origin_name"         json:"origin_name"`
+	DependsOn   []string     `bson:"depends_on"          json:"depends_on,omitempty"`
+	RetryPolicy *RetryPolicy `bson:"retry_policy"        json:"retry_policy,omitempty"`
+	// Attempts is the history of all the attempts of the job when it is retried by its retry policy
+	Attempts []*JobAttempt `bson:"attempts"            json:"attempts,omitempty"`
+	// PolicyViolations records the workflow policies violated before the job runs
+	PolicyViolations []*PolicyViolation `bson:"policy_violations"   json:"policy_violations,omitempty"`
+	// VMJobID is the id of the vm job of the latest attempt, if the job runs on vm
+	VMJobID string `bson:"vm_job_id"           json:"vm_job_id,omitempty"`
+}
+
+type JobAttempt struct {
+	Attempt      int                    `bson:"attempt"        json:"attempt"`
+	Status       config.Status          `bson:"status"         json:"status"`
+	Error        string                 `bson:"error"          json:"error"`
+	FailureClass config.JobFailureClass `bson:"failure_class"  json:"failure_class,omitempty"`
+	StartTime    int64                  `bson:"start_time"     json:"start_time"`
+	EndTime      int64                  `bson:"end_time"       json:"end_time"`
+	K8sJobName   string                 `bson:"k8s_job_name"   json:"k8s_job_name"`
+	// LogName is the job name used to get the log of this attempt
+	LogName string `bson:"log_name"       json:"log_name"`
 }
 
 type TaskJobInfo struct {
@@ -117,6 +149,8 @@ type WorkflowTaskPreview struct {
 	EndTime             int64           `bson:"end_time"              json:"end_time,omitempty"`
 	WorkflowArgs        *WorkflowV4     `bson:"workflow_args"         json:"-"`
 	Stages              []*StagePreview `bson:"stages"                json:"stages,omitempty"`
+
+	TaskCreatorIsServiceAccount bool `bson:"task_creator_is_service_account" json:"task_creator_is_service_account"`
 }
 
 type StagePreview struct {
@@ -439,9 +473,26 @@ type NacosData struct {
 }
 
 type JobTaskSQLSpec struct {
-	ID   string                `bson:"id" json:"id" yaml:"id"`
-	Type config.DBInstanceType `bson:"type" json:"type" yaml:"type"`
-	SQL  string                `bson:"sql" json:"sql" yaml:"sql"`
+	ID      string                `bson:"id" json:"id" yaml:"id"`
+	Type    config.DBInstanceType `bson:"type" json:"type" yaml:"type"`
+	SQL     string                `bson:"sql" json:"sql" yaml:"sql"`
+	Results []*SQLExecResult      `bson:"results" json:"results" yaml:"results"`
+	// RollbackSQL and DryRun are the same as the ones in the SQLJobSpec
+	RollbackSQL     string           `bson:"rollback_sql" json:"rollback_sql" yaml:"rollback_sql"`
+	RollbackResults []*SQLExecResult `bson:"rollback_results" json:"rollback_results" yaml:"rollback_results"`
+	DryRun          bool             `bson:"dry_run" json:"dry_run" yaml:"dry_run"`
+	// ApprovedBy is the users who approved the stages before the sql was executed, for audit
+	ApprovedBy []string `bson:"approved_by" json:"approved_by" yaml:"approved_by"`
+}
+
+// SQLExecResult is the result of a statement of the sql job, or a batch for sql server
+type SQLExecResult struct {
+	SQL          string        `bson:"sql" json:"sql" yaml:"sql"`
+	Status       config.Status `bson:"status" json:"status" yaml:"status"`
+	RowsAffected int64         `bson:"rows_affected" json:"rows_affected" yaml:"rows_affected"`
+	// ElapsedTime is the duration in milliseconds
+	ElapsedTime int64  `bson:"elapsed_time" json:"elapsed_time" yaml:"elapsed_time"`
+	Error       string `bson:"error" json:"error" yaml:"error"`
 }
 
 type JobTaskApolloSpec struct {
@@ -614,4 +665,6 @@ type WorkflowTaskCtx struct {
 	GlobalContextEach           func(f func(k, v string) bool)
 	ClusterIDAdd                func(clusterID string)
 	SetStatus                   func(status config.Status)
+	// CheckJobPolicies evaluates the workflow policies before the job runs, the job must not run if it returns an error
+	CheckJobPolicies func(job *JobTask) error
 }
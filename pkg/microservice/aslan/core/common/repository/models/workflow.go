@@ -330,11 +330,23 @@ type NotifyCtl struct {
 	WeChatWebHook   string   `bson:"weChat_webHook,omitempty"      yaml:"weChat_webHook,omitempty"      json:"weChat_webHook,omitempty"`
 	DingDingWebHook string   `bson:"dingding_webhook,omitempty"    yaml:"dingding_webhook,omitempty"    json:"dingding_webhook,omitempty"`
 	FeiShuWebHook   string   `bson:"feishu_webhook,omitempty"      yaml:"feishu_webhook,omitempty"      json:"feishu_webhook,omitempty"`
+	SlackWebHook    string   `bson:"slack_webhook,omitempty"       yaml:"slack_webhook,omitempty"       json:"slack_webhook,omitempty"`
+	MSTeamsWebHook  string   `bson:"msteams_webhook,omitempty"     yaml:"msteams_webhook,omitempty"     json:"msteams_webhook,omitempty"`
 	AtMobiles       []string `bson:"at_mobiles,omitempty"          yaml:"at_mobiles,omitempty"          json:"at_mobiles,omitempty"`
 	WechatUserIDs   []string `bson:"wechat_user_ids,omitempty"     yaml:"wechat_user_ids,omitempty"     json:"wechat_user_ids,omitempty"`
 	LarkUserIDs     []string `bson:"lark_user_ids,omitempty"       yaml:"lark_user_ids,omitempty"       json:"lark_user_ids,omitempty"`
 	IsAtAll         bool     `bson:"is_at_all,omitempty"           yaml:"is_at_all,omitempty"           json:"is_at_all,omitempty"`
 	NotifyTypes     []string `bson:"notify_type"                   yaml:"notify_type"                   json:"notify_type"`
+	// TemplateID is the id of the notification template of the project, the built-in message is sent if it is empty
+	TemplateID string `bson:"template_id,omitempty"         yaml:"template_id,omitempty"         json:"template_id,omitempty"`
+	// WebHookNotify is the generic http webhook, the summary of the workflow task is posted to it as json
+	WebHookNotify *WebHookNotify `bson:"webhook_notify,omitempty"      yaml:"webhook_notify,omitempty"      json:"webhook_notify,omitempty"`
+}
+
+type WebHookNotify struct {
+	Address string `bson:"address"                       yaml:"address"                       json:"address"`
+	// Secret is used to sign the body with HMAC-SHA256, the signature is sent in the X-Zadig-Signature-256 header
+	Secret string `bson:"secret"                        yaml:"secret"                        json:"secret"`
 }
 
 type TaskInfo struct {
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+)
+
+// WorkflowCodeSource is the repository path of the WorkflowV4 yaml files of a project, the workflows are synced
+// from the files on push and can't be edited in zadig.
+type WorkflowCodeSource struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"            json:"id,omitempty"`
+	ProjectName   string             `bson:"project_name"             json:"project_name"`
+	Enabled       bool               `bson:"enabled"                  json:"enabled"`
+	CodehostID    int                `bson:"codehost_id"              json:"codehost_id"`
+	RepoOwner     string             `bson:"repo_owner"               json:"repo_owner"`
+	RepoNamespace string             `bson:"repo_namespace"           json:"repo_namespace"`
+	RepoName      string             `bson:"repo_name"                json:"repo_name"`
+	Branch        string             `bson:"branch"                   json:"branch"`
+	// Path is the directory of the workflow files, every .yaml or .yml file in it is a workflow
+	Path string `bson:"path"                     json:"path"`
+	// AdoptExisting allows the workflow files to take over the workflows with the same names created in zadig,
+	// the sync is rejected otherwise
+	AdoptExisting bool `bson:"adopt_existing"           json:"adopt_existing"`
+
+	LastSyncCommit string `bson:"last_sync_commit"         json:"last_sync_commit"`
+	LastSyncTime   int64  `bson:"last_sync_time"           json:"last_sync_time"`
+	LastSyncError  string `bson:"last_sync_error"          json:"last_sync_error"`
+	UpdatedBy      string `bson:"updated_by"               json:"updated_by"`
+	UpdateTime     int64  `bson:"update_time"              json:"update_time"`
+}
+
+func (s *WorkflowCodeSource) GetRepoNamespace() string {
+	if s.RepoNamespace != "" {
+		return s.RepoNamespace
+	}
+	return s.RepoOwner
+}
+
+// WorkflowV4CodeSource marks a workflow as synced from a workflow file, it's read-only in zadig
+type WorkflowV4CodeSource struct {
+	Path     string `bson:"path"         json:"path"`
+	CommitID string `bson:"commit_id"    json:"commit_id"`
+	// ContentHash is the sha256 of the workflow file that the workflow is synced from
+	ContentHash string `bson:"content_hash" json:"content_hash"`
+	// SyncTime equals the update time of the workflow when it's synced, the workflow is modified in zadig if they differ
+	SyncTime int64 `bson:"sync_time"    json:"sync_time"`
+}
+
+// WorkflowCodeChange is the change of a workflow if the workflow files in a commit are synced
+type WorkflowCodeChange struct {
+	WorkflowName string                    `bson:"workflow_name"          json:"workflow_name"`
+	Path         string                    `bson:"path"                   json:"path"`
+	Action       config.WorkflowCodeAction `bson:"action"                 json:"action"`
+	AddedJobs    []string                  `bson:"added_jobs,omitempty"   json:"added_jobs,omitempty"`
+	DeletedJobs  []string                  `bson:"deleted_jobs,omitempty" json:"deleted_jobs,omitempty"`
+	ChangedJobs  []string                  `bson:"changed_jobs,omitempty" json:"changed_jobs,omitempty"`
+	// Error is set if the workflow file is invalid, the whole sync is rejected then
+	Error string `bson:"error,omitempty"        json:"error,omitempty"`
+}
+
+// WorkflowCodeDrift is a workflow that differs between the repository and zadig
+type WorkflowCodeDrift struct {
+	WorkflowName string                       `json:"workflow_name"`
+	Path         string                       `json:"path"`
+	Type         config.WorkflowCodeDriftType `json:"type"`
+}
+
+func (WorkflowCodeSource) TableName() string {
+	return "workflow_code_source"
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+)
+
+// WorkflowPolicy is a rego policy evaluated by OPA when a workflow v4 task is created and before each job runs.
+// The policy must define a set called deny in its package, each message in the set is a violation.
+type WorkflowPolicy struct {
+	ID          primitive.ObjectID       `bson:"_id,omitempty"  json:"id,omitempty"`
+	Name        string                   `bson:"name"           json:"name"`
+	Description string                   `bson:"description"    json:"description"`
+	Enabled     bool                     `bson:"enabled"        json:"enabled"`
+	Enforcement config.PolicyEnforcement `bson:"enforcement"    json:"enforcement"`
+	// Projects limits the policy to the given projects, empty means all projects
+	Projects []string `bson:"projects"       json:"projects"`
+	Rego     string   `bson:"rego"           json:"rego"`
+	// Package is parsed from the rego, it is the path of the policy in OPA
+	Package    string `bson:"package"        json:"package"`
+	CreatedBy  string `bson:"created_by"     json:"created_by"`
+	CreateTime int64  `bson:"create_time"    json:"create_time"`
+	UpdatedBy  string `bson:"updated_by"     json:"updated_by"`
+	UpdateTime int64  `bson:"update_time"    json:"update_time"`
+}
+
+func (WorkflowPolicy) TableName() string {
+	return "workflow_policy"
+}
+
+// WorkflowPolicyDecision is the audit record of a workflow policy evaluation
+type WorkflowPolicyDecision struct {
+	ID           primitive.ObjectID       `bson:"_id,omitempty"  json:"id,omitempty"`
+	PolicyName   string                   `bson:"policy_name"    json:"policy_name"`
+	Enforcement  config.PolicyEnforcement `bson:"enforcement"    json:"enforcement"`
+	Checkpoint   config.PolicyCheckpoint  `bson:"checkpoint"     json:"checkpoint"`
+	ProjectName  string                   `bson:"project_name"   json:"project_name"`
+	WorkflowName string                   `bson:"workflow_name"  json:"workflow_name"`
+	TaskID       int64                    `bson:"task_id"        json:"task_id"`
+	JobName      string                   `bson:"job_name"       json:"job_name"`
+	User         string                   `bson:"user"           json:"user"`
+	Allowed      bool                     `bson:"allowed"        json:"allowed"`
+	Messages     []string                 `bson:"messages"       json:"messages"`
+	// Error is set if the policy could not be evaluated, block policies deny in this case
+	Error      string `bson:"error"          json:"error"`
+	CreateTime int64  `bson:"create_time"    json:"create_time"`
+}
+
+func (WorkflowPolicyDecision) TableName() string {
+	return "workflow_policy_decision"
+}
+
+// PolicyViolation is a denied decision shown in the task detail
+type PolicyViolation struct {
+	PolicyName  string                   `bson:"policy_name"    json:"policy_name"`
+	Enforcement config.PolicyEnforcement `bson:"enforcement"    json:"enforcement"`
+	Checkpoint  config.PolicyCheckpoint  `bson:"checkpoint"     json:"checkpoint"`
+	Messages    []string                 `bson:"messages"       json:"messages"`
+}
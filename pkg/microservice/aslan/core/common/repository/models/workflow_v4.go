@@ -65,6 +65,10 @@ type WorkflowV4 struct {
 	// -1 means no limit
 	ConcurrencyLimit int          `bson:"concurrency_limit"   yaml:"concurrency_limit"   json:"concurrency_limit"`
 	CustomField      *CustomField `bson:"custom_field"        yaml:"-"                   json:"custom_field"`
+	// RetryPolicy is the default retry policy of all the jobs in the workflow, it can be overridden by the retry policy of a job
+	RetryPolicy *RetryPolicy `bson:"retry_policy"        yaml:"retry_policy,omitempty" json:"retry_policy"`
+	// CodeSource is set if the workflow is synced from a workflow file in the repository, it can't be edited in zadig then
+	CodeSource *WorkflowV4CodeSource `bson:"code_source,omitempty" yaml:"-"                   json:"code_source,omitempty"`
 }
 
 func (w *WorkflowV4) UpdateHash() {
@@ -73,7 +77,7 @@ func (w *WorkflowV4) UpdateHash() {
 
 func (w *WorkflowV4) CalculateHash() [md5.Size]byte {
 	fieldList := make(map[string]interface{})
-	ignoringFieldList := []string{"CreatedBy", "CreateTime", "UpdatedBy", "UpdateTime", "Description", "Hash"}
+	ignoringFieldList := []string{"CreatedBy", "CreateTime", "UpdatedBy", "UpdateTime", "Description", "Hash", "CodeSource"}
 	ignoringFields := sets.NewString(ignoringFieldList...)
 
 	val := reflect.ValueOf(*w)
@@ -94,7 +98,9 @@ type WorkflowStage struct {
 	Name     string    `bson:"name"          yaml:"name"         json:"name"`
 	Parallel bool      `bson:"parallel"      yaml:"parallel"     json:"parallel"`
 	Approval *Approval `bson:"approval"      yaml:"approval"     json:"approval"`
-	Jobs     []*Job    `bson:"jobs"          yaml:"jobs"         json:"jobs"`
+	// When is a condition expression, the stage will be skipped if it evaluates to false.
+	When string `bson:"when"          yaml:"when,omitempty"   json:"when"`
+	Jobs []*Job `bson:"jobs"          yaml:"jobs"         json:"jobs"`
 }
 
 type Approval struct {
@@ -204,6 +210,9 @@ type User struct {
 	RejectOrApprove config.ApproveOrReject `bson:"reject_or_approve"           yaml:"-"                          json:"reject_or_approve"`
 	Comment         string                 `bson:"comment"                     yaml:"-"                          json:"comment"`
 	OperationTime   int64                  `bson:"operation_time"              yaml:"-"                          json:"operation_time"`
+
+	// IsServiceAccount marks the approvals done by service accounts, which are not humans
+	IsServiceAccount bool `bson:"is_service_account" yaml:"-" json:"is_service_account"`
 }
 
 type Job struct {
@@ -214,6 +223,25 @@ type Job struct {
 	Spec           interface{}              `bson:"spec"           yaml:"spec"       json:"spec"`
 	RunPolicy      config.JobRunPolicy      `bson:"run_policy"     yaml:"run_policy" json:"run_policy"`
 	ServiceModules []*WorkflowServiceModule `bson:"service_modules"                  json:"service_modules"`
+	// When is a condition expression evaluated right before the job runs, the job will be skipped if it evaluates to false.
+	// workflow params, global variables and outputs of previous jobs can be used, e.g. '{{.workflow.params.branch}}' !~ '^docs/'
+	When string `bson:"when"           yaml:"when,omitempty" json:"when"`
+	// DependsOn is the names of the jobs this job waits for, jobs can depend on jobs in any stage.
+	// once a job in the workflow declares it, the workflow is scheduled by the dependency graph instead of stage by stage.
+	DependsOn []string `bson:"depends_on"     yaml:"depends_on,omitempty" json:"depends_on"`
+	// RetryPolicy overrides the retry policy of the workflow for this job
+	RetryPolicy *RetryPolicy `bson:"retry_policy"   yaml:"retry_policy,omitempty" json:"retry_policy"`
+}
+
+// RetryPolicy decides whether a failed job should be run again
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first run, 0 or 1 means no retry
+	MaxAttempts int                 `bson:"max_attempts" yaml:"max_attempts" json:"max_attempts"`
+	Backoff     config.RetryBackoff `bson:"backoff"      yaml:"backoff"      json:"backoff"`
+	// Interval is the seconds to wait before the first retry, the interval doubles for every retry if the backoff is exponential
+	Interval int64 `bson:"interval"     yaml:"interval"     json:"interval"`
+	// RetryOn is the failure classes that should be retried, all failures are retried if it is empty
+	RetryOn []config.JobFailureClass `bson:"retry_on"     yaml:"retry_on"     json:"retry_on"`
 }
 
 type WorkflowServiceModule struct {
@@ -581,6 +609,10 @@ type SQLJobSpec struct {
 	Type   config.DBInstanceType `bson:"type" json:"type" yaml:"type"`
 	SQL    string                `bson:"sql" json:"sql" yaml:"sql"`
 	Source string                `bson:"source" json:"source" yaml:"source"`
+	// RollbackSQL is executed automatically when the sql fails, it is optional
+	RollbackSQL string `bson:"rollback_sql" json:"rollback_sql" yaml:"rollback_sql"`
+	// DryRun executes the sql in a transaction and rolls it back, only for the databases supporting transactional DDL
+	DryRun bool `bson:"dry_run" json:"dry_run" yaml:"dry_run"`
 }
 
 type ApolloJobSpec struct {
@@ -787,6 +819,9 @@ type JobProperties struct {
 	ShareStorageInfo    *ShareStorageInfo    `bson:"share_storage_info"     json:"share_storage_info"    yaml:"share_storage_info"`
 	ShareStorageDetails []*StorageDetail     `bson:"share_storage_details"  json:"share_storage_details" yaml:"-"`
 	UseHostDockerDaemon bool                 `bson:"use_host_docker_daemon,omitempty" json:"use_host_docker_daemon,omitempty" yaml:"use_host_docker_daemon"`
+
+	// RunInContainer is only used by vm jobs, the agent runs the job in a container of the job image
+	RunInContainer bool `bson:"run_in_container,omitempty" json:"run_in_container,omitempty" yaml:"run_in_container,omitempty"`
 }
 
 type Step struct {
@@ -117,12 +117,15 @@ func (c *DBInstanceColl) Update(id string, args *models.DBInstance) error {
 	args.UpdatedAt = time.Now().Unix()
 	query := bson.M{"_id": oid}
 	change := bson.M{"$set": bson.M{
+		"type":       args.Type,
 		"name":       args.Name,
 		"host":       args.Host,
 		"port":       args.Port,
 		"projects":   args.Projects,
 		"username":   args.Username,
 		"password":   args.Password,
+		"database":   args.Database,
+		"ssl_mode":   args.SSLMode,
 		"update_by":  args.UpdateBy,
 		"updated_at": time.Now().Unix(),
 	}}
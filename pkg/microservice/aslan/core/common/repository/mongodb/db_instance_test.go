@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func TestDBInstanceCollUpdateRoundTrip(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("update", func(mt *mtest.T) {
+		coll := &DBInstanceColl{Collection: mt.Coll}
+		id := primitive.NewObjectID()
+		args := &models.DBInstance{
+			Type:     config.DBInstanceTypePostgreSQL,
+			Name:     "orders",
+			Projects: []string{"shop"},
+			Host:     "pg.example.com",
+			Port:     "5432",
+			Username: "admin",
+			Password: "secret",
+			Database: "orders",
+			SSLMode:  "verify-full",
+			UpdateBy: "admin",
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		require.NoError(t, coll.Update(id.Hex(), args))
+
+		started := mt.GetStartedEvent()
+		require.NotNil(t, started)
+		require.Equal(t, "update", started.CommandName)
+		set := bson.D{}
+		require.NoError(t, bson.Unmarshal(started.Command.Lookup("updates", "0", "u", "$set").Document(), &set))
+
+		// the document written by the update is served back by find
+		stored := append(bson.D{{Key: "_id", Value: id}}, set...)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.Coll.Database().Name()+"."+mt.Coll.Name(), mtest.FirstBatch, stored))
+		found, err := coll.Find(&DBInstanceCollFindOption{Id: id.Hex()})
+		require.NoError(t, err)
+
+		assert.Equal(t, id, found.ID)
+		assert.Equal(t, args.Type, found.Type)
+		assert.Equal(t, args.Name, found.Name)
+		assert.Equal(t, args.Projects, found.Projects)
+		assert.Equal(t, args.Host, found.Host)
+		assert.Equal(t, args.Port, found.Port)
+		assert.Equal(t, args.Username, found.Username)
+		assert.Equal(t, args.Password, found.Password)
+		assert.Equal(t, args.Database, found.Database)
+		assert.Equal(t, args.SSLMode, found.SSLMode)
+		assert.Equal(t, args.UpdateBy, found.UpdateBy)
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type EnvDriftColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvDriftColl() *EnvDriftColl {
+	name := models.EnvDrift{}.TableName()
+	return &EnvDriftColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvDriftColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvDriftColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "production", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func envDriftQuery(projectName, envName string, production bool) bson.M {
+	return bson.M{
+		"project_name": projectName,
+		"env_name":     envName,
+		"production":   production,
+	}
+}
+
+// List returns the drifts of the environment sorted by service and object
+func (c *EnvDriftColl) List(projectName, envName string, production bool) ([]*models.EnvDrift, error) {
+	resp := make([]*models.EnvDrift, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "service_name", Value: 1}, {Key: "kind", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := c.Find(context.TODO(), envDriftQuery(projectName, envName, production), opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Replace replaces all the drifts of the environment with the given ones
+func (c *EnvDriftColl) Replace(projectName, envName string, production bool, drifts []*models.EnvDrift) error {
+	if _, err := c.DeleteMany(context.TODO(), envDriftQuery(projectName, envName, production)); err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(drifts))
+	for _, drift := range drifts {
+		docs = append(docs, drift)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+func (c *EnvDriftColl) DeleteByEnv(projectName, envName string, production bool) error {
+	_, err := c.DeleteMany(context.TODO(), envDriftQuery(projectName, envName, production))
+	return err
+}
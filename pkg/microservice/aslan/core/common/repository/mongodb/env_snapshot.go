@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type EnvSnapshotColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSnapshotColl() *EnvSnapshotColl {
+	name := models.EnvSnapshot{}.TableName()
+	return &EnvSnapshotColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvSnapshotColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSnapshotColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "env_name", Value: 1},
+				bson.E{Key: "production", Value: 1},
+				bson.E{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "create_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *EnvSnapshotColl) Create(args *models.EnvSnapshot) error {
+	if args == nil {
+		return errors.New("env snapshot is nil")
+	}
+
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = id
+	}
+	return nil
+}
+
+func (c *EnvSnapshotColl) GetByID(idStr string) (*models.EnvSnapshot, error) {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.EnvSnapshot)
+	err = c.FindOne(context.TODO(), bson.M{"_id": id}).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSnapshotColl) DeleteByID(idStr string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+// List returns the snapshots of the environment without the recorded content, the latest snapshot comes first
+func (c *EnvSnapshotColl) List(projectName, envName string, production bool) ([]*models.EnvSnapshot, error) {
+	query := bson.M{
+		"project_name": projectName,
+		"env_name":     envName,
+		"production":   production,
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "create_time", Value: -1}}).
+		SetProjection(bson.M{"product": 0})
+
+	resp := make([]*models.EnvSnapshot, 0)
+	cursor, err := c.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListExpiredIDs returns the IDs of the snapshots of the environment older than maxDays or exceeding the latest maxItems ones,
+// zero value of maxItems or maxDays means no limit.
+func (c *EnvSnapshotColl) ListExpiredIDs(projectName, envName string, production bool, maxItems, maxDays int) ([]string, error) {
+	query := bson.M{
+		"project_name": projectName,
+		"env_name":     envName,
+		"production":   production,
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "create_time", Value: -1}}).
+		SetProjection(bson.M{"_id": 1, "create_time": 1})
+
+	cursor, err := c.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*models.EnvSnapshot, 0)
+	if err := cursor.All(context.TODO(), &snapshots); err != nil {
+		return nil, err
+	}
+
+	resp := make([]string, 0)
+	for i, snapshot := range snapshots {
+		if (maxItems > 0 && i >= maxItems) ||
+			(maxDays > 0 && snapshot.CreateTime < time.Now().AddDate(0, 0, -maxDays).Unix()) {
+			resp = append(resp, snapshot.ID.Hex())
+		}
+	}
+	return resp, nil
+}
+
+func (c *EnvSnapshotColl) DeleteByIDs(idStrs []string) error {
+	if len(idStrs) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	_, err := c.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
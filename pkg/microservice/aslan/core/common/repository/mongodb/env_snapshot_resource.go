@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type EnvSnapshotResourceColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSnapshotResourceColl() *EnvSnapshotResourceColl {
+	name := models.EnvSnapshotResource{}.TableName()
+	return &EnvSnapshotResourceColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvSnapshotResourceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSnapshotResourceColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "snapshot_id", Value: 1},
+			bson.E{Key: "kind", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvSnapshotResourceColl) BulkCreate(snapshotID string, args []*models.EnvSnapshotResource) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(args))
+	for _, resource := range args {
+		resource.SnapshotID = snapshotID
+		docs = append(docs, resource)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+// ListBySnapshot returns the resources of the snapshot sorted by kind and name
+func (c *EnvSnapshotResourceColl) ListBySnapshot(snapshotID string) ([]*models.EnvSnapshotResource, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "kind", Value: 1}, {Key: "name", Value: 1}})
+
+	resp := make([]*models.EnvSnapshotResource, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{"snapshot_id": snapshotID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvSnapshotResourceColl) DeleteBySnapshots(snapshotIDs []string) error {
+	if len(snapshotIDs) == 0 {
+		return nil
+	}
+
+	_, err := c.DeleteMany(context.TODO(), bson.M{"snapshot_id": bson.M{"$in": snapshotIDs}})
+	return err
+}
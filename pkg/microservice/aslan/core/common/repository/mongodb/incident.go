@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type IncidentColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewIncidentColl() *IncidentColl {
+	name := models.Incident{}.TableName()
+	return &IncidentColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *IncidentColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *IncidentColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "occurred_at", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "occurred_at", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *IncidentColl) Create(ctx context.Context, args *models.Incident) error {
+	if args == nil {
+		return errors.New("incident is nil")
+	}
+
+	_, err := c.InsertOne(ctx, args)
+	return err
+}
+
+func (c *IncidentColl) GetByID(ctx context.Context, idStr string) (*models.Incident, error) {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.Incident)
+	err = c.FindOne(ctx, bson.M{"_id": id}).Decode(resp)
+	return resp, err
+}
+
+func (c *IncidentColl) Resolve(ctx context.Context, idStr string, resolvedAt, updateTime int64) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return err
+	}
+
+	change := bson.M{"$set": bson.M{
+		"status":      config.IncidentStatusResolved,
+		"resolved_at": resolvedAt,
+		"update_time": updateTime,
+	}}
+	_, err = c.UpdateOne(ctx, bson.M{"_id": id}, change)
+	return err
+}
+
+type IncidentListOption struct {
+	// ProjectNames filters the incidents of the given projects, all projects are listed if it is empty
+	ProjectNames []string
+	StartTime    int64
+	EndTime      int64
+	Status       config.IncidentStatus
+}
+
+// List returns the incidents occurred in [StartTime, EndTime), the latest incident comes first
+func (c *IncidentColl) List(opt *IncidentListOption) ([]*models.Incident, error) {
+	if opt == nil {
+		opt = &IncidentListOption{}
+	}
+
+	query := bson.M{}
+	if len(opt.ProjectNames) != 0 {
+		query["project_name"] = bson.M{"$in": opt.ProjectNames}
+	}
+	if opt.StartTime != 0 || opt.EndTime != 0 {
+		timeQuery := bson.M{}
+		if opt.StartTime != 0 {
+			timeQuery["$gte"] = opt.StartTime
+		}
+		if opt.EndTime != 0 {
+			timeQuery["$lt"] = opt.EndTime
+		}
+		query["occurred_at"] = timeQuery
+	}
+	if opt.Status != "" {
+		query["status"] = opt.Status
+	}
+
+	resp := make([]*models.Incident, 0)
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{Key: "occurred_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
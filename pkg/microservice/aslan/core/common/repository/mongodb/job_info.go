@@ -156,6 +156,30 @@ func (c *JobInfoColl) GetDeployJobs(startTime, endTime int64, projectName string
 	return resp, err
 }
 
+// GetLatestDeployJob returns the latest passed deploy job of the project finished before the given time,
+// the target env is ignored if it is empty
+func (c *JobInfoColl) GetLatestDeployJob(projectName, envName string, before int64) (*models.JobInfo, error) {
+	query := bson.M{
+		"product_name": projectName,
+		"status":       string(config.StatusPassed),
+		"end_time":     bson.M{"$lte": before},
+	}
+	query["type"] = bson.M{"$in": []string{
+		string(config.JobZadigDeploy),
+		string(config.JobZadigHelmDeploy),
+		string(config.JobZadigHelmChartDeploy),
+		string(config.JobDeploy),
+	}}
+	if len(envName) != 0 {
+		query["target_env"] = envName
+	}
+
+	resp := new(models.JobInfo)
+	opts := options.FindOne().SetSort(bson.D{{Key: "end_time", Value: -1}})
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	return resp, err
+}
+
 type JobInfoCoarseGrainedData struct {
 	StartTime   int64             `json:"start_time"`
 	EndTime     int64             `json:"end_time"`
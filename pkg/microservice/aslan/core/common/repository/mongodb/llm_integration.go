@@ -88,6 +88,19 @@ func (c *LLMIntegrationColl) Update(ctx context.Context, id string, args *models
 	return err
 }
 
+// IncUsage adds the tokens used by the requests to the usage of the integration
+func (c *LLMIntegrationColl) IncUsage(ctx context.Context, id primitive.ObjectID, usage *models.LLMUsage) error {
+	query := bson.M{"_id": id}
+	change := bson.M{"$inc": bson.M{
+		"usage.requests":          usage.Requests,
+		"usage.prompt_tokens":     usage.PromptTokens,
+		"usage.completion_tokens": usage.CompletionTokens,
+		"usage.total_tokens":      usage.TotalTokens,
+	}}
+	_, err := c.UpdateOne(ctx, query, change)
+	return err
+}
+
 func (c *LLMIntegrationColl) Delete(ctx context.Context, id string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -89,3 +89,17 @@ func (c *NotificationColl) Upsert(args *models.Notification) error {
 
 	return err
 }
+
+// FindWorkflowCodeByPR returns the comment of the workflow changes in the pull request
+func (c *NotificationColl) FindWorkflowCodeByPR(codehostID int, projectID string, prID int) (*models.Notification, error) {
+	res := &models.Notification{}
+	query := bson.M{
+		"codehost_id":      codehostID,
+		"project_id":       projectID,
+		"pr_id":            prID,
+		"is_workflow_code": true,
+	}
+	err := c.FindOne(context.TODO(), query).Decode(res)
+
+	return res, err
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type NotificationTemplateColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewNotificationTemplateColl() *NotificationTemplateColl {
+	name := models.NotificationTemplate{}.TableName()
+	return &NotificationTemplateColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *NotificationTemplateColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *NotificationTemplateColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *NotificationTemplateColl) Create(args *models.NotificationTemplate) error {
+	if args == nil {
+		return errors.New("nil notification template args")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *NotificationTemplateColl) Update(args *models.NotificationTemplate) error {
+	if args == nil {
+		return errors.New("nil notification template args")
+	}
+	if args.ID.IsZero() {
+		return errors.New("empty notification template id")
+	}
+	filter := bson.M{"_id": args.ID}
+	update := bson.M{"$set": args}
+
+	_, err := c.UpdateOne(context.TODO(), filter, update)
+	return err
+}
+
+func (c *NotificationTemplateColl) ListByProject(projectName string) ([]*models.NotificationTemplate, error) {
+	resp := make([]*models.NotificationTemplate, 0)
+	ctx := context.Background()
+	query := bson.M{"project_name": projectName}
+	opts := options.Find().SetSort(bson.D{{Key: "create_time", Value: -1}})
+
+	cur, err := c.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return resp, err
+	}
+	if err := cur.All(ctx, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (c *NotificationTemplateColl) FindByID(idString string) (*models.NotificationTemplate, error) {
+	resp := new(models.NotificationTemplate)
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"_id": id}
+
+	err = c.FindOne(context.TODO(), query).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *NotificationTemplateColl) FindByName(projectName, name string) (*models.NotificationTemplate, error) {
+	resp := new(models.NotificationTemplate)
+	query := bson.M{"project_name": projectName, "name": name}
+
+	err := c.FindOne(context.TODO(), query).Decode(&resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *NotificationTemplateColl) DeleteByID(idString string) error {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return err
+	}
+	query := bson.M{"_id": id}
+
+	if _, err := c.DeleteOne(context.TODO(), query); err != nil {
+		return err
+	}
+	return nil
+}
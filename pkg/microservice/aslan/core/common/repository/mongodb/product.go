@@ -666,15 +666,95 @@ func (c *ProductColl) ListEnvByNamespace(clusterID, namespace string) ([]*models
 	return resp, nil
 }
 
-func (c *ProductColl) UpdateConfigs(envName, productName string, analysisConfig *models.AnalysisConfig, notificationConfigs []*models.NotificationConfig) error {
+func (c *ProductColl) UpdateConfigs(envName, productName string, analysisConfig *models.AnalysisConfig, notificationConfigs []*models.NotificationConfig, driftPolicy *models.DriftPolicy) error {
 	query := bson.M{"env_name": envName, "product_name": productName}
 
-	change := bson.M{"$set": bson.M{
+	set := bson.M{
 		"analysis_config":      analysisConfig,
 		"notification_configs": notificationConfigs,
 		"update_time":          time.Now().Unix(),
+	}
+	// the drift policy is kept as is if it is not specified
+	if driftPolicy != nil {
+		set["drift_policy"] = driftPolicy
+	}
+	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": set})
+
+	return err
+}
+
+func (c *ProductColl) UpdatePreviewEnvPolicy(envName, productName string, policy *models.PreviewEnvPolicy) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+
+	change := bson.M{"$set": bson.M{
+		"preview_env_policy": policy,
+		"update_time":        time.Now().Unix(),
 	}}
 	_, err := c.UpdateOne(context.TODO(), query, change)
 
 	return err
 }
+
+func (c *ProductColl) UpdatePreviewEnv(envName, productName string, previewEnv *models.PreviewEnv) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+
+	change := bson.M{"$set": bson.M{
+		"preview_env": previewEnv,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+// ListPreviewEnvBases lists the base environments with the preview environment policy enabled
+func (c *ProductColl) ListPreviewEnvBases() ([]*models.Product, error) {
+	var resp []*models.Product
+	query := bson.M{"preview_env_policy.enabled": true, "status": bson.M{"$ne": setting.ProductStatusDeleting}}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListPreviewEnvsByPR lists the preview environments created by the pull request
+func (c *ProductColl) ListPreviewEnvsByPR(codehostID int, repoNamespace, repoName string, prID int) ([]*models.Product, error) {
+	var resp []*models.Product
+	query := bson.M{
+		"preview_env.codehost_id":    codehostID,
+		"preview_env.repo_namespace": repoNamespace,
+		"preview_env.repo_name":      repoName,
+		"preview_env.pr_id":          prID,
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListExpiredPreviewEnvs lists the preview environments expired before the given time
+func (c *ProductColl) ListExpiredPreviewEnvs(before int64) ([]*models.Product, error) {
+	var resp []*models.Product
+	query := bson.M{
+		"preview_env.expire_time": bson.M{"$gt": 0, "$lt": before},
+		"status":                  bson.M{"$ne": setting.ProductStatusDeleting},
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type WorkflowCodeSourceColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowCodeSourceColl() *WorkflowCodeSourceColl {
+	name := models.WorkflowCodeSource{}.TableName()
+	return &WorkflowCodeSourceColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowCodeSourceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowCodeSourceColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "repo_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WorkflowCodeSourceColl) Find(projectName string) (*models.WorkflowCodeSource, error) {
+	resp := new(models.WorkflowCodeSource)
+	err := c.FindOne(context.TODO(), bson.M{"project_name": projectName}).Decode(resp)
+	return resp, err
+}
+
+// Upsert saves the workflow code source of the project, the sync result is kept
+func (c *WorkflowCodeSourceColl) Upsert(args *models.WorkflowCodeSource) error {
+	if args == nil {
+		return errors.New("workflow code source is nil")
+	}
+
+	query := bson.M{"project_name": args.ProjectName}
+	change := bson.M{"$set": bson.M{
+		"enabled":        args.Enabled,
+		"codehost_id":    args.CodehostID,
+		"repo_owner":     args.RepoOwner,
+		"repo_namespace": args.RepoNamespace,
+		"repo_name":      args.RepoName,
+		"branch":         args.Branch,
+		"path":           args.Path,
+		"adopt_existing": args.AdoptExisting,
+		"updated_by":     args.UpdatedBy,
+		"update_time":    args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *WorkflowCodeSourceColl) UpdateSyncResult(projectName, commitID, syncErr string, syncTime int64) error {
+	query := bson.M{"project_name": projectName}
+	change := bson.M{"$set": bson.M{
+		"last_sync_commit": commitID,
+		"last_sync_error":  syncErr,
+		"last_sync_time":   syncTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *WorkflowCodeSourceColl) Delete(projectName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
+
+// ListByRepo returns the enabled workflow code sources in the repository, they are matched by the webhook events
+func (c *WorkflowCodeSourceColl) ListByRepo(repoName string) ([]*models.WorkflowCodeSource, error) {
+	query := bson.M{
+		"enabled":   true,
+		"repo_name": repoName,
+	}
+
+	resp := make([]*models.WorkflowCodeSource, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
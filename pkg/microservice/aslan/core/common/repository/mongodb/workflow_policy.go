@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type WorkflowPolicyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowPolicyColl() *WorkflowPolicyColl {
+	name := models.WorkflowPolicy{}.TableName()
+	return &WorkflowPolicyColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowPolicyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowPolicyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Create(args *models.WorkflowPolicy) error {
+	if args == nil {
+		return errors.New("workflow policy is nil")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Update(args *models.WorkflowPolicy) error {
+	if args == nil {
+		return errors.New("workflow policy is nil")
+	}
+
+	query := bson.M{"name": args.Name}
+	change := bson.M{"$set": bson.M{
+		"description": args.Description,
+		"enabled":     args.Enabled,
+		"enforcement": args.Enforcement,
+		"projects":    args.Projects,
+		"rego":        args.Rego,
+		"package":     args.Package,
+		"updated_by":  args.UpdatedBy,
+		"update_time": args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Find(name string) (*models.WorkflowPolicy, error) {
+	resp := new(models.WorkflowPolicy)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+// List returns the workflow policies, only the enabled ones are returned if onlyEnabled is true
+func (c *WorkflowPolicyColl) List(onlyEnabled bool) ([]*models.WorkflowPolicy, error) {
+	query := bson.M{}
+	if onlyEnabled {
+		query["enabled"] = true
+	}
+
+	resp := make([]*models.WorkflowPolicy, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *WorkflowPolicyColl) Delete(name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"name": name})
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
+)
+
+type WorkflowPolicyDecisionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowPolicyDecisionColl() *WorkflowPolicyDecisionColl {
+	name := models.WorkflowPolicyDecision{}.TableName()
+	return &WorkflowPolicyDecisionColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowPolicyDecisionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowPolicyDecisionColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "policy_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WorkflowPolicyDecisionColl) Create(args *models.WorkflowPolicyDecision) error {
+	if args == nil {
+		return errors.New("workflow policy decision is nil")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+type ListWorkflowPolicyDecisionOption struct {
+	PolicyName   string
+	ProjectName  string
+	WorkflowName string
+	TaskID       int64
+	// Allowed filters the decisions by the result if it is not nil
+	Allowed  *bool
+	PageNum  int64
+	PageSize int64
+}
+
+func (c *WorkflowPolicyDecisionColl) List(opt *ListWorkflowPolicyDecisionOption) ([]*models.WorkflowPolicyDecision, int64, error) {
+	if opt == nil {
+		return nil, 0, errors.New("nil ListOption")
+	}
+
+	query := bson.M{}
+	if opt.PolicyName != "" {
+		query["policy_name"] = opt.PolicyName
+	}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.WorkflowName != "" {
+		query["workflow_name"] = opt.WorkflowName
+	}
+	if opt.TaskID > 0 {
+		query["task_id"] = opt.TaskID
+	}
+	if opt.Allowed != nil {
+		query["allowed"] = *opt.Allowed
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "create_time", Value: -1}})
+	if opt.PageNum > 0 && opt.PageSize > 0 {
+		opts.SetSkip((opt.PageNum - 1) * opt.PageSize)
+		opts.SetLimit(opt.PageSize)
+	}
+
+	count, err := c.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp := make([]*models.WorkflowPolicyDecision, 0)
+	cursor, err := c.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, count, err
+}
@@ -47,6 +47,8 @@ type ListWorkflowV4Option struct {
 	Category       setting.WorkflowCategory
 	JobTypes       []config.JobType
 	Infrastructure string
+	// CodeManaged returns only the workflows synced from the workflow files in the repository
+	CodeManaged bool
 }
 
 func NewWorkflowV4Coll() *WorkflowV4Coll {
@@ -241,6 +243,9 @@ func (c *WorkflowV4Coll) List(opt *ListWorkflowV4Option, pageNum, pageSize int64
 	if len(opt.JobTypes) > 0 {
 		query["stages.jobs.type"] = bson.M{"$in": opt.JobTypes}
 	}
+	if opt.CodeManaged {
+		query["code_source"] = bson.M{"$ne": nil}
+	}
 	count, err := c.CountDocuments(context.TODO(), query)
 	if err != nil {
 		return nil, count, err
@@ -307,6 +312,14 @@ func (c *WorkflowV4Coll) Update(idString string, obj *models.WorkflowV4) error {
 	return err
 }
 
+// UpdateCodeSource sets the code source of the workflow, the workflow becomes editable if it's nil
+func (c *WorkflowV4Coll) UpdateCodeSource(name string, codeSource *models.WorkflowV4CodeSource) error {
+	query := bson.M{"name": name}
+	change := bson.M{"$set": bson.M{"code_source": codeSource}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *WorkflowV4Coll) DeleteByID(idString string) error {
 	id, err := primitive.ObjectIDFromHex(idString)
 	if err != nil {
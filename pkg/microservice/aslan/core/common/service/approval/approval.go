@@ -23,6 +23,7 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/setting"
 )
 
 type ApproveMap struct {
@@ -79,7 +80,7 @@ func (c *ApproveWithLock) IsApproval() (bool, int, error) {
 	return false, ApproveCount, nil
 }
 
-func (c *ApproveWithLock) DoApproval(userName, userID, comment string, appvove bool) error {
+func (c *ApproveWithLock) DoApproval(userName, userID, identityType, comment string, appvove bool) error {
 	c.Lock()
 	defer c.Unlock()
 	for _, user := range c.Approval.ApproveUsers {
@@ -91,6 +92,7 @@ func (c *ApproveWithLock) DoApproval(userName, userID, comment string, appvove b
 		}
 		user.Comment = comment
 		user.OperationTime = time.Now().Unix()
+		user.IsServiceAccount = identityType == setting.ServiceAccountIdentityType
 		if appvove {
 			user.RejectOrApprove = config.Approve
 			return nil
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+// Client wraps the clients of bitbucket cloud and bitbucket data center/server,
+// the owner of a repository is the workspace on bitbucket cloud and the project key on bitbucket data center.
+type Client struct {
+	Cloud  *bitbucket.Client
+	Server *bitbucket.ServerClient
+}
+
+func NewClient(source, address, username, token, proxyAddress string, enableProxy bool) *Client {
+	if source == setting.SourceFromBitbucketServer {
+		return &Client{Server: bitbucket.NewServerClient(address, username, token, proxyAddress, enableProxy)}
+	}
+	return &Client{Cloud: bitbucket.NewClient(username, token, proxyAddress, enableProxy)}
+}
+
+// ListChangedFiles returns the files changed in the commit range from..to
+func (c *Client) ListChangedFiles(owner, repo, from, to string) ([]string, error) {
+	if c.Server != nil {
+		return c.Server.ListChangedFiles(owner, repo, from, to)
+	}
+	return c.Cloud.ListChangedFiles(owner, repo, from, to)
+}
+
+func (c *Client) ListPullRequestChangedFiles(owner, repo string, id int) ([]string, error) {
+	if c.Server != nil {
+		return c.Server.ListPullRequestChangedFiles(owner, repo, id)
+	}
+	return c.Cloud.ListPullRequestChangedFiles(owner, repo, id)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"strconv"
+
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+// CreateOrUpdateComment creates a comment of the pull request if commentID is empty, or updates the comment,
+// the id of the comment is returned.
+func (c *Client) CreateOrUpdateComment(owner, repo string, prID int, commentID, body string) (string, error) {
+	if commentID != "" {
+		id, err := strconv.ParseInt(commentID, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		if c.Server != nil {
+			return commentID, c.Server.UpdatePullRequestComment(owner, repo, prID, id, body)
+		}
+		return commentID, c.Cloud.UpdatePullRequestComment(owner, repo, prID, id, body)
+	}
+
+	if c.Server != nil {
+		comment, err := c.Server.CreatePullRequestComment(owner, repo, prID, body)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(comment.ID, 10), nil
+	}
+	comment, err := c.Cloud.CreatePullRequestComment(owner, repo, prID, body)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(comment.ID, 10), nil
+}
+
+func (c *Client) SetCommitStatus(owner, repo, sha string, status *bitbucket.CommitStatus) error {
+	if c.Server != nil {
+		return c.Server.SetCommitStatus(sha, status)
+	}
+	return c.Cloud.SetCommitStatus(owner, repo, sha, status)
+}
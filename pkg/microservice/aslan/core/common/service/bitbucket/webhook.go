@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"strconv"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	gitservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+const webhookName = "zadig"
+
+func (c *Client) CreateWebHook(owner, repo string) (string, error) {
+	if c.Server != nil {
+		hook, err := c.Server.CreateHook(owner, repo, &bitbucket.ServerHook{
+			Name:          webhookName,
+			URL:           config.WebHookURL(),
+			Active:        true,
+			Events:        bitbucket.ServerEvents,
+			Configuration: map[string]string{"secret": gitservice.GetHookSecret()},
+		})
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(hook.ID), nil
+	}
+
+	hook, err := c.Cloud.CreateHook(owner, repo, &bitbucket.Hook{
+		Description: webhookName,
+		URL:         config.WebHookURL(),
+		Active:      true,
+		Secret:      gitservice.GetHookSecret(),
+		Events:      bitbucket.CloudEvents,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hook.UUID, nil
+}
+
+func (c *Client) DeleteWebHook(owner, repo, hookID string) error {
+	if c.Server != nil {
+		return c.Server.DeleteHook(owner, repo, hookID)
+	}
+	return c.Cloud.DeleteHook(owner, repo, hookID)
+}
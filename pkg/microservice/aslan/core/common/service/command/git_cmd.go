@@ -104,12 +104,21 @@ func RunGitCmds(codehostDetail *systemconfig.CodeHost, repoOwner, repoNamespace,
 	}
 	user = userpassPair[0]
 	repo.User = user
+	switch repo.Source {
+	case setting.SourceFromBitbucket, setting.SourceFromBitbucketServer, setting.SourceFromGitea:
+		// these code hosts are authorized by the username and the access token directly
+		repo.User = codehostDetail.Username
+	}
 	if password != "" {
 		repo.Password = password
 		tokens = append(tokens, repo.Password)
 	}
 	tokens = append(tokens, repo.OauthToken)
-	cmds = append(cmds, buildGitCommands(repo, hostNames)...)
+	gitCmds, err := buildGitCommands(repo, hostNames)
+	if err != nil {
+		return err
+	}
+	cmds = append(cmds, gitCmds...)
 
 	// write ssh key
 	if len(hostNames.List()) > 0 {
@@ -168,11 +177,11 @@ func RunGitCmds(codehostDetail *systemconfig.CodeHost, repoOwner, repoNamespace,
 	return nil
 }
 
-func buildGitCommands(repo *Repo, hostNames sets.String) []*Command {
+func buildGitCommands(repo *Repo, hostNames sets.String) ([]*Command, error) {
 	cmds := make([]*Command, 0)
 
 	if len(repo.Name) == 0 {
-		return cmds
+		return cmds, nil
 	}
 
 	repoName := repo.Name
@@ -229,6 +238,15 @@ func buildGitCommands(repo *Repo, hostNames sets.String) []*Command {
 	} else if repo.Source == setting.SourceFromGiteeEE || repo.Source == setting.SourceFromGitee {
 		giteeURL := step.HTTPSCloneURL(repo.Source, repo.OauthToken, repo.Owner, repo.Name, repo.Address)
 		cmds = append(cmds, &Command{Cmd: RemoteAdd(repo.RemoteName, giteeURL), DisableTrace: true})
+	} else if repo.Source == setting.SourceFromBitbucket || repo.Source == setting.SourceFromBitbucketServer || repo.Source == setting.SourceFromGitea {
+		cloneURL, err := types.BasicAuthCloneURL(repo.Source, repo.Address, repo.User, repo.OauthToken, owner, repo.Name)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, &Command{
+			Cmd:          RemoteAdd(repo.RemoteName, cloneURL),
+			DisableTrace: true,
+		})
 	} else if repo.Source == setting.SourceFromOther {
 		if repo.AuthType == types.SSHAuthType {
 			host := getHost(repo.Address)
@@ -277,7 +295,7 @@ func buildGitCommands(repo *Repo, hostNames sets.String) []*Command {
 
 	setCmdsWorkDir(workDir, cmds)
 
-	return cmds
+	return cmds, nil
 }
 
 // InitGit creates an empty git repository.
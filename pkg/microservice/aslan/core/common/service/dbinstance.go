@@ -17,20 +17,21 @@ limitations under the License.
 package service
 
 import (
-	"database/sql"
-	"fmt"
-
-	_ "github.com/go-sql-driver/mysql"
+	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
-	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/v2/pkg/tool/crypto"
+	"github.com/koderover/zadig/v2/pkg/tool/sqlexec"
 )
 
+const validateDBInstanceTimeout = 10 * time.Second
+
 func ListDBInstances(encryptedKey string, log *zap.SugaredLogger) ([]*commonmodels.DBInstance, error) {
 	aesKey, err := GetAesKeyFromEncryptedKey(encryptedKey, log)
 	if err != nil {
@@ -77,6 +78,9 @@ func CreateDBInstance(args *commonmodels.DBInstance, log *zap.SugaredLogger) err
 	if args == nil {
 		return errors.New("nil DBInstance")
 	}
+	if err := sqlexec.ValidateSSLMode(args.SSLMode); err != nil {
+		return err
+	}
 
 	if err := commonrepo.NewDBInstanceColl().Create(args); err != nil {
 		log.Errorf("CreateDBInstance err:%v", err)
@@ -90,6 +94,9 @@ func FindDBInstance(id, name string) (*commonmodels.DBInstance, error) {
 }
 
 func UpdateDBInstance(id string, args *commonmodels.DBInstance, log *zap.SugaredLogger) error {
+	if err := sqlexec.ValidateSSLMode(args.SSLMode); err != nil {
+		return err
+	}
 	return commonrepo.NewDBInstanceColl().Update(id, args)
 }
 
@@ -101,25 +108,12 @@ func ValidateDBInstance(args *commonmodels.DBInstance) error {
 	if args == nil {
 		return errors.New("nil DBInstance")
 	}
-	switch args.Type {
-	case config.DBInstanceTypeMySQL, config.DBInstanceTypeMariaDB:
-		return validateMySQLInstance(args)
-	default:
-		return errors.Errorf("invalid db type %s", args.Type)
-	}
-}
-
-func validateMySQLInstance(args *commonmodels.DBInstance) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8&parseTime=True&loc=Local", args.Username, args.Password, args.Host, args.Port)
-
-	db, err := sql.Open("mysql", dsn)
+	sqlConfig, err := commonutil.GetDBInstanceSQLConfig(args)
 	if err != nil {
-		return errors.Errorf("connect mysql failed, err: %s", err)
+		return err
 	}
-	defer db.Close()
 
-	if err = db.Ping(); err != nil {
-		return errors.Errorf("ping mysql failed, err: %s", err)
-	}
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), validateDBInstanceTimeout)
+	defer cancel()
+	return sqlexec.Ping(ctx, sqlConfig)
 }
@@ -32,6 +32,9 @@ const (
 	IMNotifyTypeDingDing IMNotifyType = "dingding"
 	IMNotifyTypeWeChat   IMNotifyType = "wechat"
 	IMNotifyTypeLark     IMNotifyType = "feishu"
+	IMNotifyTypeSlack    IMNotifyType = "slack"
+	IMNotifyTypeMSTeams  IMNotifyType = "msteams"
+	IMNotifyTypeWebhook  IMNotifyType = "webhook"
 )
 
 type IMNotifyService struct {
@@ -44,7 +47,7 @@ func NewIMNotifyClient() *IMNotifyService {
 	}
 }
 
-func (w *IMNotifyService) SendMessageRequest(uri string, message interface{}) ([]byte, error) {
+func (w *IMNotifyService) SendMessageRequest(uri string, message interface{}, rfs ...httpclient.RequestFunc) ([]byte, error) {
 	c := httpclient.New()
 
 	// 使用代理
@@ -54,7 +57,7 @@ func (w *IMNotifyService) SendMessageRequest(uri string, message interface{}) ([
 		log.Infof("send im notify message is using proxy:%s\n", proxies[0].GetProxyURL())
 	}
 
-	res, err := c.Post(uri, httpclient.SetBody(message))
+	res, err := c.Post(uri, append([]httpclient.RequestFunc{httpclient.SetBody(message)}, rfs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imnotify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"title":"test"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), WebhookSignature("secret", body))
+
+	// the value from the github webhook documentation
+	assert.Equal(t, "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", WebhookSignature("It's a Secret to Everybody", []byte("Hello, World!")))
+
+	assert.NotEqual(t, WebhookSignature("secret", body), WebhookSignature("another", body))
+	assert.NotEqual(t, WebhookSignature("secret", body), WebhookSignature("secret", []byte(`{"title":"tested"}`)))
+}
+
+func TestMarkdownToSlack(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "bold",
+			content: "**执行用户**：admin",
+			want:    "*执行用户*：admin",
+		},
+		{
+			name:    "heading",
+			content: "#### 工作流 test #1 执行成功 ",
+			want:    "*工作流 test #1 执行成功*",
+		},
+		{
+			name:    "link",
+			content: "[点击查看更多信息](http://zadig.example.com/v1/projects)",
+			want:    "<http://zadig.example.com/v1/projects|点击查看更多信息>",
+		},
+		{
+			name:    "rule",
+			content: "a\n---\nb",
+			want:    "a\n\nb",
+		},
+		{
+			name:    "plain text",
+			content: "a * b",
+			want:    "a * b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MarkdownToSlack(tt.content))
+		})
+	}
+}
+
+func TestMarkdownToMSTeams(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "heading",
+			content: "#### 工作流 test #1 执行成功",
+			want:    "**工作流 test #1 执行成功**",
+		},
+		{
+			name:    "line breaks",
+			content: "**执行用户**：admin \n**项目名称**：test \n\n\n[详情](http://zadig.example.com)",
+			want:    "**执行用户**：admin\n\n**项目名称**：test\n\n[详情](http://zadig.example.com)",
+		},
+		{
+			name:    "rule",
+			content: "a\n---\nb",
+			want:    "a\n\nb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MarkdownToMSTeams(tt.content))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imnotify
+
+import (
+	"strings"
+)
+
+const (
+	msTeamsMessageCardType    = "MessageCard"
+	msTeamsMessageCardContext = "http://schema.org/extensions"
+)
+
+type MSTeamsMessageCard struct {
+	Type    string `json:"@type"`
+	Context string `json:"@context"`
+	Summary string `json:"summary"`
+	Text    string `json:"text"`
+}
+
+// SendMSTeamsMessage sends the markdown content to a microsoft teams incoming webhook as a message card,
+// the title is the summary shown in the notification.
+func (w *IMNotifyService) SendMSTeamsMessage(uri, title, content string) error {
+	message := &MSTeamsMessageCard{
+		Type:    msTeamsMessageCardType,
+		Context: msTeamsMessageCardContext,
+		Summary: MarkdownToPlainText(title),
+		Text:    MarkdownToMSTeams(content),
+	}
+
+	_, err := w.SendMessageRequest(uri, message)
+	return err
+}
+
+// MarkdownToMSTeams converts the markdown used by the notifications to the markdown supported by the teams message card,
+// headings are not supported and a single line break is ignored by teams.
+func MarkdownToMSTeams(content string) string {
+	content = markdownHeadingRegexp.ReplaceAllString(content, "**$1**")
+	content = markdownRuleRegexp.ReplaceAllString(content, "")
+	lines := strings.Split(content, "\n")
+	resp := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimRight(line, " "); line != "" {
+			resp = append(resp, line)
+		}
+	}
+	return strings.Join(resp, "\n\n")
+}
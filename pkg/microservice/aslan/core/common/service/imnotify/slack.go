@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imnotify
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	slackBlockTypeSection = "section"
+	slackTextTypeMrkdwn   = "mrkdwn"
+	// slackSectionMaxLength is the max length of the text of a slack section block
+	slackSectionMaxLength = 3000
+)
+
+var (
+	markdownHeadingRegexp = regexp.MustCompile(`(?m)^#{1,6}[ \t]*(.+?)[ \t]*$`)
+	markdownBoldRegexp    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	markdownLinkRegexp    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	markdownRuleRegexp    = regexp.MustCompile(`(?m)^---[ \t]*$`)
+)
+
+type SlackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []*SlackBlock `json:"blocks,omitempty"`
+}
+
+type SlackBlock struct {
+	Type string     `json:"type"`
+	Text *SlackText `json:"text,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SendSlackMessage sends the markdown content to a slack incoming webhook, the title is the text shown in the notification.
+func (w *IMNotifyService) SendSlackMessage(uri, title, content string) error {
+	text := MarkdownToSlack(content)
+	if utf8.RuneCountInString(text) > slackSectionMaxLength {
+		text = string([]rune(text)[:slackSectionMaxLength-3]) + "..."
+	}
+	message := &SlackMessage{
+		Text: MarkdownToPlainText(title),
+		Blocks: []*SlackBlock{
+			{
+				Type: slackBlockTypeSection,
+				Text: &SlackText{
+					Type: slackTextTypeMrkdwn,
+					Text: text,
+				},
+			},
+		},
+	}
+
+	_, err := w.SendMessageRequest(uri, message)
+	return err
+}
+
+// MarkdownToSlack converts the markdown used by the notifications to the slack mrkdwn format
+func MarkdownToSlack(content string) string {
+	content = markdownBoldRegexp.ReplaceAllString(content, "*$1*")
+	content = markdownHeadingRegexp.ReplaceAllString(content, "*$1*")
+	content = markdownLinkRegexp.ReplaceAllString(content, "<$2|$1>")
+	content = markdownRuleRegexp.ReplaceAllString(content, "")
+	return content
+}
+
+// MarkdownToPlainText removes the heading and bold marks of the markdown, it is used for titles and summaries
+func MarkdownToPlainText(content string) string {
+	content = markdownBoldRegexp.ReplaceAllString(content, "$1")
+	content = markdownHeadingRegexp.ReplaceAllString(content, "$1")
+	return strings.TrimSpace(content)
+}
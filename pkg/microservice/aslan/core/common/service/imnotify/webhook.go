@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imnotify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+const (
+	WebhookEventHeader     = "X-Zadig-Event"
+	WebhookSignatureHeader = "X-Zadig-Signature-256"
+
+	WebhookEventWorkflow    = "workflow"
+	WebhookEventEnvAnalysis = "env_analysis"
+	WebhookEventEnvDrift    = "env_drift"
+)
+
+// SendWebhookMessage posts the payload as json to the address. If the secret is set, the hex encoded HMAC-SHA256
+// of the body is sent in the X-Zadig-Signature-256 header with the prefix "sha256=", just like github webhooks.
+func (w *IMNotifyService) SendWebhookMessage(address, secret, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload, err: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type":     "application/json",
+		WebhookEventHeader: event,
+	}
+	if secret != "" {
+		headers[WebhookSignatureHeader] = "sha256=" + WebhookSignature(secret, body)
+	}
+
+	_, err = w.SendMessageRequest(address, body, httpclient.SetHeaders(headers))
+	return err
+}
+
+// WebhookSignature returns the hex encoded HMAC-SHA256 of the body
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
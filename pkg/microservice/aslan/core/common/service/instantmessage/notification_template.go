@@ -0,0 +1,363 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	configbase "github.com/koderover/zadig/v2/pkg/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/types"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+// NotificationTemplateContext is the data the notification templates are rendered with
+type NotificationTemplateContext struct {
+	WorkflowName        string
+	WorkflowDisplayName string
+	TaskID              int64
+	ProjectName         string
+	Creator             string
+	Status              string
+	StatusText          string
+	Error               string
+	StartTime           int64
+	EndTime             int64
+	// Duration is the seconds the task has been running
+	Duration    int64
+	DetailURL   string
+	WebHookType string
+	Jobs        []*NotificationTemplateJob
+	Approvals   []*NotificationTemplateApproval
+}
+
+type NotificationTemplateJob struct {
+	Name        string
+	Type        string
+	TypeText    string
+	Status      string
+	StatusText  string
+	Error       string
+	StartTime   int64
+	EndTime     int64
+	Duration    int64
+	Env         string
+	Images      []string
+	Commits     []*NotificationTemplateCommit
+	TestResults []*NotificationTemplateTestResult
+}
+
+type NotificationTemplateCommit struct {
+	RepoName  string
+	Branch    string
+	Tag       string
+	CommitID  string
+	CommitURL string
+	Message   string
+	Author    string
+	PRs       []int
+}
+
+type NotificationTemplateTestResult struct {
+	TestName    string
+	TotalCase   int
+	SuccessCase int
+	FailedCase  int
+	// TestTime is the seconds the test takes
+	TestTime float64
+}
+
+type NotificationTemplateApproval struct {
+	StageName string
+	Type      string
+	Status    string
+	Approvers []*NotificationTemplateApprover
+}
+
+type NotificationTemplateApprover struct {
+	Name          string
+	Result        string
+	Comment       string
+	OperationTime int64
+}
+
+// RenderNotificationTemplate renders the title and content of the template with the workflow task
+func RenderNotificationTemplate(tpl *models.NotificationTemplate, task *models.WorkflowTask, webHookType string) (string, string, error) {
+	ctx := BuildNotificationTemplateContext(task, webHookType)
+	title, err := executeNotificationTemplate(tpl.Title, ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render the title of notification template %s, err: %w", tpl.Name, err)
+	}
+	content, err := executeNotificationTemplate(tpl.Content, ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render the content of notification template %s, err: %w", tpl.Name, err)
+	}
+	return title, content, nil
+}
+
+// LintNotificationTemplate checks the syntax of the title and content of the template
+func LintNotificationTemplate(tpl *models.NotificationTemplate) error {
+	if _, err := template.New("title").Funcs(notificationTemplateFuncs).Parse(tpl.Title); err != nil {
+		return fmt.Errorf("invalid title: %w", err)
+	}
+	if _, err := template.New("content").Funcs(notificationTemplateFuncs).Parse(tpl.Content); err != nil {
+		return fmt.Errorf("invalid content: %w", err)
+	}
+	return nil
+}
+
+var notificationTemplateFuncs = template.FuncMap{
+	"formatTime": func(t int64) string {
+		if t <= 0 {
+			return ""
+		}
+		return time.Unix(t, 0).Format("2006-01-02 15:04:05")
+	},
+	"duration": func(seconds int64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	},
+	"join":     strings.Join,
+	"contains": strings.Contains,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"truncate": func(length int, s string) string {
+		if r := []rune(s); len(r) > length {
+			return string(r[:length])
+		}
+		return s
+	},
+}
+
+func executeNotificationTemplate(tplContent string, ctx *NotificationTemplateContext) (string, error) {
+	tmpl, err := template.New("notify").Funcs(notificationTemplateFuncs).Parse(tplContent)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := bytes.NewBufferString("")
+	if err := tmpl.Execute(buffer, ctx); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// BuildNotificationTemplateContext collects the jobs, images, commits, test results and approvals of the workflow task
+func BuildNotificationTemplateContext(task *models.WorkflowTask, webHookType string) *NotificationTemplateContext {
+	ctx := &NotificationTemplateContext{
+		WorkflowName:        task.WorkflowName,
+		WorkflowDisplayName: task.WorkflowDisplayName,
+		TaskID:              task.TaskID,
+		ProjectName:         task.ProjectName,
+		Creator:             task.TaskCreator,
+		Status:              string(task.Status),
+		StatusText:          getWorkflowTaskStatusText(task.Status),
+		Error:               task.Error,
+		StartTime:           task.StartTime,
+		EndTime:             task.EndTime,
+		Duration:            getNotificationDuration(task.StartTime, task.EndTime),
+		DetailURL:           fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s", configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName)),
+		WebHookType:         webHookType,
+		Jobs:                make([]*NotificationTemplateJob, 0),
+		Approvals:           make([]*NotificationTemplateApproval, 0),
+	}
+	if task.Status == config.StatusWaitingApprove {
+		ctx.StatusText = "等待审批"
+	}
+
+	for _, stage := range task.Stages {
+		if approval := getNotificationTemplateApproval(stage); approval != nil {
+			ctx.Approvals = append(ctx.Approvals, approval)
+		}
+		for _, job := range stage.Jobs {
+			ctx.Jobs = append(ctx.Jobs, getNotificationTemplateJob(task, job))
+		}
+	}
+	return ctx
+}
+
+func getNotificationDuration(startTime, endTime int64) int64 {
+	if startTime <= 0 {
+		return 0
+	}
+	if endTime < startTime {
+		endTime = time.Now().Unix()
+	}
+	return endTime - startTime
+}
+
+func getNotificationTemplateJob(task *models.WorkflowTask, job *models.JobTask) *NotificationTemplateJob {
+	resp := &NotificationTemplateJob{
+		Name:        job.Name,
+		Type:        job.JobType,
+		TypeText:    getJobTypeText(job.JobType),
+		Status:      string(job.Status),
+		StatusText:  getJobStatusText(job.Status),
+		Error:       job.Error,
+		StartTime:   job.StartTime,
+		EndTime:     job.EndTime,
+		Duration:    getNotificationDuration(job.StartTime, job.EndTime),
+		Images:      make([]string, 0),
+		Commits:     make([]*NotificationTemplateCommit, 0),
+		TestResults: make([]*NotificationTemplateTestResult, 0),
+	}
+
+	switch job.JobType {
+	case string(config.JobZadigBuild), string(config.JobFreestyle), string(config.JobZadigTesting), string(config.JobZadigScanning):
+		jobSpec := &models.JobTaskFreestyleSpec{}
+		if err := models.IToi(job.Spec, jobSpec); err != nil {
+			log.Errorf("failed to decode the spec of job %s, err: %s", job.Name, err)
+			return resp
+		}
+		for _, stepTask := range jobSpec.Steps {
+			if stepTask.StepType != config.StepGit {
+				continue
+			}
+			stepSpec := &step.StepGitSpec{}
+			if err := models.IToi(stepTask.Spec, stepSpec); err != nil {
+				continue
+			}
+			for _, repo := range stepSpec.Repos {
+				resp.Commits = append(resp.Commits, getNotificationTemplateCommit(repo))
+			}
+		}
+		for _, env := range jobSpec.Properties.Envs {
+			if env.Key == "IMAGE" && env.Value != "" {
+				resp.Images = append(resp.Images, env.Value)
+			}
+		}
+		if job.JobType == string(config.JobZadigTesting) {
+			reports, err := mongodb.NewCustomWorkflowTestReportColl().ListByWorkflow(task.WorkflowName, job.Name, task.TaskID)
+			if err != nil {
+				log.Errorf("failed to list test reports of job %s, err: %s", job.Name, err)
+			}
+			for _, report := range reports {
+				resp.TestResults = append(resp.TestResults, &NotificationTemplateTestResult{
+					TestName:    report.ZadigTestName,
+					TotalCase:   report.TestCaseNum,
+					SuccessCase: report.SuccessCaseNum,
+					FailedCase:  report.TestCaseNum - report.SuccessCaseNum,
+					TestTime:    report.TestTime,
+				})
+			}
+		}
+	case string(config.JobZadigDeploy):
+		jobSpec := &models.JobTaskDeploySpec{}
+		if err := models.IToi(job.Spec, jobSpec); err != nil {
+			log.Errorf("failed to decode the spec of job %s, err: %s", job.Name, err)
+			return resp
+		}
+		resp.Env = jobSpec.Env
+		for _, image := range jobSpec.ServiceAndImages {
+			resp.Images = append(resp.Images, image.Image)
+		}
+	case string(config.JobZadigHelmDeploy):
+		jobSpec := &models.JobTaskHelmDeploySpec{}
+		if err := models.IToi(job.Spec, jobSpec); err != nil {
+			log.Errorf("failed to decode the spec of job %s, err: %s", job.Name, err)
+			return resp
+		}
+		resp.Env = jobSpec.Env
+		for _, image := range jobSpec.ImageAndModules {
+			resp.Images = append(resp.Images, image.Image)
+		}
+	}
+	return resp
+}
+
+func getNotificationTemplateCommit(repo *types.Repository) *NotificationTemplateCommit {
+	commitID := repo.CommitID
+	if len(commitID) > 8 {
+		commitID = commitID[0:8]
+	}
+	return &NotificationTemplateCommit{
+		RepoName:  repo.RepoName,
+		Branch:    repo.Branch,
+		Tag:       repo.Tag,
+		CommitID:  commitID,
+		CommitURL: repo.CommitURL(),
+		Message:   strings.Trim(repo.CommitMessage, "\n"),
+		Author:    repo.AuthorName,
+		PRs:       repo.PRs,
+	}
+}
+
+func getNotificationTemplateApproval(stage *models.StageTask) *NotificationTemplateApproval {
+	if stage.Approval == nil || !stage.Approval.Enabled {
+		return nil
+	}
+	resp := &NotificationTemplateApproval{
+		StageName: stage.Name,
+		Type:      string(stage.Approval.Type),
+		Status:    string(stage.Approval.Status),
+		Approvers: make([]*NotificationTemplateApprover, 0),
+	}
+	switch stage.Approval.Type {
+	case config.NativeApproval:
+		if stage.Approval.NativeApproval == nil {
+			return resp
+		}
+		for _, user := range stage.Approval.NativeApproval.ApproveUsers {
+			name := user.UserName
+			if name == "" {
+				name = user.GroupName
+			}
+			resp.Approvers = append(resp.Approvers, &NotificationTemplateApprover{
+				Name:          name,
+				Result:        string(user.RejectOrApprove),
+				Comment:       user.Comment,
+				OperationTime: user.OperationTime,
+			})
+		}
+	case config.LarkApproval:
+		if stage.Approval.LarkApproval == nil {
+			return resp
+		}
+		for _, node := range stage.Approval.LarkApproval.ApprovalNodes {
+			for _, user := range node.ApproveUsers {
+				resp.Approvers = append(resp.Approvers, &NotificationTemplateApprover{
+					Name:          user.Name,
+					Result:        string(user.RejectOrApprove),
+					Comment:       user.Comment,
+					OperationTime: user.OperationTime,
+				})
+			}
+		}
+	case config.DingTalkApproval:
+		if stage.Approval.DingTalkApproval == nil {
+			return resp
+		}
+		for _, node := range stage.Approval.DingTalkApproval.ApprovalNodes {
+			for _, user := range node.ApproveUsers {
+				resp.Approvers = append(resp.Approvers, &NotificationTemplateApprover{
+					Name:          user.Name,
+					Result:        string(user.RejectOrApprove),
+					Comment:       user.Comment,
+					OperationTime: user.OperationTime,
+				})
+			}
+		}
+	}
+	return resp
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/types"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+func newNotificationTemplateTask() *models.WorkflowTask {
+	return &models.WorkflowTask{
+		ProjectName:         "demo",
+		WorkflowName:        "build-demo",
+		WorkflowDisplayName: "build demo",
+		TaskID:              3,
+		TaskCreator:         "admin",
+		Status:              config.StatusPassed,
+		StartTime:           1700000000,
+		EndTime:             1700000090,
+		Stages: []*models.StageTask{
+			{
+				Name: "approve",
+				Approval: &models.Approval{
+					Enabled: true,
+					Type:    config.NativeApproval,
+					Status:  config.StatusPassed,
+					NativeApproval: &models.NativeApproval{
+						ApproveUsers: []*models.User{
+							{UserName: "reviewer", RejectOrApprove: config.Approve, Comment: "lgtm"},
+							{GroupName: "ops"},
+						},
+					},
+				},
+			},
+			{
+				Name: "build",
+				Jobs: []*models.JobTask{
+					{
+						Name:      "build",
+						JobType:   string(config.JobZadigBuild),
+						Status:    config.StatusPassed,
+						StartTime: 1700000000,
+						EndTime:   1700000060,
+						Spec: &models.JobTaskFreestyleSpec{
+							Properties: models.JobProperties{
+								Envs: []*models.KeyVal{{Key: "IMAGE", Value: "koderover.tencentcloudcr.com/demo/service1:20240101"}},
+							},
+							Steps: []*models.StepTask{
+								{
+									StepType: config.StepGit,
+									Spec: &step.StepGitSpec{
+										Repos: []*types.Repository{
+											{
+												Source:        types.ProviderGitlab,
+												Address:       "https://gitlab.example.com",
+												RepoOwner:     "koderover",
+												RepoName:      "demo",
+												Branch:        "main",
+												CommitID:      "0123456789abcdef",
+												CommitMessage: "fix bug\n",
+												AuthorName:    "dev",
+												PRs:           []int{12},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					{
+						Name:    "deploy",
+						JobType: string(config.JobZadigDeploy),
+						Status:  config.StatusFailed,
+						Error:   "timeout",
+						Spec: &models.JobTaskDeploySpec{
+							Env:              "dev",
+							ServiceAndImages: []*models.DeployServiceModule{{ServiceModule: "service1", Image: "koderover.tencentcloudcr.com/demo/service1:20240101"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildNotificationTemplateContext(t *testing.T) {
+	ctx := BuildNotificationTemplateContext(newNotificationTemplateTask(), "slack")
+
+	assert.Equal(t, "build-demo", ctx.WorkflowName)
+	assert.Equal(t, int64(3), ctx.TaskID)
+	assert.Equal(t, "admin", ctx.Creator)
+	assert.Equal(t, string(config.StatusPassed), ctx.Status)
+	assert.Equal(t, int64(90), ctx.Duration)
+	assert.Equal(t, "slack", ctx.WebHookType)
+	assert.Contains(t, ctx.DetailURL, "/v1/projects/detail/demo/pipelines/custom/build-demo/3?display_name=build%20demo")
+
+	require.Len(t, ctx.Approvals, 1)
+	assert.Equal(t, "approve", ctx.Approvals[0].StageName)
+	assert.Equal(t, []*NotificationTemplateApprover{
+		{Name: "reviewer", Result: string(config.Approve), Comment: "lgtm"},
+		{Name: "ops"},
+	}, ctx.Approvals[0].Approvers)
+
+	require.Len(t, ctx.Jobs, 2)
+	build := ctx.Jobs[0]
+	assert.Equal(t, int64(60), build.Duration)
+	assert.Equal(t, []string{"koderover.tencentcloudcr.com/demo/service1:20240101"}, build.Images)
+	assert.Equal(t, []*NotificationTemplateCommit{
+		{
+			RepoName:  "demo",
+			Branch:    "main",
+			CommitID:  "01234567",
+			CommitURL: "https://gitlab.example.com/koderover/demo/-/commit/0123456789abcdef",
+			Message:   "fix bug",
+			Author:    "dev",
+			PRs:       []int{12},
+		},
+	}, build.Commits)
+
+	deploy := ctx.Jobs[1]
+	assert.Equal(t, "dev", deploy.Env)
+	assert.Equal(t, "timeout", deploy.Error)
+	assert.Equal(t, []string{"koderover.tencentcloudcr.com/demo/service1:20240101"}, deploy.Images)
+	assert.Empty(t, deploy.Commits)
+}
+
+func TestRenderNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		title       string
+		content     string
+		wantTitle   string
+		wantContent string
+		wantErr     bool
+	}{
+		{
+			name:        "fields",
+			title:       "{{.WorkflowDisplayName}} #{{.TaskID}} {{upper .Status}}",
+			content:     "{{.Creator}} {{.WebHookType}} {{duration .Duration}}",
+			wantTitle:   "build demo #3 PASSED",
+			wantContent: "admin feishu 1m30s",
+		},
+		{
+			name:        "jobs",
+			title:       "{{len .Jobs}} jobs",
+			content:     "{{range .Jobs}}{{.Name}}:{{.Status}}{{range .Commits}} {{.CommitID}}{{end}} {{join .Images \",\"}};{{end}}",
+			wantTitle:   "2 jobs",
+			wantContent: "build:passed 01234567 koderover.tencentcloudcr.com/demo/service1:20240101;deploy:failed koderover.tencentcloudcr.com/demo/service1:20240101;",
+		},
+		{
+			name:        "functions",
+			title:       "{{truncate 5 .WorkflowDisplayName}}",
+			content:     "{{if contains .Status \"pass\"}}ok{{end}} {{lower \"ABC\"}}",
+			wantTitle:   "build",
+			wantContent: "ok abc",
+		},
+		{
+			name:    "unknown field",
+			title:   "{{.Unknown}}",
+			content: "content",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, content, err := RenderNotificationTemplate(&models.NotificationTemplate{
+				Name:    tt.name,
+				Title:   tt.title,
+				Content: tt.content,
+			}, newNotificationTemplateTask(), "feishu")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTitle, title)
+			assert.Equal(t, tt.wantContent, content)
+		})
+	}
+}
+
+func TestLintNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", title: "{{.WorkflowName}}", content: "{{range .Jobs}}{{.Name}}{{end}}"},
+		{name: "custom functions", title: "{{formatTime .StartTime}}", content: "{{truncate 10 .Error}}"},
+		{name: "invalid title", title: "{{.WorkflowName", content: "content", wantErr: true},
+		{name: "invalid content", title: "title", content: "{{range .Jobs}}", wantErr: true},
+		{name: "unknown function", title: "title", content: "{{unknown .Status}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LintNotificationTemplate(&models.NotificationTemplate{Title: tt.title, Content: tt.content})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -32,6 +32,8 @@ import (
 	configbase "github.com/koderover/zadig/v2/pkg/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/imnotify"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 	"github.com/koderover/zadig/v2/pkg/types"
 	"github.com/koderover/zadig/v2/pkg/types/step"
@@ -58,13 +60,19 @@ func (w *Service) SendWorkflowTaskAproveNotifications(workflowName string, taskI
 		if !notify.Enabled {
 			continue
 		}
-		title, content, larkCard, err := w.getApproveNotificationContent(notify, task)
+		var title, content string
+		var larkCard *LarkCard
+		if notify.TemplateID != "" {
+			title, content, larkCard, err = w.getTemplateNotificationContent(notify, task)
+		} else {
+			title, content, larkCard, err = w.getApproveNotificationContent(notify, task)
+		}
 		if err != nil {
 			errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
 			log.Error(errMsg)
 			return errors.New(errMsg)
 		}
-		if err := w.sendNotification(title, content, notify, larkCard); err != nil {
+		if err := w.sendNotification(title, content, notify, larkCard, task); err != nil {
 			log.Errorf("failed to send notification, err: %s", err)
 		}
 	}
@@ -103,19 +111,53 @@ func (w *Service) SendWorkflowTaskNotifications(task *models.WorkflowTask) error
 		}
 		statusSets := sets.NewString(notify.NotifyTypes...)
 		if statusSets.Has(string(task.Status)) || (statusChanged && statusSets.Has(string(config.StatusChanged))) {
-			title, content, larkCard, err := w.getNotificationContent(notify, task)
+			var title, content string
+			var larkCard *LarkCard
+			if notify.TemplateID != "" {
+				title, content, larkCard, err = w.getTemplateNotificationContent(notify, task)
+			} else {
+				title, content, larkCard, err = w.getNotificationContent(notify, task)
+			}
 			if err != nil {
 				errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
 				log.Error(errMsg)
 				return errors.New(errMsg)
 			}
-			if err := w.sendNotification(title, content, notify, larkCard); err != nil {
+			if err := w.sendNotification(title, content, notify, larkCard, task); err != nil {
 				log.Errorf("failed to send notification, err: %s", err)
 			}
 		}
 	}
 	return nil
 }
+
+// getTemplateNotificationContent renders the notification template of the project instead of the built-in message,
+// the rendered title is the header of the lark card or the first line of the markdown message.
+func (w *Service) getTemplateNotificationContent(notify *models.NotifyCtl, task *models.WorkflowTask) (string, string, *LarkCard, error) {
+	tpl, err := mongodb.NewNotificationTemplateColl().FindByID(notify.TemplateID)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to find notification template %s, err: %s", notify.TemplateID, err)
+	}
+	if tpl.ProjectName != task.ProjectName {
+		return "", "", nil, fmt.Errorf("notification template %s does not belong to project %s", tpl.Name, task.ProjectName)
+	}
+	title, content, err := RenderNotificationTemplate(tpl, task, notify.WebHookType)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if notify.WebHookType != feiShuType {
+		content = fmt.Sprintf("%s\n%s%s", title, content, getNotifyAtContent(notify))
+		return title, content, nil, nil
+	}
+
+	lc := NewLarkCard()
+	lc.SetConfig(true)
+	lc.SetHeader(getColorTemplateWithStatus(task.Status), title, feiShuTagText)
+	lc.AddI18NElementsZhcnFeild(content, true)
+	return "", "", lc, nil
+}
+
 func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *models.WorkflowTask) (string, string, *LarkCard, error) {
 	workflowNotification := &workflowTaskNotification{
 		Task:               task,
@@ -344,20 +386,7 @@ func getWorkflowTaskTplExec(tplcontent string, args *workflowTaskNotification) (
 			}
 			return markdownColorComment
 		},
-		"taskStatus": func(status config.Status) string {
-			if status == config.StatusPassed {
-				return "执行成功"
-			} else if status == config.StatusCancelled {
-				return "执行取消"
-			} else if status == config.StatusTimeout {
-				return "执行超时"
-			} else if status == config.StatusReject {
-				return "执行被拒绝"
-			} else if status == config.StatusCreated {
-				return "开始执行"
-			}
-			return "执行失败"
-		},
+		"taskStatus": getWorkflowTaskStatusText,
 		"getIcon": func(status config.Status) string {
 			if status == config.StatusPassed || status == config.StatusCreated {
 				return "👍"
@@ -386,6 +415,21 @@ func getWorkflowTaskTplExec(tplcontent string, args *workflowTaskNotification) (
 	return buffer.String(), nil
 }
 
+func getWorkflowTaskStatusText(status config.Status) string {
+	if status == config.StatusPassed {
+		return "执行成功"
+	} else if status == config.StatusCancelled {
+		return "执行取消"
+	} else if status == config.StatusTimeout {
+		return "执行超时"
+	} else if status == config.StatusReject {
+		return "执行被拒绝"
+	} else if status == config.StatusCreated {
+		return "开始执行"
+	}
+	return "执行失败"
+}
+
 type jobTaskNotification struct {
 	Job         *models.JobTask `json:"task"`
 	WebHookType string          `json:"web_hook_type"`
@@ -393,70 +437,8 @@ type jobTaskNotification struct {
 
 func getJobTaskTplExec(tplcontent string, args *jobTaskNotification) (string, error) {
 	tmpl := template.Must(template.New("notify").Funcs(template.FuncMap{
-		"taskStatus": func(status config.Status) string {
-			if status == config.StatusPassed {
-				return "执行成功"
-			} else if status == config.StatusCancelled {
-				return "执行取消"
-			} else if status == config.StatusTimeout {
-				return "执行超时"
-			} else if status == config.StatusReject {
-				return "执行被拒绝"
-			} else if status == "" {
-				return "未执行"
-			}
-			return "执行失败"
-		},
-		"jobType": func(jobType string) string {
-			switch jobType {
-			case string(config.JobZadigBuild):
-				return "构建"
-			case string(config.JobZadigDeploy):
-				return "部署"
-			case string(config.JobZadigHelmDeploy):
-				return "helm部署"
-			case string(config.JobCustomDeploy):
-				return "自定义部署"
-			case string(config.JobFreestyle):
-				return "通用任务"
-			case string(config.JobPlugin):
-				return "自定义任务"
-			case string(config.JobZadigTesting):
-				return "测试"
-			case string(config.JobZadigScanning):
-				return "代码扫描"
-			case string(config.JobZadigDistributeImage):
-				return "镜像分发"
-			case string(config.JobK8sBlueGreenDeploy):
-				return "蓝绿部署"
-			case string(config.JobK8sBlueGreenRelease):
-				return "蓝绿发布"
-			case string(config.JobK8sCanaryDeploy):
-				return "金丝雀部署"
-			case string(config.JobK8sCanaryRelease):
-				return "金丝雀发布"
-			case string(config.JobK8sGrayRelease):
-				return "灰度发布"
-			case string(config.JobK8sGrayRollback):
-				return "灰度回滚"
-			case string(config.JobK8sPatch):
-				return "更新 k8s YAML"
-			case string(config.JobIstioRelease):
-				return "istio 发布"
-			case string(config.JobIstioRollback):
-				return "istio 回滚"
-			case string(config.JobJira):
-				return "jira 问题状态变更"
-			case string(config.JobNacos):
-				return "Nacos 配置变更"
-			case string(config.JobApollo):
-				return "Apollo 配置变更"
-			case string(config.JobMeegoTransition):
-				return "飞书工作项状态变更"
-			default:
-				return string(jobType)
-			}
-		},
+		"taskStatus": getJobStatusText,
+		"jobType":    getJobTypeText,
 	}).Parse(tplcontent))
 
 	buffer := bytes.NewBufferString("")
@@ -468,8 +450,190 @@ func getJobTaskTplExec(tplcontent string, args *jobTaskNotification) (string, er
 	return buffer.String(), nil
 }
 
-func (w *Service) sendNotification(title, content string, notify *models.NotifyCtl, card *LarkCard) error {
+func getJobStatusText(status config.Status) string {
+	if status == config.StatusPassed {
+		return "执行成功"
+	} else if status == config.StatusCancelled {
+		return "执行取消"
+	} else if status == config.StatusTimeout {
+		return "执行超时"
+	} else if status == config.StatusReject {
+		return "执行被拒绝"
+	} else if status == "" {
+		return "未执行"
+	}
+	return "执行失败"
+}
+
+func getJobTypeText(jobType string) string {
+	switch jobType {
+	case string(config.JobZadigBuild):
+		return "构建"
+	case string(config.JobZadigDeploy):
+		return "部署"
+	case string(config.JobZadigHelmDeploy):
+		return "helm部署"
+	case string(config.JobCustomDeploy):
+		return "自定义部署"
+	case string(config.JobFreestyle):
+		return "通用任务"
+	case string(config.JobPlugin):
+		return "自定义任务"
+	case string(config.JobZadigTesting):
+		return "测试"
+	case string(config.JobZadigScanning):
+		return "代码扫描"
+	case string(config.JobZadigDistributeImage):
+		return "镜像分发"
+	case string(config.JobK8sBlueGreenDeploy):
+		return "蓝绿部署"
+	case string(config.JobK8sBlueGreenRelease):
+		return "蓝绿发布"
+	case string(config.JobK8sCanaryDeploy):
+		return "金丝雀部署"
+	case string(config.JobK8sCanaryRelease):
+		return "金丝雀发布"
+	case string(config.JobK8sGrayRelease):
+		return "灰度发布"
+	case string(config.JobK8sGrayRollback):
+		return "灰度回滚"
+	case string(config.JobK8sPatch):
+		return "更新 k8s YAML"
+	case string(config.JobIstioRelease):
+		return "istio 发布"
+	case string(config.JobIstioRollback):
+		return "istio 回滚"
+	case string(config.JobJira):
+		return "jira 问题状态变更"
+	case string(config.JobNacos):
+		return "Nacos 配置变更"
+	case string(config.JobApollo):
+		return "Apollo 配置变更"
+	case string(config.JobMeegoTransition):
+		return "飞书工作项状态变更"
+	default:
+		return string(jobType)
+	}
+}
+
+// workflowTaskWebhookPayload is the summary of the task posted to the generic webhook, the task itself is not posted
+// since its specs contain credentials such as the code host tokens and the registry passwords.
+type workflowTaskWebhookPayload struct {
+	Title     string                   `json:"title"`
+	DetailURL string                   `json:"detail_url"`
+	Task      *workflowTaskWebhookTask `json:"task"`
+}
+
+type workflowTaskWebhookTask struct {
+	ProjectName         string                    `json:"project_name"`
+	WorkflowName        string                    `json:"workflow_name"`
+	WorkflowDisplayName string                    `json:"workflow_display_name"`
+	TaskID              int64                     `json:"task_id"`
+	TaskCreator         string                    `json:"task_creator"`
+	Status              config.Status             `json:"status"`
+	StartTime           int64                     `json:"start_time"`
+	EndTime             int64                     `json:"end_time"`
+	Jobs                []*workflowTaskWebhookJob `json:"jobs"`
+}
+
+type workflowTaskWebhookJob struct {
+	Name      string                       `json:"name"`
+	JobType   string                       `json:"type"`
+	Status    config.Status                `json:"status"`
+	StartTime int64                        `json:"start_time"`
+	EndTime   int64                        `json:"end_time"`
+	Image     string                       `json:"image,omitempty"`
+	Commits   []*workflowTaskWebhookCommit `json:"commits,omitempty"`
+}
+
+type workflowTaskWebhookCommit struct {
+	Source        string `json:"source"`
+	RepoOwner     string `json:"repo_owner"`
+	RepoName      string `json:"repo_name"`
+	Branch        string `json:"branch,omitempty"`
+	Tag           string `json:"tag,omitempty"`
+	PRs           []int  `json:"prs,omitempty"`
+	CommitID      string `json:"commit_id"`
+	CommitMessage string `json:"commit_message,omitempty"`
+}
+
+func newWorkflowTaskWebhookPayload(title string, task *models.WorkflowTask) *workflowTaskWebhookPayload {
+	webhookTask := &workflowTaskWebhookTask{
+		ProjectName:         task.ProjectName,
+		WorkflowName:        task.WorkflowName,
+		WorkflowDisplayName: task.WorkflowDisplayName,
+		TaskID:              task.TaskID,
+		TaskCreator:         task.TaskCreator,
+		Status:              task.Status,
+		StartTime:           task.StartTime,
+		EndTime:             task.EndTime,
+		Jobs:                make([]*workflowTaskWebhookJob, 0),
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			webhookJob := &workflowTaskWebhookJob{
+				Name:      job.Name,
+				JobType:   job.JobType,
+				Status:    job.Status,
+				StartTime: job.StartTime,
+				EndTime:   job.EndTime,
+			}
+			if job.JobType == string(config.JobZadigBuild) || job.JobType == string(config.JobFreestyle) {
+				jobSpec := &models.JobTaskFreestyleSpec{}
+				models.IToi(job.Spec, jobSpec)
+				for _, env := range jobSpec.Properties.Envs {
+					if env.Key == "IMAGE" {
+						webhookJob.Image = env.Value
+					}
+				}
+				for _, stepTask := range jobSpec.Steps {
+					if stepTask.StepType != config.StepGit {
+						continue
+					}
+					stepSpec := &step.StepGitSpec{}
+					models.IToi(stepTask.Spec, stepSpec)
+					for _, repo := range stepSpec.Repos {
+						webhookJob.Commits = append(webhookJob.Commits, &workflowTaskWebhookCommit{
+							Source:        repo.Source,
+							RepoOwner:     repo.RepoOwner,
+							RepoName:      repo.RepoName,
+							Branch:        repo.Branch,
+							Tag:           repo.Tag,
+							PRs:           repo.PRs,
+							CommitID:      repo.CommitID,
+							CommitMessage: repo.CommitMessage,
+						})
+					}
+				}
+			}
+			webhookTask.Jobs = append(webhookTask.Jobs, webhookJob)
+		}
+	}
+	return &workflowTaskWebhookPayload{
+		Title:     imnotify.MarkdownToPlainText(title),
+		DetailURL: fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s", configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName)),
+		Task:      webhookTask,
+	}
+}
+
+func (w *Service) sendNotification(title, content string, notify *models.NotifyCtl, card *LarkCard, task *models.WorkflowTask) error {
 	switch notify.WebHookType {
+	case string(imnotify.IMNotifyTypeSlack):
+		if err := imnotify.NewIMNotifyClient().SendSlackMessage(notify.SlackWebHook, title, content); err != nil {
+			return err
+		}
+	case string(imnotify.IMNotifyTypeMSTeams):
+		if err := imnotify.NewIMNotifyClient().SendMSTeamsMessage(notify.MSTeamsWebHook, title, content); err != nil {
+			return err
+		}
+	case string(imnotify.IMNotifyTypeWebhook):
+		if notify.WebHookNotify == nil {
+			return fmt.Errorf("webhook of the notification is empty")
+		}
+		payload := newWorkflowTaskWebhookPayload(title, task)
+		if err := imnotify.NewIMNotifyClient().SendWebhookMessage(notify.WebHookNotify.Address, notify.WebHookNotify.Secret, imnotify.WebhookEventWorkflow, payload); err != nil {
+			return err
+		}
 	case dingDingType:
 		if err := w.sendDingDingMessage(notify.DingDingWebHook, title, content, notify.AtMobiles, notify.IsAtAll); err != nil {
 			return err
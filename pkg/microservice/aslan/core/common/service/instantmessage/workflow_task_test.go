@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/types"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+func TestNewWorkflowTaskWebhookPayload(t *testing.T) {
+	task := &models.WorkflowTask{
+		ProjectName:         "demo",
+		WorkflowName:        "build-demo",
+		WorkflowDisplayName: "build demo",
+		TaskID:              3,
+		TaskCreator:         "admin",
+		Status:              config.StatusPassed,
+		Stages: []*models.StageTask{
+			{
+				Jobs: []*models.JobTask{
+					{
+						Name:    "build",
+						JobType: string(config.JobZadigBuild),
+						Status:  config.StatusPassed,
+						Spec: &models.JobTaskFreestyleSpec{
+							Properties: models.JobProperties{
+								Envs: []*models.KeyVal{
+									{Key: "IMAGE", Value: "koderover.tencentcloudcr.com/demo/service1:20240101"},
+									{Key: "TOKEN", Value: "env-secret", IsCredential: true},
+								},
+								Registries: []*models.RegistryNamespace{
+									{RegAddr: "koderover.tencentcloudcr.com", AccessKey: "registry-ak", SecretKey: "registry-sk"},
+								},
+							},
+							Steps: []*models.StepTask{
+								{
+									StepType: config.StepGit,
+									Spec: &step.StepGitSpec{
+										Repos: []*types.Repository{
+											{
+												Source:        types.ProviderGitlab,
+												RepoOwner:     "koderover",
+												RepoName:      "demo",
+												Branch:        "main",
+												PRs:           []int{12},
+												CommitID:      "0123456789abcdef",
+												CommitMessage: "fix bug",
+												OauthToken:    "oauth-token",
+												Password:      "repo-password",
+											},
+										},
+									},
+								},
+								{
+									StepType: config.StepArchive,
+									Spec: &step.StepArchiveSpec{
+										S3: &step.S3{Ak: "s3-ak", Sk: "s3-sk"},
+									},
+								},
+							},
+						},
+					},
+					{
+						Name:    "deploy",
+						JobType: string(config.JobZadigDeploy),
+						Status:  config.StatusFailed,
+					},
+				},
+			},
+		},
+	}
+
+	payload := newWorkflowTaskWebhookPayload("#### 工作流 build demo #3 执行成功", task)
+	assert.Equal(t, "工作流 build demo #3 执行成功", payload.Title)
+	assert.Contains(t, payload.DetailURL, "/v1/projects/detail/demo/pipelines/custom/build-demo/3?display_name=build%20demo")
+	assert.Equal(t, "demo", payload.Task.ProjectName)
+	assert.Equal(t, int64(3), payload.Task.TaskID)
+	assert.Equal(t, config.StatusPassed, payload.Task.Status)
+
+	require.Len(t, payload.Task.Jobs, 2)
+	build := payload.Task.Jobs[0]
+	assert.Equal(t, config.StatusPassed, build.Status)
+	assert.Equal(t, "koderover.tencentcloudcr.com/demo/service1:20240101", build.Image)
+	require.Len(t, build.Commits, 1)
+	assert.Equal(t, &workflowTaskWebhookCommit{
+		Source:        types.ProviderGitlab,
+		RepoOwner:     "koderover",
+		RepoName:      "demo",
+		Branch:        "main",
+		PRs:           []int{12},
+		CommitID:      "0123456789abcdef",
+		CommitMessage: "fix bug",
+	}, build.Commits[0])
+	assert.Equal(t, config.StatusFailed, payload.Task.Jobs[1].Status)
+	assert.Empty(t, payload.Task.Jobs[1].Commits)
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	for _, secret := range []string{"env-secret", "registry-ak", "registry-sk", "oauth-token", "repo-password", "s3-ak", "s3-sk"} {
+		assert.NotContains(t, string(body), secret)
+	}
+}
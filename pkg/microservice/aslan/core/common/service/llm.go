@@ -3,10 +3,17 @@ package service
 import (
 	"context"
 	"fmt"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/tool/cache"
 	"github.com/koderover/zadig/v2/pkg/tool/llm"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
 func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
@@ -15,28 +22,99 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 		return nil, fmt.Errorf("Could find the llm integration for %s: %w", name, err)
 	}
 
+	return newLLMClient(llmIntegration)
+}
+
+// GetDefaultLLMClient returns the client of the llm integration, only one integration can be created for now
+func GetDefaultLLMClient(ctx context.Context) (llm.ILLM, error) {
+	llmIntegrations, err := commonrepo.NewLLMIntegrationColl().FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find the llm integration: %w", err)
+	}
+	if len(llmIntegrations) == 0 {
+		return nil, fmt.Errorf("Could not find the llm integration")
+	}
+	return newLLMClient(llmIntegrations[0])
+}
+
+func GetLLMProviderName(llmIntegration *models.LLMIntegration) string {
+	if llmIntegration.ProviderName != "" {
+		return llmIntegration.ProviderName
+	}
+	return llmIntegration.Name
+}
+
+func newLLMClient(llmIntegration *models.LLMIntegration) (llm.ILLM, error) {
+	provider := GetLLMProviderName(llmIntegration)
 	llmConfig := llm.LLMConfig{
 		Name:    llmIntegration.Name,
+		Model:   llmIntegration.Model,
 		Token:   llmIntegration.Token,
 		BaseURL: llmIntegration.BaseURL,
 	}
 	if llmIntegration.EnableProxy {
 		llmConfig.Proxy = config.ProxyHTTPSAddr()
 	}
+	// the analysis prompts are too long for the default openai model
+	if llmConfig.Model == "" && (provider == llm.ProviderOpenAI || provider == llm.ProviderAzureOpenAI) {
+		llmConfig.Model = openai.GPT3Dot5Turbo16K
+	}
 
-	llmClient, err := llm.NewClient(name)
+	llmClient, err := llm.NewClient(provider)
 	if err != nil {
-		return nil, fmt.Errorf("Could not create the llm client for %s: %w", name, err)
+		return nil, fmt.Errorf("Could not create the llm client for %s: %w", provider, err)
 	}
 
 	err = llmClient.Configure(llmConfig)
 	if err != nil {
-		return nil, fmt.Errorf("Could not configure the llm client for %s: %w", name, err)
+		return nil, fmt.Errorf("Could not configure the llm client for %s: %w", provider, err)
 	}
 
-	return llmClient, nil
+	return &usageRecordingClient{ILLM: llmClient, integrationID: llmIntegration.ID}, nil
 }
 
-func GetDefaultLLMClient(ctx context.Context) (llm.ILLM, error) {
-	return GetLLMClient(ctx, "openai")
+// usageRecordingClient saves the tokens used by every request of the client into the usage of its llm integration
+type usageRecordingClient struct {
+	llm.ILLM
+	integrationID primitive.ObjectID
+
+	mu       sync.Mutex
+	recorded llm.Usage
+}
+
+func (c *usageRecordingClient) GetCompletion(ctx context.Context, prompt string, options ...llm.ParamOption) (string, error) {
+	defer c.recordUsage()
+	return c.ILLM.GetCompletion(ctx, prompt, options...)
+}
+
+func (c *usageRecordingClient) GetCompletionStream(ctx context.Context, prompt string, handler llm.StreamHandler, options ...llm.ParamOption) (string, error) {
+	defer c.recordUsage()
+	return c.ILLM.GetCompletionStream(ctx, prompt, handler, options...)
+}
+
+func (c *usageRecordingClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...llm.ParamOption) (string, error) {
+	defer c.recordUsage()
+	return c.ILLM.Parse(ctx, prompt, cache, options...)
+}
+
+// recordUsage saves the usage added since the last record, the client may be shared by concurrent requests
+func (c *usageRecordingClient) recordUsage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	usage := c.ILLM.GetUsage()
+	delta := &models.LLMUsage{
+		Requests:         usage.Requests - c.recorded.Requests,
+		PromptTokens:     usage.PromptTokens - c.recorded.PromptTokens,
+		CompletionTokens: usage.CompletionTokens - c.recorded.CompletionTokens,
+		TotalTokens:      usage.TotalTokens - c.recorded.TotalTokens,
+	}
+	if delta.Requests == 0 {
+		return
+	}
+	if err := commonrepo.NewLLMIntegrationColl().IncUsage(context.Background(), c.integrationID, delta); err != nil {
+		log.Errorf("failed to save the usage of llm integration %s: %v", c.integrationID.Hex(), err)
+		return
+	}
+	c.recorded = usage
 }
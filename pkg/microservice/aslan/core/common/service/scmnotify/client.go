@@ -30,11 +30,14 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/bitbucket"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitea"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitee"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/v2/pkg/tool/gerrit"
 	gitlabtool "github.com/koderover/zadig/v2/pkg/tool/git/gitlab"
+	giteatool "github.com/koderover/zadig/v2/pkg/tool/gitea"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
@@ -187,6 +190,32 @@ func (c *Client) Comment(notify *models.Notification) error {
 		if err != nil {
 			return fmt.Errorf("failed to comment gitee due to %s/%d %v", notify.ProjectID, notify.PrID, err)
 		}
+	} else if strings.ToLower(codeHostDetail.Type) == setting.SourceFromBitbucket || strings.ToLower(codeHostDetail.Type) == setting.SourceFromBitbucketServer {
+		cli := bitbucket.NewClient(codeHostDetail.Type, codeHostDetail.Address, codeHostDetail.Username, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		commentID, err := cli.CreateOrUpdateComment(notify.RepoOwner, notify.RepoName, notify.PrID, notify.CommentID, comment)
+		if err != nil {
+			return fmt.Errorf("failed to comment bitbucket due to %s/%s/%d %v", notify.RepoOwner, notify.RepoName, notify.PrID, err)
+		}
+		notify.CommentID = commentID
+	} else if strings.ToLower(codeHostDetail.Type) == setting.SourceFromGitea {
+		cli := gitea.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		if notify.CommentID == "" {
+			var giteaComment *giteatool.Comment
+			giteaComment, err = cli.CreatePullRequestComment(notify.RepoOwner, notify.RepoName, notify.PrID, comment)
+			if err == nil {
+				notify.CommentID = strconv.FormatInt(giteaComment.ID, 10)
+			}
+		} else {
+			commentID, parseErr := strconv.ParseInt(notify.CommentID, 10, 64)
+			if parseErr != nil {
+				return fmt.Errorf("failed to parse commentID %v,err: %s", notify.CommentID, parseErr)
+			}
+			err = cli.UpdatePullRequestComment(notify.RepoOwner, notify.RepoName, commentID, comment)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to comment gitea due to %s/%s/%d %v", notify.RepoOwner, notify.RepoName, notify.PrID, err)
+		}
 	} else {
 		return fmt.Errorf("non gitlab source not supported to comment")
 	}
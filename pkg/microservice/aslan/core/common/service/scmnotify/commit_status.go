@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scmnotify
+
+import (
+	"fmt"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/v2/pkg/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/bitbucket"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitea"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
+	bitbuckettool "github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+	giteatool "github.com/koderover/zadig/v2/pkg/tool/gitea"
+)
+
+// setCommitStatusForWorkflowV4 sets the commit status of the pull request on bitbucket and gitea,
+// false is returned if the hook does not come from them so the github check is used instead.
+func setCommitStatusForWorkflowV4(workflowArgs *models.WorkflowV4, taskID int64, status config.Status, log *zap.SugaredLogger) (bool, error) {
+	hook := workflowArgs.HookPayload
+	ch, err := systemconfig.New().GetCodeHost(hook.CodehostID)
+	if err != nil {
+		return false, nil
+	}
+	if ch.Type != setting.SourceFromBitbucket && ch.Type != setting.SourceFromBitbucketServer && ch.Type != setting.SourceFromGitea {
+		return false, nil
+	}
+	if hook.CommitID == "" {
+		log.Warnf("No commit found in the hook payload of workflow %s, skip to set commit status", workflowArgs.Name)
+		return true, nil
+	}
+
+	displayName := getDisplayName(workflowArgs)
+	key := fmt.Sprintf("zadig/%s", workflowArgs.Name)
+	description := fmt.Sprintf("Workflow [%s] is %s.", displayName, getCommitStatusText(status))
+	taskURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(), workflowArgs.Project, workflowArgs.Name, taskID, url.QueryEscape(displayName))
+
+	log.Infof("Start to set %s commit status of %s/%s@%s to %s", ch.Type, hook.Owner, hook.Repo, hook.CommitID, status)
+	if ch.Type == setting.SourceFromGitea {
+		cli := gitea.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		return true, cli.SetCommitStatus(hook.Owner, hook.Repo, hook.CommitID, &giteatool.CommitStatus{
+			State:       getGiteaCommitState(status),
+			TargetURL:   taskURL,
+			Description: description,
+			Context:     key,
+		})
+	}
+
+	cli := bitbucket.NewClient(ch.Type, ch.Address, ch.Username, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	return true, cli.SetCommitStatus(hook.Owner, hook.Repo, hook.CommitID, &bitbuckettool.CommitStatus{
+		Key:         key,
+		Name:        displayName,
+		State:       getBitbucketCommitState(status),
+		URL:         taskURL,
+		Description: description,
+	})
+}
+
+func getCommitStatusText(status config.Status) string {
+	switch status {
+	case config.StatusCreated:
+		return "queued"
+	case config.StatusRunning:
+		return "running"
+	default:
+		return string(status)
+	}
+}
+
+func getBitbucketCommitState(status config.Status) string {
+	switch status {
+	case config.StatusCreated, config.StatusRunning:
+		return bitbuckettool.StatusInProgress
+	case config.StatusPassed:
+		return bitbuckettool.StatusSuccessful
+	case config.StatusCancelled, config.StatusSkipped:
+		return bitbuckettool.StatusStopped
+	default:
+		return bitbuckettool.StatusFailed
+	}
+}
+
+func getGiteaCommitState(status config.Status) string {
+	switch status {
+	case config.StatusCreated, config.StatusRunning:
+		return giteatool.StatusPending
+	case config.StatusPassed:
+		return giteatool.StatusSuccess
+	case config.StatusCancelled, config.StatusSkipped:
+		return giteatool.StatusWarning
+	case config.StatusFailed, config.StatusReject:
+		return giteatool.StatusFailure
+	default:
+		return giteatool.StatusError
+	}
+}
@@ -166,6 +166,10 @@ func convertStatus(status string) string {
 		return "运行不稳定"
 	case "Completed":
 		return "删除完成"
+	case "success":
+		return "创建成功"
+	case "failed":
+		return "创建失败"
 	default:
 		return "准备中"
 	}
@@ -651,6 +655,97 @@ func (s *Service) UpdateEnvAndTaskWebhookComment(workflowArgs *models.WorkflowTa
 	return nil
 }
 
+// SendPreviewEnvWebhookComment creates the comment of the preview environment in the pull request,
+// the comment is updated instead if the notification already exists
+func (s *Service) SendPreviewEnvWebhookComment(
+	notificationID string, mainRepo *models.MainHookRepo, prID int, baseURI string, prTaskInfo *models.PrTaskInfo, logger *zap.SugaredLogger,
+) (*models.Notification, error) {
+	prTaskInfo.EnvStatus = convertStatus(prTaskInfo.EnvStatus)
+
+	if notificationID != "" {
+		notification, err := s.Coll.Find(notificationID)
+		if err != nil {
+			logger.Errorf("SendPreviewEnvWebhookComment can't find notification by id %s %s", notificationID, err)
+			return nil, err
+		}
+		notification.PrTask = prTaskInfo
+		if err := s.Client.Comment(notification); err != nil {
+			logger.Errorf("failed to comment to %s %v", notification.ToString(), err)
+			return nil, err
+		} else if err := s.Coll.Upsert(notification); err != nil {
+			logger.Errorf("failed to save %s %v", notification.ToString(), err)
+			return nil, err
+		}
+		return notification, nil
+	}
+
+	notification := &models.Notification{
+		CodehostID:   mainRepo.CodehostID,
+		PrID:         prID,
+		ProjectID:    strings.TrimLeft(mainRepo.GetRepoNamespace()+"/"+mainRepo.RepoName, "/"),
+		BaseURI:      baseURI,
+		IsPreviewEnv: true,
+		PrTask:       prTaskInfo,
+		Label:        mainRepo.GetLabelValue(),
+		Revision:     mainRepo.Revision,
+		RepoOwner:    mainRepo.RepoOwner,
+		RepoName:     mainRepo.RepoName,
+	}
+
+	if err := s.Client.Comment(notification); err != nil {
+		logger.Errorf("failed to comment to %s %v", notification.ToString(), err)
+		return nil, err
+	} else if err := s.Coll.Create(notification); err != nil {
+		logger.Errorf("failed to save %s %v", notification.ToString(), err)
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// SendWorkflowCodeWebhookComment comments the workflow changes of the workflow files in the pull request,
+// the comment is updated on the following pushes to the pull request
+func (s *Service) SendWorkflowCodeWebhookComment(
+	mainRepo *models.MainHookRepo, prID int, baseURI string, changes []*models.WorkflowCodeChange, logger *zap.SugaredLogger,
+) error {
+	projectID := strings.TrimLeft(mainRepo.GetRepoNamespace()+"/"+mainRepo.RepoName, "/")
+	notification, err := s.Coll.FindWorkflowCodeByPR(mainRepo.CodehostID, projectID, prID)
+	if err == nil {
+		notification.WorkflowCodeChanges = changes
+		notification.Revision = mainRepo.Revision
+		if err := s.Client.Comment(notification); err != nil {
+			logger.Errorf("failed to comment to %s %v", notification.ToString(), err)
+			return err
+		} else if err := s.Coll.Upsert(notification); err != nil {
+			logger.Errorf("failed to save %s %v", notification.ToString(), err)
+			return err
+		}
+		return nil
+	}
+
+	notification = &models.Notification{
+		CodehostID:          mainRepo.CodehostID,
+		PrID:                prID,
+		ProjectID:           projectID,
+		BaseURI:             baseURI,
+		IsWorkflowCode:      true,
+		WorkflowCodeChanges: changes,
+		Label:               mainRepo.GetLabelValue(),
+		Revision:            mainRepo.Revision,
+		RepoOwner:           mainRepo.RepoOwner,
+		RepoName:            mainRepo.RepoName,
+	}
+
+	if err := s.Client.Comment(notification); err != nil {
+		logger.Errorf("failed to comment to %s %v", notification.ToString(), err)
+		return err
+	} else if err := s.Coll.Create(notification); err != nil {
+		logger.Errorf("failed to save %s %v", notification.ToString(), err)
+		return err
+	}
+	return nil
+}
+
 func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, taskID int64, log *zap.SugaredLogger) error {
 	hook := workflowArgs.HookPayload
 
@@ -658,6 +753,10 @@ func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		return nil
 	}
 
+	if ok, err := setCommitStatusForWorkflowV4(workflowArgs, taskID, config.StatusCreated, log); ok {
+		return err
+	}
+
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
 		log.Errorf("getGithubAppClient failed, err:%v", err)
@@ -717,6 +816,10 @@ func (s *Service) UpdateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		return nil
 	}
 
+	if ok, err := setCommitStatusForWorkflowV4(workflowArgs, taskID, config.StatusRunning, log); ok {
+		return err
+	}
+
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
 		log.Errorf("getGithubAppClient failed, err:%v", err)
@@ -777,6 +880,10 @@ func (s *Service) CompleteGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4,
 		return nil
 	}
 
+	if ok, err := setCommitStatusForWorkflowV4(workflowArgs, taskID, status, log); ok {
+		return err
+	}
+
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
 		log.Errorf("getGithubAppClient failed, err:%v", err)
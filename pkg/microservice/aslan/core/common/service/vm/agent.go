@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"fmt"
+	"sync"
+
+	vmmongodb "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/vm"
+	"github.com/koderover/zadig/v2/pkg/tool/wsconn"
+)
+
+// AgentConns holds the channels of the zadig-agents connected to this aslan instance, keyed by vm id. An agent is
+// connected to only one of the aslan replicas, the map is not shared between them.
+var AgentConns = &AgentConnMap{conns: make(map[string]*wsconn.AgentConn)}
+
+type AgentConnMap struct {
+	mu    sync.RWMutex
+	conns map[string]*wsconn.AgentConn
+}
+
+func (m *AgentConnMap) Set(vmID string, conn *wsconn.AgentConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.conns[vmID]; ok && old != conn {
+		old.Close()
+	}
+	m.conns[vmID] = conn
+}
+
+// Delete removes the channel of the vm only if it is still the given one, the agent may have reconnected in between.
+func (m *AgentConnMap) Delete(vmID string, conn *wsconn.AgentConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conns[vmID] == conn {
+		delete(m.conns, vmID)
+	}
+}
+
+func (m *AgentConnMap) Get(vmID string) (*wsconn.AgentConn, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conn, ok := m.conns[vmID]
+	return conn, ok
+}
+
+// CancelAgentJob pushes the cancellation of a vm job to the agent running it. The cancellation is only pushed if the
+// agent is connected to this aslan replica, it's not forwarded to the other replicas. An agent connected elsewhere, or
+// not connected at all, learns the cancellation from the response to its next job report, which is sent every
+// second while the job runs, so the cancellation is delayed by about a second instead of being lost.
+func CancelAgentJob(jobID string) error {
+	job, err := vmmongodb.NewVMJobColl().FindByID(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to find vm job %s, error: %s", jobID, err)
+	}
+	if job.VMID == "" {
+		return nil
+	}
+
+	conn, ok := AgentConns.Get(job.VMID)
+	if !ok {
+		return nil
+	}
+	return conn.Send(wsconn.AgentMsgCancel, "", jobID, nil)
+}
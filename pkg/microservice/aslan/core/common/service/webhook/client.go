@@ -46,7 +46,7 @@ func NewClient() *client {
 type task struct {
 	ID                                                          int
 	owner, namespace, repo, address, token, ref, ak, sk, region string
-	from                                                        string
+	from, username                                              string
 	add, enableProxy, isManual                                  bool
 	err                                                         error
 	doneCh                                                      chan struct{}
@@ -60,6 +60,7 @@ type TaskOption struct {
 	Repo        string
 	Address     string
 	Token       string
+	Username    string
 	Ref         string
 	From        string
 	AK          string
@@ -81,6 +82,7 @@ func (c *client) AddWebHook(taskOption *TaskOption) error {
 		repo:        taskOption.Repo,
 		address:     taskOption.Address,
 		token:       taskOption.Token,
+		username:    taskOption.Username,
 		ref:         getFullReference(taskOption.Name, taskOption.Ref),
 		from:        taskOption.From,
 		add:         true,
@@ -119,6 +121,7 @@ func (c *client) RemoveWebHook(taskOption *TaskOption) error {
 		repo:        taskOption.Repo,
 		address:     taskOption.Address,
 		token:       taskOption.Token,
+		username:    taskOption.Username,
 		ref:         getFullReference(taskOption.Name, taskOption.Ref),
 		from:        taskOption.From,
 		add:         false,
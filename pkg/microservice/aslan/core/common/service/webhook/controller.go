@@ -27,6 +27,8 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/bitbucket"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitea"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitee"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/gitlab"
@@ -166,6 +168,10 @@ func removeWebhook(t *task, logger *zap.Logger) {
 		}
 	case setting.SourceFromGitee, setting.SourceFromGiteeEE:
 		cl = gitee.NewClient(t.ID, t.token, config.ProxyHTTPSAddr(), t.enableProxy, t.address)
+	case setting.SourceFromBitbucket, setting.SourceFromBitbucketServer:
+		cl = bitbucket.NewClient(t.from, t.address, t.username, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+	case setting.SourceFromGitea:
+		cl = gitea.NewClient(t.address, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
@@ -244,6 +250,10 @@ func addWebhook(t *task, logger *zap.Logger) {
 		}
 	case setting.SourceFromGitee, setting.SourceFromGiteeEE:
 		cl = gitee.NewClient(t.ID, t.token, config.ProxyHTTPSAddr(), t.enableProxy, t.address)
+	case setting.SourceFromBitbucket, setting.SourceFromBitbucketServer:
+		cl = bitbucket.NewClient(t.from, t.address, t.username, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+	case setting.SourceFromGitea:
+		cl = gitea.NewClient(t.address, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
@@ -218,7 +218,8 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 			}
 
 			switch ch.Type {
-			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromGiteeEE:
+			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromGiteeEE,
+				setting.SourceFromBitbucket, setting.SourceFromBitbucketServer, setting.SourceFromGitea:
 				err = webhook.NewClient().RemoveWebHook(&webhook.TaskOption{
 					ID:          ch.ID,
 					Name:        wh.name,
@@ -227,6 +228,7 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 					Repo:        wh.repo,
 					Address:     ch.Address,
 					Token:       ch.AccessToken,
+					Username:    ch.Username,
 					AK:          ch.AccessKey,
 					SK:          ch.SecretKey,
 					Region:      ch.Region,
@@ -256,7 +258,8 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 			}
 
 			switch ch.Type {
-			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromGiteeEE:
+			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromGiteeEE,
+				setting.SourceFromBitbucket, setting.SourceFromBitbucketServer, setting.SourceFromGitea:
 				err = webhook.NewClient().AddWebHook(&webhook.TaskOption{
 					ID:        ch.ID,
 					Name:      wh.name,
@@ -265,6 +268,7 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 					Repo:      wh.repo,
 					Address:   ch.Address,
 					Token:     ch.AccessToken,
+					Username:  ch.Username,
 					Ref:       name,
 					AK:        ch.AccessKey,
 					SK:        ch.SecretKey,
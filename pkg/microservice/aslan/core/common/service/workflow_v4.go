@@ -48,6 +48,9 @@ func DeleteWorkflowV4sByProjectName(projectName string, log *zap.SugaredLogger)
 			errList = multierror.Append(errList, fmt.Errorf("productName %s workflowV4 delete %s error: %s", projectName, workflowV4.Name, err))
 		}
 	}
+	if err := mongodb.NewWorkflowCodeSourceColl().Delete(projectName); err != nil {
+		errList = multierror.Append(errList, fmt.Errorf("productName %s workflow code source delete error: %s", projectName, err))
+	}
 	if err := errList.ErrorOrNil(); err != nil {
 		log.Error(err)
 		return err
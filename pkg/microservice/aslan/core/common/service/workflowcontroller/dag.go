@@ -0,0 +1,293 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+)
+
+// dagStage tracks a stage when the jobs are scheduled by the dependency graph,
+// the stage starts when the first of its jobs is ready and finishes when all of its jobs are done.
+type dagStage struct {
+	stage     *commonmodels.StageTask
+	startOnce sync.Once
+	started   bool
+	// err is set when the approval of the stage is not passed or the condition can not be evaluated
+	err       error
+	skipped   bool
+	remaining int
+}
+
+type dagJob struct {
+	job     *commonmodels.JobTask
+	stage   *dagStage
+	deps    []*dagJob
+	started bool
+	done    bool
+}
+
+func (j *dagJob) ready() bool {
+	for _, dep := range j.deps {
+		if !dep.done {
+			return false
+		}
+		if dep.job.Status != config.StatusPassed && dep.job.Status != config.StatusSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDAGWorkflowTask returns true if any job task declares depends_on.
+func IsDAGWorkflowTask(stages []*commonmodels.StageTask) bool {
+	_, dependsOn := jobTaskDependencyInput(stages)
+	return commonutil.HasJobDependencies(dependsOn)
+}
+
+func jobTaskDependencyInput(stages []*commonmodels.StageTask) ([]*commonutil.JobDependencyStage, map[string][]string) {
+	dependencyStages := make([]*commonutil.JobDependencyStage, 0, len(stages))
+	dependsOn := make(map[string][]string)
+	for _, stage := range stages {
+		dependencyStage := &commonutil.JobDependencyStage{Parallel: stage.Parallel}
+		for _, job := range stage.Jobs {
+			if job.OriginName == "" {
+				continue
+			}
+			if _, ok := dependsOn[job.OriginName]; ok {
+				continue
+			}
+			dependencyStage.Jobs = append(dependencyStage.Jobs, job.OriginName)
+			dependsOn[job.OriginName] = job.DependsOn
+		}
+		dependencyStages = append(dependencyStages, dependencyStage)
+	}
+	return dependencyStages, dependsOn
+}
+
+// GetJobTaskDependencies returns the upstream workflow jobs of each workflow job in the task,
+// the jobs which are not in the task, e.g. skipped when the task was created, are ignored.
+func GetJobTaskDependencies(stages []*commonmodels.StageTask) map[string][]string {
+	dependencyStages, dependsOn := jobTaskDependencyInput(stages)
+	dependencies := commonutil.ResolveJobDependencies(dependencyStages, dependsOn)
+	for job, deps := range dependencies {
+		existed := []string{}
+		for _, dep := range deps {
+			if _, ok := dependencies[dep]; ok {
+				existed = append(existed, dep)
+			}
+		}
+		dependencies[job] = existed
+	}
+	return dependencies
+}
+
+func buildDAG(stages []*commonmodels.StageTask) ([]*dagJob, []*dagStage, error) {
+	dependencies := GetJobTaskDependencies(stages)
+	if err := commonutil.ValidateJobDependencies(dependencies); err != nil {
+		return nil, nil, err
+	}
+
+	jobs := []*dagJob{}
+	dagStages := []*dagStage{}
+	jobsByOrigin := make(map[string][]*dagJob)
+	for _, stage := range stages {
+		ds := &dagStage{stage: stage}
+		dagStages = append(dagStages, ds)
+		for _, job := range stage.Jobs {
+			dj := &dagJob{job: job, stage: ds}
+			// should skip passed job when workflow task be restarted
+			if job.Status == config.StatusPassed {
+				dj.started = true
+				dj.done = true
+			} else {
+				ds.remaining++
+			}
+			jobs = append(jobs, dj)
+			jobsByOrigin[job.OriginName] = append(jobsByOrigin[job.OriginName], dj)
+		}
+	}
+	for _, dj := range jobs {
+		for _, dep := range dependencies[dj.job.OriginName] {
+			dj.deps = append(dj.deps, jobsByOrigin[dep]...)
+		}
+	}
+	return jobs, dagStages, nil
+}
+
+// RunDAG runs the jobs of all the stages by the dependency graph, a job starts as soon as all of its upstream jobs passed or were skipped.
+// no more jobs will be started once a job failed, the running jobs will be waited for.
+func RunDAG(ctx context.Context, stages []*commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	jobs, dagStages, err := buildDAG(stages)
+	if err != nil {
+		logger.Errorf("build job dependency graph error: %v", err)
+		for _, stage := range stages {
+			if stage.Status != config.StatusPassed {
+				stage.Status = config.StatusFailed
+				stage.Error = err.Error()
+				break
+			}
+		}
+		ack()
+		return
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(chan *dagJob)
+	sem := make(chan struct{}, concurrency)
+	running := 0
+	failed := false
+	for {
+		if !failed && ctx.Err() == nil {
+			for _, dj := range jobs {
+				if dj.started || !dj.ready() {
+					continue
+				}
+				dj.started = true
+				running++
+				go func(dj *dagJob) {
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					runDAGJob(ctx, dj, workflowCtx, logger, ack)
+					results <- dj
+				}(dj)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		dj := <-results
+		running--
+		dj.done = true
+		if dj.stage.err != nil || statusFailed(dj.job.Status) {
+			failed = true
+		}
+		if isOriginJobDone(jobs, dj.job.OriginName) {
+			setJobImagesVariable(workflowCtx, dj.job.OriginName)
+		}
+		dj.stage.remaining--
+		if dj.stage.remaining == 0 {
+			finishDAGStage(ctx, dj.stage, logger, ack)
+		}
+	}
+	// finish the stages which were interrupted by failed jobs
+	for _, ds := range dagStages {
+		if ds.started && ds.remaining > 0 {
+			finishDAGStage(ctx, ds, logger, ack)
+		}
+	}
+}
+
+func runDAGJob(ctx context.Context, dj *dagJob, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	ds := dj.stage
+	ds.startOnce.Do(func() {
+		startDAGStage(ctx, ds, workflowCtx, logger, ack)
+	})
+	if ds.err != nil {
+		return
+	}
+	if ds.skipped {
+		jobcontroller.SkipJobs([]*commonmodels.JobTask{dj.job}, ds.stage.SkipReason)
+		ack()
+		return
+	}
+	jobcontroller.RunJobs(ctx, []*commonmodels.JobTask{dj.job}, workflowCtx, 1, logger, ack)
+}
+
+func startDAGStage(ctx context.Context, ds *dagStage, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	stage := ds.stage
+	ds.started = true
+	run, err := evaluateStageCondition(stage, workflowCtx)
+	if err != nil {
+		stage.Status = config.StatusFailed
+		stage.Error = err.Error()
+		ds.err = err
+		logger.Errorf("stage: %s condition error: %v", stage.Name, err)
+		ack()
+		return
+	}
+	if !run {
+		ds.skipped = true
+		stage.SkipReason = fmt.Sprintf("condition %s evaluated to false", stage.When)
+		logger.Infof("skip stage: %s, %s", stage.Name, stage.SkipReason)
+	}
+	stage.Status = config.StatusRunning
+	ack()
+	logger.Infof("start stage: %s,status: %s", stage.Name, stage.Status)
+	if !ds.skipped {
+		if err := waitForApprove(ctx, stage, workflowCtx, logger, ack); err != nil {
+			stage.Error = err.Error()
+			ds.err = err
+			logger.Errorf("finish stage: %s,status: %s error: %s", stage.Name, stage.Status, stage.Error)
+			ack()
+			return
+		}
+	}
+	stage.StartTime = time.Now().Unix()
+	ack()
+}
+
+func finishDAGStage(ctx context.Context, ds *dagStage, logger *zap.SugaredLogger, ack func()) {
+	stage := ds.stage
+	// the status of a stage which failed to start was already set
+	if ds.err == nil {
+		updateStageStatus(ctx, stage)
+	}
+	stage.EndTime = time.Now().Unix()
+	logger.Infof("finish stage: %s,status: %s", stage.Name, stage.Status)
+	ack()
+}
+
+func isOriginJobDone(jobs []*dagJob, originName string) bool {
+	for _, dj := range jobs {
+		if dj.job.OriginName == originName && !dj.done {
+			return false
+		}
+	}
+	return true
+}
+
+// setJobImagesVariable sets the IMAGES variable of a workflow job after all of its job tasks are done,
+// it works like CustomStageCtl.AfterRun but for a single job, since stages may run at the same time in a DAG.
+func setJobImagesVariable(workflowCtx *commonmodels.WorkflowTaskCtx, jobName string) {
+	images := []string{}
+	for k, v := range workflowCtx.GlobalContextGetAll() {
+		list := reg.FindStringSubmatch(k)
+		if len(list) > 0 && list[1] == jobName {
+			images = append(images, v)
+		}
+	}
+	if len(images) == 0 {
+		return
+	}
+	key := fmt.Sprintf("{{.job.%s.IMAGES}}", jobName)
+	if _, ok := workflowCtx.GlobalContextGet(key); !ok {
+		workflowCtx.GlobalContextSet(key, strings.Join(images, ","))
+	}
+}
@@ -31,6 +31,8 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/v2/pkg/tool/metrics"
 	"github.com/koderover/zadig/v2/pkg/util/rand"
 )
 
@@ -108,7 +110,8 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 	if job.Status == config.StatusPassed {
 		return
 	}
-	// render global variables for every job.
+	// render global variables for every job, the condition is evaluated with the variables as parameters.
+	when := job.When
 	workflowCtx.GlobalContextEach(func(k, v string) bool {
 		b, _ := json.Marshal(job)
 		v = strings.Trim(v, "\n")
@@ -118,6 +121,31 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		}
 		return true
 	})
+	job.When = when
+	if skip := skipJobByCondition(job, workflowCtx, logger); skip {
+		ack()
+		return
+	}
+	if denied := denyJobByPolicy(job, workflowCtx, logger); denied {
+		ack()
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		runJobAttempt(ctx, job, workflowCtx, logger, ack)
+		if !shouldRetryJob(ctx, job, attempt, logger) {
+			recordJobAttempt(job, job.Name)
+			return
+		}
+		recordJobAttempt(job, archiveJobLog(workflowCtx, job, logger))
+		if !waitRetryBackoff(ctx, job.RetryPolicy, attempt) {
+			return
+		}
+		resetJobForRetry(job)
+		ack()
+	}
+}
+
+func runJobAttempt(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
 	job.Status = config.StatusPrepare
 	job.StartTime = time.Now().Unix()
 	job.K8sJobName = getJobName(workflowCtx.WorkflowName, workflowCtx.TaskID)
@@ -135,6 +163,10 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		}
 		job.EndTime = time.Now().Unix()
 		logger.Infof("finish job: %s,status: %s", job.Name, job.Status)
+		metrics.ObserveJobDuration(workflowCtx.ProjectName, workflowCtx.WorkflowName, job.JobType, string(job.Status), job.EndTime-job.StartTime)
+		if jobStatusFailed(job.Status) {
+			metrics.RegisterJobFailure(workflowCtx.ProjectName, workflowCtx.WorkflowName, job.JobType, string(job.Status))
+		}
 		ack()
 		logger.Infof("updating job info into db...")
 		err := jobCtl.SaveInfo(ctx)
@@ -146,6 +178,65 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 	jobCtl.Run(ctx)
 }
 
+// GlobalVariables returns the global variables of the workflow task, keyed by the variable like {{.workflow.params.branch}}.
+func GlobalVariables(workflowCtx *commonmodels.WorkflowTaskCtx) map[string]string {
+	variables := make(map[string]string)
+	workflowCtx.GlobalContextEach(func(k, v string) bool {
+		variables[k] = v
+		return true
+	})
+	return variables
+}
+
+// denyJobByPolicy evaluates the workflow policies before the job runs, returns true if the job is denied by a block policy.
+func denyJobByPolicy(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) bool {
+	if workflowCtx.CheckJobPolicies == nil {
+		return false
+	}
+	if err := workflowCtx.CheckJobPolicies(job); err != nil {
+		logError(job, fmt.Sprintf("job: %s %v", job.Name, err), logger)
+		job.StartTime = time.Now().Unix()
+		job.EndTime = job.StartTime
+		return true
+	}
+	return false
+}
+
+// skipJobByCondition evaluates the job condition with the global variables, returns true if the job should not run.
+// a condition that can not be evaluated fails the job instead of silently running or skipping it.
+func skipJobByCondition(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) bool {
+	if job.When == "" {
+		return false
+	}
+	run, err := commonutil.EvaluateCondition(job.When, GlobalVariables(workflowCtx))
+	if err != nil {
+		logError(job, fmt.Sprintf("job: %s condition error: %v", job.Name, err), logger)
+		job.StartTime = time.Now().Unix()
+		job.EndTime = job.StartTime
+		return true
+	}
+	if run {
+		return false
+	}
+	logger.Infof("skip job: %s, condition %s evaluated to false", job.Name, job.When)
+	job.Status = config.StatusSkipped
+	job.SkipReason = fmt.Sprintf("condition %s evaluated to false", job.When)
+	job.StartTime = time.Now().Unix()
+	job.EndTime = job.StartTime
+	return true
+}
+
+// SkipJobs marks all the given jobs as skipped with the same reason, used when a whole stage is skipped.
+func SkipJobs(jobs []*commonmodels.JobTask, reason string) {
+	now := time.Now().Unix()
+	for _, job := range jobs {
+		job.Status = config.StatusSkipped
+		job.SkipReason = reason
+		job.StartTime = now
+		job.EndTime = now
+	}
+}
+
 func RunJobs(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
 	if concurrency == 1 {
 		for _, job := range jobs {
@@ -256,6 +347,26 @@ func jobStatusFailed(status config.Status) bool {
 	return false
 }
 
+// saveRollbackIncident records a passed rollback job as an incident of the project
+func saveRollbackIncident(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, serviceName string, logger *zap.SugaredLogger) {
+	if job.Status != config.StatusPassed {
+		return
+	}
+	err := commonutil.CreateRollbackIncident(&commonmodels.Incident{
+		ProjectName:  workflowCtx.ProjectName,
+		Title:        fmt.Sprintf("rollback %s by workflow %s", serviceName, workflowCtx.WorkflowDisplayName),
+		Source:       config.IncidentSourceJobRollback,
+		ServiceName:  serviceName,
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+		ResolvedAt:   job.EndTime,
+		CreatedBy:    workflowCtx.WorkflowTaskCreatorUsername,
+	})
+	if err != nil {
+		logger.Errorf("failed to create incident for rollback job %s, error: %v", job.Name, err)
+	}
+}
+
 func logError(job *commonmodels.JobTask, msg string, logger *zap.SugaredLogger) {
 	logger.Error(msg)
 	job.Status = config.StatusFailed
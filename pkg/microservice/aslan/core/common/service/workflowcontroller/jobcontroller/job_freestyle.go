@@ -37,6 +37,7 @@ import (
 	vmmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models/vm"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
 	vmmongodb "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/vm"
+	commonvm "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/vm"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	kubeclient "github.com/koderover/zadig/v2/pkg/shared/kube/client"
@@ -44,6 +45,7 @@ import (
 	krkubeclient "github.com/koderover/zadig/v2/pkg/tool/kube/client"
 	"github.com/koderover/zadig/v2/pkg/tool/kube/informer"
 	"github.com/koderover/zadig/v2/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/v2/pkg/types/step"
 )
 
 const (
@@ -96,6 +98,7 @@ func (c *FreestyleJobCtl) Run(ctx context.Context) {
 		if vmJobID, err = c.runVMJob(ctx); err != nil {
 			return
 		}
+		c.job.VMJobID = vmJobID
 		c.vmJobWait(ctx, vmJobID)
 		c.vmComplete(ctx, vmJobID)
 	} else {
@@ -330,12 +333,18 @@ func (c *FreestyleJobCtl) vmJobWait(ctx context.Context, jobID string) {
 			c.logger.Errorf("update vm job status error: %v", err)
 			c.job.Error = fmt.Errorf("update vm job status %s error: %v", string(config.StatusCancel), err).Error()
 		}
+		if err := commonvm.CancelAgentJob(jobID); err != nil {
+			c.logger.Warnf("failed to push cancel of vm job %s to agent, error: %v", jobID, err)
+		}
 	case config.StatusTimeout:
 		err := vmmongodb.NewVMJobColl().UpdateStatus(jobID, string(config.StatusTimeout))
 		if err != nil {
 			c.logger.Errorf("update vm job status error: %v", err)
 			c.job.Error = fmt.Errorf("update vm job status %s error: %v", string(config.StatusTimeout), err).Error()
 		}
+		if err := commonvm.CancelAgentJob(jobID); err != nil {
+			c.logger.Warnf("failed to push cancel of vm job %s to agent, error: %v", jobID, err)
+		}
 	}
 }
 
@@ -448,6 +457,9 @@ func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskFreestyleSpec, job
 			CacheDirType: jobTaskSpec.Properties.CacheDirType,
 			CacheUserDir: jobTaskSpec.Properties.CacheUserDir,
 		}
+		if jobTaskSpec.Properties.RunInContainer {
+			jobContext.Container = buildJobContainerConfig(jobTaskSpec)
+		}
 	}
 
 	return jobContext
@@ -466,3 +478,27 @@ func (c *FreestyleJobCtl) SaveInfo(ctx context.Context) error {
 		Status:              string(c.job.Status),
 	})
 }
+
+// buildJobContainerConfig returns the container of a vm job, the image is left empty when the build has no image so
+// that the agent refuses to run the job instead of pulling a wrong image.
+func buildJobContainerConfig(jobTaskSpec *commonmodels.JobTaskFreestyleSpec) *JobContainerConfig {
+	containerConfig := &JobContainerConfig{
+		CpuLimit:    jobTaskSpec.Properties.ResReqSpec.CpuLimit,
+		MemoryLimit: jobTaskSpec.Properties.ResReqSpec.MemoryLimit,
+	}
+	if jobTaskSpec.Properties.BuildOS == "" {
+		return containerConfig
+	}
+
+	containerConfig.Image = getBaseImage(jobTaskSpec.Properties.BuildOS, jobTaskSpec.Properties.ImageFrom)
+	for _, registry := range getMatchedRegistries(containerConfig.Image, jobTaskSpec.Properties.Registries) {
+		containerConfig.Registries = append(containerConfig.Registries, &step.DockerRegistry{
+			DockerRegistryID: registry.ID.Hex(),
+			Host:             registry.RegAddr,
+			Namespace:        registry.Namespace,
+			UserName:         registry.AccessKey,
+			Password:         registry.SecretKey,
+		})
+	}
+	return containerConfig
+}
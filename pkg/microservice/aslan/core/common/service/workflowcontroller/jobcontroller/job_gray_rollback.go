@@ -130,6 +130,7 @@ func (c *GrayRollbackJobCtl) timeout() int64 {
 }
 
 func (c *GrayRollbackJobCtl) SaveInfo(ctx context.Context) error {
+	saveRollbackIncident(c.job, c.workflowCtx, c.jobTaskSpec.WorkloadName, c.logger)
 	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
 		Type:                c.job.JobType,
 		WorkflowName:        c.workflowCtx.WorkflowName,
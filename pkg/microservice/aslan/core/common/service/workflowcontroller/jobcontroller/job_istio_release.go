@@ -337,12 +337,12 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 				},
 			}
 
-			c.Infof("Creating virtual service: %s")
+			c.Infof("Creating virtual service: %s", vsName)
 			c.ack()
 
 			_, err := istioClient.VirtualServices(c.jobTaskSpec.Namespace).Create(context.TODO(), zadigVirtualService, v1.CreateOptions{})
 			if err != nil {
-				c.Errorf("failed to create virtual service: %s, err: %", vsName, err)
+				c.Errorf("failed to create virtual service: %s, err: %s", vsName, err)
 				return
 			}
 		}
@@ -205,6 +205,9 @@ func (c *IstioRollbackJobCtl) timeout() int64 {
 }
 
 func (c *IstioRollbackJobCtl) SaveInfo(ctx context.Context) error {
+	if c.jobTaskSpec.Targets != nil {
+		saveRollbackIncident(c.job, c.workflowCtx, c.jobTaskSpec.Targets.WorkloadName, c.logger)
+	}
 	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
 		Type:                c.job.JobType,
 		WorkflowName:        c.workflowCtx.WorkflowName,
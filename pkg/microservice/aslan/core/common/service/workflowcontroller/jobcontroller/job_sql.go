@@ -18,8 +18,7 @@ package jobcontroller
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -27,8 +26,13 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/v2/pkg/tool/sqlexec"
 )
 
+// sqlRollbackTimeout is the timeout of the rollback sql, the rollback does not share the timeout of the job
+const sqlRollbackTimeout = 10 * time.Minute
+
 type SQLJobCtl struct {
 	job         *commonmodels.JobTask
 	workflowCtx *commonmodels.WorkflowTaskCtx
@@ -65,15 +69,10 @@ func (c *SQLJobCtl) Run(ctx context.Context) {
 		return
 	}
 	c.dbInfo = info
+	c.jobTaskSpec.ApprovedBy = c.getApprovedBy()
 
-	switch info.Type {
-	case config.DBInstanceTypeMySQL, config.DBInstanceTypeMariaDB:
-		if err := c.ExecMySQLStatement(); err != nil {
-			logError(c.job, err.Error(), c.logger)
-			return
-		}
-	default:
-		logError(c.job, "invalid db type", c.logger)
+	if err := c.ExecSQLStatements(ctx); err != nil {
+		logError(c.job, err.Error(), c.logger)
 		return
 	}
 
@@ -81,21 +80,122 @@ func (c *SQLJobCtl) Run(ctx context.Context) {
 	return
 }
 
-func (c *SQLJobCtl) ExecMySQLStatement() error {
-	info := c.dbInfo
+// ExecSQLStatements executes the statements of the script one by one and records the result of every statement,
+// the rollback script is executed if any statement failed, the statements are always rolled back in dry run mode.
+func (c *SQLJobCtl) ExecSQLStatements(ctx context.Context) error {
+	sqlConfig, err := commonutil.GetDBInstanceSQLConfig(c.dbInfo)
+	if err != nil {
+		return err
+	}
+	if c.jobTaskSpec.DryRun && !sqlexec.SupportsTransactionalDDL(sqlConfig.Dialect) {
+		return errors.Errorf("dry run is not supported for %s", c.dbInfo.Type)
+	}
+	statements, err := sqlexec.SplitStatements(sqlConfig.Dialect, c.jobTaskSpec.SQL)
+	if err != nil {
+		return errors.Errorf("split SQL error: %v", err)
+	}
+	rollbackStatements, err := sqlexec.SplitStatements(sqlConfig.Dialect, c.jobTaskSpec.RollbackSQL)
+	if err != nil {
+		return errors.Errorf("split rollback SQL error: %v", err)
+	}
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8&multiStatements=true", info.Username, info.Password, info.Host, info.Port))
+	db, err := sqlexec.Open(sqlConfig)
 	if err != nil {
 		return errors.Errorf("connect db error: %v", err)
 	}
 	defer db.Close()
 
-	// 插入示例
-	_, err = db.Exec(c.jobTaskSpec.SQL)
+	if c.jobTaskSpec.DryRun {
+		results, execErr := sqlexec.DryRun(ctx, db, statements)
+		c.jobTaskSpec.Results = convertSQLExecResults(results)
+		c.ack()
+		if execErr != nil {
+			return errors.Errorf("dry run SQL error: %v", execErr)
+		}
+		return nil
+	}
+
+	results, execErr := sqlexec.ExecStatements(ctx, db, statements)
+	c.jobTaskSpec.Results = convertSQLExecResults(results)
+	c.ack()
+	if execErr == nil {
+		return nil
+	}
+	if len(rollbackStatements) == 0 || len(results) == 0 || !results[0].Executed {
+		return errors.Errorf("exec SQL error: %v", execErr)
+	}
+
+	c.logger.Infof("sql job %s failed, executing the rollback sql", c.job.Name)
+	rollbackResults, rollbackErr := sqlexec.Rollback(db, rollbackStatements, sqlRollbackTimeout)
+	c.jobTaskSpec.RollbackResults = convertSQLExecResults(rollbackResults)
+	c.ack()
+	if rollbackErr != nil {
+		return errors.Errorf("exec SQL error: %v, rollback SQL error: %v", execErr, rollbackErr)
+	}
+	return errors.Errorf("exec SQL error: %v, rolled back", execErr)
+}
+
+// getApprovedBy returns the users who approved the stages of the workflow task
+func (c *SQLJobCtl) getApprovedBy() []string {
+	task, err := mongodb.NewworkflowTaskv4Coll().Find(c.workflowCtx.WorkflowName, c.workflowCtx.TaskID)
 	if err != nil {
-		return errors.Errorf("exec SQL error: %v", err)
+		c.logger.Warnf("failed to find workflow task to get the approvers: %v", err)
+		return nil
+	}
+	resp := []string{}
+	for _, stage := range task.Stages {
+		approval := stage.Approval
+		if approval == nil || !approval.Enabled || approval.Status != config.StatusPassed {
+			continue
+		}
+		if approval.NativeApproval != nil {
+			for _, user := range approval.NativeApproval.ApproveUsers {
+				if user.RejectOrApprove == config.Approve {
+					resp = append(resp, user.UserName)
+				}
+			}
+		}
+		if approval.LarkApproval != nil {
+			for _, node := range approval.LarkApproval.ApprovalNodes {
+				for _, user := range node.ApproveUsers {
+					if user.RejectOrApprove == config.Approve {
+						resp = append(resp, user.Name)
+					}
+				}
+			}
+		}
+		if approval.DingTalkApproval != nil {
+			for _, node := range approval.DingTalkApproval.ApprovalNodes {
+				for _, user := range node.ApproveUsers {
+					if user.RejectOrApprove == config.Approve {
+						resp = append(resp, user.Name)
+					}
+				}
+			}
+		}
+	}
+	return resp
+}
+
+func convertSQLExecResults(results []*sqlexec.StatementResult) []*commonmodels.SQLExecResult {
+	resp := make([]*commonmodels.SQLExecResult, 0, len(results))
+	for _, result := range results {
+		execResult := &commonmodels.SQLExecResult{
+			SQL:          result.SQL,
+			Status:       config.StatusPassed,
+			RowsAffected: result.RowsAffected,
+			ElapsedTime:  result.Duration.Milliseconds(),
+		}
+		switch {
+		case !result.Executed:
+			execResult.Status = config.StatusSkipped
+		case result.Err != nil:
+			execResult.Status = config.StatusFailed
+			execResult.Error = result.Err.Error()
+		}
+		resp = append(resp, execResult)
 	}
-	return nil
+	return resp
 }
 
 func (c *SQLJobCtl) SaveInfo(ctx context.Context) error {
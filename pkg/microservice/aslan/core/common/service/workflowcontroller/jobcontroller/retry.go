@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	vmmongodb "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/vm"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	s3tool "github.com/koderover/zadig/v2/pkg/tool/s3"
+)
+
+const (
+	defaultJobRetryInterval = 10 * time.Second
+	maxJobRetryInterval     = 10 * time.Minute
+)
+
+// ClassifyJobFailure returns the failure class of a finished job by its status and error message.
+func ClassifyJobFailure(status config.Status, errMsg string) config.JobFailureClass {
+	if status == config.StatusTimeout {
+		return config.JobFailureTimeout
+	}
+	switch {
+	case strings.Contains(errMsg, "Evicted") || strings.Contains(errMsg, "evicted"):
+		return config.JobFailurePodEvicted
+	case strings.Contains(errMsg, "ImagePullBackOff") || strings.Contains(errMsg, "ErrImagePull") || strings.Contains(errMsg, "ErrImageNeverPull"):
+		return config.JobFailureImagePullError
+	}
+	return config.JobFailureOther
+}
+
+func retryEnabled(policy *commonmodels.RetryPolicy) bool {
+	return policy != nil && policy.MaxAttempts > 1
+}
+
+// shouldRetryJob returns true if the failed job should be run again by its retry policy,
+// cancelled jobs and jobs which used up their attempts are never retried.
+func shouldRetryJob(ctx context.Context, job *commonmodels.JobTask, attempt int, logger *zap.SugaredLogger) bool {
+	policy := job.RetryPolicy
+	if !retryEnabled(policy) || attempt >= policy.MaxAttempts {
+		return false
+	}
+	if job.Status != config.StatusFailed && job.Status != config.StatusTimeout {
+		return false
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	class := ClassifyJobFailure(job.Status, job.Error)
+	if len(policy.RetryOn) > 0 {
+		matched := false
+		for _, retryOn := range policy.RetryOn {
+			if retryOn == class {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			logger.Infof("job: %s failed with %s, which is not in the retry policy", job.Name, class)
+			return false
+		}
+	}
+	logger.Infof("job: %s failed with %s, retry attempt %d of %d", job.Name, class, attempt+1, policy.MaxAttempts)
+	return true
+}
+
+// RetryBackoffInterval returns the time to wait before the next attempt after the given attempt failed.
+func RetryBackoffInterval(policy *commonmodels.RetryPolicy, attempt int) time.Duration {
+	interval := defaultJobRetryInterval
+	if policy.Interval > 0 {
+		interval = time.Duration(policy.Interval) * time.Second
+	}
+	if policy.Backoff == config.RetryBackoffExponential {
+		for i := 1; i < attempt && interval < maxJobRetryInterval; i++ {
+			interval *= 2
+		}
+	}
+	if interval > maxJobRetryInterval {
+		interval = maxJobRetryInterval
+	}
+	return interval
+}
+
+// waitRetryBackoff returns false if the workflow was cancelled during the wait.
+func waitRetryBackoff(ctx context.Context, policy *commonmodels.RetryPolicy, attempt int) bool {
+	timer := time.NewTimer(RetryBackoffInterval(policy, attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// recordJobAttempt appends the finished attempt to the history of the job if it has a retry policy.
+func recordJobAttempt(job *commonmodels.JobTask, logName string) {
+	if !retryEnabled(job.RetryPolicy) {
+		return
+	}
+	attempt := &commonmodels.JobAttempt{
+		Attempt:    len(job.Attempts) + 1,
+		Status:     job.Status,
+		Error:      job.Error,
+		StartTime:  job.StartTime,
+		EndTime:    job.EndTime,
+		K8sJobName: job.K8sJobName,
+		LogName:    logName,
+	}
+	if jobStatusFailed(job.Status) {
+		attempt.FailureClass = ClassifyJobFailure(job.Status, job.Error)
+	}
+	job.Attempts = append(job.Attempts, attempt)
+}
+
+func resetJobForRetry(job *commonmodels.JobTask) {
+	job.Status = config.StatusPrepare
+	job.Error = ""
+	job.StartTime = 0
+	job.EndTime = 0
+}
+
+// archiveJobLog copies the saved log of the failed attempt so it will not be overwritten by the next attempt,
+// returns the job name used to get the archived log, the job name is returned if there is no log to archive.
+func archiveJobLog(workflowCtx *commonmodels.WorkflowTaskCtx, job *commonmodels.JobTask, logger *zap.SugaredLogger) string {
+	// only the logs of the freestyle like jobs are saved into the object storage
+	switch config.JobType(job.JobType) {
+	case config.JobFreestyle, config.JobZadigBuild, config.JobZadigTesting, config.JobZadigScanning, config.JobPlugin:
+	default:
+		return job.Name
+	}
+	logName := fmt.Sprintf("%s-attempt-%d", job.Name, len(job.Attempts)+1)
+
+	store, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		logger.Errorf("archive job: %s log, failed to get default s3 storage: %v", job.Name, err)
+		return job.Name
+	}
+	if store.Subfolder != "" {
+		store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, strings.ToLower(workflowCtx.WorkflowName), workflowCtx.TaskID, "log")
+	} else {
+		store.Subfolder = fmt.Sprintf("%s/%d/%s", strings.ToLower(workflowCtx.WorkflowName), workflowCtx.TaskID, "log")
+	}
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Region, store.Insecure, forcedPathStyle)
+	if err != nil {
+		logger.Errorf("archive job: %s log, s3 create client error: %v", job.Name, err)
+		return job.Name
+	}
+	oldKey := GetObjectPath(store.Subfolder, strings.Replace(strings.ToLower(job.Name), "_", "-", -1)+".log")
+	newKey := GetObjectPath(store.Subfolder, strings.Replace(strings.ToLower(logName), "_", "-", -1)+".log")
+
+	// the log of a vm job is uploaded when the agent reports the end of the job, a job that timed out or was cancelled
+	// may not be reported yet, so the log streamed to this instance so far is archived instead.
+	if job.Infrastructure == setting.JobVMInfrastructure {
+		if logFile := vmJobLogFile(job.VMJobID); logFile != "" {
+			if err := s3client.Upload(store.Bucket, logFile, newKey); err != nil {
+				logger.Errorf("archive job: %s log error: %v", job.Name, err)
+				return job.Name
+			}
+			return logName
+		}
+	}
+	if err := s3client.CopyObject(store.Bucket, oldKey, newKey); err != nil {
+		logger.Errorf("archive job: %s log error: %v", job.Name, err)
+		return job.Name
+	}
+	return logName
+}
+
+// vmJobLogFile returns the local log file of the vm job if it has not been uploaded and removed yet.
+func vmJobLogFile(vmJobID string) string {
+	if vmJobID == "" {
+		return ""
+	}
+	vmJob, err := vmmongodb.NewVMJobColl().FindByID(vmJobID)
+	if err != nil || vmJob.LogFile == "" {
+		return ""
+	}
+	if _, err := os.Stat(vmJob.LogFile); err != nil {
+		return ""
+	}
+	return vmJob.LogFile
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func TestClassifyJobFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status config.Status
+		errMsg string
+		want   config.JobFailureClass
+	}{
+		{name: "timeout", status: config.StatusTimeout, errMsg: "job timeout", want: config.JobFailureTimeout},
+		{name: "timeout with evicted pod", status: config.StatusTimeout, errMsg: "pod Evicted", want: config.JobFailureTimeout},
+		{name: "evicted pod", status: config.StatusFailed, errMsg: "pod test was Evicted: the node was low on resource", want: config.JobFailurePodEvicted},
+		{name: "evicted pod in lower case", status: config.StatusFailed, errMsg: "pod was evicted", want: config.JobFailurePodEvicted},
+		{name: "image pull back off", status: config.StatusFailed, errMsg: "container is waiting: ImagePullBackOff", want: config.JobFailureImagePullError},
+		{name: "image pull error", status: config.StatusFailed, errMsg: "ErrImagePull: not found", want: config.JobFailureImagePullError},
+		{name: "image never pulled", status: config.StatusFailed, errMsg: "ErrImageNeverPull", want: config.JobFailureImagePullError},
+		{name: "other", status: config.StatusFailed, errMsg: "exit code 1", want: config.JobFailureOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyJobFailure(tt.status, tt.errMsg))
+		})
+	}
+}
+
+func TestRetryBackoffInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *commonmodels.RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{name: "default interval", policy: &commonmodels.RetryPolicy{}, attempt: 1, want: defaultJobRetryInterval},
+		{name: "fixed", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffFixed, Interval: 5}, attempt: 3, want: 5 * time.Second},
+		{name: "exponential first retry", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffExponential, Interval: 5}, attempt: 1, want: 5 * time.Second},
+		{name: "exponential second retry", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffExponential, Interval: 5}, attempt: 2, want: 10 * time.Second},
+		{name: "exponential third retry", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffExponential, Interval: 5}, attempt: 3, want: 20 * time.Second},
+		{name: "exponential default interval", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffExponential}, attempt: 2, want: 2 * defaultJobRetryInterval},
+		{name: "exponential capped", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffExponential, Interval: 60}, attempt: 9, want: maxJobRetryInterval},
+		{name: "fixed capped", policy: &commonmodels.RetryPolicy{Backoff: config.RetryBackoffFixed, Interval: 3600}, attempt: 1, want: maxJobRetryInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RetryBackoffInterval(tt.policy, tt.attempt))
+		})
+	}
+}
+
+func TestShouldRetryJob(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	policy := &commonmodels.RetryPolicy{MaxAttempts: 3}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		job     *commonmodels.JobTask
+		attempt int
+		want    bool
+	}{
+		{
+			name:    "no retry policy",
+			job:     &commonmodels.JobTask{Status: config.StatusFailed},
+			attempt: 1,
+		},
+		{
+			name:    "single attempt",
+			job:     &commonmodels.JobTask{Status: config.StatusFailed, RetryPolicy: &commonmodels.RetryPolicy{MaxAttempts: 1}},
+			attempt: 1,
+		},
+		{
+			name:    "failed",
+			job:     &commonmodels.JobTask{Status: config.StatusFailed, RetryPolicy: policy},
+			attempt: 1,
+			want:    true,
+		},
+		{
+			name:    "timeout",
+			job:     &commonmodels.JobTask{Status: config.StatusTimeout, RetryPolicy: policy},
+			attempt: 2,
+			want:    true,
+		},
+		{
+			name:    "max attempts used up",
+			job:     &commonmodels.JobTask{Status: config.StatusFailed, RetryPolicy: policy},
+			attempt: 3,
+		},
+		{
+			name:    "passed",
+			job:     &commonmodels.JobTask{Status: config.StatusPassed, RetryPolicy: policy},
+			attempt: 1,
+		},
+		{
+			name:    "cancelled job",
+			job:     &commonmodels.JobTask{Status: config.StatusCancelled, RetryPolicy: policy},
+			attempt: 1,
+		},
+		{
+			name:    "cancelled workflow",
+			ctx:     cancelled,
+			job:     &commonmodels.JobTask{Status: config.StatusFailed, RetryPolicy: policy},
+			attempt: 1,
+		},
+		{
+			name: "failure class in retry on",
+			job: &commonmodels.JobTask{Status: config.StatusFailed, Error: "pod Evicted", RetryPolicy: &commonmodels.RetryPolicy{
+				MaxAttempts: 3,
+				RetryOn:     []config.JobFailureClass{config.JobFailureTimeout, config.JobFailurePodEvicted},
+			}},
+			attempt: 1,
+			want:    true,
+		},
+		{
+			name: "failure class not in retry on",
+			job: &commonmodels.JobTask{Status: config.StatusFailed, Error: "exit code 1", RetryPolicy: &commonmodels.RetryPolicy{
+				MaxAttempts: 3,
+				RetryOn:     []config.JobFailureClass{config.JobFailureTimeout, config.JobFailurePodEvicted},
+			}},
+			attempt: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			assert.Equal(t, tt.want, shouldRetryJob(ctx, tt.job, tt.attempt, zap.NewNop().Sugar()))
+		})
+	}
+}
+
+func TestWaitRetryBackoffCancelled(t *testing.T) {
+	policy := &commonmodels.RetryPolicy{MaxAttempts: 3, Interval: 60}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	assert.False(t, waitRetryBackoff(ctx, policy, 1))
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestJobAttemptHistory(t *testing.T) {
+	job := &commonmodels.JobTask{
+		Name:        "build",
+		RetryPolicy: &commonmodels.RetryPolicy{MaxAttempts: 3},
+		Status:      config.StatusFailed,
+		Error:       "pod Evicted",
+		StartTime:   100,
+		EndTime:     200,
+		K8sJobName:  "build-1",
+	}
+	recordJobAttempt(job, "build-attempt-1")
+	resetJobForRetry(job)
+	assert.Equal(t, config.StatusPrepare, job.Status)
+	assert.Empty(t, job.Error)
+	assert.Zero(t, job.StartTime)
+	assert.Zero(t, job.EndTime)
+
+	job.Status = config.StatusPassed
+	job.StartTime, job.EndTime, job.K8sJobName = 210, 300, "build-2"
+	recordJobAttempt(job, job.Name)
+
+	assert.Equal(t, []*commonmodels.JobAttempt{
+		{
+			Attempt:      1,
+			Status:       config.StatusFailed,
+			Error:        "pod Evicted",
+			FailureClass: config.JobFailurePodEvicted,
+			StartTime:    100,
+			EndTime:      200,
+			K8sJobName:   "build-1",
+			LogName:      "build-attempt-1",
+		},
+		{
+			Attempt:    2,
+			Status:     config.StatusPassed,
+			StartTime:  210,
+			EndTime:    300,
+			K8sJobName: "build-2",
+			LogName:    "build",
+		},
+	}, job.Attempts)
+}
+
+func TestJobAttemptHistoryWithoutRetryPolicy(t *testing.T) {
+	job := &commonmodels.JobTask{Name: "build", Status: config.StatusFailed}
+	recordJobAttempt(job, job.Name)
+	assert.Empty(t, job.Attempts)
+}
@@ -21,6 +21,7 @@ import (
 
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/v2/pkg/types"
+	"github.com/koderover/zadig/v2/pkg/types/step"
 )
 
 type JobContext struct {
@@ -45,6 +46,8 @@ type JobContext struct {
 	Outputs []string                 `yaml:"outputs"`
 	// used to vm job
 	Cache *JobCacheConfig `yaml:"cache"`
+	// Container is set when the vm job runs in a container on the agent host
+	Container *JobContainerConfig `yaml:"container,omitempty"`
 }
 
 func (j *JobContext) Decode(job string) error {
@@ -60,6 +63,15 @@ type JobCacheConfig struct {
 	CacheUserDir string             `json:"cache_user_dir"`
 }
 
+// JobContainerConfig is the container a vm job runs in, the limits are in the units of setting.RequestSpec
+type JobContainerConfig struct {
+	Image       string `yaml:"image"`
+	CpuLimit    int    `yaml:"cpu_limit"`
+	MemoryLimit int    `yaml:"memory_limit"`
+	// Registries are the registries matching the image, the agent logs in to them to pull the image
+	Registries []*step.DockerRegistry `yaml:"registries,omitempty"`
+}
+
 type EnvVar []string
 
 // Proxy 翻墙配置信息
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/tool/metrics"
+)
+
+// NormalClusterIDs returns the IDs of all connected clusters, used to collect the dind and executor metrics
+func NormalClusterIDs() []string {
+	clusters, err := commonrepo.NewK8SClusterColl().List(&commonrepo.ClusterListOpts{})
+	if err != nil {
+		log.Errorf("failed to list clusters for metrics, err: %v", err)
+		return nil
+	}
+
+	resp := make([]string, 0)
+	for _, cluster := range clusters {
+		if cluster.Status != setting.Normal {
+			continue
+		}
+		resp = append(resp, cluster.ID.Hex())
+	}
+	return resp
+}
+
+func observeWorkflowTaskDone(workflowTask *commonmodels.WorkflowTask) {
+	if workflowTask.StartTime == 0 || workflowTask.EndTime < workflowTask.StartTime {
+		return
+	}
+	metrics.ObserveWorkflowDuration(workflowTask.ProjectName, workflowTask.WorkflowName, string(workflowTask.Status), workflowTask.EndTime-workflowTask.StartTime)
+}
@@ -80,6 +80,19 @@ func CreateTask(t *commonmodels.WorkflowTask) error {
 	return Push(t)
 }
 
+// CreateRejectedTask saves the task rejected before it runs, the task is kept in the task list with the reason but
+// never sent to the queue
+func CreateRejectedTask(t *commonmodels.WorkflowTask, reason string) error {
+	t.Status = config.StatusReject
+	t.Error = reason
+	t.EndTime = time.Now().Unix()
+	if _, err := commonrepo.NewworkflowTaskv4Coll().Create(t); err != nil {
+		log.Errorf("create rejected workflow task v4 error: %v", err)
+		return err
+	}
+	return nil
+}
+
 func UpdateTask(t *commonmodels.WorkflowTask) error {
 	t.Status = config.StatusWaiting
 	if err := commonrepo.NewworkflowTaskv4Coll().Update(t.ID.Hex(), t); err != nil {
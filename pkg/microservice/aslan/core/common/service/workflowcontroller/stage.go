@@ -33,9 +33,12 @@ import (
 	dingservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/dingtalk"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/instantmessage"
 	larkservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/lark"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/v2/pkg/tool/dingtalk"
 	"github.com/koderover/zadig/v2/pkg/tool/lark"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/tool/metrics"
 )
 
 type StageCtl interface {
@@ -67,11 +70,31 @@ func runStage(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *c
 }
 
 func RunStages(ctx context.Context, stages []*commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	if IsDAGWorkflowTask(stages) {
+		RunDAG(ctx, stages, workflowCtx, concurrency, logger, ack)
+		return
+	}
 	for _, stage := range stages {
 		// should skip passed stage when workflow task be restarted
 		if stage.Status == config.StatusPassed {
 			continue
 		}
+		run, err := evaluateStageCondition(stage, workflowCtx)
+		if err != nil {
+			stage.Status = config.StatusFailed
+			stage.Error = err.Error()
+			logger.Errorf("stage: %s condition error: %v", stage.Name, err)
+			ack()
+			return
+		}
+		if !run {
+			stage.Status = config.StatusSkipped
+			stage.SkipReason = fmt.Sprintf("condition %s evaluated to false", stage.When)
+			jobcontroller.SkipJobs(stage.Jobs, stage.SkipReason)
+			logger.Infof("skip stage: %s, %s", stage.Name, stage.SkipReason)
+			ack()
+			continue
+		}
 		runStage(ctx, stage, workflowCtx, concurrency, logger, ack)
 		if statusFailed(stage.Status) {
 			return
@@ -79,13 +102,21 @@ func RunStages(ctx context.Context, stages []*commonmodels.StageTask, workflowCt
 	}
 }
 
-func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool) error {
+// evaluateStageCondition evaluates the stage condition with the global variables.
+func evaluateStageCondition(stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx) (bool, error) {
+	if stage.When == "" {
+		return true, nil
+	}
+	return commonutil.EvaluateCondition(stage.When, jobcontroller.GlobalVariables(workflowCtx))
+}
+
+func ApproveStage(workflowName, stageName, userName, userID, identityType, comment string, taskID int64, approve bool) error {
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowName, taskID, stageName)
 	approveWithL, ok := approvalservice.GlobalApproveMap.GetApproval(approveKey)
 	if !ok {
 		return fmt.Errorf("workflow %s ID %d stage %s do not need approve", workflowName, taskID, stageName)
 	}
-	return approveWithL.DoApproval(userName, userID, comment, approve)
+	return approveWithL.DoApproval(userName, userID, identityType, comment, approve)
 }
 
 func waitForApprove(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) (err error) {
@@ -109,6 +140,7 @@ func waitForApprove(ctx context.Context, stage *commonmodels.StageTask, workflow
 		} else {
 			stage.Approval.Status = stage.Status
 		}
+		metrics.ObserveApprovalWaitTime(workflowCtx.ProjectName, workflowCtx.WorkflowName, string(stage.Approval.Status), stage.Approval.EndTime-stage.Approval.StartTime)
 	}()
 	// workflowCtx.SetStatus contain ack() function, so we don't need to call ack() here
 	stage.Status = config.StatusWaitingApprove
@@ -34,11 +34,13 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/instantmessage"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/scmnotify"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowpolicy"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowstat"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	kubeclient "github.com/koderover/zadig/v2/pkg/shared/kube/client"
 	"github.com/koderover/zadig/v2/pkg/tool/kube/updater"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/tool/metrics"
 )
 
 var cancelChannelMap sync.Map
@@ -113,6 +115,12 @@ func (c *workflowCtl) setWorkflowStatus(status config.Status) {
 	c.ack()
 }
 
+func (c *workflowCtl) checkJobPolicies(job *commonmodels.JobTask) error {
+	violations, err := workflowpolicy.EvaluateJob(c.workflowTask, job, c.logger)
+	job.PolicyViolations = violations
+	return err
+}
+
 func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 	if c.workflowTask.GlobalContext == nil {
 		c.workflowTask.GlobalContext = make(map[string]string)
@@ -126,6 +134,9 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 
 	c.workflowTask.Status = config.StatusRunning
 	c.workflowTask.StartTime = time.Now().Unix()
+	if !c.workflowTask.IsRestart && c.workflowTask.CreateTime > 0 {
+		metrics.ObserveWorkflowQueueWaitTime(c.workflowTask.ProjectName, c.workflowTask.WorkflowName, c.workflowTask.StartTime-c.workflowTask.CreateTime)
+	}
 	c.ack()
 	c.logger.Infof("start workflow: %s,status: %s", c.workflowTask.WorkflowName, c.workflowTask.Status)
 	defer func() {
@@ -160,6 +171,7 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 		GlobalContextEach:           c.globalContextEach,
 		ClusterIDAdd:                c.addCluterID,
 		SetStatus:                   c.setWorkflowStatus,
+		CheckJobPolicies:            c.checkJobPolicies,
 	}
 	defer jobcontroller.CleanWorkflowJobs(ctx, c.workflowTask, workflowCtx, c.logger, c.ack)
 	if err := scmnotify.NewService().UpdateWebhookCommentForWorkflowV4(c.workflowTask, c.logger); err != nil {
@@ -265,6 +277,7 @@ func (c *workflowCtl) updateWorkflowTask() {
 		if err := workflowstat.UpdateWorkflowStat(c.workflowTask.WorkflowName, string(config.WorkflowTypeV4), string(c.workflowTask.Status), c.workflowTask.ProjectName, c.workflowTask.EndTime-c.workflowTask.StartTime, c.workflowTask.IsRestart); err != nil {
 			log.Warnf("Failed to update workflow stat for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
 		}
+		observeWorkflowTaskDone(c.workflowTask)
 	}
 }
 
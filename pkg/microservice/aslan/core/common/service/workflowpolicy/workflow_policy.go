@@ -0,0 +1,263 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/picket/client/opa"
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+const (
+	// PackagePrefix is the required prefix of the rego package of a workflow policy,
+	// it keeps the workflow policies apart from the rbac policies in OPA.
+	PackagePrefix = "workflow_policy."
+	// denyRule is the set of violation messages every workflow policy must define
+	denyRule = "deny"
+)
+
+var packageRegex = regexp.MustCompile(`(?m)^\s*package\s+([A-Za-z_][A-Za-z0-9_.]*)\s*(#.*)?$`)
+
+// queryPolicy returns the violation messages of the policy, it's replaced in the tests
+var queryPolicy = evaluatePolicy
+
+// Input is the input document the workflow policies are evaluated with
+type Input struct {
+	Checkpoint   config.PolicyCheckpoint   `json:"checkpoint"`
+	ProjectName  string                    `json:"project_name"`
+	WorkflowName string                    `json:"workflow_name"`
+	TaskID       int64                     `json:"task_id"`
+	User         string                    `json:"user"`
+	Time         *InputTime                `json:"time"`
+	Stages       []*commonmodels.StageTask `json:"stages"`
+	// Job is the job about to run, it is only set at the job_run checkpoint
+	Job *commonmodels.JobTask `json:"job,omitempty"`
+}
+
+// InputTime is the evaluation time in the timezone of aslan
+type InputTime struct {
+	Unix   int64 `json:"unix"`
+	Hour   int   `json:"hour"`
+	Minute int   `json:"minute"`
+	// Weekday starts from 0 which is Sunday
+	Weekday int `json:"weekday"`
+}
+
+type denyResult struct {
+	Result *[]interface{} `json:"result"`
+}
+
+// ParsePackage returns the package declared in the rego module
+func ParsePackage(rego string) (string, error) {
+	match := packageRegex.FindStringSubmatch(rego)
+	if len(match) < 2 {
+		return "", fmt.Errorf("package is not declared in the rego")
+	}
+	if !strings.HasPrefix(match[1], PackagePrefix) {
+		return "", fmt.Errorf("package %s must start with %s", match[1], PackagePrefix)
+	}
+	return match[1], nil
+}
+
+// LoadPolicy pushes the rego of the policy to OPA, an invalid module is rejected by the compiler of OPA
+func LoadPolicy(policy *commonmodels.WorkflowPolicy) error {
+	if err := opa.NewDefault().UpsertPolicy(opaPolicyID(policy.Name), policy.Rego); err != nil {
+		return fmt.Errorf("failed to load policy %s into opa: %s", policy.Name, err)
+	}
+	return nil
+}
+
+// UnloadPolicy removes the policy from OPA
+func UnloadPolicy(name string) error {
+	return opa.NewDefault().DeletePolicy(opaPolicyID(name))
+}
+
+// EvaluateTaskCreation evaluates the enabled policies of the project against the task to be created.
+// It returns the violations to be recorded in the task, and an error if the task is denied by a block policy.
+func EvaluateTaskCreation(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) ([]*commonmodels.PolicyViolation, error) {
+	return evaluate(config.PolicyCheckpointTaskCreate, task, nil, logger)
+}
+
+// EvaluateJob evaluates the enabled policies of the project before the job runs.
+// It returns the violations to be recorded in the job, and an error if the job is denied by a block policy.
+func EvaluateJob(task *commonmodels.WorkflowTask, job *commonmodels.JobTask, logger *zap.SugaredLogger) ([]*commonmodels.PolicyViolation, error) {
+	return evaluate(config.PolicyCheckpointJobRun, task, job, logger)
+}
+
+func evaluate(checkpoint config.PolicyCheckpoint, task *commonmodels.WorkflowTask, job *commonmodels.JobTask, logger *zap.SugaredLogger) ([]*commonmodels.PolicyViolation, error) {
+	policies, err := commonrepo.NewWorkflowPolicyColl().List(true)
+	if err != nil {
+		logger.Errorf("failed to list workflow policies, error: %s", err)
+		return nil, fmt.Errorf("failed to list workflow policies: %s", err)
+	}
+
+	violations, decisions, err := evaluatePolicies(policies, checkpoint, task, job, time.Now(), logger)
+	for _, decision := range decisions {
+		if err := commonrepo.NewWorkflowPolicyDecisionColl().Create(decision); err != nil {
+			logger.Errorf("failed to save the decision of workflow policy %s, error: %s", decision.PolicyName, err)
+		}
+	}
+	return violations, err
+}
+
+// evaluatePolicies evaluates the policies matching the project of the task and returns the violations and the decisions
+// to be logged. A policy which can't be evaluated is violated, so a block policy can't be bypassed by breaking OPA.
+func evaluatePolicies(policies []*commonmodels.WorkflowPolicy, checkpoint config.PolicyCheckpoint, task *commonmodels.WorkflowTask, job *commonmodels.JobTask, now time.Time, logger *zap.SugaredLogger) ([]*commonmodels.PolicyViolation, []*commonmodels.WorkflowPolicyDecision, error) {
+	input := &Input{
+		Checkpoint:   checkpoint,
+		ProjectName:  task.ProjectName,
+		WorkflowName: task.WorkflowName,
+		TaskID:       task.TaskID,
+		User:         task.TaskCreator,
+		Time: &InputTime{
+			Unix:    now.Unix(),
+			Hour:    now.Hour(),
+			Minute:  now.Minute(),
+			Weekday: int(now.Weekday()),
+		},
+		Stages: task.Stages,
+		Job:    job,
+	}
+
+	violations := make([]*commonmodels.PolicyViolation, 0)
+	decisions := make([]*commonmodels.WorkflowPolicyDecision, 0)
+	denied := make([]string, 0)
+	for _, policy := range policies {
+		if !policyMatchProject(policy, task.ProjectName) {
+			continue
+		}
+
+		messages, evalErr := queryPolicy(policy, input)
+		decision := &commonmodels.WorkflowPolicyDecision{
+			PolicyName:   policy.Name,
+			Enforcement:  policy.Enforcement,
+			Checkpoint:   checkpoint,
+			ProjectName:  task.ProjectName,
+			WorkflowName: task.WorkflowName,
+			TaskID:       task.TaskID,
+			User:         task.TaskCreator,
+			Allowed:      evalErr == nil && len(messages) == 0,
+			Messages:     messages,
+			CreateTime:   now.Unix(),
+		}
+		if job != nil {
+			decision.JobName = job.Name
+		}
+		if evalErr != nil {
+			logger.Errorf("failed to evaluate workflow policy %s, error: %s", policy.Name, evalErr)
+			decision.Error = evalErr.Error()
+			messages = []string{fmt.Sprintf("failed to evaluate the policy: %s", evalErr)}
+		}
+		decisions = append(decisions, decision)
+		if decision.Allowed {
+			continue
+		}
+
+		violations = append(violations, &commonmodels.PolicyViolation{
+			PolicyName:  policy.Name,
+			Enforcement: policy.Enforcement,
+			Checkpoint:  checkpoint,
+			Messages:    messages,
+		})
+		if policy.Enforcement == config.PolicyEnforcementBlock {
+			denied = append(denied, fmt.Sprintf("%s: %s", policy.Name, strings.Join(messages, "; ")))
+		}
+	}
+
+	if len(denied) > 0 {
+		return violations, decisions, fmt.Errorf("denied by workflow policy %s", strings.Join(denied, ", "))
+	}
+	return violations, decisions, nil
+}
+
+// evaluatePolicy returns the sorted violation messages of the policy
+func evaluatePolicy(policy *commonmodels.WorkflowPolicy, input *Input) ([]string, error) {
+	if err := ensurePolicyLoaded(policy); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("%s.%s", policy.Package, denyRule)
+	res := &denyResult{}
+	if err := opa.NewDefault().Query(path, input, res); err != nil {
+		return nil, err
+	}
+	if res.Result == nil {
+		// the module is gone if OPA has been restarted, push it again and retry once
+		if err := LoadPolicy(policy); err != nil {
+			return nil, err
+		}
+		res = &denyResult{}
+		if err := opa.NewDefault().Query(path, input, res); err != nil {
+			return nil, err
+		}
+		if res.Result == nil {
+			return nil, fmt.Errorf("rule %s is not defined", path)
+		}
+	}
+
+	messages := make([]string, 0, len(*res.Result))
+	for _, item := range *res.Result {
+		if msg, ok := item.(string); ok {
+			messages = append(messages, msg)
+			continue
+		}
+		b, _ := json.Marshal(item)
+		messages = append(messages, string(b))
+	}
+	sort.Strings(messages)
+	return messages, nil
+}
+
+// ensurePolicyLoaded pushes the policy to OPA unless OPA already has the same rego. The module in OPA is checked on
+// every evaluation instead of being cached, since the policy may be updated or pushed by other aslan replicas.
+func ensurePolicyLoaded(policy *commonmodels.WorkflowPolicy) error {
+	raw, err := opa.NewDefault().GetPolicy(opaPolicyID(policy.Name))
+	if err != nil && !httpclient.IsNotFound(err) {
+		return fmt.Errorf("failed to get policy %s from opa: %s", policy.Name, err)
+	}
+	if err == nil && raw == policy.Rego {
+		return nil
+	}
+	return LoadPolicy(policy)
+}
+
+func policyMatchProject(policy *commonmodels.WorkflowPolicy, projectName string) bool {
+	if len(policy.Projects) == 0 {
+		return true
+	}
+	for _, project := range policy.Projects {
+		if project == projectName {
+			return true
+		}
+	}
+	return false
+}
+
+func opaPolicyID(name string) string {
+	return fmt.Sprintf("zadig-workflow-policy-%s", name)
+}
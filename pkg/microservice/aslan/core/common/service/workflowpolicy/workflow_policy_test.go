@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowpolicy
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&log.Config{Level: "info"})
+	os.Exit(m.Run())
+}
+
+func TestParsePackage(t *testing.T) {
+	tests := []struct {
+		name    string
+		rego    string
+		pkg     string
+		wantErr bool
+	}{
+		{name: "package", rego: "package workflow_policy.approval\n\ndeny[msg] { false; msg := \"\" }", pkg: "workflow_policy.approval"},
+		{name: "comment and spaces", rego: "# the approval policy\n  package workflow_policy.prod.approval # prod only\n", pkg: "workflow_policy.prod.approval"},
+		{name: "rbac package", rego: "package rbac\n", wantErr: true},
+		{name: "no package", rego: "deny[msg] { msg := \"denied\" }", wantErr: true},
+		{name: "package in a comment", rego: "# package workflow_policy.approval\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := ParsePackage(tt.rego)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pkg, pkg)
+		})
+	}
+}
+
+func TestPolicyMatchProject(t *testing.T) {
+	assert.True(t, policyMatchProject(&commonmodels.WorkflowPolicy{}, "project"))
+	assert.True(t, policyMatchProject(&commonmodels.WorkflowPolicy{Projects: []string{"other", "project"}}, "project"))
+	assert.False(t, policyMatchProject(&commonmodels.WorkflowPolicy{Projects: []string{"other"}}, "project"))
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	results := map[string][]string{
+		"allow":        nil,
+		"warn":         {"no approval"},
+		"block":        {"image not allowed"},
+		"other-block":  {"never evaluated"},
+		"broken-block": nil,
+	}
+	queryPolicy = func(policy *commonmodels.WorkflowPolicy, input *Input) ([]string, error) {
+		if policy.Name == "broken-block" {
+			return nil, fmt.Errorf("opa is unavailable")
+		}
+		return results[policy.Name], nil
+	}
+	defer func() { queryPolicy = evaluatePolicy }()
+
+	newPolicy := func(name string, enforcement config.PolicyEnforcement, projects ...string) *commonmodels.WorkflowPolicy {
+		return &commonmodels.WorkflowPolicy{Name: name, Enforcement: enforcement, Projects: projects}
+	}
+	task := &commonmodels.WorkflowTask{ProjectName: "project", WorkflowName: "workflow", TaskID: 1}
+
+	t.Run("allowed and warned", func(t *testing.T) {
+		violations, decisions, err := evaluatePolicies([]*commonmodels.WorkflowPolicy{
+			newPolicy("allow", config.PolicyEnforcementBlock),
+			newPolicy("warn", config.PolicyEnforcementWarn),
+			newPolicy("other-block", config.PolicyEnforcementBlock, "other"),
+		}, config.PolicyCheckpointTaskCreate, task, nil, time.Now(), log.SugaredLogger())
+		assert.NoError(t, err)
+		assert.Len(t, decisions, 2)
+		assert.True(t, decisions[0].Allowed)
+		assert.False(t, decisions[1].Allowed)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "warn", violations[0].PolicyName)
+		assert.Equal(t, []string{"no approval"}, violations[0].Messages)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		violations, _, err := evaluatePolicies([]*commonmodels.WorkflowPolicy{
+			newPolicy("block", config.PolicyEnforcementBlock),
+		}, config.PolicyCheckpointTaskCreate, task, nil, time.Now(), log.SugaredLogger())
+		assert.ErrorContains(t, err, "image not allowed")
+		assert.Len(t, violations, 1)
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		job := &commonmodels.JobTask{Name: "deploy"}
+		violations, decisions, err := evaluatePolicies([]*commonmodels.WorkflowPolicy{
+			newPolicy("broken-block", config.PolicyEnforcementBlock),
+		}, config.PolicyCheckpointJobRun, task, job, time.Now(), log.SugaredLogger())
+		assert.ErrorContains(t, err, "broken-block")
+		assert.Len(t, violations, 1)
+		assert.Equal(t, config.PolicyCheckpointJobRun, violations[0].Checkpoint)
+		assert.Len(t, decisions, 1)
+		assert.False(t, decisions[0].Allowed)
+		assert.Equal(t, "deploy", decisions[0].JobName)
+		assert.Contains(t, decisions[0].Error, "opa is unavailable")
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// variableRegex matches zadig variables like {{.workflow.params.branch}} or {{.job.build.svc.output.IMAGE}},
+// together with the quotes around it if the variable is written as a string literal like '{{.workflow.params.branch}}'
+var variableRegex = regexp.MustCompile(`'{{\.[^{}]+}}'|"{{\.[^{}]+}}"|{{\.[^{}]+}}`)
+
+var conditionFunctions = map[string]govaluate.ExpressionFunction{
+	"hasPrefix": func(args ...interface{}) (interface{}, error) {
+		s, prefix, err := twoStringArgs("hasPrefix", args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(s, prefix), nil
+	},
+	"hasSuffix": func(args ...interface{}) (interface{}, error) {
+		s, suffix, err := twoStringArgs("hasSuffix", args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(s, suffix), nil
+	},
+	"contains": func(args ...interface{}) (interface{}, error) {
+		s, substr, err := twoStringArgs("contains", args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Contains(s, substr), nil
+	},
+}
+
+func twoStringArgs(name string, args []interface{}) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("%s expects 2 arguments, got %d", name, len(args))
+	}
+	return fmt.Sprint(args[0]), fmt.Sprint(args[1]), nil
+}
+
+// ValidateCondition checks the syntax of a job or stage condition expression.
+// Variables are not rendered at save time, so they are replaced by parameters before parsing.
+func ValidateCondition(condition string) error {
+	if strings.TrimSpace(condition) == "" {
+		return nil
+	}
+	expr, _ := parameterizeCondition(condition, nil)
+	if _, err := govaluate.NewEvaluableExpressionWithFunctions(expr, conditionFunctions); err != nil {
+		return fmt.Errorf("invalid condition %q: %v", condition, err)
+	}
+	return nil
+}
+
+// EvaluateCondition evaluates a condition expression with the values of its variables, an empty condition is always true.
+// Variables are passed to the expression as parameters rather than pasted into it, so their values can never change
+// the meaning of the expression. Variables that have no value are treated as empty strings.
+func EvaluateCondition(condition string, variables map[string]string) (bool, error) {
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+	expr, params := parameterizeCondition(condition, variables)
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(expr, conditionFunctions)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %v", condition, err)
+	}
+	result, err := expression.Evaluate(params)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition %q: %v", condition, err)
+	}
+	ok, isBool := result.(bool)
+	if !isBool {
+		return false, fmt.Errorf("condition %q should return a bool, got %v", condition, result)
+	}
+	return ok, nil
+}
+
+// parameterizeCondition replaces every variable in the condition with a parameter and returns the parameter values.
+// A quoted variable is always a string, an unquoted one is a number or a bool if its value looks like one.
+func parameterizeCondition(condition string, variables map[string]string) (string, map[string]interface{}) {
+	params := make(map[string]interface{})
+	expr := variableRegex.ReplaceAllStringFunc(condition, func(match string) string {
+		name := fmt.Sprintf("zadig_variable_%d", len(params))
+		quoted := match[0] == '\'' || match[0] == '"'
+		if quoted {
+			match = match[1 : len(match)-1]
+		}
+		value := strings.Trim(variables[match], "\n")
+		params[name] = value
+		if !quoted {
+			params[name] = typedValue(value)
+		}
+		return name
+	})
+	return expr, params
+}
+
+func typedValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
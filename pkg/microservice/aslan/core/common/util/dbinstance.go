@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/tool/sqlexec"
+)
+
+// GetSQLDialect returns the sql dialect used to connect the db instance and split the sql scripts
+func GetSQLDialect(dbType config.DBInstanceType) (sqlexec.Dialect, error) {
+	switch dbType {
+	case config.DBInstanceTypeMySQL, config.DBInstanceTypeMariaDB:
+		return sqlexec.DialectMySQL, nil
+	case config.DBInstanceTypePostgreSQL:
+		return sqlexec.DialectPostgreSQL, nil
+	case config.DBInstanceTypeSQLServer:
+		return sqlexec.DialectSQLServer, nil
+	default:
+		return "", fmt.Errorf("invalid db type %s", dbType)
+	}
+}
+
+func GetDBInstanceSQLConfig(info *commonmodels.DBInstance) (*sqlexec.Config, error) {
+	dialect, err := GetSQLDialect(info.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlexec.Config{
+		Dialect:  dialect,
+		Host:     info.Host,
+		Port:     info.Port,
+		Username: info.Username,
+		Password: info.Password,
+		Database: info.Database,
+		SSLMode:  info.SSLMode,
+	}, nil
+}
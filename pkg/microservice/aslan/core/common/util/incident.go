@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// CreateRollbackIncident records a resolved incident for a rollback. If the occurred time is not set,
+// the incident is considered to be started when the latest deploy job of the project (and the env if given)
+// finished before the rollback, and restored when the rollback finished.
+func CreateRollbackIncident(incident *models.Incident) error {
+	if incident.ResolvedAt == 0 {
+		incident.ResolvedAt = time.Now().Unix()
+	}
+
+	if incident.OccurredAt == 0 {
+		incident.OccurredAt = incident.ResolvedAt
+		deployJob, err := commonrepo.NewJobInfoColl().GetLatestDeployJob(incident.ProjectName, incident.EnvName, incident.ResolvedAt)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		if err == nil && deployJob.EndTime > 0 {
+			incident.OccurredAt = deployJob.EndTime
+		}
+	}
+
+	now := time.Now().Unix()
+	incident.Status = config.IncidentStatusResolved
+	incident.CreateTime = now
+	incident.UpdateTime = now
+	return commonrepo.NewIncidentColl().Create(context.TODO(), incident)
+}
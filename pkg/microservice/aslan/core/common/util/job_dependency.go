@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JobDependencyStage is the jobs of a stage used to resolve the job dependencies
+type JobDependencyStage struct {
+	Parallel bool
+	Jobs     []string
+}
+
+// ResolveJobDependencies returns the upstream jobs of every job in a workflow, dependsOn is the depends_on declared by the jobs.
+// A job with depends_on only waits for the declared jobs, which may be in any stage.
+// A job without depends_on waits for all the jobs of the previous stage, or for the previous job if its stage is not parallel,
+// just like a workflow running stage by stage.
+func ResolveJobDependencies(stages []*JobDependencyStage, dependsOn map[string][]string) map[string][]string {
+	resp := make(map[string][]string)
+	var previousStage []string
+	for _, stage := range stages {
+		for i, job := range stage.Jobs {
+			if deps, ok := dependsOn[job]; ok && len(deps) > 0 {
+				resp[job] = deps
+				continue
+			}
+			if !stage.Parallel && i > 0 {
+				resp[job] = []string{stage.Jobs[i-1]}
+				continue
+			}
+			resp[job] = previousStage
+		}
+		if len(stage.Jobs) > 0 {
+			previousStage = stage.Jobs
+		}
+	}
+	return resp
+}
+
+// HasJobDependencies returns true if any job declares depends_on, which means the workflow should be scheduled as a DAG.
+func HasJobDependencies(dependsOn map[string][]string) bool {
+	for _, deps := range dependsOn {
+		if len(deps) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateJobDependencies checks the resolved dependencies for unknown jobs and cycles.
+func ValidateJobDependencies(dependencies map[string][]string) error {
+	jobs := make([]string, 0, len(dependencies))
+	for job, deps := range dependencies {
+		for _, dep := range deps {
+			if dep == job {
+				return fmt.Errorf("job %s can not depend on itself", job)
+			}
+			if _, ok := dependencies[dep]; !ok {
+				return fmt.Errorf("job %s depends on job %s which does not exist", job, dep)
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	// sort the jobs so the reported cycle is stable
+	sort.Strings(jobs)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(job string) error
+	visit = func(job string) error {
+		switch state[job] {
+		case visiting:
+			for i, name := range path {
+				if name == job {
+					return fmt.Errorf("job dependency cycle detected: %s", strings.Join(append(path[i:], job), " -> "))
+				}
+			}
+			return fmt.Errorf("job dependency cycle detected at job %s", job)
+		case visited:
+			return nil
+		}
+		state[job] = visiting
+		path = append(path, job)
+		for _, dep := range dependencies[job] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[job] = visited
+		return nil
+	}
+	for _, job := range jobs {
+		if err := visit(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
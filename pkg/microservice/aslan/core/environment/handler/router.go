@@ -178,6 +178,14 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		production.POST("/environments/:name/analysis", RunProductionAnalysis)
 		production.GET("/environments/:name/analysis/cron", GetProductionEnvAnalysisCron)
 		production.PUT("/environments/:name/analysis/cron", UpsertProductionEnvAnalysisCron)
+		production.GET("/environments/:name/drift", GetProductionEnvDriftReport)
+		production.POST("/environments/:name/drift/detect", DetectProductionEnvDrift)
+		production.GET("/environments/:name/snapshots", ListProductionEnvSnapshots)
+		production.POST("/environments/:name/snapshots", CreateProductionEnvSnapshot)
+		production.GET("/environments/:name/snapshots/diff", DiffProductionEnvSnapshots)
+		production.GET("/environments/:name/snapshots/:id", GetProductionEnvSnapshot)
+		production.DELETE("/environments/:name/snapshots/:id", DeleteProductionEnvSnapshot)
+		production.POST("/environments/:name/snapshots/:id/restore", RestoreProductionEnvSnapshot)
 		production.PUT("/environments/:name/k8s/globalVariables", UpdateProductionEnvK8sProductGlobalVariables)
 		production.POST("/environments/:name/k8s/globalVariables/preview", PreviewProductionEnvGlobalVariables)
 
@@ -237,6 +245,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 
 		environments.GET("/:name", GetEnvironment)
 		environments.PUT("/:name/envRecycle", UpdateProductRecycleDay)
+		environments.GET("/:name/previewEnvPolicy", GetPreviewEnvPolicy)
+		environments.PUT("/:name/previewEnvPolicy", UpdatePreviewEnvPolicy)
 		environments.PUT("/:name/alias", UpdateProductAlias)
 		environments.POST("/:name/affectedservices", AffectedServices)
 		environments.POST("/:name/estimated-values", EstimatedValues)
@@ -291,6 +301,14 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.POST("/:name/analysis", RunAnalysis)
 		environments.GET("/:name/analysis/cron", GetEnvAnalysisCron)
 		environments.PUT("/:name/analysis/cron", UpsertEnvAnalysisCron)
+		environments.GET("/:name/drift", GetEnvDriftReport)
+		environments.POST("/:name/drift/detect", DetectEnvDrift)
+		environments.GET("/:name/snapshots", ListEnvSnapshots)
+		environments.POST("/:name/snapshots", CreateEnvSnapshot)
+		environments.GET("/:name/snapshots/diff", DiffEnvSnapshots)
+		environments.GET("/:name/snapshots/:id", GetEnvSnapshot)
+		environments.DELETE("/:name/snapshots/:id", DeleteEnvSnapshot)
+		environments.POST("/:name/snapshots/:id/restore", RestoreEnvSnapshot)
 		environments.GET("/analysis/history", GetEnvAnalysisHistory)
 
 		environments.POST("/:name/sleep", EnvSleep)
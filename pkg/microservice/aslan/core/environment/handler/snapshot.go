@@ -0,0 +1,420 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+type envSnapshotAction int
+
+const (
+	envSnapshotActionView envSnapshotAction = iota
+	envSnapshotActionEdit
+	envSnapshotActionRestore
+)
+
+// envSnapshotPermitted checks the environment permission required by the snapshot action,
+// the recorded content can only be compared or restored by the users who can edit the environment.
+func envSnapshotPermitted(ctx *internalhandler.Context, projectKey string, production bool, action envSnapshotAction) bool {
+	if ctx.Resources.IsSystemAdmin {
+		return true
+	}
+	authInfo, ok := ctx.Resources.ProjectAuthInfo[projectKey]
+	if !ok {
+		return false
+	}
+	if authInfo.IsProjectAdmin {
+		return true
+	}
+
+	var view, create, edit bool
+	if production {
+		view, create, edit = authInfo.ProductionEnv.View, authInfo.ProductionEnv.Create, authInfo.ProductionEnv.EditConfig
+	} else {
+		view, create, edit = authInfo.Env.View, authInfo.Env.Create, authInfo.Env.EditConfig
+	}
+
+	switch action {
+	case envSnapshotActionView:
+		return view
+	case envSnapshotActionEdit:
+		return edit
+	case envSnapshotActionRestore:
+		return create && edit
+	}
+	return false
+}
+
+// @Summary Create Environment Snapshot
+// @Description Record the services, variables and namespace resources of the environment as a snapshot
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	body 		body 		service.CreateEnvSnapshotArgs 	true 	"body"
+// @Success 200 		{object} 	commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/environments/{name}/snapshots [post]
+func CreateEnvSnapshot(c *gin.Context) {
+	createEnvSnapshot(c, false)
+}
+
+// @Summary Create Production Environment Snapshot
+// @Description Record the services, variables and namespace resources of the production environment as a snapshot
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	body 		body 		service.CreateEnvSnapshotArgs 	true 	"body"
+// @Success 200 		{object} 	commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/production/environments/{name}/snapshots [post]
+func CreateProductionEnvSnapshot(c *gin.Context) {
+	createEnvSnapshot(c, true)
+}
+
+func createEnvSnapshot(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionEdit) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	args := new(service.CreateEnvSnapshotArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectKey, setting.OperationSceneEnv, "新建", "环境快照", fmt.Sprintf("%s:%s", envName, args.Name), "", ctx.Logger, envName)
+
+	ctx.Resp, ctx.Err = service.CreateEnvSnapshot(projectKey, envName, production, args, ctx.UserName, ctx.Logger)
+}
+
+// @Summary List Environment Snapshots
+// @Description List the snapshots of the environment, the recorded content is not returned
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Success 200 		{object} 	[]commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/environments/{name}/snapshots [get]
+func ListEnvSnapshots(c *gin.Context) {
+	listEnvSnapshots(c, false)
+}
+
+// @Summary List Production Environment Snapshots
+// @Description List the snapshots of the production environment, the recorded content is not returned
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Success 200 		{object} 	[]commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/production/environments/{name}/snapshots [get]
+func ListProductionEnvSnapshots(c *gin.Context) {
+	listEnvSnapshots(c, true)
+}
+
+func listEnvSnapshots(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionView) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListEnvSnapshots(projectKey, envName, production, ctx.Logger)
+}
+
+// @Summary Get Environment Snapshot
+// @Description Get the snapshot of the environment with the recorded content, the values of the secrets are redacted
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Success 200 		{object} 	commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/environments/{name}/snapshots/{id} [get]
+func GetEnvSnapshot(c *gin.Context) {
+	getEnvSnapshot(c, false)
+}
+
+// @Summary Get Production Environment Snapshot
+// @Description Get the snapshot of the production environment with the recorded content, the values of the secrets are redacted
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Success 200 		{object} 	commonmodels.EnvSnapshot
+// @Router /api/aslan/environment/production/environments/{name}/snapshots/{id} [get]
+func GetProductionEnvSnapshot(c *gin.Context) {
+	getEnvSnapshot(c, true)
+}
+
+func getEnvSnapshot(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionView) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetEnvSnapshot(projectKey, envName, production, c.Param("id"), ctx.Logger)
+}
+
+// @Summary Delete Environment Snapshot
+// @Description Delete the snapshot of the environment
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Success 200
+// @Router /api/aslan/environment/environments/{name}/snapshots/{id} [delete]
+func DeleteEnvSnapshot(c *gin.Context) {
+	deleteEnvSnapshot(c, false)
+}
+
+// @Summary Delete Production Environment Snapshot
+// @Description Delete the snapshot of the production environment
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Success 200
+// @Router /api/aslan/environment/production/environments/{name}/snapshots/{id} [delete]
+func DeleteProductionEnvSnapshot(c *gin.Context) {
+	deleteEnvSnapshot(c, true)
+}
+
+func deleteEnvSnapshot(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionEdit) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectKey, setting.OperationSceneEnv, "删除", "环境快照", fmt.Sprintf("%s:%s", envName, c.Param("id")), "", ctx.Logger, envName)
+
+	ctx.Err = service.DeleteEnvSnapshot(projectKey, envName, production, c.Param("id"), ctx.Logger)
+}
+
+// @Summary Restore Environment Snapshot
+// @Description Create a new environment from the snapshot, both the create and the edit permission are required
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Param 	body 		body 		service.RestoreEnvSnapshotArgs 	true 	"body"
+// @Success 200
+// @Router /api/aslan/environment/environments/{name}/snapshots/{id}/restore [post]
+func RestoreEnvSnapshot(c *gin.Context) {
+	restoreEnvSnapshot(c, false)
+}
+
+// @Summary Restore Production Environment Snapshot
+// @Description Create a new environment from the snapshot, both the create and the edit permission are required
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	id 			path		string							true	"snapshot id"
+// @Param 	body 		body 		service.RestoreEnvSnapshotArgs 	true 	"body"
+// @Success 200
+// @Router /api/aslan/environment/production/environments/{name}/snapshots/{id}/restore [post]
+func RestoreProductionEnvSnapshot(c *gin.Context) {
+	restoreEnvSnapshot(c, true)
+}
+
+func restoreEnvSnapshot(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionRestore) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	args := new(service.RestoreEnvSnapshotArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectKey, setting.OperationSceneEnv, "恢复", "环境快照", fmt.Sprintf("%s:%s->%s", envName, c.Param("id"), args.EnvName), "", ctx.Logger, args.EnvName)
+
+	ctx.Err = service.RestoreEnvSnapshot(projectKey, envName, production, c.Param("id"), args, ctx.UserName, ctx.RequestID, ctx.Logger)
+}
+
+// @Summary Diff Environment Snapshots
+// @Description Compare the target snapshot with the base snapshot, the edit permission is required
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	base		query		string							true	"base snapshot id"
+// @Param 	target		query		string							true	"target snapshot id"
+// @Success 200 		{object} 	service.EnvSnapshotDiff
+// @Router /api/aslan/environment/environments/{name}/snapshots/diff [get]
+func DiffEnvSnapshots(c *gin.Context) {
+	diffEnvSnapshots(c, false)
+}
+
+// @Summary Diff Production Environment Snapshots
+// @Description Compare the target snapshot with the base snapshot, the edit permission is required
+// @Tags 	environment
+// @Accept 	json
+// @Produce json
+// @Param 	name 		path		string							true	"env name"
+// @Param 	projectName	query		string							true	"project name"
+// @Param 	base		query		string							true	"base snapshot id"
+// @Param 	target		query		string							true	"target snapshot id"
+// @Success 200 		{object} 	service.EnvSnapshotDiff
+// @Router /api/aslan/environment/production/environments/{name}/snapshots/diff [get]
+func DiffProductionEnvSnapshots(c *gin.Context) {
+	diffEnvSnapshots(c, true)
+}
+
+func diffEnvSnapshots(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if !envSnapshotPermitted(ctx, projectKey, production, envSnapshotActionEdit) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	if envName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty name")
+		return
+	}
+
+	baseID, targetID := c.Query("base"), c.Query("target")
+	if baseID == "" || targetID == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("base and target can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.DiffEnvSnapshots(projectKey, envName, production, baseID, targetID, ctx.Logger)
+}
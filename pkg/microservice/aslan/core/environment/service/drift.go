@@ -0,0 +1,445 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	configbase "github.com/koderover/zadig/v2/pkg/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/imnotify"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/kube"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	kubeclient "github.com/koderover/zadig/v2/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+	"github.com/koderover/zadig/v2/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/v2/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+)
+
+// maxDriftNotificationItems is the max number of drifted objects listed in one notification
+const maxDriftNotificationItems = 20
+
+type EnvDriftReport struct {
+	Policy *commonmodels.DriftPolicy `json:"policy"`
+	Drifts []*commonmodels.EnvDrift  `json:"drifts"`
+}
+
+func GetEnvDriftReport(projectName, envName string, production bool, logger *zap.SugaredLogger) (*EnvDriftReport, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:       projectName,
+		EnvName:    envName,
+		Production: &production,
+	})
+	if err != nil {
+		return nil, e.ErrGetEnvDrift.AddErr(fmt.Errorf("failed to get environment %s/%s, err: %w", projectName, envName, err))
+	}
+
+	drifts, err := commonrepo.NewEnvDriftColl().List(projectName, envName, production)
+	if err != nil {
+		logger.Errorf("failed to list drifts of environment %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvDrift.AddErr(err)
+	}
+
+	policy := &commonmodels.DriftPolicy{}
+	if env.DriftPolicy != nil {
+		policy = env.DriftPolicy
+	}
+	return &EnvDriftReport{
+		Policy: policy,
+		Drifts: drifts,
+	}, nil
+}
+
+// DetectEnvDrift runs the drift detection of the environment immediately and returns the new report
+func DetectEnvDrift(projectName, envName string, production bool, logger *zap.SugaredLogger) (*EnvDriftReport, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:       projectName,
+		EnvName:    envName,
+		Production: &production,
+	})
+	if err != nil {
+		return nil, e.ErrDetectEnvDrift.AddErr(fmt.Errorf("failed to get environment %s/%s, err: %w", projectName, envName, err))
+	}
+	if env.DriftPolicy == nil || !env.DriftPolicy.Enabled {
+		return nil, e.ErrDetectEnvDrift.AddDesc("drift detection is not enabled for the environment")
+	}
+
+	drifts, err := detectEnvDrift(env, logger)
+	if err != nil {
+		return nil, e.ErrDetectEnvDrift.AddErr(err)
+	}
+	return &EnvDriftReport{
+		Policy: env.DriftPolicy,
+		Drifts: drifts,
+	}, nil
+}
+
+// DetectEnvDrifts compares the rendered k8s yaml services with the live objects for all the environments
+// which enabled the drift detection, it is called periodically by the cron of aslan.
+func DetectEnvDrifts() {
+	logger := log.SugaredLogger().With("service", "DetectEnvDrifts")
+
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{})
+	if err != nil {
+		logger.Errorf("failed to list environments, err: %s", err)
+		return
+	}
+
+	for _, env := range envs {
+		if env.DriftPolicy == nil || !env.DriftPolicy.Enabled {
+			continue
+		}
+		if env.IsSleeping() || env.Status == setting.ProductStatusDeleting {
+			continue
+		}
+		if _, err := detectEnvDrift(env, logger); err != nil {
+			logger.Errorf("failed to detect drift of environment %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+}
+
+func envDriftKey(serviceName, kind, name string) string {
+	return fmt.Sprintf("%s/%s/%s", serviceName, kind, name)
+}
+
+func detectEnvDrift(env *commonmodels.Product, logger *zap.SugaredLogger) ([]*commonmodels.EnvDrift, error) {
+	policy := env.DriftPolicy
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube client of cluster %s, err: %w", env.ClusterID, err)
+	}
+
+	existedDrifts, err := commonrepo.NewEnvDriftColl().List(env.ProductName, env.EnvName, env.Production)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existed drifts, err: %w", err)
+	}
+	existedDriftMap := make(map[string]*commonmodels.EnvDrift)
+	for _, drift := range existedDrifts {
+		existedDriftMap[envDriftKey(drift.ServiceName, drift.Kind, drift.Name)] = drift
+	}
+
+	ignoredFields := sets.NewString(policy.IgnoredFields...)
+	now := time.Now().Unix()
+	drifts := make([]*commonmodels.EnvDrift, 0)
+	newDrifts := make([]*commonmodels.EnvDrift, 0)
+	for _, svc := range env.GetSvcList() {
+		// only k8s yaml services deployed by zadig are rendered from the templates
+		if svc.Type != setting.K8SDeployType || !commonutil.ServiceDeployed(svc.ServiceName, env.ServiceDeployStrategy) {
+			continue
+		}
+
+		manifest, err := kube.RenderEnvService(env, svc.GetServiceRender(), svc)
+		if err != nil {
+			logger.Warnf("failed to render service %s of environment %s/%s, err: %s", svc.ServiceName, env.ProductName, env.EnvName, err)
+			continue
+		}
+		objects, err := kube.ManifestToUnstructured(manifest)
+		if err != nil {
+			logger.Warnf("failed to parse the manifest of service %s, err: %s", svc.ServiceName, err)
+			continue
+		}
+
+		for _, obj := range objects {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(env.Namespace)
+			}
+
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(obj.GroupVersionKind())
+			found, err := getter.GetResourceInCache(obj.GetNamespace(), obj.GetName(), live, kubeClient)
+			if err != nil {
+				logger.Warnf("failed to get %s/%s from cluster, err: %s", obj.GetKind(), obj.GetName(), err)
+				continue
+			}
+
+			drift := &commonmodels.EnvDrift{
+				ProjectName: env.ProductName,
+				EnvName:     env.EnvName,
+				Production:  env.Production,
+				ServiceName: svc.ServiceName,
+				Namespace:   obj.GetNamespace(),
+				Kind:        obj.GetKind(),
+				Name:        obj.GetName(),
+				DetectedAt:  now,
+				UpdateTime:  now,
+			}
+			if !found {
+				drift.Missing = true
+			} else {
+				expected := obj.DeepCopy().UnstructuredContent()
+				delete(expected, "status")
+				if obj.GetKind() == setting.Secret {
+					normalizeSecretData(expected)
+				}
+				drift.Fields = compareDriftFields("", expected, live.UnstructuredContent(), ignoredFields)
+				if len(drift.Fields) == 0 {
+					continue
+				}
+				if obj.GetKind() == setting.Secret {
+					maskSecretDriftFields(drift.Fields)
+				}
+			}
+
+			if policy.AutoRevert {
+				if err := updater.CreateOrPatchUnstructured(obj, kubeClient); err != nil {
+					logger.Warnf("failed to revert drift of %s/%s, err: %s", obj.GetKind(), obj.GetName(), err)
+				} else {
+					drift.Reverted = true
+				}
+			}
+
+			if existed, ok := existedDriftMap[envDriftKey(drift.ServiceName, drift.Kind, drift.Name)]; ok {
+				drift.DetectedAt = existed.DetectedAt
+			} else {
+				newDrifts = append(newDrifts, drift)
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+
+	if err := commonrepo.NewEnvDriftColl().Replace(env.ProductName, env.EnvName, env.Production, drifts); err != nil {
+		return nil, fmt.Errorf("failed to save drifts, err: %w", err)
+	}
+
+	// only the drifts detected for the first time are sent to avoid sending the same drift repeatedly
+	if policy.Alert && len(newDrifts) > 0 {
+		if err := envDriftNotification(env, newDrifts); err != nil {
+			logger.Errorf("failed to send drift notification of environment %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+	return drifts, nil
+}
+
+// normalizeSecretData merges the stringData of the secret into the base64 encoded data,
+// as kubernetes does when the secret is written, so that the rendered secret can be compared with the live one
+func normalizeSecretData(secret map[string]interface{}) {
+	stringData, ok := secret["stringData"].(map[string]interface{})
+	delete(secret, "stringData")
+	if !ok || len(stringData) == 0 {
+		return
+	}
+
+	data, ok := secret["data"].(map[string]interface{})
+	if !ok {
+		data = make(map[string]interface{})
+	}
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(driftValueString(value)))
+	}
+	secret["data"] = data
+}
+
+// maskSecretDriftFields hides the secret values so that they are neither stored nor sent in the notifications
+func maskSecretDriftFields(fields []*commonmodels.DriftField) {
+	for _, field := range fields {
+		if field.Path != "data" && !strings.HasPrefix(field.Path, "data.") {
+			continue
+		}
+		if field.Expected != "" {
+			field.Expected = setting.MaskValue
+		}
+		if field.Actual != "" {
+			field.Actual = setting.MaskValue
+		}
+	}
+}
+
+// compareDriftFields returns the fields set in the expected object but differ in the actual one,
+// fields only set in the actual object such as the defaults filled by kubernetes are not drifts.
+func compareDriftFields(path string, expected, actual interface{}, ignoredFields sets.String) []*commonmodels.DriftField {
+	if ignoredFields.Has(path) || expected == nil {
+		return nil
+	}
+
+	resp := make([]*commonmodels.DriftField, 0)
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualValue, ok := actual.(map[string]interface{})
+		if !ok {
+			return append(resp, newDriftField(path, expected, actual))
+		}
+		keys := make([]string, 0, len(expectedValue))
+		for key := range expectedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			subPath := key
+			if path != "" {
+				subPath = path + "." + key
+			}
+			resp = append(resp, compareDriftFields(subPath, expectedValue[key], actualValue[key], ignoredFields)...)
+		}
+	case []interface{}:
+		actualValue, ok := actual.([]interface{})
+		if !ok {
+			return append(resp, newDriftField(path, expected, actual))
+		}
+		for i, item := range expectedValue {
+			subPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(actualValue) {
+				resp = append(resp, newDriftField(subPath, item, nil))
+				continue
+			}
+			resp = append(resp, compareDriftFields(subPath, item, actualValue[i], ignoredFields)...)
+		}
+	default:
+		if !driftValueEqual(expected, actual) {
+			resp = append(resp, newDriftField(path, expected, actual))
+		}
+	}
+	return resp
+}
+
+// driftValueEqual compares the scalar values, quantities like 1 and 1000m are treated as equal
+func driftValueEqual(expected, actual interface{}) bool {
+	expectedStr, actualStr := driftValueString(expected), driftValueString(actual)
+	if expectedStr == actualStr {
+		return true
+	}
+
+	expectedQuantity, err := resource.ParseQuantity(expectedStr)
+	if err != nil {
+		return false
+	}
+	actualQuantity, err := resource.ParseQuantity(actualStr)
+	if err != nil {
+		return false
+	}
+	return expectedQuantity.Cmp(actualQuantity) == 0
+}
+
+func driftValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func newDriftField(path string, expected, actual interface{}) *commonmodels.DriftField {
+	return &commonmodels.DriftField{
+		Path:     path,
+		Expected: driftValueString(expected),
+		Actual:   driftValueString(actual),
+	}
+}
+
+type envDriftWebhookPayload struct {
+	Title       string                         `json:"title"`
+	DetailURL   string                         `json:"detail_url"`
+	ProjectName string                         `json:"project_name"`
+	EnvName     string                         `json:"env_name"`
+	Event       commonmodels.NotificationEvent `json:"event"`
+	Drifts      []*commonmodels.EnvDrift       `json:"drifts"`
+}
+
+func envDriftNotification(env *commonmodels.Product, drifts []*commonmodels.EnvDrift) error {
+	title := fmt.Sprintf("⚠️ %s / %s 环境配置漂移", env.ProductName, env.EnvName)
+	detailURL := fmt.Sprintf("%s/v1/projects/detail/%s/envs/detail?envName=%s", configbase.SystemAddress(), env.ProductName, env.EnvName)
+
+	lines := make([]string, 0)
+	for i, drift := range drifts {
+		if i >= maxDriftNotificationItems {
+			lines = append(lines, fmt.Sprintf("- ... 共 %d 个对象", len(drifts)))
+			break
+		}
+		line := fmt.Sprintf("- %s: %s/%s", drift.ServiceName, drift.Kind, drift.Name)
+		if drift.Missing {
+			line += " 不存在"
+		} else {
+			paths := make([]string, 0, len(drift.Fields))
+			for _, field := range drift.Fields {
+				paths = append(paths, field.Path)
+			}
+			line += fmt.Sprintf(" (%s)", strings.Join(paths, ", "))
+		}
+		if drift.Reverted {
+			line += " 已自动恢复"
+		}
+		lines = append(lines, line)
+	}
+	detail := fmt.Sprintf("**检测时间：%s** \n%s \n", time.Now().Format("2006-01-02 15:04:05"), strings.Join(lines, "\n"))
+	content := fmt.Sprintf("### %s \n%s\n[点击查看更多信息](%s)", title, detail, detailURL)
+
+	imnotifyClient := imnotify.NewIMNotifyClient()
+	for _, notifyConfig := range env.NotificationConfigs {
+		subscribed := false
+		for _, event := range notifyConfig.Events {
+			if event == commonmodels.NotificationEventEnvDrift {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			continue
+		}
+
+		var err error
+		switch imnotify.IMNotifyType(notifyConfig.WebHookType) {
+		case imnotify.IMNotifyTypeDingDing:
+			err = imnotifyClient.SendDingDingMessage(notifyConfig.WebHookURL, title, content, nil, false)
+		case imnotify.IMNotifyTypeLark:
+			lc := imnotify.NewLarkCard()
+			lc.SetConfig(true)
+			lc.SetHeader(imnotify.GetColorTemplateWithStatus(config.StatusFailed), title, "plain_text")
+			lc.AddI18NElementsZhcnFeild(detail, true)
+			lc.AddI18NElementsZhcnAction("点击查看更多信息", detailURL)
+			err = imnotifyClient.SendFeishuMessage(notifyConfig.WebHookURL, lc)
+		case imnotify.IMNotifyTypeWeChat:
+			err = imnotifyClient.SendWeChatWorkMessage(imnotify.WeChatTextTypeMarkdown, notifyConfig.WebHookURL, content)
+		case imnotify.IMNotifyTypeSlack:
+			err = imnotifyClient.SendSlackMessage(notifyConfig.WebHookURL, title, content)
+		case imnotify.IMNotifyTypeMSTeams:
+			err = imnotifyClient.SendMSTeamsMessage(notifyConfig.WebHookURL, title, content)
+		case imnotify.IMNotifyTypeWebhook:
+			payload := &envDriftWebhookPayload{
+				Title:       title,
+				DetailURL:   detailURL,
+				ProjectName: env.ProductName,
+				EnvName:     env.EnvName,
+				Event:       commonmodels.NotificationEventEnvDrift,
+				Drifts:      drifts,
+			}
+			err = imnotifyClient.SendWebhookMessage(notifyConfig.WebHookURL, notifyConfig.WebHookSecret, imnotify.WebhookEventEnvDrift, payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
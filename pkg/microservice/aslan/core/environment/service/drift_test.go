@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/setting"
+)
+
+var _ = Describe("Testing drift", func() {
+
+	DescribeTable("test compareDriftFields",
+		func(expected, actual map[string]interface{}, ignoredFields []string, want []*commonmodels.DriftField) {
+			Expect(compareDriftFields("", expected, actual, sets.NewString(ignoredFields...))).To(Equal(want))
+		},
+		Entry("same objects",
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			nil,
+			[]*commonmodels.DriftField{},
+		),
+		Entry("fields defaulted by kubernetes are not drifts",
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "revisionHistoryLimit": int64(10)}},
+			nil,
+			[]*commonmodels.DriftField{},
+		),
+		Entry("changed scalar",
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3)}},
+			nil,
+			[]*commonmodels.DriftField{{Path: "spec.replicas", Expected: "1", Actual: "3"}},
+		),
+		Entry("ignored field",
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3)}},
+			[]string{"spec.replicas"},
+			[]*commonmodels.DriftField{},
+		),
+		Entry("changed and missing list items",
+			map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"image": "nginx:1.0"},
+				map[string]interface{}{"image": "redis"},
+			}},
+			map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"image": "nginx:2.0"},
+			}},
+			nil,
+			[]*commonmodels.DriftField{
+				{Path: "containers[0].image", Expected: "nginx:1.0", Actual: "nginx:2.0"},
+				{Path: "containers[1]", Expected: `{"image":"redis"}`, Actual: ""},
+			},
+		),
+		Entry("type mismatch",
+			map[string]interface{}{"data": map[string]interface{}{"a": "b"}},
+			map[string]interface{}{"data": "a"},
+			nil,
+			[]*commonmodels.DriftField{{Path: "data", Expected: `{"a":"b"}`, Actual: "a"}},
+		),
+	)
+
+	DescribeTable("test driftValueEqual",
+		func(expected, actual interface{}, want bool) {
+			Expect(driftValueEqual(expected, actual)).To(Equal(want))
+		},
+		Entry("equal strings", "nginx", "nginx", true),
+		Entry("different strings", "nginx", "redis", false),
+		Entry("number and string", int64(80), "80", true),
+		Entry("cpu quantities", "1", "1000m", true),
+		Entry("memory quantities", "1Gi", "1024Mi", true),
+		Entry("different quantities", "500m", "1", false),
+		Entry("nil and empty string", nil, "", true),
+		Entry("nil and value", nil, "a", false),
+	)
+
+	Describe("test secret drift", func() {
+		It("should compare stringData with the encoded data and mask the values", func() {
+			expected := map[string]interface{}{
+				"kind":       setting.Secret,
+				"data":       map[string]interface{}{"user": "YWRtaW4="},
+				"stringData": map[string]interface{}{"password": "123456"},
+			}
+			normalizeSecretData(expected)
+			Expect(expected).NotTo(HaveKey("stringData"))
+
+			actual := map[string]interface{}{
+				"kind": setting.Secret,
+				"data": map[string]interface{}{"user": "YWRtaW4=", "password": "MTIzNDU2"},
+			}
+			Expect(compareDriftFields("", expected, actual, sets.NewString())).To(BeEmpty())
+
+			actual["data"] = map[string]interface{}{"user": "cm9vdA==", "password": "MTIzNDU2"}
+			fields := compareDriftFields("", expected, actual, sets.NewString())
+			maskSecretDriftFields(fields)
+			Expect(fields).To(Equal([]*commonmodels.DriftField{{Path: "data.user", Expected: setting.MaskValue, Actual: setting.MaskValue}}))
+		})
+	})
+})
@@ -3252,6 +3252,7 @@ func UpdateProductGlobalVariablesWithRender(product *commonmodels.Product, produ
 type EnvConfigsArgs struct {
 	AnalysisConfig      *models.AnalysisConfig       `json:"analysis_config"`
 	NotificationConfigs []*models.NotificationConfig `json:"notification_configs"`
+	DriftPolicy         *models.DriftPolicy          `json:"drift_policy"`
 }
 
 func GetEnvConfigs(projectName, envName string, production *bool, logger *zap.SugaredLogger) (*EnvConfigsArgs, error) {
@@ -3273,10 +3274,21 @@ func GetEnvConfigs(projectName, envName string, production *bool, logger *zap.Su
 	if env.NotificationConfigs != nil {
 		notificationConfigs = env.NotificationConfigs
 	}
+	for _, notifyConfig := range notificationConfigs {
+		if notifyConfig.WebHookSecret != "" {
+			notifyConfig.WebHookSecret = setting.MaskValue
+		}
+	}
+
+	driftPolicy := &models.DriftPolicy{}
+	if env.DriftPolicy != nil {
+		driftPolicy = env.DriftPolicy
+	}
 
 	configs := &EnvConfigsArgs{
 		AnalysisConfig:      analysisConfig,
 		NotificationConfigs: notificationConfigs,
+		DriftPolicy:         driftPolicy,
 	}
 	return configs, nil
 }
@@ -3287,7 +3299,7 @@ func UpdateEnvConfigs(projectName, envName string, arg *EnvConfigsArgs, producti
 		Name:       projectName,
 		Production: production,
 	}
-	_, err := commonrepo.NewProductColl().Find(opt)
+	env, err := commonrepo.NewProductColl().Find(opt)
 	if err != nil {
 		return e.ErrUpdateEnvConfigs.AddErr(fmt.Errorf("failed to get environment %s/%s, err: %w", projectName, envName, err))
 	}
@@ -3299,11 +3311,34 @@ func UpdateEnvConfigs(projectName, envName string, arg *EnvConfigsArgs, producti
 		}
 	}
 
-	err = commonrepo.NewProductColl().UpdateConfigs(envName, projectName, arg.AnalysisConfig, arg.NotificationConfigs)
+	// the masked secret is submitted if the secret of the webhook is not changed
+	savedSecrets := make(map[string]string)
+	for _, notifyConfig := range env.NotificationConfigs {
+		savedSecrets[notifyConfig.WebHookURL] = notifyConfig.WebHookSecret
+	}
+	for _, notifyConfig := range arg.NotificationConfigs {
+		if notifyConfig.WebHookSecret != setting.MaskValue {
+			continue
+		}
+		secret, ok := savedSecrets[notifyConfig.WebHookURL]
+		if !ok {
+			return e.ErrUpdateEnvConfigs.AddErr(fmt.Errorf("the secret of webhook %s is required", notifyConfig.WebHookURL))
+		}
+		notifyConfig.WebHookSecret = secret
+	}
+
+	err = commonrepo.NewProductColl().UpdateConfigs(envName, projectName, arg.AnalysisConfig, arg.NotificationConfigs, arg.DriftPolicy)
 	if err != nil {
 		return e.ErrUpdateEnvConfigs.AddErr(fmt.Errorf("failed to update environment %s/%s, err: %w", projectName, envName, err))
 	}
 
+	// the recorded drifts are outdated once the detection is disabled
+	if arg.DriftPolicy != nil && !arg.DriftPolicy.Enabled {
+		if err := commonrepo.NewEnvDriftColl().DeleteByEnv(projectName, envName, env.Production); err != nil {
+			logger.Warnf("failed to delete drifts of environment %s/%s, err: %s", projectName, envName, err)
+		}
+	}
+
 	return nil
 }
 
@@ -3613,12 +3648,41 @@ func EnvAnalysisNotification(projectName, envName, result string, configs []*com
 			if err := imnotifyClient.SendWeChatWorkMessage(imnotify.WeChatTextTypeMarkdown, config.WebHookURL, content); err != nil {
 				return err
 			}
+		case imnotify.IMNotifyTypeSlack:
+			if err := imnotifyClient.SendSlackMessage(config.WebHookURL, title, content); err != nil {
+				return err
+			}
+		case imnotify.IMNotifyTypeMSTeams:
+			if err := imnotifyClient.SendMSTeamsMessage(config.WebHookURL, title, content); err != nil {
+				return err
+			}
+		case imnotify.IMNotifyTypeWebhook:
+			payload := &envAnalysisWebhookPayload{
+				Title:       imnotify.MarkdownToPlainText(title),
+				DetailURL:   fmt.Sprintf("%s/v1/projects/detail/%s/envs/detail?envName=%s", configbase.SystemAddress(), projectName, envName),
+				ProjectName: projectName,
+				EnvName:     envName,
+				Event:       status,
+				Result:      result,
+			}
+			if err := imnotifyClient.SendWebhookMessage(config.WebHookURL, config.WebHookSecret, imnotify.WebhookEventEnvAnalysis, payload); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+type envAnalysisWebhookPayload struct {
+	Title       string                         `json:"title"`
+	DetailURL   string                         `json:"detail_url"`
+	ProjectName string                         `json:"project_name"`
+	EnvName     string                         `json:"env_name"`
+	Event       commonmodels.NotificationEvent `json:"event"`
+	Result      string                         `json:"result"`
+}
+
 type envAnalysisNotification struct {
 	BaseURI     string                   `json:"base_uri"`
 	WebHookType imnotify.IMNotifyType    `json:"web_hook_type"`
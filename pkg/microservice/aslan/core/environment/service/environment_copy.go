@@ -149,6 +149,8 @@ func BulkCopyYamlProduct(projectName, user, requestID string, arg CopyYamlProduc
 			newProduct.BaseName = item.BaseName
 			newProduct.GlobalVariables = item.GlobalVariables
 			newProduct.DefaultValues = item.DefaultValues
+			newProduct.PreviewEnvPolicy = nil
+			newProduct.PreviewEnv = nil
 
 			svcVariableKVMap := make(map[string][]*commontypes.RenderVariableKV)
 			for _, sv := range item.Services {
@@ -281,6 +283,8 @@ func copySingleHelmProduct(templateProduct *templatemodels.Product, productInfo
 	productInfo.BaseName = arg.BaseName
 	productInfo.Namespace = commonservice.GetProductEnvNamespace(arg.EnvName, arg.ProductName, arg.Namespace)
 	productInfo.EnvConfigs = arg.EnvConfigs
+	productInfo.PreviewEnvPolicy = nil
+	productInfo.PreviewEnv = nil
 
 	// merge chart infos, use chart info in product to override charts in template_project
 	sourceChartMap := make(map[string]*templatemodels.ServiceRender)
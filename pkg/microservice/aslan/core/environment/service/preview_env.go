@@ -0,0 +1,257 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/crypto"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+	"github.com/koderover/zadig/v2/pkg/tool/klock"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/util"
+)
+
+func GetPreviewEnvPolicy(projectName, envName string, logger *zap.SugaredLogger) (*commonmodels.PreviewEnvPolicy, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:       projectName,
+		EnvName:    envName,
+		Production: util.GetBoolPointer(false),
+	})
+	if err != nil {
+		logger.Errorf("failed to find environment %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetPreviewEnvPolicy.AddErr(err)
+	}
+	if env.PreviewEnvPolicy == nil {
+		return &commonmodels.PreviewEnvPolicy{Repos: make([]*commonmodels.PreviewEnvRepo, 0)}, nil
+	}
+	return env.PreviewEnvPolicy, nil
+}
+
+func UpdatePreviewEnvPolicy(projectName, envName string, policy *commonmodels.PreviewEnvPolicy, logger *zap.SugaredLogger) error {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:       projectName,
+		EnvName:    envName,
+		Production: util.GetBoolPointer(false),
+	})
+	if err != nil {
+		logger.Errorf("failed to find environment %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdatePreviewEnvPolicy.AddErr(err)
+	}
+
+	if policy.Enabled {
+		if !env.ShareEnv.Enable || !env.ShareEnv.IsBase {
+			return e.ErrUpdatePreviewEnvPolicy.AddDesc("preview environments can only be created from the base environment of environment sharing")
+		}
+		if policy.TTL < 0 {
+			return e.ErrUpdatePreviewEnvPolicy.AddDesc("ttl can not be negative")
+		}
+		services := env.GetServiceMap()
+		for _, repo := range policy.Repos {
+			if repo.MainRepo == nil || repo.MainRepo.CodehostID == 0 || repo.MainRepo.RepoName == "" {
+				return e.ErrUpdatePreviewEnvPolicy.AddDesc("codehost and repo can not be empty")
+			}
+			for _, svc := range repo.Services {
+				if _, ok := services[svc.ServiceName]; !ok {
+					return e.ErrUpdatePreviewEnvPolicy.AddDesc(fmt.Sprintf("service %s is not deployed in environment %s", svc.ServiceName, envName))
+				}
+			}
+			if repo.Workflow == "" {
+				return e.ErrUpdatePreviewEnvPolicy.AddDesc("workflow can not be empty")
+			}
+			workflow, err := commonrepo.NewWorkflowV4Coll().Find(repo.Workflow)
+			if err != nil || workflow.Project != projectName {
+				return e.ErrUpdatePreviewEnvPolicy.AddDesc(fmt.Sprintf("workflow %s is not found in project %s", repo.Workflow, projectName))
+			}
+		}
+	}
+
+	if err := commonrepo.NewProductColl().UpdatePreviewEnvPolicy(envName, projectName, policy); err != nil {
+		logger.Errorf("failed to update preview env policy of environment %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdatePreviewEnvPolicy.AddErr(err)
+	}
+	return nil
+}
+
+type PreviewEnvArgs struct {
+	MainRepo *commonmodels.MainHookRepo
+	PrID     int
+	CommitID string
+	// Services are the changed services of the pull request
+	Services []string
+	BaseURI  string
+}
+
+// previewEnvLockKey is the name of the lock which serializes the events of a pull request across the aslan replicas
+func previewEnvLockKey(mainRepo *commonmodels.MainHookRepo, prID int) string {
+	repo := fmt.Sprintf("%d/%s/%s", mainRepo.CodehostID, mainRepo.GetRepoNamespace(), mainRepo.RepoName)
+	return fmt.Sprintf("preview-env-%s-%d", crypto.Sha1([]byte(repo))[:10], prID)
+}
+
+// CreateOrUpdatePreviewEnv creates a sub environment of the base environment with the changed services of the pull request,
+// the expire time of the preview environment is refreshed if it already exists. The preview environment is returned
+// so that the changed services can be built and deployed to it.
+func CreateOrUpdatePreviewEnv(baseEnv *commonmodels.Product, args *PreviewEnvArgs, requestID string, logger *zap.SugaredLogger) (*commonmodels.Product, error) {
+	lockKey := previewEnvLockKey(args.MainRepo, args.PrID)
+	klock.Lock(lockKey)
+	defer func() {
+		if err := klock.UnlockWithRetry(lockKey, 3); err != nil {
+			logger.Warnf("failed to unlock %s, err: %s", lockKey, err)
+		}
+	}()
+
+	var expireTime int64
+	if baseEnv.PreviewEnvPolicy.TTL > 0 {
+		expireTime = time.Now().Add(time.Duration(baseEnv.PreviewEnvPolicy.TTL) * time.Hour).Unix()
+	}
+
+	previewEnvs, err := commonrepo.NewProductColl().ListPreviewEnvsByPR(args.MainRepo.CodehostID, args.MainRepo.GetRepoNamespace(), args.MainRepo.RepoName, args.PrID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list preview environments of pr %d, err: %w", args.PrID, err)
+	}
+	for _, env := range previewEnvs {
+		if env.ProductName != baseEnv.ProductName || env.ShareEnv.BaseEnv != baseEnv.EnvName || env.Status == setting.ProductStatusDeleting {
+			continue
+		}
+		env.PreviewEnv.CommitID = args.CommitID
+		env.PreviewEnv.ExpireTime = expireTime
+		if err := commonrepo.NewProductColl().UpdatePreviewEnv(env.EnvName, env.ProductName, env.PreviewEnv); err != nil {
+			return nil, fmt.Errorf("failed to update preview environment %s, err: %w", env.EnvName, err)
+		}
+		sendPreviewEnvComment(env, nil, args.BaseURI, env.Status, logger)
+		return env, nil
+	}
+
+	serviceSet := sets.NewString(args.Services...)
+	envName := fmt.Sprintf("pr-%d-%s%s", args.PrID, util.GetRandomNumString(3), util.GetRandomString(3))
+	product := *baseEnv
+	util.Clear(&product.ID)
+	product.EnvName = envName
+	product.Namespace = commonservice.GetProductEnvNamespace(envName, baseEnv.ProductName, "")
+	product.UpdateBy = setting.SystemUser
+	product.Status = ""
+	product.Error = ""
+	product.BaseName = ""
+	product.RecycleDay = 0
+	product.PreSleepStatus = nil
+	product.ShareEnv = commonmodels.ProductShareEnv{
+		Enable:  true,
+		IsBase:  false,
+		BaseEnv: baseEnv.EnvName,
+	}
+	product.PreviewEnvPolicy = nil
+	product.PreviewEnv = &commonmodels.PreviewEnv{
+		CodehostID:    args.MainRepo.CodehostID,
+		RepoOwner:     args.MainRepo.RepoOwner,
+		RepoNamespace: args.MainRepo.GetRepoNamespace(),
+		RepoName:      args.MainRepo.RepoName,
+		PrID:          args.PrID,
+		CommitID:      args.CommitID,
+		ExpireTime:    expireTime,
+	}
+	// only the changed services are deployed, the others are served by the base environment
+	product.Services = make([][]*commonmodels.ProductService, 0)
+	for _, group := range baseEnv.Services {
+		services := make([]*commonmodels.ProductService, 0)
+		for _, svc := range group {
+			if serviceSet.Has(svc.ServiceName) {
+				services = append(services, svc)
+			}
+		}
+		product.Services = append(product.Services, services)
+	}
+
+	if err := CreateProduct(setting.SystemUser, requestID, &ProductCreateArg{&product, nil}, logger); err != nil {
+		return nil, fmt.Errorf("failed to create preview environment %s, err: %w", envName, err)
+	}
+	logger.Infof("preview environment %s/%s is created for pr %d of %s/%s", product.ProductName, envName, args.PrID, args.MainRepo.GetRepoNamespace(), args.MainRepo.RepoName)
+
+	sendPreviewEnvComment(&product, args.MainRepo, args.BaseURI, "Creating", logger)
+	return &product, nil
+}
+
+// DeletePreviewEnvsByPR deletes the preview environments created from the base environment when the pull request is closed
+func DeletePreviewEnvsByPR(baseEnv *commonmodels.Product, mainRepo *commonmodels.MainHookRepo, prID int, baseURI, requestID string, logger *zap.SugaredLogger) error {
+	previewEnvs, err := commonrepo.NewProductColl().ListPreviewEnvsByPR(mainRepo.CodehostID, mainRepo.GetRepoNamespace(), mainRepo.RepoName, prID)
+	if err != nil {
+		return fmt.Errorf("failed to list preview environments of pr %d, err: %w", prID, err)
+	}
+	for _, env := range previewEnvs {
+		if env.ProductName != baseEnv.ProductName || env.ShareEnv.BaseEnv != baseEnv.EnvName || env.Status == setting.ProductStatusDeleting {
+			continue
+		}
+		deletePreviewEnv(env, baseURI, requestID, logger)
+	}
+	return nil
+}
+
+// CleanExpiredPreviewEnvs deletes the preview environments exceeding the ttl
+func CleanExpiredPreviewEnvs() {
+	logger := log.SugaredLogger().With("service", "CleanExpiredPreviewEnvs")
+
+	envs, err := commonrepo.NewProductColl().ListExpiredPreviewEnvs(time.Now().Unix())
+	if err != nil {
+		logger.Errorf("failed to list expired preview environments, err: %s", err)
+		return
+	}
+	for _, env := range envs {
+		deletePreviewEnv(env, "", "", logger)
+	}
+}
+
+func deletePreviewEnv(env *commonmodels.Product, baseURI, requestID string, logger *zap.SugaredLogger) {
+	if err := DeleteProduct(setting.SystemUser, env.EnvName, env.ProductName, requestID, true, logger); err != nil {
+		logger.Errorf("failed to delete preview environment %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		return
+	}
+	logger.Infof("preview environment %s/%s of pr %d is deleted", env.ProductName, env.EnvName, env.PreviewEnv.PrID)
+
+	sendPreviewEnvComment(env, nil, baseURI, "Completed", logger)
+}
+
+// sendPreviewEnvComment creates or updates the comment in the pull request, mainRepo is only used when the comment is created
+func sendPreviewEnvComment(env *commonmodels.Product, mainRepo *commonmodels.MainHookRepo, baseURI, status string, logger *zap.SugaredLogger) {
+	if env.PreviewEnv.NotificationID == "" && mainRepo == nil {
+		return
+	}
+
+	notification, err := scmnotify.NewService().SendPreviewEnvWebhookComment(env.PreviewEnv.NotificationID, mainRepo, env.PreviewEnv.PrID, baseURI, &commonmodels.PrTaskInfo{
+		EnvName:     env.EnvName,
+		ProductName: env.ProductName,
+		EnvStatus:   status,
+		ExpireTime:  env.PreviewEnv.ExpireTime,
+	}, logger)
+	if err != nil {
+		logger.Warnf("failed to comment preview environment %s/%s in pr %d, err: %s", env.ProductName, env.EnvName, env.PreviewEnv.PrID, err)
+		return
+	}
+	if env.PreviewEnv.NotificationID == "" {
+		env.PreviewEnv.NotificationID = notification.ID.Hex()
+		if err := commonrepo.NewProductColl().UpdatePreviewEnv(env.EnvName, env.ProductName, env.PreviewEnv); err != nil {
+			logger.Warnf("failed to save the notification of preview environment %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing preview env", func() {
+
+	Describe("test previewEnvLockKey", func() {
+		It("should be a valid configmap name and differ by repo and pr", func() {
+			repo := &commonmodels.MainHookRepo{CodehostID: 1, RepoOwner: "KodeRover", RepoName: "Zadig_Repo"}
+			key := previewEnvLockKey(repo, 12)
+			Expect(validation.IsDNS1123Subdomain(key)).To(BeEmpty())
+			Expect(key).To(Equal(previewEnvLockKey(repo, 12)))
+			Expect(key).NotTo(Equal(previewEnvLockKey(repo, 13)))
+
+			otherRepo := &commonmodels.MainHookRepo{CodehostID: 2, RepoOwner: "KodeRover", RepoName: "Zadig_Repo"}
+			Expect(key).NotTo(Equal(previewEnvLockKey(otherRepo, 12)))
+		})
+	})
+})
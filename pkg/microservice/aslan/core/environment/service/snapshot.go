@@ -0,0 +1,618 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	kubeclient "github.com/koderover/zadig/v2/pkg/shared/kube/client"
+	"github.com/koderover/zadig/v2/pkg/tool/crypto"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+	"github.com/koderover/zadig/v2/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/v2/pkg/util"
+)
+
+const (
+	snapshotDiffAdded   = "added"
+	snapshotDiffDeleted = "deleted"
+	snapshotDiffChanged = "changed"
+)
+
+// snapshotResourceKinds are the kinds of the namespace resources recorded in the snapshot
+var snapshotResourceKinds = map[string]config.CommonEnvCfgType{
+	setting.ConfigMap:             config.CommonEnvCfgTypeConfigMap,
+	setting.Secret:                config.CommonEnvCfgTypeSecret,
+	setting.PersistentVolumeClaim: config.CommonEnvCfgTypePvc,
+}
+
+// snapshotIgnoredAnnotations are set by kubernetes, they are removed from the recorded resources
+var snapshotIgnoredAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+type CreateEnvSnapshotArgs struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (args *CreateEnvSnapshotArgs) Validate() error {
+	if args.Name == "" {
+		return fmt.Errorf("snapshot name can not be empty")
+	}
+	return nil
+}
+
+type RestoreEnvSnapshotArgs struct {
+	// EnvName is the name of the new environment created from the snapshot
+	EnvName string `json:"env_name"`
+	// ClusterID and Namespace are the same as the snapshotted environment if not set
+	ClusterID string `json:"cluster_id"`
+	Namespace string `json:"namespace"`
+}
+
+func (args *RestoreEnvSnapshotArgs) Validate() error {
+	if args.EnvName == "" {
+		return fmt.Errorf("env name can not be empty")
+	}
+	return nil
+}
+
+func CreateEnvSnapshot(projectName, envName string, production bool, args *CreateEnvSnapshotArgs, userName string, logger *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:       projectName,
+		EnvName:    envName,
+		Production: &production,
+	})
+	if err != nil {
+		return nil, e.ErrCreateEnvSnapshot.AddErr(fmt.Errorf("failed to get environment %s/%s, err: %w", projectName, envName, err))
+	}
+	if env.Source != setting.SourceFromZadig && env.Source != setting.SourceFromHelm {
+		return nil, e.ErrCreateEnvSnapshot.AddDesc("only k8s yaml and helm environments support snapshots")
+	}
+
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, e.ErrCreateEnvSnapshot.AddErr(fmt.Errorf("failed to get kube client, err: %w", err))
+	}
+
+	resources := make([]*commonmodels.EnvSnapshotResource, 0)
+	for kind := range snapshotResourceKinds {
+		gvk := schema.GroupVersionKind{Version: "v1", Kind: kind}
+		objects, err := getter.ListUnstructuredResourceInCache(env.Namespace, labels.Everything(), nil, gvk, kubeClient)
+		if err != nil {
+			return nil, e.ErrCreateEnvSnapshot.AddErr(fmt.Errorf("failed to list %s in namespace %s, err: %w", kind, env.Namespace, err))
+		}
+		for _, obj := range objects {
+			if skipSnapshotResource(obj) {
+				continue
+			}
+			resource, err := newEnvSnapshotResource(obj)
+			if err != nil {
+				return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+			}
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].Name < resources[j].Name
+	})
+
+	snapshot := &commonmodels.EnvSnapshot{
+		Name:        args.Name,
+		Description: args.Description,
+		ProjectName: projectName,
+		EnvName:     envName,
+		Production:  production,
+		ClusterID:   env.ClusterID,
+		Namespace:   env.Namespace,
+		Product:     env,
+		Resources:   resources,
+		CreatedBy:   userName,
+		CreateTime:  time.Now().Unix(),
+	}
+	if err := encryptSnapshotResources(resources); err != nil {
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+	if err := commonrepo.NewEnvSnapshotColl().Create(snapshot); err != nil {
+		logger.Errorf("failed to create snapshot %s of environment %s/%s, err: %s", args.Name, projectName, envName, err)
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+	if err := commonrepo.NewEnvSnapshotResourceColl().BulkCreate(snapshot.ID.Hex(), resources); err != nil {
+		logger.Errorf("failed to save the resources of snapshot %s, err: %s", args.Name, err)
+		if err := deleteEnvSnapshots([]string{snapshot.ID.Hex()}); err != nil {
+			logger.Errorf("failed to delete the incomplete snapshot %s, err: %s", args.Name, err)
+		}
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+
+	cleanExpiredEnvSnapshots(projectName, envName, production, logger)
+
+	// the content is too large to be returned, use the get API instead
+	snapshot.Product = nil
+	snapshot.Resources = nil
+	return snapshot, nil
+}
+
+// cleanExpiredEnvSnapshots deletes the snapshots of the environment exceeding the retention of the system capacity strategy
+func cleanExpiredEnvSnapshots(projectName, envName string, production bool, logger *zap.SugaredLogger) {
+	strategy, err := commonrepo.NewStrategyColl().GetByTarget(commonmodels.EnvSnapshotRetention)
+	if err == mongo.ErrNoDocuments {
+		strategy = commonmodels.DefaultEnvSnapshotRetention
+	} else if err != nil {
+		logger.Warnf("failed to get the retention of environment snapshots, err: %s", err)
+		return
+	}
+	if strategy.Retention == nil {
+		return
+	}
+
+	expired, err := commonrepo.NewEnvSnapshotColl().ListExpiredIDs(projectName, envName, production, strategy.Retention.MaxItems, strategy.Retention.MaxDays)
+	if err != nil {
+		logger.Warnf("failed to list the expired snapshots of environment %s/%s, err: %s", projectName, envName, err)
+		return
+	}
+	if err := deleteEnvSnapshots(expired); err != nil {
+		logger.Warnf("failed to clean the expired snapshots of environment %s/%s, err: %s", projectName, envName, err)
+	}
+}
+
+func deleteEnvSnapshots(ids []string) error {
+	if err := commonrepo.NewEnvSnapshotResourceColl().DeleteBySnapshots(ids); err != nil {
+		return err
+	}
+	return commonrepo.NewEnvSnapshotColl().DeleteByIDs(ids)
+}
+
+// encryptSnapshotResources encrypts the recorded secrets, they are decrypted only for restoring and comparing
+func encryptSnapshotResources(resources []*commonmodels.EnvSnapshotResource) error {
+	for _, resource := range resources {
+		if resource.Kind != setting.Secret || resource.Encrypted {
+			continue
+		}
+		encrypted, err := crypto.AesEncrypt(resource.Yaml)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secret %s, err: %w", resource.Name, err)
+		}
+		resource.Yaml = encrypted
+		resource.Encrypted = true
+	}
+	return nil
+}
+
+func decryptSnapshotResources(resources []*commonmodels.EnvSnapshotResource) error {
+	for _, resource := range resources {
+		if !resource.Encrypted {
+			continue
+		}
+		decrypted, err := crypto.AesDecrypt(resource.Yaml)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s %s, err: %w", resource.Kind, resource.Name, err)
+		}
+		resource.Yaml = decrypted
+		resource.Encrypted = false
+	}
+	return nil
+}
+
+// redactSnapshotSecret replaces the values of the secret with the mask, the keys are kept to show what is changed
+func redactSnapshotSecret(secretYaml string) string {
+	content := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(secretYaml), &content); err != nil {
+		return setting.MaskValue
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := content[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range values {
+			values[key] = setting.MaskValue
+		}
+	}
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return setting.MaskValue
+	}
+	return string(data)
+}
+
+// skipSnapshotResource returns true for the resources created by kubernetes or helm for internal use
+func skipSnapshotResource(obj *unstructured.Unstructured) bool {
+	switch obj.GetKind() {
+	case setting.ConfigMap:
+		return obj.GetName() == "kube-root-ca.crt" || obj.GetName() == "istio-ca-root-cert"
+	case setting.Secret:
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		return secretType == "kubernetes.io/service-account-token" || secretType == "helm.sh/release.v1"
+	}
+	return false
+}
+
+func newEnvSnapshotResource(obj *unstructured.Unstructured) (*commonmodels.EnvSnapshotResource, error) {
+	serviceName := obj.GetLabels()["s-service"]
+	managed := serviceName != "" || obj.GetLabels()["app.kubernetes.io/managed-by"] == "Helm"
+
+	obj.SetManagedFields(nil)
+	obj.SetUID("")
+	obj.SetSelfLink("")
+	obj.SetResourceVersion("")
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetGeneration(0)
+	obj.SetOwnerReferences(nil)
+	obj.SetNamespace("")
+	annotations := obj.GetAnnotations()
+	for _, annotation := range snapshotIgnoredAnnotations {
+		delete(annotations, annotation)
+	}
+	obj.SetAnnotations(annotations)
+	content := obj.UnstructuredContent()
+	delete(content, "status")
+	if obj.GetKind() == setting.PersistentVolumeClaim {
+		// the bound volume can not be reused by the restored environment
+		unstructured.RemoveNestedField(content, "spec", "volumeName")
+	}
+
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s/%s, err: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return &commonmodels.EnvSnapshotResource{
+		Kind:        obj.GetKind(),
+		Name:        obj.GetName(),
+		ServiceName: serviceName,
+		Managed:     managed,
+		Yaml:        string(data),
+	}, nil
+}
+
+func ListEnvSnapshots(projectName, envName string, production bool, logger *zap.SugaredLogger) ([]*commonmodels.EnvSnapshot, error) {
+	resp, err := commonrepo.NewEnvSnapshotColl().List(projectName, envName, production)
+	if err != nil {
+		logger.Errorf("failed to list snapshots of environment %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrListEnvSnapshot.AddErr(err)
+	}
+	return resp, nil
+}
+
+// getEnvSnapshot returns the snapshot of the environment, the recorded resources are loaded and decrypted if withResources is true
+func getEnvSnapshot(projectName, envName string, production bool, id string, withResources bool) (*commonmodels.EnvSnapshot, error) {
+	snapshot, err := commonrepo.NewEnvSnapshotColl().GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.ProjectName != projectName || snapshot.EnvName != envName || snapshot.Production != production {
+		return nil, fmt.Errorf("snapshot %s not found in environment %s/%s", id, projectName, envName)
+	}
+	if !withResources {
+		return snapshot, nil
+	}
+
+	snapshot.Resources, err = commonrepo.NewEnvSnapshotResourceColl().ListBySnapshot(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the resources of snapshot %s, err: %w", id, err)
+	}
+	if err := decryptSnapshotResources(snapshot.Resources); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetEnvSnapshot returns the snapshot with the recorded content, the values of the secrets are redacted
+func GetEnvSnapshot(projectName, envName string, production bool, id string, logger *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	snapshot, err := getEnvSnapshot(projectName, envName, production, id, true)
+	if err != nil {
+		logger.Errorf("failed to get snapshot %s, err: %s", id, err)
+		return nil, e.ErrGetEnvSnapshot.AddErr(err)
+	}
+	for _, resource := range snapshot.Resources {
+		if resource.Kind == setting.Secret {
+			resource.Yaml = redactSnapshotSecret(resource.Yaml)
+		}
+	}
+	return snapshot, nil
+}
+
+func DeleteEnvSnapshot(projectName, envName string, production bool, id string, logger *zap.SugaredLogger) error {
+	if _, err := getEnvSnapshot(projectName, envName, production, id, false); err != nil {
+		return e.ErrDeleteEnvSnapshot.AddErr(err)
+	}
+	if err := deleteEnvSnapshots([]string{id}); err != nil {
+		logger.Errorf("failed to delete snapshot %s, err: %s", id, err)
+		return e.ErrDeleteEnvSnapshot.AddErr(err)
+	}
+	return nil
+}
+
+// RestoreEnvSnapshot creates a new environment with the services, variables and namespace resources recorded in the snapshot,
+// the resources applied by the services are restored by deploying the services of the recorded revisions.
+func RestoreEnvSnapshot(projectName, envName string, production bool, id string, args *RestoreEnvSnapshotArgs, userName, requestID string, logger *zap.SugaredLogger) error {
+	snapshot, err := getEnvSnapshot(projectName, envName, production, id, true)
+	if err != nil {
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+	if snapshot.Product == nil {
+		return e.ErrRestoreEnvSnapshot.AddDesc("the snapshot does not contain the environment")
+	}
+
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: args.EnvName}); err == nil {
+		return e.ErrRestoreEnvSnapshot.AddDesc(fmt.Sprintf("environment %s already exists", args.EnvName))
+	}
+
+	product := *snapshot.Product
+	util.Clear(&product.ID)
+	product.EnvName = args.EnvName
+	product.ClusterID = snapshot.ClusterID
+	if args.ClusterID != "" {
+		product.ClusterID = args.ClusterID
+	}
+	product.Namespace = commonservice.GetProductEnvNamespace(args.EnvName, projectName, args.Namespace)
+	product.Status = ""
+	product.Error = ""
+	product.PreSleepStatus = nil
+	// sharing and grayscale relations are bound to the original environments, they are not restored
+	product.ShareEnv = commonmodels.ProductShareEnv{}
+	product.IstioGrayscale = commonmodels.IstioGrayscale{}
+	product.PreviewEnvPolicy = nil
+	product.PreviewEnv = nil
+
+	product.EnvConfigs = make([]*commonmodels.CreateUpdateCommonEnvCfgArgs, 0)
+	for _, resource := range snapshot.Resources {
+		if resource.Managed {
+			continue
+		}
+		product.EnvConfigs = append(product.EnvConfigs, &commonmodels.CreateUpdateCommonEnvCfgArgs{
+			EnvName:          args.EnvName,
+			ProductName:      projectName,
+			Name:             resource.Name,
+			YamlData:         resource.Yaml,
+			CommonEnvCfgType: snapshotResourceKinds[resource.Kind],
+		})
+	}
+
+	if err := CreateProduct(userName, requestID, &ProductCreateArg{&product, nil}, logger); err != nil {
+		logger.Errorf("failed to restore snapshot %s to environment %s, err: %s", id, args.EnvName, err)
+		return e.ErrRestoreEnvSnapshot.AddErr(err)
+	}
+	return nil
+}
+
+type EnvSnapshotDiff struct {
+	GlobalValues *EnvSnapshotValueDiff      `json:"global_values,omitempty"`
+	Services     []*EnvSnapshotServiceDiff  `json:"services"`
+	Resources    []*EnvSnapshotResourceDiff `json:"resources"`
+}
+
+type EnvSnapshotValueDiff struct {
+	Base   string `json:"base"`
+	Target string `json:"target"`
+}
+
+type EnvSnapshotServiceDiff struct {
+	ServiceName string                  `json:"service_name"`
+	Status      string                  `json:"status"`
+	Base        *EnvSnapshotServiceInfo `json:"base,omitempty"`
+	Target      *EnvSnapshotServiceInfo `json:"target,omitempty"`
+}
+
+type EnvSnapshotServiceInfo struct {
+	Revision     int64    `json:"revision"`
+	ChartVersion string   `json:"chart_version,omitempty"`
+	Images       []string `json:"images"`
+	Variables    string   `json:"variables"`
+}
+
+type EnvSnapshotResourceDiff struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	BaseYaml   string `json:"base_yaml,omitempty"`
+	TargetYaml string `json:"target_yaml,omitempty"`
+}
+
+// DiffEnvSnapshots compares the target snapshot with the base snapshot, only the differences are returned
+func DiffEnvSnapshots(projectName, envName string, production bool, baseID, targetID string, logger *zap.SugaredLogger) (*EnvSnapshotDiff, error) {
+	base, err := getEnvSnapshot(projectName, envName, production, baseID, true)
+	if err != nil {
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	target, err := getEnvSnapshot(projectName, envName, production, targetID, true)
+	if err != nil {
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	if base.Product == nil || target.Product == nil {
+		return nil, e.ErrDiffEnvSnapshot.AddDesc("the snapshot does not contain the environment")
+	}
+
+	resp := &EnvSnapshotDiff{}
+
+	baseGlobalValues, err := snapshotGlobalValues(base.Product)
+	if err != nil {
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	targetGlobalValues, err := snapshotGlobalValues(target.Product)
+	if err != nil {
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	if baseGlobalValues != targetGlobalValues {
+		resp.GlobalValues = &EnvSnapshotValueDiff{
+			Base:   baseGlobalValues,
+			Target: targetGlobalValues,
+		}
+	}
+
+	resp.Services = diffSnapshotServices(base.Product, target.Product)
+	resp.Resources = diffSnapshotResources(base.Resources, target.Resources)
+	return resp, nil
+}
+
+func diffSnapshotServices(base, target *commonmodels.Product) []*EnvSnapshotServiceDiff {
+	resp := make([]*EnvSnapshotServiceDiff, 0)
+
+	baseServices := base.GetServiceMap()
+	targetServices := target.GetServiceMap()
+	serviceNames := make([]string, 0)
+	for name := range baseServices {
+		serviceNames = append(serviceNames, name)
+	}
+	for name := range targetServices {
+		if _, ok := baseServices[name]; !ok {
+			serviceNames = append(serviceNames, name)
+		}
+	}
+	sort.Strings(serviceNames)
+	for _, name := range serviceNames {
+		baseSvc, inBase := baseServices[name]
+		targetSvc, inTarget := targetServices[name]
+		diff := &EnvSnapshotServiceDiff{ServiceName: name}
+		switch {
+		case !inBase:
+			diff.Status = snapshotDiffAdded
+			diff.Target = newEnvSnapshotServiceInfo(targetSvc)
+		case !inTarget:
+			diff.Status = snapshotDiffDeleted
+			diff.Base = newEnvSnapshotServiceInfo(baseSvc)
+		default:
+			diff.Base = newEnvSnapshotServiceInfo(baseSvc)
+			diff.Target = newEnvSnapshotServiceInfo(targetSvc)
+			if diff.Base.Revision == diff.Target.Revision && diff.Base.ChartVersion == diff.Target.ChartVersion &&
+				diff.Base.Variables == diff.Target.Variables && strings.Join(diff.Base.Images, ",") == strings.Join(diff.Target.Images, ",") {
+				continue
+			}
+			diff.Status = snapshotDiffChanged
+		}
+		resp = append(resp, diff)
+	}
+	return resp
+}
+
+// diffSnapshotResources compares the decrypted resources, the values of the secrets are redacted in the result
+func diffSnapshotResources(base, target []*commonmodels.EnvSnapshotResource) []*EnvSnapshotResourceDiff {
+	resp := make([]*EnvSnapshotResourceDiff, 0)
+
+	resourceKey := func(resource *commonmodels.EnvSnapshotResource) string {
+		return resource.Kind + "/" + resource.Name
+	}
+	baseResources := make(map[string]*commonmodels.EnvSnapshotResource)
+	for _, resource := range base {
+		baseResources[resourceKey(resource)] = resource
+	}
+	targetResources := make(map[string]*commonmodels.EnvSnapshotResource)
+	for _, resource := range target {
+		targetResources[resourceKey(resource)] = resource
+		baseResource, ok := baseResources[resourceKey(resource)]
+		switch {
+		case !ok:
+			resp = append(resp, &EnvSnapshotResourceDiff{
+				Kind:       resource.Kind,
+				Name:       resource.Name,
+				Status:     snapshotDiffAdded,
+				TargetYaml: resource.Yaml,
+			})
+		case baseResource.Yaml != resource.Yaml:
+			resp = append(resp, &EnvSnapshotResourceDiff{
+				Kind:       resource.Kind,
+				Name:       resource.Name,
+				Status:     snapshotDiffChanged,
+				BaseYaml:   baseResource.Yaml,
+				TargetYaml: resource.Yaml,
+			})
+		}
+	}
+	for _, resource := range base {
+		if _, ok := targetResources[resourceKey(resource)]; !ok {
+			resp = append(resp, &EnvSnapshotResourceDiff{
+				Kind:     resource.Kind,
+				Name:     resource.Name,
+				Status:   snapshotDiffDeleted,
+				BaseYaml: resource.Yaml,
+			})
+		}
+	}
+
+	for _, diff := range resp {
+		if diff.Kind != setting.Secret {
+			continue
+		}
+		if diff.BaseYaml != "" {
+			diff.BaseYaml = redactSnapshotSecret(diff.BaseYaml)
+		}
+		if diff.TargetYaml != "" {
+			diff.TargetYaml = redactSnapshotSecret(diff.TargetYaml)
+		}
+	}
+	return resp
+}
+
+// snapshotGlobalValues returns the global values of helm environments or the global variables of k8s yaml environments
+func snapshotGlobalValues(product *commonmodels.Product) (string, error) {
+	if product.Source == setting.SourceFromHelm {
+		return product.DefaultValues, nil
+	}
+	if len(product.GlobalVariables) == 0 {
+		return "", nil
+	}
+	data, err := yaml.Marshal(product.GlobalVariables)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal global variables, err: %w", err)
+	}
+	return string(data), nil
+}
+
+func newEnvSnapshotServiceInfo(svc *commonmodels.ProductService) *EnvSnapshotServiceInfo {
+	info := &EnvSnapshotServiceInfo{
+		Revision: svc.Revision,
+		Images:   make([]string, 0),
+	}
+	for _, container := range svc.Containers {
+		info.Images = append(info.Images, container.Image)
+	}
+	sort.Strings(info.Images)
+	if svc.Render != nil {
+		info.ChartVersion = svc.Render.ChartVersion
+		if svc.Render.OverrideYaml != nil {
+			info.Variables = svc.Render.OverrideYaml.YamlContent
+		}
+		if svc.Render.OverrideValues != "" {
+			info.Variables += svc.Render.OverrideValues
+		}
+	}
+	return info
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/setting"
+)
+
+var testSnapshotSecret = `apiVersion: v1
+data:
+  password: MTIzNDU2
+kind: Secret
+metadata:
+  name: db
+stringData:
+  user: admin
+type: Opaque
+`
+
+func newTestSnapshotProduct(services ...*commonmodels.ProductService) *commonmodels.Product {
+	return &commonmodels.Product{Services: [][]*commonmodels.ProductService{services}}
+}
+
+var _ = Describe("Testing snapshot", func() {
+
+	Describe("test newEnvSnapshotResource", func() {
+		It("should remove the fields set by kubernetes", func() {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       setting.PersistentVolumeClaim,
+				"metadata": map[string]interface{}{
+					"name":            "data",
+					"namespace":       "dev",
+					"uid":             "1234",
+					"resourceVersion": "42",
+					"labels":          map[string]interface{}{"s-service": "mysql"},
+					"annotations": map[string]interface{}{
+						"pv.kubernetes.io/bind-completed": "yes",
+						"owner":                           "team",
+					},
+				},
+				"spec":   map[string]interface{}{"volumeName": "pvc-1234", "storageClassName": "standard"},
+				"status": map[string]interface{}{"phase": "Bound"},
+			}}
+
+			resource, err := newEnvSnapshotResource(obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resource.ServiceName).To(Equal("mysql"))
+			Expect(resource.Managed).To(BeTrue())
+
+			content := make(map[string]interface{})
+			Expect(yaml.Unmarshal([]byte(resource.Yaml), &content)).To(Succeed())
+			Expect(content).NotTo(HaveKey("status"))
+			Expect(content["spec"]).To(Equal(map[string]interface{}{"storageClassName": "standard"}))
+			Expect(content["metadata"]).To(Equal(map[string]interface{}{
+				"name":        "data",
+				"labels":      map[string]interface{}{"s-service": "mysql"},
+				"annotations": map[string]interface{}{"owner": "team"},
+			}))
+		})
+	})
+
+	DescribeTable("test skipSnapshotResource",
+		func(kind, name, secretType string, want bool) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": kind}}
+			obj.SetName(name)
+			if secretType != "" {
+				obj.Object["type"] = secretType
+			}
+			Expect(skipSnapshotResource(obj)).To(Equal(want))
+		},
+		Entry("root ca", setting.ConfigMap, "kube-root-ca.crt", "", true),
+		Entry("configmap", setting.ConfigMap, "app-config", "", false),
+		Entry("service account token", setting.Secret, "default-token", "kubernetes.io/service-account-token", true),
+		Entry("helm release", setting.Secret, "sh.helm.release.v1.app.v1", "helm.sh/release.v1", true),
+		Entry("opaque secret", setting.Secret, "db", "Opaque", false),
+	)
+
+	Describe("test redactSnapshotSecret", func() {
+		It("should mask the values and keep the keys", func() {
+			content := make(map[string]interface{})
+			Expect(yaml.Unmarshal([]byte(redactSnapshotSecret(testSnapshotSecret)), &content)).To(Succeed())
+			Expect(content["data"]).To(Equal(map[string]interface{}{"password": setting.MaskValue}))
+			Expect(content["stringData"]).To(Equal(map[string]interface{}{"user": setting.MaskValue}))
+			Expect(content["type"]).To(Equal("Opaque"))
+		})
+
+		It("should mask the whole content if it can not be parsed", func() {
+			Expect(redactSnapshotSecret("data: [")).To(Equal(setting.MaskValue))
+		})
+	})
+
+	Describe("test diffSnapshotResources", func() {
+		It("should return the added, changed and deleted resources", func() {
+			base := []*commonmodels.EnvSnapshotResource{
+				{Kind: setting.ConfigMap, Name: "same", Yaml: "a"},
+				{Kind: setting.ConfigMap, Name: "changed", Yaml: "a"},
+				{Kind: setting.ConfigMap, Name: "deleted", Yaml: "a"},
+			}
+			target := []*commonmodels.EnvSnapshotResource{
+				{Kind: setting.ConfigMap, Name: "same", Yaml: "a"},
+				{Kind: setting.ConfigMap, Name: "changed", Yaml: "b"},
+				{Kind: setting.ConfigMap, Name: "added", Yaml: "c"},
+			}
+			Expect(diffSnapshotResources(base, target)).To(Equal([]*EnvSnapshotResourceDiff{
+				{Kind: setting.ConfigMap, Name: "changed", Status: snapshotDiffChanged, BaseYaml: "a", TargetYaml: "b"},
+				{Kind: setting.ConfigMap, Name: "added", Status: snapshotDiffAdded, TargetYaml: "c"},
+				{Kind: setting.ConfigMap, Name: "deleted", Status: snapshotDiffDeleted, BaseYaml: "a"},
+			}))
+		})
+
+		It("should compare the secret values but never return them", func() {
+			changedSecret := `apiVersion: v1
+data:
+  password: YWJjZGVm
+kind: Secret
+metadata:
+  name: db
+`
+			base := []*commonmodels.EnvSnapshotResource{{Kind: setting.Secret, Name: "db", Yaml: testSnapshotSecret}}
+			target := []*commonmodels.EnvSnapshotResource{{Kind: setting.Secret, Name: "db", Yaml: changedSecret}}
+
+			diffs := diffSnapshotResources(base, target)
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].Status).To(Equal(snapshotDiffChanged))
+			Expect(diffs[0].BaseYaml).NotTo(ContainSubstring("MTIzNDU2"))
+			Expect(diffs[0].BaseYaml).NotTo(ContainSubstring("admin"))
+			Expect(diffs[0].TargetYaml).NotTo(ContainSubstring("YWJjZGVm"))
+			Expect(diffs[0].TargetYaml).To(ContainSubstring(setting.MaskValue))
+
+			Expect(diffSnapshotResources(base, base)).To(BeEmpty())
+		})
+	})
+
+	Describe("test diffSnapshotServices", func() {
+		It("should return the added, changed and deleted services", func() {
+			base := newTestSnapshotProduct(
+				&commonmodels.ProductService{ServiceName: "same", Revision: 1},
+				&commonmodels.ProductService{ServiceName: "changed", Revision: 1, Containers: []*commonmodels.Container{{Image: "nginx:1"}}},
+				&commonmodels.ProductService{ServiceName: "deleted", Revision: 1},
+			)
+			target := newTestSnapshotProduct(
+				&commonmodels.ProductService{ServiceName: "same", Revision: 1},
+				&commonmodels.ProductService{ServiceName: "changed", Revision: 1, Containers: []*commonmodels.Container{{Image: "nginx:2"}}},
+				&commonmodels.ProductService{ServiceName: "added", Revision: 2},
+			)
+
+			diffs := diffSnapshotServices(base, target)
+			Expect(diffs).To(HaveLen(3))
+			Expect(diffs[0].ServiceName).To(Equal("added"))
+			Expect(diffs[0].Status).To(Equal(snapshotDiffAdded))
+			Expect(diffs[0].Target.Revision).To(Equal(int64(2)))
+			Expect(diffs[1].ServiceName).To(Equal("changed"))
+			Expect(diffs[1].Status).To(Equal(snapshotDiffChanged))
+			Expect(diffs[1].Base.Images).To(Equal([]string{"nginx:1"}))
+			Expect(diffs[1].Target.Images).To(Equal([]string{"nginx:2"}))
+			Expect(diffs[2].ServiceName).To(Equal("deleted"))
+			Expect(diffs[2].Status).To(Equal(snapshotDiffDeleted))
+		})
+	})
+})
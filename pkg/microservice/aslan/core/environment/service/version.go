@@ -413,5 +413,20 @@ func RollbackEnvServiceVersion(ctx *internalhandler.Context, projectName, envNam
 		}
 	}
 
+	// the rollback is recorded as an incident for the change failure rate and the mean time to restore,
+	// it does not fail the rollback if the incident can not be saved
+	err = commonutil.CreateRollbackIncident(&commonmodels.Incident{
+		ProjectName: projectName,
+		Title:       fmt.Sprintf("rollback service %s to revision %d", serviceName, revision),
+		Source:      config.IncidentSourceEnvRollback,
+		EnvName:     envName,
+		ServiceName: serviceName,
+		Production:  isProduction,
+		CreatedBy:   ctx.UserName,
+	})
+	if err != nil {
+		log.Errorf("failed to create incident for the rollback of %s/%s/%s, error: %v", projectName, envName, serviceName, err)
+	}
+
 	return nil
 }
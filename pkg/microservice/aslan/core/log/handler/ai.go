@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -30,3 +31,20 @@ func AIAnalyzeBuildLog(c *gin.Context) {
 	args.Log = string(data)
 	ctx.Resp, ctx.Err = ai.AnalyzeBuildLog(args, c.Query("projectName"), c.Param("workflowName"), c.Param("jobName"), taskID, ctx.Logger)
 }
+
+// AIAnalyzeBuildLogSSE streams the analysis of the build log as it is generated
+func AIAnalyzeBuildLogSSE(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("failed to get raw data")
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+
+	args := &ai.BuildLogAnalysisArgs{Log: string(data)}
+	internalhandler.Stream(c, func(ctx1 context.Context, streamChan chan interface{}) {
+		ai.AnalyzeBuildLogStream(ctx1, streamChan, args, ctx.Logger)
+	}, ctx.Logger)
+}
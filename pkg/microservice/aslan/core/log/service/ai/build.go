@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	openapi "github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service"
@@ -28,7 +27,7 @@ func AnalyzeBuildLog(args *BuildLogAnalysisArgs, project, pipeline, job string,
 	log := args.Log
 	prompt := fmt.Sprintf("%s; 构建日志数据: \"\"\"%s\"\"\"", BuildLogAnalysisPrompt, util.RemoveExtraSpaces(splitBuildLogByRowNum(log, 500)))
 
-	answer, err := client.GetCompletion(ctx, prompt, llm.WithModel(openapi.GPT3Dot5Turbo16K))
+	answer, err := client.GetCompletion(ctx, prompt)
 	if err != nil {
 		logger.Errorf("failed to get answer from ai: %v, the error is: %+v", client.GetName(), err)
 		return "", err
@@ -37,6 +36,34 @@ func AnalyzeBuildLog(args *BuildLogAnalysisArgs, project, pipeline, job string,
 	return answer, nil
 }
 
+// AnalyzeBuildLogStream sends the chunks of the analysis to the stream channel as they are generated by the llm
+func AnalyzeBuildLogStream(ctx context.Context, streamChan chan interface{}, args *BuildLogAnalysisArgs, logger *zap.SugaredLogger) {
+	client, err := service.GetDefaultLLMClient(ctx)
+	if err != nil {
+		logger.Errorf("failed to get llm client, the error is: %+v", err)
+		sendStream(ctx, streamChan, err.Error())
+		return
+	}
+
+	prompt := fmt.Sprintf("%s; 构建日志数据: \"\"\"%s\"\"\"", BuildLogAnalysisPrompt, util.RemoveExtraSpaces(splitBuildLogByRowNum(args.Log, 500)))
+	_, err = client.GetCompletionStream(ctx, prompt, func(chunk string) error {
+		return sendStream(ctx, streamChan, chunk)
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Errorf("failed to get answer from ai: %v, the error is: %+v", client.GetName(), err)
+		sendStream(ctx, streamChan, err.Error())
+	}
+}
+
+func sendStream(ctx context.Context, streamChan chan interface{}, msg string) error {
+	select {
+	case streamChan <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func calculateTokenNum(msg string) (int, error) {
 	num, err := llm.NumTokensFromPrompt(msg, "")
 	if err != nil {
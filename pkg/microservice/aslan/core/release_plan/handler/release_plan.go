@@ -225,6 +225,26 @@ func ExecuteReleaseJob(c *gin.Context) {
 	ctx.Err = service.ExecuteReleaseJob(ctx, c.Param("id"), req)
 }
 
+func ExecuteAllReleaseJobs(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	err = commonutil.CheckZadigXLicenseStatus()
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	// only release plan manager can execute release jobs
+	// and the workflow execute permission is checked in service
+	ctx.Err = service.ExecuteAllReleaseJobs(ctx, c.Param("id"))
+}
+
 func UpdateReleaseJobStatus(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -20,9 +20,11 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow"
 	"github.com/koderover/zadig/v2/pkg/shared/client/user"
 	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
@@ -30,6 +32,9 @@ import (
 	"github.com/koderover/zadig/v2/pkg/types"
 )
 
+// createWorkflowTask is replaced in tests
+var createWorkflowTask = workflow.CreateWorkflowTaskV4
+
 type ReleaseJobExecutor interface {
 	Execute(plan *models.ReleasePlan) error
 }
@@ -39,6 +44,7 @@ type ExecuteReleaseJobContext struct {
 	UserID        string
 	Account       string
 	UserName      string
+	IdentityType  string
 }
 
 func NewReleaseJobExecutor(c *ExecuteReleaseJobContext, args *ExecuteReleaseJobArgs) (ReleaseJobExecutor, error) {
@@ -89,6 +95,7 @@ func (e *TextReleaseJobExecutor) Execute(plan *models.ReleasePlan) error {
 		job.Status = config.ReleasePlanJobStatusDone
 		job.ExecutedBy = e.ExecutedBy
 		job.ExecutedTime = time.Now().Unix()
+		job.FinishedTime = time.Now().Unix()
 		return nil
 	}
 	return errors.Errorf("job %s not found", e.ID)
@@ -129,39 +136,161 @@ func (e *WorkflowReleaseJobExecutor) Execute(plan *models.ReleasePlan) error {
 		if job.Status != config.ReleasePlanJobStatusTodo && job.Status != config.ReleasePlanJobStatusFailed {
 			return errors.Errorf("job %s status %s can't execute", job.Name, job.Status)
 		}
-		// check workflow execute permission
-		ctx := e.Ctx
-		if !ctx.AuthResources.IsSystemAdmin {
-			if _, ok := ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project]; !ok {
-				return ErrPermissionDenied
-			}
-
-			if !ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project].IsProjectAdmin &&
-				!ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project].Workflow.Execute {
-				// check if the permission is given by collaboration mode
-				permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, spec.Workflow.Project, types.ResourceTypeWorkflow, spec.Workflow.Name, types.WorkflowActionRun)
-				if err != nil || !permitted {
-					return ErrPermissionDenied
-				}
-			}
-		}
-
-		result, err := workflow.CreateWorkflowTaskV4(&workflow.CreateWorkflowTaskV4Args{
-			Name:    ctx.UserName,
-			Account: ctx.Account,
-			UserID:  ctx.UserID,
-		}, spec.Workflow, log.SugaredLogger().With("source", "release plan"))
-		if err != nil {
-			return errors.Wrapf(err, "failed to create workflow task %s", spec.Workflow.Name)
+		if err := checkWorkflowExecutePermission(e.Ctx, spec.Workflow); err != nil {
+			return err
 		}
+		return startWorkflowReleaseJob(e.Ctx, job, spec)
+	}
+	return errors.Errorf("job %s not found", e.ID)
+}
 
-		spec.TaskID = result.TaskID
-		spec.Status = config.StatusPrepare
-		job.Spec = spec
-		job.Status = config.ReleasePlanJobStatusRunning
-		job.ExecutedBy = ctx.UserName
-		job.ExecutedTime = time.Now().Unix()
+func checkWorkflowExecutePermission(ctx *ExecuteReleaseJobContext, workflow *models.WorkflowV4) error {
+	if ctx.AuthResources.IsSystemAdmin {
 		return nil
 	}
-	return errors.Errorf("job %s not found", e.ID)
+	if _, ok := ctx.AuthResources.ProjectAuthInfo[workflow.Project]; !ok {
+		return ErrPermissionDenied
+	}
+
+	if !ctx.AuthResources.ProjectAuthInfo[workflow.Project].IsProjectAdmin &&
+		!ctx.AuthResources.ProjectAuthInfo[workflow.Project].Workflow.Execute {
+		// check if the permission is given by collaboration mode
+		permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, workflow.Project, types.ResourceTypeWorkflow, workflow.Name, types.WorkflowActionRun)
+		if err != nil || !permitted {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+// startWorkflowReleaseJob creates the workflow task of the release job, the permission should be checked before
+func startWorkflowReleaseJob(ctx *ExecuteReleaseJobContext, job *models.ReleaseJob, spec *models.WorkflowReleaseJobSpec) error {
+	result, err := createWorkflowTask(&workflow.CreateWorkflowTaskV4Args{
+		Name:         ctx.UserName,
+		Account:      ctx.Account,
+		UserID:       ctx.UserID,
+		IdentityType: ctx.IdentityType,
+	}, spec.Workflow, log.SugaredLogger().With("source", "release plan"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create workflow task %s", spec.Workflow.Name)
+	}
+
+	spec.TaskID = result.TaskID
+	spec.Status = config.StatusPrepare
+	job.Spec = spec
+	job.Status = config.ReleasePlanJobStatusRunning
+	job.ExecutedBy = ctx.UserName
+	job.ExecutedTime = time.Now().Unix()
+	job.FinishedTime = 0
+	job.Error = ""
+	return nil
+}
+
+// getReleaseJobDependencies returns the upstream job ids of every release job,
+// a job without depends_on has no upstream jobs and runs in parallel with the others
+func getReleaseJobDependencies(jobs []*models.ReleaseJob) map[string][]string {
+	stage := &commonutil.JobDependencyStage{Parallel: true}
+	dependsOn := make(map[string][]string)
+	for _, job := range jobs {
+		stage.Jobs = append(stage.Jobs, job.ID)
+		dependsOn[job.ID] = job.DependsOn
+	}
+	return commonutil.ResolveJobDependencies([]*commonutil.JobDependencyStage{stage}, dependsOn)
+}
+
+func hasReleaseJobDependencies(jobs []*models.ReleaseJob) bool {
+	for _, job := range jobs {
+		if len(job.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReleaseJobDependencies checks whether the upstream jobs of the release job are all done,
+// release jobs can be executed in any order if no job declares depends_on
+func checkReleaseJobDependencies(plan *models.ReleasePlan, jobID string) error {
+	if !hasReleaseJobDependencies(plan.Jobs) {
+		return nil
+	}
+	jobMap := lo.KeyBy(plan.Jobs, func(job *models.ReleaseJob) string {
+		return job.ID
+	})
+	for _, dep := range getReleaseJobDependencies(plan.Jobs)[jobID] {
+		if upstream, ok := jobMap[dep]; ok && upstream.Status != config.ReleasePlanJobStatusDone {
+			return errors.Errorf("release job %s should be done first", upstream.Name)
+		}
+	}
+	return nil
+}
+
+// executeReleaseJobsInOrder starts the workflow release jobs whose upstream jobs are all done.
+// Text release jobs are left to the manager, and the auto execution stops when any release job fails.
+// It returns the logs of the started jobs.
+func executeReleaseJobsInOrder(plan *models.ReleasePlan) ([]*models.ReleasePlanLog, error) {
+	execution := plan.AutoExecution
+	if execution == nil {
+		return nil, nil
+	}
+	for _, job := range plan.Jobs {
+		if job.Status == config.ReleasePlanJobStatusFailed {
+			plan.AutoExecution = nil
+			return nil, errors.Errorf("release job %s failed", job.Name)
+		}
+	}
+	if checkReleasePlanJobsAllDone(plan) {
+		plan.AutoExecution = nil
+		return nil, nil
+	}
+
+	ctx := &ExecuteReleaseJobContext{
+		UserID:       execution.UserID,
+		Account:      execution.Account,
+		UserName:     execution.UserName,
+		IdentityType: execution.IdentityType,
+	}
+	jobMap := lo.KeyBy(plan.Jobs, func(job *models.ReleaseJob) string {
+		return job.ID
+	})
+	dependencies := getReleaseJobDependencies(plan.Jobs)
+	planLogs := make([]*models.ReleasePlanLog, 0)
+	for _, job := range plan.Jobs {
+		if job.Status != config.ReleasePlanJobStatusTodo || job.Type != config.JobWorkflow {
+			continue
+		}
+		ready := lo.EveryBy(dependencies[job.ID], func(dep string) bool {
+			upstream, ok := jobMap[dep]
+			return !ok || upstream.Status == config.ReleasePlanJobStatusDone
+		})
+		if !ready {
+			continue
+		}
+
+		spec := new(models.WorkflowReleaseJobSpec)
+		err := models.IToi(job.Spec, spec)
+		if err == nil && spec.Workflow == nil {
+			err = errors.New("workflow is nil")
+		}
+		if err == nil {
+			err = startWorkflowReleaseJob(ctx, job, spec)
+		}
+		if err != nil {
+			job.Status = config.ReleasePlanJobStatusFailed
+			job.Error = err.Error()
+			job.FinishedTime = time.Now().Unix()
+			plan.AutoExecution = nil
+			return planLogs, errors.Wrapf(err, "execute release job %s", job.Name)
+		}
+		planLogs = append(planLogs, &models.ReleasePlanLog{
+			PlanID:     plan.ID.Hex(),
+			Username:   execution.UserName,
+			Account:    execution.Account,
+			Verb:       VerbExecute,
+			TargetName: job.Name,
+			TargetType: TargetTypeReleaseJob,
+			Detail:     "执行全部",
+			CreatedAt:  time.Now().Unix(),
+		})
+	}
+	return planLogs, nil
 }
@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/shared/handler"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+)
+
+func newReleaseJob(id, name string, status config.ReleasePlanJobStatus, dependsOn ...string) *models.ReleaseJob {
+	return &models.ReleaseJob{
+		ID:                id,
+		Name:              name,
+		Type:              config.JobWorkflow,
+		DependsOn:         dependsOn,
+		ReleaseJobRuntime: models.ReleaseJobRuntime{Status: status},
+	}
+}
+
+func TestResolveReleaseJobDependencies(t *testing.T) {
+	jobs := []*models.ReleaseJob{
+		newReleaseJob("1", "db", config.ReleasePlanJobStatusTodo),
+		newReleaseJob("2", "backend", config.ReleasePlanJobStatusTodo, "db"),
+		newReleaseJob("3", "frontend", config.ReleasePlanJobStatusTodo, "2"),
+	}
+	require.NoError(t, resolveReleaseJobDependencies(jobs))
+	assert.Equal(t, []string{"1"}, jobs[1].DependsOn)
+	assert.Equal(t, []string{"2"}, jobs[2].DependsOn)
+
+	// the dependencies are kept by id after the job is renamed
+	jobs[0].Name = "database"
+	require.NoError(t, resolveReleaseJobDependencies(jobs))
+	require.NoError(t, lintReleaseJobDependencies(jobs))
+	assert.Equal(t, []string{"1"}, jobs[1].DependsOn)
+
+	unknown := []*models.ReleaseJob{
+		newReleaseJob("1", "db", config.ReleasePlanJobStatusTodo, "cache"),
+	}
+	assert.Error(t, resolveReleaseJobDependencies(unknown))
+
+	ambiguous := []*models.ReleaseJob{
+		newReleaseJob("1", "db", config.ReleasePlanJobStatusTodo),
+		newReleaseJob("2", "db", config.ReleasePlanJobStatusTodo),
+		newReleaseJob("3", "backend", config.ReleasePlanJobStatusTodo, "db"),
+	}
+	assert.Error(t, resolveReleaseJobDependencies(ambiguous))
+}
+
+func TestLintReleaseJobDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobs    []*models.ReleaseJob
+		wantErr bool
+	}{
+		{
+			name: "no dependencies",
+			jobs: []*models.ReleaseJob{
+				newReleaseJob("1", "a", config.ReleasePlanJobStatusTodo),
+				newReleaseJob("2", "a", config.ReleasePlanJobStatusTodo),
+			},
+		},
+		{
+			name: "chain",
+			jobs: []*models.ReleaseJob{
+				newReleaseJob("1", "a", config.ReleasePlanJobStatusTodo),
+				newReleaseJob("2", "b", config.ReleasePlanJobStatusTodo, "1"),
+				newReleaseJob("3", "c", config.ReleasePlanJobStatusTodo, "1", "2"),
+			},
+		},
+		{
+			name: "depends on itself",
+			jobs: []*models.ReleaseJob{
+				newReleaseJob("1", "a", config.ReleasePlanJobStatusTodo, "1"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			jobs: []*models.ReleaseJob{
+				newReleaseJob("1", "a", config.ReleasePlanJobStatusTodo, "3"),
+				newReleaseJob("2", "b", config.ReleasePlanJobStatusTodo, "1"),
+				newReleaseJob("3", "c", config.ReleasePlanJobStatusTodo, "2"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown job",
+			jobs: []*models.ReleaseJob{
+				newReleaseJob("1", "a", config.ReleasePlanJobStatusTodo, "4"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lintReleaseJobDependencies(tt.jobs)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetReleaseJobDependencies(t *testing.T) {
+	jobs := []*models.ReleaseJob{
+		newReleaseJob("1", "db", config.ReleasePlanJobStatusTodo),
+		newReleaseJob("2", "backend", config.ReleasePlanJobStatusTodo, "1"),
+		newReleaseJob("3", "docs", config.ReleasePlanJobStatusTodo),
+	}
+	dependencies := getReleaseJobDependencies(jobs)
+	assert.Empty(t, dependencies["1"])
+	assert.Equal(t, []string{"1"}, dependencies["2"])
+	// a job without depends_on does not wait for the previous job
+	assert.Empty(t, dependencies["3"])
+}
+
+func TestCheckReleaseJobDependencies(t *testing.T) {
+	plan := &models.ReleasePlan{Jobs: []*models.ReleaseJob{
+		newReleaseJob("1", "db", config.ReleasePlanJobStatusTodo),
+		newReleaseJob("2", "backend", config.ReleasePlanJobStatusTodo, "1"),
+		newReleaseJob("3", "docs", config.ReleasePlanJobStatusTodo),
+	}}
+	assert.NoError(t, checkReleaseJobDependencies(plan, "1"))
+	assert.Error(t, checkReleaseJobDependencies(plan, "2"))
+	assert.NoError(t, checkReleaseJobDependencies(plan, "3"))
+
+	plan.Jobs[0].Status = config.ReleasePlanJobStatusDone
+	assert.NoError(t, checkReleaseJobDependencies(plan, "2"))
+}
+
+func TestExecuteAllReleaseJobsAsServiceAccount(t *testing.T) {
+	log.Init(&log.Config{Level: "info"})
+	created := make([]*workflow.CreateWorkflowTaskV4Args, 0)
+	oldCreateWorkflowTask := createWorkflowTask
+	createWorkflowTask = func(args *workflow.CreateWorkflowTaskV4Args, wf *models.WorkflowV4, log *zap.SugaredLogger) (*workflow.CreateTaskV4Resp, error) {
+		created = append(created, args)
+		return &workflow.CreateTaskV4Resp{WorkflowName: wf.Name, TaskID: int64(len(created))}, nil
+	}
+	defer func() { createWorkflowTask = oldCreateWorkflowTask }()
+
+	newWorkflowJob := func(id, name string, dependsOn ...string) *models.ReleaseJob {
+		job := newReleaseJob(id, name, config.ReleasePlanJobStatusTodo, dependsOn...)
+		job.Spec = &models.WorkflowReleaseJobSpec{Workflow: &models.WorkflowV4{Name: name}}
+		return job
+	}
+	plan := &models.ReleasePlan{Jobs: []*models.ReleaseJob{
+		newWorkflowJob("1", "db"),
+		newWorkflowJob("2", "backend", "1"),
+	}}
+	plan.AutoExecution = newReleasePlanAutoExecution(&handler.Context{
+		UserID:       "sa-id",
+		Account:      "ci-bot",
+		UserName:     "ci-bot",
+		IdentityType: setting.ServiceAccountIdentityType,
+	})
+
+	_, err := executeReleaseJobsInOrder(plan)
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, setting.ServiceAccountIdentityType, created[0].IdentityType)
+	assert.Equal(t, "ci-bot", created[0].Account)
+
+	// the watcher starts the next job with the stored execution after the first one is done
+	data, err := bson.Marshal(plan.AutoExecution)
+	require.NoError(t, err)
+	plan.AutoExecution = new(models.ReleasePlanAutoExecution)
+	require.NoError(t, bson.Unmarshal(data, plan.AutoExecution))
+	plan.Jobs[0].Status = config.ReleasePlanJobStatusDone
+
+	_, err = executeReleaseJobsInOrder(plan)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	assert.Equal(t, config.ReleasePlanJobStatusRunning, plan.Jobs[1].Status)
+	assert.Equal(t, setting.ServiceAccountIdentityType, created[1].IdentityType)
+	assert.Equal(t, "sa-id", created[1].UserID)
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 )
 
 func lintReleaseJob(_type config.ReleasePlanJobType, spec interface{}) error {
@@ -56,6 +57,75 @@ func lintReleaseTimeRange(start, end int64) error {
 	return nil
 }
 
+func lintReleaseScheduleExecuteTime(scheduleTime, start, end int64) error {
+	if scheduleTime == 0 || (start == 0 && end == 0) {
+		return nil
+	}
+	if scheduleTime < start || scheduleTime > end {
+		return errors.New("schedule execute time should be in the release time range")
+	}
+	return nil
+}
+
+// resolveReleaseJobDependencies replaces the job names in depends_on with the job ids, so renaming a job does not
+// break the dependencies. Clients can only refer to the jobs created in the same request by their names.
+func resolveReleaseJobDependencies(jobs []*models.ReleaseJob) error {
+	ids := sets.NewString()
+	nameToIDs := make(map[string][]string)
+	for _, job := range jobs {
+		ids.Insert(job.ID)
+		nameToIDs[job.Name] = append(nameToIDs[job.Name], job.ID)
+	}
+	for _, job := range jobs {
+		for i, dep := range job.DependsOn {
+			if ids.Has(dep) {
+				continue
+			}
+			switch matched := nameToIDs[dep]; len(matched) {
+			case 0:
+				return errors.Errorf("release job %s depends on job %s which does not exist", job.Name, dep)
+			case 1:
+				job.DependsOn[i] = matched[0]
+			default:
+				return errors.Errorf("release job %s depends on job %s whose name is not unique", job.Name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// lintReleaseJobDependencies checks the resolved depends_on of the release jobs for unknown jobs and cycles
+func lintReleaseJobDependencies(jobs []*models.ReleaseJob) error {
+	if !hasReleaseJobDependencies(jobs) {
+		return nil
+	}
+
+	nameCount := make(map[string]int)
+	for _, job := range jobs {
+		nameCount[job.Name]++
+	}
+	idToName := make(map[string]string)
+	for _, job := range jobs {
+		idToName[job.ID] = job.Name
+		if nameCount[job.Name] > 1 {
+			idToName[job.ID] = fmt.Sprintf("%s(%s)", job.Name, job.ID)
+		}
+	}
+	// report the dependencies by job names
+	dependencies := make(map[string][]string)
+	for id, deps := range getReleaseJobDependencies(jobs) {
+		name := idToName[id]
+		dependencies[name] = make([]string, 0, len(deps))
+		for _, dep := range deps {
+			if depName, ok := idToName[dep]; ok {
+				dep = depName
+			}
+			dependencies[name] = append(dependencies[name], dep)
+		}
+	}
+	return commonutil.ValidateJobDependencies(dependencies)
+}
+
 func lintWorkflow(workflow *models.WorkflowV4) error {
 	if workflow == nil {
 		return fmt.Errorf("workflow cannot be empty")
@@ -86,6 +86,7 @@ type OpenAPICreateReleasePlanArgs struct {
 	ManagerIdentityType string           `bson:"manager_identity_type"       yaml:"manager_identity_type"                   json:"manager_identity_type"`
 	StartTime           int64            `bson:"start_time"       yaml:"start_time"                   json:"start_time"`
 	EndTime             int64            `bson:"end_time"       yaml:"end_time"                   json:"end_time"`
+	ScheduleExecuteTime int64            `bson:"schedule_execute_time"       yaml:"schedule_execute_time"                   json:"schedule_execute_time"`
 	Description         string           `bson:"description"       yaml:"description"                   json:"description"`
 	Approval            *models.Approval `bson:"approval"       yaml:"approval"                   json:"approval,omitempty"`
 }
@@ -98,6 +99,8 @@ func OpenAPICreateReleasePlan(c *handler.Context, rawArgs *OpenAPICreateReleaseP
 		EndTime:     rawArgs.EndTime,
 		Description: rawArgs.Description,
 		Approval:    rawArgs.Approval,
+
+		ScheduleExecuteTime: rawArgs.ScheduleExecuteTime,
 	}
 	if args.Name == "" || args.Manager == "" {
 		return errors.New("Required parameters are missing")
@@ -105,6 +108,9 @@ func OpenAPICreateReleasePlan(c *handler.Context, rawArgs *OpenAPICreateReleaseP
 	if err := lintReleaseTimeRange(args.StartTime, args.EndTime); err != nil {
 		return errors.Wrap(err, "lint release time range error")
 	}
+	if err := lintReleaseScheduleExecuteTime(args.ScheduleExecuteTime, args.StartTime, args.EndTime); err != nil {
+		return errors.Wrap(err, "lint release schedule execute time error")
+	}
 	searchUserResp, err := user.New().SearchUser(&user.SearchUserArgs{
 		Account:      args.Manager,
 		IdentityType: rawArgs.ManagerIdentityType,
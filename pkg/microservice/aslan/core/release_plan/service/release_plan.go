@@ -48,6 +48,9 @@ func CreateReleasePlan(c *handler.Context, args *models.ReleasePlan) error {
 	if err := lintReleaseTimeRange(args.StartTime, args.EndTime); err != nil {
 		return errors.Wrap(err, "lint release time range error")
 	}
+	if err := lintReleaseScheduleExecuteTime(args.ScheduleExecuteTime, args.StartTime, args.EndTime); err != nil {
+		return errors.Wrap(err, "lint release schedule execute time error")
+	}
 	userInfo, err := user.New().GetUserByID(args.ManagerID)
 	if err != nil {
 		return errors.Errorf("Failed to get user by id %s, error: %v", args.ManagerID, err)
@@ -63,6 +66,12 @@ func CreateReleasePlan(c *handler.Context, args *models.ReleasePlan) error {
 		job.ReleaseJobRuntime = models.ReleaseJobRuntime{}
 		job.ID = uuid.New().String()
 	}
+	if err := resolveReleaseJobDependencies(args.Jobs); err != nil {
+		return errors.Wrap(err, "resolve release job dependencies error")
+	}
+	if err := lintReleaseJobDependencies(args.Jobs); err != nil {
+		return errors.Wrap(err, "lint release job dependencies error")
+	}
 
 	if args.Approval != nil {
 		if err := lintApproval(args.Approval); err != nil {
@@ -181,6 +190,12 @@ func UpdateReleasePlan(c *handler.Context, planID string, args *UpdateReleasePla
 	if err != nil {
 		return errors.Wrap(err, "update")
 	}
+	if err = resolveReleaseJobDependencies(plan.Jobs); err != nil {
+		return errors.Wrap(err, "resolve release job dependencies")
+	}
+	if err = lintReleaseJobDependencies(plan.Jobs); err != nil {
+		return errors.Wrap(err, "lint release job dependencies")
+	}
 
 	plan.UpdatedBy = c.UserName
 	plan.UpdateTime = time.Now().Unix()
@@ -230,22 +245,24 @@ func ExecuteReleaseJob(c *handler.Context, planID string, args *ExecuteReleaseJo
 		return errors.Errorf("plan status is %s, can not execute", plan.Status)
 	}
 
-	if !(plan.StartTime == 0 && plan.EndTime == 0) {
-		now := time.Now().Unix()
-		if now < plan.StartTime || now > plan.EndTime {
-			return errors.Errorf("plan is not in the release time range")
-		}
+	if !inReleaseTimeRange(plan, time.Now().Unix()) {
+		return errors.Errorf("plan is not in the release time range")
 	}
 
 	if plan.ManagerID != c.UserID {
 		return errors.Errorf("only manager can execute")
 	}
 
+	if err = checkReleaseJobDependencies(plan, args.ID); err != nil {
+		return err
+	}
+
 	executor, err := NewReleaseJobExecutor(&ExecuteReleaseJobContext{
 		AuthResources: c.Resources,
 		UserID:        c.UserID,
 		Account:       c.Account,
 		UserName:      c.UserName,
+		IdentityType:  c.IdentityType,
 	}, args)
 	if err != nil {
 		return errors.Wrap(err, "new release job executor")
@@ -261,6 +278,7 @@ func ExecuteReleaseJob(c *handler.Context, planID string, args *ExecuteReleaseJo
 		plan.ExecutingTime = time.Now().Unix()
 		plan.SuccessTime = time.Now().Unix()
 		plan.Status = config.StatusSuccess
+		plan.AutoExecution = nil
 	}
 
 	if err = mongodb.NewReleasePlanColl().UpdateByID(ctx, planID, plan); err != nil {
@@ -284,6 +302,106 @@ func ExecuteReleaseJob(c *handler.Context, planID string, args *ExecuteReleaseJo
 	return nil
 }
 
+// ExecuteAllReleaseJobs executes the workflow release jobs in the dependency order, the jobs are
+// started by the watcher once their upstream jobs are done and the execution stops when any job fails.
+// Text release jobs still need to be executed by the manager.
+func ExecuteAllReleaseJobs(c *handler.Context, planID string) error {
+	getLock(planID).Lock()
+	defer getLock(planID).Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	plan, err := mongodb.NewReleasePlanColl().GetByID(ctx, planID)
+	if err != nil {
+		return errors.Wrap(err, "get plan")
+	}
+
+	if plan.Status != config.StatusExecuting {
+		return errors.Errorf("plan status is %s, can not execute", plan.Status)
+	}
+
+	if !inReleaseTimeRange(plan, time.Now().Unix()) {
+		return errors.Errorf("plan is not in the release time range")
+	}
+
+	if plan.ManagerID != c.UserID {
+		return errors.Errorf("only manager can execute")
+	}
+
+	if plan.AutoExecution != nil {
+		return errors.Errorf("plan is executing all release jobs")
+	}
+
+	executeCtx := &ExecuteReleaseJobContext{
+		AuthResources: c.Resources,
+		UserID:        c.UserID,
+		Account:       c.Account,
+		UserName:      c.UserName,
+		IdentityType:  c.IdentityType,
+	}
+	for _, job := range plan.Jobs {
+		if job.Type != config.JobWorkflow || job.Status == config.ReleasePlanJobStatusDone {
+			continue
+		}
+		spec := new(models.WorkflowReleaseJobSpec)
+		if err := models.IToi(job.Spec, spec); err != nil {
+			return errors.Wrap(err, "invalid spec")
+		}
+		if spec.Workflow == nil {
+			return errors.Errorf("workflow of release job %s is nil", job.Name)
+		}
+		if err := checkWorkflowExecutePermission(executeCtx, spec.Workflow); err != nil {
+			return errors.Wrapf(err, "release job %s", job.Name)
+		}
+		// failed workflow release jobs are executed again
+		if job.Status == config.ReleasePlanJobStatusFailed {
+			job.Status = config.ReleasePlanJobStatusTodo
+			job.Error = ""
+		}
+	}
+
+	plan.AutoExecution = newReleasePlanAutoExecution(c)
+	planLogs, executeErr := executeReleaseJobsInOrder(plan)
+
+	plan.UpdatedBy = c.UserName
+	plan.UpdateTime = time.Now().Unix()
+
+	if err = mongodb.NewReleasePlanColl().UpdateByID(ctx, planID, plan); err != nil {
+		return errors.Wrap(err, "update plan")
+	}
+
+	go func() {
+		planLogs = append([]*models.ReleasePlanLog{{
+			PlanID:     planID,
+			Username:   c.UserName,
+			Account:    c.Account,
+			Verb:       VerbExecute,
+			TargetName: plan.Name,
+			TargetType: TargetTypeReleasePlan,
+			Detail:     "执行全部",
+			CreatedAt:  time.Now().Unix(),
+		}}, planLogs...)
+		for _, planLog := range planLogs {
+			if err := mongodb.NewReleasePlanLogColl().Create(planLog); err != nil {
+				log.Errorf("create release plan log error: %v", err)
+			}
+		}
+	}()
+
+	return executeErr
+}
+
+// newReleasePlanAutoExecution records the caller of execute all, the watcher starts the later release jobs on their behalf
+func newReleasePlanAutoExecution(c *handler.Context) *models.ReleasePlanAutoExecution {
+	return &models.ReleasePlanAutoExecution{
+		UserID:       c.UserID,
+		Account:      c.Account,
+		UserName:     c.UserName,
+		IdentityType: c.IdentityType,
+		StartTime:    time.Now().Unix(),
+	}
+}
+
 func UpdateReleasePlanStatus(c *handler.Context, planID, status string) error {
 	getLock(planID).Lock()
 	defer getLock(planID).Unlock()
@@ -318,11 +436,28 @@ func UpdateReleasePlanStatus(c *handler.Context, planID, status string) error {
 			job.Status = config.ReleasePlanJobStatusTodo
 			job.Updated = false
 		}
+		plan.AutoExecution = nil
 	case config.StatusExecuting:
-		if plan.Approval != nil && plan.Approval.Status != config.StatusPassed {
-			detail = "跳过审批"
+		switch plan.Status {
+		case config.StatusWaitForExecute, config.StatusPaused:
+			if !inReleaseTimeRange(plan, time.Now().Unix()) {
+				return errors.Errorf("plan is not in the release time range")
+			}
+			// jobs of the paused plan keep their status
+			if plan.Status == config.StatusWaitForExecute {
+				setReleaseJobsForExecuting(plan)
+			}
+		default:
+			if plan.Approval != nil && plan.Approval.Status != config.StatusPassed {
+				detail = "跳过审批"
+			}
+			// arm the plan if it is scheduled to execute later
+			if plan.ScheduleExecuteTime > time.Now().Unix() {
+				status = string(config.StatusWaitForExecute)
+			} else {
+				setReleaseJobsForExecuting(plan)
+			}
 		}
-		setReleaseJobsForExecuting(plan)
 	case config.StatusWaitForApprove:
 		if err := clearApprovalData(plan.Approval); err != nil {
 			return errors.Wrap(err, "clear approval data")
@@ -333,6 +468,7 @@ func UpdateReleasePlanStatus(c *handler.Context, planID, status string) error {
 	case config.StatusCancel:
 		// set executing status final time
 		plan.ExecutingTime = time.Now().Unix()
+		plan.AutoExecution = nil
 	}
 
 	// original status check and update
@@ -401,7 +537,7 @@ func ApproveReleasePlan(c *handler.Context, planID string, req *ApproveRequest)
 		approveWithL = &approvalservice.ApproveWithLock{Approval: plan.Approval.NativeApproval}
 		approvalservice.GlobalApproveMap.SetApproval(plan.Approval.NativeApproval.InstanceCode, approveWithL)
 	}
-	if err = approveWithL.DoApproval(c.UserName, c.UserID, req.Comment, req.Approve); err != nil {
+	if err = approveWithL.DoApproval(c.UserName, c.UserID, c.IdentityType, req.Comment, req.Approve); err != nil {
 		return errors.Wrap(err, "do approval")
 	}
 
@@ -423,12 +559,11 @@ func ApproveReleasePlan(c *handler.Context, planID string, req *ApproveRequest)
 			TargetName: TargetTypeReleasePlanStatus,
 			TargetType: TargetTypeReleasePlanStatus,
 			Detail:     "审批通过",
-			After:      config.StatusExecuting,
 			CreatedAt:  time.Now().Unix(),
 		}
-		plan.Status = config.StatusExecuting
 		plan.ApprovalTime = time.Now().Unix()
-		setReleaseJobsForExecuting(plan)
+		setReleasePlanForExecuting(plan)
+		planLog.After = plan.Status
 	case config.StatusReject:
 		planLog = &models.ReleasePlanLog{
 			PlanID:    planID,
@@ -504,6 +639,26 @@ func checkReleasePlanJobsAllDone(plan *models.ReleasePlan) bool {
 	return true
 }
 
+// setReleasePlanForExecuting starts executing the plan, or arms it to wait for
+// the schedule execute time if the time has not come yet
+func setReleasePlanForExecuting(plan *models.ReleasePlan) {
+	if plan.ScheduleExecuteTime > time.Now().Unix() {
+		plan.Status = config.StatusWaitForExecute
+		return
+	}
+	plan.Status = config.StatusExecuting
+	setReleaseJobsForExecuting(plan)
+}
+
+// inReleaseTimeRange checks whether the time is in the release time range,
+// plan without time range can be executed at any time
+func inReleaseTimeRange(plan *models.ReleasePlan, t int64) bool {
+	if plan.StartTime == 0 && plan.EndTime == 0 {
+		return true
+	}
+	return t >= plan.StartTime && t <= plan.EndTime
+}
+
 func setReleaseJobsForExecuting(plan *models.ReleasePlan) {
 	for _, job := range plan.Jobs {
 		if job.LastStatus == config.ReleasePlanJobStatusDone && !job.Updated {
@@ -513,5 +668,7 @@ func setReleaseJobsForExecuting(plan *models.ReleasePlan) {
 		job.Status = config.ReleasePlanJobStatusTodo
 		job.ExecutedBy = ""
 		job.ExecutedTime = 0
+		job.FinishedTime = 0
+		job.Error = ""
 	}
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
@@ -39,6 +40,8 @@ const (
 	VerbUpdateTimeRange = "update_time_range"
 	VerbUpdateManager   = "update_manager"
 
+	VerbUpdateScheduleExecuteTime = "update_schedule_execute_time"
+
 	VerbCreateReleaseJob = "create_release_job"
 	VerbUpdateReleaseJob = "update_release_job"
 	VerbDeleteReleaseJob = "delete_release_job"
@@ -78,6 +81,8 @@ func NewPlanUpdater(args *UpdateReleasePlanArgs) (PlanUpdater, error) {
 		return NewTimeRangeUpdater(args)
 	case VerbUpdateManager:
 		return NewManagerUpdater(args)
+	case VerbUpdateScheduleExecuteTime:
+		return NewScheduleExecuteTimeUpdater(args)
 	case VerbCreateReleaseJob:
 		return NewCreateReleaseJobUpdater(args)
 	case VerbUpdateReleaseJob:
@@ -178,6 +183,9 @@ func NewTimeRangeUpdater(args *UpdateReleasePlanArgs) (*TimeRangeUpdater, error)
 }
 
 func (u *TimeRangeUpdater) Update(plan *models.ReleasePlan) (before interface{}, after interface{}, err error) {
+	if err = lintReleaseScheduleExecuteTime(plan.ScheduleExecuteTime, u.StartTime, u.EndTime); err != nil {
+		return
+	}
 	format := "2006-01-02 15:04:05"
 	before = fmt.Sprintf("%s-%s", time.Unix(plan.StartTime, 0).Format(format),
 		time.Unix(plan.EndTime, 0).Format(format))
@@ -204,6 +212,53 @@ func (u *TimeRangeUpdater) Verb() string {
 	return VerbUpdate
 }
 
+type ScheduleExecuteTimeUpdater struct {
+	ScheduleExecuteTime int64 `json:"schedule_execute_time"`
+}
+
+func NewScheduleExecuteTimeUpdater(args *UpdateReleasePlanArgs) (*ScheduleExecuteTimeUpdater, error) {
+	var updater ScheduleExecuteTimeUpdater
+	if err := models.IToi(args.Spec, &updater); err != nil {
+		return nil, errors.Wrap(err, "invalid spec")
+	}
+	return &updater, nil
+}
+
+func (u *ScheduleExecuteTimeUpdater) Update(plan *models.ReleasePlan) (before interface{}, after interface{}, err error) {
+	if err = lintReleaseScheduleExecuteTime(u.ScheduleExecuteTime, plan.StartTime, plan.EndTime); err != nil {
+		return
+	}
+	format := "2006-01-02 15:04:05"
+	before, after = "", ""
+	if plan.ScheduleExecuteTime != 0 {
+		before = time.Unix(plan.ScheduleExecuteTime, 0).Format(format)
+	}
+	if u.ScheduleExecuteTime != 0 {
+		after = time.Unix(u.ScheduleExecuteTime, 0).Format(format)
+	}
+	plan.ScheduleExecuteTime = u.ScheduleExecuteTime
+	return
+}
+
+func (u *ScheduleExecuteTimeUpdater) Lint() error {
+	if u.ScheduleExecuteTime < 0 {
+		return fmt.Errorf("invalid schedule execute time")
+	}
+	return nil
+}
+
+func (u *ScheduleExecuteTimeUpdater) TargetName() string {
+	return "定时执行时间"
+}
+
+func (u *ScheduleExecuteTimeUpdater) TargetType() string {
+	return TargetTypeMetadata
+}
+
+func (u *ScheduleExecuteTimeUpdater) Verb() string {
+	return VerbUpdate
+}
+
 type ManagerUpdater struct {
 	ManagerID string `json:"manager_id"`
 	Manager   string `json:"manager"`
@@ -251,9 +306,10 @@ func (u *ManagerUpdater) Verb() string {
 }
 
 type CreateReleaseJobUpdater struct {
-	Name string                    `json:"name"`
-	Type config.ReleasePlanJobType `json:"type"`
-	Spec interface{}               `json:"spec"`
+	Name      string                    `json:"name"`
+	Type      config.ReleasePlanJobType `json:"type"`
+	Spec      interface{}               `json:"spec"`
+	DependsOn []string                  `json:"depends_on"`
 }
 
 func NewCreateReleaseJobUpdater(args *UpdateReleasePlanArgs) (*CreateReleaseJobUpdater, error) {
@@ -267,10 +323,11 @@ func NewCreateReleaseJobUpdater(args *UpdateReleasePlanArgs) (*CreateReleaseJobU
 func (u *CreateReleaseJobUpdater) Update(plan *models.ReleasePlan) (before interface{}, after interface{}, err error) {
 	before, after = nil, u
 	job := &models.ReleaseJob{
-		ID:   uuid.New().String(),
-		Name: u.Name,
-		Type: u.Type,
-		Spec: u.Spec,
+		ID:        uuid.New().String(),
+		Name:      u.Name,
+		Type:      u.Type,
+		Spec:      u.Spec,
+		DependsOn: u.DependsOn,
 	}
 	plan.Jobs = append(plan.Jobs, job)
 	return
@@ -297,10 +354,11 @@ func (u *CreateReleaseJobUpdater) Verb() string {
 }
 
 type UpdateReleaseJobUpdater struct {
-	ID   string                    `json:"id"`
-	Name string                    `json:"name"`
-	Type config.ReleasePlanJobType `json:"type"`
-	Spec interface{}               `json:"spec"`
+	ID        string                    `json:"id"`
+	Name      string                    `json:"name"`
+	Type      config.ReleasePlanJobType `json:"type"`
+	Spec      interface{}               `json:"spec"`
+	DependsOn []string                  `json:"depends_on"`
 }
 
 func NewUpdateReleaseJobUpdater(args *UpdateReleasePlanArgs) (*UpdateReleaseJobUpdater, error) {
@@ -320,6 +378,7 @@ func (u *UpdateReleaseJobUpdater) Update(plan *models.ReleasePlan) (before inter
 			before, after = job, u
 			job.Name = u.Name
 			job.Spec = u.Spec
+			job.DependsOn = u.DependsOn
 			job.Updated = true
 			return
 		}
@@ -367,6 +426,10 @@ func (u *DeleteReleaseJobUpdater) Update(plan *models.ReleasePlan) (before inter
 		if job.ID == u.ID {
 			u.name = job.Name
 			plan.Jobs = append(plan.Jobs[:i], plan.Jobs[i+1:]...)
+			// the deleted job no longer blocks the jobs depending on it
+			for _, other := range plan.Jobs {
+				other.DependsOn = lo.Without(other.DependsOn, u.ID)
+			}
 			return
 		}
 	}
@@ -18,6 +18,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,6 +28,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/notify"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
@@ -35,15 +37,18 @@ func WatchExecutingWorkflow() {
 	for {
 		time.Sleep(time.Second * 3)
 		t := time.Now()
-		list, _, err := mongodb.NewReleasePlanColl().ListByOptions(&mongodb.ListReleasePlanOption{
-			Status: config.StatusExecuting,
-		})
-		if err != nil {
-			log.Errorf("list executing workflow error: %v", err)
-			continue
-		}
-		for _, plan := range list {
-			updatePlanWorkflowReleaseJob(plan, log)
+		// workflow tasks started before the plan is paused are still running
+		for _, status := range []config.ReleasePlanStatus{config.StatusExecuting, config.StatusPaused} {
+			list, _, err := mongodb.NewReleasePlanColl().ListByOptions(&mongodb.ListReleasePlanOption{
+				Status: status,
+			})
+			if err != nil {
+				log.Errorf("list %s workflow error: %v", status, err)
+				continue
+			}
+			for _, plan := range list {
+				updatePlanWorkflowReleaseJob(plan, log)
+			}
 		}
 		if time.Since(t) > time.Millisecond*200 {
 			log.Warnf("watch executing workflow cost %s", time.Since(t))
@@ -63,7 +68,7 @@ func updatePlanWorkflowReleaseJob(plan *models.ReleasePlan, log *zap.SugaredLogg
 		return
 	}
 	// plan status maybe changed during no lock time
-	if plan.Status != config.StatusExecuting {
+	if plan.Status != config.StatusExecuting && plan.Status != config.StatusPaused {
 		return
 	}
 	for _, job := range plan.Jobs {
@@ -81,20 +86,50 @@ func updatePlanWorkflowReleaseJob(plan *models.ReleasePlan, log *zap.SugaredLogg
 			spec.Status = task.Status
 			if lo.Contains(config.FailedStatus(), task.Status) {
 				job.Status = config.ReleasePlanJobStatusFailed
+				job.Error = task.Error
+				job.FinishedTime = time.Now().Unix()
 			}
 			if task.Status == config.StatusPassed {
 				job.Status = config.ReleasePlanJobStatusDone
+				job.FinishedTime = time.Now().Unix()
 			}
 			if checkReleasePlanJobsAllDone(plan) {
 				plan.ExecutingTime = time.Now().Unix()
 				plan.SuccessTime = time.Now().Unix()
 				plan.Status = config.StatusSuccess
+				plan.AutoExecution = nil
 			}
 		}
 	}
+
+	var planLogs []*models.ReleasePlanLog
+	if plan.Status == config.StatusExecuting && plan.AutoExecution != nil {
+		planLogs, err = executeReleaseJobsInOrder(plan)
+		if err != nil {
+			planLogs = append(planLogs, &models.ReleasePlanLog{
+				PlanID:     plan.ID.Hex(),
+				Username:   "系统",
+				Verb:       VerbExecute,
+				TargetName: plan.Name,
+				TargetType: TargetTypeReleasePlan,
+				Detail:     fmt.Sprintf("执行全部已停止: %v", err),
+				CreatedAt:  time.Now().Unix(),
+			})
+		}
+	}
+
 	if err := mongodb.NewReleasePlanColl().UpdateByID(ctx, plan.ID.Hex(), plan); err != nil {
 		log.Errorf("update plan %s error: %v", plan.ID.Hex(), err)
+		return
 	}
+
+	go func() {
+		for _, planLog := range planLogs {
+			if err := mongodb.NewReleasePlanLogColl().Create(planLog); err != nil {
+				log.Errorf("create release plan log error: %v", err)
+			}
+		}
+	}()
 	return
 }
 
@@ -165,12 +200,11 @@ func updatePlanApproval(plan *models.ReleasePlan) error {
 			TargetName: TargetTypeReleasePlanStatus,
 			TargetType: TargetTypeReleasePlanStatus,
 			Detail:     "审批通过",
-			After:      config.StatusExecuting,
 			CreatedAt:  time.Now().Unix(),
 		}
-		plan.Status = config.StatusExecuting
 		plan.ApprovalTime = time.Now().Unix()
-		setReleaseJobsForExecuting(plan)
+		setReleasePlanForExecuting(plan)
+		planLog.After = plan.Status
 	case config.StatusReject:
 		planLog = &models.ReleasePlanLog{
 			PlanID:    plan.ID.Hex(),
@@ -194,3 +228,104 @@ func updatePlanApproval(plan *models.ReleasePlan) error {
 
 	return nil
 }
+
+// WatchScheduledReleasePlan starts the armed plans at the schedule execute time
+// and pauses the executing plans when the release time range is over
+func WatchScheduledReleasePlan() {
+	log := log.SugaredLogger().With("service", "WatchScheduledReleasePlan")
+	for {
+		time.Sleep(time.Second * 3)
+		t := time.Now()
+		for _, status := range []config.ReleasePlanStatus{config.StatusWaitForExecute, config.StatusExecuting} {
+			list, _, err := mongodb.NewReleasePlanColl().ListByOptions(&mongodb.ListReleasePlanOption{
+				Status: status,
+			})
+			if err != nil {
+				log.Errorf("list %s release plan error: %v", status, err)
+				continue
+			}
+			for _, plan := range list {
+				if err := updatePlanSchedule(plan, log); err != nil {
+					log.Errorf("update plan %s schedule error: %v", plan.Name, err)
+				}
+			}
+		}
+		if time.Since(t) > time.Millisecond*200 {
+			log.Warnf("watch scheduled release plan cost %s", time.Since(t))
+		}
+	}
+}
+
+func updatePlanSchedule(plan *models.ReleasePlan, log *zap.SugaredLogger) error {
+	getLock(plan.ID.Hex()).Lock()
+	defer getLock(plan.ID.Hex()).Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	plan, err := mongodb.NewReleasePlanColl().GetByID(ctx, plan.ID.Hex())
+	if err != nil {
+		return errors.Errorf("get plan %s error: %v", plan.ID.Hex(), err)
+	}
+
+	// plan status maybe changed during no lock time
+	before := plan.Status
+	status, detail, changed := scheduledPlanStatus(plan, time.Now().Unix())
+	if !changed {
+		return nil
+	}
+	plan.Status = status
+	if before == config.StatusWaitForExecute && status == config.StatusExecuting {
+		setReleaseJobsForExecuting(plan)
+	}
+
+	if err := mongodb.NewReleasePlanColl().UpdateByID(ctx, plan.ID.Hex(), plan); err != nil {
+		return errors.Errorf("update plan %s error: %v", plan.ID.Hex(), err)
+	}
+
+	go func() {
+		if err := mongodb.NewReleasePlanLogColl().Create(&models.ReleasePlanLog{
+			PlanID:     plan.ID.Hex(),
+			Username:   "系统",
+			Verb:       VerbUpdate,
+			TargetName: TargetTypeReleasePlanStatus,
+			TargetType: TargetTypeReleasePlanStatus,
+			Detail:     detail,
+			Before:     before,
+			After:      plan.Status,
+			CreatedAt:  time.Now().Unix(),
+		}); err != nil {
+			log.Errorf("create release plan log error: %v", err)
+		}
+		notify.SendMessage(plan.Manager, "发布计划状态变更", fmt.Sprintf("发布计划：%s，%s", plan.Name, detail), "", log)
+	}()
+
+	return nil
+}
+
+// scheduledPlanStatus decides the status of an armed or executing plan at the time, the detail describes the change.
+// An armed plan starts at the schedule execute time if it is in the release time range, and a plan is paused once the
+// release time range is over. changed is false if the plan should keep its status.
+func scheduledPlanStatus(plan *models.ReleasePlan, now int64) (status config.ReleasePlanStatus, detail string, changed bool) {
+	timeRangeOver := !(plan.StartTime == 0 && plan.EndTime == 0) && now > plan.EndTime
+	switch plan.Status {
+	case config.StatusWaitForExecute:
+		if now < plan.ScheduleExecuteTime {
+			return plan.Status, "", false
+		}
+		if timeRangeOver {
+			return config.StatusPaused, "发布窗口期已结束，发布计划暂停", true
+		}
+		// wait for the release time range to start
+		if !inReleaseTimeRange(plan, now) {
+			return plan.Status, "", false
+		}
+		return config.StatusExecuting, "到达定时执行时间，开始执行", true
+	case config.StatusExecuting:
+		if !timeRangeOver || checkReleasePlanJobsAllDone(plan) {
+			return plan.Status, "", false
+		}
+		return config.StatusPaused, "发布窗口期已结束，发布计划暂停", true
+	default:
+		return plan.Status, "", false
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func TestInReleaseTimeRange(t *testing.T) {
+	shanghai := time.FixedZone("Asia/Shanghai", 8*60*60)
+	// the release window is 22:00 - 02:00 the next day in Asia/Shanghai, which spans two days in UTC as well
+	start := time.Date(2024, 3, 1, 22, 0, 0, 0, shanghai).Unix()
+	end := time.Date(2024, 3, 2, 2, 0, 0, 0, shanghai).Unix()
+	plan := &models.ReleasePlan{StartTime: start, EndTime: end}
+
+	tests := []struct {
+		name string
+		plan *models.ReleasePlan
+		now  int64
+		want bool
+	}{
+		{name: "no time range", plan: &models.ReleasePlan{}, now: start, want: true},
+		{name: "before start", plan: plan, now: start - 1, want: false},
+		{name: "at start", plan: plan, now: start, want: true},
+		{name: "across midnight", plan: plan, now: time.Date(2024, 3, 2, 0, 30, 0, 0, shanghai).Unix(), want: true},
+		{name: "same instant in another timezone", plan: plan, now: time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC).Unix(), want: true},
+		{name: "at end", plan: plan, now: end, want: true},
+		{name: "after end", plan: plan, now: end + 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, inReleaseTimeRange(tt.plan, tt.now))
+		})
+	}
+}
+
+func TestLintReleaseScheduleExecuteTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		scheduleTime int64
+		start        int64
+		end          int64
+		wantErr      bool
+	}{
+		{name: "no schedule", start: 100, end: 200},
+		{name: "no time range", scheduleTime: 50},
+		{name: "at start", scheduleTime: 100, start: 100, end: 200},
+		{name: "at end", scheduleTime: 200, start: 100, end: 200},
+		{name: "before start", scheduleTime: 99, start: 100, end: 200, wantErr: true},
+		{name: "after end", scheduleTime: 201, start: 100, end: 200, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lintReleaseScheduleExecuteTime(tt.scheduleTime, tt.start, tt.end)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestScheduledPlanStatus(t *testing.T) {
+	todo := []*models.ReleaseJob{{ReleaseJobRuntime: models.ReleaseJobRuntime{Status: config.ReleasePlanJobStatusTodo}}}
+	done := []*models.ReleaseJob{{ReleaseJobRuntime: models.ReleaseJobRuntime{Status: config.ReleasePlanJobStatusDone}}}
+
+	tests := []struct {
+		name        string
+		plan        *models.ReleasePlan
+		now         int64
+		wantStatus  config.ReleasePlanStatus
+		wantChanged bool
+	}{
+		{
+			name:       "armed plan waits for the schedule time",
+			plan:       &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 150, StartTime: 100, EndTime: 200},
+			now:        149,
+			wantStatus: config.StatusWaitForExecute,
+		},
+		{
+			name:        "armed plan starts at the schedule time",
+			plan:        &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 150, StartTime: 100, EndTime: 200},
+			now:         150,
+			wantStatus:  config.StatusExecuting,
+			wantChanged: true,
+		},
+		{
+			name:        "armed plan without time range starts at the schedule time",
+			plan:        &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 150},
+			now:         1000,
+			wantStatus:  config.StatusExecuting,
+			wantChanged: true,
+		},
+		{
+			name:       "armed plan waits for the time range to start",
+			plan:       &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 50, StartTime: 100, EndTime: 200},
+			now:        99,
+			wantStatus: config.StatusWaitForExecute,
+		},
+		{
+			name:        "armed plan starts at the end of the time range",
+			plan:        &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 150, StartTime: 100, EndTime: 200},
+			now:         200,
+			wantStatus:  config.StatusExecuting,
+			wantChanged: true,
+		},
+		{
+			name:        "armed plan is paused if the time range is over",
+			plan:        &models.ReleasePlan{Status: config.StatusWaitForExecute, ScheduleExecuteTime: 150, StartTime: 100, EndTime: 200},
+			now:         201,
+			wantStatus:  config.StatusPaused,
+			wantChanged: true,
+		},
+		{
+			name:       "executing plan keeps executing in the time range",
+			plan:       &models.ReleasePlan{Status: config.StatusExecuting, StartTime: 100, EndTime: 200, Jobs: todo},
+			now:        200,
+			wantStatus: config.StatusExecuting,
+		},
+		{
+			name:        "executing plan is paused when the time range is over",
+			plan:        &models.ReleasePlan{Status: config.StatusExecuting, StartTime: 100, EndTime: 200, Jobs: todo},
+			now:         201,
+			wantStatus:  config.StatusPaused,
+			wantChanged: true,
+		},
+		{
+			name:       "finished plan is not paused",
+			plan:       &models.ReleasePlan{Status: config.StatusExecuting, StartTime: 100, EndTime: 200, Jobs: done},
+			now:        201,
+			wantStatus: config.StatusExecuting,
+		},
+		{
+			name:       "executing plan without time range is never paused",
+			plan:       &models.ReleasePlan{Status: config.StatusExecuting, Jobs: todo},
+			now:        time.Now().Unix(),
+			wantStatus: config.StatusExecuting,
+		},
+		{
+			name:       "paused plan is left to the manager",
+			plan:       &models.ReleasePlan{Status: config.StatusPaused, StartTime: 100, EndTime: 200},
+			now:        150,
+			wantStatus: config.StatusPaused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, changed := scheduledPlanStatus(tt.plan, tt.now)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantChanged, changed)
+		})
+	}
+}
@@ -64,6 +64,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/tool/klock"
 	"github.com/koderover/zadig/v2/pkg/tool/kube/multicluster"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/tool/metrics"
 	mongotool "github.com/koderover/zadig/v2/pkg/tool/mongo"
 	"github.com/koderover/zadig/v2/pkg/tool/rsa"
 )
@@ -160,6 +161,8 @@ func Start(ctx context.Context) {
 
 	go multiclusterservice.ClusterApplyUpgrade()
 
+	go metrics.StartClusterMetricsCollector(ctx, time.Minute, workflowcontroller.NormalClusterIDs)
+
 	initRsaKey()
 
 	initCron()
@@ -194,6 +197,14 @@ func initCron() {
 		log.Infof("[CRONJOB] gitlab token updated....")
 	})
 
+	Scheduler.Every(5).Minutes().Do(func() {
+		environmentservice.DetectEnvDrifts()
+	})
+
+	Scheduler.Every(5).Minutes().Do(func() {
+		environmentservice.CleanExpiredPreviewEnvs()
+	})
+
 	Scheduler.StartAsync()
 }
 
@@ -328,6 +339,7 @@ func initKlock() {
 func initReleasePlanWatcher() {
 	go releaseplanservice.WatchExecutingWorkflow()
 	go releaseplanservice.WatchApproval()
+	go releaseplanservice.WatchScheduledReleasePlan()
 }
 
 func initDatabase() {
@@ -431,11 +443,19 @@ func initDatabase() {
 		commonrepo.NewVariableSetColl(),
 		commonrepo.NewJobInfoColl(),
 		commonrepo.NewStatDashboardConfigColl(),
+		commonrepo.NewIncidentColl(),
+		commonrepo.NewEnvDriftColl(),
+		commonrepo.NewEnvSnapshotColl(),
+		commonrepo.NewEnvSnapshotResourceColl(),
+		commonrepo.NewWorkflowCodeSourceColl(),
+		commonrepo.NewWorkflowPolicyColl(),
+		commonrepo.NewWorkflowPolicyDecisionColl(),
 		commonrepo.NewProjectManagementColl(),
 		commonrepo.NewImageTagsCollColl(),
 		commonrepo.NewLLMIntegrationColl(),
 		commonrepo.NewReleasePlanColl(),
 		commonrepo.NewReleasePlanLogColl(),
+		commonrepo.NewNotificationTemplateColl(),
 		commonrepo.NewEnvServiceVersionColl(),
 
 		// msg queue
@@ -0,0 +1,242 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/stat/service"
+	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+type getDORAStatReq struct {
+	StartTime int64    `form:"start_time"`
+	EndTime   int64    `form:"end_time"`
+	Projects  []string `form:"projects"`
+}
+
+func (req *getDORAStatReq) Validate() error {
+	if req.StartTime == 0 && req.EndTime == 0 {
+		now := time.Now()
+		req.StartTime = now.AddDate(0, -1, 0).Unix()
+		req.EndTime = now.Unix()
+	}
+
+	if req.EndTime < req.StartTime {
+		return e.ErrInvalidParam.AddDesc("invalid time range")
+	}
+
+	if req.EndTime-req.StartTime > 60*60*24*365 {
+		return e.ErrInvalidParam.AddDesc("time range should be less than 365 days")
+	}
+	return nil
+}
+
+func ListIncidents(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getDORAStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListIncidents(args.StartTime, args.EndTime, args.Projects, ctx.Logger)
+}
+
+func GetIncidentOverview(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getDORAStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetIncidentOverview(args.StartTime, args.EndTime, ctx.Logger)
+}
+
+func CreateIncident(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(service.CreateIncidentArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	// authorization checks
+	if !canEditIncident(ctx, args.ProjectName, args.Production) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "新增", "故障记录", args.Title, "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = service.CreateIncident(ctx.UserName, args, ctx.Logger)
+}
+
+type resolveIncidentReq struct {
+	ResolvedAt int64 `json:"resolved_at"`
+}
+
+func ResolveIncident(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(resolveIncidentReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	incident, err := service.GetIncident(c.Param("id"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	// authorization checks
+	if !canEditIncident(ctx, incident.ProjectName, incident.Production) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, incident.ProjectName, "解决", "故障记录", incident.Title, "", ctx.Logger)
+
+	ctx.Err = service.ResolveIncident(c.Param("id"), args.ResolvedAt, ctx.Logger)
+}
+
+// canEditIncident checks the user can edit the environments of the incident, an incident is a failure of the environments
+func canEditIncident(ctx *internalhandler.Context, projectName string, production bool) bool {
+	if ctx.Resources.IsSystemAdmin {
+		return true
+	}
+	authInfo, ok := ctx.Resources.ProjectAuthInfo[projectName]
+	if !ok {
+		return false
+	}
+	if authInfo.IsProjectAdmin {
+		return true
+	}
+	if production {
+		return authInfo.ProductionEnv.EditConfig
+	}
+	return authInfo.Env.EditConfig
+}
+
+func GetDORAStats(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getDORAStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	if err := commonutil.CheckZadigXLicenseStatus(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetDORAStats(args.StartTime, args.EndTime, args.Projects, ctx.Logger)
+}
+
+func ExportDORAStats(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getDORAStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	if err := commonutil.CheckZadigXLicenseStatus(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	fileBytes, err := service.ExportDORAStats(args.StartTime, args.EndTime, args.Projects, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	fileName := fmt.Sprintf("dora-%s-%s.csv", time.Unix(args.StartTime, 0).Format("20060102"), time.Unix(args.EndTime, 0).Format("20060102"))
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, "text/csv", fileBytes)
+}
+
+func GetDORAStatsOpenAPI(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getStatReqV2)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	var projects []string
+	if args.ProjectName != "" {
+		projects = []string{args.ProjectName}
+	}
+	ctx.Resp, ctx.Err = service.GetDORAStats(args.StartTime, args.EndTime, projects, ctx.Logger)
+}
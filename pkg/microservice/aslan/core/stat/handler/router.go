@@ -74,6 +74,13 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		v2.GET("/ai/radar", GetEfficiencyRadar)
 		v2.GET("/ai/attention", GetMonthAttention)
 		v2.GET("/ai/requirement/period", GetRequirementDevDepPeriod)
+		// DORA change failure rate and mean time to restore
+		v2.GET("/incident", ListIncidents)
+		v2.GET("/incident/overview", GetIncidentOverview)
+		v2.POST("/incident", CreateIncident)
+		v2.PUT("/incident/:id/resolve", ResolveIncident)
+		v2.GET("/dora", GetDORAStats)
+		v2.GET("/dora/export", ExportDORAStats)
 	}
 }
 
@@ -92,5 +99,8 @@ func (*OpenAPIRouter) Inject(router *gin.RouterGroup) {
 	v2 := router.Group("/v2")
 	{
 		v2.GET("/release", GetReleaseStatOpenAPI)
+		v2.GET("/dora", GetDORAStatsOpenAPI)
+		v2.POST("/incident", CreateIncident)
+		v2.PUT("/incident/:id/resolve", ResolveIncident)
 	}
 }
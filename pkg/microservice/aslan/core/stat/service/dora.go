@@ -0,0 +1,311 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+	"github.com/koderover/zadig/v2/pkg/util"
+)
+
+type CreateIncidentArgs struct {
+	ProjectName string `json:"project_name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	EnvName     string `json:"env_name"`
+	ServiceName string `json:"service_name"`
+	Production  bool   `json:"production"`
+	// OccurredAt is the time the failure started, current time is used if it is not set
+	OccurredAt int64 `json:"occurred_at"`
+	// ResolvedAt is set if the incident is already resolved when it is reported
+	ResolvedAt int64 `json:"resolved_at"`
+}
+
+func (args *CreateIncidentArgs) Validate() error {
+	if args.ProjectName == "" {
+		return fmt.Errorf("project_name is required")
+	}
+	if args.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if args.ResolvedAt != 0 && args.OccurredAt != 0 && args.ResolvedAt < args.OccurredAt {
+		return fmt.Errorf("resolved_at should not be earlier than occurred_at")
+	}
+	return nil
+}
+
+func CreateIncident(username string, args *CreateIncidentArgs, logger *zap.SugaredLogger) (*commonmodels.Incident, error) {
+	if err := args.Validate(); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	now := time.Now().Unix()
+	incident := &commonmodels.Incident{
+		ProjectName: args.ProjectName,
+		Title:       args.Title,
+		Description: args.Description,
+		Source:      config.IncidentSourceAPI,
+		EnvName:     args.EnvName,
+		ServiceName: args.ServiceName,
+		Production:  args.Production,
+		Status:      config.IncidentStatusOpen,
+		OccurredAt:  args.OccurredAt,
+		ResolvedAt:  args.ResolvedAt,
+		CreatedBy:   username,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	if incident.OccurredAt == 0 {
+		incident.OccurredAt = now
+	}
+	if incident.ResolvedAt != 0 {
+		incident.Status = config.IncidentStatusResolved
+	}
+
+	if err := commonrepo.NewIncidentColl().Create(context.TODO(), incident); err != nil {
+		logger.Errorf("failed to create incident for project: %s, error: %s", args.ProjectName, err)
+		return nil, e.ErrCreateIncident.AddErr(err)
+	}
+	return incident, nil
+}
+
+func ListIncidents(startTime, endTime int64, projectList []string, logger *zap.SugaredLogger) ([]*commonmodels.Incident, error) {
+	incidents, err := commonrepo.NewIncidentColl().List(&commonrepo.IncidentListOption{
+		ProjectNames: projectList,
+		StartTime:    startTime,
+		EndTime:      endTime,
+	})
+	if err != nil {
+		logger.Errorf("failed to list incidents, error: %s", err)
+		return nil, e.ErrListIncident.AddErr(err)
+	}
+	return incidents, nil
+}
+
+// ResolveIncident marks the incident as resolved, current time is used if resolvedAt is not set
+func GetIncident(id string, logger *zap.SugaredLogger) (*commonmodels.Incident, error) {
+	incident, err := commonrepo.NewIncidentColl().GetByID(context.TODO(), id)
+	if err != nil {
+		logger.Errorf("failed to find incident: %s, error: %s", id, err)
+		return nil, e.ErrGetIncident.AddErr(err)
+	}
+	return incident, nil
+}
+
+// GetIncidentOverview returns the daily count of the incidents occurred in the time range
+func GetIncidentOverview(start, end int64, logger *zap.SugaredLogger) (*DailyJobInfo, error) {
+	incidents, err := commonrepo.NewIncidentColl().List(&commonrepo.IncidentListOption{
+		StartTime: start,
+		EndTime:   end,
+	})
+	if err != nil {
+		logger.Errorf("failed to list incidents, error: %s", err)
+		return nil, e.ErrListIncident.AddErr(err)
+	}
+	incidentDayMap := make(map[int64]int)
+	for _, incident := range incidents {
+		incidentDayMap[util.GetMidnightTimestamp(incident.OccurredAt)]++
+	}
+	incidentData := &project30DayOverview{
+		name: "故障",
+		data: make([]*currently30DayOverview, 0),
+	}
+	for day, count := range incidentDayMap {
+		incidentData.data = append(incidentData.data, &currently30DayOverview{
+			day:   day,
+			count: count,
+		})
+	}
+	return reBuildData(start, end, incidentData), nil
+}
+
+func ResolveIncident(id string, resolvedAt int64, logger *zap.SugaredLogger) error {
+	incident, err := commonrepo.NewIncidentColl().GetByID(context.TODO(), id)
+	if err != nil {
+		logger.Errorf("failed to find incident: %s, error: %s", id, err)
+		return e.ErrResolveIncident.AddErr(err)
+	}
+	if incident.Status == config.IncidentStatusResolved {
+		return e.ErrResolveIncident.AddDesc("incident is already resolved")
+	}
+
+	now := time.Now().Unix()
+	if resolvedAt == 0 {
+		resolvedAt = now
+	}
+	if resolvedAt < incident.OccurredAt {
+		return e.ErrResolveIncident.AddDesc("resolved time should not be earlier than the occurred time")
+	}
+
+	if err := commonrepo.NewIncidentColl().Resolve(context.TODO(), id, resolvedAt, now); err != nil {
+		logger.Errorf("failed to resolve incident: %s, error: %s", id, err)
+		return e.ErrResolveIncident.AddErr(err)
+	}
+	return nil
+}
+
+type DORAStatByProject struct {
+	ProjectKey        string           `json:"project_key"`
+	ProjectName       string           `json:"project_name"`
+	DeployCount       int              `json:"deploy_count"`
+	IncidentCount     int              `json:"incident_count"`
+	ChangeFailureRate float64          `json:"change_failure_rate"`
+	MeanTimeToRestore float64          `json:"mean_time_to_restore"`
+	DailyStat         []*DORADailyStat `json:"daily_stat"`
+}
+
+type DORADailyStat struct {
+	Date              string  `json:"date"`
+	DeployCount       int     `json:"deploy_count"`
+	IncidentCount     int     `json:"incident_count"`
+	ChangeFailureRate float64 `json:"change_failure_rate"`
+	MeanTimeToRestore float64 `json:"mean_time_to_restore"`
+}
+
+// GetDORAStats returns the change failure rate and the mean time to restore of the projects, along with the daily trend
+func GetDORAStats(startTime, endTime int64, projectList []string, logger *zap.SugaredLogger) ([]*DORAStatByProject, error) {
+	var projects []*templaterepo.ProjectInfo
+	var err error
+	if len(projectList) != 0 {
+		projects, err = templaterepo.NewProductColl().ListProjectBriefs(projectList)
+	} else {
+		projects, err = templaterepo.NewProductColl().ListNonPMProject()
+	}
+	if err != nil {
+		logger.Errorf("failed to list projects to get DORA stats, error: %s", err)
+		return nil, e.ErrGetDORAStatistics.AddErr(err)
+	}
+
+	resp := make([]*DORAStatByProject, 0)
+	for _, project := range projects {
+		deployJobs, err := commonrepo.NewJobInfoColl().GetDeployJobs(startTime, endTime, project.Name)
+		if err != nil {
+			logger.Errorf("failed to get deploy jobs for project: %s, error: %s", project.Name, err)
+			return nil, e.ErrGetDORAStatistics.AddErr(err)
+		}
+		incidents, err := commonrepo.NewIncidentColl().List(&commonrepo.IncidentListOption{
+			ProjectNames: []string{project.Name},
+			StartTime:    startTime,
+			EndTime:      endTime,
+		})
+		if err != nil {
+			logger.Errorf("failed to get incidents for project: %s, error: %s", project.Name, err)
+			return nil, e.ErrGetDORAStatistics.AddErr(err)
+		}
+
+		stat := &DORAStatByProject{
+			ProjectKey:    project.Name,
+			ProjectName:   project.Alias,
+			DeployCount:   countPassedJobs(deployJobs),
+			IncidentCount: len(incidents),
+			DailyStat:     make([]*DORADailyStat, 0),
+		}
+		rate, _ := calculateChangeFailureRate(deployJobs, incidents)
+		stat.ChangeFailureRate = math.Round(rate*100) / 100
+		mttr, _ := calculateMeanTimeToRestore(incidents)
+		stat.MeanTimeToRestore = math.Round(mttr*100) / 100
+
+		dateJobMap := make(map[string][]*commonmodels.JobInfo)
+		for _, job := range deployJobs {
+			date := time.Unix(job.StartTime, 0).Format("2006-01-02")
+			dateJobMap[date] = append(dateJobMap[date], job)
+		}
+		dateIncidentMap := make(map[string][]*commonmodels.Incident)
+		for _, incident := range incidents {
+			date := time.Unix(incident.OccurredAt, 0).Format("2006-01-02")
+			dateIncidentMap[date] = append(dateIncidentMap[date], incident)
+		}
+		for date := time.Unix(startTime, 0); date.Unix() < endTime; date = date.AddDate(0, 0, 1) {
+			day := date.Format("2006-01-02")
+			dailyRate, _ := calculateChangeFailureRate(dateJobMap[day], dateIncidentMap[day])
+			dailyMTTR, _ := calculateMeanTimeToRestore(dateIncidentMap[day])
+			stat.DailyStat = append(stat.DailyStat, &DORADailyStat{
+				Date:              day,
+				DeployCount:       countPassedJobs(dateJobMap[day]),
+				IncidentCount:     len(dateIncidentMap[day]),
+				ChangeFailureRate: math.Round(dailyRate*100) / 100,
+				MeanTimeToRestore: math.Round(dailyMTTR*100) / 100,
+			})
+		}
+		resp = append(resp, stat)
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].ProjectKey < resp[j].ProjectKey
+	})
+	return resp, nil
+}
+
+// ExportDORAStats exports the daily DORA stats of the projects as a csv file
+func ExportDORAStats(startTime, endTime int64, projectList []string, logger *zap.SugaredLogger) ([]byte, error) {
+	stats, err := GetDORAStats(startTime, endTime, projectList, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	header := []string{"project_key", "project_name", "date", "deploy_count", "incident_count", "change_failure_rate(%)", "mean_time_to_restore(s)"}
+	if err := writer.Write(header); err != nil {
+		return nil, e.ErrGetDORAStatistics.AddErr(err)
+	}
+	for _, stat := range stats {
+		for _, daily := range stat.DailyStat {
+			record := []string{
+				stat.ProjectKey,
+				stat.ProjectName,
+				daily.Date,
+				strconv.Itoa(daily.DeployCount),
+				strconv.Itoa(daily.IncidentCount),
+				strconv.FormatFloat(daily.ChangeFailureRate, 'f', 2, 64),
+				strconv.FormatFloat(daily.MeanTimeToRestore, 'f', 2, 64),
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, e.ErrGetDORAStatistics.AddErr(err)
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, e.ErrGetDORAStatistics.AddErr(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func countPassedJobs(jobs []*commonmodels.JobInfo) int {
+	counter := 0
+	for _, job := range jobs {
+		if job.Status == string(config.StatusPassed) {
+			counter++
+		}
+	}
+	return counter
+}
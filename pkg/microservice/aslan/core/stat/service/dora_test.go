@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func newDeployJobs(statuses ...config.Status) []*commonmodels.JobInfo {
+	jobs := make([]*commonmodels.JobInfo, 0, len(statuses))
+	for _, status := range statuses {
+		jobs = append(jobs, &commonmodels.JobInfo{Type: string(config.JobZadigDeploy), Status: string(status)})
+	}
+	return jobs
+}
+
+func newIncidents(n int) []*commonmodels.Incident {
+	incidents := make([]*commonmodels.Incident, 0, n)
+	for i := 0; i < n; i++ {
+		incidents = append(incidents, &commonmodels.Incident{Status: config.IncidentStatusOpen})
+	}
+	return incidents
+}
+
+func TestCalculateChangeFailureRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		jobs      []*commonmodels.JobInfo
+		incidents []*commonmodels.Incident
+		wantRate  float64
+		wantOK    bool
+	}{
+		{
+			name:   "no deployment",
+			wantOK: false,
+		},
+		{
+			name:      "only failed deployments",
+			jobs:      newDeployJobs(config.StatusFailed, config.StatusCancelled),
+			incidents: newIncidents(1),
+			wantOK:    false,
+		},
+		{
+			name:     "no incident",
+			jobs:     newDeployJobs(config.StatusPassed, config.StatusPassed),
+			wantRate: 0,
+			wantOK:   true,
+		},
+		{
+			name:      "failed deployments are not counted",
+			jobs:      newDeployJobs(config.StatusPassed, config.StatusPassed, config.StatusPassed, config.StatusPassed, config.StatusFailed),
+			incidents: newIncidents(1),
+			wantRate:  25,
+			wantOK:    true,
+		},
+		{
+			name:      "capped at 100",
+			jobs:      newDeployJobs(config.StatusPassed),
+			incidents: newIncidents(3),
+			wantRate:  100,
+			wantOK:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, ok := calculateChangeFailureRate(tt.jobs, tt.incidents)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.InDelta(t, tt.wantRate, rate, 0.001)
+		})
+	}
+}
+
+func TestCalculateMeanTimeToRestore(t *testing.T) {
+	tests := []struct {
+		name      string
+		incidents []*commonmodels.Incident
+		wantMTTR  float64
+		wantOK    bool
+	}{
+		{
+			name:   "no incident",
+			wantOK: false,
+		},
+		{
+			name: "open incidents are not counted",
+			incidents: []*commonmodels.Incident{
+				{Status: config.IncidentStatusOpen, OccurredAt: 100},
+			},
+			wantOK: false,
+		},
+		{
+			name: "resolved incidents",
+			incidents: []*commonmodels.Incident{
+				{Status: config.IncidentStatusResolved, OccurredAt: 100, ResolvedAt: 160},
+				{Status: config.IncidentStatusResolved, OccurredAt: 200, ResolvedAt: 320},
+				{Status: config.IncidentStatusOpen, OccurredAt: 300},
+			},
+			wantMTTR: 90,
+			wantOK:   true,
+		},
+		{
+			name: "resolved before occurred is ignored",
+			incidents: []*commonmodels.Incident{
+				{Status: config.IncidentStatusResolved, OccurredAt: 100, ResolvedAt: 50},
+				{Status: config.IncidentStatusResolved, OccurredAt: 100, ResolvedAt: 130},
+			},
+			wantMTTR: 30,
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mttr, ok := calculateMeanTimeToRestore(tt.incidents)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.InDelta(t, tt.wantMTTR, mttr, 0.001)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/Knetic/govaluate"
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
 	"github.com/koderover/zadig/v2/pkg/util"
@@ -107,6 +108,16 @@ func CreateCalculatorFromConfig(cfg *StatDashboardConfig) (StatCalculator, error
 			Weight:   cfg.Weight,
 			Function: cfg.Function,
 		}, nil
+	case config.DashboardDataTypeChangeFailureRate:
+		return &ChangeFailureRateCalculator{
+			Weight:   cfg.Weight,
+			Function: cfg.Function,
+		}, nil
+	case config.DashboardDataTypeMeanTimeToRestore:
+		return &MeanTimeToRestoreCalculator{
+			Weight:   cfg.Weight,
+			Function: cfg.Function,
+		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported config id: %s", cfg.ID)
 	}
@@ -432,6 +443,89 @@ func (c *ReleaseFrequencyCalculator) GetWeightedScore(fact float64) (float64, er
 	return calculateWeightedScore(fact, c.Function, c.Weight)
 }
 
+// ChangeFailureRateCalculator calculates the percentage of the deployments causing a failure, the failures are
+// the incidents reported through the API and the rollbacks of the environments, gray release and istio release
+type ChangeFailureRateCalculator struct {
+	Weight   int64
+	Function string
+}
+
+func (c *ChangeFailureRateCalculator) GetFact(startTime, endTime int64, project string) (float64, bool, error) {
+	deployJobList, err := commonrepo.NewJobInfoColl().GetDeployJobs(startTime, endTime, project)
+	if err != nil {
+		return 0, false, err
+	}
+	incidents, err := commonrepo.NewIncidentColl().List(&commonrepo.IncidentListOption{
+		ProjectNames: []string{project},
+		StartTime:    startTime,
+		EndTime:      endTime,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	rate, ok := calculateChangeFailureRate(deployJobList, incidents)
+	return rate, ok, nil
+}
+
+func (c *ChangeFailureRateCalculator) GetWeightedScore(fact float64) (float64, error) {
+	return calculateWeightedScore(fact, c.Function, c.Weight)
+}
+
+// MeanTimeToRestoreCalculator calculates the average seconds taken to restore the service from the incidents
+type MeanTimeToRestoreCalculator struct {
+	Weight   int64
+	Function string
+}
+
+func (c *MeanTimeToRestoreCalculator) GetFact(startTime, endTime int64, project string) (float64, bool, error) {
+	incidents, err := commonrepo.NewIncidentColl().List(&commonrepo.IncidentListOption{
+		ProjectNames: []string{project},
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Status:       config.IncidentStatusResolved,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	mttr, ok := calculateMeanTimeToRestore(incidents)
+	return mttr, ok, nil
+}
+
+func (c *MeanTimeToRestoreCalculator) GetWeightedScore(fact float64) (float64, error) {
+	return calculateWeightedScore(fact, c.Function, c.Weight)
+}
+
+func calculateChangeFailureRate(deployJobs []*commonmodels.JobInfo, incidents []*commonmodels.Incident) (float64, bool) {
+	deployCounter := countPassedJobs(deployJobs)
+	if deployCounter == 0 {
+		return 0, false
+	}
+
+	rate := float64(len(incidents)) * 100 / float64(deployCounter)
+	if rate > 100 {
+		rate = 100
+	}
+	return rate, true
+}
+
+func calculateMeanTimeToRestore(incidents []*commonmodels.Incident) (float64, bool) {
+	var totalTimesTaken int64 = 0
+	resolvedCounter := 0
+	for _, incident := range incidents {
+		if incident.Status != config.IncidentStatusResolved || incident.ResolvedAt < incident.OccurredAt {
+			continue
+		}
+		totalTimesTaken += incident.ResolvedAt - incident.OccurredAt
+		resolvedCounter++
+	}
+	if resolvedCounter == 0 {
+		return 0, false
+	}
+	return float64(totalTimesTaken) / float64(resolvedCounter), true
+}
+
 func calculateWeightedScore(fact float64, function string, weight int64) (float64, error) {
 	expression, err := govaluate.NewEvaluableExpression(function)
 	if err != nil {
@@ -346,6 +346,22 @@ var defaultStatDashboardConfigMap = map[string]*commonmodels.StatDashboardConfig
 		Function: config.DashboardFunctionReleaseFrequency,
 		Weight:   0,
 	},
+	config.DashboardDataTypeChangeFailureRate: {
+		Type:     config.DashboardDataCategoryQuality,
+		Name:     "变更失败率",
+		ItemKey:  config.DashboardDataTypeChangeFailureRate,
+		Source:   config.DashboardDataSourceZadig,
+		Function: config.DashboardFunctionChangeFailureRate,
+		Weight:   0,
+	},
+	config.DashboardDataTypeMeanTimeToRestore: {
+		Type:     config.DashboardDataCategoryQuality,
+		Name:     "平均恢复时间",
+		ItemKey:  config.DashboardDataTypeMeanTimeToRestore,
+		Source:   config.DashboardDataSourceZadig,
+		Function: config.DashboardFunctionMeanTimeToRestore,
+		Weight:   0,
+	},
 }
 
 func createDefaultStatDashboardConfig() []*commonmodels.StatDashboardConfig {
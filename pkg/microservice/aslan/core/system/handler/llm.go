@@ -29,10 +29,12 @@ import (
 )
 
 type CreateLLMIntegrationRequest struct {
-	Name        string `json:"name"`
-	Token       string `json:"token"`
-	BaseURL     string `json:"base_url"`
-	EnableProxy bool   `json:"enable_proxy"`
+	Name         string `json:"name"`
+	ProviderName string `json:"provider_name"`
+	Model        string `json:"model"`
+	Token        string `json:"token"`
+	BaseURL      string `json:"base_url"`
+	EnableProxy  bool   `json:"enable_proxy"`
 }
 
 // @Summary Create a llm integration
@@ -125,6 +127,20 @@ func CheckLLMIntegration(c *gin.Context) {
 	ctx.Resp = resp
 }
 
+// @Summary List llm providers
+// @Description List the registered llm providers which can be used by the llm integration
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Success 200 		{array} 	string
+// @Router /api/aslan/system/llm/provider [get]
+func ListLLMProviders(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp = service.ListLLMProviders()
+}
+
 // @Summary Update a llm integration
 // @Description Update a llm integration
 // @Tags 	system
@@ -184,9 +200,11 @@ func DeleteLLMIntegration(c *gin.Context) {
 
 func convertLLMArgToModel(args *CreateLLMIntegrationRequest) *commonmodels.LLMIntegration {
 	return &commonmodels.LLMIntegration{
-		Name:        args.Name,
-		Token:       args.Token,
-		BaseURL:     args.BaseURL,
-		EnableProxy: args.EnableProxy,
+		Name:         args.Name,
+		ProviderName: args.ProviderName,
+		Model:        args.Model,
+		Token:        args.Token,
+		BaseURL:      args.BaseURL,
+		EnableProxy:  args.EnableProxy,
 	}
 }
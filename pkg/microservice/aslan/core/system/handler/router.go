@@ -391,6 +391,20 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		llm.GET("/integration/:id", GetLLMIntegration)
 		llm.PUT("/integration/:id", UpdateLLMIntegration)
 		llm.DELETE("/integration/:id", DeleteLLMIntegration)
+		llm.GET("/provider", ListLLMProviders)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// workflow policy
+	// ---------------------------------------------------------------------------------------
+	workflowPolicy := router.Group("workflow/policy")
+	{
+		workflowPolicy.GET("", ListWorkflowPolicies)
+		workflowPolicy.POST("", CreateWorkflowPolicy)
+		workflowPolicy.GET("/decision", ListWorkflowPolicyDecisions)
+		workflowPolicy.GET("/:name", GetWorkflowPolicy)
+		workflowPolicy.PUT("/:name", UpdateWorkflowPolicy)
+		workflowPolicy.DELETE("/:name", DeleteWorkflowPolicy)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+func ListWorkflowPolicies(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListWorkflowPolicies(ctx.Logger)
+}
+
+func GetWorkflowPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetWorkflowPolicy(c.Param("name"), ctx.Logger)
+}
+
+func CreateWorkflowPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args := new(commonmodels.WorkflowPolicy)
+	if err := json.Unmarshal(data, args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid workflow policy args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统配置-工作流策略", args.Name, string(data), ctx.Logger)
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Err = service.CreateWorkflowPolicy(args, ctx.UserName, ctx.Logger)
+}
+
+func UpdateWorkflowPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args := new(commonmodels.WorkflowPolicy)
+	if err := json.Unmarshal(data, args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid workflow policy args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-工作流策略", c.Param("name"), string(data), ctx.Logger)
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Err = service.UpdateWorkflowPolicy(c.Param("name"), args, ctx.UserName, ctx.Logger)
+}
+
+func DeleteWorkflowPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统配置-工作流策略", c.Param("name"), "", ctx.Logger)
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Err = service.DeleteWorkflowPolicy(c.Param("name"), ctx.Logger)
+}
+
+type listWorkflowPolicyDecisionsQuery struct {
+	PolicyName   string `form:"policy_name"`
+	ProjectName  string `form:"project_name"`
+	WorkflowName string `form:"workflow_name"`
+	TaskID       int64  `form:"task_id"`
+	Allowed      *bool  `form:"allowed"`
+	PageSize     int64  `form:"page_size,default=20"`
+	PageNum      int64  `form:"page_num,default=1"`
+}
+
+func ListWorkflowPolicyDecisions(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := &listWorkflowPolicyDecisionsQuery{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListWorkflowPolicyDecisions(&commonrepo.ListWorkflowPolicyDecisionOption{
+		PolicyName:   args.PolicyName,
+		ProjectName:  args.ProjectName,
+		WorkflowName: args.WorkflowName,
+		TaskID:       args.TaskID,
+		Allowed:      args.Allowed,
+		PageNum:      args.PageNum,
+		PageSize:     args.PageSize,
+	}, ctx.Logger)
+}
@@ -30,6 +30,9 @@ type OperationLog struct {
 	RequestBody string             `bson:"request_body"                json:"request_body"`
 	Status      int                `bson:"status"                      json:"status"`
 	CreatedAt   int64              `bson:"created_at"                  json:"created_at"`
+
+	// IsServiceAccount marks the operations done by service accounts, which are not humans
+	IsServiceAccount bool `bson:"is_service_account" json:"is_service_account"`
 }
 
 func (OperationLog) TableName() string {
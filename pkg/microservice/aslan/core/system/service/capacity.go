@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models/task"
@@ -43,7 +45,10 @@ func UpdateSysCapStrategy(strategy *commonmodels.CapacityStrategy) error {
 	}
 
 	// 更新成功后，立即按照新的配置清理数据
-	go handleWorkflowTaskRetentionCenter(strategy, false)
+	// 环境快照在每次创建快照时按照配置清理
+	if strategy.Target == commonmodels.WorkflowTaskRetention {
+		go handleWorkflowTaskRetentionCenter(strategy, false)
+	}
 
 	return nil
 }
@@ -53,6 +58,9 @@ func GetCapacityStrategy(target commonmodels.CapacityTarget) (*commonmodels.Capa
 	if err != nil && target == commonmodels.WorkflowTaskRetention {
 		return commonmodels.DefaultWorkflowTaskRetention, nil // Return default setup
 	}
+	if err == mongo.ErrNoDocuments && target == commonmodels.EnvSnapshotRetention {
+		return commonmodels.DefaultEnvSnapshotRetention, nil
+	}
 	return result, err
 }
 
@@ -386,10 +394,10 @@ func cleanStaleTaskV4s(tasks []*commonmodels.WorkflowTask, s3Server *s3.S3, dryR
 }
 
 func validateStrategy(strategy *commonmodels.CapacityStrategy) error {
-	if strategy.Target == commonmodels.WorkflowTaskRetention {
+	if strategy.Target == commonmodels.WorkflowTaskRetention || strategy.Target == commonmodels.EnvSnapshotRetention {
 		retention := strategy.Retention
 		if retention == nil {
-			return errors.New("SysCap strategy: nil retention config for WorkflowTaskRetention")
+			return fmt.Errorf("SysCap strategy: nil retention config for %s", strategy.Target)
 		}
 		if !(retention.MaxDays > 0 && retention.MaxItems == 0) &&
 			!(retention.MaxDays == 0 && retention.MaxItems > 0) {
@@ -22,7 +22,9 @@ import (
 
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service"
 	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+	"github.com/koderover/zadig/v2/pkg/tool/llm"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
@@ -73,6 +75,9 @@ func CreateLLMIntegration(ctx context.Context, args *commonmodels.LLMIntegration
 	if count > 0 {
 		return e.ErrCreateLLMIntegration.AddDesc("llm integration already exists")
 	}
+	if provider := commonservice.GetLLMProviderName(args); !llm.IsProviderSupported(provider) {
+		return e.ErrCreateLLMIntegration.AddDesc(fmt.Sprintf("llm provider %s is not supported", provider))
+	}
 
 	if err := commonrepo.NewLLMIntegrationColl().Create(ctx, args); err != nil {
 		fmtErr := fmt.Errorf("CreateLLMIntegration err: %w", err)
@@ -83,6 +88,9 @@ func CreateLLMIntegration(ctx context.Context, args *commonmodels.LLMIntegration
 }
 
 func UpdateLLMIntegration(ctx context.Context, ID string, args *commonmodels.LLMIntegration) error {
+	if provider := commonservice.GetLLMProviderName(args); !llm.IsProviderSupported(provider) {
+		return e.ErrUpdateLLMIntegration.AddDesc(fmt.Sprintf("llm provider %s is not supported", provider))
+	}
 	if err := commonrepo.NewLLMIntegrationColl().Update(ctx, ID, args); err != nil {
 		fmtErr := fmt.Errorf("UpdateLLMIntegration err: %w", err)
 		log.Error(fmtErr)
@@ -99,3 +107,7 @@ func DeleteLLMIntegration(ctx context.Context, ID string) error {
 	}
 	return nil
 }
+
+func ListLLMProviders() []string {
+	return llm.ListProviders()
+}
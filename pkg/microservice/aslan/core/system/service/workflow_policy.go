@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowpolicy"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+var workflowPolicyNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type ListWorkflowPolicyDecisionsResp struct {
+	Decisions []*commonmodels.WorkflowPolicyDecision `json:"decisions"`
+	Total     int64                                  `json:"total"`
+}
+
+func ListWorkflowPolicies(log *zap.SugaredLogger) ([]*commonmodels.WorkflowPolicy, error) {
+	resp, err := commonrepo.NewWorkflowPolicyColl().List(false)
+	if err != nil {
+		log.Errorf("WorkflowPolicy.List error: %s", err)
+		return nil, e.ErrGetWorkflowPolicy.AddErr(err)
+	}
+	return resp, nil
+}
+
+func GetWorkflowPolicy(name string, log *zap.SugaredLogger) (*commonmodels.WorkflowPolicy, error) {
+	resp, err := commonrepo.NewWorkflowPolicyColl().Find(name)
+	if err != nil {
+		log.Errorf("WorkflowPolicy.Find %s error: %s", name, err)
+		return nil, e.ErrGetWorkflowPolicy.AddErr(err)
+	}
+	return resp, nil
+}
+
+// CreateWorkflowPolicy saves the policy after it is compiled by OPA, a policy which can not be compiled is rejected
+func CreateWorkflowPolicy(args *commonmodels.WorkflowPolicy, userName string, log *zap.SugaredLogger) error {
+	if _, err := commonrepo.NewWorkflowPolicyColl().Find(args.Name); err == nil {
+		return e.ErrCreateWorkflowPolicy.AddDesc(fmt.Sprintf("policy %s already exists", args.Name))
+	}
+	if err := validateWorkflowPolicy(args); err != nil {
+		return e.ErrCreateWorkflowPolicy.AddErr(err)
+	}
+
+	now := time.Now().Unix()
+	args.CreatedBy = userName
+	args.CreateTime = now
+	args.UpdatedBy = userName
+	args.UpdateTime = now
+	if err := workflowpolicy.LoadPolicy(args); err != nil {
+		log.Errorf("failed to load workflow policy %s, error: %s", args.Name, err)
+		return e.ErrCreateWorkflowPolicy.AddErr(err)
+	}
+
+	if err := commonrepo.NewWorkflowPolicyColl().Create(args); err != nil {
+		log.Errorf("WorkflowPolicy.Create %s error: %s", args.Name, err)
+		return e.ErrCreateWorkflowPolicy.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateWorkflowPolicy(name string, args *commonmodels.WorkflowPolicy, userName string, log *zap.SugaredLogger) error {
+	if _, err := commonrepo.NewWorkflowPolicyColl().Find(name); err != nil {
+		log.Errorf("WorkflowPolicy.Find %s error: %s", name, err)
+		return e.ErrUpdateWorkflowPolicy.AddErr(err)
+	}
+	args.Name = name
+	if err := validateWorkflowPolicy(args); err != nil {
+		return e.ErrUpdateWorkflowPolicy.AddErr(err)
+	}
+
+	args.UpdatedBy = userName
+	args.UpdateTime = time.Now().Unix()
+	if err := workflowpolicy.LoadPolicy(args); err != nil {
+		log.Errorf("failed to load workflow policy %s, error: %s", args.Name, err)
+		return e.ErrUpdateWorkflowPolicy.AddErr(err)
+	}
+
+	if err := commonrepo.NewWorkflowPolicyColl().Update(args); err != nil {
+		log.Errorf("WorkflowPolicy.Update %s error: %s", name, err)
+		return e.ErrUpdateWorkflowPolicy.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteWorkflowPolicy(name string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewWorkflowPolicyColl().Delete(name); err != nil {
+		log.Errorf("WorkflowPolicy.Delete %s error: %s", name, err)
+		return e.ErrDeleteWorkflowPolicy.AddErr(err)
+	}
+	// the policy is never evaluated once it is deleted from db, a stale module left in OPA does no harm
+	if err := workflowpolicy.UnloadPolicy(name); err != nil {
+		log.Warnf("failed to unload workflow policy %s from opa, error: %s", name, err)
+	}
+	return nil
+}
+
+func ListWorkflowPolicyDecisions(opt *commonrepo.ListWorkflowPolicyDecisionOption, log *zap.SugaredLogger) (*ListWorkflowPolicyDecisionsResp, error) {
+	decisions, total, err := commonrepo.NewWorkflowPolicyDecisionColl().List(opt)
+	if err != nil {
+		log.Errorf("WorkflowPolicyDecision.List error: %s", err)
+		return nil, e.ErrListWorkflowPolicyDecisions.AddErr(err)
+	}
+	return &ListWorkflowPolicyDecisionsResp{
+		Decisions: decisions,
+		Total:     total,
+	}, nil
+}
+
+func validateWorkflowPolicy(args *commonmodels.WorkflowPolicy) error {
+	if !workflowPolicyNameRegex.MatchString(args.Name) {
+		return fmt.Errorf("invalid policy name %s, only lowercase letters, digits and - are allowed", args.Name)
+	}
+	switch args.Enforcement {
+	case config.PolicyEnforcementBlock, config.PolicyEnforcementWarn:
+	default:
+		return fmt.Errorf("invalid enforcement %s", args.Enforcement)
+	}
+
+	pkg, err := workflowpolicy.ParsePackage(args.Rego)
+	if err != nil {
+		return err
+	}
+	policies, err := commonrepo.NewWorkflowPolicyColl().List(false)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		// the deny sets of the policies in the same package would be merged by OPA
+		if policy.Name != args.Name && policy.Package == pkg {
+			return fmt.Errorf("package %s is already used by policy %s", pkg, policy.Name)
+		}
+	}
+	args.Package = pkg
+	return nil
+}
@@ -27,6 +27,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		vm.PUT("/:vmid/agent/offline", OfflineVM)
 		vm.PUT("/:vmid/agent/recovery", RecoveryVM)
 		vm.PUT("/:vmid/agent/upgrade", UpgradeAgent)
+		vm.PUT("/agents/version", PinAgentVersion)
 		vm.GET("/vms", ListVMs)
 		vm.GET("/labels", ListVMLabels)
 	}
@@ -38,5 +39,6 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		vmAgent.POST("/heartbeat", HeartbeatAgent)
 		vmAgent.GET("/job/request", PollingAgentJob)
 		vmAgent.POST("/job/report", ReportAgentJob)
+		vmAgent.GET("/connect", ConnectAgent)
 	}
 }
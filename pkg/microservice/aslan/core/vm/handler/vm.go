@@ -202,3 +202,46 @@ func ReportAgentJob(c *gin.Context) {
 
 	ctx.Resp, ctx.Err = service.ReportAgentJob(args, ctx.Logger)
 }
+
+func ConnectAgent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	token := c.Query("token")
+	if token == "" {
+		ctx.Err = fmt.Errorf("invalid request: %s", "token is empty")
+		return
+	}
+
+	ctx.Err = service.ServeAgentConn(c, token, ctx.Logger)
+}
+
+func PinAgentVersion(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(service.PinAgentVersionArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = fmt.Errorf("invalid request: %s", err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = fmt.Errorf("invalid request: %s", err)
+		return
+	}
+
+	ctx.Err = service.PinAgentVersion(args, ctx.UserName, ctx.Logger)
+}
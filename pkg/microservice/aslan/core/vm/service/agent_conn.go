@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	vmmodel "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models/vm"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	vmmongodb "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/vm"
+	commonvm "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/vm"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/wsconn"
+)
+
+const agentDispatchInterval = time.Second
+
+var agentUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+type AgentReadyArgs struct {
+	Free int `json:"free"`
+}
+
+type AgentLogArgs struct {
+	Log string `json:"log"`
+}
+
+type AgentReportAck struct {
+	Resp  *ReportAgentJobResp `json:"resp"`
+	Error string              `json:"error"`
+}
+
+type agentSession struct {
+	token  string
+	vmName string
+	conn   *wsconn.AgentConn
+	// free is the number of jobs the agent told us it can accept
+	free     int32
+	messages *agentMessageQueue
+	// jobs caches the jobs the streamed logs are appended to, it's only accessed by process
+	jobs   map[string]*vmmodel.VMJob
+	logger *zap.SugaredLogger
+}
+
+// agentMessageQueue is an unbounded fifo of the logs and reports of an agent. The reader of the channel never blocks on
+// a slow log write or report, so it keeps answering the heartbeats of the agent.
+type agentMessageQueue struct {
+	mu     sync.Mutex
+	items  []*wsconn.AgentMessage
+	notify chan struct{}
+}
+
+func newAgentMessageQueue() *agentMessageQueue {
+	return &agentMessageQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *agentMessageQueue) push(msg *wsconn.AgentMessage) {
+	q.mu.Lock()
+	q.items = append(q.items, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// popAll returns the queued messages in the order they were pushed
+func (q *agentMessageQueue) popAll() []*wsconn.AgentMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// ServeAgentConn upgrades the request of a zadig-agent to a websocket channel, over which jobs are dispatched to the
+// agent, cancellations are pushed to it, and its logs and reports are received. It returns when the channel drops,
+// after which the agent falls back to polling.
+func ServeAgentConn(c *gin.Context, token string, logger *zap.SugaredLogger) error {
+	vm, err := commonrepo.NewPrivateKeyColl().Find(commonrepo.FindPrivateKeyOption{
+		Token: token,
+	})
+	if err != nil {
+		logger.Errorf("failed to find vm by token, error: %s", err)
+		return fmt.Errorf("failed to find vm by token, error: %s", err)
+	}
+	if vm.Agent == nil {
+		return fmt.Errorf("zadig server vm %s agent is nil in db", vm.Name)
+	}
+	if vm.Status == setting.VMOffline {
+		return fmt.Errorf("vm %s status is %s", vm.Name, vm.Status)
+	}
+
+	ws, err := agentUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Errorf("failed to upgrade agent %s connection, error: %s", vm.Name, err)
+		return fmt.Errorf("failed to upgrade agent %s connection, error: %s", vm.Name, err)
+	}
+
+	conn := wsconn.NewAgentConn(ws)
+	defer conn.Close()
+
+	vmID := vm.ID.Hex()
+	commonvm.AgentConns.Set(vmID, conn)
+	defer commonvm.AgentConns.Delete(vmID, conn)
+
+	session := &agentSession{
+		token:    token,
+		vmName:   vm.Name,
+		conn:     conn,
+		messages: newAgentMessageQueue(),
+		jobs:     make(map[string]*vmmodel.VMJob),
+		logger:   logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go session.dispatch(ctx)
+	go session.process(ctx)
+
+	logger.Infof("agent of vm %s connected", vm.Name)
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			logger.Infof("agent of vm %s disconnected: %s", vm.Name, err)
+			return nil
+		}
+
+		switch msg.Type {
+		case wsconn.AgentMsgHeartbeat:
+			if err := conn.Send(wsconn.AgentMsgHeartbeat, "", "", nil); err != nil {
+				logger.Warnf("failed to answer heartbeat of agent %s, error: %s", vm.Name, err)
+			}
+		case wsconn.AgentMsgReady:
+			args := new(AgentReadyArgs)
+			if err := json.Unmarshal(msg.Data, args); err != nil {
+				logger.Warnf("invalid ready message from agent %s, error: %s", vm.Name, err)
+				continue
+			}
+			atomic.StoreInt32(&session.free, int32(args.Free))
+		case wsconn.AgentMsgLog, wsconn.AgentMsgReport:
+			// logs and reports of a job must be handled in the order the agent sent them
+			session.messages.push(msg)
+		default:
+			logger.Warnf("unknown message type %s from agent %s", msg.Type, vm.Name)
+		}
+	}
+}
+
+// dispatch pushes created jobs to the agent while it has free slots.
+func (s *agentSession) dispatch(ctx context.Context) {
+	ticker := time.NewTicker(agentDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for atomic.LoadInt32(&s.free) > 0 {
+				job, err := PollingAgentJob(s.token, 0, s.logger)
+				if err != nil {
+					s.logger.Debugf("failed to get job for agent %s, error: %s", s.vmName, err)
+					break
+				}
+				if job == nil {
+					break
+				}
+
+				if err := s.conn.Send(wsconn.AgentMsgDispatch, "", job.ID, job); err != nil {
+					// the job stays in prepare status and is timed out by the workflow, same as a lost polling response
+					s.logger.Errorf("failed to dispatch job %s to agent %s, error: %s", job.ID, s.vmName, err)
+					return
+				}
+				atomic.AddInt32(&s.free, -1)
+			}
+		}
+	}
+}
+
+func (s *agentSession) process(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.messages.notify:
+			for _, msg := range s.messages.popAll() {
+				switch msg.Type {
+				case wsconn.AgentMsgLog:
+					args := new(AgentLogArgs)
+					if err := json.Unmarshal(msg.Data, args); err != nil {
+						s.logger.Warnf("invalid log message from agent %s, error: %s", s.vmName, err)
+						continue
+					}
+					if err := s.appendLog(msg.JobID, args.Log); err != nil {
+						s.logger.Errorf("failed to save log of job %s from agent %s, error: %s", msg.JobID, s.vmName, err)
+					}
+				case wsconn.AgentMsgReport:
+					s.report(msg)
+				}
+			}
+		}
+	}
+}
+
+// appendLog appends the log lines to the log file of the job, the job is cached so it's only read from the database
+// once until the job is reported.
+func (s *agentSession) appendLog(jobID, log string) error {
+	job, ok := s.jobs[jobID]
+	if !ok {
+		var err error
+		job, err = vmmongodb.NewVMJobColl().FindByID(jobID)
+		if err != nil {
+			return fmt.Errorf("failed to find job %s, error: %s", jobID, err)
+		}
+		s.jobs[jobID] = job
+	}
+	return appendVMJobLog(job, log, s.logger)
+}
+
+func (s *agentSession) report(msg *wsconn.AgentMessage) {
+	// the report updates the job in the database, the cached job is stale then
+	delete(s.jobs, msg.JobID)
+
+	ack := new(AgentReportAck)
+	args := new(ReportJobArgs)
+	if err := json.Unmarshal(msg.Data, args); err != nil {
+		ack.Error = fmt.Sprintf("invalid report message, error: %s", err)
+	} else {
+		args.Token = s.token
+		resp, err := ReportAgentJob(args, s.logger)
+		if err != nil {
+			ack.Error = err.Error()
+		}
+		ack.Resp = resp
+	}
+
+	if err := s.conn.Send(wsconn.AgentMsgReportAck, msg.ID, msg.JobID, ack); err != nil {
+		s.logger.Warnf("failed to ack report of job %s to agent %s, error: %s", msg.JobID, s.vmName, err)
+		return
+	}
+
+	if ack.Resp != nil && (ack.Resp.JobStatus == string(config.StatusCancelled) || ack.Resp.JobStatus == string(config.StatusTimeout)) {
+		if err := s.conn.Send(wsconn.AgentMsgCancel, "", ack.Resp.JobID, nil); err != nil {
+			s.logger.Warnf("failed to push cancel of job %s to agent %s, error: %s", ack.Resp.JobID, s.vmName, err)
+		}
+	}
+}
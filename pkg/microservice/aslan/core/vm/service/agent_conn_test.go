@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/tool/wsconn"
+)
+
+func TestAgentMessageQueueKeepsOrder(t *testing.T) {
+	q := newAgentMessageQueue()
+
+	// pushing never blocks, no matter how far behind the consumer is
+	for i := 0; i < 1000; i++ {
+		q.push(&wsconn.AgentMessage{Type: wsconn.AgentMsgLog, ID: strconv.Itoa(i)})
+	}
+
+	<-q.notify
+	msgs := q.popAll()
+	assert.Len(t, msgs, 1000)
+	for i, msg := range msgs {
+		assert.Equal(t, strconv.Itoa(i), msg.ID)
+	}
+	assert.Empty(t, q.popAll())
+}
+
+func TestAgentMessageQueueNotify(t *testing.T) {
+	q := newAgentMessageQueue()
+
+	select {
+	case <-q.notify:
+		t.Fatal("empty queue notified")
+	default:
+	}
+
+	q.push(&wsconn.AgentMessage{Type: wsconn.AgentMsgReport})
+	q.push(&wsconn.AgentMessage{Type: wsconn.AgentMsgLog})
+	<-q.notify
+	assert.Len(t, q.popAll(), 2)
+
+	// a push after the consumer drained the queue notifies again
+	q.push(&wsconn.AgentMessage{Type: wsconn.AgentMsgLog})
+	select {
+	case <-q.notify:
+	default:
+		t.Fatal("queue not notified")
+	}
+	assert.Len(t, q.popAll(), 1)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	vmmodel "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models/vm"
 	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	vmmongodb "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/vm"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 	s3tool "github.com/koderover/zadig/v2/pkg/tool/s3"
@@ -144,3 +145,21 @@ func GetObjectPath(subFolder, name string) string {
 
 	return strings.TrimLeft(name, "/")
 }
+
+// appendVMJobLog appends the log lines streamed by the agent to the log file of a running job, the job is only
+// updated in the database when its log file is created.
+func appendVMJobLog(job *vmmodel.VMJob, log string, logger *zap.SugaredLogger) error {
+	// the log of a finished job has been uploaded to s3 already
+	if job.Status == string(config.StatusCancelled) || job.Status == string(config.StatusTimeout) || job.Status == string(config.StatusFailed) || job.Status == string(config.StatusPassed) {
+		return nil
+	}
+
+	logFile := job.LogFile
+	if err := savaVMJobLog(job, log, logger); err != nil {
+		return err
+	}
+	if job.LogFile != logFile {
+		return vmmongodb.NewVMJobColl().Update(job.ID.Hex(), job)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package service
 
 import (
 	"fmt"
+	"regexp"
 )
 
 type CreateVMRequest struct {
@@ -114,6 +115,11 @@ type HeartbeatParameters struct {
 	DiskSpace     uint64 `json:"disk_space"`
 	FreeDiskSpace uint64 `json:"free_disk_space"`
 	VMname        string `json:"vm_name"`
+
+	AgentVersion string `json:"agent_version"`
+	// UpgradeVersion and UpgradeError are set when the last upgrade of the agent failed and was rolled back
+	UpgradeVersion string `json:"upgrade_version"`
+	UpgradeError   string `json:"upgrade_error"`
 }
 
 type HeartbeatRequest struct {
@@ -135,6 +141,52 @@ type HeartbeatResponse struct {
 	VmName                 string       `json:"vm_name"`
 	Description            string       `json:"description"`
 	ZadigVersion           string       `json:"zadig_version"`
+
+	// AgentDownloadURL and AgentChecksum are the package of AgentVersion, the checksum is sha256 and may be empty
+	AgentDownloadURL string `json:"agent_download_url"`
+	AgentChecksum    string `json:"agent_checksum"`
+}
+
+type PinAgentVersionArgs struct {
+	VMIDs []string `json:"vm_ids"`
+	// Label pins all the vms with the label
+	Label string `json:"label"`
+	// Version is the desired agent version, empty unpins the vms
+	Version string `json:"version"`
+	// Checksums are the sha256 checksums of the agent packages, keyed by platform like linux_amd64. The agents only
+	// install a package with a pinned checksum, so every platform of the pinned vms needs one.
+	Checksums map[string]string `json:"checksums"`
+}
+
+var (
+	// agentVersionRegexp matches the released agent versions, the version is a part of the package url
+	agentVersionRegexp  = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.]+)?$`)
+	agentChecksumRegexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+func (args *PinAgentVersionArgs) Validate() error {
+	if len(args.VMIDs) == 0 && args.Label == "" {
+		return fmt.Errorf("vm_ids or label is required")
+	}
+	if args.Version == "" {
+		return nil
+	}
+
+	if !agentVersionRegexp.MatchString(args.Version) {
+		return fmt.Errorf("invalid version %s", args.Version)
+	}
+	if len(args.Checksums) == 0 {
+		return fmt.Errorf("checksums are required")
+	}
+	for platform, checksum := range args.Checksums {
+		if !isAgentPlatform(platform) {
+			return fmt.Errorf("unsupported platform %s", platform)
+		}
+		if !agentChecksumRegexp.MatchString(checksum) {
+			return fmt.Errorf("invalid sha256 checksum %s of platform %s", checksum, platform)
+		}
+	}
+	return nil
 }
 
 type ObjectConfig struct {
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+const agentPackageBaseURL = "https://resources.koderover.com/dist"
+
+// agentPlatforms are the platforms agent packages are released for
+var agentPlatforms = []string{setting.LinuxAmd64, setting.LinuxArm64, setting.MacOSAmd64, setting.MacOSArm64, setting.WinAmd64}
+
+// PinAgentVersion sets the version the agents of the vms upgrade themselves to, the agents pick it up on their next heartbeat.
+func PinAgentVersion(args *PinAgentVersionArgs, user string, logger *zap.SugaredLogger) error {
+	version := strings.TrimPrefix(args.Version, "v")
+
+	vms, err := commonrepo.NewPrivateKeyColl().List(&commonrepo.PrivateKeyArgs{})
+	if err != nil {
+		logger.Errorf("failed to list vms, error: %s", err)
+		return e.ErrUpgradeZadigVMAgent.AddErr(fmt.Errorf("failed to list vms, error: %s", err))
+	}
+
+	vmIDs := make(map[string]bool)
+	for _, id := range args.VMIDs {
+		vmIDs[id] = true
+	}
+
+	pinned := make([]*commonmodels.PrivateKey, 0)
+	for _, vm := range vms {
+		if vm.Type != setting.NewVMType || vm.Agent == nil {
+			continue
+		}
+		if !vmIDs[vm.ID.Hex()] && (args.Label == "" || vm.Label != args.Label) {
+			continue
+		}
+		// the agents refuse packages without checksum, no vm is pinned if one of them could not upgrade
+		if version != "" && vm.VMInfo != nil {
+			platform := agentPlatform(vm.VMInfo)
+			if _, ok := args.Checksums[platform]; !ok {
+				return e.ErrUpgradeZadigVMAgent.AddErr(fmt.Errorf("checksum of platform %s of vm %s is required", platform, vm.Name))
+			}
+		}
+		pinned = append(pinned, vm)
+	}
+
+	for _, vm := range pinned {
+		vm.Agent.DesiredVersion = version
+		vm.Agent.DesiredChecksums = args.Checksums
+		vm.Agent.UpgradeStatus = ""
+		vm.Agent.UpgradeError = ""
+		vm.UpdateBy = user
+		vm.UpdateTime = time.Now().Unix()
+		if err := commonrepo.NewPrivateKeyColl().Update(vm.ID.Hex(), vm); err != nil {
+			logger.Errorf("failed to pin vm %s agent version, error: %s", vm.Name, err)
+			return e.ErrUpgradeZadigVMAgent.AddErr(fmt.Errorf("failed to pin vm %s agent version, error: %s", vm.Name, err))
+		}
+	}
+	return nil
+}
+
+// checkAgentUpgrade records the upgrade outcome reported by the agent and tells it to upgrade if it is not on the
+// desired version. A failed upgrade is not advertised again until the version is pinned again.
+func checkAgentUpgrade(vm *commonmodels.PrivateKey, params *HeartbeatParameters, resp *HeartbeatResponse, logger *zap.SugaredLogger) {
+	// agents that do not report their version do not support self-upgrade
+	if params == nil || params.AgentVersion == "" {
+		return
+	}
+	agentVersion := strings.TrimPrefix(params.AgentVersion, "v")
+	vm.Agent.AgentVersion = agentVersion
+
+	desired := vm.Agent.DesiredVersion
+	if desired == "" {
+		return
+	}
+	if agentVersion == desired {
+		if vm.Agent.UpgradeStatus == setting.VMAgentUpgrading {
+			vm.Agent.UpgradeStatus = setting.VMAgentUpgradeSucceeded
+			vm.Agent.UpgradeError = ""
+		}
+		return
+	}
+
+	switch vm.Agent.UpgradeStatus {
+	case setting.VMAgentUpgradeFailed:
+		return
+	case setting.VMAgentUpgrading:
+		if strings.TrimPrefix(params.UpgradeVersion, "v") == desired && params.UpgradeError != "" {
+			logger.Warnf("vm %s agent failed to upgrade to %s: %s", vm.Name, desired, params.UpgradeError)
+			vm.Agent.UpgradeStatus = setting.VMAgentUpgradeFailed
+			vm.Agent.UpgradeError = params.UpgradeError
+			return
+		}
+	}
+
+	if vm.VMInfo == nil {
+		return
+	}
+	platform := agentPlatform(vm.VMInfo)
+	url, err := getAgentPackageURL(desired, platform)
+	if err != nil {
+		vm.Agent.UpgradeStatus = setting.VMAgentUpgradeFailed
+		vm.Agent.UpgradeError = err.Error()
+		return
+	}
+
+	vm.Agent.UpgradeStatus = setting.VMAgentUpgrading
+	resp.NeedUpdateAgentVersion = true
+	resp.AgentVersion = desired
+	resp.AgentDownloadURL = url
+	resp.AgentChecksum = vm.Agent.DesiredChecksums[platform]
+}
+
+func getAgentPackageURL(version, platform string) (string, error) {
+	if !isAgentPlatform(platform) {
+		return "", fmt.Errorf("unsupported platform %s", platform)
+	}
+	return fmt.Sprintf("%s/zadig-agent-%s-v%s.tar.gz", agentPackageBaseURL, strings.ReplaceAll(platform, "_", "-"), version), nil
+}
+
+func agentPlatform(info *commonmodels.VMInfo) string {
+	return fmt.Sprintf("%s_%s", info.Platform, info.Architecture)
+}
+
+func isAgentPlatform(platform string) bool {
+	for _, p := range agentPlatforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
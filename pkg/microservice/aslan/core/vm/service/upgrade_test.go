@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/setting"
+)
+
+func TestPinAgentVersionArgsValidate(t *testing.T) {
+	checksum := strings.Repeat("a1", 32)
+	tests := []struct {
+		name    string
+		args    *PinAgentVersionArgs
+		wantErr string
+	}{
+		{
+			name:    "no vms",
+			args:    &PinAgentVersionArgs{Version: "2.1.0", Checksums: map[string]string{setting.LinuxAmd64: checksum}},
+			wantErr: "vm_ids or label is required",
+		},
+		{
+			name: "unpin",
+			args: &PinAgentVersionArgs{Label: "build"},
+		},
+		{
+			name: "pin",
+			args: &PinAgentVersionArgs{VMIDs: []string{"vm"}, Version: "v2.1.0-rc.1", Checksums: map[string]string{setting.LinuxAmd64: checksum}},
+		},
+		{
+			name:    "version with path",
+			args:    &PinAgentVersionArgs{Label: "build", Version: "2.1.0/../../evil", Checksums: map[string]string{setting.LinuxAmd64: checksum}},
+			wantErr: "invalid version 2.1.0/../../evil",
+		},
+		{
+			name:    "no checksums",
+			args:    &PinAgentVersionArgs{Label: "build", Version: "2.1.0"},
+			wantErr: "checksums are required",
+		},
+		{
+			name:    "unsupported platform",
+			args:    &PinAgentVersionArgs{Label: "build", Version: "2.1.0", Checksums: map[string]string{"plan9_amd64": checksum}},
+			wantErr: "unsupported platform plan9_amd64",
+		},
+		{
+			name:    "invalid checksum",
+			args:    &PinAgentVersionArgs{Label: "build", Version: "2.1.0", Checksums: map[string]string{setting.LinuxAmd64: "abc"}},
+			wantErr: "invalid sha256 checksum abc of platform linux_amd64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGetAgentPackageURL(t *testing.T) {
+	url, err := getAgentPackageURL("2.1.0", setting.WinAmd64)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://resources.koderover.com/dist/zadig-agent-windows-amd64-v2.1.0.tar.gz", url)
+
+	_, err = getAgentPackageURL("2.1.0", "plan9_amd64")
+	assert.EqualError(t, err, "unsupported platform plan9_amd64")
+}
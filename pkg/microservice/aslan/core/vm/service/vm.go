@@ -294,7 +294,7 @@ func RegisterAgent(args *RegisterAgentRequest, logger *zap.SugaredLogger) (*Regi
 		Token: args.Token,
 	})
 	if err != nil {
-		logger.Errorf("failed to find vm by token %s, error: %s", args.Token, err)
+		logger.Errorf("failed to find vm by token, error: %s", err)
 		return nil, err
 	}
 
@@ -346,7 +346,7 @@ func VerifyAgent(args *VerifyAgentRequest, logger *zap.SugaredLogger) (*VerifyAg
 		Token: args.Token,
 	})
 	if err != nil {
-		logger.Errorf("failed to find vm by token %s, error: %s", args.Token, err)
+		logger.Errorf("failed to find vm by token, error: %s", err)
 		return nil, err
 	}
 
@@ -365,7 +365,7 @@ func Heartbeat(args *HeartbeatRequest, logger *zap.SugaredLogger) (*HeartbeatRes
 		Token: args.Token,
 	})
 	if err != nil {
-		logger.Errorf("failed to find vm by token %s, error: %s", args.Token, err)
+		logger.Errorf("failed to find vm by token, error: %s", err)
 		return nil, err
 	}
 
@@ -395,6 +395,7 @@ func Heartbeat(args *HeartbeatRequest, logger *zap.SugaredLogger) (*HeartbeatRes
 		return nil, fmt.Errorf("zadig server vm %s agent is nil in db", args.Token)
 	}
 	vm.Agent.LastHeartbeatTime = time.Now().Unix()
+	checkAgentUpgrade(vm, args.Parameters, resp, logger)
 
 	err = commonrepo.NewPrivateKeyColl().Update(vm.ID.Hex(), vm)
 	if err != nil {
@@ -458,8 +459,8 @@ func PollingAgentJob(token string, retry int, logger *zap.SugaredLogger) (*Polli
 		Token: token,
 	})
 	if err != nil {
-		logger.Errorf("failed to find vm by token %s, error: %s", token, err)
-		return nil, fmt.Errorf("failed to find vm by token %s, error: %s", token, err)
+		logger.Errorf("failed to find vm by token, error: %s", err)
+		return nil, fmt.Errorf("failed to find vm by token, error: %s", err)
 	}
 
 	// check vm status
@@ -530,8 +531,8 @@ func ReportAgentJob(args *ReportJobArgs, logger *zap.SugaredLogger) (*ReportAgen
 		Token: args.Token,
 	})
 	if err != nil {
-		logger.Errorf("failed to find vm by token %s, error: %s", args.Token, err)
-		return nil, fmt.Errorf("failed to find vm by token %s, error: %s", args.Token, err)
+		logger.Errorf("failed to find vm by token, error: %s", err)
+		return nil, fmt.Errorf("failed to find vm by token, error: %s", err)
 	}
 
 	// update job
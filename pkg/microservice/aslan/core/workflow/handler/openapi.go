@@ -15,12 +15,19 @@ import (
 	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
 	e "github.com/koderover/zadig/v2/pkg/tool/errors"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/types"
 )
 
 func CreateCustomWorkflowTask(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
 	args := new(workflowservice.OpenAPICreateCustomWorkflowTaskArgs)
 	data, err := c.GetRawData()
 	if err != nil {
@@ -37,7 +44,25 @@ func CreateCustomWorkflowTask(c *gin.Context) {
 		return
 	}
 
-	ctx.Resp, ctx.Err = workflowservice.CreateCustomWorkflowTask(ctx.UserName, args, ctx.Logger)
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[args.ProjectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[args.ProjectName].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[args.ProjectName].Workflow.Execute {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, args.ProjectName, types.ResourceTypeWorkflow, args.WorkflowName, types.WorkflowActionRun)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflowservice.CreateCustomWorkflowTask(ctx.UserName, ctx.IdentityType, args, ctx.Logger)
 }
 
 func OpenAPICreateWorkflowView(c *gin.Context) {
@@ -349,7 +374,7 @@ func OpenAPIApproveStage(c *gin.Context) {
 		return
 	}
 
-	ctx.Err = workflowservice.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, ctx.Logger)
+	ctx.Err = workflowservice.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, ctx.IdentityType, args.Comment, args.TaskID, args.Approve, ctx.Logger)
 }
 
 func generalRequestValidate(c *gin.Context) (string, int64, error) {
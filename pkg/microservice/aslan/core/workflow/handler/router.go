@@ -218,6 +218,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		workflowV4.GET("/bluegreen/:envName/:serviceName", GetBlueGreenServiceK8sServiceYaml)
 		workflowV4.GET("/jenkins/:id/:jobName", GetJenkinsJobParams)
 		workflowV4.POST("/sql/validate", ValidateSQL)
+		workflowV4.GET("/codesource", GetWorkflowCodeSource)
+		workflowV4.PUT("/codesource", UpdateWorkflowCodeSource)
+		workflowV4.POST("/codesource/sync", SyncWorkflowCode)
+		workflowV4.GET("/codesource/plan", PlanWorkflowCode)
+		workflowV4.GET("/codesource/drift", GetWorkflowCodeDrifts)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -252,6 +257,19 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		view.PUT("", UpdateWorkflowView)
 	}
 
+	// ---------------------------------------------------------------------------------------
+	// notification template 接口
+	// ---------------------------------------------------------------------------------------
+	notificationTemplate := router.Group("notification/template")
+	{
+		notificationTemplate.GET("", ListNotificationTemplates)
+		notificationTemplate.POST("", CreateNotificationTemplate)
+		notificationTemplate.POST("/preview", PreviewNotificationTemplate)
+		notificationTemplate.GET("/:id", GetNotificationTemplate)
+		notificationTemplate.PUT("/:id", UpdateNotificationTemplate)
+		notificationTemplate.DELETE("/:id", DeleteNotificationTemplate)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// plugin repo 接口
 	// ---------------------------------------------------------------------------------------
@@ -27,6 +27,8 @@ import (
 
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/webhook"
 	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+	"github.com/koderover/zadig/v2/pkg/tool/gitea"
 	"github.com/koderover/zadig/v2/pkg/tool/gitee"
 )
 
@@ -39,12 +41,17 @@ func ProcessWebHook(c *gin.Context) {
 		ctx.Err = err
 		return
 	}
-	if github.WebHookType(c.Request) != "" {
+	// gitea sends the X-GitHub-Event header as well, so it must be checked before github
+	if gitea.HookEventType(c.Request) != "" {
+		ctx.Err = webhook.ProcessGiteaHook(payload, c.Request, ctx.RequestID, ctx.Logger)
+	} else if github.WebHookType(c.Request) != "" {
 		ctx.Err = processGithub(payload, c.Request, ctx.RequestID, ctx.Logger)
 	} else if gitlab.HookEventType(c.Request) != "" {
 		ctx.Err = webhook.ProcessGitlabHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	} else if gitee.HookEventType(c.Request) != "" {
 		ctx.Err = webhook.ProcessGiteeHook(payload, c.Request, ctx.RequestID, ctx.Logger)
+	} else if bitbucket.HookEventType(c.Request) != "" {
+		ctx.Err = webhook.ProcessBitbucketHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	} else {
 		ctx.Err = webhook.ProcessGerritHook(payload, c.Request, ctx.RequestID, ctx.Logger)
 	}
@@ -85,5 +92,17 @@ func processGithub(payload []byte, req *http.Request, requestID string, log *zap
 		log.Errorf("error happens to trigger workflowV4 for github %v", err)
 		errs = multierror.Append(errs, err)
 	}
+	// webhooks for preview environments
+	err = webhook.ProcessGithubWebHookForPreviewEnv(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to trigger preview env for github %v", err)
+		errs = multierror.Append(errs, err)
+	}
+	// webhooks for workflow as code
+	err = webhook.ProcessGithubWebHookForWorkflowCode(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to sync workflow code for github %v", err)
+		errs = multierror.Append(errs, err)
+	}
 	return errs.ErrorOrNil()
 }
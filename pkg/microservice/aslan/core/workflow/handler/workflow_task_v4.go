@@ -95,9 +95,10 @@ func CreateWorkflowTaskV4(c *gin.Context) {
 	}
 
 	ctx.Resp, ctx.Err = workflow.CreateWorkflowTaskV4(&workflow.CreateWorkflowTaskV4Args{
-		Name:    ctx.UserName,
-		Account: ctx.Account,
-		UserID:  ctx.UserID,
+		Name:         ctx.UserName,
+		Account:      ctx.Account,
+		UserID:       ctx.UserID,
+		IdentityType: ctx.IdentityType,
 	}, args, ctx.Logger)
 }
 
@@ -519,7 +520,7 @@ func ApproveStage(c *gin.Context) {
 		return
 	}
 
-	ctx.Err = workflow.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, ctx.Logger)
+	ctx.Err = workflow.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, ctx.IdentityType, args.Comment, args.TaskID, args.Approve, ctx.Logger)
 }
 
 func GetWorkflowV4ArtifactFileContent(c *gin.Context) {
@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/config"
+	gitservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
+)
+
+// emptyCommitHash is the from hash of a ref created on bitbucket data center
+const emptyCommitHash = "0000000000000000000000000000000000000000"
+
+// bitbucketPushEvent is a branch or a tag pushed to bitbucket cloud or bitbucket data center,
+// the owner is the workspace on bitbucket cloud and the project key on bitbucket data center.
+type bitbucketPushEvent struct {
+	source    string
+	owner     string
+	repo      string
+	ref       string
+	before    string
+	after     string
+	committer string
+}
+
+func (e *bitbucketPushEvent) isTag() bool {
+	return strings.HasPrefix(e.ref, "refs/tags/")
+}
+
+// bitbucketPullRequestEvent is a pull request opened or updated on bitbucket cloud or bitbucket data center
+type bitbucketPullRequestEvent struct {
+	source       string
+	owner        string
+	repo         string
+	number       int
+	sourceBranch string
+	targetBranch string
+	headCommit   string
+	author       string
+}
+
+func ProcessBitbucketHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	eventKey := bitbucket.HookEventType(req)
+	if eventKey == bitbucket.EventDiagnosticsPing {
+		return nil
+	}
+
+	if err := bitbucket.ValidateSignature(req.Header.Get(bitbucket.SignatureHeader), payload, gitservice.GetHookSecret()); err != nil {
+		log.Errorf("Failed to validate bitbucket webhook signature, err: %s", err)
+		return err
+	}
+
+	event, err := bitbucket.ParseHook(eventKey, payload)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		log.Infof("bitbucket event %s is skipped", eventKey)
+		return nil
+	}
+
+	events, err := convertBitbucketEvent(event, log)
+	if err != nil {
+		return err
+	}
+
+	baseURI := config.SystemAddress()
+	errorList := &multierror.Error{}
+	for _, ev := range events {
+		if err := TriggerWorkflowV4ByBitbucketEvent(ev, baseURI, requestID, log); err != nil {
+			errorList = multierror.Append(errorList, err)
+		}
+	}
+	return errorList.ErrorOrNil()
+}
+
+// convertBitbucketEvent converts the events of bitbucket cloud and bitbucket data center to the same format,
+// a push may contain several refs so it is converted to one event for each ref.
+func convertBitbucketEvent(event interface{}, log *zap.SugaredLogger) ([]interface{}, error) {
+	var resp []interface{}
+	switch ev := event.(type) {
+	case *bitbucket.PushEvent:
+		if ev.Repository == nil {
+			return nil, fmt.Errorf("repository not found in bitbucket push event")
+		}
+		owner, repo := splitRepoFullName(ev.Repository.FullName)
+		var committer string
+		if ev.Actor != nil {
+			committer = ev.Actor.Nickname
+		}
+		for _, change := range ev.Push.Changes {
+			// the ref is deleted
+			if change.New == nil || change.New.Target == nil {
+				continue
+			}
+			pushEvent := &bitbucketPushEvent{
+				source:    setting.SourceFromBitbucket,
+				owner:     owner,
+				repo:      repo,
+				ref:       "refs/heads/" + change.New.Name,
+				after:     change.New.Target.Hash,
+				committer: committer,
+			}
+			if change.Type() == "tag" {
+				pushEvent.ref = "refs/tags/" + change.New.Name
+			}
+			if change.Old != nil && change.Old.Target != nil {
+				pushEvent.before = change.Old.Target.Hash
+			}
+			resp = append(resp, pushEvent)
+		}
+	case *bitbucket.PullRequestEvent:
+		pr := ev.PullRequest
+		if pr == nil || pr.Source == nil || pr.Destination == nil || pr.Destination.Repository == nil {
+			return nil, fmt.Errorf("pull request not found in bitbucket pull request event")
+		}
+		if pr.State != "OPEN" {
+			log.Infof("bitbucket pull request %d in state %s is skipped", pr.ID, pr.State)
+			return nil, nil
+		}
+		// bitbucket cloud does not expose the refs of pull requests, the source branch must be in the same repository
+		if pr.Source.Repository != nil && pr.Source.Repository.FullName != pr.Destination.Repository.FullName {
+			log.Infof("bitbucket pull request %d from fork %s is skipped", pr.ID, pr.Source.Repository.FullName)
+			return nil, nil
+		}
+		owner, repo := splitRepoFullName(pr.Destination.Repository.FullName)
+		prEvent := &bitbucketPullRequestEvent{
+			source:       setting.SourceFromBitbucket,
+			owner:        owner,
+			repo:         repo,
+			number:       pr.ID,
+			sourceBranch: pr.Source.Branch.Name,
+			targetBranch: pr.Destination.Branch.Name,
+			headCommit:   pr.Source.Commit.Hash,
+		}
+		if pr.Author != nil {
+			prEvent.author = pr.Author.Nickname
+		}
+		resp = append(resp, prEvent)
+	case *bitbucket.ServerRefsChangedEvent:
+		if ev.Repository == nil || ev.Repository.Project == nil {
+			return nil, fmt.Errorf("repository not found in bitbucket refs changed event")
+		}
+		var committer string
+		if ev.Actor != nil {
+			committer = ev.Actor.Name
+		}
+		for _, change := range ev.Changes {
+			if change.Type == bitbucket.ServerRefChangeDeleted || change.Ref == nil {
+				continue
+			}
+			pushEvent := &bitbucketPushEvent{
+				source:    setting.SourceFromBitbucketServer,
+				owner:     ev.Repository.Project.Key,
+				repo:      ev.Repository.Slug,
+				ref:       change.RefID,
+				after:     change.ToHash,
+				committer: committer,
+			}
+			if change.FromHash != emptyCommitHash {
+				pushEvent.before = change.FromHash
+			}
+			resp = append(resp, pushEvent)
+		}
+	case *bitbucket.ServerPullRequestEvent:
+		pr := ev.PullRequest
+		if pr == nil || pr.FromRef == nil || pr.ToRef == nil || pr.ToRef.Repository == nil || pr.ToRef.Repository.Project == nil {
+			return nil, fmt.Errorf("pull request not found in bitbucket pull request event")
+		}
+		if pr.State != "OPEN" {
+			log.Infof("bitbucket pull request %d in state %s is skipped", pr.ID, pr.State)
+			return nil, nil
+		}
+		prEvent := &bitbucketPullRequestEvent{
+			source:       setting.SourceFromBitbucketServer,
+			owner:        pr.ToRef.Repository.Project.Key,
+			repo:         pr.ToRef.Repository.Slug,
+			number:       pr.ID,
+			sourceBranch: pr.FromRef.DisplayID,
+			targetBranch: pr.ToRef.DisplayID,
+			headCommit:   pr.FromRef.LatestCommit,
+		}
+		if pr.Author.User != nil {
+			prEvent.author = pr.Author.User.Name
+		}
+		resp = append(resp, prEvent)
+	}
+	return resp, nil
+}
+
+func splitRepoFullName(fullName string) (string, string) {
+	owner, repo, _ := strings.Cut(fullName, "/")
+	return owner, repo
+}
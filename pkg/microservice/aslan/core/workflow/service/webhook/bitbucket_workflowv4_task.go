@@ -0,0 +1,364 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/bitbucket"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/scmnotify"
+	workflowservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/v2/pkg/types"
+)
+
+type bitbucketEventMatcherForWorkflowV4 interface {
+	Match(*commonmodels.MainHookRepo) (bool, error)
+	GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository
+}
+
+type bitbucketPushEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketPushEvent
+}
+
+func (bpem *bitbucketPushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	ev := bpem.event
+	if hookRepo.Source != ev.source || (hookRepo.RepoOwner+"/"+hookRepo.RepoName) != (ev.owner+"/"+ev.repo) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPush) {
+		return false, nil
+	}
+
+	branch := getBranchFromRef(ev.ref)
+	isRegular := hookRepo.IsRegular
+	if !isRegular && hookRepo.Branch != branch {
+		return false, nil
+	}
+	if isRegular {
+		// Do not use regexp.MustCompile to avoid panic
+		matched, err := regexp.MatchString(hookRepo.Branch, branch)
+		if err != nil || !matched {
+			return false, nil
+		}
+	}
+	hookRepo.Branch = branch
+	hookRepo.Committer = ev.committer
+
+	// the branch is newly created, there is nothing to compare with
+	if ev.before == "" {
+		return true, nil
+	}
+	changedFiles, err := findChangedFilesOfBitbucketEvent(hookRepo.CodehostID, func(cli *bitbucket.Client) ([]string, error) {
+		return cli.ListChangedFiles(ev.owner, ev.repo, ev.before, ev.after)
+	})
+	if err != nil {
+		bpem.log.Warnf("failed to get changes of push event %s/%s %s, err: %s", ev.owner, ev.repo, ev.ref, err)
+		return false, err
+	}
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (bpem *bitbucketPushEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		RepoOwner:     hookRepo.RepoOwner,
+		Branch:        hookRepo.Branch,
+		Source:        hookRepo.Source,
+	}
+}
+
+type bitbucketMergeEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketPullRequestEvent
+}
+
+func (bmem *bitbucketMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	ev := bmem.event
+	if hookRepo.Source != ev.source || (hookRepo.RepoOwner+"/"+hookRepo.RepoName) != (ev.owner+"/"+ev.repo) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPr) {
+		return false, nil
+	}
+
+	isRegular := hookRepo.IsRegular
+	if !isRegular && hookRepo.Branch != ev.targetBranch {
+		return false, nil
+	}
+	if isRegular {
+		matched, err := regexp.MatchString(hookRepo.Branch, ev.targetBranch)
+		if err != nil || !matched {
+			return false, nil
+		}
+	}
+	hookRepo.Branch = ev.targetBranch
+	hookRepo.Committer = ev.author
+
+	changedFiles, err := findChangedFilesOfBitbucketEvent(hookRepo.CodehostID, func(cli *bitbucket.Client) ([]string, error) {
+		return cli.ListPullRequestChangedFiles(ev.owner, ev.repo, ev.number)
+	})
+	if err != nil {
+		bmem.log.Warnf("failed to get changes of pull request %s/%s#%d, err: %s", ev.owner, ev.repo, ev.number, err)
+		return false, err
+	}
+	bmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
+
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (bmem *bitbucketMergeEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	repo := &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		PR:            bmem.event.number,
+		Source:        hookRepo.Source,
+	}
+	// bitbucket cloud does not expose the refs of pull requests, checkout the source branch instead
+	if bmem.event.source == setting.SourceFromBitbucket {
+		repo.CheckoutRef = "refs/heads/" + bmem.event.sourceBranch
+	}
+	return repo
+}
+
+type bitbucketTagEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketPushEvent
+}
+
+func (btem *bitbucketTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	ev := btem.event
+	if hookRepo.Source != ev.source || (hookRepo.RepoOwner+"/"+hookRepo.RepoName) != (ev.owner+"/"+ev.repo) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventTag) {
+		return false, nil
+	}
+
+	hookRepo.Tag = getTagFromRef(ev.ref)
+	hookRepo.Committer = ev.committer
+
+	return true, nil
+}
+
+func (btem *bitbucketTagEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		Tag:           hookRepo.Tag,
+		Source:        hookRepo.Source,
+	}
+}
+
+func createBitbucketEventMatcherForWorkflowV4(event interface{}, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) bitbucketEventMatcherForWorkflowV4 {
+	switch evt := event.(type) {
+	case *bitbucketPushEvent:
+		if evt.isTag() {
+			return &bitbucketTagEventMatcherForWorkflowV4{
+				workflow: workflow,
+				log:      log,
+				event:    evt,
+			}
+		}
+		return &bitbucketPushEventMatcherForWorkflowV4{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		}
+	case *bitbucketPullRequestEvent:
+		return &bitbucketMergeEventMatcherForWorkflowV4{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		}
+	}
+
+	return nil
+}
+
+func findChangedFilesOfBitbucketEvent(codehostID int, listFunc func(cli *bitbucket.Client) ([]string, error)) ([]string, error) {
+	detail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
+	}
+
+	cli := bitbucket.NewClient(detail.Type, detail.Address, detail.Username, detail.AccessToken, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	return listFunc(cli)
+}
+
+func TriggerWorkflowV4ByBitbucketEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
+	if err != nil {
+		errMsg := fmt.Sprintf("list workflow v4 error: %v", err)
+		log.Error(errMsg)
+		return fmt.Errorf(errMsg)
+	}
+
+	mErr := &multierror.Error{}
+	var hookPayload *commonmodels.HookPayload
+	var notification *commonmodels.Notification
+
+	for _, workflow := range workflows {
+		if workflow.HookCtls == nil {
+			continue
+		}
+		for _, item := range workflow.HookCtls {
+			if !item.Enabled {
+				continue
+			}
+			matcher := createBitbucketEventMatcherForWorkflowV4(event, workflow, log)
+			if matcher == nil {
+				errMsg := fmt.Sprintf("unsupported bitbucket event %T", event)
+				log.Error(errMsg)
+				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				continue
+			}
+			matches, err := matcher.Match(item.MainRepo)
+			if err != nil {
+				mErr = multierror.Append(mErr, err)
+			}
+			if !matches {
+				continue
+			}
+
+			log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
+			eventRepo := matcher.GetHookRepo(item.MainRepo)
+
+			autoCancelOpt := &AutoCancelOpt{
+				TaskType:     config.WorkflowType,
+				MainRepo:     item.MainRepo,
+				AutoCancel:   item.AutoCancel,
+				WorkflowName: workflow.Name,
+			}
+			var mergeRequestID, commitID, ref, eventType string
+			var prID int
+			switch ev := event.(type) {
+			case *bitbucketPullRequestEvent:
+				eventType = EventTypePR
+				mergeRequestID = strconv.Itoa(ev.number)
+				commitID = ev.headCommit
+				prID = ev.number
+				autoCancelOpt.Type = eventType
+				autoCancelOpt.CommitID = commitID
+				autoCancelOpt.MergeRequestID = mergeRequestID
+				hookPayload = &commonmodels.HookPayload{
+					Owner:          eventRepo.RepoOwner,
+					Repo:           eventRepo.RepoName,
+					CodehostID:     item.MainRepo.CodehostID,
+					Branch:         eventRepo.Branch,
+					Ref:            commitID,
+					IsPr:           true,
+					MergeRequestID: mergeRequestID,
+					CommitID:       commitID,
+					EventType:      eventType,
+				}
+			case *bitbucketPushEvent:
+				if ev.isTag() {
+					eventType = EventTypeTag
+					break
+				}
+				eventType = EventTypePush
+				ref = ev.ref
+				commitID = ev.after
+				autoCancelOpt.Type = eventType
+				autoCancelOpt.CommitID = commitID
+				autoCancelOpt.Ref = ref
+				hookPayload = &commonmodels.HookPayload{
+					Owner:      eventRepo.RepoOwner,
+					Repo:       eventRepo.RepoName,
+					CodehostID: item.MainRepo.CodehostID,
+					Branch:     eventRepo.Branch,
+					Ref:        ref,
+					IsPr:       false,
+					CommitID:   commitID,
+					EventType:  eventType,
+				}
+			}
+			if autoCancelOpt.Type != "" {
+				err := AutoCancelWorkflowV4Task(autoCancelOpt, log)
+				if err != nil {
+					log.Errorf("failed to auto cancel workflowV4 task when receive event %v due to %v ", event, err)
+					mErr = multierror.Append(mErr, err)
+				}
+
+				if autoCancelOpt.Type == EventTypePR && notification == nil {
+					notification, err = scmnotify.NewService().SendInitWebhookComment(
+						item.MainRepo, prID, baseURI, false, false, false, true, log,
+					)
+					if err != nil {
+						log.Errorf("failed to init webhook comment due to %s", err)
+						mErr = multierror.Append(mErr, err)
+					}
+				}
+			}
+			if err := job.MergeArgs(workflow, item.WorkflowArg); err != nil {
+				errMsg := fmt.Sprintf("merge workflow args error: %v", err)
+				log.Error(errMsg)
+				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				continue
+			}
+			if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
+				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
+				log.Error(errMsg)
+				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				continue
+			}
+			if notification != nil {
+				workflow.NotificationID = notification.ID.Hex()
+			}
+			workflow.HookPayload = hookPayload
+			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
+				Name: setting.WebhookTaskCreator,
+			}, workflow, log); err != nil {
+				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
+				log.Error(errMsg)
+				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+			} else {
+				if workflow.HookPayload != nil && workflow.HookPayload.IsPr {
+					// Updating the commit status in the git repository, this will not cause the function to return error if this function call fails
+					if err := scmnotify.NewService().CreateGitCheckForWorkflowV4(workflow, resp.TaskID, log); err != nil {
+						log.Warnf("Failed to create bitbucket commit status for custom workflow %s, taskID: %d the error is: %s", workflow.Name, resp.TaskID, err)
+					}
+				}
+				log.Infof("succeed to create task %v", resp)
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/config"
+	gitservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/v2/pkg/tool/gitea"
+)
+
+func ProcessGiteaHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	if err := gitea.ValidateSignature(req.Header.Get(gitea.SignatureHeader), payload, gitservice.GetHookSecret()); err != nil {
+		log.Errorf("Failed to validate gitea webhook signature, err: %s", err)
+		return err
+	}
+
+	eventType := gitea.HookEventType(req)
+	event, err := gitea.ParseHook(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	switch ev := event.(type) {
+	case *gitea.PushEvent:
+		// the ref is deleted
+		if ev.After == emptyCommitHash {
+			return nil
+		}
+	case *gitea.PullRequestEvent:
+		if ev.PullRequest == nil || ev.PullRequest.Base == nil || ev.PullRequest.Head == nil {
+			log.Warnf("pull request not found in gitea event %s", eventType)
+			return nil
+		}
+		if ev.Action != gitea.PullRequestActionOpened && ev.Action != gitea.PullRequestActionReopened && ev.Action != gitea.PullRequestActionSynchronized {
+			log.Infof("gitea pull request action %s is skipped", ev.Action)
+			return nil
+		}
+	default:
+		log.Infof("gitea event %s is skipped", eventType)
+		return nil
+	}
+
+	return TriggerWorkflowV4ByGiteaEvent(event, config.SystemAddress(), requestID, log)
+}
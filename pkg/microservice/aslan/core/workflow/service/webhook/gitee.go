@@ -65,6 +65,12 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 	baseURI := config.SystemAddress()
 	var errorList = &multierror.Error{}
 	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	appendErr := func(err error) {
+		errLock.Lock()
+		defer errLock.Unlock()
+		errorList = multierror.Append(errorList, err)
+	}
 
 	switch event := event.(type) {
 	case *gitee.PushEvent:
@@ -88,8 +94,8 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -97,8 +103,8 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -106,11 +112,15 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	case *gitee.PullRequestEvent:
+		// the preview environments are deleted when the pull request is closed
+		if event.Action == "close" || event.Action == "merge" {
+			return TriggerPreviewEnvByGiteeEvent(event, baseURI, requestID, log)
+		}
 		if event.Action != "open" && event.Action != "update" {
 			return fmt.Errorf("action %s is skipped", event.Action)
 		}
@@ -123,8 +133,8 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -132,16 +142,24 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 		//workflowv4 webhook
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
+			}
+		}()
+		//preview env webhook
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := TriggerPreviewEnvByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	case *gitee.TagPushEvent:
@@ -149,8 +167,8 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -158,16 +176,16 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 		//workflowv4 webhook
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowV4ByGiteeEvent(event, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	}
@@ -561,6 +561,102 @@ func ProcessGithubWebHookForWorkflowV4(payload []byte, req *http.Request, reques
 	return nil
 }
 
+func ProcessGithubWebHookForPreviewEnv(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	forwardedProto := req.Header.Get("X-Forwarded-Proto")
+	forwardedHost := req.Header.Get("X-Forwarded-Host")
+	baseURI := fmt.Sprintf("%s://%s", forwardedProto, forwardedHost)
+
+	if github.WebHookType(req) != "pull_request" {
+		return nil
+	}
+
+	err := validateSecret(payload, []byte(gitservice.GetHookSecret()), req)
+	if err != nil {
+		return err
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(req), payload)
+	if err != nil {
+		return err
+	}
+
+	et, ok := event.(*github.PullRequestEvent)
+	if !ok || (et.GetAction() != "opened" && et.GetAction() != "synchronize" && et.GetAction() != "reopened" && et.GetAction() != "closed") {
+		return nil
+	}
+	err = triggerPreviewEnvByPullRequest(&previewEnvPullRequest{
+		pathWithNamespace: et.GetRepo().GetFullName(),
+		targetBranch:      et.GetPullRequest().GetBase().GetRef(),
+		prID:              et.GetPullRequest().GetNumber(),
+		commitID:          et.GetPullRequest().GetHead().GetSHA(),
+		closed:            et.GetAction() == "closed",
+		diffFunc: func(codehostID int) ([]string, error) {
+			return findChangedFilesOfPullRequest(et, codehostID)
+		},
+	}, baseURI, requestID, log)
+	if err != nil {
+		log.Errorf("triggerPreviewEnvByPullRequest error: %v", err)
+		return e.ErrGithubWebHook.AddErr(err)
+	}
+	return nil
+}
+
+func ProcessGithubWebHookForWorkflowCode(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	forwardedProto := req.Header.Get("X-Forwarded-Proto")
+	forwardedHost := req.Header.Get("X-Forwarded-Host")
+	baseURI := fmt.Sprintf("%s://%s", forwardedProto, forwardedHost)
+
+	hookType := github.WebHookType(req)
+	if hookType != "push" && hookType != "pull_request" {
+		return nil
+	}
+
+	err := validateSecret(payload, []byte(gitservice.GetHookSecret()), req)
+	if err != nil {
+		return err
+	}
+
+	event, err := github.ParseWebHook(hookType, payload)
+	if err != nil {
+		return err
+	}
+
+	switch et := event.(type) {
+	case *github.PushEvent:
+		if et.GetDeleted() {
+			return nil
+		}
+		err = triggerWorkflowCodeByEvent(&workflowCodeEvent{
+			source:            setting.SourceFromGithub,
+			pathWithNamespace: et.GetRepo().GetFullName(),
+			branch:            getBranchFromRef(et.GetRef()),
+			commitID:          et.GetAfter(),
+			diffFunc: func(codehostID int) ([]string, error) {
+				return pushEventCommitsFiles(et), nil
+			},
+		}, baseURI, log)
+	case *github.PullRequestEvent:
+		if et.GetAction() != "opened" && et.GetAction() != "synchronize" && et.GetAction() != "reopened" {
+			return nil
+		}
+		err = triggerWorkflowCodeByEvent(&workflowCodeEvent{
+			source:            setting.SourceFromGithub,
+			pathWithNamespace: et.GetRepo().GetFullName(),
+			branch:            et.GetPullRequest().GetBase().GetRef(),
+			commitID:          et.GetPullRequest().GetHead().GetSHA(),
+			prID:              et.GetPullRequest().GetNumber(),
+			diffFunc: func(codehostID int) ([]string, error) {
+				return findChangedFilesOfPullRequest(et, codehostID)
+			},
+		}, baseURI, log)
+	}
+	if err != nil {
+		log.Errorf("triggerWorkflowCodeByEvent error: %v", err)
+		return e.ErrGithubWebHook.AddErr(err)
+	}
+	return nil
+}
+
 const (
 	EventTypePR   = "pr"
 	EventTypePush = "push"
@@ -101,6 +101,12 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 
 	//触发工作流webhook和测试管理webhook
 	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	appendErr := func(err error) {
+		errLock.Lock()
+		defer errLock.Unlock()
+		errorList = multierror.Append(errorList, err)
+	}
 
 	if pushEvent != nil {
 		//add webhook user
@@ -119,8 +125,8 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -128,8 +134,8 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerPipelineByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerPipelineByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -137,24 +143,32 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := TriggerScanningByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerScanningByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowV4ByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGitlabEvent(pushEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowCodeByGitlabEvent(pushEvent, baseURI, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	}
@@ -164,8 +178,8 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -173,8 +187,8 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerPipelineByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerPipelineByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -182,24 +196,40 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := TriggerScanningByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := TriggerWorkflowV4ByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerScanningByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerPreviewEnvByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGitlabEvent(mergeEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowCodeByGitlabEvent(mergeEvent, baseURI, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	}
@@ -209,8 +239,8 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
@@ -218,24 +248,24 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerTestByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerTestByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerScanningByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerScanningByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err = TriggerWorkflowV4ByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
-				errorList = multierror.Append(errorList, err)
+			if err := TriggerWorkflowV4ByGitlabEvent(tagEvent, baseURI, requestID, log); err != nil {
+				appendErr(err)
 			}
 		}()
 	}
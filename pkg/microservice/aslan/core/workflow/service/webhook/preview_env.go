@@ -0,0 +1,274 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	environmentservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/environment/service"
+	workflowservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/gitee"
+	"github.com/koderover/zadig/v2/pkg/types"
+)
+
+// previewEnvPullRequest is the pull request event of any code host which triggers the preview environments
+type previewEnvPullRequest struct {
+	// pathWithNamespace is the full name of the target repo, e.g. owner/repo
+	pathWithNamespace string
+	targetBranch      string
+	prID              int
+	commitID          string
+	closed            bool
+	// diffFunc returns the changed files of the pull request
+	diffFunc func(codehostID int) ([]string, error)
+}
+
+// triggerPreviewEnvByPullRequest creates or deletes the preview environments of the base environments whose policy matches the pull request
+func triggerPreviewEnvByPullRequest(pr *previewEnvPullRequest, baseURI, requestID string, log *zap.SugaredLogger) error {
+	baseEnvs, err := commonrepo.NewProductColl().ListPreviewEnvBases()
+	if err != nil {
+		return fmt.Errorf("failed to list the base environments of preview environments, err: %w", err)
+	}
+
+	mErr := &multierror.Error{}
+	for _, baseEnv := range baseEnvs {
+		for _, repo := range baseEnv.PreviewEnvPolicy.Repos {
+			if repo.MainRepo == nil || !checkRepoNamespaceMatch(repo.MainRepo, pr.pathWithNamespace) || !matchPreviewEnvBranch(repo.MainRepo, pr.targetBranch) {
+				continue
+			}
+			mainRepo := *repo.MainRepo
+			mainRepo.Branch = pr.targetBranch
+			mainRepo.Revision = pr.commitID
+
+			if pr.closed {
+				if err := environmentservice.DeletePreviewEnvsByPR(baseEnv, &mainRepo, pr.prID, baseURI, requestID, log); err != nil {
+					mErr = multierror.Append(mErr, err)
+				}
+				break
+			}
+
+			changedFiles, err := pr.diffFunc(mainRepo.CodehostID)
+			if err != nil {
+				log.Warnf("failed to get the changed files of pr %d in %s, err: %s", pr.prID, pr.pathWithNamespace, err)
+				mErr = multierror.Append(mErr, err)
+				continue
+			}
+			services := make([]string, 0)
+			for _, svc := range repo.Services {
+				for _, file := range changedFiles {
+					if MatchFolders(svc.MatchFolders).ContainsFile(file) {
+						services = append(services, svc.ServiceName)
+						break
+					}
+				}
+			}
+			if len(services) == 0 {
+				log.Infof("no service of environment %s/%s is changed by pr %d in %s", baseEnv.ProductName, baseEnv.EnvName, pr.prID, pr.pathWithNamespace)
+				continue
+			}
+
+			previewEnv, err := environmentservice.CreateOrUpdatePreviewEnv(baseEnv, &environmentservice.PreviewEnvArgs{
+				MainRepo: &mainRepo,
+				PrID:     pr.prID,
+				CommitID: pr.commitID,
+				Services: services,
+				BaseURI:  baseURI,
+			}, requestID, log)
+			if err != nil {
+				log.Errorf("failed to create preview environment of %s/%s for pr %d, err: %s", baseEnv.ProductName, baseEnv.EnvName, pr.prID, err)
+				mErr = multierror.Append(mErr, err)
+				break
+			}
+
+			if err := triggerPreviewEnvWorkflow(repo.Workflow, previewEnv, services, &mainRepo, pr, log); err != nil {
+				log.Errorf("failed to deploy preview environment %s/%s for pr %d, err: %s", previewEnv.ProductName, previewEnv.EnvName, pr.prID, err)
+				mErr = multierror.Append(mErr, err)
+			}
+			break
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// triggerPreviewEnvWorkflow runs the workflow configured in the preview env policy to build the changed services
+// at the commit of the pull request and deploy them to the preview environment
+func triggerPreviewEnvWorkflow(workflowName string, previewEnv *commonmodels.Product, services []string, mainRepo *commonmodels.MainHookRepo, pr *previewEnvPullRequest, log *zap.SugaredLogger) error {
+	if workflowName == "" {
+		return nil
+	}
+
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		return fmt.Errorf("failed to find workflow %s, err: %w", workflowName, err)
+	}
+	if workflow.Project != previewEnv.ProductName {
+		return fmt.Errorf("workflow %s does not belong to project %s", workflowName, previewEnv.ProductName)
+	}
+
+	// the services changed after the preview environment is created are not deployed in it
+	deployServices := make([]string, 0)
+	envServices := previewEnv.GetServiceMap()
+	for _, service := range services {
+		if _, ok := envServices[service]; ok {
+			deployServices = append(deployServices, service)
+		} else {
+			log.Infof("service %s is not deployed in preview environment %s/%s, skip it", service, previewEnv.ProductName, previewEnv.EnvName)
+		}
+	}
+	if len(deployServices) == 0 {
+		return nil
+	}
+
+	if err := setPreviewEnvWorkflowArgs(workflow, previewEnv.EnvName, deployServices); err != nil {
+		return fmt.Errorf("failed to set the args of workflow %s, err: %w", workflowName, err)
+	}
+	eventRepo := &types.Repository{
+		Source:        mainRepo.Source,
+		CodehostID:    mainRepo.CodehostID,
+		RepoOwner:     mainRepo.RepoOwner,
+		RepoNamespace: mainRepo.GetRepoNamespace(),
+		RepoName:      mainRepo.RepoName,
+		Branch:        pr.targetBranch,
+		PR:            pr.prID,
+	}
+	if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
+		return fmt.Errorf("failed to merge the pull request into workflow %s, err: %w", workflowName, err)
+	}
+	workflow.HookPayload = &commonmodels.HookPayload{
+		Owner:          mainRepo.RepoOwner,
+		Repo:           mainRepo.RepoName,
+		Branch:         pr.targetBranch,
+		IsPr:           true,
+		MergeRequestID: strconv.Itoa(pr.prID),
+		CommitID:       pr.commitID,
+		CodehostID:     mainRepo.CodehostID,
+		EventType:      EventTypePR,
+	}
+
+	resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
+		Name: setting.WebhookTaskCreator,
+	}, workflow, log)
+	if err != nil {
+		return err
+	}
+	log.Infof("workflow task %s/%d is created for preview environment %s/%s", workflowName, resp.TaskID, previewEnv.ProductName, previewEnv.EnvName)
+	return nil
+}
+
+// setPreviewEnvWorkflowArgs limits the build and deploy jobs of the workflow to the changed services,
+// and points the deploy jobs to the preview environment
+func setPreviewEnvWorkflowArgs(workflow *commonmodels.WorkflowV4, envName string, services []string) error {
+	serviceSet := sets.NewString(services...)
+	for _, stage := range workflow.Stages {
+		for _, workflowJob := range stage.Jobs {
+			switch workflowJob.JobType {
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(workflowJob.Spec, spec); err != nil {
+					return err
+				}
+				builds := make([]*commonmodels.ServiceAndBuild, 0)
+				for _, build := range spec.ServiceAndBuilds {
+					if serviceSet.Has(build.ServiceName) {
+						builds = append(builds, build)
+					}
+				}
+				spec.ServiceAndBuilds = builds
+				workflowJob.Spec = spec
+			case config.JobZadigDeploy:
+				spec := &commonmodels.ZadigDeployJobSpec{}
+				if err := commonmodels.IToi(workflowJob.Spec, spec); err != nil {
+					return err
+				}
+				spec.Env = envName
+				spec.Production = false
+				serviceAndImages := make([]*commonmodels.ServiceAndImage, 0)
+				for _, serviceAndImage := range spec.ServiceAndImages {
+					if serviceSet.Has(serviceAndImage.ServiceName) {
+						serviceAndImages = append(serviceAndImages, serviceAndImage)
+					}
+				}
+				spec.ServiceAndImages = serviceAndImages
+				deployServices := make([]*commonmodels.DeployService, 0)
+				for _, service := range spec.Services {
+					if serviceSet.Has(service.ServiceName) {
+						deployServices = append(deployServices, service)
+					}
+				}
+				spec.Services = deployServices
+				workflowJob.Spec = spec
+			}
+		}
+	}
+	return nil
+}
+
+func TriggerPreviewEnvByGitlabEvent(event *gitlab.MergeEvent, baseURI, requestID string, log *zap.SugaredLogger) error {
+	action := event.ObjectAttributes.Action
+	if action != "open" && action != "reopen" && action != "update" && action != "close" && action != "merge" {
+		return nil
+	}
+	return triggerPreviewEnvByPullRequest(&previewEnvPullRequest{
+		pathWithNamespace: event.ObjectAttributes.Target.PathWithNamespace,
+		targetBranch:      event.ObjectAttributes.TargetBranch,
+		prID:              event.ObjectAttributes.IID,
+		commitID:          event.ObjectAttributes.LastCommit.ID,
+		closed:            action == "close" || action == "merge",
+		diffFunc: func(codehostID int) ([]string, error) {
+			return findChangedFilesOfMergeRequest(event, codehostID)
+		},
+	}, baseURI, requestID, log)
+}
+
+func TriggerPreviewEnvByGiteeEvent(event *gitee.PullRequestEvent, baseURI, requestID string, log *zap.SugaredLogger) error {
+	if event.Action != "open" && event.Action != "update" && event.Action != "close" && event.Action != "merge" {
+		return nil
+	}
+	return triggerPreviewEnvByPullRequest(&previewEnvPullRequest{
+		pathWithNamespace: event.PullRequest.Base.Repo.FullName,
+		targetBranch:      event.PullRequest.Base.Ref,
+		prID:              event.PullRequest.Number,
+		commitID:          event.PullRequest.Head.Sha,
+		closed:            event.Action == "close" || event.Action == "merge",
+		diffFunc: func(codehostID int) ([]string, error) {
+			return findChangedFilesOfPullRequestEvent(event, codehostID)
+		},
+	}, baseURI, requestID, log)
+}
+
+func matchPreviewEnvBranch(hookRepo *commonmodels.MainHookRepo, branch string) bool {
+	if hookRepo.Branch == "" {
+		return true
+	}
+	if hookRepo.IsRegular {
+		matched, err := regexp.MatchString(hookRepo.Branch, branch)
+		return err == nil && matched
+	}
+	return hookRepo.Branch == branch
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing preview env", func() {
+
+	DescribeTable("test matchPreviewEnvBranch",
+		func(hookRepo *commonmodels.MainHookRepo, branch string, want bool) {
+			Expect(matchPreviewEnvBranch(hookRepo, branch)).To(Equal(want))
+		},
+		Entry("any branch", &commonmodels.MainHookRepo{}, "dev", true),
+		Entry("same branch", &commonmodels.MainHookRepo{Branch: "main"}, "main", true),
+		Entry("different branch", &commonmodels.MainHookRepo{Branch: "main"}, "dev", false),
+		Entry("regular branch", &commonmodels.MainHookRepo{Branch: "^release-.*", IsRegular: true}, "release-1.0", true),
+		Entry("unmatched regular branch", &commonmodels.MainHookRepo{Branch: "^release-.*", IsRegular: true}, "main", false),
+	)
+
+	Describe("test setPreviewEnvWorkflowArgs", func() {
+		It("should build and deploy the changed services to the preview environment", func() {
+			workflow := &commonmodels.WorkflowV4{
+				Stages: []*commonmodels.WorkflowStage{
+					{Jobs: []*commonmodels.Job{{
+						Name:    "build",
+						JobType: config.JobZadigBuild,
+						Spec: &commonmodels.ZadigBuildJobSpec{ServiceAndBuilds: []*commonmodels.ServiceAndBuild{
+							{ServiceName: "frontend", ServiceModule: "frontend"},
+							{ServiceName: "backend", ServiceModule: "backend"},
+						}},
+					}}},
+					{Jobs: []*commonmodels.Job{{
+						Name:    "deploy",
+						JobType: config.JobZadigDeploy,
+						Spec: &commonmodels.ZadigDeployJobSpec{
+							Env:        "prod",
+							Production: true,
+							ServiceAndImages: []*commonmodels.ServiceAndImage{
+								{ServiceName: "frontend", ServiceModule: "frontend"},
+								{ServiceName: "backend", ServiceModule: "backend"},
+							},
+							Services: []*commonmodels.DeployService{
+								{ServiceName: "frontend"},
+								{ServiceName: "backend"},
+							},
+						},
+					}}},
+				},
+			}
+
+			Expect(setPreviewEnvWorkflowArgs(workflow, "pr-1-abc", []string{"backend"})).To(Succeed())
+
+			buildSpec := workflow.Stages[0].Jobs[0].Spec.(*commonmodels.ZadigBuildJobSpec)
+			Expect(buildSpec.ServiceAndBuilds).To(HaveLen(1))
+			Expect(buildSpec.ServiceAndBuilds[0].ServiceName).To(Equal("backend"))
+
+			deploySpec := workflow.Stages[1].Jobs[0].Spec.(*commonmodels.ZadigDeployJobSpec)
+			Expect(deploySpec.Env).To(Equal("pr-1-abc"))
+			Expect(deploySpec.Production).To(BeFalse())
+			Expect(deploySpec.ServiceAndImages).To(HaveLen(1))
+			Expect(deploySpec.ServiceAndImages[0].ServiceName).To(Equal("backend"))
+			Expect(deploySpec.Services).To(HaveLen(1))
+			Expect(deploySpec.Services[0].ServiceName).To(Equal("backend"))
+		})
+	})
+})
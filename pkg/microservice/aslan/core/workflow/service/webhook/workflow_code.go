@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/scmnotify"
+	workflowservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
+)
+
+// workflowCodeEvent is the push or pull request event of any code host, the workflow files are synced on push
+// and the workflow changes are commented in the pull request.
+type workflowCodeEvent struct {
+	// source is the type of the code host, e.g. github
+	source string
+	// pathWithNamespace is the full name of the repo, e.g. owner/repo
+	pathWithNamespace string
+	// branch is the pushed branch or the target branch of the pull request
+	branch   string
+	commitID string
+	// prID is 0 for the push event
+	prID int
+	// diffFunc returns the changed files of the event
+	diffFunc func(codehostID int) ([]string, error)
+}
+
+// triggerWorkflowCodeByEvent syncs or plans the workflow files of the projects whose workflow code source matches the event
+func triggerWorkflowCodeByEvent(event *workflowCodeEvent, baseURI string, log *zap.SugaredLogger) error {
+	repoName := event.pathWithNamespace[strings.LastIndex(event.pathWithNamespace, "/")+1:]
+	sources, err := commonrepo.NewWorkflowCodeSourceColl().ListByRepo(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list workflow code sources, err: %w", err)
+	}
+
+	mErr := &multierror.Error{}
+	for _, source := range sources {
+		mainRepo := &commonmodels.MainHookRepo{
+			Source:        event.source,
+			RepoOwner:     source.RepoOwner,
+			RepoNamespace: source.RepoNamespace,
+			RepoName:      source.RepoName,
+			Branch:        event.branch,
+			CodehostID:    source.CodehostID,
+			Revision:      event.commitID,
+		}
+		if source.Branch != event.branch || !checkRepoNamespaceMatch(mainRepo, event.pathWithNamespace) {
+			continue
+		}
+		ch, err := systemconfig.New().GetCodeHost(source.CodehostID)
+		if err != nil || ch.Type != event.source {
+			continue
+		}
+
+		changedFiles, err := event.diffFunc(source.CodehostID)
+		if err != nil {
+			log.Warnf("failed to get the changed files of %s in %s, err: %s", event.commitID, event.pathWithNamespace, err)
+			mErr = multierror.Append(mErr, err)
+			continue
+		}
+		if !containsWorkflowFile(source.Path, changedFiles) {
+			continue
+		}
+
+		if event.prID == 0 {
+			if _, err := workflowservice.SyncWorkflowsFromCode(source.ProjectName, event.commitID, setting.WebhookTaskCreator, log); err != nil {
+				mErr = multierror.Append(mErr, err)
+			}
+			continue
+		}
+
+		changes, err := workflowservice.PlanWorkflowCodeChanges(source.ProjectName, event.commitID, log)
+		if err != nil {
+			log.Errorf("failed to plan the workflow changes of project %s for pr %d, err: %s", source.ProjectName, event.prID, err)
+			mErr = multierror.Append(mErr, err)
+			continue
+		}
+		if err := scmnotify.NewService().SendWorkflowCodeWebhookComment(mainRepo, event.prID, baseURI, changes, log); err != nil {
+			mErr = multierror.Append(mErr, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+func containsWorkflowFile(path string, files []string) bool {
+	for _, file := range files {
+		if path == "" || strings.HasPrefix(file, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func TriggerWorkflowCodeByGitlabEvent(event interface{}, baseURI string, log *zap.SugaredLogger) error {
+	switch ev := event.(type) {
+	case *gitlab.PushEvent:
+		return triggerWorkflowCodeByEvent(&workflowCodeEvent{
+			source:            setting.SourceFromGitlab,
+			pathWithNamespace: ev.Project.PathWithNamespace,
+			branch:            getBranchFromRef(ev.Ref),
+			commitID:          ev.After,
+			diffFunc: func(codehostID int) ([]string, error) {
+				changedFiles := make([]string, 0)
+				for _, commit := range ev.Commits {
+					changedFiles = append(changedFiles, commit.Added...)
+					changedFiles = append(changedFiles, commit.Removed...)
+					changedFiles = append(changedFiles, commit.Modified...)
+				}
+				return changedFiles, nil
+			},
+		}, baseURI, log)
+	case *gitlab.MergeEvent:
+		action := ev.ObjectAttributes.Action
+		if action != "open" && action != "reopen" && action != "update" {
+			return nil
+		}
+		return triggerWorkflowCodeByEvent(&workflowCodeEvent{
+			source:            setting.SourceFromGitlab,
+			pathWithNamespace: ev.ObjectAttributes.Target.PathWithNamespace,
+			branch:            ev.ObjectAttributes.TargetBranch,
+			commitID:          ev.ObjectAttributes.LastCommit.ID,
+			prID:              ev.ObjectAttributes.IID,
+			diffFunc: func(codehostID int) ([]string, error) {
+				return findChangedFilesOfMergeRequest(ev, codehostID)
+			},
+		}, baseURI, log)
+	default:
+		return nil
+	}
+}
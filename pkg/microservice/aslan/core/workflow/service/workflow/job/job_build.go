@@ -270,6 +270,10 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			jobTaskSpec.Properties.CacheEnable = buildInfo.CacheEnable
 			jobTaskSpec.Properties.CacheDirType = buildInfo.CacheDirType
 			jobTaskSpec.Properties.CacheUserDir = buildInfo.CacheUserDir
+			jobTaskSpec.Properties.RunInContainer = buildInfo.PreBuild.RunInContainer
+			if jobTaskSpec.Properties.RunInContainer && jobTaskSpec.Properties.BuildOS == "" {
+				return resp, fmt.Errorf("build %s runs in container but has no build image", buildInfo.Name)
+			}
 		} else {
 			clusterInfo, err := commonrepo.NewK8SClusterColl().Get(buildInfo.PreBuild.ClusterID)
 			if err != nil {
@@ -319,6 +323,15 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			Spec:     step.StepGitSpec{Repos: renderRepos(build.Repos, buildInfo.Repos, jobTaskSpec.Properties.Envs)},
 		}
 		jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
+		// init cache restore steps
+		for _, rule := range buildInfo.CacheRules {
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+				Name:     fmt.Sprintf("%s-cache-restore-%s", build.ServiceName, rule.Name),
+				JobName:  jobTask.Name,
+				StepType: config.StepCacheRestore,
+				Spec:     buildCacheStepSpec(rule, j.workflow.Project, buildInfo.Name, defaultS3),
+			})
+		}
 		// init debug before step
 		debugBeforeStep := &commonmodels.StepTask{
 			Name:     build.ServiceName + "-debug_before",
@@ -359,6 +372,15 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			StepType: config.StepDebugAfter,
 		}
 		jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugAfterStep)
+		// init cache save steps
+		for _, rule := range buildInfo.CacheRules {
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+				Name:     fmt.Sprintf("%s-cache-save-%s", build.ServiceName, rule.Name),
+				JobName:  jobTask.Name,
+				StepType: config.StepCacheSave,
+				Spec:     buildCacheStepSpec(rule, j.workflow.Project, buildInfo.Name, defaultS3),
+			})
+		}
 		// init docker build step
 		if buildInfo.PostBuild != nil && buildInfo.PostBuild.DockerBuild != nil {
 			dockefileContent := ""
@@ -519,6 +541,24 @@ func getBuildJobVariables(build *commonmodels.ServiceAndBuild, taskID int64, pro
 	return ret
 }
 
+// buildCacheStepSpec caches are shared by all the jobs using the same build, the hash of the key files is appended to the key
+// and the rule name is used as the restore key, so a stale cache is restored when the key files change.
+func buildCacheStepSpec(rule *commonmodels.BuildCacheRule, project, buildName string, defaultS3 *commonmodels.S3Storage) *step.StepCacheSpec {
+	spec := &step.StepCacheSpec{
+		Key:       rule.Name,
+		KeyFiles:  rule.KeyFiles,
+		Paths:     rule.Paths,
+		S3DestDir: path.Join("cache", project, buildName),
+		S3Storage: modelS3toS3(defaultS3),
+		MaxSize:   rule.MaxSize,
+		TTL:       rule.TTL,
+	}
+	if len(rule.KeyFiles) > 0 {
+		spec.RestoreKeys = []string{rule.Name + step.CacheKeySeparator}
+	}
+	return spec
+}
+
 func modelS3toS3(modelS3 *commonmodels.S3Storage) *step.S3 {
 	resp := &step.S3{
 		Ak:        modelS3.Ak,
@@ -612,6 +652,10 @@ func mergeRepos(templateRepos []*types.Repository, customRepos []*types.Reposito
 			repo.Tag = cv.Tag
 			repo.PR = cv.PR
 			repo.PRs = cv.PRs
+			// the pull request of bitbucket cloud is fetched by the source branch ref
+			if cv.CheckoutRef != "" {
+				repo.CheckoutRef = cv.CheckoutRef
+			}
 		}
 	}
 	return templateRepos
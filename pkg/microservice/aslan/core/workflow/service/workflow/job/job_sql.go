@@ -22,6 +22,8 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/v2/pkg/tool/sqlexec"
 )
 
 type SQLJob struct {
@@ -72,9 +74,11 @@ func (j *SQLJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		Key:     j.job.Name,
 		JobType: string(config.JobSQL),
 		Spec: &commonmodels.JobTaskSQLSpec{
-			ID:   j.spec.ID,
-			Type: j.spec.Type,
-			SQL:  j.spec.SQL,
+			ID:          j.spec.ID,
+			Type:        j.spec.Type,
+			SQL:         j.spec.SQL,
+			RollbackSQL: j.spec.RollbackSQL,
+			DryRun:      j.spec.DryRun,
 		},
 		Timeout: 0,
 	}
@@ -86,8 +90,19 @@ func (j *SQLJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
-	if _, err := mongodb.NewDBInstanceColl().Find(&mongodb.DBInstanceCollFindOption{Id: j.spec.ID}); err != nil {
+	info, err := mongodb.NewDBInstanceColl().Find(&mongodb.DBInstanceCollFindOption{Id: j.spec.ID})
+	if err != nil {
 		return errors.Errorf("not found db instance in mongo, err: %v", err)
 	}
+	dialect, err := commonutil.GetSQLDialect(info.Type)
+	if err != nil {
+		return err
+	}
+	if j.spec.DryRun && !sqlexec.SupportsTransactionalDDL(dialect) {
+		return errors.Errorf("dry run is not supported for %s db instance %s, the DDL statements can not be rolled back", info.Type, info.Name)
+	}
+	if _, err := sqlexec.SplitStatements(dialect, j.spec.RollbackSQL); err != nil {
+		return errors.Errorf("invalid rollback sql: %v", err)
+	}
 	return nil
 }
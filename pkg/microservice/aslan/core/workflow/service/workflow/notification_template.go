@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/imnotify"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/instantmessage"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+func ListNotificationTemplates(projectName string, logger *zap.SugaredLogger) ([]*commonmodels.NotificationTemplate, error) {
+	resp, err := commonrepo.NewNotificationTemplateColl().ListByProject(projectName)
+	if err != nil {
+		logger.Errorf("failed to list notification templates of project %s, err: %s", projectName, err)
+		return nil, e.ErrInvalidParam.AddDesc(err.Error())
+	}
+	return resp, nil
+}
+
+func GetNotificationTemplate(projectName, id string, logger *zap.SugaredLogger) (*commonmodels.NotificationTemplate, error) {
+	tpl, err := commonrepo.NewNotificationTemplateColl().FindByID(id)
+	if err != nil {
+		logger.Errorf("failed to find notification template %s, err: %s", id, err)
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to find notification template %s, err: %s", id, err))
+	}
+	if tpl.ProjectName != projectName {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("notification template %s does not belong to project %s", id, projectName))
+	}
+	return tpl, nil
+}
+
+func CreateNotificationTemplate(username string, tpl *commonmodels.NotificationTemplate, logger *zap.SugaredLogger) error {
+	if err := lintNotificationTemplate(tpl); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := checkNotificationTemplateName(tpl, primitive.NilObjectID); err != nil {
+		return err
+	}
+
+	tpl.CreatedBy = username
+	tpl.CreateTime = time.Now().Unix()
+	tpl.UpdatedBy = username
+	tpl.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewNotificationTemplateColl().Create(tpl); err != nil {
+		logger.Errorf("failed to create notification template %s, err: %s", tpl.Name, err)
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to create notification template %s, err: %s", tpl.Name, err))
+	}
+	return nil
+}
+
+func UpdateNotificationTemplate(username, id string, tpl *commonmodels.NotificationTemplate, logger *zap.SugaredLogger) error {
+	origin, err := GetNotificationTemplate(tpl.ProjectName, id, logger)
+	if err != nil {
+		return err
+	}
+	if err := lintNotificationTemplate(tpl); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := checkNotificationTemplateName(tpl, origin.ID); err != nil {
+		return err
+	}
+
+	tpl.ID = origin.ID
+	tpl.CreatedBy = origin.CreatedBy
+	tpl.CreateTime = origin.CreateTime
+	tpl.UpdatedBy = username
+	tpl.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewNotificationTemplateColl().Update(tpl); err != nil {
+		logger.Errorf("failed to update notification template %s, err: %s", tpl.Name, err)
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to update notification template %s, err: %s", tpl.Name, err))
+	}
+	return nil
+}
+
+func DeleteNotificationTemplate(projectName, id string, logger *zap.SugaredLogger) error {
+	if _, err := GetNotificationTemplate(projectName, id, logger); err != nil {
+		return err
+	}
+	if err := commonrepo.NewNotificationTemplateColl().DeleteByID(id); err != nil {
+		logger.Errorf("failed to delete notification template %s, err: %s", id, err)
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to delete notification template %s, err: %s", id, err))
+	}
+	return nil
+}
+
+// checkNotificationTemplateName checks that no other template of the project has the same name
+func checkNotificationTemplateName(tpl *commonmodels.NotificationTemplate, id primitive.ObjectID) error {
+	existed, err := commonrepo.NewNotificationTemplateColl().FindByName(tpl.ProjectName, tpl.Name)
+	if err == nil && existed.ID != id {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("notification template %s already exists in project %s", tpl.Name, tpl.ProjectName))
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to find notification template %s, err: %s", tpl.Name, err))
+	}
+	return nil
+}
+
+func lintNotificationTemplate(tpl *commonmodels.NotificationTemplate) error {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if tpl.Name == "" {
+		return fmt.Errorf("notification template name cannot be empty")
+	}
+	if tpl.ProjectName == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+	if strings.TrimSpace(tpl.Title) == "" || strings.TrimSpace(tpl.Content) == "" {
+		return fmt.Errorf("title and content of the notification template cannot be empty")
+	}
+	return instantmessage.LintNotificationTemplate(tpl)
+}
+
+type PreviewNotificationTemplateArgs struct {
+	ProjectName  string `json:"project_name"`
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	WebHookType  string `json:"webhook_type"`
+	Title        string `json:"title"`
+	Content      string `json:"content"`
+}
+
+type PreviewNotificationTemplateResp struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// PreviewNotificationTemplate renders the template with a past task of the workflow,
+// the content is converted to the markdown format of slack and teams for these channels.
+func PreviewNotificationTemplate(args *PreviewNotificationTemplateArgs, logger *zap.SugaredLogger) (*PreviewNotificationTemplateResp, error) {
+	tpl := &commonmodels.NotificationTemplate{
+		Name:        "preview",
+		ProjectName: args.ProjectName,
+		Title:       args.Title,
+		Content:     args.Content,
+	}
+	if err := instantmessage.LintNotificationTemplate(tpl); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(args.WorkflowName, args.TaskID)
+	if err != nil {
+		logger.Errorf("failed to find workflow %s task %d, err: %s", args.WorkflowName, args.TaskID, err)
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("failed to find workflow %s task %d, err: %s", args.WorkflowName, args.TaskID, err))
+	}
+	if task.ProjectName != args.ProjectName {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("workflow %s does not belong to project %s", args.WorkflowName, args.ProjectName))
+	}
+
+	title, content, err := instantmessage.RenderNotificationTemplate(tpl, task, args.WebHookType)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	switch imnotify.IMNotifyType(args.WebHookType) {
+	case imnotify.IMNotifyTypeSlack:
+		content = imnotify.MarkdownToSlack(content)
+	case imnotify.IMNotifyTypeMSTeams:
+		content = imnotify.MarkdownToMSTeams(content)
+	}
+	return &PreviewNotificationTemplateResp{
+		Title:   title,
+		Content: content,
+	}, nil
+}
@@ -39,13 +39,16 @@ import (
 
 // CreateCustomWorkflowTask creates a task for custom workflow with user-friendly inputs, this is currently
 // used for openAPI
-func CreateCustomWorkflowTask(username string, args *OpenAPICreateCustomWorkflowTaskArgs, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
+func CreateCustomWorkflowTask(username, identityType string, args *OpenAPICreateCustomWorkflowTaskArgs, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
 	// first we generate a detailed workflow.
 	workflow, err := commonrepo.NewWorkflowV4Coll().Find(args.WorkflowName)
 	if err != nil {
 		log.Errorf("cannot find workflow %s, the error is: %v", args.WorkflowName, err)
 		return nil, e.ErrFindWorkflow.AddDesc(err.Error())
 	}
+	if workflow.Project != args.ProjectName {
+		return nil, e.ErrFindWorkflow.AddDesc(fmt.Sprintf("workflow %s not found in project %s", args.WorkflowName, args.ProjectName))
+	}
 
 	for _, stage := range workflow.Stages {
 		for _, job := range stage.Jobs {
@@ -86,7 +89,8 @@ func CreateCustomWorkflowTask(username string, args *OpenAPICreateCustomWorkflow
 	}
 
 	return CreateWorkflowTaskV4(&CreateWorkflowTaskV4Args{
-		Name: username,
+		Name:         username,
+		IdentityType: identityType,
 	}, workflow, log)
 }
 
@@ -422,6 +426,7 @@ func OpenAPIGetCustomWorkflowV4(workflowName, projectName string, logger *zap.Su
 		return nil, err
 	}
 
+	maskNotifySecrets(workflow.NotifyCtls)
 	resp := &OpenAPIWorkflowV4Detail{
 		Name:             workflow.Name,
 		DisplayName:      workflow.DisplayName,
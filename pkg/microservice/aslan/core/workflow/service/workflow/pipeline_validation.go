@@ -28,6 +28,7 @@ import (
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	configbase "github.com/koderover/zadig/v2/pkg/config"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
@@ -37,6 +38,7 @@ import (
 	commonservice "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/base"
 	git "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/github"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
 	kubeclient "github.com/koderover/zadig/v2/pkg/shared/kube/client"
@@ -58,6 +60,8 @@ const (
 var (
 	NameSpaceRegex   = regexp.MustCompile(NameSpaceRegexString)
 	defaultNameRegex = regexp.MustCompile(defaultNameRegexString)
+	// conditionJobRefRegex matches the job name in variables like {{.job.build.svc.output.IMAGE}}
+	conditionJobRefRegex = regexp.MustCompile(`{{\.job\.([^.{}]+)\.`)
 )
 
 func validatePipelineHookNames(p *commonmodels.Pipeline) error {
@@ -159,6 +163,152 @@ func validateSubTaskSetting(pipeName string, subtasks []map[string]interface{})
 	return nil
 }
 
+// validateWorkflowConditions checks the syntax of stage and job conditions, and makes sure the
+// job outputs referenced in a condition come from jobs which run before it.
+// In a workflow scheduled as a DAG, a job only runs after its upstream jobs, so a job condition may only
+// refer to the upstream jobs of the job, and a stage condition to the upstream jobs of all the jobs in the stage.
+func validateWorkflowConditions(workflow *commonmodels.WorkflowV4) error {
+	stages, dependsOn := workflowJobDependencyInput(workflow)
+	if commonutil.HasJobDependencies(dependsOn) {
+		dependencies := commonutil.ResolveJobDependencies(stages, dependsOn)
+		for _, stage := range workflow.Stages {
+			var stageUpstreams sets.String
+			for _, job := range stage.Jobs {
+				upstreams := transitiveUpstreamJobs(dependencies, job.Name)
+				if err := validateCondition(job.When, upstreams); err != nil {
+					return fmt.Errorf("job: %s %v", job.Name, err)
+				}
+				if stageUpstreams == nil {
+					stageUpstreams = upstreams
+				} else {
+					stageUpstreams = stageUpstreams.Intersection(upstreams)
+				}
+			}
+			if stageUpstreams == nil {
+				stageUpstreams = sets.NewString()
+			}
+			if err := validateCondition(stage.When, stageUpstreams); err != nil {
+				return fmt.Errorf("stage: %s %v", stage.Name, err)
+			}
+		}
+		return nil
+	}
+
+	previousJobs := sets.NewString()
+	for _, stage := range workflow.Stages {
+		if err := validateCondition(stage.When, previousJobs); err != nil {
+			return fmt.Errorf("stage: %s %v", stage.Name, err)
+		}
+		stageJobs := []string{}
+		for _, job := range stage.Jobs {
+			if err := validateCondition(job.When, previousJobs); err != nil {
+				return fmt.Errorf("job: %s %v", job.Name, err)
+			}
+			stageJobs = append(stageJobs, job.Name)
+		}
+		previousJobs.Insert(stageJobs...)
+	}
+	return nil
+}
+
+// transitiveUpstreamJobs returns all the jobs which are done before the job starts in a DAG
+func transitiveUpstreamJobs(dependencies map[string][]string, job string) sets.String {
+	upstreams := sets.NewString()
+	queue := append([]string{}, dependencies[job]...)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		if upstreams.Has(dep) {
+			continue
+		}
+		upstreams.Insert(dep)
+		queue = append(queue, dependencies[dep]...)
+	}
+	// a job in a cycle is not its own upstream, the cycle is reported by validateJobDependencies
+	upstreams.Delete(job)
+	return upstreams
+}
+
+func validateCondition(condition string, previousJobs sets.String) error {
+	if condition == "" {
+		return nil
+	}
+	if err := commonutil.ValidateCondition(condition); err != nil {
+		return err
+	}
+	for _, match := range conditionJobRefRegex.FindAllStringSubmatch(condition, -1) {
+		if !previousJobs.Has(match[1]) {
+			return fmt.Errorf("condition refers to job %s which does not run before it", match[1])
+		}
+	}
+	return nil
+}
+
+// validateJobDependencies checks the depends_on of the workflow jobs for unknown jobs and cycles.
+func validateJobDependencies(workflow *commonmodels.WorkflowV4) error {
+	stages, dependsOn := workflowJobDependencyInput(workflow)
+	if !commonutil.HasJobDependencies(dependsOn) {
+		return nil
+	}
+	return commonutil.ValidateJobDependencies(commonutil.ResolveJobDependencies(stages, dependsOn))
+}
+
+func workflowJobDependencyInput(workflow *commonmodels.WorkflowV4) ([]*commonutil.JobDependencyStage, map[string][]string) {
+	stages := make([]*commonutil.JobDependencyStage, 0, len(workflow.Stages))
+	dependsOn := make(map[string][]string)
+	for _, stage := range workflow.Stages {
+		dependencyStage := &commonutil.JobDependencyStage{Parallel: stage.Parallel}
+		for _, job := range stage.Jobs {
+			dependencyStage.Jobs = append(dependencyStage.Jobs, job.Name)
+			dependsOn[job.Name] = job.DependsOn
+		}
+		stages = append(stages, dependencyStage)
+	}
+	return stages, dependsOn
+}
+
+// maxJobAttempts limits the attempts of a job so a broken job will not occupy the resources for too long
+const maxJobAttempts = 10
+
+func validateRetryPolicies(workflow *commonmodels.WorkflowV4) error {
+	if err := validateRetryPolicy(workflow.RetryPolicy); err != nil {
+		return fmt.Errorf("workflow retry policy: %v", err)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if err := validateRetryPolicy(job.RetryPolicy); err != nil {
+				return fmt.Errorf("job %s retry policy: %v", job.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateRetryPolicy(policy *commonmodels.RetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 0 || policy.MaxAttempts > maxJobAttempts {
+		return fmt.Errorf("max attempts should be between 0 and %d", maxJobAttempts)
+	}
+	if policy.Interval < 0 {
+		return fmt.Errorf("interval can not be negative")
+	}
+	switch policy.Backoff {
+	case "", config.RetryBackoffFixed, config.RetryBackoffExponential:
+	default:
+		return fmt.Errorf("unknown backoff %s", policy.Backoff)
+	}
+	for _, class := range policy.RetryOn {
+		switch class {
+		case config.JobFailureTimeout, config.JobFailurePodEvicted, config.JobFailureImagePullError, config.JobFailureOther:
+		default:
+			return fmt.Errorf("unknown failure class %s", class)
+		}
+	}
+	return nil
+}
+
 func ensureTaskSecretEnvs(pipelineName string, taskType config.TaskType, kvs []*commonmodels.KeyVal) {
 	envsMap := getTaskEnvs(pipelineName)
 	for _, kv := range kvs {
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
+)
+
+var _ = Describe("Testing workflow validation", func() {
+
+	newWorkflow := func(stageWhen, jobWhen string) *commonmodels.WorkflowV4 {
+		return &commonmodels.WorkflowV4{
+			Stages: []*commonmodels.WorkflowStage{
+				{Name: "build", Jobs: []*commonmodels.Job{{Name: "build"}}},
+				{Name: "deploy", When: stageWhen, Jobs: []*commonmodels.Job{{Name: "deploy", When: jobWhen}}},
+			},
+		}
+	}
+
+	Context("validateWorkflowConditions", func() {
+		It("should be passed for empty conditions", func() {
+			Expect(validateWorkflowConditions(newWorkflow("", ""))).ShouldNot(HaveOccurred())
+		})
+		It("should be passed for conditions referring to previous jobs", func() {
+			err := validateWorkflowConditions(newWorkflow(
+				"'{{.workflow.params.branch}}' !~ '^docs/'",
+				"'{{.job.build.svc.output.IMAGE}}' != ''",
+			))
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("should raise error for invalid syntax", func() {
+			err := validateWorkflowConditions(newWorkflow("'{{.workflow.params.branch}}' ==", ""))
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should raise error for conditions referring to later jobs", func() {
+			err := validateWorkflowConditions(newWorkflow("", "'{{.job.deploy.IMAGES}}' != ''"))
+			Expect(err).Should(HaveOccurred())
+		})
+		Context("in workflows scheduled as a DAG", func() {
+			newDAGWorkflow := func(stageWhen, jobWhen string) *commonmodels.WorkflowV4 {
+				return &commonmodels.WorkflowV4{
+					Stages: []*commonmodels.WorkflowStage{
+						{Name: "build", Parallel: true, Jobs: []*commonmodels.Job{{Name: "build-a"}, {Name: "build-b"}}},
+						{Name: "test", Jobs: []*commonmodels.Job{{Name: "test-a", DependsOn: []string{"build-a"}}}},
+						{Name: "deploy", When: stageWhen, Jobs: []*commonmodels.Job{{Name: "deploy-a", When: jobWhen, DependsOn: []string{"test-a"}}}},
+					},
+				}
+			}
+			It("should be passed for conditions referring to upstream jobs", func() {
+				err := validateWorkflowConditions(newDAGWorkflow(
+					"'{{.job.test-a.output.RESULT}}' == 'ok'",
+					"'{{.job.build-a.svc.output.IMAGE}}' != ''",
+				))
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+			It("should raise error for job conditions referring to jobs in earlier stages which are not upstream", func() {
+				err := validateWorkflowConditions(newDAGWorkflow("", "'{{.job.build-b.svc.output.IMAGE}}' != ''"))
+				Expect(err).Should(HaveOccurred())
+			})
+			It("should raise error for stage conditions referring to jobs which are not upstream", func() {
+				err := validateWorkflowConditions(newDAGWorkflow("'{{.job.build-b.svc.output.IMAGE}}' != ''", ""))
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("validateJobDependencies", func() {
+		newWorkflow := func(buildDeps, deployDeps []string) *commonmodels.WorkflowV4 {
+			return &commonmodels.WorkflowV4{
+				Stages: []*commonmodels.WorkflowStage{
+					{Name: "build", Parallel: true, Jobs: []*commonmodels.Job{
+						{Name: "build-a", DependsOn: buildDeps},
+						{Name: "build-b"},
+					}},
+					{Name: "deploy", Jobs: []*commonmodels.Job{
+						{Name: "deploy-a", DependsOn: deployDeps},
+						{Name: "deploy-b"},
+					}},
+				},
+			}
+		}
+		It("should be passed for workflows without dependencies", func() {
+			Expect(validateJobDependencies(newWorkflow(nil, nil))).ShouldNot(HaveOccurred())
+		})
+		It("should be passed for dependencies across stages", func() {
+			Expect(validateJobDependencies(newWorkflow(nil, []string{"build-a"}))).ShouldNot(HaveOccurred())
+		})
+		It("should raise error for unknown jobs", func() {
+			Expect(validateJobDependencies(newWorkflow(nil, []string{"build-c"}))).Should(HaveOccurred())
+		})
+		It("should raise error for cycles", func() {
+			Expect(validateJobDependencies(newWorkflow([]string{"deploy-b"}, nil))).Should(HaveOccurred())
+			Expect(validateJobDependencies(newWorkflow([]string{"build-a"}, nil))).Should(HaveOccurred())
+		})
+	})
+
+	Context("validateRetryPolicy", func() {
+		It("should be passed for valid policies", func() {
+			Expect(validateRetryPolicy(nil)).ShouldNot(HaveOccurred())
+			Expect(validateRetryPolicy(&commonmodels.RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     config.RetryBackoffExponential,
+				Interval:    5,
+				RetryOn:     []config.JobFailureClass{config.JobFailureTimeout, config.JobFailurePodEvicted},
+			})).ShouldNot(HaveOccurred())
+		})
+		It("should raise error for invalid policies", func() {
+			Expect(validateRetryPolicy(&commonmodels.RetryPolicy{MaxAttempts: 100})).Should(HaveOccurred())
+			Expect(validateRetryPolicy(&commonmodels.RetryPolicy{MaxAttempts: 2, Backoff: "linear"})).Should(HaveOccurred())
+			Expect(validateRetryPolicy(&commonmodels.RetryPolicy{MaxAttempts: 2, RetryOn: []config.JobFailureClass{"oom"}})).Should(HaveOccurred())
+		})
+	})
+
+	Context("EvaluateCondition", func() {
+		It("should evaluate conditions", func() {
+			run, err := commonutil.EvaluateCondition("'docs/readme' !~ '^docs/'", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeFalse())
+
+			run, err = commonutil.EvaluateCondition("hasPrefix('feature/a', 'feature/') && 3 > 1", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeTrue())
+		})
+		It("should pass variables as parameters", func() {
+			variables := map[string]string{
+				"{{.workflow.params.branch}}": "feature/a",
+				"{{.workflow.params.count}}":  "3",
+				"{{.workflow.params.deploy}}": "true",
+			}
+			run, err := commonutil.EvaluateCondition("hasPrefix({{.workflow.params.branch}}, 'feature/') && {{.workflow.params.count}} > 1", variables)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeTrue())
+
+			run, err = commonutil.EvaluateCondition("'{{.workflow.params.branch}}' == 'feature/a' && {{.workflow.params.deploy}}", variables)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeTrue())
+
+			run, err = commonutil.EvaluateCondition("'{{.workflow.params.count}}' == '3'", variables)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeTrue())
+		})
+		It("should not let variable values change the expression", func() {
+			variables := map[string]string{"{{.workflow.params.branch}}": "a' || 'a' == 'a"}
+			run, err := commonutil.EvaluateCondition("'{{.workflow.params.branch}}' == 'main'", variables)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeFalse())
+
+			run, err = commonutil.EvaluateCondition("{{.workflow.params.branch}} == 'main'", variables)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeFalse())
+		})
+		It("should treat unrendered variables as empty strings", func() {
+			run, err := commonutil.EvaluateCondition("{{.job.build.svc.output.IMAGE}} != ''", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeFalse())
+
+			run, err = commonutil.EvaluateCondition("'{{.job.build.svc.output.IMAGE}}' == ''", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(run).To(BeTrue())
+		})
+		It("should raise error for non-bool results", func() {
+			_, err := commonutil.EvaluateCondition("1 + 1", nil)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,566 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+// workflowCodeSyncLock makes the syncs of the workflow files run one by one, so a push won't be overwritten by an older one
+var workflowCodeSyncLock sync.Mutex
+
+type WorkflowCodeSourceArgs struct {
+	Enabled       bool   `json:"enabled"`
+	CodehostID    int    `json:"codehost_id"`
+	RepoOwner     string `json:"repo_owner"`
+	RepoNamespace string `json:"repo_namespace"`
+	RepoName      string `json:"repo_name"`
+	Branch        string `json:"branch"`
+	Path          string `json:"path"`
+	AdoptExisting bool   `json:"adopt_existing"`
+}
+
+func (args *WorkflowCodeSourceArgs) Validate() error {
+	if !args.Enabled {
+		return nil
+	}
+	if args.CodehostID == 0 || args.RepoOwner == "" || args.RepoName == "" || args.Branch == "" {
+		return fmt.Errorf("codehost, repo and branch of the workflow files should not be empty")
+	}
+
+	ch, err := systemconfig.New().GetCodeHost(args.CodehostID)
+	if err != nil {
+		return fmt.Errorf("failed to get codehost %d, err: %w", args.CodehostID, err)
+	}
+	if ch.Type != setting.SourceFromGithub && ch.Type != setting.SourceFromGitlab {
+		return fmt.Errorf("workflow files in %s are not supported, only github and gitlab are supported", ch.Type)
+	}
+	return nil
+}
+
+type WorkflowCodeSyncResp struct {
+	Changes []*commonmodels.WorkflowCodeChange `json:"changes"`
+}
+
+type WorkflowCodeDriftResp struct {
+	CommitID string                            `json:"commit_id"`
+	Drifts   []*commonmodels.WorkflowCodeDrift `json:"drifts"`
+}
+
+// workflowCodeGetter reads the workflow files in the repository, it's implemented by the github and gitlab clients
+type workflowCodeGetter interface {
+	fs.TreeGetter
+	GetLatestRepositoryCommit(owner, repo, path, branch string) (*git.RepositoryCommit, error)
+}
+
+// workflowFile is a workflow file in the repository, err is set if it's not a valid workflow
+type workflowFile struct {
+	path     string
+	hash     string
+	workflow *commonmodels.WorkflowV4
+	err      error
+}
+
+// workflowCodePlan is how a workflow is changed by the workflow files, file is nil if the workflow is released,
+// existing is nil if the workflow is created
+type workflowCodePlan struct {
+	change   *commonmodels.WorkflowCodeChange
+	file     *workflowFile
+	existing *commonmodels.WorkflowV4
+}
+
+func GetWorkflowCodeSource(projectName string, logger *zap.SugaredLogger) (*commonmodels.WorkflowCodeSource, error) {
+	source, err := commonrepo.NewWorkflowCodeSourceColl().Find(projectName)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &commonmodels.WorkflowCodeSource{ProjectName: projectName}, nil
+		}
+		logger.Errorf("failed to find workflow code source of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetWorkflowCodeSource.AddErr(err)
+	}
+	return source, nil
+}
+
+// UpdateWorkflowCodeSource saves the repository path of the workflow files of the project, the workflows synced from
+// the files become editable again once it's disabled.
+func UpdateWorkflowCodeSource(projectName, user string, args *WorkflowCodeSourceArgs, logger *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrUpdateWorkflowCodeSource.AddErr(fmt.Errorf("failed to find project %s, err: %w", projectName, err))
+	}
+	if err := args.Validate(); err != nil {
+		return e.ErrUpdateWorkflowCodeSource.AddErr(err)
+	}
+
+	source := &commonmodels.WorkflowCodeSource{
+		ProjectName:   projectName,
+		Enabled:       args.Enabled,
+		CodehostID:    args.CodehostID,
+		RepoOwner:     args.RepoOwner,
+		RepoNamespace: args.RepoNamespace,
+		RepoName:      args.RepoName,
+		Branch:        args.Branch,
+		Path:          strings.Trim(args.Path, "/"),
+		AdoptExisting: args.AdoptExisting,
+		UpdatedBy:     user,
+		UpdateTime:    time.Now().Unix(),
+	}
+	if err := commonrepo.NewWorkflowCodeSourceColl().Upsert(source); err != nil {
+		logger.Errorf("failed to update workflow code source of project %s, err: %s", projectName, err)
+		return e.ErrUpdateWorkflowCodeSource.AddErr(err)
+	}
+
+	if !args.Enabled {
+		if err := releaseCodeManagedWorkflows(projectName); err != nil {
+			logger.Errorf("failed to release the workflows of project %s, err: %s", projectName, err)
+			return e.ErrUpdateWorkflowCodeSource.AddErr(err)
+		}
+	}
+	return nil
+}
+
+// SyncWorkflowsFromCode validates the workflow files at the commit and upserts the workflows, the workflows whose files
+// are removed are released. Nothing is changed if any of the files is invalid. The head of the branch is synced if
+// commitID is empty.
+func SyncWorkflowsFromCode(projectName, commitID, user string, logger *zap.SugaredLogger) (*WorkflowCodeSyncResp, error) {
+	workflowCodeSyncLock.Lock()
+	defer workflowCodeSyncLock.Unlock()
+
+	source, getter, err := getWorkflowCodeSourceAndGetter(projectName)
+	if err != nil {
+		return nil, e.ErrSyncWorkflowCode.AddErr(err)
+	}
+	if commitID == "" {
+		commit, err := getter.GetLatestRepositoryCommit(source.GetRepoNamespace(), source.RepoName, source.Path, source.Branch)
+		if err != nil {
+			return nil, e.ErrSyncWorkflowCode.AddErr(fmt.Errorf("failed to get the latest commit of branch %s, err: %w", source.Branch, err))
+		}
+		commitID = commit.SHA
+	}
+
+	plans, err := planWorkflowCodeChanges(source, getter, commitID, logger)
+	if err != nil {
+		recordWorkflowCodeSyncResult(source, commitID, err, logger)
+		return nil, e.ErrSyncWorkflowCode.AddErr(err)
+	}
+
+	resp := &WorkflowCodeSyncResp{Changes: make([]*commonmodels.WorkflowCodeChange, 0, len(plans))}
+	invalidErr := &multierror.Error{}
+	for _, plan := range plans {
+		resp.Changes = append(resp.Changes, plan.change)
+		if plan.change.Error != "" {
+			invalidErr = multierror.Append(invalidErr, fmt.Errorf("%s: %s", plan.change.Path, plan.change.Error))
+		}
+	}
+	if err := invalidErr.ErrorOrNil(); err != nil {
+		recordWorkflowCodeSyncResult(source, commitID, err, logger)
+		return resp, e.ErrSyncWorkflowCode.AddErr(err)
+	}
+
+	for _, plan := range plans {
+		if err := applyWorkflowCodePlan(plan, commitID, user, logger); err != nil {
+			err = fmt.Errorf("failed to %s workflow %s, err: %w", plan.change.Action, plan.change.WorkflowName, err)
+			recordWorkflowCodeSyncResult(source, commitID, err, logger)
+			return resp, e.ErrSyncWorkflowCode.AddErr(err)
+		}
+	}
+
+	recordWorkflowCodeSyncResult(source, commitID, nil, logger)
+	return resp, nil
+}
+
+// PlanWorkflowCodeChanges returns what would change in the workflows if the workflow files at the ref are synced,
+// it's used to check the workflow files in the pull requests.
+func PlanWorkflowCodeChanges(projectName, ref string, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowCodeChange, error) {
+	source, getter, err := getWorkflowCodeSourceAndGetter(projectName)
+	if err != nil {
+		return nil, e.ErrPlanWorkflowCode.AddErr(err)
+	}
+
+	plans, err := planWorkflowCodeChanges(source, getter, ref, logger)
+	if err != nil {
+		return nil, e.ErrPlanWorkflowCode.AddErr(err)
+	}
+	changes := make([]*commonmodels.WorkflowCodeChange, 0, len(plans))
+	for _, plan := range plans {
+		changes = append(changes, plan.change)
+	}
+	return changes, nil
+}
+
+// GetWorkflowCodeDrifts compares the workflow files at the head of the branch with the workflows in zadig
+func GetWorkflowCodeDrifts(projectName string, logger *zap.SugaredLogger) (*WorkflowCodeDriftResp, error) {
+	source, getter, err := getWorkflowCodeSourceAndGetter(projectName)
+	if err != nil {
+		return nil, e.ErrGetWorkflowCodeDrift.AddErr(err)
+	}
+	commit, err := getter.GetLatestRepositoryCommit(source.GetRepoNamespace(), source.RepoName, source.Path, source.Branch)
+	if err != nil {
+		return nil, e.ErrGetWorkflowCodeDrift.AddErr(fmt.Errorf("failed to get the latest commit of branch %s, err: %w", source.Branch, err))
+	}
+	files, err := loadWorkflowFiles(source, getter, commit.SHA)
+	if err != nil {
+		return nil, e.ErrGetWorkflowCodeDrift.AddErr(err)
+	}
+	managedWorkflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName, CodeManaged: true}, 0, 0)
+	if err != nil {
+		logger.Errorf("failed to list the workflows of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetWorkflowCodeDrift.AddErr(err)
+	}
+
+	resp := &WorkflowCodeDriftResp{CommitID: commit.SHA, Drifts: make([]*commonmodels.WorkflowCodeDrift, 0)}
+	managedWorkflowMap := make(map[string]*commonmodels.WorkflowV4)
+	for _, workflow := range managedWorkflows {
+		managedWorkflowMap[workflow.Name] = workflow
+	}
+	paths := sets.NewString()
+	for _, file := range files {
+		paths.Insert(file.path)
+		drift := &commonmodels.WorkflowCodeDrift{Path: file.path}
+		if file.err != nil {
+			drift.Type = config.WorkflowCodeDriftNotSynced
+			resp.Drifts = append(resp.Drifts, drift)
+			continue
+		}
+
+		drift.WorkflowName = file.workflow.Name
+		workflow, ok := managedWorkflowMap[file.workflow.Name]
+		switch {
+		case !ok:
+			drift.Type = config.WorkflowCodeDriftMissingInZadig
+		case workflow.CodeSource.ContentHash != file.hash:
+			drift.Type = config.WorkflowCodeDriftNotSynced
+		case workflow.CodeSource.SyncTime != workflow.UpdateTime:
+			drift.Type = config.WorkflowCodeDriftModified
+		default:
+			continue
+		}
+		resp.Drifts = append(resp.Drifts, drift)
+	}
+	for _, workflow := range managedWorkflows {
+		if !paths.Has(workflow.CodeSource.Path) {
+			resp.Drifts = append(resp.Drifts, &commonmodels.WorkflowCodeDrift{
+				WorkflowName: workflow.Name,
+				Path:         workflow.CodeSource.Path,
+				Type:         config.WorkflowCodeDriftMissingInRepo,
+			})
+		}
+	}
+	return resp, nil
+}
+
+func getWorkflowCodeSourceAndGetter(projectName string) (*commonmodels.WorkflowCodeSource, workflowCodeGetter, error) {
+	source, err := commonrepo.NewWorkflowCodeSourceColl().Find(projectName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find workflow code source of project %s, err: %w", projectName, err)
+	}
+	if !source.Enabled {
+		return nil, nil, fmt.Errorf("workflow code source of project %s is not enabled", projectName)
+	}
+
+	treeGetter, err := fs.GetTreeGetter(source.CodehostID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the client of codehost %d, err: %w", source.CodehostID, err)
+	}
+	getter, ok := treeGetter.(workflowCodeGetter)
+	if !ok {
+		return nil, nil, fmt.Errorf("workflow files in codehost %d are not supported", source.CodehostID)
+	}
+	return source, getter, nil
+}
+
+// loadWorkflowFiles reads the .yaml and .yml files in the path of the repository at the ref
+func loadWorkflowFiles(source *commonmodels.WorkflowCodeSource, getter workflowCodeGetter, ref string) ([]*workflowFile, error) {
+	nodes, err := getter.GetTree(source.GetRepoNamespace(), source.RepoName, source.Path, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the workflow files in %s of %s/%s, err: %w", source.Path, source.GetRepoNamespace(), source.RepoName, err)
+	}
+
+	files := make([]*workflowFile, 0)
+	for _, node := range nodes {
+		ext := filepath.Ext(node.Name)
+		if node.IsDir || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		file, err := loadWorkflowFile(source, getter, node.FullPath, ref)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
+
+func loadWorkflowFile(source *commonmodels.WorkflowCodeSource, getter workflowCodeGetter, path, ref string) (*workflowFile, error) {
+	content, err := getter.GetFileContent(source.GetRepoNamespace(), source.RepoName, path, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow file %s, err: %w", path, err)
+	}
+
+	file := &workflowFile{
+		path: path,
+		hash: fmt.Sprintf("%x", sha256.Sum256(content)),
+	}
+	workflow := new(commonmodels.WorkflowV4)
+	if err := yaml.Unmarshal(content, workflow); err != nil {
+		file.err = fmt.Errorf("invalid workflow yaml: %w", err)
+		return file, nil
+	}
+	if workflow.Name == "" {
+		file.err = fmt.Errorf("workflow name should not be empty")
+		return file, nil
+	}
+	file.workflow = workflow
+	return file, nil
+}
+
+func planWorkflowCodeChanges(source *commonmodels.WorkflowCodeSource, getter workflowCodeGetter, ref string, logger *zap.SugaredLogger) ([]*workflowCodePlan, error) {
+	files, err := loadWorkflowFiles(source, getter, ref)
+	if err != nil {
+		return nil, err
+	}
+	managedWorkflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: source.ProjectName, CodeManaged: true}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the workflows of project %s, err: %w", source.ProjectName, err)
+	}
+
+	plans := make([]*workflowCodePlan, 0)
+	names, paths := sets.NewString(), sets.NewString()
+	for _, file := range files {
+		paths.Insert(file.path)
+		plan := &workflowCodePlan{
+			change: &commonmodels.WorkflowCodeChange{Path: file.path},
+			file:   file,
+		}
+		plans = append(plans, plan)
+		if file.err != nil {
+			plan.change.Error = file.err.Error()
+			continue
+		}
+
+		workflow := file.workflow
+		plan.change.WorkflowName = workflow.Name
+		if names.Has(workflow.Name) {
+			plan.change.Error = fmt.Sprintf("工作流 [%s] 在多个文件中重复定义", workflow.Name)
+			continue
+		}
+		names.Insert(workflow.Name)
+		if workflow.Project != "" && workflow.Project != source.ProjectName {
+			plan.change.Error = fmt.Sprintf("工作流所属项目 [%s] 与当前项目 [%s] 不一致", workflow.Project, source.ProjectName)
+			continue
+		}
+		workflow.Project = source.ProjectName
+		if err := LintWorkflowV4(workflow, logger); err != nil {
+			plan.change.Error = err.Error()
+			continue
+		}
+
+		existing, err := commonrepo.NewWorkflowV4Coll().Find(workflow.Name)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, fmt.Errorf("failed to find workflow %s, err: %w", workflow.Name, err)
+			}
+			plan.change.Action = config.WorkflowCodeActionCreate
+			plan.change.AddedJobs, _, _ = diffWorkflowJobs(nil, workflow, false)
+			continue
+		}
+		action, errMsg := planExistingWorkflow(source, existing, file.hash)
+		if errMsg != "" {
+			plan.change.Error = errMsg
+			continue
+		}
+		plan.existing = existing
+		plan.change.Action = action
+		if action == config.WorkflowCodeActionUnchanged {
+			continue
+		}
+		// the jobs are compared with the file the workflow is synced from if it's not modified since then,
+		// only the job names are compared otherwise since the stored jobs are instantiated.
+		oldWorkflow, compareContent := existing, false
+		if isWorkflowSynced(existing) {
+			oldFile, err := loadWorkflowFile(source, getter, existing.CodeSource.Path, existing.CodeSource.CommitID)
+			if err == nil && oldFile.err == nil {
+				oldWorkflow, compareContent = oldFile.workflow, true
+			} else {
+				logger.Warnf("failed to load the synced file %s of workflow %s, err: %v", existing.CodeSource.Path, existing.Name, err)
+			}
+		}
+		plan.change.AddedJobs, plan.change.DeletedJobs, plan.change.ChangedJobs = diffWorkflowJobs(oldWorkflow, workflow, compareContent)
+	}
+
+	return append(plans, planReleasedWorkflows(managedWorkflows, names, paths)...), nil
+}
+
+// planExistingWorkflow decides how the workflow in zadig is changed by the workflow file with the same name, a workflow
+// created in zadig is only taken over by the file if adopting is enabled, an error message is returned otherwise.
+func planExistingWorkflow(source *commonmodels.WorkflowCodeSource, existing *commonmodels.WorkflowV4, hash string) (config.WorkflowCodeAction, string) {
+	if existing.Project != source.ProjectName {
+		return "", fmt.Sprintf("工作流标识 [%s] 已被项目 [%s] 使用", existing.Name, existing.Project)
+	}
+	if existing.CodeSource == nil {
+		if !source.AdoptExisting {
+			return "", fmt.Sprintf("工作流 [%s] 已在 Zadig 中创建，开启接管已有工作流后才能由代码管理", existing.Name)
+		}
+		return config.WorkflowCodeActionAdopt, ""
+	}
+	if isWorkflowSynced(existing) && existing.CodeSource.ContentHash == hash {
+		return config.WorkflowCodeActionUnchanged, ""
+	}
+	return config.WorkflowCodeActionUpdate, ""
+}
+
+// planReleasedWorkflows returns the managed workflows whose files are removed, the workflow in an invalid file is not
+// released since the sync is rejected anyway
+func planReleasedWorkflows(managedWorkflows []*commonmodels.WorkflowV4, names, paths sets.String) []*workflowCodePlan {
+	plans := make([]*workflowCodePlan, 0)
+	for _, workflow := range managedWorkflows {
+		if names.Has(workflow.Name) || paths.Has(workflow.CodeSource.Path) {
+			continue
+		}
+		plans = append(plans, &workflowCodePlan{
+			change: &commonmodels.WorkflowCodeChange{
+				WorkflowName: workflow.Name,
+				Path:         workflow.CodeSource.Path,
+				Action:       config.WorkflowCodeActionRelease,
+			},
+			existing: workflow,
+		})
+	}
+	return plans
+}
+
+// isWorkflowSynced returns whether the workflow is synced from a workflow file and not modified in zadig since then
+func isWorkflowSynced(workflow *commonmodels.WorkflowV4) bool {
+	return workflow.CodeSource != nil && workflow.CodeSource.SyncTime == workflow.UpdateTime
+}
+
+// checkWorkflowEditable rejects the changes of the workflow definition in zadig if the workflow is managed by a
+// workflow file, the triggers and custom fields are not part of the file and stay editable.
+func checkWorkflowEditable(workflow *commonmodels.WorkflowV4) error {
+	if workflow.CodeSource != nil {
+		return fmt.Errorf("工作流由代码仓库中的文件 [%s] 管理，请修改工作流文件", workflow.CodeSource.Path)
+	}
+	return nil
+}
+
+func applyWorkflowCodePlan(plan *workflowCodePlan, commitID, user string, logger *zap.SugaredLogger) error {
+	switch plan.change.Action {
+	case config.WorkflowCodeActionCreate, config.WorkflowCodeActionUpdate, config.WorkflowCodeActionAdopt:
+		workflow := plan.file.workflow
+		workflow.CodeSource = &commonmodels.WorkflowV4CodeSource{
+			Path:        plan.file.path,
+			CommitID:    commitID,
+			ContentHash: plan.file.hash,
+		}
+		if plan.existing == nil {
+			return CreateWorkflowV4(user, workflow, logger)
+		}
+		workflow.CreatedBy = plan.existing.CreatedBy
+		workflow.CreateTime = plan.existing.CreateTime
+		return updateWorkflowV4(plan.existing, user, workflow, logger)
+	case config.WorkflowCodeActionRelease:
+		return commonrepo.NewWorkflowV4Coll().UpdateCodeSource(plan.existing.Name, nil)
+	default:
+		return nil
+	}
+}
+
+func releaseCodeManagedWorkflows(projectName string) error {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName, CodeManaged: true}, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, workflow := range workflows {
+		if err := commonrepo.NewWorkflowV4Coll().UpdateCodeSource(workflow.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordWorkflowCodeSyncResult(source *commonmodels.WorkflowCodeSource, commitID string, syncErr error, logger *zap.SugaredLogger) {
+	errInfo := ""
+	if syncErr != nil {
+		errInfo = syncErr.Error()
+		logger.Errorf("failed to sync the workflow files of project %s at %s, err: %s", source.ProjectName, commitID, errInfo)
+	}
+	if err := commonrepo.NewWorkflowCodeSourceColl().UpdateSyncResult(source.ProjectName, commitID, errInfo, time.Now().Unix()); err != nil {
+		logger.Errorf("failed to record the sync result of project %s, err: %s", source.ProjectName, err)
+	}
+}
+
+// diffWorkflowJobs returns the added, deleted and changed jobs of the workflow, the jobs are only compared by names
+// if compareContent is false
+func diffWorkflowJobs(oldWorkflow, newWorkflow *commonmodels.WorkflowV4, compareContent bool) (added, deleted, changed []string) {
+	oldJobs, newJobs := workflowJobContents(oldWorkflow), workflowJobContents(newWorkflow)
+	for name, content := range newJobs {
+		oldContent, ok := oldJobs[name]
+		if !ok {
+			added = append(added, name)
+		} else if compareContent && oldContent != content {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldJobs {
+		if _, ok := newJobs[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(deleted)
+	sort.Strings(changed)
+	return
+}
+
+func workflowJobContents(workflow *commonmodels.WorkflowV4) map[string]string {
+	jobs := make(map[string]string)
+	if workflow == nil {
+		return jobs
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			content, _ := yaml.Marshal(job)
+			jobs[job.Name] = string(content)
+		}
+	}
+	return jobs
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing workflow as code", func() {
+
+	newWorkflow := func(jobs map[string]string) *commonmodels.WorkflowV4 {
+		stage := &commonmodels.WorkflowStage{Name: "stage"}
+		for name, script := range jobs {
+			stage.Jobs = append(stage.Jobs, &commonmodels.Job{Name: name, JobType: config.JobFreestyle, Spec: map[string]string{"script": script}})
+		}
+		return &commonmodels.WorkflowV4{Name: "workflow", Project: "project", Stages: []*commonmodels.WorkflowStage{stage}}
+	}
+
+	Context("diffWorkflowJobs", func() {
+		oldWorkflow := newWorkflow(map[string]string{"build": "make", "test": "make test", "lint": "make lint"})
+		newWorkflowWithChanges := newWorkflow(map[string]string{"build": "make all", "test": "make test", "deploy": "kubectl apply"})
+
+		It("should compare the contents of the jobs", func() {
+			added, deleted, changed := diffWorkflowJobs(oldWorkflow, newWorkflowWithChanges, true)
+			Expect(added).To(Equal([]string{"deploy"}))
+			Expect(deleted).To(Equal([]string{"lint"}))
+			Expect(changed).To(Equal([]string{"build"}))
+		})
+
+		It("should only compare the names of the jobs", func() {
+			added, deleted, changed := diffWorkflowJobs(oldWorkflow, newWorkflowWithChanges, false)
+			Expect(added).To(Equal([]string{"deploy"}))
+			Expect(deleted).To(Equal([]string{"lint"}))
+			Expect(changed).To(BeEmpty())
+		})
+
+		It("should treat all the jobs of a new workflow as added", func() {
+			added, deleted, changed := diffWorkflowJobs(nil, oldWorkflow, false)
+			Expect(added).To(Equal([]string{"build", "lint", "test"}))
+			Expect(deleted).To(BeEmpty())
+			Expect(changed).To(BeEmpty())
+		})
+	})
+
+	Context("planExistingWorkflow", func() {
+		source := &commonmodels.WorkflowCodeSource{ProjectName: "project"}
+		adoptSource := &commonmodels.WorkflowCodeSource{ProjectName: "project", AdoptExisting: true}
+		managed := func(hash string, syncTime int64) *commonmodels.WorkflowV4 {
+			workflow := newWorkflow(nil)
+			workflow.UpdateTime = 100
+			workflow.CodeSource = &commonmodels.WorkflowV4CodeSource{Path: "workflow.yaml", ContentHash: hash, SyncTime: syncTime}
+			return workflow
+		}
+
+		DescribeTable("should decide the action",
+			func(source *commonmodels.WorkflowCodeSource, existing *commonmodels.WorkflowV4, expected config.WorkflowCodeAction, rejected bool) {
+				action, errMsg := planExistingWorkflow(source, existing, "hash")
+				Expect(action).To(Equal(expected))
+				Expect(errMsg != "").To(Equal(rejected))
+			},
+			Entry("unchanged file", source, managed("hash", 100), config.WorkflowCodeActionUnchanged, false),
+			Entry("changed file", source, managed("old-hash", 100), config.WorkflowCodeActionUpdate, false),
+			Entry("modified in zadig", source, managed("hash", 50), config.WorkflowCodeActionUpdate, false),
+			Entry("created in zadig", source, newWorkflow(nil), config.WorkflowCodeAction(""), true),
+			Entry("created in zadig and adopting is enabled", adoptSource, newWorkflow(nil), config.WorkflowCodeActionAdopt, false),
+			Entry("used by another project", adoptSource, &commonmodels.WorkflowV4{Name: "workflow", Project: "another"}, config.WorkflowCodeAction(""), true),
+		)
+	})
+
+	Context("planReleasedWorkflows", func() {
+		It("should release the workflows whose files are removed", func() {
+			managedWorkflow := func(name, path string) *commonmodels.WorkflowV4 {
+				return &commonmodels.WorkflowV4{Name: name, CodeSource: &commonmodels.WorkflowV4CodeSource{Path: path}}
+			}
+			plans := planReleasedWorkflows([]*commonmodels.WorkflowV4{
+				managedWorkflow("kept", "kept.yaml"),
+				managedWorkflow("moved", "old.yaml"),
+				managedWorkflow("invalid", "invalid.yaml"),
+				managedWorkflow("removed", "removed.yaml"),
+			}, sets.NewString("kept", "moved"), sets.NewString("kept.yaml", "new.yaml", "invalid.yaml"))
+			Expect(plans).To(HaveLen(1))
+			Expect(plans[0].change.WorkflowName).To(Equal("removed"))
+			Expect(plans[0].change.Action).To(Equal(config.WorkflowCodeActionRelease))
+		})
+	})
+
+	Context("checkWorkflowEditable", func() {
+		It("should reject the changes of the workflows managed by the workflow files", func() {
+			workflow := newWorkflow(nil)
+			Expect(checkWorkflowEditable(workflow)).To(Succeed())
+			workflow.CodeSource = &commonmodels.WorkflowV4CodeSource{Path: "workflow.yaml"}
+			Expect(checkWorkflowEditable(workflow)).NotTo(Succeed())
+		})
+	})
+})
@@ -43,6 +43,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/scmnotify"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/workflowpolicy"
 	commontypes "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/types"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	jobctl "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
@@ -88,6 +89,12 @@ type WorkflowTaskPreview struct {
 	Error               string                `bson:"error,omitempty"           json:"error,omitempty"`
 	IsRestart           bool                  `bson:"is_restart"                json:"is_restart"`
 	Debug               bool                  `bson:"debug"                     json:"debug"`
+	// JobDependencies is the upstream jobs of each job, only set when the task is scheduled by the job dependency graph
+	JobDependencies map[string][]string `bson:"job_dependencies" json:"job_dependencies,omitempty"`
+	// PolicyViolations is the workflow policies violated when the task is created
+	PolicyViolations []*commonmodels.PolicyViolation `bson:"policy_violations" json:"policy_violations,omitempty"`
+	// TaskCreatorIsServiceAccount marks the tasks created by service accounts, which are not humans
+	TaskCreatorIsServiceAccount bool `bson:"task_creator_is_service_account" json:"task_creator_is_service_account"`
 }
 
 type StageTaskPreview struct {
@@ -99,6 +106,8 @@ type StageTaskPreview struct {
 	Approval  *commonmodels.Approval `bson:"approval"      json:"approval"`
 	Jobs      []*JobTaskPreview      `bson:"jobs"          json:"jobs"`
 	Error     string                 `bson:"error" json:"error""`
+	// SkipReason is set when the stage is skipped by its condition
+	SkipReason string `bson:"skip_reason"   json:"skip_reason,omitempty"`
 }
 
 type JobTaskPreview struct {
@@ -114,6 +123,13 @@ type JobTaskPreview struct {
 	Spec             interface{}   `bson:"spec"           json:"spec"`
 	// JobInfo contains the fields that make up the job task name, for frontend display
 	JobInfo interface{} `bson:"job_info" json:"job_info"`
+	// SkipReason is set when the job is skipped by its condition
+	SkipReason string `bson:"skip_reason" json:"skip_reason,omitempty"`
+	OriginName string `bson:"origin_name" json:"origin_name"`
+	// Attempts is the history of the attempts when the job is retried by its retry policy
+	Attempts []*commonmodels.JobAttempt `bson:"attempts" json:"attempts,omitempty"`
+	// PolicyViolations is the workflow policies violated before the job runs
+	PolicyViolations []*commonmodels.PolicyViolation `bson:"policy_violations" json:"policy_violations,omitempty"`
 }
 
 type ZadigBuildJobSpec struct {
@@ -302,9 +318,10 @@ func CheckWorkflowV4ApprovalInitiator(workflowName, uid string, log *zap.Sugared
 }
 
 type CreateWorkflowTaskV4Args struct {
-	Name    string
-	Account string
-	UserID  string
+	Name         string
+	Account      string
+	UserID       string
+	IdentityType string
 }
 
 func CreateWorkflowTaskV4ByBuildInTrigger(triggerName string, args *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
@@ -393,6 +410,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 
 	workflowTask.TaskID = nextTaskID
 	workflowTask.TaskCreator = args.Name
+	workflowTask.TaskCreatorIsServiceAccount = args.IdentityType == setting.ServiceAccountIdentityType
 	workflowTask.TaskRevoker = args.Name
 	workflowTask.CreateTime = time.Now().Unix()
 	workflowTask.WorkflowName = workflow.Name
@@ -410,6 +428,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 			Name:     stage.Name,
 			Parallel: stage.Parallel,
 			Approval: stage.Approval,
+			When:     stage.When,
 		}
 		for _, job := range stage.Jobs {
 			if jobctl.JobSkiped(job) {
@@ -459,6 +478,13 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 			}
 			// add breakpoint_before when workflowTask is debug mode
 			for _, jobTask := range jobs {
+				jobTask.When = job.When
+				jobTask.OriginName = job.Name
+				jobTask.DependsOn = job.DependsOn
+				jobTask.RetryPolicy = workflow.RetryPolicy
+				if job.RetryPolicy != nil {
+					jobTask.RetryPolicy = job.RetryPolicy
+				}
 				switch config.JobType(jobTask.JobType) {
 				case config.JobFreestyle, config.JobZadigTesting, config.JobZadigBuild, config.JobZadigScanning:
 					if workflowTask.IsDebug {
@@ -492,6 +518,17 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 		return resp, e.ErrCreateTask.AddDesc(err.Error())
 	}
 
+	violations, err := workflowpolicy.EvaluateTaskCreation(workflowTask, log)
+	workflowTask.PolicyViolations = violations
+	if err != nil {
+		log.Errorf("workflow task %s denied by policy: %v", workflowTask.WorkflowName, err)
+		// the denied task is kept so the violations can be found in the task detail
+		if createErr := workflowcontroller.CreateRejectedTask(workflowTask, err.Error()); createErr != nil {
+			log.Errorf("failed to save the denied workflow task %s, error: %v", workflowTask.WorkflowName, createErr)
+		}
+		return resp, e.ErrWorkflowPolicyViolated.AddDesc(err.Error())
+	}
+
 	if err := instantmessage.NewWeChatClient().SendWorkflowTaskNotifications(workflowTask); err != nil {
 		log.Errorf("send workflow task notification failed, error: %v", err)
 	}
@@ -531,6 +568,12 @@ func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 	default:
 		return errors.New("工作流任务状态无法重试")
 	}
+	// the task denied by a workflow policy at creation never ran, retrying it would bypass the policy
+	for _, violation := range task.PolicyViolations {
+		if violation.Checkpoint == config.PolicyCheckpointTaskCreate && violation.Enforcement == config.PolicyEnforcementBlock {
+			return errors.New("工作流任务被工作流策略拒绝, 无法重试")
+		}
+	}
 
 	if task.OriginWorkflowArgs == nil || task.OriginWorkflowArgs.Stages == nil {
 		return errors.New("工作流任务数据异常, 无法重试")
@@ -564,6 +607,7 @@ func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 		stage.StartTime = 0
 		stage.EndTime = 0
 		stage.Error = ""
+		stage.SkipReason = ""
 
 		if stage.Approval != nil && stage.Approval.Enabled &&
 			stage.Approval.Status != config.StatusPassed && stage.Approval.Status != "" {
@@ -578,6 +622,7 @@ func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 			jobTask.StartTime = 0
 			jobTask.EndTime = 0
 			jobTask.Error = ""
+			jobTask.SkipReason = ""
 			if t, ok := jobTaskMap[jobTask.Key]; ok {
 				jobTask.Spec = t.Spec
 			} else {
@@ -908,6 +953,8 @@ func ListWorkflowTaskV4ByFilter(filter *TaskHistoryFilter, filterList []string,
 			CreateTime:          task.CreateTime,
 			StartTime:           task.StartTime,
 			EndTime:             task.EndTime,
+
+			TaskCreatorIsServiceAccount: task.TaskCreatorIsServiceAccount,
 		}
 
 		stagePreviews := make([]*commonmodels.StagePreview, 0)
@@ -1145,30 +1192,37 @@ func GetWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLog
 		Error:               task.Error,
 		IsRestart:           task.IsRestart,
 		Debug:               task.IsDebug,
+		PolicyViolations:    task.PolicyViolations,
+
+		TaskCreatorIsServiceAccount: task.TaskCreatorIsServiceAccount,
 	}
 	timeNow := time.Now().Unix()
 	for _, stage := range task.Stages {
 		resp.Stages = append(resp.Stages, &StageTaskPreview{
-			Name:      stage.Name,
-			Status:    stage.Status,
-			StartTime: stage.StartTime,
-			EndTime:   stage.EndTime,
-			Parallel:  stage.Parallel,
-			Approval:  stage.Approval,
-			Jobs:      jobsToJobPreviews(stage.Jobs, task.GlobalContext, timeNow, task.ProjectName),
-			Error:     stage.Error,
+			Name:       stage.Name,
+			Status:     stage.Status,
+			StartTime:  stage.StartTime,
+			EndTime:    stage.EndTime,
+			Parallel:   stage.Parallel,
+			Approval:   stage.Approval,
+			Jobs:       jobsToJobPreviews(stage.Jobs, task.GlobalContext, timeNow, task.ProjectName),
+			Error:      stage.Error,
+			SkipReason: stage.SkipReason,
 		})
 	}
+	if workflowcontroller.IsDAGWorkflowTask(task.Stages) {
+		resp.JobDependencies = workflowcontroller.GetJobTaskDependencies(task.Stages)
+	}
 	return resp, nil
 }
 
-func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool, logger *zap.SugaredLogger) error {
+func ApproveStage(workflowName, stageName, userName, userID, identityType, comment string, taskID int64, approve bool, logger *zap.SugaredLogger) error {
 	if workflowName == "" || stageName == "" || taskID == 0 {
 		errMsg := fmt.Sprintf("can not find approved workflow: %s, taskID: %d,stage: %s", workflowName, taskID, stageName)
 		logger.Error(errMsg)
 		return e.ErrApproveTask.AddDesc(errMsg)
 	}
-	if err := workflowcontroller.ApproveStage(workflowName, stageName, userName, userID, comment, taskID, approve); err != nil {
+	if err := workflowcontroller.ApproveStage(workflowName, stageName, userName, userID, identityType, comment, taskID, approve); err != nil {
 		logger.Error(err)
 		return e.ErrApproveTask.AddErr(err)
 	}
@@ -1211,6 +1265,10 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 			BreakpointAfter:  job.BreakpointAfter,
 			CostSeconds:      costSeconds,
 			JobInfo:          job.JobInfo,
+			SkipReason:       job.SkipReason,
+			OriginName:       job.OriginName,
+			Attempts:         job.Attempts,
+			PolicyViolations: job.PolicyViolations,
 		}
 		switch job.JobType {
 		case string(config.JobFreestyle):
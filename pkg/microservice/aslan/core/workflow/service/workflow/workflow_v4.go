@@ -65,6 +65,7 @@ import (
 	commomtemplate "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/template"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/service/webhook"
 	commontypes "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/types"
+	commonutil "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	jobctl "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	"github.com/koderover/zadig/v2/pkg/microservice/picket/client/opa"
@@ -79,6 +80,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/tool/kube/serializer"
 	"github.com/koderover/zadig/v2/pkg/tool/lark"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
+	"github.com/koderover/zadig/v2/pkg/tool/sqlexec"
 	"github.com/koderover/zadig/v2/pkg/types"
 )
 
@@ -106,6 +108,9 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 	workflow.UpdatedBy = user
 	workflow.CreateTime = time.Now().Unix()
 	workflow.UpdateTime = time.Now().Unix()
+	if workflow.CodeSource != nil {
+		workflow.CodeSource.SyncTime = workflow.UpdateTime
+	}
 
 	if err := jobctl.InstantiateWorkflow(workflow); err != nil {
 		logger.Errorf("instantiate workflow error: %s", err)
@@ -250,6 +255,13 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", name, err)
 		return e.ErrFindWorkflow.AddErr(err)
 	}
+	if err := checkWorkflowEditable(workflow); err != nil {
+		return e.ErrUpsertWorkflow.AddDesc(err.Error())
+	}
+	return updateWorkflowV4(workflow, user, inputWorkflow, logger)
+}
+
+func updateWorkflowV4(workflow *commonmodels.WorkflowV4, user string, inputWorkflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
 	if workflow.DisplayName != inputWorkflow.DisplayName {
 		existedWorkflows, _, _ := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: workflow.Project, DisplayName: inputWorkflow.DisplayName}, 0, 0)
 		if len(existedWorkflows) > 0 {
@@ -257,12 +269,18 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 			return e.ErrUpsertWorkflow.AddDesc(errStr)
 		}
 	}
+	if err := restoreNotifySecrets(inputWorkflow.NotifyCtls, workflow.NotifyCtls); err != nil {
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
 	if err := LintWorkflowV4(inputWorkflow, logger); err != nil {
 		return err
 	}
 
 	inputWorkflow.UpdatedBy = user
 	inputWorkflow.UpdateTime = time.Now().Unix()
+	if inputWorkflow.CodeSource != nil {
+		inputWorkflow.CodeSource.SyncTime = inputWorkflow.UpdateTime
+	}
 	inputWorkflow.ID = workflow.ID
 	inputWorkflow.HookCtls = workflow.HookCtls
 	inputWorkflow.JiraHookCtls = workflow.JiraHookCtls
@@ -326,6 +344,9 @@ func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
 		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
 		return e.ErrDeleteWorkflow.AddErr(err)
 	}
+	if err := checkWorkflowEditable(workflow); err != nil {
+		return e.ErrDeleteWorkflow.AddDesc(err.Error())
+	}
 	if err := commonrepo.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
 		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
 		return e.ErrDeleteWorkflow.AddErr(err)
@@ -706,7 +727,38 @@ func clearWorkflowV4Triggers(workflow *commonmodels.WorkflowV4) {
 	workflow.JiraHookCtls = nil
 }
 
+// maskNotifySecrets masks the webhook secrets of the notifications in the response
+func maskNotifySecrets(notifyCtls []*commonmodels.NotifyCtl) {
+	for _, notify := range notifyCtls {
+		if notify.WebHookNotify != nil && notify.WebHookNotify.Secret != "" {
+			notify.WebHookNotify.Secret = setting.MaskValue
+		}
+	}
+}
+
+// restoreNotifySecrets keeps the saved secret of the webhook if the masked secret is submitted
+func restoreNotifySecrets(notifyCtls, savedNotifyCtls []*commonmodels.NotifyCtl) error {
+	savedSecrets := make(map[string]string)
+	for _, notify := range savedNotifyCtls {
+		if notify.WebHookNotify != nil {
+			savedSecrets[notify.WebHookNotify.Address] = notify.WebHookNotify.Secret
+		}
+	}
+	for _, notify := range notifyCtls {
+		if notify.WebHookNotify == nil || notify.WebHookNotify.Secret != setting.MaskValue {
+			continue
+		}
+		secret, ok := savedSecrets[notify.WebHookNotify.Address]
+		if !ok {
+			return fmt.Errorf("the secret of webhook %s is required", notify.WebHookNotify.Address)
+		}
+		notify.WebHookNotify.Secret = secret
+	}
+	return nil
+}
+
 func ensureWorkflowV4Resp(encryptedKey string, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	maskNotifySecrets(workflow.NotifyCtls)
 	for _, stage := range workflow.Stages {
 		for _, job := range stage.Jobs {
 			if job.JobType == config.JobZadigBuild {
@@ -916,6 +968,18 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 			}
 		}
 	}
+	if err := validateJobDependencies(workflow); err != nil {
+		logger.Errorf("lint workflow job dependencies failed: %v", err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := validateWorkflowConditions(workflow); err != nil {
+		logger.Errorf("lint workflow conditions failed: %v", err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := validateRetryPolicies(workflow); err != nil {
+		logger.Errorf("lint workflow retry policies failed: %v", err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
 	return nil
 }
 
@@ -1664,6 +1728,8 @@ func BulkCopyWorkflowV4(args BulkCopyWorkflowArgs, username string, log *zap.Sug
 			newItem.ID = primitive.NewObjectID()
 			// do not copy webhook triggers.
 			newItem.HookCtls = []*commonmodels.WorkflowV4Hook{}
+			// the copy is not managed by the workflow file of the original workflow
+			newItem.CodeSource = nil
 
 			newWorkflows = append(newWorkflows, &newItem)
 		} else {
@@ -2733,6 +2799,16 @@ func ValidateSQL(_type config.DBInstanceType, sql string) error {
 	switch _type {
 	case config.DBInstanceTypeMySQL, config.DBInstanceTypeMariaDB:
 		return ValidateMySQL(sql)
+	case config.DBInstanceTypePostgreSQL, config.DBInstanceTypeSQLServer:
+		// there is no parser for these dialects, only check that the script can be split into statements
+		dialect, err := commonutil.GetSQLDialect(_type)
+		if err != nil {
+			return err
+		}
+		if _, err := sqlexec.SplitStatements(dialect, sql); err != nil {
+			return errors.Errorf("parse sql statement error: %v", err)
+		}
+		return nil
 	default:
 		return errors.Errorf("not supported db type: %s", _type)
 	}
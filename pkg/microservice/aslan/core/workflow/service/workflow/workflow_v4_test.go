@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/v2/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/v2/pkg/setting"
+)
+
+var _ = Describe("Testing workflow notification secrets", func() {
+
+	newNotifyCtls := func(address, secret string) []*commonmodels.NotifyCtl {
+		return []*commonmodels.NotifyCtl{
+			{WebHookType: "webhook", WebHookNotify: &commonmodels.WebHookNotify{Address: address, Secret: secret}},
+			{WebHookType: "wechat", WeChatWebHook: "https://qyapi.weixin.qq.com/cgi-bin/webhook/send"},
+		}
+	}
+
+	It("should mask the secrets in the response", func() {
+		notifyCtls := newNotifyCtls("https://example.com/hook", "secret")
+		maskNotifySecrets(notifyCtls)
+		Expect(notifyCtls[0].WebHookNotify.Secret).To(Equal(setting.MaskValue))
+
+		notifyCtls = newNotifyCtls("https://example.com/hook", "")
+		maskNotifySecrets(notifyCtls)
+		Expect(notifyCtls[0].WebHookNotify.Secret).To(BeEmpty())
+	})
+
+	It("should restore the saved secret if the masked secret is submitted", func() {
+		notifyCtls := newNotifyCtls("https://example.com/hook", setting.MaskValue)
+		Expect(restoreNotifySecrets(notifyCtls, newNotifyCtls("https://example.com/hook", "secret"))).To(Succeed())
+		Expect(notifyCtls[0].WebHookNotify.Secret).To(Equal("secret"))
+	})
+
+	It("should keep the submitted secret", func() {
+		notifyCtls := newNotifyCtls("https://example.com/hook", "new-secret")
+		Expect(restoreNotifySecrets(notifyCtls, newNotifyCtls("https://example.com/hook", "secret"))).To(Succeed())
+		Expect(notifyCtls[0].WebHookNotify.Secret).To(Equal("new-secret"))
+	})
+
+	It("should require the secret of a new webhook", func() {
+		notifyCtls := newNotifyCtls("https://example.com/another", setting.MaskValue)
+		Expect(restoreNotifySecrets(notifyCtls, newNotifyCtls("https://example.com/hook", "secret"))).NotTo(Succeed())
+	})
+})
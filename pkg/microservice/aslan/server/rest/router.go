@@ -69,6 +69,17 @@ func init() {
 	metrics.Metrics.MustRegister(metrics.CPU)
 	metrics.Metrics.MustRegister(metrics.Memory)
 	metrics.Metrics.MustRegister(metrics.ResponseTime)
+	metrics.Metrics.MustRegister(metrics.WorkflowQueueWaitTime)
+	metrics.Metrics.MustRegister(metrics.WorkflowDuration)
+	metrics.Metrics.MustRegister(metrics.JobDuration)
+	metrics.Metrics.MustRegister(metrics.JobFailures)
+	metrics.Metrics.MustRegister(metrics.ApprovalWaitTime)
+	metrics.Metrics.MustRegister(metrics.DindPods)
+	metrics.Metrics.MustRegister(metrics.DindCPU)
+	metrics.Metrics.MustRegister(metrics.DindMemory)
+	metrics.Metrics.MustRegister(metrics.ExecutorPods)
+	metrics.Metrics.MustRegister(metrics.ExecutorCPU)
+	metrics.Metrics.MustRegister(metrics.ExecutorMemory)
 
 	metrics.UpdatePodMetrics()
 }
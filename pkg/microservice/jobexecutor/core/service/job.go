@@ -140,6 +140,9 @@ func (j *Job) Run(ctx context.Context) error {
 	if err := os.MkdirAll(job.JobOutputDir, os.ModePerm); err != nil {
 		return err
 	}
+	if err := step.ValidateSteps(j.Ctx.Steps); err != nil {
+		return err
+	}
 	hasFailed := false
 	var respErr error
 	for _, stepInfo := range j.Ctx.Steps {
@@ -28,76 +28,88 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/jobexecutor/core/service/cmd"
 	"github.com/koderover/zadig/v2/pkg/microservice/jobexecutor/core/service/configmap"
 	"github.com/koderover/zadig/v2/pkg/microservice/jobexecutor/core/service/meta"
+	sharedstep "github.com/koderover/zadig/v2/pkg/shared/step"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 	"github.com/koderover/zadig/v2/pkg/util"
 )
 
-type Step interface {
-	Run(ctx context.Context) error
+type Step = sharedstep.Step
+
+// registry is the steps the job executor can run, the steps shared with zadig-agent are registered in the shared step package
+var registry = sharedstep.NewRegistry()
+
+func init() {
+	registerStep("shell", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewShellStep(spec, c.Workspace, c.Paths, c.Envs, c.SecretEnvs))
+	})
+	registerStep("git", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewGitStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("docker_build", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewDockerBuildStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("tools", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewToolInstallStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("archive", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewArchiveStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("junit_report", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewJunitReportStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("tar_archive", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewTararchiveStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("sonar_check", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewSonarCheckStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("distribute_image", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		return newStep(NewDistributeImageStep(spec, c.Workspace, c.Envs, c.SecretEnvs))
+	})
+	registerStep("debug_before", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		updater, _ := c.Runtime.(configmap.Updater)
+		return newStep(NewDebugStep("before", c.Workspace, c.Envs, c.SecretEnvs, updater))
+	})
+	registerStep("debug_after", func(spec interface{}, c *sharedstep.Context) (Step, error) {
+		updater, _ := c.Runtime.(configmap.Updater)
+		return newStep(NewDebugStep("after", c.Workspace, c.Envs, c.SecretEnvs, updater))
+	})
 }
 
-func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs, secretEnvs []string, updater configmap.Updater) error {
-	var stepInstance Step
-	var err error
+func registerStep(stepType string, factory sharedstep.Factory) {
+	registry.Register(&sharedstep.Definition{
+		Type: stepType,
+		New:  factory,
+	})
+}
 
-	switch step.StepType {
-	case "shell":
-		stepInstance, err = NewShellStep(step.Spec, workspace, paths, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "git":
-		stepInstance, err = NewGitStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "docker_build":
-		stepInstance, err = NewDockerBuildStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "tools":
-		stepInstance, err = NewToolInstallStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "archive":
-		stepInstance, err = NewArchiveStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "junit_report":
-		stepInstance, err = NewJunitReportStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "tar_archive":
-		stepInstance, err = NewTararchiveStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "sonar_check":
-		stepInstance, err = NewSonarCheckStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "distribute_image":
-		stepInstance, err = NewDistributeImageStep(step.Spec, workspace, envs, secretEnvs)
-		if err != nil {
-			return err
-		}
-	case "debug_before":
-		stepInstance, err = NewDebugStep("before", workspace, envs, secretEnvs, updater)
-		if err != nil {
-			return err
-		}
-	case "debug_after":
-		stepInstance, err = NewDebugStep("after", workspace, envs, secretEnvs, updater)
-		if err != nil {
-			return err
+func newStep[T Step](step T, err error) (Step, error) {
+	if err != nil {
+		return nil, err
+	}
+	return step, nil
+}
+
+// ValidateSteps validates the step specs before the job starts
+func ValidateSteps(steps []*meta.Step) error {
+	for _, step := range steps {
+		if err := registry.Validate(step.StepType, step.Spec); err != nil {
+			return fmt.Errorf("step %s: %v", step.Name, err)
 		}
-	default:
-		err := fmt.Errorf("step type: %s does not match any known type", step.StepType)
+	}
+	return nil
+}
+
+func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs, secretEnvs []string, updater configmap.Updater) error {
+	stepInstance, err := registry.New(step.StepType, step.Spec, &sharedstep.Context{
+		Workspace:  workspace,
+		Paths:      paths,
+		Envs:       envs,
+		SecretEnvs: secretEnvs,
+		Logger:     stdoutLogger{},
+		Runtime:    updater,
+	})
+	if err != nil {
 		log.Error(err)
 		return err
 	}
@@ -108,6 +120,13 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 	return nil
 }
 
+// stdoutLogger writes the logs of the shared steps to stdout, which is collected as the job log
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, a ...any) {
+	fmt.Printf(format, a...)
+}
+
 func prepareScriptsEnv() []string {
 	scripts := []string{}
 	scripts = append(scripts, "eval $(ssh-agent -s) > /dev/null")
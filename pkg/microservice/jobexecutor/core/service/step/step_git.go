@@ -140,7 +140,11 @@ func (s *GitStep) runGitCmds() error {
 			tokens = append(tokens, repo.SSHKey)
 		}
 		tokens = append(tokens, repo.OauthToken)
-		cmds = append(cmds, s.buildGitCommands(repo, hostNames)...)
+		gitCmds, err := s.buildGitCommands(repo, hostNames)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, gitCmds...)
 	}
 	// write ssh key
 	if len(hostNames.List()) > 0 {
@@ -188,12 +192,12 @@ func (s *GitStep) runGitCmds() error {
 	return nil
 }
 
-func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String) []*c.Command {
+func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String) ([]*c.Command, error) {
 
 	cmds := make([]*c.Command, 0)
 
 	if len(repo.RepoName) == 0 {
-		return cmds
+		return cmds, nil
 	}
 
 	workDir := filepath.Join(s.workspace, repo.RepoName)
@@ -244,6 +248,15 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 		})
 	} else if repo.Source == types.ProviderGitee || repo.Source == types.ProviderGiteeEE {
 		cmds = append(cmds, &c.Command{Cmd: c.RemoteAdd(repo.RemoteName, HTTPSCloneURL(repo.Source, repo.OauthToken, repo.RepoOwner, repo.RepoName, repo.Address)), DisableTrace: true})
+	} else if repo.Source == types.ProviderBitbucket || repo.Source == types.ProviderBitbucketServer || repo.Source == types.ProviderGitea {
+		cloneURL, err := types.BasicAuthCloneURL(repo.Source, repo.Address, repo.Username, repo.OauthToken, owner, repo.RepoName)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, &c.Command{
+			Cmd:          c.RemoteAdd(repo.RemoteName, cloneURL),
+			DisableTrace: true,
+		})
 	} else if repo.Source == types.ProviderOther {
 		if repo.AuthType == types.SSHAuthType {
 			host := getHost(repo.Address)
@@ -281,7 +294,7 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 
 	ref := repo.Ref()
 	if ref == "" {
-		return cmds
+		return cmds, nil
 	}
 
 	cmds = append(cmds, &c.Command{Cmd: c.Fetch(repo.RemoteName, ref)}, &c.Command{Cmd: c.CheckoutHead()})
@@ -312,7 +325,7 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 
 	setCmdsWorkDir(workDir, cmds)
 
-	return cmds
+	return cmds, nil
 }
 
 func writeSSHFile(sshKey, hostName string) error {
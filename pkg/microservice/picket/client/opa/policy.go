@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+// UpsertPolicy creates or replaces the rego module with the given id in OPA
+func (c *Client) UpsertPolicy(id, module string) error {
+	url := fmt.Sprintf("v1/policies/%s", id)
+	_, err := c.Put(url, httpclient.SetHeader("Content-Type", "text/plain"), httpclient.SetBody(module))
+	return err
+}
+
+// GetPolicy returns the raw rego module with the given id in OPA, the error can be checked by httpclient.IsNotFound
+// if the module doesn't exist
+func (c *Client) GetPolicy(id string) (string, error) {
+	res := &struct {
+		Result struct {
+			Raw string `json:"raw"`
+		} `json:"result"`
+	}{}
+	url := fmt.Sprintf("v1/policies/%s", id)
+	if _, err := c.Get(url, httpclient.SetResult(res)); err != nil {
+		return "", err
+	}
+	return res.Result.Raw, nil
+}
+
+// DeletePolicy removes the rego module with the given id from OPA
+func (c *Client) DeletePolicy(id string) error {
+	url := fmt.Sprintf("v1/policies/%s", id)
+	_, err := c.Delete(url)
+	return err
+}
+
+// Query evaluates the document under the given package path with an arbitrary input,
+// the response is a json object which has a field called "result", which is absent
+// if the document is undefined
+func (c *Client) Query(path string, input interface{}, result interface{}) error {
+	req := struct {
+		Input interface{} `json:"input"`
+	}{
+		Input: input,
+	}
+
+	queryURL := fmt.Sprintf("v1/data/%s", strings.ReplaceAll(path, ".", "/"))
+	_, err := c.Post(queryURL, httpclient.SetBody(req), httpclient.SetResult(result))
+	return err
+}
@@ -295,7 +295,8 @@ func (c *CodehostColl) updateCodeHost(query bson.M, host *models.CodeHost) (*mod
 		"alias":          host.Alias,
 		"updated_at":     time.Now().Unix(),
 	}
-	if host.Type == setting.SourceFromGerrit {
+	if host.Type == setting.SourceFromGerrit || host.Type == setting.SourceFromBitbucket ||
+		host.Type == setting.SourceFromBitbucketServer || host.Type == setting.SourceFromGitea {
 		modifyValue["access_token"] = host.AccessToken
 	} else if host.Type == setting.SourceFromGitee || host.Type == setting.SourceFromGitlab || host.Type == setting.SourceFromGiteeEE {
 		modifyValue["access_token"] = host.AccessToken
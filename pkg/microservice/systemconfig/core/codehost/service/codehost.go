@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -34,6 +35,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	"github.com/koderover/zadig/v2/pkg/shared/client/aslan"
+	"github.com/koderover/zadig/v2/pkg/tool/bitbucket"
 	"github.com/koderover/zadig/v2/pkg/tool/crypto"
 )
 
@@ -47,6 +49,13 @@ func CreateSystemCodeHost(codehost *models.CodeHost, _ *zap.SugaredLogger) (*mod
 		codehost.IsReady = "2"
 		codehost.AccessToken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", codehost.Username, codehost.Password)))
 	}
+	if isTokenAuthCodeHost(codehost.Type) {
+		// bitbucket and gitea are authorized by the access token directly, no oauth is required
+		codehost.IsReady = "2"
+		if err := lintTokenAuthCodeHost(codehost); err != nil {
+			return nil, err
+		}
+	}
 
 	if codehost.Alias != "" {
 		if _, err := mongodb.NewCodehostColl().GetSystemCodeHostByAlias(codehost.Alias); err == nil {
@@ -138,6 +147,11 @@ func UpdateCodeHost(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHo
 	if host.Type == setting.SourceFromGerrit {
 		host.AccessToken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", host.Username, host.Password)))
 	}
+	if isTokenAuthCodeHost(host.Type) {
+		if err := lintTokenAuthCodeHost(host); err != nil {
+			return nil, err
+		}
+	}
 
 	var oldAlias string
 	oldCodeHost, err := mongodb.NewCodehostColl().GetCodeHostByID(host.ID, false)
@@ -153,6 +167,30 @@ func UpdateCodeHost(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHo
 	return mongodb.NewCodehostColl().UpdateCodeHost(host)
 }
 
+func isTokenAuthCodeHost(codeHostType string) bool {
+	switch codeHostType {
+	case setting.SourceFromBitbucket, setting.SourceFromBitbucketServer, setting.SourceFromGitea:
+		return true
+	}
+	return false
+}
+
+func lintTokenAuthCodeHost(host *models.CodeHost) error {
+	if host.AccessToken == "" {
+		return fmt.Errorf("access token cannot be empty")
+	}
+	if host.Type == setting.SourceFromBitbucket {
+		// bitbucket cloud always lives on bitbucket.org
+		host.Address = bitbucket.CloudAddress
+		return nil
+	}
+	if host.Address == "" {
+		return fmt.Errorf("address cannot be empty")
+	}
+	host.Address = strings.TrimSuffix(host.Address, "/")
+	return nil
+}
+
 func UpdateCodeHostToken(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHost, error) {
 	return mongodb.NewCodehostColl().UpdateCodeHostToken(host)
 }
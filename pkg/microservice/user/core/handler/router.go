@@ -41,6 +41,20 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		users.POST("/search", user.ListUsers)
 		users.GET("/count", user.CountSystemUsers)
 		users.GET("/check/duplicate", user.CheckDuplicateUser)
+
+		users.POST("/:uid/tokens", user.CreateUserToken)
+		users.GET("/:uid/tokens", user.ListUserTokens)
+		users.DELETE("/:uid/tokens/:id", user.RevokeUserToken)
+	}
+
+	serviceAccounts := router.Group("/service-accounts")
+	{
+		serviceAccounts.POST("", user.CreateServiceAccount)
+		serviceAccounts.GET("", user.ListServiceAccounts)
+		serviceAccounts.DELETE("/:uid", user.DeleteServiceAccount)
+		serviceAccounts.POST("/:uid/tokens", user.CreateServiceAccountToken)
+		serviceAccounts.GET("/:uid/tokens", user.ListServiceAccountTokens)
+		serviceAccounts.DELETE("/:uid/tokens/:id", user.RevokeServiceAccountToken)
 	}
 
 	usergroups := router.Group("user-group")
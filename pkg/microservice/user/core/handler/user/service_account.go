@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/user"
+	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+func CreateServiceAccount(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(user.CreateServiceAccountArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	if err := checkServiceAccountPermission(ctx, args.ProjectName); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.CreateServiceAccount(args, ctx.UserName, ctx.Logger)
+}
+
+func ListServiceAccounts(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("project_name")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("project_name is empty")
+		return
+	}
+
+	if err := checkServiceAccountPermission(ctx, projectName); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.ListServiceAccounts(projectName, ctx.Logger)
+}
+
+func DeleteServiceAccount(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkServiceAccountPermissionByUID(ctx, c.Param("uid")); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Err = user.DeleteServiceAccount(c.Param("uid"), ctx.Logger)
+}
+
+func CreateServiceAccountToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkServiceAccountPermissionByUID(ctx, c.Param("uid")); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(user.CreateUserTokenArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.CreateServiceAccountToken(c.Param("uid"), args, ctx.Logger)
+}
+
+func ListServiceAccountTokens(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkServiceAccountPermissionByUID(ctx, c.Param("uid")); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.ListUserTokens(c.Param("uid"), ctx.Logger)
+}
+
+func RevokeServiceAccountToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkServiceAccountPermissionByUID(ctx, c.Param("uid")); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Err = user.RevokeUserToken(c.Param("uid"), c.Param("id"), ctx.Logger)
+}
+
+// checkServiceAccountPermission allows the system admin and the project admin to manage the service accounts
+// of the project, service accounts can not be managed with a token.
+func checkServiceAccountPermission(ctx *internalhandler.Context, projectName string) error {
+	if ctx.TokenID != "" {
+		return e.ErrForbidden.AddDesc("personal access token can not be used to manage service accounts")
+	}
+
+	if err := GenerateUserAuthInfo(ctx); err != nil {
+		ctx.UnAuthorized = true
+		return fmt.Errorf("failed to generate user authorization info, error: %s", err)
+	}
+	if ctx.Resources.IsSystemAdmin {
+		return nil
+	}
+	if projectAuthInfo, ok := ctx.Resources.ProjectAuthInfo[projectName]; ok && projectAuthInfo.IsProjectAdmin {
+		return nil
+	}
+	return e.ErrForbidden
+}
+
+func checkServiceAccountPermissionByUID(ctx *internalhandler.Context, uid string) error {
+	account, err := user.GetServiceAccount(uid, ctx.Logger)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("service account %s not found", uid))
+	}
+	return checkServiceAccountPermission(ctx, account.ProjectName)
+}
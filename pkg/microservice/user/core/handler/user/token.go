@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/user"
+	internalhandler "github.com/koderover/zadig/v2/pkg/shared/handler"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+func CreateUserToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkUserTokenPermission(c, ctx); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(user.CreateUserTokenArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.CreateUserToken(c.Param("uid"), args, ctx.Logger)
+}
+
+func ListUserTokens(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkUserTokenPermission(c, ctx); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.ListUserTokens(c.Param("uid"), ctx.Logger)
+}
+
+func RevokeUserToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err := checkUserTokenPermission(c, ctx); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Err = user.RevokeUserToken(c.Param("uid"), c.Param("id"), ctx.Logger)
+}
+
+// checkUserTokenPermission allows the owner of the tokens and the system admin to manage them,
+// tokens can not be managed with a token.
+func checkUserTokenPermission(c *gin.Context, ctx *internalhandler.Context) error {
+	if ctx.TokenID != "" {
+		return e.ErrForbidden.AddDesc("personal access token can not be used to manage tokens")
+	}
+	if ctx.UserID == c.Param("uid") {
+		return nil
+	}
+
+	if err := GenerateUserAuthInfo(ctx); err != nil {
+		ctx.UnAuthorized = true
+		return fmt.Errorf("failed to generate user authorization info, error: %s", err)
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		return e.ErrForbidden
+	}
+	return nil
+}
@@ -94,3 +94,36 @@ CREATE TABLE IF NOT EXISTS `group_role_binding` (
     FOREIGN KEY (`role_id`) REFERENCES role(`id`) ON DELETE CASCADE
 ) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '角色组/角色绑定信息' ROW_FORMAT = Compact;
 
+CREATE TABLE IF NOT EXISTS `user_token` (
+    `id`           bigint(20) NOT NULL AUTO_INCREMENT,
+    `token_id`     varchar(64) NOT NULL COMMENT '令牌ID',
+    `uid`          varchar(64) NOT NULL COMMENT '用户ID',
+    `name`         varchar(64) NOT NULL COMMENT '令牌名称',
+    `projects`     varchar(2048) NOT NULL DEFAULT '' COMMENT '授权项目, 逗号分隔',
+    `scopes`       varchar(256) NOT NULL DEFAULT '' COMMENT '授权范围, 逗号分隔',
+    `expires_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间',
+    `last_used_at` int(11) unsigned NOT NULL DEFAULT '0' COMMENT '最后使用时间',
+    `revoked_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '吊销时间, 0为未吊销',
+    `created_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+    `updated_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `token_id` (`token_id`),
+    UNIQUE KEY `user_token_name` (`uid`, `name`),
+    FOREIGN KEY (`uid`) REFERENCES user(`uid`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '用户访问令牌' ROW_FORMAT = Compact;
+
+
+CREATE TABLE IF NOT EXISTS `service_account` (
+    `id`           bigint(20) NOT NULL AUTO_INCREMENT,
+    `uid`          varchar(64) NOT NULL COMMENT '用户ID',
+    `project_name` varchar(64) NOT NULL COMMENT '所属项目',
+    `name`         varchar(32) NOT NULL COMMENT '服务账号名称',
+    `description`  varchar(256) NOT NULL DEFAULT '' COMMENT '描述',
+    `created_by`   varchar(64) NOT NULL DEFAULT '' COMMENT '创建人',
+    `created_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+    `updated_at`   int(11) unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uid` (`uid`),
+    UNIQUE KEY `service_account_name` (`project_name`, `name`),
+    FOREIGN KEY (`uid`) REFERENCES user(`uid`) ON DELETE CASCADE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '服务账号' ROW_FORMAT = Compact;
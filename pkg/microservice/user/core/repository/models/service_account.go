@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// ServiceAccount is a non-human identity owned by a project. Every service account is backed by a user with
+// the service_account identity type and no login, so it can only authenticate with personal access tokens.
+type ServiceAccount struct {
+	Model
+	ID          int64  `gorm:"primarykey"          json:"-"`
+	UID         string `gorm:"column:uid"          json:"uid"`
+	ProjectName string `gorm:"column:project_name" json:"project_name"`
+	Name        string `gorm:"column:name"         json:"name"`
+	Description string `gorm:"column:description"  json:"description"`
+	CreatedBy   string `gorm:"column:created_by"   json:"created_by"`
+}
+
+// TableName sets the insert table name for this struct type
+func (ServiceAccount) TableName() string {
+	return "service_account"
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "strings"
+
+// UserToken is a personal access token of a user, the token itself is a jwt carrying the TokenID and is never stored.
+// The token only grants the intersection of the user's permissions and its scopes in its projects.
+type UserToken struct {
+	Model
+	ID      int64  `gorm:"primarykey"          json:"-"`
+	TokenID string `gorm:"column:token_id"     json:"token_id"`
+	UID     string `gorm:"column:uid"          json:"uid"`
+	Name    string `gorm:"column:name"         json:"name"`
+	// Projects and Scopes are comma separated lists
+	Projects   string `gorm:"column:projects"     json:"-"`
+	Scopes     string `gorm:"column:scopes"       json:"-"`
+	ExpiresAt  int64  `gorm:"column:expires_at"   json:"expires_at"`
+	LastUsedAt int64  `gorm:"column:last_used_at" json:"last_used_at"`
+	RevokedAt  int64  `gorm:"column:revoked_at"   json:"revoked_at"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserToken) TableName() string {
+	return "user_token"
+}
+
+func (t *UserToken) ProjectList() []string {
+	return splitList(t.Projects)
+}
+
+func (t *UserToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+func splitList(s string) []string {
+	resp := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item != "" {
+			resp = append(resp, item)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+)
+
+func CreateServiceAccount(account *models.ServiceAccount, db *gorm.DB) error {
+	account.CreatedAt = time.Now().Unix()
+	account.UpdatedAt = time.Now().Unix()
+
+	if err := db.Create(&account).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func ListServiceAccounts(projectName string, db *gorm.DB) ([]*models.ServiceAccount, error) {
+	resp := make([]*models.ServiceAccount, 0)
+
+	err := db.Where("project_name = ?", projectName).Order("name ASC").Find(&resp).Error
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetServiceAccountByUID returns nil if the uid does not belong to a service account
+func GetServiceAccountByUID(uid string, db *gorm.DB) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := db.Where("uid = ?", uid).First(&account).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+func ListServiceAccountsByUIDs(uids []string, db *gorm.DB) ([]*models.ServiceAccount, error) {
+	resp := make([]*models.ServiceAccount, 0)
+
+	err := db.Where("uid IN ?", uids).Find(&resp).Error
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+)
+
+func CreateUserToken(token *models.UserToken, db *gorm.DB) error {
+	token.CreatedAt = time.Now().Unix()
+	token.UpdatedAt = time.Now().Unix()
+
+	if err := db.Create(&token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func ListUserTokens(uid string, db *gorm.DB) ([]*models.UserToken, error) {
+	resp := make([]*models.UserToken, 0)
+
+	err := db.Where("uid = ?", uid).Order("created_at Desc").Find(&resp).Error
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetUserToken returns nil if the token does not exist
+func GetUserToken(tokenID string, db *gorm.DB) (*models.UserToken, error) {
+	var token models.UserToken
+	err := db.Where("token_id = ?", tokenID).First(&token).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func RevokeUserToken(uid, tokenID string, db *gorm.DB) error {
+	now := time.Now().Unix()
+	return db.Model(&models.UserToken{}).
+		Where("uid = ? AND token_id = ? AND revoked_at = 0", uid, tokenID).
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now}).
+		Error
+}
+
+func UpdateUserTokenLastUsed(tokenID string, lastUsedAt int64, db *gorm.DB) error {
+	return db.Model(&models.UserToken{}).
+		Where("token_id = ?", tokenID).
+		Update("last_used_at", lastUsedAt).
+		Error
+}
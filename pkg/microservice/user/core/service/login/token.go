@@ -29,6 +29,9 @@ type Claims struct {
 	PreferredUsername string          `json:"preferred_username"`
 	FederatedClaims   FederatedClaims `json:"federated_claims"`
 	jwt.StandardClaims
+
+	// TokenID is set if the jwt is a personal access token
+	TokenID string `json:"token_id,omitempty"`
 }
 
 type FederatedClaims struct {
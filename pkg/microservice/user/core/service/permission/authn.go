@@ -19,11 +19,18 @@ package permission
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/koderover/zadig/v2/pkg/config"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/orm"
 	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/login"
 	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
@@ -39,6 +46,28 @@ const (
 	generalWebhookURLRegExp      = `^\/api\/aslan\/workflow\/v4\/generalhook\/[\w-]+\/[^/]+\/webhook$`
 	codeHostAuthURLRegExp        = `^\/api\/v1\/codehosts\/\w+\/auth$`
 	testReportURLRegExp          = `^\/api\/aslan\/testing\/report\/workflowv4\/[\w-]+\/id\/\w+\/job\/[^/]+$`
+
+	// userTokenLastUsedInterval is the minimum interval in seconds to update the last used time of a personal access token
+	userTokenLastUsedInterval = 60
+)
+
+// projectQueryKeys are the query parameters used by the apis to specify the project
+var projectQueryKeys = []string{"projectName", "projectKey", "project_name", "project_key", "productName"}
+
+// tokenScopeWritePaths are the apis a personal access token of each scope can call with methods other than GET and HEAD
+var tokenScopeWritePaths = map[string][]string{
+	TokenScopeRunWorkflow:   {"/api/aslan/workflow/", "/openapi/workflows/"},
+	TokenScopeDeployNonProd: {"/api/aslan/environment/", "/openapi/environments/"},
+}
+
+// getUserToken and updateUserTokenLastUsed access the token records, they're replaced in the tests
+var (
+	getUserToken = func(tokenID string) (*models.UserToken, error) {
+		return orm.GetUserToken(tokenID, repository.DB)
+	}
+	updateUserTokenLastUsed = func(tokenID string, lastUsedAt int64) error {
+		return orm.UpdateUserTokenLastUsed(tokenID, lastUsedAt, repository.DB)
+	}
 )
 
 func IsPublicURL(reqPath, method string) bool {
@@ -185,6 +214,10 @@ func IsPublicURL(reqPath, method string) bool {
 		return true
 	}
 
+	if realPath == "/api/aslan/vm/agents/connect" && method == http.MethodGet {
+		return true
+	}
+
 	if realPath == "/api/v1/callback" {
 		return true
 	}
@@ -279,3 +312,88 @@ func ValidateToken(tokenString string) (*login.Claims, bool, error) {
 		return nil, false, fmt.Errorf("invalid token")
 	}
 }
+
+// ValidateUserToken checks that the personal access token is active and the request is within its scopes, see
+// checkUserTokenRequest. The actions in the projects are enforced by the authorization info of the token, see
+// GetTokenAuthInfo.
+func ValidateUserToken(claims *login.Claims, reqPath, method string) error {
+	token, err := getUserToken(claims.TokenID)
+	if err != nil {
+		return fmt.Errorf("failed to find token, error: %s", err)
+	}
+	if token == nil || token.UID != claims.UID {
+		return fmt.Errorf("token not found")
+	}
+	if err := checkUserTokenActive(token); err != nil {
+		return err
+	}
+	if err := checkUserTokenRequest(token, reqPath, method); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	if now-token.LastUsedAt >= userTokenLastUsedInterval {
+		if err := updateUserTokenLastUsed(token.TokenID, now); err != nil {
+			log.Warnf("failed to update last used time of token %s, error: %s", token.TokenID, err)
+		}
+	}
+	return nil
+}
+
+// checkUserTokenRequest checks the request against the scopes of the token. A token can't manage users, and the
+// projects in the query must be the projects of the token. A request other than GET and HEAD must be an api of a
+// write scope of the token and specify its project in the query, since the gateway can't resolve the project from
+// the path or the body.
+func checkUserTokenRequest(token *models.UserToken, reqPath, method string) error {
+	reqURL, err := url.Parse(reqPath)
+	if err != nil {
+		return err
+	}
+	// a token can not manage users, which includes creating tokens and reading the legacy api token
+	if strings.HasPrefix(reqURL.Path, "/api/v1/users") {
+		return fmt.Errorf("token is not allowed to access %s", reqURL.Path)
+	}
+
+	tokenProjects := sets.NewString(token.ProjectList()...)
+	reqProjects := sets.NewString()
+	for _, key := range projectQueryKeys {
+		for _, project := range reqURL.Query()[key] {
+			if !tokenProjects.Has(project) {
+				return fmt.Errorf("project %s is out of the scope of token %s", project, token.Name)
+			}
+			reqProjects.Insert(project)
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return nil
+	}
+
+	if !userTokenCanWrite(token, reqURL.Path) {
+		return fmt.Errorf("token %s is not allowed to %s %s", token.Name, method, reqURL.Path)
+	}
+	if reqProjects.Len() == 0 {
+		return fmt.Errorf("the project of %s %s is not specified in the query", method, reqURL.Path)
+	}
+	return nil
+}
+
+func userTokenCanWrite(token *models.UserToken, path string) bool {
+	for _, scope := range token.ScopeList() {
+		for _, prefix := range tokenScopeWritePaths[scope] {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkUserTokenActive(token *models.UserToken) error {
+	if token.RevokedAt > 0 {
+		return fmt.Errorf("token %s has been revoked", token.Name)
+	}
+	if token.ExpiresAt <= time.Now().Unix() {
+		return fmt.Errorf("token %s has expired", token.Name)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package permission
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/login"
+)
+
+func TestIntersectProjectActions(t *testing.T) {
+	user := generateDefaultProjectActions()
+	user.Workflow.View = true
+	user.Workflow.Execute = true
+	user.Workflow.Delete = true
+	user.Env.View = true
+
+	scope := generateDefaultProjectActions()
+	for _, verb := range TokenScopeVerbs[TokenScopeRunWorkflow] {
+		modifyUserProjectAuth(scope, verb)
+	}
+
+	resp := intersectProjectActions(user, scope)
+	assert.True(t, resp.Workflow.View)
+	assert.True(t, resp.Workflow.Execute)
+	assert.False(t, resp.Workflow.Delete)
+	assert.False(t, resp.Env.View)
+	assert.False(t, resp.IsProjectAdmin)
+
+	// the inputs are not modified
+	assert.True(t, user.Workflow.Delete)
+	assert.False(t, scope.Env.View)
+}
+
+func TestValidateUserToken(t *testing.T) {
+	now := time.Now().Unix()
+	tokens := map[string]*models.UserToken{
+		"read": {TokenID: "read", UID: "uid", Name: "read", Projects: "project", Scopes: TokenScopeReadOnly, ExpiresAt: now + 3600},
+		"run": {TokenID: "run", UID: "uid", Name: "run", Projects: "project,other", Scopes: TokenScopeReadOnly + "," + TokenScopeRunWorkflow,
+			ExpiresAt: now + 3600, LastUsedAt: now},
+		"deploy":  {TokenID: "deploy", UID: "uid", Name: "deploy", Projects: "project", Scopes: TokenScopeDeployNonProd, ExpiresAt: now + 3600},
+		"expired": {TokenID: "expired", UID: "uid", Name: "expired", Projects: "project", Scopes: TokenScopeReadOnly, ExpiresAt: now - 1},
+		"revoked": {TokenID: "revoked", UID: "uid", Name: "revoked", Projects: "project", Scopes: TokenScopeReadOnly, ExpiresAt: now + 3600, RevokedAt: now},
+	}
+	lastUsed := make(map[string]int64)
+	getUserToken = func(tokenID string) (*models.UserToken, error) {
+		if tokenID == "broken" {
+			return nil, fmt.Errorf("db is unavailable")
+		}
+		return tokens[tokenID], nil
+	}
+	updateUserTokenLastUsed = func(tokenID string, lastUsedAt int64) error {
+		lastUsed[tokenID] = lastUsedAt
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		tokenID string
+		uid     string
+		path    string
+		method  string
+		wantErr bool
+	}{
+		{name: "read", tokenID: "read", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet},
+		{name: "read without project", tokenID: "read", path: "/api/aslan/project/products/project", method: http.MethodGet},
+		{name: "read another project", tokenID: "read", path: "/api/aslan/workflow/v4?projectName=another", method: http.MethodGet, wantErr: true},
+		{name: "read another project in a repeated key", tokenID: "read", path: "/api/aslan/workflow/v4?projectName=project&projectName=another", method: http.MethodGet, wantErr: true},
+		{name: "write by read only token", tokenID: "read", path: "/api/aslan/workflow/v4/workflowtask?projectName=project", method: http.MethodPost, wantErr: true},
+		{name: "run workflow", tokenID: "run", path: "/api/aslan/workflow/v4/workflowtask?projectName=project", method: http.MethodPost},
+		{name: "run workflow by openapi", tokenID: "run", path: "/openapi/workflows/custom/task?projectKey=other", method: http.MethodPost},
+		{name: "run workflow without project", tokenID: "run", path: "/api/aslan/workflow/v4/workflowtask", method: http.MethodPost, wantErr: true},
+		{name: "deploy by run workflow token", tokenID: "run", path: "/api/aslan/environment/environments/dev/services?projectName=project", method: http.MethodPut, wantErr: true},
+		{name: "delete project by run workflow token", tokenID: "run", path: "/api/aslan/project/products/project?projectName=project", method: http.MethodDelete, wantErr: true},
+		{name: "deploy", tokenID: "deploy", path: "/api/aslan/environment/environments/dev/services?projectName=project", method: http.MethodPut},
+		{name: "manage users", tokenID: "run", path: "/api/v1/users/uid/tokens", method: http.MethodGet, wantErr: true},
+		{name: "expired", tokenID: "expired", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet, wantErr: true},
+		{name: "revoked", tokenID: "revoked", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet, wantErr: true},
+		{name: "token of another user", tokenID: "read", uid: "another", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet, wantErr: true},
+		{name: "unknown token", tokenID: "unknown", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet, wantErr: true},
+		{name: "failed to find token", tokenID: "broken", path: "/api/aslan/workflow/v4?projectName=project", method: http.MethodGet, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid := tt.uid
+			if uid == "" {
+				uid = "uid"
+			}
+			err := ValidateUserToken(&login.Claims{UID: uid, TokenID: tt.tokenID}, tt.path, tt.method)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// the last used time is only updated once in an interval
+	assert.Contains(t, lastUsed, "read")
+	assert.Contains(t, lastUsed, "deploy")
+	assert.NotContains(t, lastUsed, "run")
+}
@@ -19,6 +19,7 @@ package permission
 import (
 	"database/sql"
 	"fmt"
+	"reflect"
 
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -186,6 +187,57 @@ func GetUserAuthInfo(uid string, logger *zap.SugaredLogger) (*AuthorizedResource
 	return resp, nil
 }
 
+// GetTokenAuthInfo returns the resources authorized to a personal access token of the user, which is the intersection of
+// the permissions of the user and the scopes of the token in the projects of the token. A token never has system permissions.
+func GetTokenAuthInfo(uid, tokenID string, logger *zap.SugaredLogger) (*AuthorizedResources, error) {
+	token, err := getUserToken(tokenID)
+	if err != nil {
+		logger.Errorf("failed to find token %s, error: %s", tokenID, err)
+		return nil, fmt.Errorf("failed to find token %s, error: %s", tokenID, err)
+	}
+	if token == nil || token.UID != uid {
+		return nil, fmt.Errorf("token %s not found for uid: %s", tokenID, uid)
+	}
+	if err := checkUserTokenActive(token); err != nil {
+		return nil, err
+	}
+
+	userAuthInfo, err := GetUserAuthInfo(uid, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	scopeActions := generateDefaultProjectActions()
+	for _, scope := range token.ScopeList() {
+		for _, verb := range TokenScopeVerbs[scope] {
+			modifyUserProjectAuth(scopeActions, verb)
+		}
+	}
+
+	projectInfo := make(map[string]ProjectActions)
+	for _, project := range token.ProjectList() {
+		if userAuthInfo.IsSystemAdmin {
+			projectInfo[project] = *intersectProjectActions(scopeActions, scopeActions)
+			continue
+		}
+		actions, ok := userAuthInfo.ProjectAuthInfo[project]
+		if !ok {
+			continue
+		}
+		if actions.IsProjectAdmin {
+			projectInfo[project] = *intersectProjectActions(scopeActions, scopeActions)
+			continue
+		}
+		projectInfo[project] = *intersectProjectActions(&actions, scopeActions)
+	}
+
+	return &AuthorizedResources{
+		IsSystemAdmin:   false,
+		ProjectAuthInfo: projectInfo,
+		SystemActions:   generateDefaultSystemActions(),
+	}, nil
+}
+
 func CheckCollaborationModePermission(uid, projectKey, resource, resourceName, action string) (hasPermission bool, err error) {
 	hasPermission = false
 	collabInstance, findErr := mongodb.NewCollaborationInstanceColl().FindInstance(uid, projectKey)
@@ -502,6 +554,27 @@ func generateAdminRoleResource() *AuthorizedResources {
 	}
 }
 
+// intersectProjectActions returns a new ProjectActions with the actions granted by both a and b.
+func intersectProjectActions(a, b *ProjectActions) *ProjectActions {
+	resp := generateDefaultProjectActions()
+	intersectActions(reflect.ValueOf(resp).Elem(), reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem())
+	return resp
+}
+
+func intersectActions(dst, a, b reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		switch dst.Field(i).Kind() {
+		case reflect.Bool:
+			dst.Field(i).SetBool(a.Field(i).Bool() && b.Field(i).Bool())
+		case reflect.Ptr:
+			if dst.Field(i).IsNil() || a.Field(i).IsNil() || b.Field(i).IsNil() {
+				continue
+			}
+			intersectActions(dst.Field(i).Elem(), a.Field(i).Elem(), b.Field(i).Elem())
+		}
+	}
+}
+
 // generateDefaultProjectActions generate an ProjectActions without any authorization info.
 func generateDefaultProjectActions() *ProjectActions {
 	return &ProjectActions{
@@ -211,6 +211,11 @@ func CreateRoleBindings(role, ns string, identityList []*types.Identity, log *za
 		}
 	}
 
+	if err := checkServiceAccountNamespace(ns, userIDList...); err != nil {
+		log.Errorf("failed to create role binding for role: %s, error: %s", role, err)
+		return err
+	}
+
 	tx := repository.DB.Begin()
 
 	// create role bindings for users first
@@ -233,6 +238,11 @@ func CreateRoleBindings(role, ns string, identityList []*types.Identity, log *za
 }
 
 func UpdateRoleBindingForUser(uid, namespace string, roles []string, log *zap.SugaredLogger) error {
+	if err := checkServiceAccountNamespace(namespace, uid); err != nil {
+		log.Errorf("failed to update role binding for user: %s, error: %s", uid, err)
+		return err
+	}
+
 	tx := repository.DB.Begin()
 
 	roleIDList := make([]uint, 0)
@@ -331,3 +341,20 @@ func DeleteRoleBindingForUserGroup(gid, namespace string, log *zap.SugaredLogger
 
 	return nil
 }
+
+// checkServiceAccountNamespace makes sure service accounts are only bound to the roles of their own project
+func checkServiceAccountNamespace(namespace string, uids ...string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	accounts, err := orm.ListServiceAccountsByUIDs(uids, repository.DB)
+	if err != nil {
+		return fmt.Errorf("failed to list service accounts, error: %s", err)
+	}
+	for _, account := range accounts {
+		if account.ProjectName != namespace {
+			return fmt.Errorf("service account %s can only be bound to the roles of project %s", account.Name, account.ProjectName)
+		}
+	}
+	return nil
+}
@@ -141,6 +141,38 @@ const (
 	VerbDeleteDBInstanceManagement = "delete_dbinstance_management"
 )
 
+// personal access token scopes, a token only grants the project actions of its scopes
+const (
+	TokenScopeReadOnly      = "read_only"
+	TokenScopeRunWorkflow   = "run_workflow"
+	TokenScopeDeployNonProd = "deploy_non_prod"
+)
+
+// TokenScopeVerbs is the project actions granted by each personal access token scope
+var TokenScopeVerbs = map[string][]string{
+	TokenScopeReadOnly: {
+		VerbGetDelivery,
+		VerbGetTest,
+		VerbGetService,
+		VerbGetProductionService,
+		VerbGetBuild,
+		VerbGetWorkflow,
+		VerbGetEnvironment,
+		VerbGetProductionEnv,
+		VerbGetScan,
+	},
+	TokenScopeRunWorkflow: {
+		VerbGetWorkflow,
+		VerbRunWorkflow,
+	},
+	TokenScopeDeployNonProd: {
+		VerbGetService,
+		VerbGetEnvironment,
+		VerbConfigEnvironment,
+		VerbManageEnvironment,
+	},
+}
+
 type AuthorizedResources struct {
 	IsSystemAdmin   bool                      `json:"is_system_admin"`
 	ProjectAuthInfo map[string]ProjectActions `json:"project_auth_info"`
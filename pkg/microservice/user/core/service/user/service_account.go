@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/orm"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/crypto"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+// service account names are used as the name of the backing user, which is at most 32 characters
+var serviceAccountNameRegExp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+// serviceAccountAccount returns the account of the backing user. Service account names are only unique in a project,
+// so the account is derived from both to stay unique and within the 32 characters of an account.
+func serviceAccountAccount(projectName, name string) string {
+	return "sa-" + crypto.Sha1([]byte(projectName + "/" + name))[:24]
+}
+
+type CreateServiceAccountArgs struct {
+	ProjectName string `json:"project_name"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateServiceAccount creates a service account in the project. The backing user has no login,
+// roles are granted with the ordinary role bindings of the project.
+func CreateServiceAccount(args *CreateServiceAccountArgs, createdBy string, logger *zap.SugaredLogger) (*models.ServiceAccount, error) {
+	if args.ProjectName == "" {
+		return nil, e.ErrInvalidParam.AddDesc("project_name is empty")
+	}
+	if !serviceAccountNameRegExp.MatchString(args.Name) {
+		return nil, e.ErrInvalidParam.AddDesc("service account name must be 1 to 32 lowercase alphanumeric characters or '-'")
+	}
+	if len(args.Description) > 256 {
+		return nil, e.ErrInvalidParam.AddDesc("description must be at most 256 characters")
+	}
+
+	uid, _ := uuid.NewUUID()
+	user := &models.User{
+		UID:          uid.String(),
+		Name:         args.Name,
+		Account:      serviceAccountAccount(args.ProjectName, args.Name),
+		IdentityType: setting.ServiceAccountIdentityType,
+	}
+	account := &models.ServiceAccount{
+		UID:         user.UID,
+		ProjectName: args.ProjectName,
+		Name:        args.Name,
+		Description: args.Description,
+		CreatedBy:   createdBy,
+	}
+
+	tx := repository.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+	if err := orm.CreateUser(user, tx); err != nil {
+		tx.Rollback()
+		logger.Errorf("failed to create user for service account %s, error: %s", args.Name, err)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("service account %s already exists in project %s", args.Name, args.ProjectName))
+		}
+		return nil, err
+	}
+	if err := orm.CreateServiceAccount(account, tx); err != nil {
+		tx.Rollback()
+		logger.Errorf("failed to create service account %s, error: %s", args.Name, err)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("service account %s already exists in project %s", args.Name, args.ProjectName))
+		}
+		return nil, err
+	}
+	return account, tx.Commit().Error
+}
+
+func ListServiceAccounts(projectName string, logger *zap.SugaredLogger) ([]*models.ServiceAccount, error) {
+	accounts, err := orm.ListServiceAccounts(projectName, repository.DB)
+	if err != nil {
+		logger.Errorf("failed to list service accounts of project %s, error: %s", projectName, err)
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetServiceAccount returns nil if the uid does not belong to a service account
+func GetServiceAccount(uid string, logger *zap.SugaredLogger) (*models.ServiceAccount, error) {
+	account, err := orm.GetServiceAccountByUID(uid, repository.DB)
+	if err != nil {
+		logger.Errorf("failed to get service account %s, error: %s", uid, err)
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteServiceAccount deletes the backing user, its role bindings and tokens are deleted with it
+func DeleteServiceAccount(uid string, logger *zap.SugaredLogger) error {
+	account, err := GetServiceAccount(uid, logger)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("service account %s not found", uid))
+	}
+
+	if err := orm.DeleteUserByUid(uid, repository.DB); err != nil {
+		logger.Errorf("failed to delete service account %s, error: %s", account.Name, err)
+		return err
+	}
+	return nil
+}
+
+// CreateServiceAccountToken creates a token for the service account, the token is always
+// scoped to the project of the service account.
+func CreateServiceAccountToken(uid string, args *CreateUserTokenArgs, logger *zap.SugaredLogger) (*UserToken, error) {
+	account, err := GetServiceAccount(uid, logger)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("service account %s not found", uid))
+	}
+
+	args.Projects = []string{account.ProjectName}
+	return CreateUserToken(uid, args, logger)
+}
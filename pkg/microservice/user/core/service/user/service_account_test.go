@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAccountAccount(t *testing.T) {
+	name := strings.Repeat("a", 32)
+	account := serviceAccountAccount("project", name)
+	assert.LessOrEqual(t, len(account), 32)
+	assert.Equal(t, account, serviceAccountAccount("project", name))
+
+	// the same name in different projects are different accounts
+	assert.NotEqual(t, serviceAccountAccount("project", "ci"), serviceAccountAccount("another", "ci"))
+	assert.NotEqual(t, serviceAccountAccount("a", "b-c"), serviceAccountAccount("a-b", "c"))
+}
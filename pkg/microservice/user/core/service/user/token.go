@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/orm"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/login"
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/permission"
+	"github.com/koderover/zadig/v2/pkg/setting"
+	e "github.com/koderover/zadig/v2/pkg/tool/errors"
+)
+
+type CreateUserTokenArgs struct {
+	Name     string   `json:"name"`
+	Projects []string `json:"projects"`
+	Scopes   []string `json:"scopes"`
+	// ExpiresAt is the unix timestamp the token expires at, a token must expire
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type UserToken struct {
+	TokenID    string   `json:"token_id"`
+	Name       string   `json:"name"`
+	Projects   []string `json:"projects"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  int64    `json:"expires_at"`
+	LastUsedAt int64    `json:"last_used_at"`
+	RevokedAt  int64    `json:"revoked_at"`
+	CreatedAt  int64    `json:"created_at"`
+	// Token is only returned when the token is created, it can not be retrieved afterwards
+	Token string `json:"token,omitempty"`
+}
+
+// CreateUserToken creates a personal access token for the user
+func CreateUserToken(uid string, args *CreateUserTokenArgs, logger *zap.SugaredLogger) (*UserToken, error) {
+	if err := validateUserTokenArgs(args); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	user, err := orm.GetUserByUid(uid, repository.DB)
+	if err != nil {
+		logger.Errorf("CreateUserToken getUserByUid:%s error, error msg:%s", uid, err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("user %s not found", uid))
+	}
+
+	tokens, err := orm.ListUserTokens(uid, repository.DB)
+	if err != nil {
+		logger.Errorf("failed to list tokens of user %s, error: %s", uid, err)
+		return nil, err
+	}
+	for _, token := range tokens {
+		if token.Name == args.Name {
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("token %s already exists", args.Name))
+		}
+	}
+
+	tokenID := uuid.NewString()
+	tokenString, err := login.CreateToken(&login.Claims{
+		Name:              user.Name,
+		UID:               user.UID,
+		Email:             user.Email,
+		PreferredUsername: user.Account,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  setting.ProductName,
+			ExpiresAt: args.ExpiresAt,
+		},
+		FederatedClaims: login.FederatedClaims{
+			ConnectorId: user.IdentityType,
+			UserId:      user.Account,
+		},
+		TokenID: tokenID,
+	})
+	if err != nil {
+		logger.Errorf("failed to sign token %s for user %s, error: %s", args.Name, uid, err)
+		return nil, err
+	}
+
+	token := &models.UserToken{
+		TokenID:   tokenID,
+		UID:       uid,
+		Name:      args.Name,
+		Projects:  strings.Join(args.Projects, ","),
+		Scopes:    strings.Join(args.Scopes, ","),
+		ExpiresAt: args.ExpiresAt,
+	}
+	if err := orm.CreateUserToken(token, repository.DB); err != nil {
+		logger.Errorf("failed to create token %s for user %s, error: %s", args.Name, uid, err)
+		return nil, err
+	}
+
+	resp := toUserToken(token)
+	resp.Token = tokenString
+	return resp, nil
+}
+
+func ListUserTokens(uid string, logger *zap.SugaredLogger) ([]*UserToken, error) {
+	tokens, err := orm.ListUserTokens(uid, repository.DB)
+	if err != nil {
+		logger.Errorf("failed to list tokens of user %s, error: %s", uid, err)
+		return nil, err
+	}
+
+	resp := make([]*UserToken, 0, len(tokens))
+	for _, token := range tokens {
+		resp = append(resp, toUserToken(token))
+	}
+	return resp, nil
+}
+
+// RevokeUserToken revokes the token immediately, the record is kept for audit
+func RevokeUserToken(uid, tokenID string, logger *zap.SugaredLogger) error {
+	if err := orm.RevokeUserToken(uid, tokenID, repository.DB); err != nil {
+		logger.Errorf("failed to revoke token %s of user %s, error: %s", tokenID, uid, err)
+		return err
+	}
+	return nil
+}
+
+func validateUserTokenArgs(args *CreateUserTokenArgs) error {
+	if args.Name == "" || len(args.Name) > 64 {
+		return fmt.Errorf("token name must be 1 to 64 characters")
+	}
+	if len(args.Projects) == 0 {
+		return fmt.Errorf("token must be scoped to at least one project")
+	}
+	for _, project := range args.Projects {
+		if project == "" || strings.Contains(project, ",") {
+			return fmt.Errorf("invalid project %q", project)
+		}
+	}
+	if len(args.Scopes) == 0 {
+		return fmt.Errorf("token must have at least one scope")
+	}
+	for _, scope := range args.Scopes {
+		if _, ok := permission.TokenScopeVerbs[scope]; !ok {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+	if args.ExpiresAt <= time.Now().Unix() {
+		return fmt.Errorf("token must expire in the future")
+	}
+	return nil
+}
+
+func toUserToken(token *models.UserToken) *UserToken {
+	return &UserToken{
+		TokenID:    token.TokenID,
+		Name:       token.Name,
+		Projects:   token.ProjectList(),
+		Scopes:     token.ScopeList(),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		RevokedAt:  token.RevokedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/v2/pkg/microservice/user/core/service/permission"
+)
+
+func TestValidateUserTokenArgs(t *testing.T) {
+	newArgs := func(modify func(args *CreateUserTokenArgs)) *CreateUserTokenArgs {
+		args := &CreateUserTokenArgs{
+			Name:      "ci",
+			Projects:  []string{"project"},
+			Scopes:    []string{permission.TokenScopeRunWorkflow},
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(args)
+		}
+		return args
+	}
+
+	tests := []struct {
+		name    string
+		args    *CreateUserTokenArgs
+		wantErr bool
+	}{
+		{name: "valid", args: newArgs(nil)},
+		{name: "empty name", args: newArgs(func(args *CreateUserTokenArgs) { args.Name = "" }), wantErr: true},
+		{name: "long name", args: newArgs(func(args *CreateUserTokenArgs) { args.Name = strings.Repeat("a", 65) }), wantErr: true},
+		{name: "no project", args: newArgs(func(args *CreateUserTokenArgs) { args.Projects = nil }), wantErr: true},
+		{name: "empty project", args: newArgs(func(args *CreateUserTokenArgs) { args.Projects = []string{""} }), wantErr: true},
+		{name: "project with comma", args: newArgs(func(args *CreateUserTokenArgs) { args.Projects = []string{"a,b"} }), wantErr: true},
+		{name: "no scope", args: newArgs(func(args *CreateUserTokenArgs) { args.Scopes = nil }), wantErr: true},
+		{name: "unknown scope", args: newArgs(func(args *CreateUserTokenArgs) { args.Scopes = []string{"admin"} }), wantErr: true},
+		{name: "expired", args: newArgs(func(args *CreateUserTokenArgs) { args.ExpiresAt = time.Now().Unix() - 1 }), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUserTokenArgs(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
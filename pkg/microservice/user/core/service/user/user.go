@@ -172,6 +172,15 @@ func GetUser(uid string, logger *zap.SugaredLogger) (*types.UserInfo, error) {
 	if user == nil {
 		return nil, nil
 	}
+	// service accounts have no login and only authenticate with personal access tokens, so no api token is issued
+	if user.IdentityType == setting.ServiceAccountIdentityType {
+		return &types.UserInfo{
+			Uid:          user.UID,
+			Name:         user.Name,
+			IdentityType: user.IdentityType,
+			Account:      user.Account,
+		}, nil
+	}
 	userLogin, err := orm.GetUserLogin(uid, user.Account, config.AccountLoginType, repository.DB)
 	if err != nil {
 		logger.Errorf("GetUser GetUserLogin:%s error, error msg:%s", uid, err.Error())
@@ -17,6 +17,8 @@ limitations under the License.
 package user
 
 import (
+	"fmt"
+
 	"github.com/google/uuid"
 	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository"
 	"github.com/koderover/zadig/v2/pkg/microservice/user/core/repository/models"
@@ -27,6 +29,10 @@ import (
 )
 
 func CreateUserGroup(groupName, desc string, uids []string, logger *zap.SugaredLogger) error {
+	if err := checkNoServiceAccount(uids); err != nil {
+		return err
+	}
+
 	tx := repository.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -174,9 +180,27 @@ func DeleteUserGroup(groupID string, logger *zap.SugaredLogger) error {
 }
 
 func BulkAddUserToUserGroup(groupID string, uids []string, logger *zap.SugaredLogger) error {
+	if err := checkNoServiceAccount(uids); err != nil {
+		return err
+	}
 	return orm.BulkCreateGroupBindings(groupID, uids, repository.DB)
 }
 
 func BulkRemoveUserFromUserGroup(groupID string, uids []string, logger *zap.SugaredLogger) error {
 	return orm.BulkDeleteGroupBindings(groupID, uids, repository.DB)
 }
+
+// checkNoServiceAccount makes sure service accounts only get roles from the role bindings of their own project
+func checkNoServiceAccount(uids []string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	accounts, err := orm.ListServiceAccountsByUIDs(uids, repository.DB)
+	if err != nil {
+		return err
+	}
+	if len(accounts) > 0 {
+		return fmt.Errorf("service account %s can not be added to user groups", accounts[0].Name)
+	}
+	return nil
+}
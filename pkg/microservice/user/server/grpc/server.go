@@ -122,8 +122,24 @@ func (s *AuthServer) Check(ctx context.Context, request *ext_authz_v3.CheckReque
 				return resp, nil
 			}
 
-			// if the expiration time is so huge that it is not possible, it is a constant api token, we don't check for the redis.
-			if claims.ExpiresAt-time.Now().Unix() < 8760*60*60 {
+			if claims.TokenID != "" {
+				// personal access tokens are checked against their records and scopes instead of the login cache
+				if err := permission.ValidateUserToken(claims, requestPath, method); err != nil {
+					resp.Status = &rpc_status.Status{Code: int32(code.Code_PERMISSION_DENIED)}
+					resp.HttpResponse = &ext_authz_v3.CheckResponse_DeniedResponse{DeniedResponse: &ext_authz_v3.DeniedHttpResponse{
+						Status: &typev3.HttpStatus{Code: http.StatusForbidden},
+					}}
+					logger.Info("Request Denied",
+						zap.String("path", requestPath),
+						zap.String("method", method),
+						zap.String("body", body),
+						zap.String("reason", "personal access token denied"),
+						zap.String("error", err.Error()),
+					)
+					return resp, nil
+				}
+			} else if claims.ExpiresAt-time.Now().Unix() < 8760*60*60 {
+				// if the expiration time is so huge that it is not possible, it is a constant api token, we don't check for the redis.
 				// check if the given token is removed from the cache
 				token, err := cache.NewRedisCache(config.RedisUserTokenDB()).GetString(claims.UID)
 				if err != nil {
@@ -263,6 +263,12 @@ const (
 	SourceFromGitee = "gitee"
 	// SourceFromGiteeEE Configure the source as gitee-enterprise
 	SourceFromGiteeEE = "gitee-enterprise"
+	// SourceFromBitbucket Configure the source as bitbucket cloud
+	SourceFromBitbucket = "bitbucket"
+	// SourceFromBitbucketServer Configure the source as bitbucket data center/server
+	SourceFromBitbucketServer = "bitbucket-server"
+	// SourceFromGitea Configure the source as gitea
+	SourceFromGitea = "gitea"
 	// SourceFromOther Configure the source as other
 	SourceFromOther = "other"
 	// SourceFromChartTemplate The configuration source is helmTemplate
@@ -639,6 +645,10 @@ const (
 	ReadProjectOnly RoleType = "read-project-only"
 )
 
+// ServiceAccountIdentityType is the identity type of service accounts, the non-human identities
+// used by automation. They can only authenticate with personal access tokens.
+const ServiceAccountIdentityType = "service_account"
+
 // ModernWorkflowType 自由编排工作流
 const ModernWorkflowType = "ModernWorkflow"
 
@@ -849,6 +859,11 @@ const (
 	VMJobStatusSuccess     = "success"
 	VMJobStatusFailed      = "failed"
 
+	// vm agent upgrade status
+	VMAgentUpgrading        = "upgrading"
+	VMAgentUpgradeSucceeded = "succeeded"
+	VMAgentUpgradeFailed    = "failed"
+
 	// vm platform type
 	LinuxAmd64 = "linux_amd64"
 	LinuxArm64 = "linux_arm64"
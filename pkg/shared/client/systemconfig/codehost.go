@@ -32,6 +32,10 @@ const (
 	GiteeProvider   = "gitee"
 	GiteeEEProvider = "gitee-enterprise"
 	OtherProvider   = "other"
+
+	BitbucketProvider       = "bitbucket"
+	BitbucketServerProvider = "bitbucket-server"
+	GiteaProvider           = "gitea"
 )
 
 type CodeHost struct {
@@ -214,6 +214,18 @@ func (c *Client) GetUserAuthInfo(uid string) (*AuthorizedResources, error) {
 	return resp, err
 }
 
+// GetTokenAuthInfo returns the resources authorized to the personal access token of the user
+func (c *Client) GetTokenAuthInfo(uid, tokenID string) (*AuthorizedResources, error) {
+	url := "/authorization/auth-info"
+	resp := &AuthorizedResources{}
+	queries := make(map[string]string)
+	queries["uid"] = uid
+	queries["token_id"] = tokenID
+
+	_, err := c.Get(url, httpclient.SetQueryParams(queries), httpclient.SetResult(resp))
+	return resp, err
+}
+
 func (c *Client) CheckUserAuthInfoForCollaborationMode(uid, projectKey, resource, resourceName, action string) (bool, error) {
 	url := "/authorization/collaboration-permission"
 	resp := &types.CheckCollaborationModePermissionResp{}
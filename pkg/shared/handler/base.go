@@ -38,6 +38,9 @@ import (
 	"github.com/koderover/zadig/v2/pkg/util/ginzap"
 )
 
+// handlerContextKey is the key of the Context of the request in the gin context
+const handlerContextKey = "handlerContext"
+
 // Context struct
 type Context struct {
 	Logger       *zap.SugaredLogger
@@ -50,6 +53,9 @@ type Context struct {
 	IdentityType string
 	RequestID    string
 	Resources    *user.AuthorizedResources
+
+	// TokenID is set if the request is authenticated by a personal access token
+	TokenID string
 }
 
 type jwtClaims struct {
@@ -59,6 +65,8 @@ type jwtClaims struct {
 	Account         string          `json:"preferred_username"`
 	FederatedClaims FederatedClaims `json:"federated_claims"`
 	jwt.StandardClaims
+
+	TokenID string `json:"token_id,omitempty"`
 }
 
 type FederatedClaims struct {
@@ -85,14 +93,32 @@ func NewContext(c *gin.Context) *Context {
 		claims.Name = "system"
 	}
 
-	return &Context{
+	ctx := &Context{
 		UserName:     claims.Name,
 		UserID:       claims.UID,
 		Account:      claims.Account,
 		IdentityType: claims.FederatedClaims.ConnectorId,
 		Logger:       ginzap.WithContext(c).Sugar(),
 		RequestID:    c.GetString(setting.RequestID),
+		TokenID:      claims.TokenID,
+	}
+	c.Set(handlerContextKey, ctx)
+	return ctx
+}
+
+// contextOf returns the context created for the request, so the token is not parsed again
+func contextOf(c *gin.Context) *Context {
+	if v, ok := c.Get(handlerContextKey); ok {
+		if ctx, ok := v.(*Context); ok {
+			return ctx
+		}
 	}
+	return NewContext(c)
+}
+
+// IsServiceAccount reports whether the caller is a service account rather than a human user.
+func (ctx *Context) IsServiceAccount() bool {
+	return ctx.IdentityType == setting.ServiceAccountIdentityType
 }
 
 // NewContextWithAuthorization returns a context with user authorization info.
@@ -103,7 +129,12 @@ func NewContextWithAuthorization(c *gin.Context) (*Context, error) {
 	var err error
 	resp := NewContext(c)
 	// there is a case where the request does not have token (system call), in this case we will have admin access
-	resourceAuthInfo, err = user.New().GetUserAuthInfo(resp.UserID)
+	if resp.TokenID != "" {
+		// a personal access token only has the permissions within its scopes
+		resourceAuthInfo, err = user.New().GetTokenAuthInfo(resp.UserID, resp.TokenID)
+	} else {
+		resourceAuthInfo, err = user.New().GetUserAuthInfo(resp.UserID)
+	}
 	if err != nil {
 		logger.Errorf("failed to generate user authorization info, error: %s", err)
 		return resp, err
@@ -241,6 +272,8 @@ func InsertOperationLog(c *gin.Context, username, productName, method, function,
 		RequestBody: requestBody,
 		Status:      0,
 		CreatedAt:   time.Now().Unix(),
+
+		IsServiceAccount: contextOf(c).IsServiceAccount(),
 	}
 	err := mongodb.NewOperationLogColl().Insert(req)
 	if err != nil {
@@ -261,6 +294,8 @@ func InsertDetailedOperationLog(c *gin.Context, username, productName, scene, me
 		Targets:     targets,
 		Status:      0,
 		CreatedAt:   time.Now().Unix(),
+
+		IsServiceAccount: contextOf(c).IsServiceAccount(),
 	}
 	err := mongodb.NewOperationLogColl().Insert(req)
 	if err != nil {
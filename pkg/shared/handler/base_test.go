@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+)
+
+var _ = ginkgo.Describe("Context of the request", func() {
+	ginkgo.BeforeEach(func() {
+		log.Init(&log.Config{Level: "info"})
+	})
+
+	newGinContext := func(identityType string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/aslan/workflow/v4/workflowtask", nil)
+		claims := jwtClaims{Name: "ci", UID: "uid", FederatedClaims: FederatedClaims{ConnectorId: identityType}}
+		payload, _ := json.Marshal(claims)
+		c.Request.Header.Set(setting.AuthorizationHeader, "Bearer e30."+base64.RawURLEncoding.EncodeToString(payload)+".signature")
+		return c
+	}
+
+	ginkgo.DescribeTable("should tell service accounts from humans",
+		func(identityType string, expected bool) {
+			Expect(NewContext(newGinContext(identityType)).IsServiceAccount()).To(Equal(expected))
+		},
+		ginkgo.Entry("service account", setting.ServiceAccountIdentityType, true),
+		ginkgo.Entry("system user", "system", false),
+		ginkgo.Entry("unknown", "", false),
+	)
+
+	ginkgo.It("should reuse the context of the request", func() {
+		c := newGinContext(setting.ServiceAccountIdentityType)
+		ctx := NewContext(c)
+		Expect(contextOf(c)).To(BeIdenticalTo(ctx))
+	})
+
+	ginkgo.It("should create the context if it's not created", func() {
+		c := newGinContext(setting.ServiceAccountIdentityType)
+		ctx := contextOf(c)
+		Expect(ctx.IsServiceAccount()).To(BeTrue())
+		Expect(ctx.UserName).To(Equal("ci"))
+	})
+})
@@ -0,0 +1,554 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/v2/pkg/tool/s3"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+var cacheSchema = fmt.Sprintf(`{
+	"type": "object",
+	"required": ["key", "paths", "s3_storage"],
+	"properties": {
+		"key": {"type": "string", "minLength": 1},
+		"key_files": {"type": ["array", "null"], "items": {"type": "string", "minLength": 1}},
+		"restore_keys": {"type": ["array", "null"], "items": {"type": "string", "minLength": 1}},
+		"paths": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+		"s3_dest_dir": {"type": "string"},
+		"s3_storage": %s,
+		"fail_on_miss": {"type": "boolean"},
+		"max_size": {"type": "integer", "minimum": 0},
+		"ttl": {"type": "integer", "minimum": 0}
+	}
+}`, s3StorageSchema)
+
+const cacheFileSuffix = ".tar.gz"
+
+func init() {
+	Register(&Definition{
+		Type:   "cache_restore",
+		Schema: cacheSchema,
+		New: func(spec interface{}, stepCtx *Context) (Step, error) {
+			return NewCacheRestoreStep(spec, stepCtx)
+		},
+	})
+	Register(&Definition{
+		Type:   "cache_save",
+		Schema: cacheSchema,
+		New: func(spec interface{}, stepCtx *Context) (Step, error) {
+			return NewCacheSaveStep(spec, stepCtx)
+		},
+	})
+}
+
+type cacheStep struct {
+	spec    *step.StepCacheSpec
+	stepCtx *Context
+	envMap  map[string]string
+	paths   []string
+}
+
+func newCacheStep(spec interface{}, stepCtx *Context) (*cacheStep, error) {
+	s := &cacheStep{stepCtx: stepCtx}
+	if err := decodeSpec(spec, &s.spec); err != nil {
+		return nil, err
+	}
+	s.envMap = makeEnvMap(stepCtx.Envs, stepCtx.SecretEnvs)
+	for _, p := range s.spec.Paths {
+		s.paths = append(s.paths, resolvePath(p, stepCtx.Workspace, s.envMap))
+	}
+	return s, nil
+}
+
+// cacheKey returns the key of the cache, the hash of the key files is appended if any of them exists
+func (s *cacheStep) cacheKey() (string, error) {
+	key := replaceEnvWithValue(s.spec.Key, s.envMap)
+	if len(s.spec.KeyFiles) == 0 {
+		return key, nil
+	}
+	hash, err := hashKeyFiles(s.spec.KeyFiles, s.stepCtx.Workspace, s.envMap)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		s.stepCtx.Logger.Printf("No key file matches %s, the cache key is not hashed.\n", strings.Join(s.spec.KeyFiles, ","))
+		return key, nil
+	}
+	return key + step.CacheKeySeparator + hash, nil
+}
+
+func (s *cacheStep) objectKey(key string) string {
+	return objectKey(s.spec.S3Storage, replaceEnvWithValue(s.spec.S3DestDir, s.envMap), key)
+}
+
+func (s *cacheStep) expired(info *s3.ObjectInfo) bool {
+	if s.spec.TTL <= 0 {
+		return false
+	}
+	return time.Since(info.LastModified) > time.Duration(s.spec.TTL)*24*time.Hour
+}
+
+// CacheRestoreStep downloads the cache of the key, or the latest cache of the restore keys if the key is not found,
+// and restores it to the cache paths
+type CacheRestoreStep struct {
+	*cacheStep
+}
+
+func NewCacheRestoreStep(spec interface{}, stepCtx *Context) (*CacheRestoreStep, error) {
+	s, err := newCacheStep(spec, stepCtx)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheRestoreStep{cacheStep: s}, nil
+}
+
+func (s *CacheRestoreStep) Run(ctx context.Context) error {
+	start := time.Now()
+	s.stepCtx.Logger.Printf("Start restoring cache.\n")
+	defer func() {
+		s.stepCtx.Logger.Printf("Cache restore ended. Duration: %.2f seconds.\n", time.Since(start).Seconds())
+	}()
+
+	key, err := s.cacheKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate cache key, err: %s", err)
+	}
+	client, err := newS3Client(s.spec.S3Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to download cache, err: %s", err)
+	}
+
+	hit, err := s.findCache(client, key)
+	if err != nil {
+		return fmt.Errorf("failed to find cache %s, err: %s", key, err)
+	}
+	if hit == nil {
+		if s.spec.FailOnMiss {
+			return fmt.Errorf("cache miss: key %s", key)
+		}
+		s.stepCtx.Logger.Printf("Cache miss: key %s, skip restoring.\n", key)
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "zadig-cache")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tarName := filepath.Join(tmpDir, "cache"+cacheFileSuffix)
+	if err := client.Download(s.spec.S3Storage.Bucket, hit.Key, tarName); err != nil {
+		return fmt.Errorf("failed to download cache %s, err: %s", hit.Key, err)
+	}
+	if err := extractCache(tarName, s.paths); err != nil {
+		return fmt.Errorf("failed to restore cache %s, err: %s", hit.Key, err)
+	}
+	s.stepCtx.Logger.Printf("Cache restored from %s, size: %.2f MB.\n", path.Base(hit.Key), float64(hit.Size)/1024/1024)
+	return nil
+}
+
+// findCache finds the cache of the key first, then the latest cache of every restore key in order
+func (s *CacheRestoreStep) findCache(client *s3.Client, key string) (*s3.ObjectInfo, error) {
+	info, err := client.StatObject(s.spec.S3Storage.Bucket, s.objectKey(key)+cacheFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && !s.expired(info) {
+		s.stepCtx.Logger.Printf("Cache hit: key %s.\n", key)
+		return info, nil
+	}
+
+	for _, restoreKey := range s.spec.RestoreKeys {
+		restoreKey = replaceEnvWithValue(restoreKey, s.envMap)
+		infos, err := client.ListObjectInfos(s.spec.S3Storage.Bucket, s.objectKey(restoreKey))
+		if err != nil {
+			return nil, err
+		}
+		var latest *s3.ObjectInfo
+		for _, info := range infos {
+			if !strings.HasSuffix(info.Key, cacheFileSuffix) || s.expired(info) {
+				continue
+			}
+			if latest == nil || info.LastModified.After(latest.LastModified) {
+				latest = info
+			}
+		}
+		if latest != nil {
+			s.stepCtx.Logger.Printf("Cache hit by restore key %s: %s.\n", restoreKey, strings.TrimSuffix(path.Base(latest.Key), cacheFileSuffix))
+			return latest, nil
+		}
+	}
+	return nil, nil
+}
+
+// CacheSaveStep archives the cache paths and uploads them as the cache of the key, the expired caches in the same dir are evicted
+type CacheSaveStep struct {
+	*cacheStep
+}
+
+func NewCacheSaveStep(spec interface{}, stepCtx *Context) (*CacheSaveStep, error) {
+	s, err := newCacheStep(spec, stepCtx)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheSaveStep{cacheStep: s}, nil
+}
+
+func (s *CacheSaveStep) Run(ctx context.Context) error {
+	start := time.Now()
+	s.stepCtx.Logger.Printf("Start saving cache.\n")
+	defer func() {
+		s.stepCtx.Logger.Printf("Cache save ended. Duration: %.2f seconds.\n", time.Since(start).Seconds())
+	}()
+
+	key, err := s.cacheKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate cache key, err: %s", err)
+	}
+	client, err := newS3Client(s.spec.S3Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to upload cache, err: %s", err)
+	}
+	defer s.evictExpiredCaches(client)
+
+	objectKey := s.objectKey(key) + cacheFileSuffix
+	// the cache is addressed by the content of the key files, so the existing cache is not overwritten
+	info, err := client.StatObject(s.spec.S3Storage.Bucket, objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to find cache %s, err: %s", key, err)
+	}
+	if info != nil && !s.expired(info) {
+		s.stepCtx.Logger.Printf("Cache %s already exists, skip saving.\n", key)
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "zadig-cache")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tarName := filepath.Join(tmpDir, "cache"+cacheFileSuffix)
+	archived, err := archiveCache(tarName, s.paths)
+	if err != nil {
+		return fmt.Errorf("failed to archive cache %s, err: %s", key, err)
+	}
+	if archived == 0 {
+		s.stepCtx.Logger.Printf("No cache path exists, skip saving cache %s.\n", key)
+		return nil
+	}
+	tarInfo, err := os.Stat(tarName)
+	if err != nil {
+		return err
+	}
+	size := float64(tarInfo.Size()) / 1024 / 1024
+	if s.spec.MaxSize > 0 && size > float64(s.spec.MaxSize) {
+		s.stepCtx.Logger.Printf("Cache %s size %.2f MB exceeds the limit %d MB, skip saving.\n", key, size, s.spec.MaxSize)
+		return nil
+	}
+	if err := client.Upload(s.spec.S3Storage.Bucket, tarName, objectKey); err != nil {
+		return fmt.Errorf("failed to upload cache %s, err: %s", key, err)
+	}
+	s.stepCtx.Logger.Printf("Cache %s saved, size: %.2f MB.\n", key, size)
+	return nil
+}
+
+// evictExpiredCaches deletes the expired caches in the cache dir, failures are only logged
+func (s *CacheSaveStep) evictExpiredCaches(client *s3.Client) {
+	if s.spec.TTL <= 0 {
+		return
+	}
+	prefix := s.objectKey("")
+	if prefix != "" {
+		prefix += "/"
+	}
+	infos, err := client.ListObjectInfos(s.spec.S3Storage.Bucket, prefix)
+	if err != nil {
+		s.stepCtx.Logger.Printf("Failed to list caches to evict, err: %s.\n", err)
+		return
+	}
+	expired := make([]string, 0)
+	for _, info := range infos {
+		if strings.HasSuffix(info.Key, cacheFileSuffix) && s.expired(info) {
+			expired = append(expired, info.Key)
+		}
+	}
+	// at most 1000 objects can be deleted in a request
+	for i := 0; i < len(expired); i += 1000 {
+		end := i + 1000
+		if end > len(expired) {
+			end = len(expired)
+		}
+		if err := client.DeleteObjects(s.spec.S3Storage.Bucket, expired[i:end]); err != nil {
+			s.stepCtx.Logger.Printf("Failed to evict expired caches, err: %s.\n", err)
+			return
+		}
+	}
+	if len(expired) > 0 {
+		s.stepCtx.Logger.Printf("%d expired caches evicted.\n", len(expired))
+	}
+}
+
+// hashKeyFiles returns the sha256 of the files matching the patterns, empty string is returned if no file matches
+func hashKeyFiles(patterns []string, workspace string, envs map[string]string) (string, error) {
+	files := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(resolvePath(pattern, workspace, envs))
+		if err != nil {
+			return "", fmt.Errorf("invalid key file pattern %s: %v", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() || seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		// the workspace may differ between runners, so the relative path is hashed
+		name := file
+		if rel, err := filepath.Rel(workspace, file); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		h.Write([]byte(filepath.ToSlash(name)))
+		h.Write([]byte{0})
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// archiveCache archives the paths into a tar.gz file, the files of the i-th path are stored under the dir named i,
+// so the cache can be restored to the same paths on any host. It returns the number of the archived paths.
+func archiveCache(dst string, paths []string) (int, error) {
+	file, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+
+	archived := 0
+	for i, root := range paths {
+		if _, err := os.Lstat(root); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(p); err != nil {
+					return err
+				}
+			} else if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = path.Join(strconv.Itoa(i), filepath.ToSlash(rel))
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		archived++
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	if err := gw.Close(); err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// extractCache restores the tar.gz file created by archiveCache to the paths
+func extractCache(src string, paths []string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		index, rel, _ := strings.Cut(strings.TrimSuffix(header.Name, "/"), "/")
+		i, err := strconv.Atoi(index)
+		// skip the entries of the paths which are not cached any more
+		if err != nil || i < 0 || i >= len(paths) {
+			continue
+		}
+		root := filepath.Clean(paths[i])
+		rel = path.Clean("/" + rel)
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err := checkCacheEntryPath(root, target); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)|0700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// the links are restored only if they point into the cached path, the entries written through them stay in it
+			if filepath.IsAbs(header.Linkname) || !withinPath(root, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("invalid link %s of %s in cache", header.Linkname, header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			// a file is not written through an existing link
+			if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkCacheEntryPath rejects the entries whose parent directory resolves outside of root, e.g. through a link in root
+func checkCacheEntryPath(root, target string) error {
+	if target == root {
+		return nil
+	}
+	realRoot, err := resolveExistingPath(root)
+	if err != nil {
+		return err
+	}
+	parent, err := resolveExistingPath(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if !withinPath(realRoot, parent) {
+		return fmt.Errorf("invalid path %s in cache, it is outside of %s", target, root)
+	}
+	return nil
+}
+
+// resolveExistingPath resolves the links in the longest existing prefix of p
+func resolveExistingPath(p string) (string, error) {
+	missing := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		parent := filepath.Dir(p)
+		if !os.IsNotExist(err) || parent == p {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(p), missing)
+		p = parent
+	}
+}
+
+func withinPath(root, p string) bool {
+	return p == root || strings.HasPrefix(p, root+string(filepath.Separator))
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveAndExtractCache(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "dir", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "dir", "sub", "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.Symlink("sub/a.txt", filepath.Join(src, "dir", "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("file"), 0644))
+
+	tarName := filepath.Join(t.TempDir(), "cache.tar.gz")
+	archived, err := archiveCache(tarName, []string{
+		filepath.Join(src, "dir"),
+		filepath.Join(src, "missing"),
+		filepath.Join(src, "file.txt"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, archived)
+
+	dst := t.TempDir()
+	require.NoError(t, extractCache(tarName, []string{
+		filepath.Join(dst, "dir"),
+		filepath.Join(dst, "missing"),
+		filepath.Join(dst, "file.txt"),
+	}))
+
+	content, err := os.ReadFile(filepath.Join(dst, "dir", "sub", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(content))
+	link, err := os.Readlink(filepath.Join(dst, "dir", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/a.txt", link)
+	content, err = os.ReadFile(filepath.Join(dst, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "file", string(content))
+	_, err = os.Stat(filepath.Join(dst, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// writeCacheArchive writes a cache archive with the entries, the content of a regular file is its name
+func writeCacheArchive(t *testing.T, headers ...*tar.Header) string {
+	tarName := filepath.Join(t.TempDir(), "cache.tar.gz")
+	file, err := os.Create(tarName)
+	require.NoError(t, err)
+	defer file.Close()
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Mode = 0644
+			header.Size = int64(len(header.Name))
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(header.Name))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return tarName
+}
+
+func TestExtractCacheRejectsEscapingLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		linkname string
+	}{
+		{name: "absolute link", linkname: "/etc"},
+		{name: "relative link", linkname: "../../outside"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := t.TempDir()
+			tarName := writeCacheArchive(t,
+				&tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+				&tar.Header{Name: "0/link", Typeflag: tar.TypeSymlink, Linkname: tt.linkname},
+				&tar.Header{Name: "0/link/passwd", Typeflag: tar.TypeReg},
+			)
+			assert.Error(t, extractCache(tarName, []string{filepath.Join(dst, "cache")}))
+			_, err := os.Lstat(filepath.Join(dst, "cache", "link"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestExtractCacheRejectsEntriesOutsideOfPath(t *testing.T) {
+	dst, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "cache"), 0755))
+	// a link left in the cached path by the previous build
+	require.NoError(t, os.Symlink(outside, filepath.Join(dst, "cache", "link")))
+
+	tarName := writeCacheArchive(t, &tar.Header{Name: "0/link/file", Typeflag: tar.TypeReg})
+	assert.Error(t, extractCache(tarName, []string{filepath.Join(dst, "cache")}))
+	_, err := os.Stat(filepath.Join(outside, "file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExtractCacheWritesThroughLinksInPath(t *testing.T) {
+	dst, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "cache"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "file"), filepath.Join(dst, "cache", "file")))
+
+	tarName := writeCacheArchive(t,
+		&tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "0/lib/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "0/bin", Typeflag: tar.TypeSymlink, Linkname: "lib"},
+		&tar.Header{Name: "0/bin/tool", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "0/file", Typeflag: tar.TypeReg},
+	)
+	require.NoError(t, extractCache(tarName, []string{filepath.Join(dst, "cache")}))
+
+	content, err := os.ReadFile(filepath.Join(dst, "cache", "lib", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, "0/bin/tool", string(content))
+	// the link in the cached path is replaced instead of written through
+	info, err := os.Lstat(filepath.Join(dst, "cache", "file"))
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+	_, err = os.Stat(filepath.Join(outside, "file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResolvePath(t *testing.T) {
+	envs := makeEnvMap([]string{"GOPATH=/go", "DIR=node_modules"})
+	assert.Equal(t, "/go/pkg/mod", resolvePath("$GOPATH/pkg/mod", "/workspace", envs))
+	assert.Equal(t, "/workspace/app/node_modules", resolvePath("app/${DIR}", "/workspace", envs))
+}
+
+func TestHashKeyFiles(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "web"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "go.sum"), []byte("go.sum"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "web", "package-lock.json"), []byte("lock"), 0644))
+
+	hash, err := hashKeyFiles([]string{"go.sum", "*/package-lock.json", "pom.xml"}, workspace, nil)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// the hash does not depend on the workspace and the order of the patterns
+	otherWorkspace := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(otherWorkspace, "web"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(otherWorkspace, "go.sum"), []byte("go.sum"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(otherWorkspace, "web", "package-lock.json"), []byte("lock"), 0644))
+	otherHash, err := hashKeyFiles([]string{"web/package-lock.json", "go.sum"}, otherWorkspace, nil)
+	require.NoError(t, err)
+	assert.Equal(t, hash, otherHash)
+
+	// the hash changes with the content of the key files
+	require.NoError(t, os.WriteFile(filepath.Join(otherWorkspace, "go.sum"), []byte("go.sum changed"), 0644))
+	otherHash, err = hashKeyFiles([]string{"web/package-lock.json", "go.sum"}, otherWorkspace, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, otherHash)
+
+	hash, err = hashKeyFiles([]string{"pom.xml"}, workspace, nil)
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+func init() {
+	Register(&Definition{
+		Type: "html_report",
+		Schema: fmt.Sprintf(`{
+	"type": "object",
+	"required": ["report_dir", "index_file", "s3_storage"],
+	"properties": {
+		"report_dir": {"type": "string", "minLength": 1},
+		"index_file": {"type": "string", "minLength": 1},
+		"s3_dest_dir": {"type": "string"},
+		"s3_storage": %s
+	}
+}`, s3StorageSchema),
+		New: func(spec interface{}, stepCtx *Context) (Step, error) {
+			return NewHtmlReportStep(spec, stepCtx)
+		},
+	})
+}
+
+// HtmlReportStep uploads the html report dir to the object storage, so the report can be viewed by its index file
+type HtmlReportStep struct {
+	spec    *step.StepHtmlReportSpec
+	stepCtx *Context
+}
+
+func NewHtmlReportStep(spec interface{}, stepCtx *Context) (*HtmlReportStep, error) {
+	htmlReportStep := &HtmlReportStep{stepCtx: stepCtx}
+	if err := decodeSpec(spec, &htmlReportStep.spec); err != nil {
+		return nil, err
+	}
+	return htmlReportStep, nil
+}
+
+func (s *HtmlReportStep) Run(ctx context.Context) error {
+	start := time.Now()
+	s.stepCtx.Logger.Printf("Start uploading html report.\n")
+	defer func() {
+		s.stepCtx.Logger.Printf("Html report ended. Duration: %.2f seconds.\n", time.Since(start).Seconds())
+	}()
+
+	envMap := makeEnvMap(s.stepCtx.Envs, s.stepCtx.SecretEnvs)
+	reportDir := resolvePath(s.spec.ReportDir, s.stepCtx.Workspace, envMap)
+	indexFile := filepath.Join(reportDir, replaceEnvWithValue(s.spec.IndexFile, envMap))
+	if info, err := os.Stat(indexFile); err != nil || info.IsDir() {
+		return fmt.Errorf("html report index file %s not found", indexFile)
+	}
+
+	client, err := newS3Client(s.spec.S3Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to upload html report, err: %s", err)
+	}
+	destDir := objectKey(s.spec.S3Storage, replaceEnvWithValue(s.spec.S3DestDir, envMap))
+	if err := client.UploadDir(s.spec.S3Storage.Bucket, reportDir, destDir); err != nil {
+		return fmt.Errorf("failed to upload html report %s, err: %s", reportDir, err)
+	}
+	return nil
+}
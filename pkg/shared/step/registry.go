@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package step is the step registry shared by the job executor and zadig-agent,
+// steps registered here can be run by both of them.
+package step
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type Step interface {
+	Run(ctx context.Context) error
+}
+
+// Logger writes the step logs into the job log
+type Logger interface {
+	Printf(format string, a ...any)
+}
+
+// Context is the runtime of a step
+type Context struct {
+	Workspace  string
+	Paths      string
+	Envs       []string
+	SecretEnvs []string
+	Logger     Logger
+	// Runtime is the executor specific runtime, which is only used by the steps registered by the executor itself,
+	// such as the config map updater of the job executor
+	Runtime interface{}
+}
+
+// Factory creates a step from the spec
+type Factory func(spec interface{}, stepCtx *Context) (Step, error)
+
+type Definition struct {
+	Type string
+	// Schema is the json schema of the step spec, the spec is not validated if it is empty
+	Schema string
+	New    Factory
+}
+
+var (
+	sharedDefinitions      = make(map[string]*Definition)
+	sharedDefinitionsMutex sync.RWMutex
+)
+
+// Register registers a step shared by all the executors, it is called in the init function of the step
+func Register(def *Definition) {
+	sharedDefinitionsMutex.Lock()
+	defer sharedDefinitionsMutex.Unlock()
+	sharedDefinitions[def.Type] = def
+}
+
+// Registry is the steps an executor can run
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]*Definition
+}
+
+// NewRegistry returns a registry with all the shared steps
+func NewRegistry() *Registry {
+	r := &Registry{definitions: make(map[string]*Definition)}
+	sharedDefinitionsMutex.RLock()
+	defer sharedDefinitionsMutex.RUnlock()
+	for stepType, def := range sharedDefinitions {
+		r.definitions[stepType] = def
+	}
+	return r
+}
+
+// Register registers an executor specific step, it overrides the shared step of the same type
+func (r *Registry) Register(def *Definition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.definitions[def.Type] = def
+}
+
+func (r *Registry) Get(stepType string) (*Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.definitions[stepType]
+	return def, ok
+}
+
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]string, 0, len(r.definitions))
+	for stepType := range r.definitions {
+		resp = append(resp, stepType)
+	}
+	sort.Strings(resp)
+	return resp
+}
+
+// Validate validates the spec against the schema of the step type
+func (r *Registry) Validate(stepType string, spec interface{}) error {
+	def, ok := r.Get(stepType)
+	if !ok {
+		return fmt.Errorf("step type: %s does not match any known type", stepType)
+	}
+	if def.Schema == "" {
+		return nil
+	}
+	if err := validateSchema(def.Schema, spec); err != nil {
+		return fmt.Errorf("invalid %s step spec: %v", stepType, err)
+	}
+	return nil
+}
+
+// New creates a step of the step type
+func (r *Registry) New(stepType string, spec interface{}, stepCtx *Context) (Step, error) {
+	def, ok := r.Get(stepType)
+	if !ok {
+		return nil, fmt.Errorf("step type: %s does not match any known type", stepType)
+	}
+	return def.New(spec, stepCtx)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStep struct{}
+
+func (fakeStep) Run(ctx context.Context) error {
+	return nil
+}
+
+func TestNewRegistry(t *testing.T) {
+	r := NewRegistry()
+	assert.Equal(t, []string{"cache_restore", "cache_save", "html_report"}, r.Types())
+
+	r.Register(&Definition{
+		Type: "shell",
+		New: func(spec interface{}, stepCtx *Context) (Step, error) {
+			return fakeStep{}, nil
+		},
+	})
+	_, ok := r.Get("shell")
+	assert.True(t, ok)
+	// executor specific steps are not shared
+	_, ok = NewRegistry().Get("shell")
+	assert.False(t, ok)
+
+	s, err := r.New("shell", nil, &Context{})
+	require.NoError(t, err)
+	assert.NoError(t, s.Run(context.Background()))
+
+	_, err = r.New("unknown", nil, &Context{})
+	assert.Error(t, err)
+}
+
+func TestRegistryValidate(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&Definition{Type: "shell"})
+
+	tests := []struct {
+		name     string
+		stepType string
+		spec     interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "step without schema",
+			stepType: "shell",
+			spec:     map[string]interface{}{"scripts": []string{"echo"}},
+		},
+		{
+			name:     "unknown step",
+			stepType: "unknown",
+			wantErr:  true,
+		},
+		{
+			name:     "valid html report",
+			stepType: "html_report",
+			spec: map[string]interface{}{
+				"report_dir": "report",
+				"index_file": "index.html",
+				"s3_storage": map[string]interface{}{"bucket": "zadig"},
+			},
+		},
+		{
+			name:     "html report without index file",
+			stepType: "html_report",
+			spec: map[string]interface{}{
+				"report_dir": "report",
+				"s3_storage": map[string]interface{}{"bucket": "zadig"},
+			},
+			wantErr: true,
+		},
+		{
+			// spec unmarshalled by the job executor from yaml
+			name:     "valid cache from yaml",
+			stepType: "cache_save",
+			spec: map[interface{}]interface{}{
+				"key":        "go-mod",
+				"paths":      []interface{}{"/root/go/pkg/mod"},
+				"s3_storage": map[interface{}]interface{}{"bucket": "zadig"},
+			},
+		},
+		{
+			name:     "cache without paths",
+			stepType: "cache_restore",
+			spec: map[string]interface{}{
+				"key":        "go-mod",
+				"paths":      []string{},
+				"s3_storage": map[string]interface{}{"bucket": "zadig"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.stepType, tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
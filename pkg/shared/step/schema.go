@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	yamlv2 "gopkg.in/yaml.v2"
+	"sigs.k8s.io/yaml"
+)
+
+func validateSchema(schema string, spec interface{}) error {
+	// the spec of the job executor is unmarshalled from yaml, which can not be marshalled to json directly
+	yamlBytes, err := yamlv2.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshal spec %+v failed: %v", spec, err)
+	}
+	jsonBytes, err := yaml.YAMLToJSON(yamlBytes)
+	if err != nil {
+		return fmt.Errorf("convert spec %s to json failed: %v", yamlBytes, err)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(schema), gojsonschema.NewBytesLoader(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("validate spec failed: %v", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	errs := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		errs = append(errs, e.String())
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// decodeSpec converts the spec to the spec struct by its yaml tags, just like the other steps
+func decodeSpec(spec interface{}, out interface{}) error {
+	yamlBytes, err := yamlv2.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yamlv2.Unmarshal(yamlBytes, out); err != nil {
+		return fmt.Errorf("unmarshal spec %s failed", yamlBytes)
+	}
+	return nil
+}
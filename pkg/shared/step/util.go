@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/s3"
+	"github.com/koderover/zadig/v2/pkg/types/step"
+)
+
+const s3StorageSchema = `{
+	"type": "object",
+	"required": ["bucket"],
+	"properties": {
+		"endpoint": {"type": "string"},
+		"bucket": {"type": "string", "minLength": 1},
+		"subfolder": {"type": "string"}
+	}
+}`
+
+func makeEnvMap(envs ...[]string) map[string]string {
+	envMap := map[string]string{}
+	for _, env := range envs {
+		for _, env := range env {
+			sl := strings.SplitN(env, "=", 2)
+			if len(sl) != 2 {
+				continue
+			}
+			envMap[sl[0]] = sl[1]
+		}
+	}
+	return envMap
+}
+
+func replaceEnvWithValue(str string, envs map[string]string) string {
+	ret := str
+	// Exec twice to render nested variables
+	for i := 0; i < 2; i++ {
+		for key, value := range envs {
+			ret = strings.ReplaceAll(ret, fmt.Sprintf("${%s}", key), value)
+			ret = strings.ReplaceAll(ret, fmt.Sprintf("$%s", key), value)
+		}
+	}
+	return ret
+}
+
+// resolvePath renders the envs in the path, relative path is relative to the workspace
+func resolvePath(p, workspace string, envs map[string]string) string {
+	p = replaceEnvWithValue(p, envs)
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(workspace, p)
+}
+
+func newS3Client(storage *step.S3) (*s3.Client, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("object storage is not set")
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	return s3.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+}
+
+func objectKey(storage *step.S3, elem ...string) string {
+	return strings.TrimLeft(path.Join(append([]string{storage.Subfolder}, elem...)...), "/")
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+const (
+	// CloudAddress is the address of bitbucket cloud
+	CloudAddress = "https://bitbucket.org"
+	// CloudAPIAddress is the api address of bitbucket cloud
+	CloudAPIAddress = "https://api.bitbucket.org/2.0"
+
+	defaultPageSize = 100
+)
+
+// commit status states shared by bitbucket cloud and bitbucket data center
+const (
+	StatusInProgress = "INPROGRESS"
+	StatusSuccessful = "SUCCESSFUL"
+	StatusFailed     = "FAILED"
+	StatusStopped    = "STOPPED"
+)
+
+type Workspace struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type Repository struct {
+	UUID        string `json:"uuid"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	MainBranch  *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+type Ref struct {
+	Type    string  `json:"type"`
+	Name    string  `json:"name"`
+	Message string  `json:"message"`
+	Target  *Commit `json:"target"`
+}
+
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Message string    `json:"message"`
+	Date    time.Time `json:"date"`
+	Author  *Author   `json:"author"`
+}
+
+type Author struct {
+	Raw  string `json:"raw"`
+	User *User  `json:"user"`
+}
+
+type User struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+	AccountID   string `json:"account_id"`
+}
+
+type PullRequest struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	State       string      `json:"state"`
+	Author      *User       `json:"author"`
+	Source      *PREndpoint `json:"source"`
+	Destination *PREndpoint `json:"destination"`
+	CreatedOn   time.Time   `json:"created_on"`
+	UpdatedOn   time.Time   `json:"updated_on"`
+}
+
+type PREndpoint struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Commit struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+	Repository *Repository `json:"repository"`
+}
+
+type DiffStat struct {
+	Status string `json:"status"`
+	Old    *struct {
+		Path string `json:"path"`
+	} `json:"old"`
+	New *struct {
+		Path string `json:"path"`
+	} `json:"new"`
+}
+
+type Hook struct {
+	UUID        string   `json:"uuid,omitempty"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Active      bool     `json:"active"`
+	Secret      string   `json:"secret,omitempty"`
+	Events      []string `json:"events"`
+}
+
+type Comment struct {
+	ID      int64 `json:"id"`
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+}
+
+// CommitStatus is the build status of a commit
+type CommitStatus struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// Client is the client of bitbucket cloud
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a bitbucket cloud client, the username and the app password are used for basic auth,
+// the token is used as a bearer token of the workspace/repository access token if no username is provided.
+func NewClient(username, token, proxyAddr string, enableProxy bool) *Client {
+	opts := []httpclient.ClientFunc{
+		httpclient.SetHostURL(CloudAPIAddress),
+	}
+	if username != "" {
+		opts = append(opts, httpclient.SetBasicAuth(username, token))
+	} else {
+		opts = append(opts, httpclient.SetAuthToken(token))
+	}
+	if enableProxy {
+		opts = append(opts, httpclient.SetProxy(proxyAddr))
+	}
+
+	return &Client{Client: httpclient.New(opts...)}
+}
+
+func repoPath(workspace, repo string) string {
+	return fmt.Sprintf("/repositories/%s/%s", url.PathEscape(workspace), url.PathEscape(repo))
+}
+
+func pageParams(page, perPage int) map[string]string {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = defaultPageSize
+	}
+	return map[string]string{
+		"page":    strconv.Itoa(page),
+		"pagelen": strconv.Itoa(perPage),
+	}
+}
+
+func (c *Client) ListWorkspaces() ([]*Workspace, error) {
+	resp := &struct {
+		Values []struct {
+			Workspace *Workspace `json:"workspace"`
+		} `json:"values"`
+	}{}
+	if _, err := c.Get("/user/permissions/workspaces", httpclient.SetQueryParams(pageParams(1, defaultPageSize)), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+
+	res := make([]*Workspace, 0, len(resp.Values))
+	for _, v := range resp.Values {
+		if v.Workspace != nil {
+			res = append(res, v.Workspace)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) ListRepositories(workspace, keyword string, page, perPage int) ([]*Repository, error) {
+	params := pageParams(page, perPage)
+	if keyword != "" {
+		params["q"] = fmt.Sprintf(`name ~ "%s"`, keyword)
+	}
+	resp := &struct {
+		Values []*Repository `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("/repositories/%s", url.PathEscape(workspace)), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *Client) listRefs(workspace, repo, kind, keyword string) ([]*Ref, error) {
+	params := pageParams(1, defaultPageSize)
+	if keyword != "" {
+		params["q"] = fmt.Sprintf(`name ~ "%s"`, keyword)
+	}
+	resp := &struct {
+		Values []*Ref `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("%s/refs/%s", repoPath(workspace, repo), kind), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *Client) ListBranches(workspace, repo, keyword string) ([]*Ref, error) {
+	return c.listRefs(workspace, repo, "branches", keyword)
+}
+
+func (c *Client) ListTags(workspace, repo, keyword string) ([]*Ref, error) {
+	return c.listRefs(workspace, repo, "tags", keyword)
+}
+
+// ListPullRequests lists the open pull requests, all of them are returned if targetBranch is empty
+func (c *Client) ListPullRequests(workspace, repo, targetBranch string) ([]*PullRequest, error) {
+	params := pageParams(1, defaultPageSize)
+	params["state"] = "OPEN"
+	if targetBranch != "" {
+		params["q"] = fmt.Sprintf(`destination.branch.name = "%s"`, targetBranch)
+	}
+	resp := &struct {
+		Values []*PullRequest `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("%s/pullrequests", repoPath(workspace, repo)), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *Client) ListCommits(workspace, repo, branch string, page, perPage int) ([]*Commit, error) {
+	resp := &struct {
+		Values []*Commit `json:"values"`
+	}{}
+	path := fmt.Sprintf("%s/commits", repoPath(workspace, repo))
+	if branch != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(branch))
+	}
+	if _, err := c.Get(path, httpclient.SetQueryParams(pageParams(page, perPage)), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+// ListChangedFiles returns the files changed in the commit range from..to,
+// only the files of the to commit are returned if from is empty
+func (c *Client) ListChangedFiles(workspace, repo, from, to string) ([]string, error) {
+	spec := to
+	if from != "" {
+		spec = fmt.Sprintf("%s..%s", to, from)
+	}
+	return c.listDiffStat(fmt.Sprintf("%s/diffstat/%s", repoPath(workspace, repo), spec))
+}
+
+func (c *Client) ListPullRequestChangedFiles(workspace, repo string, id int) ([]string, error) {
+	return c.listDiffStat(fmt.Sprintf("%s/pullrequests/%d/diffstat", repoPath(workspace, repo), id))
+}
+
+func (c *Client) listDiffStat(path string) ([]string, error) {
+	resp := &struct {
+		Values []*DiffStat `json:"values"`
+	}{}
+	if _, err := c.Get(path, httpclient.SetQueryParam("pagelen", "500"), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(resp.Values))
+	for _, stat := range resp.Values {
+		if stat.New != nil {
+			files = append(files, stat.New.Path)
+		}
+		if stat.Old != nil && (stat.New == nil || stat.Old.Path != stat.New.Path) {
+			files = append(files, stat.Old.Path)
+		}
+	}
+	return files, nil
+}
+
+func (c *Client) CreateHook(workspace, repo string, hook *Hook) (*Hook, error) {
+	res := &Hook{}
+	if _, err := c.Post(fmt.Sprintf("%s/hooks", repoPath(workspace, repo)), httpclient.SetBody(hook), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteHook(workspace, repo, uuid string) error {
+	_, err := c.Delete(fmt.Sprintf("%s/hooks/%s", repoPath(workspace, repo), url.PathEscape(uuid)))
+	return err
+}
+
+func (c *Client) CreatePullRequestComment(workspace, repo string, prID int, body string) (*Comment, error) {
+	res := &Comment{}
+	if _, err := c.Post(fmt.Sprintf("%s/pullrequests/%d/comments", repoPath(workspace, repo), prID),
+		httpclient.SetBody(map[string]interface{}{"content": map[string]string{"raw": body}}), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) UpdatePullRequestComment(workspace, repo string, prID int, commentID int64, body string) error {
+	_, err := c.Put(fmt.Sprintf("%s/pullrequests/%d/comments/%d", repoPath(workspace, repo), prID, commentID),
+		httpclient.SetBody(map[string]interface{}{"content": map[string]string{"raw": body}}))
+	return err
+}
+
+// SetCommitStatus creates or updates the build status with the same key of a commit
+func (c *Client) SetCommitStatus(workspace, repo, sha string, status *CommitStatus) error {
+	_, err := c.Post(fmt.Sprintf("%s/commit/%s/statuses/build", repoPath(workspace, repo), sha), httpclient.SetBody(status))
+	return err
+}
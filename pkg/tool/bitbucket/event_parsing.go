@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	EventKeyHeader  = "X-Event-Key"
+	SignatureHeader = "X-Hub-Signature"
+
+	// events of bitbucket cloud
+	EventRepoPush           = "repo:push"
+	EventPullRequestCreated = "pullrequest:created"
+	EventPullRequestUpdated = "pullrequest:updated"
+
+	// events of bitbucket data center/server
+	EventRefsChanged       = "repo:refs_changed"
+	EventPROpened          = "pr:opened"
+	EventPRFromRefUpdated  = "pr:from_ref_updated"
+	EventDiagnosticsPing   = "diagnostics:ping"
+	ServerRefTypeBranch    = "BRANCH"
+	ServerRefTypeTag       = "TAG"
+	ServerRefChangeDeleted = "DELETE"
+)
+
+// CloudEvents are the events subscribed by the webhooks of bitbucket cloud
+var CloudEvents = []string{EventRepoPush, EventPullRequestCreated, EventPullRequestUpdated}
+
+// ServerEvents are the events subscribed by the webhooks of bitbucket data center/server
+var ServerEvents = []string{EventRefsChanged, EventPROpened, EventPRFromRefUpdated}
+
+// PushEvent is the repo:push event of bitbucket cloud, a push may contain the changes of several branches and tags
+type PushEvent struct {
+	Actor      *User       `json:"actor"`
+	Repository *Repository `json:"repository"`
+	Push       struct {
+		Changes []*PushChange `json:"changes"`
+	} `json:"push"`
+}
+
+type PushChange struct {
+	New     *Ref      `json:"new"`
+	Old     *Ref      `json:"old"`
+	Created bool      `json:"created"`
+	Closed  bool      `json:"closed"`
+	Commits []*Commit `json:"commits"`
+}
+
+// Type returns the ref type of the change, branch or tag
+func (c *PushChange) Type() string {
+	if c.New == nil {
+		return ""
+	}
+	return c.New.Type
+}
+
+// PullRequestEvent is the pullrequest:created and pullrequest:updated event of bitbucket cloud
+type PullRequestEvent struct {
+	Actor       *User        `json:"actor"`
+	Repository  *Repository  `json:"repository"`
+	PullRequest *PullRequest `json:"pullrequest"`
+}
+
+// ServerRefsChangedEvent is the repo:refs_changed event of bitbucket data center/server
+type ServerRefsChangedEvent struct {
+	EventKey   string             `json:"eventKey"`
+	Actor      *ServerUser        `json:"actor"`
+	Repository *ServerRepository  `json:"repository"`
+	Changes    []*ServerRefChange `json:"changes"`
+}
+
+type ServerRefChange struct {
+	Ref      *ServerRef `json:"ref"`
+	RefID    string     `json:"refId"`
+	FromHash string     `json:"fromHash"`
+	ToHash   string     `json:"toHash"`
+	Type     string     `json:"type"`
+}
+
+// ServerPullRequestEvent is the pr:opened and pr:from_ref_updated event of bitbucket data center/server
+type ServerPullRequestEvent struct {
+	EventKey    string             `json:"eventKey"`
+	Actor       *ServerUser        `json:"actor"`
+	PullRequest *ServerPullRequest `json:"pullRequest"`
+}
+
+// HookEventType returns the event key of a bitbucket webhook request
+func HookEventType(r *http.Request) string {
+	return r.Header.Get(EventKeyHeader)
+}
+
+// IsServerEvent returns true if the event is sent by bitbucket data center/server
+func IsServerEvent(eventKey string) bool {
+	return eventKey == EventRefsChanged || strings.HasPrefix(eventKey, "pr:") || eventKey == EventDiagnosticsPing
+}
+
+// ParseHook parses the payload of the supported events, nil is returned for the events not supported
+func ParseHook(eventKey string, payload []byte) (interface{}, error) {
+	var event interface{}
+	switch eventKey {
+	case EventRepoPush:
+		event = &PushEvent{}
+	case EventPullRequestCreated, EventPullRequestUpdated:
+		event = &PullRequestEvent{}
+	case EventRefsChanged:
+		event = &ServerRefsChangedEvent{}
+	case EventPROpened, EventPRFromRefUpdated:
+		event = &ServerPullRequestEvent{}
+	default:
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("failed to parse bitbucket event %s: %v", eventKey, err)
+	}
+	return event, nil
+}
+
+// ValidateSignature validates the X-Hub-Signature header sent by bitbucket, e.g. sha256=<hex digest>
+func ValidateSignature(signature string, payload []byte, secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	digest := strings.TrimPrefix(signature, "sha256=")
+	expected, err := hex.DecodeString(digest)
+	if err != nil || digest == signature {
+		return fmt.Errorf("invalid signature %q", signature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("payload signature check failed")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateSignature(t *testing.T) {
+	payload := []byte(`{"eventKey":"repo:refs_changed"}`)
+	type testcase struct {
+		signature string
+		secret    string
+		valid     bool
+	}
+	testcases := []testcase{
+		{"", "", true},
+		{"sha256=3d8ca3b1b1d0cb2a1ea3f8e35ed7e7b2c0cca6a1ed12af8fb2ef27d5c8b8ad02", "", true},
+		{"", "secret", false},
+		{"3d8ca3b1", "secret", false},
+		{"sha256=00", "secret", false},
+		{"sha256=" + sign(payload, "secret"), "secret", true},
+		{"sha256=" + sign(payload, "another"), "secret", false},
+	}
+
+	for _, ts := range testcases {
+		err := ValidateSignature(ts.signature, payload, ts.secret)
+		if (err == nil) != ts.valid {
+			t.Errorf("Expected signature <%s> with secret <%s> to be valid: %v, but got err: %v", ts.signature, ts.secret, ts.valid, err)
+		}
+	}
+}
+
+func TestParseHook(t *testing.T) {
+	event, err := ParseHook(EventRepoPush, []byte(`{"push":{"changes":[{"new":{"type":"tag","name":"v1.0.0"}}]}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse push event: %v", err)
+	}
+	push, ok := event.(*PushEvent)
+	if !ok || len(push.Push.Changes) != 1 || push.Push.Changes[0].Type() != "tag" {
+		t.Errorf("Expected a tag push event but got %+v", event)
+	}
+
+	event, err = ParseHook(EventDiagnosticsPing, []byte(`{}`))
+	if err != nil || event != nil {
+		t.Errorf("Expected ping event to be ignored but got %+v, err: %v", event, err)
+	}
+}
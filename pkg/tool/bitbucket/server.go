@@ -0,0 +1,305 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+const (
+	serverAPIPrefix         = "/rest/api/1.0"
+	serverBuildStatusPrefix = "/rest/build-status/1.0"
+)
+
+type ServerProject struct {
+	ID   int    `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type ServerRepository struct {
+	ID          int            `json:"id"`
+	Slug        string         `json:"slug"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Project     *ServerProject `json:"project"`
+}
+
+type ServerRef struct {
+	ID           string            `json:"id"`
+	DisplayID    string            `json:"displayId"`
+	Type         string            `json:"type"`
+	LatestCommit string            `json:"latestCommit"`
+	IsDefault    bool              `json:"isDefault"`
+	Repository   *ServerRepository `json:"repository,omitempty"`
+}
+
+type ServerUser struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+}
+
+type ServerCommit struct {
+	ID              string      `json:"id"`
+	DisplayID       string      `json:"displayId"`
+	Message         string      `json:"message"`
+	Author          *ServerUser `json:"author"`
+	AuthorTimestamp int64       `json:"authorTimestamp"`
+}
+
+type ServerPullRequest struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Author struct {
+		User *ServerUser `json:"user"`
+	} `json:"author"`
+	FromRef     *ServerRef `json:"fromRef"`
+	ToRef       *ServerRef `json:"toRef"`
+	CreatedDate int64      `json:"createdDate"`
+	UpdatedDate int64      `json:"updatedDate"`
+}
+
+type ServerHook struct {
+	ID            int               `json:"id,omitempty"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Active        bool              `json:"active"`
+	Events        []string          `json:"events"`
+	Configuration map[string]string `json:"configuration,omitempty"`
+}
+
+type ServerComment struct {
+	ID      int64  `json:"id"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// ServerClient is the client of bitbucket data center/server
+type ServerClient struct {
+	*httpclient.Client
+}
+
+// NewServerClient returns a bitbucket data center client, the username and the password are used for basic auth,
+// the token is used as a bearer token of the http access token if no username is provided.
+func NewServerClient(address, username, token, proxyAddr string, enableProxy bool) *ServerClient {
+	opts := []httpclient.ClientFunc{
+		httpclient.SetHostURL(strings.TrimSuffix(address, "/")),
+	}
+	if username != "" {
+		opts = append(opts, httpclient.SetBasicAuth(username, token))
+	} else {
+		opts = append(opts, httpclient.SetAuthToken(token))
+	}
+	if enableProxy {
+		opts = append(opts, httpclient.SetProxy(proxyAddr))
+	}
+
+	return &ServerClient{Client: httpclient.New(opts...)}
+}
+
+func serverRepoPath(project, repo string) string {
+	return fmt.Sprintf("%s/projects/%s/repos/%s", serverAPIPrefix, url.PathEscape(project), url.PathEscape(repo))
+}
+
+func serverPageParams(page, perPage int) map[string]string {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = defaultPageSize
+	}
+	return map[string]string{
+		"start": strconv.Itoa((page - 1) * perPage),
+		"limit": strconv.Itoa(perPage),
+	}
+}
+
+func (c *ServerClient) ListProjects(keyword string) ([]*ServerProject, error) {
+	params := serverPageParams(1, defaultPageSize)
+	if keyword != "" {
+		params["name"] = keyword
+	}
+	resp := &struct {
+		Values []*ServerProject `json:"values"`
+	}{}
+	if _, err := c.Get(serverAPIPrefix+"/projects", httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *ServerClient) ListRepositories(project, keyword string, page, perPage int) ([]*ServerRepository, error) {
+	params := serverPageParams(page, perPage)
+	params["projectkey"] = project
+	if keyword != "" {
+		params["name"] = keyword
+	}
+	resp := &struct {
+		Values []*ServerRepository `json:"values"`
+	}{}
+	if _, err := c.Get(serverAPIPrefix+"/repos", httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *ServerClient) GetDefaultBranch(project, repo string) (*ServerRef, error) {
+	res := &ServerRef{}
+	if _, err := c.Get(fmt.Sprintf("%s/branches/default", serverRepoPath(project, repo)), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *ServerClient) listRefs(project, repo, kind, keyword string) ([]*ServerRef, error) {
+	params := serverPageParams(1, defaultPageSize)
+	if keyword != "" {
+		params["filterText"] = keyword
+	}
+	resp := &struct {
+		Values []*ServerRef `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("%s/%s", serverRepoPath(project, repo), kind), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *ServerClient) ListBranches(project, repo, keyword string) ([]*ServerRef, error) {
+	return c.listRefs(project, repo, "branches", keyword)
+}
+
+func (c *ServerClient) ListTags(project, repo, keyword string) ([]*ServerRef, error) {
+	return c.listRefs(project, repo, "tags", keyword)
+}
+
+// ListPullRequests lists the open pull requests, all of them are returned if targetBranch is empty
+func (c *ServerClient) ListPullRequests(project, repo, targetBranch string) ([]*ServerPullRequest, error) {
+	params := serverPageParams(1, defaultPageSize)
+	params["state"] = "OPEN"
+	if targetBranch != "" {
+		params["at"] = "refs/heads/" + targetBranch
+		params["direction"] = "INCOMING"
+	}
+	resp := &struct {
+		Values []*ServerPullRequest `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("%s/pull-requests", serverRepoPath(project, repo)), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (c *ServerClient) ListCommits(project, repo, branch string, page, perPage int) ([]*ServerCommit, error) {
+	params := serverPageParams(page, perPage)
+	if branch != "" {
+		params["until"] = branch
+	}
+	resp := &struct {
+		Values []*ServerCommit `json:"values"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("%s/commits", serverRepoPath(project, repo)), httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+// ListChangedFiles returns the files changed in the commit range from..to,
+// only the files of the to commit are returned if from is empty
+func (c *ServerClient) ListChangedFiles(project, repo, from, to string) ([]string, error) {
+	params := map[string]string{"until": to, "limit": "1000"}
+	if from != "" {
+		params["since"] = from
+	}
+	return c.listChanges(fmt.Sprintf("%s/changes", serverRepoPath(project, repo)), params)
+}
+
+func (c *ServerClient) ListPullRequestChangedFiles(project, repo string, id int) ([]string, error) {
+	return c.listChanges(fmt.Sprintf("%s/pull-requests/%d/changes", serverRepoPath(project, repo), id), map[string]string{"limit": "1000"})
+}
+
+func (c *ServerClient) listChanges(path string, params map[string]string) ([]string, error) {
+	resp := &struct {
+		Values []struct {
+			Path struct {
+				ToString string `json:"toString"`
+			} `json:"path"`
+			SrcPath *struct {
+				ToString string `json:"toString"`
+			} `json:"srcPath"`
+		} `json:"values"`
+	}{}
+	if _, err := c.Get(path, httpclient.SetQueryParams(params), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(resp.Values))
+	for _, change := range resp.Values {
+		files = append(files, change.Path.ToString)
+		if change.SrcPath != nil && change.SrcPath.ToString != change.Path.ToString {
+			files = append(files, change.SrcPath.ToString)
+		}
+	}
+	return files, nil
+}
+
+func (c *ServerClient) CreateHook(project, repo string, hook *ServerHook) (*ServerHook, error) {
+	res := &ServerHook{}
+	if _, err := c.Post(fmt.Sprintf("%s/webhooks", serverRepoPath(project, repo)), httpclient.SetBody(hook), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *ServerClient) DeleteHook(project, repo, id string) error {
+	_, err := c.Delete(fmt.Sprintf("%s/webhooks/%s", serverRepoPath(project, repo), url.PathEscape(id)))
+	return err
+}
+
+func (c *ServerClient) CreatePullRequestComment(project, repo string, prID int, text string) (*ServerComment, error) {
+	res := &ServerComment{}
+	if _, err := c.Post(fmt.Sprintf("%s/pull-requests/%d/comments", serverRepoPath(project, repo), prID),
+		httpclient.SetBody(map[string]string{"text": text}), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdatePullRequestComment updates the text of a comment, the latest version of the comment is required by bitbucket
+func (c *ServerClient) UpdatePullRequestComment(project, repo string, prID int, commentID int64, text string) error {
+	path := fmt.Sprintf("%s/pull-requests/%d/comments/%d", serverRepoPath(project, repo), prID, commentID)
+	comment := &ServerComment{}
+	if _, err := c.Get(path, httpclient.SetResult(comment)); err != nil {
+		return err
+	}
+
+	_, err := c.Put(path, httpclient.SetBody(map[string]interface{}{"text": text, "version": comment.Version}))
+	return err
+}
+
+// SetCommitStatus creates or updates the build status with the same key of a commit
+func (c *ServerClient) SetCommitStatus(sha string, status *CommitStatus) error {
+	_, err := c.Post(fmt.Sprintf("%s/commits/%s", serverBuildStatusPrefix, sha), httpclient.SetBody(status))
+	return err
+}
@@ -230,6 +230,10 @@ var (
 	ErrAnalysisEnvResource      = NewHTTPError(6151, "AI环境巡检失败")
 	ErrListPod                  = NewHTTPError(6152, "列出Pod失败")
 	ErrGetPodDetail             = NewHTTPError(6153, "获取Pod详情失败")
+	ErrGetEnvDrift              = NewHTTPError(6154, "获取环境配置漂移失败")
+	ErrDetectEnvDrift           = NewHTTPError(6155, "检测环境配置漂移失败")
+	ErrGetPreviewEnvPolicy      = NewHTTPError(6156, "获取预览环境策略失败")
+	ErrUpdatePreviewEnvPolicy   = NewHTTPError(6157, "更新预览环境策略失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// it report APIs Range: 6100 - 6149
@@ -870,6 +874,11 @@ var (
 	ErrUpdateStatisticsDashboardConfig = NewHTTPError(7002, "更新统计看板配置失败")
 	ErrDeleteStatisticsDashboardConfig = NewHTTPError(7003, "删除统计看板配置失败")
 	ErrGetStatisticsDashboard          = NewHTTPError(7004, "获取统计看板失败")
+	ErrCreateIncident                  = NewHTTPError(7005, "创建故障记录失败")
+	ErrListIncident                    = NewHTTPError(7006, "列出故障记录失败")
+	ErrResolveIncident                 = NewHTTPError(7007, "恢复故障记录失败")
+	ErrGetDORAStatistics               = NewHTTPError(7008, "获取 DORA 统计失败")
+	ErrGetIncident                     = NewHTTPError(7009, "获取故障记录失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// llm integraton Error Range: 7010 - 7019
@@ -923,4 +932,33 @@ var (
 	ErrSetIstioGrayscaleConfig          = NewHTTPError(7064, "设置Istio灰度失败")
 	ErrGetIstioGrayscalePortalService   = NewHTTPError(7065, "获取Istio灰度入口服务配置失败")
 	ErrSetupIstioGrayscalePortalService = NewHTTPError(7066, "设置Istio灰度入口服务失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// Env Snapshot APIs Range: 7070 - 7079
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateEnvSnapshot  = NewHTTPError(7070, "创建环境快照失败")
+	ErrListEnvSnapshot    = NewHTTPError(7071, "列出环境快照失败")
+	ErrGetEnvSnapshot     = NewHTTPError(7072, "获取环境快照失败")
+	ErrDeleteEnvSnapshot  = NewHTTPError(7073, "删除环境快照失败")
+	ErrRestoreEnvSnapshot = NewHTTPError(7074, "恢复环境快照失败")
+	ErrDiffEnvSnapshot    = NewHTTPError(7075, "对比环境快照失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// Workflow As Code APIs Range: 7080 - 7089
+	//-----------------------------------------------------------------------------------------------
+	ErrGetWorkflowCodeSource    = NewHTTPError(7080, "获取工作流代码源失败")
+	ErrUpdateWorkflowCodeSource = NewHTTPError(7081, "更新工作流代码源失败")
+	ErrSyncWorkflowCode         = NewHTTPError(7082, "同步工作流代码失败")
+	ErrPlanWorkflowCode         = NewHTTPError(7083, "计算工作流变更失败")
+	ErrGetWorkflowCodeDrift     = NewHTTPError(7084, "获取工作流代码漂移失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// Workflow Policy APIs Range: 7090 - 7099
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateWorkflowPolicy        = NewHTTPError(7090, "创建工作流策略失败")
+	ErrUpdateWorkflowPolicy        = NewHTTPError(7091, "更新工作流策略失败")
+	ErrDeleteWorkflowPolicy        = NewHTTPError(7092, "删除工作流策略失败")
+	ErrGetWorkflowPolicy           = NewHTTPError(7093, "获取工作流策略失败")
+	ErrListWorkflowPolicyDecisions = NewHTTPError(7094, "获取工作流策略审计记录失败")
+	ErrWorkflowPolicyViolated      = NewHTTPError(7095, "违反工作流策略")
 )
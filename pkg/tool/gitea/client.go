@@ -0,0 +1,301 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/v2/pkg/tool/httpclient"
+)
+
+const (
+	apiPrefix       = "/api/v1"
+	defaultPageSize = 100
+)
+
+// commit status states of gitea
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusError   = "error"
+	StatusFailure = "failure"
+	StatusWarning = "warning"
+)
+
+type User struct {
+	ID       int64  `json:"id"`
+	Login    string `json:"login"`
+	UserName string `json:"username"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+}
+
+type Organization struct {
+	ID       int64  `json:"id"`
+	UserName string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+type Repository struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         *User  `json:"owner"`
+}
+
+type Branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+type Tag struct {
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	ZipballURL string `json:"zipball_url"`
+	TarballURL string `json:"tarball_url"`
+}
+
+type PRBranch struct {
+	Ref  string      `json:"ref"`
+	Sha  string      `json:"sha"`
+	Repo *Repository `json:"repo"`
+}
+
+type PullRequest struct {
+	ID        int64     `json:"id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	User      *User     `json:"user"`
+	Head      *PRBranch `json:"head"`
+	Base      *PRBranch `json:"base"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Commit struct {
+	Sha    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+type Hook struct {
+	ID     int64             `json:"id,omitempty"`
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config"`
+	Events []string          `json:"events"`
+	Active bool              `json:"active"`
+}
+
+type Comment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// CommitStatus is the status of a commit
+type CommitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a gitea client authorized by the access token
+func NewClient(address, token, proxyAddr string, enableProxy bool) *Client {
+	opts := []httpclient.ClientFunc{
+		httpclient.SetHostURL(strings.TrimSuffix(address, "/") + apiPrefix),
+		httpclient.SetClientHeader("Authorization", "token "+token),
+	}
+	if enableProxy {
+		opts = append(opts, httpclient.SetProxy(proxyAddr))
+	}
+
+	return &Client{Client: httpclient.New(opts...)}
+}
+
+func repoPath(owner, repo string) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+}
+
+func pageParams(page, perPage int) map[string]string {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = defaultPageSize
+	}
+	return map[string]string{
+		"page":  strconv.Itoa(page),
+		"limit": strconv.Itoa(perPage),
+	}
+}
+
+func (c *Client) GetAuthenticatedUser() (*User, error) {
+	res := &User{}
+	if _, err := c.Get("/user", httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListOrganizations() ([]*Organization, error) {
+	var res []*Organization
+	if _, err := c.Get("/user/orgs", httpclient.SetQueryParams(pageParams(1, defaultPageSize)), httpclient.SetResult(&res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListRepositories lists the repositories of an organization, or the repositories of the authenticated user if org is empty
+func (c *Client) ListRepositories(org, keyword string, page, perPage int) ([]*Repository, error) {
+	path := "/user/repos"
+	if org != "" {
+		path = fmt.Sprintf("/orgs/%s/repos", url.PathEscape(org))
+	}
+	var repos []*Repository
+	if _, err := c.Get(path, httpclient.SetQueryParams(pageParams(page, perPage)), httpclient.SetResult(&repos)); err != nil {
+		return nil, err
+	}
+	if keyword == "" {
+		return repos, nil
+	}
+
+	res := make([]*Repository, 0, len(repos))
+	for _, repo := range repos {
+		if strings.Contains(strings.ToLower(repo.Name), strings.ToLower(keyword)) {
+			res = append(res, repo)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) ListBranches(owner, repo string) ([]*Branch, error) {
+	var res []*Branch
+	if _, err := c.Get(fmt.Sprintf("%s/branches", repoPath(owner, repo)), httpclient.SetQueryParams(pageParams(1, defaultPageSize)), httpclient.SetResult(&res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(owner, repo string) ([]*Tag, error) {
+	var res []*Tag
+	if _, err := c.Get(fmt.Sprintf("%s/tags", repoPath(owner, repo)), httpclient.SetQueryParams(pageParams(1, defaultPageSize)), httpclient.SetResult(&res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListPullRequests lists the open pull requests, all of them are returned if targetBranch is empty
+func (c *Client) ListPullRequests(owner, repo, targetBranch string) ([]*PullRequest, error) {
+	params := pageParams(1, defaultPageSize)
+	params["state"] = "open"
+	var prs []*PullRequest
+	if _, err := c.Get(fmt.Sprintf("%s/pulls", repoPath(owner, repo)), httpclient.SetQueryParams(params), httpclient.SetResult(&prs)); err != nil {
+		return nil, err
+	}
+	if targetBranch == "" {
+		return prs, nil
+	}
+
+	res := make([]*PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		if pr.Base != nil && pr.Base.Ref == targetBranch {
+			res = append(res, pr)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) ListCommits(owner, repo, branch string, page, perPage int) ([]*Commit, error) {
+	params := pageParams(page, perPage)
+	if branch != "" {
+		params["sha"] = branch
+	}
+	var res []*Commit
+	if _, err := c.Get(fmt.Sprintf("%s/commits", repoPath(owner, repo)), httpclient.SetQueryParams(params), httpclient.SetResult(&res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListPullRequestChangedFiles(owner, repo string, number int) ([]string, error) {
+	var res []struct {
+		Filename         string `json:"filename"`
+		PreviousFilename string `json:"previous_filename"`
+	}
+	if _, err := c.Get(fmt.Sprintf("%s/pulls/%d/files", repoPath(owner, repo), number), httpclient.SetQueryParam("limit", "1000"), httpclient.SetResult(&res)); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(res))
+	for _, file := range res {
+		files = append(files, file.Filename)
+		if file.PreviousFilename != "" && file.PreviousFilename != file.Filename {
+			files = append(files, file.PreviousFilename)
+		}
+	}
+	return files, nil
+}
+
+func (c *Client) CreateHook(owner, repo string, hook *Hook) (*Hook, error) {
+	res := &Hook{}
+	if _, err := c.Post(fmt.Sprintf("%s/hooks", repoPath(owner, repo)), httpclient.SetBody(hook), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) DeleteHook(owner, repo string, id int64) error {
+	_, err := c.Delete(fmt.Sprintf("%s/hooks/%d", repoPath(owner, repo), id))
+	return err
+}
+
+func (c *Client) CreatePullRequestComment(owner, repo string, number int, body string) (*Comment, error) {
+	res := &Comment{}
+	if _, err := c.Post(fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number),
+		httpclient.SetBody(map[string]string{"body": body}), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) UpdatePullRequestComment(owner, repo string, commentID int64, body string) error {
+	_, err := c.Patch(fmt.Sprintf("%s/issues/comments/%d", repoPath(owner, repo), commentID), httpclient.SetBody(map[string]string{"body": body}))
+	return err
+}
+
+// SetCommitStatus creates a status of a commit, the latest status with the same context is shown by gitea
+func (c *Client) SetCommitStatus(owner, repo, sha string, status *CommitStatus) error {
+	_, err := c.Post(fmt.Sprintf("%s/statuses/%s", repoPath(owner, repo), sha), httpclient.SetBody(status))
+	return err
+}
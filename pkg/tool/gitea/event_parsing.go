@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	EventTypeHeader = "X-Gitea-Event"
+	SignatureHeader = "X-Gitea-Signature"
+
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+
+	PullRequestActionOpened       = "opened"
+	PullRequestActionReopened     = "reopened"
+	PullRequestActionSynchronized = "synchronized"
+)
+
+// Events are the events subscribed by the webhooks of gitea, tag pushes are sent as push events
+var Events = []string{EventPush, EventPullRequest}
+
+type PayloadCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+	Author   struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		UserName string `json:"username"`
+	} `json:"author"`
+}
+
+// PushEvent is the push event of gitea, which is sent for both branches and tags
+type PushEvent struct {
+	Ref        string           `json:"ref"`
+	Before     string           `json:"before"`
+	After      string           `json:"after"`
+	Commits    []*PayloadCommit `json:"commits"`
+	Repository *Repository      `json:"repository"`
+	Pusher     *User            `json:"pusher"`
+	Sender     *User            `json:"sender"`
+}
+
+// IsTag returns true if a tag is pushed
+func (e *PushEvent) IsTag() bool {
+	return strings.HasPrefix(e.Ref, "refs/tags/")
+}
+
+type PullRequestEvent struct {
+	Action      string       `json:"action"`
+	Number      int          `json:"number"`
+	PullRequest *PullRequest `json:"pull_request"`
+	Repository  *Repository  `json:"repository"`
+	Sender      *User        `json:"sender"`
+}
+
+// HookEventType returns the event type of a gitea webhook request
+func HookEventType(r *http.Request) string {
+	return r.Header.Get(EventTypeHeader)
+}
+
+// ParseHook parses the payload of the supported events, nil is returned for the events not supported
+func ParseHook(eventType string, payload []byte) (interface{}, error) {
+	var event interface{}
+	switch eventType {
+	case EventPush:
+		event = &PushEvent{}
+	case EventPullRequest:
+		event = &PullRequestEvent{}
+	default:
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("failed to parse gitea event %s: %v", eventType, err)
+	}
+	return event, nil
+}
+
+// ValidateSignature validates the X-Gitea-Signature header, which is the hex digest of the payload signed by the secret
+func ValidateSignature(signature string, payload []byte, secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || signature == "" {
+		return fmt.Errorf("invalid signature %q", signature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("payload signature check failed")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateSignature(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+
+	tests := []struct {
+		name      string
+		signature string
+		secret    string
+		wantErr   bool
+	}{
+		{name: "valid", signature: sign("secret", payload), secret: "secret"},
+		{name: "no secret", signature: "", secret: ""},
+		{name: "wrong secret", signature: sign("another", payload), secret: "secret", wantErr: true},
+		{name: "empty signature", signature: "", secret: "secret", wantErr: true},
+		{name: "not hex", signature: "not-a-hex-digest", secret: "secret", wantErr: true},
+		{name: "tampered payload", signature: sign("secret", []byte(`{"ref":"refs/heads/dev"}`)), secret: "secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSignature(tt.signature, payload, tt.secret)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseHook(t *testing.T) {
+	event, err := ParseHook(EventPush, []byte(`{
+		"ref": "refs/tags/v1.0.0",
+		"after": "abc123",
+		"commits": [{"id": "abc123", "message": "release", "modified": ["main.go"]}],
+		"repository": {"name": "zadig", "owner": {"username": "koderover"}}
+	}`))
+	require.NoError(t, err)
+	push, ok := event.(*PushEvent)
+	require.True(t, ok)
+	assert.True(t, push.IsTag())
+	assert.Equal(t, "abc123", push.After)
+	require.Len(t, push.Commits, 1)
+	assert.Equal(t, []string{"main.go"}, push.Commits[0].Modified)
+	assert.Equal(t, "koderover", push.Repository.Owner.UserName)
+
+	event, err = ParseHook(EventPullRequest, []byte(`{
+		"action": "synchronized",
+		"number": 12,
+		"pull_request": {"number": 12, "head": {"ref": "feature", "sha": "def456"}, "base": {"ref": "main"}},
+		"repository": {"name": "zadig"}
+	}`))
+	require.NoError(t, err)
+	pr, ok := event.(*PullRequestEvent)
+	require.True(t, ok)
+	assert.Equal(t, PullRequestActionSynchronized, pr.Action)
+	assert.Equal(t, "def456", pr.PullRequest.Head.Sha)
+	assert.Equal(t, "main", pr.PullRequest.Base.Ref)
+
+	event, err = ParseHook("issues", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+
+	_, err = ParseHook(EventPush, []byte(`{"ref": `))
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/koderover/zadig/v2/pkg/tool/cache"
+)
+
+const ProviderFake = "fake"
+
+// FakeClient returns the given responses in order without calling any server, it is used in tests.
+// register it by Register(ProviderFake, func() ILLM { return NewFakeClient("answer") }) to use it through NewClient.
+type FakeClient struct {
+	usageCounter
+	mu        sync.Mutex
+	name      string
+	responses []string
+	err       error
+	// Prompts records all the prompts received by the client
+	Prompts []string
+}
+
+func NewFakeClient(responses ...string) *FakeClient {
+	return &FakeClient{responses: responses}
+}
+
+// WithError makes all the completions fail with the error
+func (c *FakeClient) WithError(err error) *FakeClient {
+	c.err = err
+	return c
+}
+
+func (c *FakeClient) Configure(config LLMConfig) error {
+	c.name = config.GetName()
+	return nil
+}
+
+// GetCompletion returns the next response, the last response is repeated once all the responses are used
+func (c *FakeClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Prompts = append(c.Prompts, prompt)
+	if c.err != nil {
+		return "", c.err
+	}
+	response := ""
+	if len(c.responses) > 0 {
+		response = c.responses[0]
+		if len(c.responses) > 1 {
+			c.responses = c.responses[1:]
+		}
+	}
+	// count the words as tokens
+	c.addUsage(len(strings.Fields(prompt)), len(strings.Fields(response)))
+	return response, nil
+}
+
+// GetCompletionStream sends the response word by word
+func (c *FakeClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	response, err := c.GetCompletion(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+	for _, chunk := range strings.SplitAfter(response, " ") {
+		if chunk == "" {
+			continue
+		}
+		if err := handler(chunk); err != nil {
+			return "", err
+		}
+	}
+	return response, nil
+}
+
+func (c *FakeClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *FakeClient) GetName() string {
+	if c.name == "" {
+		return ProviderFake
+	}
+	return c.name
+}
@@ -18,32 +18,85 @@ package llm
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/koderover/zadig/v2/pkg/tool/cache"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
+const (
+	ProviderOpenAI      = "openai"
+	ProviderAzureOpenAI = "azureopenai"
+	ProviderOllama      = "ollama"
+	ProviderTGI         = "tgi"
+)
+
+// ClientFactory creates a new client of a provider, a new client should be returned for every call
+// since the client keeps its own config and token usage.
+type ClientFactory func() ILLM
+
 var (
-	clients = map[string]ILLM{
-		"openai":      &OpenAIClient{},
-		"azureopenai": &OpenAIClient{},
+	providersMutex sync.RWMutex
+	providers      = map[string]ClientFactory{
+		ProviderOpenAI:      func() ILLM { return &OpenAIClient{} },
+		ProviderAzureOpenAI: func() ILLM { return &OpenAIClient{} },
+		ProviderOllama:      func() ILLM { return &OllamaClient{} },
+		ProviderTGI:         func() ILLM { return &TGIClient{} },
 	}
 )
 
+// StreamHandler is called with every chunk of a streaming completion, returning an error stops the stream.
+type StreamHandler func(chunk string) error
+
 type ILLM interface {
 	Configure(config LLMConfig) error
 	GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error)
+	// GetCompletionStream calls the handler with the chunks of the completion as they are generated,
+	// the whole completion is returned when the stream is finished.
+	GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error)
 	Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error)
 	GetName() string
+	// GetUsage returns the tokens used by all the requests of the client
+	GetUsage() Usage
+}
+
+// Register adds a provider or replaces the provider with the same name.
+func Register(provider string, factory ClientFactory) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+	providers[provider] = factory
+}
+
+func IsProviderSupported(provider string) bool {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	_, ok := providers[provider]
+	return ok
+}
+
+func ListProviders() []string {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	resp := make([]string, 0, len(providers))
+	for provider := range providers {
+		resp = append(resp, provider)
+	}
+	sort.Strings(resp)
+	return resp
 }
 
 func NewClient(provider string) (ILLM, error) {
-	if c, ok := clients[provider]; !ok {
+	providersMutex.RLock()
+	factory, ok := providers[provider]
+	providersMutex.RUnlock()
+	if !ok {
 		return nil, fmt.Errorf("provider %s not supported", provider)
-	} else {
-		return c, nil
 	}
+	return factory(), nil
 }
 
 type LLMConfig struct {
@@ -86,3 +139,38 @@ func GetCacheKey(provider string, sEnc string) string {
 
 	return hex.EncodeToString(hash[:])
 }
+
+// parseWithCache gets the completion of the prompt from the cache first, the completion is cached after it is generated.
+func parseWithCache(ctx context.Context, client ILLM, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	cacheKey := GetCacheKey(client.GetName(), prompt)
+
+	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
+		response, err := cache.Load(cacheKey)
+		if err != nil {
+			return "", err
+		}
+
+		if response != "" {
+			output, err := base64.StdEncoding.DecodeString(response)
+			if err != nil {
+				log.Errorf("error decoding cached data: %v", err)
+				return "", nil
+			}
+			return string(output), nil
+		}
+	}
+
+	response, err := client.GetCompletion(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+
+	err = cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response)))
+
+	if err != nil {
+		log.Errorf("error storing value to cache: %v", err)
+		return "", nil
+	}
+
+	return response, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	for _, provider := range []string{ProviderOpenAI, ProviderAzureOpenAI, ProviderOllama, ProviderTGI} {
+		assert.True(t, IsProviderSupported(provider), provider)
+	}
+	_, err := NewClient("unknown")
+	assert.Error(t, err)
+
+	Register(ProviderFake, func() ILLM { return NewFakeClient("fine") })
+	a, err := NewClient(ProviderFake)
+	require.NoError(t, err)
+	b, err := NewClient(ProviderFake)
+	require.NoError(t, err)
+	assert.NotSame(t, a, b)
+	assert.Contains(t, ListProviders(), ProviderFake)
+}
+
+func TestFakeClient(t *testing.T) {
+	client := NewFakeClient("first answer", "second answer")
+	answer, err := client.GetCompletion(context.Background(), "what happened")
+	require.NoError(t, err)
+	assert.Equal(t, "first answer", answer)
+
+	chunks := []string{}
+	answer, err = client.GetCompletionStream(context.Background(), "and then", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "second answer", answer)
+	assert.Equal(t, []string{"second ", "answer"}, chunks)
+	assert.Equal(t, Usage{Requests: 2, PromptTokens: 4, CompletionTokens: 4, TotalTokens: 8}, client.GetUsage())
+
+	_, err = NewFakeClient().WithError(fmt.Errorf("down")).GetCompletion(context.Background(), "hi")
+	assert.Error(t, err)
+}
+
+func TestOllamaClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		req := &ollamaChatRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		assert.Equal(t, "qwen", req.Model)
+		if !req.Stream {
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"build failed"},"done":true,"prompt_eval_count":10,"eval_count":3}`)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"build "},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"failed"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":10,"eval_count":2}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ProviderOllama)
+	require.NoError(t, err)
+	require.NoError(t, client.Configure(LLMConfig{BaseURL: server.URL, Model: "qwen"}))
+
+	answer, err := client.GetCompletion(context.Background(), "analyze the log")
+	require.NoError(t, err)
+	assert.Equal(t, "build failed", answer)
+
+	chunks := []string{}
+	answer, err = client.GetCompletionStream(context.Background(), "analyze the log", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "build failed", answer)
+	assert.Equal(t, []string{"build ", "failed"}, chunks)
+	assert.Equal(t, Usage{Requests: 2, PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}, client.GetUsage())
+}
+
+func TestTGIClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/generate":
+			fmt.Fprint(w, `{"generated_text":"out of memory","details":{"generated_tokens":3,"prefill":[{"text":"a"},{"text":"b"}]}}`)
+		case "/generate_stream":
+			fmt.Fprint(w, "data:{\"token\":{\"text\":\"out \",\"special\":false}}\n\n")
+			fmt.Fprint(w, "data:{\"token\":{\"text\":\"of memory\",\"special\":false}}\n\n")
+			fmt.Fprint(w, "data:{\"token\":{\"text\":\"</s>\",\"special\":true},\"details\":{\"generated_tokens\":3}}\n\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &TGIClient{}
+	require.Error(t, client.Configure(LLMConfig{}))
+	require.NoError(t, client.Configure(LLMConfig{BaseURL: server.URL, Token: "secret"}))
+
+	answer, err := client.GetCompletion(context.Background(), "analyze the log")
+	require.NoError(t, err)
+	assert.Equal(t, "out of memory", answer)
+
+	answer, err = client.GetCompletionStream(context.Background(), "analyze the log", func(string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "out of memory", answer)
+	assert.Equal(t, Usage{Requests: 2, PromptTokens: 2, CompletionTokens: 6, TotalTokens: 8}, client.GetUsage())
+}
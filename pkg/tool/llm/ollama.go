@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/koderover/zadig/v2/pkg/tool/cache"
+)
+
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "llama3"
+)
+
+// OllamaClient talks to an Ollama server, or any local server with the same chat API, see https://github.com/ollama/ollama/blob/main/docs/api.md
+type OllamaClient struct {
+	usageCounter
+	name       string
+	model      string
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float32  `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	Error           string        `json:"error"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+func (c *OllamaClient) Configure(config LLMConfig) error {
+	httpClient, err := newHTTPClient(config.GetProxy())
+	if err != nil {
+		return err
+	}
+	c.httpClient = httpClient
+	c.name = config.GetName()
+	c.model = config.GetModel()
+	c.token = config.GetToken()
+	c.baseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+	if c.baseURL == "" {
+		c.baseURL = DefaultOllamaBaseURL
+	}
+	return nil
+}
+
+func (c *OllamaClient) chatRequest(prompt string, stream bool, options ...ParamOption) *ollamaChatRequest {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
+
+	model := opts.Model
+	if model == "" {
+		model = c.model
+	}
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+	return &ollamaChatRequest{
+		Model:    model,
+		Messages: []ollamaMessage{{Role: "user", Content: prompt}},
+		Stream:   stream,
+		Options: &ollamaOptions{
+			Temperature: opts.Temperature,
+			NumPredict:  opts.MaxTokens,
+			Stop:        opts.StopWords,
+		},
+	}
+}
+
+func (c *OllamaClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.httpClient, c.baseURL+"/api/chat", c.token, c.chatRequest(prompt, false, options...))
+	if err != nil {
+		return "", fmt.Errorf("create ollama chat completion failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := &ollamaChatResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", fmt.Errorf("decode ollama chat completion failed: %v", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("create ollama chat completion failed: %s", result.Error)
+	}
+	c.addUsage(result.PromptEvalCount, result.EvalCount)
+	return result.Message.Content, nil
+}
+
+// GetCompletionStream reads the stream of ollama, which is a json object per line
+func (c *OllamaClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.httpClient, c.baseURL+"/api/chat", c.token, c.chatRequest(prompt, true, options...))
+	if err != nil {
+		return "", fmt.Errorf("create ollama chat completion stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var completion strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		chunk := &ollamaChatResponse{}
+		if err := json.Unmarshal([]byte(line), chunk); err != nil {
+			return completion.String(), fmt.Errorf("decode ollama chat completion stream failed: %v", err)
+		}
+		if chunk.Error != "" {
+			return completion.String(), fmt.Errorf("receive ollama chat completion stream failed: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			completion.WriteString(chunk.Message.Content)
+			if err := handler(chunk.Message.Content); err != nil {
+				return completion.String(), err
+			}
+		}
+		if chunk.Done {
+			c.addUsage(chunk.PromptEvalCount, chunk.EvalCount)
+			return completion.String(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return completion.String(), fmt.Errorf("receive ollama chat completion stream failed: %v", err)
+	}
+	return completion.String(), nil
+}
+
+func (c *OllamaClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *OllamaClient) GetName() string {
+	if c.name == "" {
+		return ProviderOllama
+	}
+	return c.name
+}
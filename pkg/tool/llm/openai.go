@@ -17,11 +17,10 @@ package llm
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
+	"io"
+	"strings"
 
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/sashabaranov/go-openai"
@@ -36,6 +35,7 @@ const (
 )
 
 type OpenAIClient struct {
+	usageCounter
 	name    string
 	model   string
 	client  *openai.Client
@@ -58,19 +58,18 @@ func (c *OpenAIClient) Configure(config LLMConfig) error {
 		// config.GetAPIType() == "OPEN_AI"
 		c.apiType = "OPEN_AI"
 		defaultConfig = openai.DefaultConfig(token)
+		// self-hosted servers with OpenAI compatible APIs can be used by the base url
+		if config.GetBaseURL() != "" {
+			defaultConfig.BaseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+		}
 	}
 
 	if config.GetProxy() != "" {
-		proxyUrl, err := url.Parse(config.GetProxy())
+		httpClient, err := newHTTPClient(config.GetProxy())
 		if err != nil {
-			return fmt.Errorf("invalid proxy url %s", config.GetProxy())
-		}
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyUrl),
-		}
-		defaultConfig.HTTPClient = &http.Client{
-			Transport: transport,
+			return err
 		}
+		defaultConfig.HTTPClient = httpClient
 	}
 
 	client := openai.NewClientWithConfig(defaultConfig)
@@ -84,8 +83,7 @@ func (c *OpenAIClient) Configure(config LLMConfig) error {
 	return nil
 }
 
-// @todo add ability to supply multiple messages
-func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+func (c *OpenAIClient) chatCompletionRequest(prompt string, options ...ParamOption) openai.ChatCompletionRequest {
 	opts := ParamOptions{}
 	for _, opt := range options {
 		opt(&opts)
@@ -101,74 +99,72 @@ func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options
 		}
 	}
 
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    "user",
-			Content: prompt,
+	return openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
 		},
+		// MaxTokens is omitted from the request if it is 0
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		Stop:        opts.StopWords,
+		LogitBias:   opts.LogitBias,
 	}
+}
 
-	var resp openai.ChatCompletionResponse
-	var err error
-	if opts.MaxTokens == 0 {
-		resp, err = c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:       model,
-			Messages:    messages,
-			Temperature: opts.Temperature,
-			Stop:        opts.StopWords,
-			LogitBias:   opts.LogitBias,
-		})
-	} else {
-		resp, err = c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:       model,
-			Messages:    messages,
-			MaxTokens:   opts.MaxTokens,
-			Temperature: opts.Temperature,
-			Stop:        opts.StopWords,
-			LogitBias:   opts.LogitBias,
-		})
-	}
-
+// @todo add ability to supply multiple messages
+func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	resp, err := c.client.CreateChatCompletion(ctx, c.chatCompletionRequest(prompt, options...))
 	if err != nil {
 		return "", fmt.Errorf("create chat completion failed: %v", err)
 	}
+	c.addUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return "", errors.New("create chat completion failed: no choices returned")
+	}
 
 	return resp.Choices[0].Message.Content, nil
 }
 
-func (a *OpenAIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
-	// Check for cached data
-	cacheKey := GetCacheKey(a.GetName(), prompt)
+func (c *OpenAIClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	req := c.chatCompletionRequest(prompt, options...)
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("create chat completion stream failed: %v", err)
+	}
+	defer stream.Close()
 
-	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
-		response, err := cache.Load(cacheKey)
+	var completion strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
-			return "", err
+			return completion.String(), fmt.Errorf("receive chat completion stream failed: %v", err)
 		}
-
-		if response != "" {
-			output, err := base64.StdEncoding.DecodeString(response)
-			if err != nil {
-				log.Errorf("error decoding cached data: %v", err)
-				return "", nil
-			}
-			return string(output), nil
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		chunk := resp.Choices[0].Delta.Content
+		completion.WriteString(chunk)
+		if err := handler(chunk); err != nil {
+			return completion.String(), err
 		}
 	}
 
-	response, err := a.GetCompletion(ctx, prompt, options...)
-	if err != nil {
-		return "", err
-	}
-
-	err = cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response)))
-
-	if err != nil {
-		log.Errorf("error storing value to cache: %v", err)
-		return "", nil
-	}
+	// the stream response does not contain the usage, so the tokens are counted locally
+	promptTokens, _ := NumTokensFromPrompt(prompt, req.Model)
+	completionTokens, _ := NumTokensFromPrompt(completion.String(), req.Model)
+	c.addUsage(promptTokens, completionTokens)
+	return completion.String(), nil
+}
 
-	return response, nil
+func (a *OpenAIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, a, prompt, cache, options...)
 }
 
 func (a *OpenAIClient) GetName() string {
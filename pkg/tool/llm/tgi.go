@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/koderover/zadig/v2/pkg/tool/cache"
+)
+
+// TGIClient talks to a Hugging Face text-generation-inference server, which serves a single model and
+// has its own API instead of the OpenAI one, see https://huggingface.github.io/text-generation-inference/
+type TGIClient struct {
+	usageCounter
+	name       string
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+type tgiParameters struct {
+	MaxNewTokens int      `json:"max_new_tokens,omitempty"`
+	Temperature  float32  `json:"temperature,omitempty"`
+	Stop         []string `json:"stop,omitempty"`
+	Details      bool     `json:"details"`
+	// DecoderInputDetails returns the prompt tokens in the prefill details, it is not supported when streaming
+	DecoderInputDetails bool `json:"decoder_input_details"`
+}
+
+type tgiGenerateRequest struct {
+	Inputs     string         `json:"inputs"`
+	Parameters *tgiParameters `json:"parameters"`
+}
+
+type tgiDetails struct {
+	GeneratedTokens int `json:"generated_tokens"`
+	Prefill         []struct {
+		Text string `json:"text"`
+	} `json:"prefill"`
+}
+
+type tgiGenerateResponse struct {
+	GeneratedText string      `json:"generated_text"`
+	Details       *tgiDetails `json:"details"`
+	Error         string      `json:"error"`
+}
+
+type tgiStreamResponse struct {
+	Token struct {
+		Text    string `json:"text"`
+		Special bool   `json:"special"`
+	} `json:"token"`
+	Details *tgiDetails `json:"details"`
+	Error   string      `json:"error"`
+}
+
+func (c *TGIClient) Configure(config LLMConfig) error {
+	if config.GetBaseURL() == "" {
+		return errors.New("base url of the text-generation-inference server is required")
+	}
+	httpClient, err := newHTTPClient(config.GetProxy())
+	if err != nil {
+		return err
+	}
+	c.httpClient = httpClient
+	c.name = config.GetName()
+	c.token = config.GetToken()
+	c.baseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+	return nil
+}
+
+// generateRequest builds the request, the model option is ignored since the server only serves one model
+func (c *TGIClient) generateRequest(prompt string, stream bool, options ...ParamOption) *tgiGenerateRequest {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
+
+	return &tgiGenerateRequest{
+		Inputs: prompt,
+		Parameters: &tgiParameters{
+			MaxNewTokens:        opts.MaxTokens,
+			Temperature:         opts.Temperature,
+			Stop:                opts.StopWords,
+			Details:             true,
+			DecoderInputDetails: !stream,
+		},
+	}
+}
+
+func (c *TGIClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.httpClient, c.baseURL+"/generate", c.token, c.generateRequest(prompt, false, options...))
+	if err != nil {
+		return "", fmt.Errorf("create tgi completion failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := &tgiGenerateResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", fmt.Errorf("decode tgi completion failed: %v", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("create tgi completion failed: %s", result.Error)
+	}
+	c.addTGIUsage(result.Details)
+	return result.GeneratedText, nil
+}
+
+// GetCompletionStream reads the server-sent events of the stream, every event contains a token
+func (c *TGIClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.httpClient, c.baseURL+"/generate_stream", c.token, c.generateRequest(prompt, true, options...))
+	if err != nil {
+		return "", fmt.Errorf("create tgi completion stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var completion strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		event := &tgiStreamResponse{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), event); err != nil {
+			return completion.String(), fmt.Errorf("decode tgi completion stream failed: %v", err)
+		}
+		if event.Error != "" {
+			return completion.String(), fmt.Errorf("receive tgi completion stream failed: %s", event.Error)
+		}
+		if !event.Token.Special && event.Token.Text != "" {
+			completion.WriteString(event.Token.Text)
+			if err := handler(event.Token.Text); err != nil {
+				return completion.String(), err
+			}
+		}
+		// only the last event contains the details
+		if event.Details != nil {
+			c.addTGIUsage(event.Details)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return completion.String(), fmt.Errorf("receive tgi completion stream failed: %v", err)
+	}
+	return completion.String(), nil
+}
+
+func (c *TGIClient) addTGIUsage(details *tgiDetails) {
+	if details == nil {
+		c.addUsage(0, 0)
+		return
+	}
+	c.addUsage(len(details.Prefill), details.GeneratedTokens)
+}
+
+func (c *TGIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *TGIClient) GetName() string {
+	if c.name == "" {
+		return ProviderTGI
+	}
+	return c.name
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import "sync"
+
+// Usage is the token accounting of a client
+type Usage struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// usageCounter is embedded by the clients to count the tokens of their requests
+type usageCounter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (c *usageCounter) addUsage(promptTokens, completionTokens int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.Requests++
+	c.usage.PromptTokens += int64(promptTokens)
+	c.usage.CompletionTokens += int64(completionTokens)
+	c.usage.TotalTokens += int64(promptTokens + completionTokens)
+}
+
+func (c *usageCounter) GetUsage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
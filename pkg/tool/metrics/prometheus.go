@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,6 +14,7 @@ import (
 	"github.com/koderover/zadig/v2/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/v2/pkg/setting"
 	"github.com/koderover/zadig/v2/pkg/shared/kube/client"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
 )
 
 var (
@@ -86,6 +88,98 @@ var (
 		},
 		[]string{"method", "handler", "status"},
 	)
+
+	WorkflowQueueWaitTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workflow_queue_wait_time",
+			Help:    "Time in seconds a workflow task waits in the queue before it starts running",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+		[]string{"project", "workflow"},
+	)
+
+	WorkflowDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workflow_duration",
+			Help:    "Duration in seconds of finished workflow tasks",
+			Buckets: prometheus.ExponentialBuckets(10, 2, 12),
+		},
+		[]string{"project", "workflow", "status"},
+	)
+
+	JobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workflow_job_duration",
+			Help:    "Duration in seconds of finished workflow jobs",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 12),
+		},
+		[]string{"project", "workflow", "job_type", "status"},
+	)
+
+	JobFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workflow_job_failures_total",
+			Help: "Number of workflow jobs that ended in failed, timeout or cancelled status",
+		},
+		[]string{"project", "workflow", "job_type", "status"},
+	)
+
+	ApprovalWaitTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workflow_approval_wait_time",
+			Help:    "Time in seconds a workflow stage waits for approval",
+			Buckets: prometheus.ExponentialBuckets(30, 2, 12),
+		},
+		[]string{"project", "workflow", "status"},
+	)
+
+	DindPods = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dind_pods",
+			Help: "Number of running dind pods",
+		},
+		[]string{"cluster"},
+	)
+
+	DindCPU = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dind_cpu",
+			Help: "CPU usage of dind pods",
+		},
+		[]string{"cluster"},
+	)
+
+	DindMemory = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dind_memory",
+			Help: "Memory usage of dind pods",
+		},
+		[]string{"cluster"},
+	)
+
+	ExecutorPods = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "executor_pods",
+			Help: "Number of running workflow job executor pods",
+		},
+		[]string{"cluster", "job_type"},
+	)
+
+	ExecutorCPU = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "executor_cpu",
+			Help: "CPU usage of workflow job executor pods",
+		},
+		[]string{"cluster"},
+	)
+
+	ExecutorMemory = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "executor_memory",
+			Help: "Memory usage of workflow job executor pods",
+		},
+		[]string{"cluster"},
+	)
 )
 
 func SetRunningWorkflows(value int64) {
@@ -149,3 +243,181 @@ func updateResourceMetrics(serviceName string, metrics v1beta1.PodMetrics) {
 		}
 	}
 }
+
+func ObserveWorkflowQueueWaitTime(projectName, workflowName string, seconds int64) {
+	WorkflowQueueWaitTime.WithLabelValues(projectName, workflowName).Observe(float64(seconds))
+}
+
+func ObserveWorkflowDuration(projectName, workflowName, status string, seconds int64) {
+	WorkflowDuration.WithLabelValues(projectName, workflowName, status).Observe(float64(seconds))
+}
+
+func ObserveJobDuration(projectName, workflowName, jobType, status string, seconds int64) {
+	JobDuration.WithLabelValues(projectName, workflowName, jobType, status).Observe(float64(seconds))
+}
+
+func RegisterJobFailure(projectName, workflowName, jobType, status string) {
+	JobFailures.WithLabelValues(projectName, workflowName, jobType, status).Inc()
+}
+
+func ObserveApprovalWaitTime(projectName, workflowName, status string, seconds int64) {
+	ApprovalWaitTime.WithLabelValues(projectName, workflowName, status).Observe(float64(seconds))
+}
+
+// clusterMetricsTimeout bounds the time spent on collecting the metrics of a single cluster
+const clusterMetricsTimeout = 10 * time.Second
+
+// listClusterPodMetrics lists the metrics of the pods matching the selector in the cluster namespace
+var listClusterPodMetrics = func(ctx context.Context, clusterID, namespace, selector string) ([]v1beta1.PodMetrics, error) {
+	metricsClient, err := client.GetKubeMetricsClient(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	podMetrics, err := metricsClient.PodMetricses(namespace).List(ctx, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return podMetrics.Items, nil
+}
+
+type clusterUsage struct {
+	dindPods       int
+	dindCPU        int64
+	dindMemory     int64
+	executorPods   map[string]int
+	executorCPU    int64
+	executorMemory int64
+}
+
+var (
+	clusterMetricsMutex sync.Mutex
+	// collectedExecutorJobTypes records the executor job types exported for each cluster in the last collection
+	collectedExecutorJobTypes = make(map[string]map[string]struct{})
+)
+
+// StartClusterMetricsCollector refreshes the dind and job executor usage of the clusters returned by clusterIDs
+// every interval until ctx is done, so that scraping the metrics never waits for the clusters.
+func StartClusterMetricsCollector(ctx context.Context, interval time.Duration, clusterIDs func() []string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		CollectClusterMetrics(ctx, clusterIDs())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectClusterMetrics collects the usage of the given clusters concurrently and replaces the exported cluster metrics.
+// Clusters that fail to report within clusterMetricsTimeout are dropped from the metrics.
+func CollectClusterMetrics(ctx context.Context, clusterIDs []string) {
+	usages := make(map[string]*clusterUsage)
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, clusterID := range clusterIDs {
+		wg.Add(1)
+		go func(clusterID string) {
+			defer wg.Done()
+
+			clusterCtx, cancel := context.WithTimeout(ctx, clusterMetricsTimeout)
+			defer cancel()
+
+			usage, err := collectClusterUsage(clusterCtx, clusterID)
+			if err != nil {
+				log.Warnf("failed to collect metrics of cluster %s, err: %v", clusterID, err)
+				return
+			}
+			mu.Lock()
+			usages[clusterID] = usage
+			mu.Unlock()
+		}(clusterID)
+	}
+	wg.Wait()
+
+	setClusterMetrics(usages)
+}
+
+func collectClusterUsage(ctx context.Context, clusterID string) (*clusterUsage, error) {
+	namespace := setting.AttachedClusterNamespace
+	if clusterID == setting.LocalClusterID {
+		namespace = config.Namespace()
+	}
+
+	dindMetrics, err := listClusterPodMetrics(ctx, clusterID, namespace, "app.kubernetes.io/component=dind")
+	if err != nil {
+		return nil, err
+	}
+	executorMetrics, err := listClusterPodMetrics(ctx, clusterID, namespace, setting.JobLabelSTypeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &clusterUsage{
+		dindPods:     len(dindMetrics),
+		executorPods: make(map[string]int),
+	}
+	usage.dindCPU, usage.dindMemory = sumPodUsage(dindMetrics)
+	for _, podMetric := range executorMetrics {
+		usage.executorPods[podMetric.Labels[setting.JobLabelSTypeKey]]++
+	}
+	usage.executorCPU, usage.executorMemory = sumPodUsage(executorMetrics)
+	return usage, nil
+}
+
+// setClusterMetrics overwrites the cluster metrics in place and deletes the series that are no longer reported,
+// instead of resetting the vectors, so a concurrent scrape never sees them empty.
+func setClusterMetrics(usages map[string]*clusterUsage) {
+	clusterMetricsMutex.Lock()
+	defer clusterMetricsMutex.Unlock()
+
+	for clusterID, jobTypes := range collectedExecutorJobTypes {
+		usage, ok := usages[clusterID]
+		if !ok {
+			DindPods.DeleteLabelValues(clusterID)
+			DindCPU.DeleteLabelValues(clusterID)
+			DindMemory.DeleteLabelValues(clusterID)
+			ExecutorCPU.DeleteLabelValues(clusterID)
+			ExecutorMemory.DeleteLabelValues(clusterID)
+		}
+		for jobType := range jobTypes {
+			if ok {
+				if _, stillRunning := usage.executorPods[jobType]; stillRunning {
+					continue
+				}
+			}
+			ExecutorPods.DeleteLabelValues(clusterID, jobType)
+		}
+	}
+
+	collected := make(map[string]map[string]struct{})
+	for clusterID, usage := range usages {
+		DindPods.WithLabelValues(clusterID).Set(float64(usage.dindPods))
+		DindCPU.WithLabelValues(clusterID).Set(float64(usage.dindCPU) / 1000)
+		DindMemory.WithLabelValues(clusterID).Set(float64(usage.dindMemory) / 1024 / 1024)
+		ExecutorCPU.WithLabelValues(clusterID).Set(float64(usage.executorCPU) / 1000)
+		ExecutorMemory.WithLabelValues(clusterID).Set(float64(usage.executorMemory) / 1024 / 1024)
+
+		jobTypes := make(map[string]struct{})
+		for jobType, count := range usage.executorPods {
+			ExecutorPods.WithLabelValues(clusterID, jobType).Set(float64(count))
+			jobTypes[jobType] = struct{}{}
+		}
+		collected[clusterID] = jobTypes
+	}
+	collectedExecutorJobTypes = collected
+}
+
+func sumPodUsage(podMetrics []v1beta1.PodMetrics) (cpu, memory int64) {
+	for _, podMetric := range podMetrics {
+		for _, container := range podMetric.Containers {
+			cpu += container.Usage.Cpu().MilliValue()
+			memory += container.Usage.Memory().Value()
+		}
+	}
+	return
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"github.com/koderover/zadig/v2/pkg/setting"
+	"github.com/koderover/zadig/v2/pkg/tool/log"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&log.Config{Level: "info"})
+	os.Exit(m.Run())
+}
+
+func newPodMetrics(jobType, cpu, memory string) v1beta1.PodMetrics {
+	return v1beta1.PodMetrics{
+		ObjectMeta: v1.ObjectMeta{Labels: map[string]string{setting.JobLabelSTypeKey: jobType}},
+		Containers: []v1beta1.ContainerMetrics{{
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}},
+	}
+}
+
+func fakeClusterPodMetrics(t *testing.T, clusters map[string][]v1beta1.PodMetrics, slowClusters map[string]bool) {
+	origin := listClusterPodMetrics
+	t.Cleanup(func() { listClusterPodMetrics = origin })
+
+	listClusterPodMetrics = func(ctx context.Context, clusterID, namespace, selector string) ([]v1beta1.PodMetrics, error) {
+		if slowClusters[clusterID] {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		pods, ok := clusters[clusterID]
+		if !ok {
+			return nil, fmt.Errorf("cluster %s not found", clusterID)
+		}
+		if selector != setting.JobLabelSTypeKey {
+			return nil, nil
+		}
+		return pods, nil
+	}
+}
+
+func TestCollectClusterMetrics(t *testing.T) {
+	fakeClusterPodMetrics(t, map[string][]v1beta1.PodMetrics{
+		"a": {
+			newPodMetrics("build", "500m", "1Mi"),
+			newPodMetrics("build", "500m", "1Mi"),
+			newPodMetrics("test", "1", "2Mi"),
+		},
+		"b": {newPodMetrics("deploy", "250m", "1Mi")},
+	}, nil)
+
+	CollectClusterMetrics(context.Background(), []string{"a", "b"})
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(ExecutorPods.WithLabelValues("a", "build")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ExecutorPods.WithLabelValues("a", "test")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(ExecutorCPU.WithLabelValues("a")))
+	assert.Equal(t, float64(4), testutil.ToFloat64(ExecutorMemory.WithLabelValues("a")))
+	assert.Equal(t, float64(0.25), testutil.ToFloat64(ExecutorCPU.WithLabelValues("b")))
+	assert.Equal(t, 2, testutil.CollectAndCount(ExecutorCPU))
+
+	// the executors of cluster a are finished and cluster b is no longer reachable
+	fakeClusterPodMetrics(t, map[string][]v1beta1.PodMetrics{
+		"a": {newPodMetrics("test", "1", "2Mi")},
+	}, nil)
+
+	CollectClusterMetrics(context.Background(), []string{"a", "b"})
+
+	assert.Equal(t, 1, testutil.CollectAndCount(ExecutorPods))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ExecutorPods.WithLabelValues("a", "test")))
+	assert.Equal(t, 1, testutil.CollectAndCount(ExecutorCPU))
+	assert.Equal(t, 1, testutil.CollectAndCount(DindPods))
+
+	CollectClusterMetrics(context.Background(), nil)
+	assert.Equal(t, 0, testutil.CollectAndCount(ExecutorPods))
+	assert.Equal(t, 0, testutil.CollectAndCount(DindPods))
+}
+
+func TestCollectClusterMetricsTimeout(t *testing.T) {
+	fakeClusterPodMetrics(t, map[string][]v1beta1.PodMetrics{
+		"a": {newPodMetrics("build", "1", "1Mi")},
+	}, map[string]bool{"slow": true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	CollectClusterMetrics(ctx, []string{"a", "slow"})
+
+	assert.Less(t, time.Since(start), clusterMetricsTimeout)
+	assert.Equal(t, float64(1), testutil.ToFloat64(ExecutorPods.WithLabelValues("a", "build")))
+	assert.Equal(t, 1, testutil.CollectAndCount(ExecutorCPU))
+
+	CollectClusterMetrics(context.Background(), nil)
+}
@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"io/fs"
 	"mime"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -298,3 +300,53 @@ func (c *Client) ListFiles(bucketName, prefix string, recursive bool) ([]string,
 
 	return ret, nil
 }
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// StatObject returns the info of the object, nil is returned if the object does not exist
+func (c *Client) StatObject(bucketName, objectKey string) (*ObjectInfo, error) {
+	output, err := c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		// HeadObject has no body, so the error code is the http status text
+		if e, ok := err.(awserr.RequestFailure); ok && e.StatusCode() == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         aws.Int64Value(output.ContentLength),
+		LastModified: aws.TimeValue(output.LastModified),
+	}, nil
+}
+
+// ListObjectInfos lists the info of all the objects with given prefix recursively
+func (c *Client) ListObjectInfos(bucketName, prefix string) ([]*ObjectInfo, error) {
+	ret := make([]*ObjectInfo, 0)
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			ret = append(ret, &ObjectInfo{
+				Key:          aws.StringValue(item.Key),
+				Size:         aws.Int64Value(item.Size),
+				LastModified: aws.TimeValue(item.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("bucket [%s] listing objects with prefix [%v] failed, error: %v", bucketName, prefix, err)
+		return nil, err
+	}
+	return ret, nil
+}
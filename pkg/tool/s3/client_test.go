@@ -17,7 +17,15 @@ limitations under the License.
 package s3
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDetectMimetype(t *testing.T) {
@@ -41,3 +49,104 @@ func TestDetectMimetype(t *testing.T) {
 		}
 	}
 }
+
+// newFakeS3 serves the HEAD object and the paged list objects requests of the bucket "bucket"
+func newFakeS3(t *testing.T) *Client {
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	objects := []struct {
+		key  string
+		size int64
+	}{
+		// sorted by key like s3 does
+		{"cache/go-mod~3.tar.gz", 3},
+		{"cache/go~1.tar.gz", 1},
+		{"cache/go~2.tar.gz", 2},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/bucket/cache/go~1.tar.gz":
+			w.Header().Set("Content-Length", "1")
+			w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && r.URL.Path == "/bucket/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket":
+			prefix, marker := r.URL.Query().Get("prefix"), r.URL.Query().Get("marker")
+			contents := ""
+			matched := 0
+			truncated, nextMarker := false, ""
+			for _, object := range objects {
+				if !strings.HasPrefix(object.key, prefix) || object.key <= marker {
+					continue
+				}
+				// one object per page to test the paging
+				if matched == 1 {
+					truncated = true
+					break
+				}
+				matched++
+				nextMarker = object.key
+				contents += fmt.Sprintf("<Contents><Key>%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>",
+					object.key, object.size, lastModified.Format(time.RFC3339))
+			}
+			if !truncated {
+				nextMarker = ""
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>bucket</Name><Prefix>%s</Prefix><IsTruncated>%t</IsTruncated><NextMarker>%s</NextMarker>%s</ListBucketResult>`,
+				prefix, truncated, nextMarker, contents)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL, "ak", "sk", "", true, true)
+	require.NoError(t, err)
+	return client
+}
+
+func TestStatObject(t *testing.T) {
+	client := newFakeS3(t)
+
+	info, err := client.StatObject("bucket", "cache/go~1.tar.gz")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, "cache/go~1.tar.gz", info.Key)
+	assert.Equal(t, int64(1), info.Size)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), info.LastModified.UTC())
+
+	info, err = client.StatObject("bucket", "cache/missing.tar.gz")
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+
+	_, err = client.StatObject("bucket", "forbidden")
+	assert.Error(t, err)
+}
+
+func TestListObjectInfos(t *testing.T) {
+	client := newFakeS3(t)
+
+	tests := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"cache/go~", []string{"cache/go~1.tar.gz", "cache/go~2.tar.gz"}},
+		{"cache/go", []string{"cache/go~1.tar.gz", "cache/go~2.tar.gz", "cache/go-mod~3.tar.gz"}},
+		{"cache/node~", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			infos, err := client.ListObjectInfos("bucket", tt.prefix)
+			require.NoError(t, err)
+			keys := make([]string, 0, len(infos))
+			for _, info := range infos {
+				keys = append(keys, info.Key)
+			}
+			assert.ElementsMatch(t, tt.keys, keys)
+		})
+	}
+}
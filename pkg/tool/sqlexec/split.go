@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlexec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// mysqlDelimiterRegex matches the DELIMITER command of the mysql client, which is used to create procedures and triggers
+	mysqlDelimiterRegex = regexp.MustCompile(`(?i)^\s*DELIMITER\s+(\S+)\s*$`)
+	// sqlServerBatchRegex matches the GO batch separator of the sql server tools
+	sqlServerBatchRegex = regexp.MustCompile(`(?i)^\s*GO\s*(--.*)?$`)
+	// postgresDollarTagRegex matches the tags of dollar-quoted strings like $$ or $body$
+	postgresDollarTagRegex = regexp.MustCompile(`^\$[A-Za-z_][A-Za-z0-9_]*\$|^\$\$`)
+)
+
+// SplitStatements splits a script into statements by the rules of the dialect:
+// mysql statements end with the delimiter which is ; by default and can be changed by the DELIMITER command,
+// postgresql statements end with ; outside of quoted strings, dollar-quoted strings and comments,
+// sql server scripts are split into batches by the GO lines and every batch is executed as a whole.
+func SplitStatements(dialect Dialect, script string) ([]string, error) {
+	s := &splitter{dialect: dialect, script: script, delimiter: ";"}
+	switch dialect {
+	case DialectMySQL, DialectPostgreSQL, DialectSQLServer:
+	default:
+		return nil, fmt.Errorf("unsupported sql dialect: %s", dialect)
+	}
+	return s.split()
+}
+
+type splitter struct {
+	dialect    Dialect
+	script     string
+	delimiter  string
+	statements []string
+	current    strings.Builder
+	// hasCode is false if the current statement only contains spaces and comments
+	hasCode bool
+}
+
+func (s *splitter) flush() {
+	if s.hasCode {
+		s.statements = append(s.statements, strings.TrimSpace(s.current.String()))
+	}
+	s.current.Reset()
+	s.hasCode = false
+}
+
+func (s *splitter) split() ([]string, error) {
+	script := s.script
+	lineStart := true
+	for i := 0; i < len(script); {
+		if lineStart {
+			lineStart = false
+			line := script[i:]
+			if end := strings.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end]
+			}
+			if s.dialect == DialectMySQL {
+				if match := mysqlDelimiterRegex.FindStringSubmatch(line); match != nil {
+					s.flush()
+					s.delimiter = match[1]
+					i += len(line)
+					continue
+				}
+			}
+			if s.dialect == DialectSQLServer && sqlServerBatchRegex.MatchString(line) {
+				s.flush()
+				i += len(line)
+				continue
+			}
+		}
+
+		c := script[i]
+		switch {
+		case c == '\n':
+			s.current.WriteByte(c)
+			lineStart = true
+			i++
+		case strings.HasPrefix(script[i:], "--") || (c == '#' && s.dialect == DialectMySQL):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			s.current.WriteString(script[i : i+end])
+			i += end
+		case strings.HasPrefix(script[i:], "/*"):
+			end, err := s.blockCommentEnd(i)
+			if err != nil {
+				return nil, err
+			}
+			s.current.WriteString(script[i:end])
+			i = end
+		case c == '\'' || c == '"' || (c == '`' && s.dialect == DialectMySQL) || (c == '[' && s.dialect == DialectSQLServer):
+			end, err := s.quoteEnd(i)
+			if err != nil {
+				return nil, err
+			}
+			s.write(script[i:end])
+			i = end
+		case c == '$' && s.dialect == DialectPostgreSQL && !isIdentifierByte(script, i-1):
+			tag := postgresDollarTagRegex.FindString(script[i:])
+			if tag == "" {
+				s.write(string(c))
+				i++
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string %s", tag)
+			}
+			end = i + len(tag) + end + len(tag)
+			s.write(script[i:end])
+			i = end
+		case s.dialect != DialectSQLServer && strings.HasPrefix(script[i:], s.delimiter):
+			s.flush()
+			i += len(s.delimiter)
+		default:
+			if c != ' ' && c != '\t' && c != '\r' {
+				s.hasCode = true
+			}
+			s.current.WriteByte(c)
+			i++
+		}
+	}
+	s.flush()
+	return s.statements, nil
+}
+
+func (s *splitter) write(code string) {
+	s.hasCode = true
+	s.current.WriteString(code)
+}
+
+// blockCommentEnd returns the index after the end of the comment starting at i, comments can be nested in postgresql
+func (s *splitter) blockCommentEnd(i int) (int, error) {
+	depth := 0
+	for j := i; j < len(s.script)-1; j++ {
+		switch {
+		case s.script[j] == '/' && s.script[j+1] == '*' && (depth == 0 || s.dialect == DialectPostgreSQL):
+			depth++
+			j++
+		case s.script[j] == '*' && s.script[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment")
+}
+
+// quoteEnd returns the index after the end of the quoted string or identifier starting at i,
+// the quote is escaped by doubling it, a backslash escapes the next character in mysql and in the escape strings of postgresql.
+func (s *splitter) quoteEnd(i int) (int, error) {
+	quote := s.script[i]
+	closing := quote
+	if quote == '[' {
+		closing = ']'
+	}
+	backslashEscape := false
+	if quote == '\'' || quote == '"' {
+		switch s.dialect {
+		case DialectMySQL:
+			backslashEscape = true
+		case DialectPostgreSQL:
+			backslashEscape = quote == '\'' && i > 0 && (s.script[i-1] == 'E' || s.script[i-1] == 'e') && !isIdentifierByte(s.script, i-2)
+		}
+	}
+	for j := i + 1; j < len(s.script); j++ {
+		c := s.script[j]
+		if backslashEscape && c == '\\' {
+			j++
+			continue
+		}
+		if c == closing {
+			if j+1 < len(s.script) && s.script[j+1] == closing {
+				j++
+				continue
+			}
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string starting with %c", quote)
+}
+
+func isIdentifierByte(script string, i int) bool {
+	if i < 0 || i >= len(script) {
+		return false
+	}
+	c := script[i]
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		script  string
+		want    []string
+	}{
+		{
+			name:    "mysql statements with quotes and comments",
+			dialect: DialectMySQL,
+			script: `-- create table
+CREATE TABLE t (id INT, name VARCHAR(32));
+INSERT INTO t VALUES (1, 'a;b'), (2, 'it\'s'); # comment; with semicolon
+/* block; comment */
+UPDATE ` + "`t`" + ` SET name = "x;y" WHERE id = 1;`,
+			want: []string{
+				"-- create table\nCREATE TABLE t (id INT, name VARCHAR(32))",
+				"INSERT INTO t VALUES (1, 'a;b'), (2, 'it\\'s')",
+				"# comment; with semicolon\n/* block; comment */\nUPDATE `t` SET name = \"x;y\" WHERE id = 1",
+			},
+		},
+		{
+			name:    "mysql delimiter",
+			dialect: DialectMySQL,
+			script: `DELIMITER $$
+CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END$$
+DELIMITER ;
+CALL p();`,
+			want: []string{
+				"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END",
+				"CALL p()",
+			},
+		},
+		{
+			name:    "postgresql dollar quoting",
+			dialect: DialectPostgreSQL,
+			script: `CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;
+SELECT E'a\';b', 'c'';d', $$x;y$$;
+/* nested /* comment; */ still comment; */`,
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+				"SELECT E'a\\';b', 'c'';d', $$x;y$$",
+			},
+		},
+		{
+			name:    "sql server batches",
+			dialect: DialectSQLServer,
+			script: `CREATE TABLE t (id INT);
+INSERT INTO t VALUES (1);
+GO
+SELECT [a;GO] FROM t; -- GO
+go
+`,
+			want: []string{
+				"CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);",
+				"SELECT [a;GO] FROM t; -- GO",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitStatements(tt.dialect, tt.script)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitStatementsError(t *testing.T) {
+	_, err := SplitStatements(DialectPostgreSQL, "SELECT 'a;")
+	assert.Error(t, err)
+	_, err = SplitStatements(DialectPostgreSQL, "SELECT $$a;")
+	assert.Error(t, err)
+	_, err = SplitStatements("oracle", "SELECT 1 FROM dual")
+	assert.Error(t, err)
+}
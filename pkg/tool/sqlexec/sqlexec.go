@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlexec
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+type Dialect string
+
+const (
+	DialectMySQL      Dialect = "mysql"
+	DialectPostgreSQL Dialect = "postgresql"
+	DialectSQLServer  Dialect = "sqlserver"
+)
+
+// SSL modes of the postgresql connection, see https://www.postgresql.org/docs/current/libpq-ssl.html
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+
+	DefaultSSLMode = SSLModeRequire
+)
+
+// Config is the connection info of a database instance
+type Config struct {
+	Dialect  Dialect
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// Database is optional, the default database of the user is used if it is empty
+	Database string
+	// SSLMode is the ssl mode of the postgresql connection, DefaultSSLMode is used if it is empty
+	SSLMode string
+}
+
+// ValidateSSLMode checks the ssl mode is supported by the postgresql driver, an empty mode means the default one
+func ValidateSSLMode(mode string) error {
+	switch mode {
+	case "", SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		return nil
+	default:
+		return fmt.Errorf("unsupported ssl mode: %s", mode)
+	}
+}
+
+func (c *Config) driverAndDSN() (string, string, error) {
+	switch c.Dialect {
+	case DialectMySQL:
+		return "mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", c.Username, c.Password, c.Host, c.Port, c.Database), nil
+	case DialectPostgreSQL:
+		if err := ValidateSSLMode(c.SSLMode); err != nil {
+			return "", "", err
+		}
+		sslMode := c.SSLMode
+		if sslMode == "" {
+			sslMode = DefaultSSLMode
+		}
+		dsn := &url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.Username, c.Password),
+			Host:     net.JoinHostPort(c.Host, c.Port),
+			Path:     "/" + c.Database,
+			RawQuery: url.Values{"sslmode": []string{sslMode}}.Encode(),
+		}
+		return "postgres", dsn.String(), nil
+	case DialectSQLServer:
+		query := url.Values{}
+		if c.Database != "" {
+			query.Set("database", c.Database)
+		}
+		dsn := &url.URL{
+			Scheme:   "sqlserver",
+			User:     url.UserPassword(c.Username, c.Password),
+			Host:     net.JoinHostPort(c.Host, c.Port),
+			RawQuery: query.Encode(),
+		}
+		return "sqlserver", dsn.String(), nil
+	default:
+		return "", "", fmt.Errorf("unsupported sql dialect: %s", c.Dialect)
+	}
+}
+
+func Open(c *Config) (*sql.DB, error) {
+	driver, dsn, err := c.driverAndDSN()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s failed: %v", c.Dialect, err)
+	}
+	return db, nil
+}
+
+// Ping checks the connectivity of the database instance
+func Ping(ctx context.Context, c *Config) error {
+	db, err := Open(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping %s failed: %v", c.Dialect, err)
+	}
+	return nil
+}
+
+// Execer is implemented by both *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type StatementResult struct {
+	SQL string
+	// Executed is false for the statements after a failed one
+	Executed     bool
+	RowsAffected int64
+	Duration     time.Duration
+	Err          error
+}
+
+// ExecStatements executes the statements one by one and stops at the first failed statement,
+// the results of all the statements are returned, the error of the failed statement is returned as well.
+func ExecStatements(ctx context.Context, execer Execer, statements []string) ([]*StatementResult, error) {
+	results := make([]*StatementResult, 0, len(statements))
+	var execErr error
+	for i, statement := range statements {
+		result := &StatementResult{SQL: statement}
+		results = append(results, result)
+		if execErr != nil {
+			continue
+		}
+
+		start := time.Now()
+		res, err := execer.ExecContext(ctx, statement)
+		result.Executed = true
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Err = err
+			execErr = fmt.Errorf("exec statement %d failed: %v", i+1, err)
+			continue
+		}
+		// some drivers do not support the rows affected, e.g. for ddl statements
+		if rows, err := res.RowsAffected(); err == nil {
+			result.RowsAffected = rows
+		}
+	}
+	return results, execErr
+}
+
+// Rollback executes the rollback statements of a failed script. The rollback runs on its own context since the context
+// of the script is usually done when the script failed by cancel or timeout, which is exactly when the rollback matters.
+func Rollback(db *sql.DB, statements []string, timeout time.Duration) ([]*StatementResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return ExecStatements(ctx, db, statements)
+}
+
+// SupportsTransactionalDDL returns true if the DDL statements of the dialect can be rolled back,
+// mysql commits the transaction implicitly before and after a DDL statement.
+func SupportsTransactionalDDL(dialect Dialect) bool {
+	return dialect == DialectPostgreSQL || dialect == DialectSQLServer
+}
+
+// DryRun executes the statements in a transaction and always rolls it back
+func DryRun(ctx context.Context, db *sql.DB, statements []string) ([]*StatementResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	return ExecStatements(ctx, tx, statements)
+}
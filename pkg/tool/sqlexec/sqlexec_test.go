@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlexec
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverAndDSN(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		wantDriver string
+		wantDSN    string
+		wantErr    bool
+	}{
+		{
+			name:       "postgresql requires ssl by default",
+			config:     &Config{Dialect: DialectPostgreSQL, Host: "db", Port: "5432", Username: "u", Password: "p", Database: "app"},
+			wantDriver: "postgres",
+			wantDSN:    "postgres://u:p@db:5432/app?sslmode=require",
+		},
+		{
+			name:       "postgresql with ssl disabled",
+			config:     &Config{Dialect: DialectPostgreSQL, Host: "db", Port: "5432", Username: "u", Password: "p", Database: "app", SSLMode: SSLModeDisable},
+			wantDriver: "postgres",
+			wantDSN:    "postgres://u:p@db:5432/app?sslmode=disable",
+		},
+		{
+			name:       "postgresql with certificate verification",
+			config:     &Config{Dialect: DialectPostgreSQL, Host: "db", Port: "5432", Username: "u", Password: "p@ss", SSLMode: SSLModeVerifyFull},
+			wantDriver: "postgres",
+			wantDSN:    "postgres://u:p%40ss@db:5432/?sslmode=verify-full",
+		},
+		{
+			name:    "postgresql with unsupported ssl mode",
+			config:  &Config{Dialect: DialectPostgreSQL, Host: "db", Port: "5432", SSLMode: "prefer"},
+			wantErr: true,
+		},
+		{
+			name:       "sqlserver",
+			config:     &Config{Dialect: DialectSQLServer, Host: "db", Port: "1433", Username: "sa", Password: "p", Database: "app"},
+			wantDriver: "sqlserver",
+			wantDSN:    "sqlserver://sa:p@db:1433?database=app",
+		},
+		{
+			name:    "unsupported dialect",
+			config:  &Config{Dialect: "oracle"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, dsn, err := tt.config.driverAndDSN()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDriver, driver)
+			assert.Equal(t, tt.wantDSN, dsn)
+		})
+	}
+}
+
+// fakeDriver records the statements executed on a connection, statements containing FAIL return an error
+type fakeDriver struct {
+	mu         sync.Mutex
+	executed   []string
+	committed  int
+	rolledBack int
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{driver: d}, nil }
+
+type fakeConn struct{ driver *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return &fakeTx{driver: c.driver}, nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "FAIL") {
+		return nil, errors.New("statement failed")
+	}
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.executed = append(c.driver.executed, query)
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{ driver *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.committed++
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.rolledBack++
+	return nil
+}
+
+var fakeDriverID int
+
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDriver) {
+	fakeDriverID++
+	name := fmt.Sprintf("sqlexec-fake-%d", fakeDriverID)
+	d := &fakeDriver{}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestExecStatements(t *testing.T) {
+	db, d := openFakeDB(t)
+
+	results, err := ExecStatements(context.Background(), db, []string{"CREATE TABLE a", "FAIL", "CREATE TABLE b"})
+	assert.Error(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Executed)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, int64(1), results[0].RowsAffected)
+	assert.True(t, results[1].Executed)
+	assert.Error(t, results[1].Err)
+	assert.False(t, results[2].Executed)
+	assert.Equal(t, []string{"CREATE TABLE a"}, d.executed)
+}
+
+func TestDryRun(t *testing.T) {
+	db, d := openFakeDB(t)
+
+	results, err := DryRun(context.Background(), db, []string{"CREATE TABLE a", "INSERT INTO a VALUES (1)"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[1].Executed)
+	assert.Equal(t, 0, d.committed)
+	assert.Equal(t, 1, d.rolledBack)
+
+	_, err = DryRun(context.Background(), db, []string{"FAIL"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, d.committed)
+	assert.Equal(t, 2, d.rolledBack)
+}
+
+func TestRollbackAfterCancel(t *testing.T) {
+	db, d := openFakeDB(t)
+
+	// the script fails because the job is cancelled, the rollback still runs
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ExecStatements(ctx, db, []string{"CREATE TABLE a"})
+	assert.Error(t, err)
+
+	results, err := Rollback(db, []string{"DROP TABLE a"}, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Executed)
+	assert.Equal(t, []string{"DROP TABLE a"}, d.executed)
+}
+
+func TestSupportsTransactionalDDL(t *testing.T) {
+	assert.False(t, SupportsTransactionalDDL(DialectMySQL))
+	assert.True(t, SupportsTransactionalDDL(DialectPostgreSQL))
+	assert.True(t, SupportsTransactionalDDL(DialectSQLServer))
+}
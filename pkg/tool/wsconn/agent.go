@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsconn
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// AgentMessageType is the type of the messages on the channel between zadig-agent and aslan
+type AgentMessageType string
+
+const (
+	// AgentMsgHeartbeat is sent by the agent periodically and echoed by aslan
+	AgentMsgHeartbeat AgentMessageType = "heartbeat"
+	// AgentMsgReady is sent by the agent with the number of jobs it can accept
+	AgentMsgReady AgentMessageType = "ready"
+	// AgentMsgDispatch is sent by aslan with a job for the agent to run
+	AgentMsgDispatch AgentMessageType = "dispatch"
+	// AgentMsgCancel is sent by aslan when a job running on the agent is cancelled or timed out
+	AgentMsgCancel AgentMessageType = "cancel"
+	// AgentMsgLog is sent by the agent with the new log lines of a job
+	AgentMsgLog AgentMessageType = "log"
+	// AgentMsgReport is sent by the agent with the status of a job, aslan answers with AgentMsgReportAck
+	AgentMsgReport    AgentMessageType = "report"
+	AgentMsgReportAck AgentMessageType = "report_ack"
+)
+
+const (
+	AgentHeartbeatInterval = 10 * time.Second
+	// AgentReadTimeout is how long a side waits for any message before it considers the channel dropped
+	AgentReadTimeout  = 3 * AgentHeartbeatInterval
+	agentWriteTimeout = 10 * time.Second
+)
+
+type AgentMessage struct {
+	Type AgentMessageType `json:"type"`
+	// ID correlates a report with its ack
+	ID    string          `json:"id,omitempty"`
+	JobID string          `json:"job_id,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// AgentConn is a websocket connection carrying AgentMessage, it is safe to send from multiple goroutines.
+type AgentConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func NewAgentConn(conn *websocket.Conn) *AgentConn {
+	return &AgentConn{conn: conn}
+}
+
+func (c *AgentConn) Send(msgType AgentMessageType, id, jobID string, data interface{}) error {
+	msg := &AgentMessage{
+		Type:  msgType,
+		ID:    id,
+		JobID: jobID,
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		msg.Data = raw
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(agentWriteTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+// Receive blocks until the next message arrives, it fails if nothing arrives within AgentReadTimeout.
+func (c *AgentConn) Receive() (*AgentMessage, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(AgentReadTimeout)); err != nil {
+		return nil, err
+	}
+	msg := new(AgentMessage)
+	if err := c.conn.ReadJSON(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *AgentConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsconn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAgentConnPair returns the client and server sides of a websocket connection
+func newAgentConnPair(t *testing.T) (*AgentConn, *AgentConn) {
+	serverConns := make(chan *AgentConn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade: %s", err)
+			return
+		}
+		serverConns <- NewAgentConn(ws)
+	}))
+	t.Cleanup(server.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	client := NewAgentConn(ws)
+	serverConn := <-serverConns
+	t.Cleanup(func() {
+		client.Close()
+		serverConn.Close()
+	})
+	return client, serverConn
+}
+
+func TestAgentConnSendReceive(t *testing.T) {
+	client, server := newAgentConnPair(t)
+
+	type logArgs struct {
+		Log string `json:"log"`
+	}
+	require.NoError(t, client.Send(AgentMsgLog, "", "job-1", &logArgs{Log: "hello"}))
+	require.NoError(t, client.Send(AgentMsgReport, "7", "job-1", nil))
+
+	msg, err := server.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, AgentMsgLog, msg.Type)
+	assert.Equal(t, "job-1", msg.JobID)
+	assert.JSONEq(t, `{"log":"hello"}`, string(msg.Data))
+
+	msg, err = server.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, AgentMsgReport, msg.Type)
+	assert.Equal(t, "7", msg.ID)
+	assert.Empty(t, msg.Data)
+}
+
+func TestAgentConnConcurrentSend(t *testing.T) {
+	client, server := newAgentConnPair(t)
+
+	const senders, perSender = 4, 50
+	errs := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		go func() {
+			for j := 0; j < perSender; j++ {
+				if err := client.Send(AgentMsgHeartbeat, "", "", nil); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < senders; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	for i := 0; i < senders*perSender; i++ {
+		msg, err := server.Receive()
+		require.NoError(t, err)
+		assert.Equal(t, AgentMsgHeartbeat, msg.Type)
+	}
+}
+
+func TestAgentConnSendMarshalError(t *testing.T) {
+	client, _ := newAgentConnPair(t)
+
+	assert.Error(t, client.Send(AgentMsgLog, "", "job-1", make(chan int)))
+}
+
+func TestAgentConnReceiveAfterClose(t *testing.T) {
+	client, server := newAgentConnPair(t)
+
+	require.NoError(t, client.Close())
+	_, err := server.Receive()
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+// CacheKeySeparator joins the key and the hash of the key files, it must not appear in the keys
+const CacheKeySeparator = "~"
+
+// StepCacheSpec is the spec of both the cache_restore and cache_save steps
+type StepCacheSpec struct {
+	// Key identifies the cache in the object storage
+	Key string `bson:"key"                        json:"key"                               yaml:"key"`
+	// KeyFiles are the lock files, such as go.sum and package-lock.json, the hash of them is appended to the key
+	KeyFiles []string `bson:"key_files"                  json:"key_files"                         yaml:"key_files"`
+	// RestoreKeys are the key prefixes used to restore the latest cache when the key is not found
+	RestoreKeys []string `bson:"restore_keys"               json:"restore_keys"                      yaml:"restore_keys"`
+	// Paths are the cached files or dirs, relative paths are relative to the workspace
+	Paths     []string `bson:"paths"                      json:"paths"                             yaml:"paths"`
+	S3DestDir string   `bson:"s3_dest_dir"                json:"s3_dest_dir"                       yaml:"s3_dest_dir"`
+	S3Storage *S3      `bson:"s3_storage"                 json:"s3_storage"                        yaml:"s3_storage"`
+	// FailOnMiss fails the cache_restore step if the cache is not found
+	FailOnMiss bool `bson:"fail_on_miss"               json:"fail_on_miss"                      yaml:"fail_on_miss"`
+	// MaxSize is the max size of the cache in MB, larger cache is not saved, 0 means no limit
+	MaxSize int64 `bson:"max_size"                   json:"max_size"                          yaml:"max_size"`
+	// TTL is the days a cache is kept since it is saved, expired caches are not restored
+	// and they are evicted when saving caches to the same dir, 0 means caches never expire
+	TTL int64 `bson:"ttl"                        json:"ttl"                               yaml:"ttl"`
+}